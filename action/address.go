@@ -0,0 +1,62 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/address"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagPreferFamily IP family chosen when the backend address is a name resolving to both, any, ipv4 or ipv6
+	FlagPreferFamily = "prefer-family"
+	// EnvPreferFamily IP family chosen when the backend address is a name resolving to both, any, ipv4 or ipv6
+	EnvPreferFamily = "VAAS_PREFER_FAMILY"
+	// FlagAddressSource how the backend address is resolved: auto, dns, interface or first-interface
+	FlagAddressSource = "addr-source"
+	// EnvAddressSource how the backend address is resolved: auto, dns, interface or first-interface
+	EnvAddressSource = "VAAS_ADDR_SOURCE"
+	// FlagDNSServer host:port of the DNS server hostnames are resolved with, the system resolver when empty
+	FlagDNSServer = "dns-server"
+	// EnvDNSServer host:port of the DNS server hostnames are resolved with, the system resolver when empty
+	EnvDNSServer = "VAAS_DNS_SERVER"
+)
+
+// AddressConfig represents flag values resolving the backend address
+type AddressConfig struct {
+	Source       string
+	PreferFamily string
+	DNSServer    string
+}
+
+// newAddressResolver creates the resolver of config, replaced in tests
+var newAddressResolver = defaultAddressResolver
+
+func defaultAddressResolver(config AddressConfig) (address.Resolver, error) {
+	return address.New(config.Source, address.Options{Family: config.PreferFamily, DNSServer: config.DNSServer})
+}
+
+// resolveAddress returns the backend address in the form VaaS stores it. IP literals are kept, and other names,
+// such as hostnames or interfaces, are resolved to an IP address of the preferred family when there is one.
+// The first-interface source resolves an address whatever value is given, even an empty one.
+func resolveAddress(ctx context.Context, value string, config AddressConfig) (string, error) {
+	resolver, err := newAddressResolver(config)
+	if err != nil {
+		return "", configError{fmt.Errorf("invalid --%s or --%s: %s", FlagAddressSource, FlagPreferFamily, err)}
+	}
+	normalized := vaas.NormalizeAddress(value)
+	if config.Source != address.SourceFirstInterface && (value == "" || net.ParseIP(normalized) != nil) {
+		return normalized, nil
+	}
+
+	ip, err := resolver.Resolve(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve backend address %s: %w", value, err)
+	}
+	log.WithContext(ctx).Infof("Resolved backend address %q to %s", value, ip)
+	return vaas.NormalizeAddress(ip.String()), nil
+}
@@ -0,0 +1,49 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/address"
+)
+
+type fakeResolver map[string]string
+
+func (r fakeResolver) Resolve(ctx context.Context, name string) (net.IP, error) {
+	if ip, ok := r[name]; ok {
+		return net.ParseIP(ip), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestResolveAddressResolvesNames(t *testing.T) {
+	newAddressResolver = func(config AddressConfig) (address.Resolver, error) {
+		return fakeResolver{"backend.example.com": "2001:DB8::1", "": "10.0.0.2"}, nil
+	}
+	defer func() { newAddressResolver = defaultAddressResolver }()
+
+	resolved, err := resolveAddress(context.Background(), "backend.example.com", AddressConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", resolved)
+
+	resolved, err = resolveAddress(context.Background(), "", AddressConfig{Source: address.SourceFirstInterface})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", resolved)
+
+	_, err = resolveAddress(context.Background(), "missing.example.com", AddressConfig{})
+	assert.EqualError(t, err, "could not resolve backend address missing.example.com: not found")
+}
+
+func TestResolveAddressKeepsIPLiterals(t *testing.T) {
+	resolved, err := resolveAddress(context.Background(), "[2001:DB8::1]", AddressConfig{Source: address.SourceDNS})
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", resolved)
+
+	_, err = resolveAddress(context.Background(), "10.0.0.1", AddressConfig{PreferFamily: "ipv5"})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}
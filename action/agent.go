@@ -0,0 +1,237 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/systemd"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// AgentName is the CLI name of this action
+	AgentName = "agent"
+	// FlagDrainPeriod how long a backend keeps serving with weight 0 before it is deregistered
+	FlagDrainPeriod = "drain-period"
+	// EnvDrainPeriod how long a backend keeps serving with weight 0 before it is deregistered
+	EnvDrainPeriod = "VAAS_DRAIN_PERIOD"
+	// FlagHeartbeatInterval how often the agent checks its backend, re-registering it when missing, 0 to not
+	FlagHeartbeatInterval = "heartbeat-interval"
+	// EnvHeartbeatInterval how often the agent checks its backend, re-registering it when missing, 0 to not
+	EnvHeartbeatInterval = "VAAS_HEARTBEAT_INTERVAL"
+	// FlagHeartbeatRenewal how often the agent renews the heartbeat tag of its backend, 0 to not tag it
+	FlagHeartbeatRenewal = "heartbeat-renewal"
+	// EnvHeartbeatRenewal how often the agent renews the heartbeat tag of its backend, 0 to not tag it
+	EnvHeartbeatRenewal = "VAAS_HEARTBEAT_RENEWAL"
+
+	defaultDrainPeriod = 30 * time.Second
+	// deregisterTimeout limits VaaS requests made after the termination signal, when ctx is already done
+	deregisterTimeout = time.Minute
+)
+
+// GetAgentDrainFlags returns flags configuring this action besides registration, such as deregistration
+func GetAgentDrainFlags() []cli.Flag {
+	return append([]cli.Flag{
+		cli.DurationFlag{
+			Name:   FlagDrainPeriod,
+			Usage:  "how long the backend keeps serving with weight 0 before it is deregistered",
+			Value:  defaultDrainPeriod,
+			EnvVar: EnvDrainPeriod,
+		},
+		cli.DurationFlag{
+			Name:   FlagHeartbeatInterval,
+			Usage:  "how often to check the backend, re-registering it when missing, 0 to not",
+			EnvVar: EnvHeartbeatInterval,
+		},
+		cli.DurationFlag{
+			Name:   FlagHeartbeatRenewal,
+			Usage:  "how often to renew the heartbeat tag of the backend, which makes VaaS regenerate VCL, 0 to not tag it",
+			EnvVar: EnvHeartbeatRenewal,
+		},
+	}, GetRampFlags()...)
+}
+
+// HeartbeatConfig represents heartbeat flag values
+type HeartbeatConfig struct {
+	// Interval is how often the backend is checked, 0 to not check it unless Renewal is set
+	Interval time.Duration
+	// Renewal is how often the heartbeat tag of the backend is renewed, 0 to not tag it
+	Renewal time.Duration
+}
+
+// GetHeartbeatParameters returns heartbeat flag values
+func GetHeartbeatParameters(c *cli.Context) HeartbeatConfig {
+	return HeartbeatConfig{Interval: c.Duration(FlagHeartbeatInterval), Renewal: c.Duration(FlagHeartbeatRenewal)}
+}
+
+// interval returns how often the backend is checked, Renewal when Interval is not set
+func (h HeartbeatConfig) interval() time.Duration {
+	if h.Interval > 0 {
+		return h.Interval
+	}
+	return h.Renewal
+}
+
+// GetAgentFlags returns a list of flags available for this action
+func GetAgentFlags() []cli.Flag {
+	return append(GetRegisterFlags(), GetAgentDrainFlags()...)
+}
+
+// AgentCLI registers a backend using CLI data, ramping up its weight if configured to,
+// and drains and deregisters it once ctx is done
+func AgentCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+	ramp, err := GetRampParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	defer superviseBySystemd(ctx)()
+	apiClient := newAPIClient(config)
+	return runAgent(ctx, apiClient, config, getRegisterParameters(c, config.Director), c.Duration(FlagDrainPeriod), ramp,
+		GetHeartbeatParameters(c))
+}
+
+// AgentK8s registers a backend using K8s data, ramping up its weight if configured to,
+// and drains and deregisters it once ctx is done
+func AgentK8s(ctx context.Context, podInfo *k8s.PodInfo, config CommonConfig, drainPeriod time.Duration,
+	ramp RampConfig, hb HeartbeatConfig) error {
+	config, registerConfig, err := getK8sRegisterParameters(podInfo, config)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	defer superviseBySystemd(ctx)()
+	return runAgent(ctx, newAPIClient(config), config, registerConfig, drainPeriod, ramp, hb)
+}
+
+// runAgent registers a backend, ramps up its weight, waits for ctx to be done and then drains and deregisters it.
+// With ramp steps the backend is registered with the weight of the first step. With a heartbeat interval the
+// backend is checked meanwhile, see heartbeat.
+func runAgent(ctx context.Context, client vaas.Client, config CommonConfig, rc RegisterConfig, drainPeriod time.Duration,
+	ramp RampConfig, hb HeartbeatConfig) error {
+	if len(config.Directors) > 1 {
+		return fmt.Errorf("%s supports a single director, got %d", AgentName, len(config.Directors))
+	}
+	if hb.Renewal > 0 {
+		rc.Tags = vaas.WithHeartbeat(rc.Tags, time.Now())
+	}
+	reassert := rc
+	var weights []int
+	if ramp.enabled() {
+		weights = ramp.weights(rc.Weight)
+		rc.Weight = weights[0]
+	}
+	if err := register(ctx, client, config, rc); err != nil {
+		return err
+	}
+	backendID, err := client.FindBackendID(ctx, config.Director, config.Address, config.Port)
+	if err != nil {
+		return fmt.Errorf("could not determine backend ID: %s", err)
+	}
+
+	if weights != nil {
+		log.WithContext(ctx).WithField(FlagBackendID, backendID).Infof("Ramping up weight through %v over %s", weights, ramp.Duration)
+		if err := rampUp(ctx, client, []int{backendID}, weights, ramp.interval()); err != nil && ctx.Err() == nil {
+			log.WithContext(ctx).WithField(FlagBackendID, backendID).Errorf("Ramp-up stopped, backend keeps its weight: %s", err)
+		}
+	}
+
+	log.WithContext(ctx).WithField(FlagBackendID, backendID).Info("Backend registered, waiting for termination signal")
+	notifySystemd(ctx, systemd.Ready, systemd.Status(fmt.Sprintf("Backend %d registered in %s", backendID, config.Director)))
+	if hb.interval() > 0 {
+		backendID = heartbeat(ctx, client, config, reassert, backendID, hb)
+	} else {
+		<-ctx.Done()
+	}
+
+	deregisterCtx, cancel := context.WithTimeout(context.Background(), drainPeriod+deregisterTimeout)
+	defer cancel()
+	notifySystemd(ctx, systemd.Status(fmt.Sprintf("Draining and deregistering backend %d", backendID)))
+	return drainAndDeregister(deregisterCtx, client, config, backendID, drainPeriod)
+}
+
+// heartbeat checks the backend every interval of hb until ctx is done, returning its ID by then. The backend is
+// registered again with rc when it is missing from VaaS, e.g. after it was deleted by hand. Checks only read the
+// backend; its heartbeat tag, which reconcile uses to prune backends of agents gone silent, is patched once it is
+// older than the renewal of hb, as every change of a backend makes VaaS regenerate VCL. Failures are only logged.
+func heartbeat(ctx context.Context, client vaas.Client, config CommonConfig, rc RegisterConfig, backendID int,
+	hb HeartbeatConfig) int {
+	ticker := time.NewTicker(hb.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return backendID
+		case <-ticker.C:
+		}
+
+		logger := log.WithContext(ctx).WithField(FlagBackendID, backendID)
+		backend, err := client.GetBackend(ctx, backendID)
+		if errors.Is(err, vaas.ErrBackendNotFound) {
+			logger.Warn("Backend missing from VaaS, registering it again")
+			if hb.Renewal > 0 {
+				rc.Tags = vaas.WithHeartbeat(rc.Tags, time.Now())
+			}
+			if err := register(ctx, client, config, rc); err != nil {
+				logger.Errorf("Could not register backend again: %s", err)
+				continue
+			}
+			if id, err := client.FindBackendID(ctx, config.Director, config.Address, config.Port); err == nil {
+				backendID = id
+			}
+			continue
+		}
+		if err != nil {
+			logger.Errorf("Could not check backend: %s", err)
+			continue
+		}
+		if last, ok := vaas.LastHeartbeat(backend.Tags); hb.Renewal <= 0 || (ok && time.Since(last) < hb.Renewal) {
+			continue
+		}
+		if err := client.UpdateBackend(ctx, backendID, vaas.BackendPatch{Tags: vaas.WithHeartbeat(backend.Tags, time.Now())}); err != nil {
+			logger.Errorf("Could not renew heartbeat: %s", err)
+			continue
+		}
+		logger.Debug("Renewed heartbeat")
+	}
+}
+
+// drainAndDeregister stops sending new traffic to a backend, waits drainPeriod for in-flight requests and removes it
+func drainAndDeregister(ctx context.Context, client vaas.Client, config CommonConfig, backendID int, drainPeriod time.Duration) error {
+	logger := log.WithContext(ctx).WithField(FlagBackendID, backendID)
+	if err := protectLastBackend(ctx, client, config, withID(backendID)); err != nil {
+		return err
+	}
+	if err := client.SetBackendWeight(ctx, backendID, 0); err != nil {
+		logger.Errorf("Could not drain backend, deregistering right away: %s", err)
+	} else {
+		logger.Infof("Draining backend for %s", drainPeriod)
+		select {
+		case <-time.After(drainPeriod):
+		case <-ctx.Done():
+		}
+	}
+
+	if err := deleteBackend(ctx, client, config, backendID); err != nil {
+		return fmt.Errorf("could not deregister: %w", err)
+	}
+	logger.Info("Successfully scheduled backend for deletion via VaaS")
+	return removeStateOf(config.StateFile, backendID)
+}
@@ -0,0 +1,109 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/systemd"
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestAgentDrainsBackendBeforeDeregistering(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := runAgent(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}, 0, RampConfig{}, HeartbeatConfig{})
+
+	require.NoError(t, err)
+	require.Empty(t, client.Backends())
+	calls := client.Calls()
+	require.Equal(t, []string{"SetBackendWeight", "DeleteBackend"}, calls[len(calls)-2:])
+}
+
+func TestAgentHeartbeatReRegistersMissingBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, register(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}))
+	backendID, err := client.FindBackendID(ctx, "director", "127.0.0.1", 80)
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteBackend(ctx, backendID))
+
+	done := make(chan int)
+	go func() {
+		done <- heartbeat(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}, backendID,
+			HeartbeatConfig{Interval: time.Millisecond, Renewal: time.Hour})
+	}()
+	require.Eventually(t, func() bool { return len(client.Backends()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, ok := vaas.LastHeartbeat(client.Backends()[0].Tags)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+
+	reregisteredID := <-done
+	assert.NotEqual(t, backendID, reregisteredID)
+}
+
+func TestAgentHeartbeatOnlyReadsBackendUntilItsTagIsDue(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, register(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: vaas.WithHeartbeat(nil, time.Now())}))
+	backendID, err := client.FindBackendID(ctx, "director", "127.0.0.1", 80)
+	require.NoError(t, err)
+
+	for _, hb := range []HeartbeatConfig{{Interval: time.Millisecond}, {Interval: time.Millisecond, Renewal: time.Hour}} {
+		checkCtx, stop := context.WithTimeout(ctx, 20*time.Millisecond)
+		assert.Equal(t, backendID, heartbeat(checkCtx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}, backendID, hb))
+		stop()
+	}
+
+	assert.Contains(t, client.Calls(), "GetBackend")
+	assert.NotContains(t, client.Calls(), "UpdateBackend")
+
+	checkCtx, stop := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer stop()
+	heartbeat(checkCtx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}, backendID,
+		HeartbeatConfig{Interval: time.Millisecond, Renewal: time.Nanosecond})
+	assert.Contains(t, client.Calls(), "UpdateBackend")
+}
+
+func TestAgentNotifiesSystemdOnceBackendIsRegistered(t *testing.T) {
+	defer func(notify func(...string) (bool, error)) { sdNotify = notify }(sdNotify)
+	var states []string
+	sdNotify = func(s ...string) (bool, error) {
+		states = append(states, s...)
+		return true, nil
+	}
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	require.NoError(t, runAgent(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}, 0, RampConfig{}, HeartbeatConfig{}))
+
+	require.Len(t, states, 3)
+	assert.Equal(t, systemd.Ready, states[0])
+	assert.Contains(t, states[1], "registered in director")
+	assert.Contains(t, states[2], "Draining")
+}
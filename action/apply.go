@@ -0,0 +1,208 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ApplyName is the CLI name of this action
+	ApplyName = "apply"
+	// FlagDesiredStateFile file the desired state is read from, stdin when empty or -
+	FlagDesiredStateFile = "file"
+	// FlagPlan only prints the changes apply would make
+	FlagPlan = "plan"
+)
+
+// GetApplyFlags returns a list of flags available for this action
+func GetApplyFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagDesiredStateFile,
+			Usage: "YAML or JSON file the desired state of directors is read from, stdin when not given",
+		},
+		cli.BoolFlag{
+			Name:  FlagPlan,
+			Usage: "print the changes without applying them, like --" + FlagDryRun,
+		},
+		cli.BoolFlag{
+			Name:  FlagAllowEmpty,
+			Usage: "apply directors without backends, deleting every backend of them",
+		},
+	}
+}
+
+// ApplyCLI makes directors and their backends in VaaS match the desired state file of CLI data, with the fewest
+// API calls, and prints the plan of changes, as text or JSON with --output=json. With --plan or --dry-run the
+// plan is only printed.
+func ApplyCLI(ctx context.Context, c *cli.Context) error {
+	config := getCommonParameters(c.Parent())
+	var state vaas.DesiredState
+	if err := readDocument(c.String(FlagDesiredStateFile), os.Stdin, &state); err != nil {
+		return configError{fmt.Errorf("invalid desired state: %s", err)}
+	}
+	if err := state.Validate(); err != nil {
+		return configError{fmt.Errorf("invalid desired state: %s", err)}
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return configError{fmt.Errorf("error reading VaaS secret key: %s", err)}
+	}
+
+	apiClient := newAPIClient(config)
+	plan, err := vaas.NewPlan(ctx, apiClient, state, vaas.PlanOptions{AllowEmpty: c.Bool(FlagAllowEmpty)})
+	if errors.Is(err, vaas.ErrEmptyDirector) {
+		return configError{fmt.Errorf("%s, pass --%s to delete every backend of it", err, FlagAllowEmpty)}
+	}
+	if err != nil {
+		return err
+	}
+
+	planOnly := config.DryRun || c.Bool(FlagPlan)
+	if add, update, remove := plan.Count(); !planOnly && remove > 0 {
+		question := fmt.Sprintf("Apply %d additions, %d changes and %d deletions to VaaS?", add, update, remove)
+		if err := confirm(config, question); err != nil {
+			return err
+		}
+	}
+	if !planOnly {
+		err = plan.Apply(ctx, apiClient)
+	}
+	if printErr := printPlan(c.App.Writer, config.Output, plan, !planOnly); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// printPlan prints changes of plan with + for additions, ~ for updates and - for deletions, and their errors
+// when applied
+func printPlan(w io.Writer, output string, plan vaas.Plan, applied bool) error {
+	if output == OutputJSON {
+		return printJSON(w, planOutput(plan))
+	}
+	if plan.Empty() {
+		_, err := fmt.Fprintln(w, "No changes, VaaS matches the desired state.")
+		return err
+	}
+
+	for _, director := range plan.Directors {
+		if director.Create == nil && len(director.Changes) == 0 {
+			continue
+		}
+		header := "director " + director.Name
+		if director.Create != nil {
+			header += " (create, service " + director.Create.Service + ")"
+		}
+		fmt.Fprintln(w, header+failure(director.Err))
+		for _, change := range director.Changes {
+			line := changeSymbol(change.Action) + " " + vaas.HostPort(change.Backend.Address, change.Backend.Port)
+			if details := changeDetails(change); len(details) > 0 {
+				line += " " + strings.Join(details, ", ")
+			}
+			fmt.Fprintln(w, "  "+line+failure(change.Err))
+		}
+	}
+
+	add, update, remove := plan.Count()
+	summary := "Plan"
+	if applied {
+		summary = "Applied"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d to add, %d to change, %d to delete.\n", summary, add, update, remove)
+	return err
+}
+
+func changeSymbol(action string) string {
+	switch action {
+	case vaas.ActionAdd:
+		return "+"
+	case vaas.ActionUpdate:
+		return "~"
+	}
+	return "-"
+}
+
+// changeDetails describes the DC, weight and tags of an added backend, or those changed by an update
+func changeDetails(change vaas.PlanChange) []string {
+	backend := change.Backend
+	tags := strings.Join(backend.Tags, ",")
+	switch {
+	case change.Action == vaas.ActionAdd:
+		details := []string{"dc " + backend.DC.Symbol}
+		if backend.Weight != nil {
+			details = append(details, "weight "+strconv.Itoa(*backend.Weight))
+		}
+		if len(backend.Tags) > 0 {
+			details = append(details, "tags "+tags)
+		}
+		return details
+	case change.Action == vaas.ActionUpdate && change.Current != nil:
+		current := change.Current
+		var details []string
+		if current.DC.Symbol != backend.DC.Symbol {
+			details = append(details, fmt.Sprintf("dc %s -> %s", current.DC.Symbol, backend.DC.Symbol))
+		}
+		if current.GetWeight() != backend.GetWeight() {
+			details = append(details, fmt.Sprintf("weight %d -> %d", current.GetWeight(), backend.GetWeight()))
+		}
+		if currentTags := strings.Join(current.Tags, ","); currentTags != tags {
+			details = append(details, fmt.Sprintf("tags [%s] -> [%s]", currentTags, tags))
+		}
+		return details
+	}
+	return nil
+}
+
+func failure(err error) string {
+	if err == nil {
+		return ""
+	}
+	return " (failed: " + err.Error() + ")"
+}
+
+type planChangeOutput struct {
+	Action  string   `json:"action"`
+	Address string   `json:"address"`
+	Port    int      `json:"port"`
+	Details []string `json:"details,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type directorPlanOutput struct {
+	Name    string             `json:"name"`
+	Create  bool               `json:"create,omitempty"`
+	Error   string             `json:"error,omitempty"`
+	Changes []planChangeOutput `json:"changes"`
+}
+
+func planOutput(plan vaas.Plan) []directorPlanOutput {
+	directors := make([]directorPlanOutput, 0, len(plan.Directors))
+	for _, director := range plan.Directors {
+		printed := directorPlanOutput{Name: director.Name, Create: director.Create != nil,
+			Changes: make([]planChangeOutput, 0, len(director.Changes))}
+		if director.Err != nil {
+			printed.Error = director.Err.Error()
+		}
+		for _, change := range director.Changes {
+			c := planChangeOutput{Action: change.Action, Address: change.Backend.Address, Port: change.Backend.Port,
+				Details: changeDetails(change)}
+			if change.Err != nil {
+				c.Error = change.Err.Error()
+			}
+			printed.Changes = append(printed.Changes, c)
+		}
+		directors = append(directors, printed)
+	}
+	return directors
+}
@@ -0,0 +1,54 @@
+package action
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestReadDesiredStateFromYAML(t *testing.T) {
+	input := "directors:\n  - name: director\n    backends:\n      - address: 10.0.0.1\n        port: 80\n        dc: dc1\n        weight: 5\n"
+
+	var state vaas.DesiredState
+	require.NoError(t, readDocument("-", strings.NewReader(input), &state))
+
+	weight := 5
+	assert.Equal(t, vaas.DesiredState{Directors: []vaas.DesiredDirector{{Name: "director", Backends: []vaas.DesiredBackend{
+		{Address: "10.0.0.1", Port: 80, DC: "dc1", Weight: &weight}}}}}, state)
+}
+
+func TestPrintPlanAsText(t *testing.T) {
+	one, five := 1, 5
+	dc := vaas.DC{Symbol: "dc1"}
+	plan := vaas.Plan{Directors: []vaas.DirectorPlan{
+		{Name: "unchanged"},
+		{Name: "director", Changes: []vaas.PlanChange{
+			{Action: vaas.ActionAdd, Backend: vaas.Backend{Address: "10.0.0.1", Port: 80, DC: dc, Tags: []string{"a"}}},
+			{Action: vaas.ActionUpdate, Backend: vaas.Backend{Address: "10.0.0.2", Port: 80, DC: dc, Weight: &five},
+				Current: &vaas.Backend{Address: "10.0.0.2", Port: 80, DC: dc, Weight: &one}, Err: errors.New("conflict")},
+			{Action: vaas.ActionDelete, Backend: vaas.Backend{Address: "10.0.0.3", Port: 80}},
+		}},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, printPlan(&out, OutputText, plan, false))
+
+	assert.Equal(t, "director director\n"+
+		"  + 10.0.0.1:80 dc dc1, tags a\n"+
+		"  ~ 10.0.0.2:80 weight 1 -> 5 (failed: conflict)\n"+
+		"  - 10.0.0.3:80\n"+
+		"Plan: 1 to add, 1 to change, 1 to delete.\n", out.String())
+}
+
+func TestPrintEmptyPlan(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printPlan(&out, OutputText, vaas.Plan{Directors: []vaas.DirectorPlan{{Name: "director"}}}, false))
+
+	assert.Equal(t, "No changes, VaaS matches the desired state.\n", out.String())
+}
@@ -0,0 +1,113 @@
+package action
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagUserAgent User-Agent of VaaS requests, naming this hook and its version by default
+	FlagUserAgent = "user-agent"
+	// EnvUserAgent User-Agent of VaaS requests, naming this hook and its version by default
+	EnvUserAgent = "VAAS_USER_AGENT"
+	// FlagHeader name=value header added to every VaaS request, e.g. for audit logs, can be repeated
+	FlagHeader = "header"
+	// EnvHeaders name=value headers added to every VaaS request, separated by commas
+	EnvHeaders = "VAAS_HEADERS"
+	// FlagAuditLog file appending a JSON line for every request changing VaaS, with its outcome
+	FlagAuditLog = "audit-log"
+	// EnvAuditLog file appending a JSON line for every request changing VaaS, with its outcome
+	EnvAuditLog = "VAAS_AUDIT_LOG"
+	// FlagAuditLogMaxSize size in megabytes the audit log is rotated at, 0 to never rotate it
+	FlagAuditLogMaxSize = "audit-log-max-size"
+	// EnvAuditLogMaxSize size in megabytes the audit log is rotated at, 0 to never rotate it
+	EnvAuditLogMaxSize = "VAAS_AUDIT_LOG_MAX_SIZE"
+	// FlagAuditLogMaxBackups number of rotated audit logs kept
+	FlagAuditLogMaxBackups = "audit-log-max-backups"
+	// EnvAuditLogMaxBackups number of rotated audit logs kept
+	EnvAuditLogMaxBackups = "VAAS_AUDIT_LOG_MAX_BACKUPS"
+
+	// DefaultAuditLogMaxSize is the size in megabytes the audit log is rotated at by default
+	DefaultAuditLogMaxSize = 100
+	// DefaultAuditLogMaxBackups is the number of rotated audit logs kept by default
+	DefaultAuditLogMaxBackups = 5
+)
+
+// auditLogs are audit logs opened by path, shared by clients of the process so that one of them rotates each
+var (
+	auditLogsMu sync.Mutex
+	auditLogs   = map[string]*vaas.AuditLog{}
+)
+
+// AuditConfig represents flag values attributing VaaS requests to their initiator and recording them locally
+type AuditConfig struct {
+	UserAgent string
+	Headers   []string
+	// Log is the audit log file, none when empty
+	Log           string
+	LogMaxSize    int
+	LogMaxBackups int
+}
+
+func (config AuditConfig) validate() error {
+	for _, header := range config.Headers {
+		if _, _, err := parseHeader(header); err != nil {
+			return configError{err}
+		}
+	}
+	if config.Log != "" {
+		if _, err := config.openLog(); err != nil {
+			return configError{err}
+		}
+	}
+	return nil
+}
+
+// openLog returns the audit log of config, opening it on first use
+func (config AuditConfig) openLog() (*vaas.AuditLog, error) {
+	auditLogsMu.Lock()
+	defer auditLogsMu.Unlock()
+	if auditLog, ok := auditLogs[config.Log]; ok {
+		return auditLog, nil
+	}
+	auditLog, err := vaas.OpenAuditLog(config.Log, int64(config.LogMaxSize)<<20, config.LogMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs[config.Log] = auditLog
+	return auditLog, nil
+}
+
+// options returns options of a client sending headers of config, skipping invalid ones rejected by validate
+func (config AuditConfig) options() []vaas.Option {
+	var options []vaas.Option
+	if config.UserAgent != "" {
+		options = append(options, vaas.WithUserAgent(config.UserAgent))
+	}
+	for _, header := range config.Headers {
+		if name, value, err := parseHeader(header); err == nil {
+			options = append(options, vaas.WithHeader(name, value))
+		}
+	}
+	if config.Log != "" {
+		if auditLog, err := config.openLog(); err != nil {
+			log.Errorf("Requests are not recorded in audit log: %s", err)
+		} else {
+			options = append(options, vaas.WithAuditLog(auditLog))
+		}
+	}
+	return options
+}
+
+func parseHeader(header string) (string, string, error) {
+	parts := strings.SplitN(header, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", "", fmt.Errorf("invalid --%s %q, expected name=value", FlagHeader, header)
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
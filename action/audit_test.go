@@ -0,0 +1,47 @@
+package action
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditConfigRejectsHeadersWithoutValue(t *testing.T) {
+	require.NoError(t, AuditConfig{Headers: []string{"X-Deploy-ID=42", "X-Initiator = ci"}}.validate())
+
+	err := AuditConfig{Headers: []string{"X-Deploy-ID"}}.validate()
+
+	var invalid configError
+	require.True(t, errors.As(err, &invalid), err)
+	assert.EqualError(t, err, `invalid --header "X-Deploy-ID", expected name=value`)
+}
+
+func TestAuditConfigOptions(t *testing.T) {
+	options := AuditConfig{UserAgent: "pipeline", Headers: []string{"X-Deploy-ID=42", "invalid"}}.options()
+
+	assert.Len(t, options, 2)
+}
+
+func TestAuditConfigOpensAuditLogOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := AuditConfig{Log: filepath.Join(dir, "audit.log")}
+
+	require.NoError(t, config.validate())
+	first, err := config.openLog()
+	require.NoError(t, err)
+	second, err := config.openLog()
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Len(t, config.options(), 1)
+
+	err = AuditConfig{Log: filepath.Join(dir, "missing", "audit.log")}.validate()
+	var invalid configError
+	assert.True(t, errors.As(err, &invalid), err)
+}
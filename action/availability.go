@@ -0,0 +1,97 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagOnUnavailable what registration does when VaaS is unavailable, OnUnavailableFail or OnUnavailableSkip
+	FlagOnUnavailable = "on-vaas-unavailable"
+	// EnvOnUnavailable what registration does when VaaS is unavailable, OnUnavailableFail or OnUnavailableSkip
+	EnvOnUnavailable = "VAAS_ON_UNAVAILABLE"
+	// FlagMaintenanceWait how long registration waits for VaaS maintenance to end, 0 to not wait
+	FlagMaintenanceWait = "maintenance-wait"
+	// EnvMaintenanceWait how long registration waits for VaaS maintenance to end, 0 to not wait
+	EnvMaintenanceWait = "VAAS_MAINTENANCE_WAIT"
+
+	// OnUnavailableFail makes registration fail when VaaS is unavailable
+	OnUnavailableFail = "fail"
+	// OnUnavailableSkip makes registration succeed with a warning when VaaS is unavailable
+	OnUnavailableSkip = "skip"
+)
+
+// maintenancePollInterval is how often VaaS is asked again when it gives no Retry-After during maintenance
+var maintenancePollInterval = 10 * time.Second
+
+// AvailabilityConfig represents flag values of what registration does when VaaS is unavailable
+type AvailabilityConfig struct {
+	OnUnavailable   string
+	MaintenanceWait time.Duration
+}
+
+func (config AvailabilityConfig) validate() error {
+	switch config.OnUnavailable {
+	case "", OnUnavailableFail, OnUnavailableSkip:
+	default:
+		return configError{fmt.Errorf("invalid --%s %q, expected %s or %s",
+			FlagOnUnavailable, config.OnUnavailable, OnUnavailableFail, OnUnavailableSkip)}
+	}
+	if config.MaintenanceWait < 0 {
+		return configError{fmt.Errorf("invalid --%s %s, expected a non-negative duration", FlagMaintenanceWait, config.MaintenanceWait)}
+	}
+	return nil
+}
+
+// run calls operation again while VaaS is in maintenance, up to MaintenanceWait, then applies skipWhenUnavailable
+func (config AvailabilityConfig) run(ctx context.Context, operation func() error) error {
+	deadline := time.Now().Add(config.MaintenanceWait)
+	err := operation()
+	for errors.Is(err, vaas.ErrMaintenance) {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		delay := maintenanceDelay(err)
+		if delay > remaining {
+			delay = remaining
+		}
+		log.WithContext(ctx).WithError(err).Warnf("VaaS in maintenance, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		err = operation()
+	}
+	return config.skipWhenUnavailable(err)
+}
+
+// maintenanceDelay returns how long to wait before asking VaaS again, as it asked in Retry-After if it did
+func maintenanceDelay(err error) time.Duration {
+	var apiError *vaas.APIError
+	if errors.As(err, &apiError) && apiError.RetryAfter > 0 {
+		return apiError.RetryAfter
+	}
+	return maintenancePollInterval
+}
+
+// skipWhenUnavailable turns err into a warning when VaaS is unavailable and registration is configured to be skipped.
+// VaaS is unavailable when it cannot be reached, fails with a server error or is in maintenance, not only once the
+// circuit breaker opens, which a single run may never send enough requests for.
+func (config AvailabilityConfig) skipWhenUnavailable(err error) error {
+	if config.OnUnavailable != OnUnavailableSkip {
+		return err
+	}
+	if !unavailable(err) && !errors.Is(err, vaas.ErrMaintenance) {
+		return err
+	}
+	log.WithError(err).Warn("VaaS unavailable, skipping registration")
+	return nil
+}
@@ -0,0 +1,105 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestSkipWhenUnavailable(t *testing.T) {
+	open := fmt.Errorf("%w: probe request in progress", vaas.ErrCircuitOpen)
+	skip := AvailabilityConfig{OnUnavailable: OnUnavailableSkip}
+	fail := AvailabilityConfig{OnUnavailable: OnUnavailableFail}
+
+	require.NoError(t, skip.skipWhenUnavailable(open))
+	require.Equal(t, open, fail.skipWhenUnavailable(open))
+	require.Equal(t, vaas.ErrDCNotFound, skip.skipWhenUnavailable(vaas.ErrDCNotFound))
+	require.NoError(t, skip.skipWhenUnavailable(maintenanceError(0)))
+	require.Error(t, AvailabilityConfig{OnUnavailable: "ignore"}.validate())
+	require.Error(t, AvailabilityConfig{MaintenanceWait: -time.Second}.validate())
+
+	client := vaastest.NewClient()
+	client.FailOn("GetDC", open)
+	err := register(context.Background(), client, CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80},
+		RegisterConfig{Weight: 1, DC: "dc1"})
+	require.NoError(t, skip.skipWhenUnavailable(err))
+}
+
+func TestSkipWhenFreshClientFindsVaaSUnavailable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	skip := AvailabilityConfig{OnUnavailable: OnUnavailableSkip}
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+
+	for _, url := range []string{ts.URL, down.URL} {
+		client := vaas.NewClient(url, "username", "api-key")
+		err := skip.run(context.Background(), func() error {
+			return register(context.Background(), client, config, RegisterConfig{Weight: 1, DC: "dc1"})
+		})
+		require.NoError(t, err, url)
+	}
+}
+
+func TestRunWaitsForMaintenanceToEnd(t *testing.T) {
+	calls := 0
+	config := AvailabilityConfig{OnUnavailable: OnUnavailableFail, MaintenanceWait: time.Second}
+
+	err := config.run(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("cannot find dc: %w", maintenanceError(time.Millisecond))
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRunGivesUpAfterMaintenanceWait(t *testing.T) {
+	defer func(interval time.Duration) { maintenancePollInterval = interval }(maintenancePollInterval)
+	maintenancePollInterval = 10 * time.Millisecond
+	calls := 0
+	config := AvailabilityConfig{MaintenanceWait: 35 * time.Millisecond}
+
+	err := config.run(context.Background(), func() error {
+		calls++
+		return maintenanceError(0)
+	})
+
+	require.True(t, errors.Is(err, vaas.ErrMaintenance), err)
+	assert.Equal(t, ExitCodeMaintenance, ExitCode(err))
+	assert.True(t, calls > 1 && calls <= 5, calls)
+}
+
+func TestRunDoesNotRetryOtherErrors(t *testing.T) {
+	calls := 0
+	config := AvailabilityConfig{MaintenanceWait: time.Minute}
+
+	err := config.run(context.Background(), func() error {
+		calls++
+		return vaas.ErrDCNotFound
+	})
+
+	require.Equal(t, vaas.ErrDCNotFound, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, ExitCode(err))
+}
+
+func maintenanceError(retryAfter time.Duration) error {
+	return &vaas.APIError{StatusCode: http.StatusServiceUnavailable, Maintenance: true, RetryAfter: retryAfter}
+}
@@ -1,11 +1,20 @@
 package action
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/resolver"
+	"github.com/allegro/vaas-registration-hook/secret"
+	"github.com/allegro/vaas-registration-hook/vaas"
 )
 
 // These Flag* consts exist to make any changes to flags consistent across the project
@@ -14,10 +23,49 @@ const (
 	FlagDebug = "debug"
 	// EnvDebug turn on debugging output
 	EnvDebug = "DEBUG"
+	// FlagDebugHTTP logs VaaS requests and responses with their headers and bodies, with credentials masked
+	FlagDebugHTTP = "debug-http"
+	// EnvDebugHTTP logs VaaS requests and responses with their headers and bodies, with credentials masked
+	EnvDebugHTTP = "VAAS_DEBUG_HTTP"
+	// FlagDebugHTTPBodyLimit number of bytes of each body logged with --debug-http
+	FlagDebugHTTPBodyLimit = "debug-http-body-limit"
+	// EnvDebugHTTPBodyLimit number of bytes of each body logged with --debug-http
+	EnvDebugHTTPBodyLimit = "VAAS_DEBUG_HTTP_BODY_LIMIT"
+	// EnvFaultInjection faults injected into VaaS requests for game days, e.g. latency=0.2:2s,error=0.1,reset=0.05.
+	// It has no flag, so that it is not enabled by accident.
+	EnvFaultInjection = "VAAS_FAULT_INJECTION"
+	// FlagConfigFile YAML or JSON file with values of flags not given otherwise
+	FlagConfigFile = "config"
+	// EnvConfigFile YAML or JSON file with values of flags not given otherwise
+	EnvConfigFile = "VAAS_HOOK_CONFIG"
+	// FlagDryRun logs changes instead of sending them to VaaS
+	FlagDryRun = "dry-run"
+	// EnvDryRun logs changes instead of sending them to VaaS
+	EnvDryRun = "VAAS_DRY_RUN"
+	// FlagYes answers yes to confirmation prompts of destructive actions
+	FlagYes = "yes"
+	// EnvYes answers yes to confirmation prompts of destructive actions
+	EnvYes = "VAAS_YES"
 	// FlagVaaSURL address of the VaaS host to query
 	FlagVaaSURL = "vaas-url"
 	// EnvVaaSURL address of the VaaS host to query
 	EnvVaaSURL = "VAAS_URL"
+	// FlagVaaSSocket Unix domain socket VaaS is reached through, e.g. of an API gateway sidecar
+	FlagVaaSSocket = "vaas-socket"
+	// EnvVaaSSocket Unix domain socket VaaS is reached through, e.g. of an API gateway sidecar
+	EnvVaaSSocket = "VAAS_SOCKET"
+	// FlagAPIVersion version of VaaS API to use, auto to use the newest one VaaS offers
+	FlagAPIVersion = "api-version"
+	// EnvAPIVersion version of VaaS API to use, auto to use the newest one VaaS offers
+	EnvAPIVersion = "VAAS_API_VERSION"
+	// FlagStrictDecode rejects VaaS responses with fields unknown to the client
+	FlagStrictDecode = "strict-decode"
+	// EnvStrictDecode rejects VaaS responses with fields unknown to the client
+	EnvStrictDecode = "VAAS_STRICT_DECODE"
+	// FlagSchemaCheck warns about fields of VaaS responses unknown to the client
+	FlagSchemaCheck = "schema-check"
+	// EnvSchemaCheck warns about fields of VaaS responses unknown to the client
+	EnvSchemaCheck = "VAAS_SCHEMA_CHECK"
 	// FlagUser represents the user name for Auth
 	FlagUser = "user"
 	// EnvVaaSUser represents the user name for Auth
@@ -30,16 +78,144 @@ const (
 	FlagSecretKeyFile = "key-file"
 	// EnvVaaSKeyFile client key for Auth
 	EnvVaaSKeyFile = "VAAS_KEY_FILE"
-	// FlagDirector represents the director name
+	// FlagDirector represents the director name, can be repeated or list several directors separated by commas
 	FlagDirector = "director"
+	// FlagCluster represents the name of the logical VaaS cluster directors have to be served by
+	FlagCluster = "cluster"
+	// EnvCluster represents the name of the logical VaaS cluster directors have to be served by
+	EnvCluster = "VAAS_CLUSTER"
 	// FlagAddr address of this backend
 	FlagAddress = "addr"
 	// FlagPort represents the port of this backend
 	FlagPort = "port"
 	// FlagCanaryTag
 	FlagCanaryTag = "canary"
+	// FlagAsyncTimeout how long to wait for VaaS to apply changes, 0 to not wait
+	FlagAsyncTimeout = "async-timeout"
+	// EnvAsyncTimeout how long to wait for VaaS to apply changes, 0 to not wait
+	EnvAsyncTimeout = "VAAS_ASYNC_TIMEOUT"
+	// FlagRequestTimeout limits the time of a single VaaS request, 0 for no limit
+	FlagRequestTimeout = "request-timeout"
+	// EnvRequestTimeout limits the time of a single VaaS request, 0 for no limit
+	EnvRequestTimeout = "VAAS_REQUEST_TIMEOUT"
+	// FlagOperationTimeout limits a VaaS call together with its retries and task polling, 0 for no limit
+	FlagOperationTimeout = "operation-timeout"
+	// EnvOperationTimeout limits a VaaS call together with its retries and task polling, 0 for no limit
+	EnvOperationTimeout = "VAAS_OPERATION_TIMEOUT"
+	// FlagDialTimeout limits opening a connection to VaaS, 0 for no limit
+	FlagDialTimeout = "dial-timeout"
+	// EnvDialTimeout limits opening a connection to VaaS, 0 for no limit
+	EnvDialTimeout = "VAAS_DIAL_TIMEOUT"
+	// FlagTLSHandshakeTimeout limits the TLS handshake with VaaS, 0 for no limit
+	FlagTLSHandshakeTimeout = "tls-handshake-timeout"
+	// EnvTLSHandshakeTimeout limits the TLS handshake with VaaS, 0 for no limit
+	EnvTLSHandshakeTimeout = "VAAS_TLS_HANDSHAKE_TIMEOUT"
+	// FlagMaxConcurrentRequests maximum number of VaaS requests sent at once, 0 for no limit
+	FlagMaxConcurrentRequests = "max-concurrent-requests"
+	// EnvMaxConcurrentRequests maximum number of VaaS requests sent at once, 0 for no limit
+	EnvMaxConcurrentRequests = "VAAS_MAX_CONCURRENT_REQUESTS"
+	// FlagRateLimit maximum average number of VaaS requests per second, 0 for no limit
+	FlagRateLimit = "rate-limit"
+	// EnvRateLimit maximum average number of VaaS requests per second, 0 for no limit
+	EnvRateLimit = "VAAS_RATE_LIMIT"
+	// FlagRateLimitBurst number of VaaS requests sent at once before the rate limit applies
+	FlagRateLimitBurst = "rate-limit-burst"
+	// EnvRateLimitBurst number of VaaS requests sent at once before the rate limit applies
+	EnvRateLimitBurst = "VAAS_RATE_LIMIT_BURST"
+	// FlagCircuitBreakerThreshold consecutive VaaS failures after which requests fail fast, 0 to never fail fast
+	FlagCircuitBreakerThreshold = "circuit-breaker-threshold"
+	// EnvCircuitBreakerThreshold consecutive VaaS failures after which requests fail fast, 0 to never fail fast
+	EnvCircuitBreakerThreshold = "VAAS_CIRCUIT_BREAKER_THRESHOLD"
+	// FlagCircuitBreakerCooldown how long requests fail fast before VaaS is probed again
+	FlagCircuitBreakerCooldown = "circuit-breaker-cooldown"
+	// EnvCircuitBreakerCooldown how long requests fail fast before VaaS is probed again
+	EnvCircuitBreakerCooldown = "VAAS_CIRCUIT_BREAKER_COOLDOWN"
+	// FlagLockRetryAttempts attempts to add a backend while VaaS reports its director changed concurrently
+	FlagLockRetryAttempts = "lock-retry-attempts"
+	// EnvLockRetryAttempts attempts to add a backend while VaaS reports its director changed concurrently
+	EnvLockRetryAttempts = "VAAS_LOCK_RETRY_ATTEMPTS"
+	// FlagLockRetryDelay delay before retrying a backend rejected for a concurrent change, doubled on every retry
+	FlagLockRetryDelay = "lock-retry-delay"
+	// EnvLockRetryDelay delay before retrying a backend rejected for a concurrent change, doubled on every retry
+	EnvLockRetryDelay = "VAAS_LOCK_RETRY_DELAY"
+	// FlagStateFile file recording the registered backend for deregistration, empty to not record it
+	FlagStateFile = "state-file"
+	// EnvStateFile file recording the registered backend for deregistration, empty to not record it
+	EnvStateFile = "VAAS_STATE_FILE"
+	// FlagPushGateway URL of Prometheus Pushgateway receiving metrics of VaaS requests
+	FlagPushGateway = "metrics-pushgateway"
+	// EnvPushGateway URL of Prometheus Pushgateway receiving metrics of VaaS requests
+	EnvPushGateway = "VAAS_METRICS_PUSHGATEWAY"
+
+	// FlagLogFormat format of log lines, text or json
+	FlagLogFormat = "log-format"
+	// EnvLogFormat format of log lines, text or json
+	EnvLogFormat = "VAAS_LOG_FORMAT"
+	// FlagLogLevel minimum level of logged lines
+	FlagLogLevel = "log-level"
+	// EnvLogLevel minimum level of logged lines
+	EnvLogLevel = "VAAS_LOG_LEVEL"
+	// FlagCorrelationID correlates log lines and VaaS requests of this run, random when not given
+	FlagCorrelationID = "correlation-id"
+	// EnvCorrelationID correlates log lines and VaaS requests of this run, random when not given
+	EnvCorrelationID = "VAAS_CORRELATION_ID"
+
+	// FlagOTLPEndpoint address of the OpenTelemetry collector receiving spans over OTLP/HTTP, empty to not export them
+	FlagOTLPEndpoint = "otlp-endpoint"
+	// EnvOTLPEndpoint address of the OpenTelemetry collector receiving spans over OTLP/HTTP, empty to not export them
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// FlagOTLPHeader key=value header of requests exporting spans, can be repeated
+	FlagOTLPHeader = "otlp-header"
+	// EnvOTLPHeaders key=value headers of requests exporting spans, separated by commas
+	EnvOTLPHeaders = "OTEL_EXPORTER_OTLP_HEADERS"
+	// FlagTraceServiceName service name of exported spans
+	FlagTraceServiceName = "trace-service-name"
+	// EnvTraceServiceName service name of exported spans
+	EnvTraceServiceName = "OTEL_SERVICE_NAME"
+	// FlagTraceParent W3C traceparent of the span, e.g. of pod startup, spans of this run are children of
+	FlagTraceParent = "trace-parent"
+	// EnvTraceParent W3C traceparent of the span, e.g. of pod startup, spans of this run are children of
+	EnvTraceParent = "TRACEPARENT"
 
-	// IDFileLoc file containing VaaS backend ID
+	// FlagCacheTTL how long directors and DCs found in VaaS are cached, 0 to not cache them
+	FlagCacheTTL = "cache-ttl"
+	// EnvCacheTTL how long directors and DCs found in VaaS are cached, 0 to not cache them
+	EnvCacheTTL = "VAAS_CACHE_TTL"
+	// FlagCacheFile file caching directors and DCs across runs, empty to cache them in memory only
+	FlagCacheFile = "cache-file"
+	// EnvCacheFile file caching directors and DCs across runs, empty to cache them in memory only
+	EnvCacheFile = "VAAS_CACHE_FILE"
+	// FlagNoCache looks up directors and DCs in VaaS bypassing the cache
+	FlagNoCache = "no-cache"
+	// EnvNoCache looks up directors and DCs in VaaS bypassing the cache
+	EnvNoCache = "VAAS_NO_CACHE"
+
+	// FlagCACert file with CA certificates to verify VaaS with
+	FlagCACert = "ca-cert"
+	// EnvCACert file with CA certificates to verify VaaS with
+	EnvCACert = "VAAS_CA_CERT"
+	// FlagClientCert file with client certificate for Auth
+	FlagClientCert = "client-cert"
+	// EnvClientCert file with client certificate for Auth
+	EnvClientCert = "VAAS_CLIENT_CERT"
+	// FlagClientKey file with client certificate key for Auth
+	FlagClientKey = "client-key"
+	// EnvClientKey file with client certificate key for Auth
+	EnvClientKey = "VAAS_CLIENT_KEY"
+	// FlagInsecureSkipVerify disables VaaS certificate verification
+	FlagInsecureSkipVerify = "insecure-skip-verify"
+	// FlagProxyURL HTTP(S) or SOCKS5 proxy VaaS is reached through, instead of HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	FlagProxyURL = "proxy-url"
+	// EnvProxyURL HTTP(S) or SOCKS5 proxy VaaS is reached through, instead of HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	EnvProxyURL = "VAAS_PROXY_URL"
+	// FlagProxyAuth user:password authenticating to the proxy
+	FlagProxyAuth = "proxy-auth"
+	// EnvProxyAuth user:password authenticating to the proxy
+	EnvProxyAuth = "VAAS_PROXY_AUTH"
+
+	// IDFileLoc file containing VaaS backend ID.
+	//
+	// Deprecated: it is shared by every task of a host, so the state file is given per task with --state-file.
 	IDFileLoc = "/tmp/vaas.id"
 )
 
@@ -47,37 +223,484 @@ const (
 type CommonConfig struct {
 	Debug        bool
 	DryRun       bool
+	AssumeYes    bool
+	Output       string
 	Canary       bool
 	Director     string
+	Directors    []string
+	Cluster      string
 	Address      string
 	VaaSURL      string
+	APIVersion   string
+	StrictDecode bool
+	SchemaCheck  bool
 	VaaSUser     string
 	VaaSKey      string
 	VaaSKeyFile  string
 	Port         int
 	AsyncTimeout time.Duration
+	// RequestTimeout limits the time of a single VaaS request, 0 for no limit
+	RequestTimeout time.Duration
+	// OperationTimeout limits a VaaS call together with its retries and task polling, 0 for no limit
+	OperationTimeout time.Duration
+	// DialTimeout and TLSHandshakeTimeout limit connecting to VaaS, 0 for no limit
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	// MaxConcurrentRequests limits the number of VaaS requests sent at once, 0 for no limit
+	MaxConcurrentRequests int
+	RateLimit             RateLimitConfig
+	CircuitBreaker        CircuitBreakerConfig
+	LockRetry             LockRetryConfig
+	Availability          AvailabilityConfig
+	Resolution            AddressConfig
+	Registry              RegistryConfig
+	Consul                ConsulConfig
+	PushGateway           string
+	StateFile             string
+	TLS                   TLSConfig
+	Proxy                 ProxyConfig
+	Log                   LogConfig
+	Tracing               TracingConfig
+	Cache                 CacheConfig
+	Credentials           CredentialsConfig
+	Vault                 VaultConfig
+	// ProtectLastBackend refuses to deregister the only remaining backend of a director, unless Force is set
+	ProtectLastBackend bool
+	Force              bool
+	// MultiVaaS are VaaS endpoints, e.g. of other regions, used besides VaaSURL
+	MultiVaaS MultiVaaSConfig
+	// Events are webhooks notified of registrations
+	Events EventsConfig
+	// DCInference infers the DC of backends registered without one
+	DCInference DCConfig
+	// Audit are the User-Agent and headers of VaaS requests
+	Audit AuditConfig
+	// DebugHTTP logs VaaS requests and responses in full
+	DebugHTTP DebugHTTPConfig
+}
+
+// RateLimitConfig represents rate limit flag values
+type RateLimitConfig struct {
+	RPS   float64
+	Burst int
+}
+
+// CircuitBreakerConfig represents circuit breaker flag values
+type CircuitBreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// LockRetryConfig represents flag values of retries of backends added to directors changed concurrently
+type LockRetryConfig struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// policy returns vaas.DefaultLockRetryPolicy with the attempts and delay of config
+func (config LockRetryConfig) policy() vaas.RetryPolicy {
+	policy := vaas.DefaultLockRetryPolicy
+	policy.MaxAttempts = config.Attempts
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if config.Delay > 0 {
+		policy.BaseDelay = config.Delay
+	}
+	return policy
+}
+
+// LogConfig represents logging flag values
+type LogConfig struct {
+	Format        string
+	Level         string
+	CorrelationID string
+}
+
+// TracingConfig represents tracing flag values
+type TracingConfig struct {
+	Endpoint    string
+	Headers     []string
+	ServiceName string
+	TraceParent string
+}
+
+// CacheConfig represents lookup cache flag values
+type CacheConfig struct {
+	TTL      time.Duration
+	File     string
+	Disabled bool
+}
+
+// DebugHTTPConfig represents request dump flag values
+type DebugHTTPConfig struct {
+	Enabled   bool
+	BodyLimit int
+}
+
+// TLSConfig represents TLS flag values
+type TLSConfig struct {
+	CACertFile         string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+}
+
+// ProxyConfig represents proxy flag values
+type ProxyConfig struct {
+	URL  string
+	Auth string
+	// Socket is a Unix domain socket VaaS is dialed through instead of its host
+	Socket string
 }
 
 func getCommonParameters(c *cli.Context) CommonConfig {
-	return CommonConfig{
-		Debug:       c.Bool(FlagDebug),
-		VaaSURL:     c.String(FlagVaaSURL),
-		VaaSUser:    c.String(FlagUser),
-		VaaSKeyFile: c.String(FlagSecretKeyFile),
-		VaaSKey:     c.String(FlagSecretKey),
-		Director:    c.String(FlagDirector),
-		Address:     c.String(FlagAddress),
-		Port:        c.Int(FlagPort),
-		Canary:      c.Bool(FlagCanaryTag),
+	config := CommonConfig{
+		Debug:        c.Bool(FlagDebug),
+		DryRun:       c.Bool(FlagDryRun),
+		AssumeYes:    c.Bool(FlagYes),
+		Output:       c.String(FlagOutput),
+		VaaSURL:      c.String(FlagVaaSURL),
+		APIVersion:   c.String(FlagAPIVersion),
+		StrictDecode: c.Bool(FlagStrictDecode),
+		SchemaCheck:  c.Bool(FlagSchemaCheck),
+		VaaSUser:     c.String(FlagUser),
+		VaaSKeyFile:  c.String(FlagSecretKeyFile),
+		VaaSKey:      c.String(FlagSecretKey),
+		Cluster:      c.String(FlagCluster),
+		Address:      c.String(FlagAddress),
+		Port:         c.Int(FlagPort),
+		Canary:       c.Bool(FlagCanaryTag),
+		AsyncTimeout: c.Duration(FlagAsyncTimeout),
+		PushGateway:  c.String(FlagPushGateway),
+		StateFile:    c.String(FlagStateFile),
+
+		ProtectLastBackend: c.Bool(FlagProtectLastBackend),
+		Force:              c.Bool(FlagForce),
+		Credentials: CredentialsConfig{
+			UserFile:       c.String(FlagUserFile),
+			SecretDir:      c.String(FlagSecretDir),
+			ReloadInterval: c.Duration(FlagSecretReloadInterval),
+		},
+		Vault: VaultConfig{
+			Address:       c.String(FlagVaultAddress),
+			Namespace:     c.String(FlagVaultNamespace),
+			CACertFile:    c.String(FlagVaultCACert),
+			Auth:          c.String(FlagVaultAuth),
+			AuthMount:     c.String(FlagVaultAuthMount),
+			Token:         c.String(FlagVaultToken),
+			RoleID:        c.String(FlagVaultRoleID),
+			SecretID:      c.String(FlagVaultSecretID),
+			Role:          c.String(FlagVaultRole),
+			Path:          c.String(FlagVaultPath),
+			UsernameField: c.String(FlagVaultUsernameField),
+			KeyField:      c.String(FlagVaultKeyField),
+		},
+		MultiVaaS: MultiVaaSConfig{
+			SecondaryURLs: c.StringSlice(FlagVaaSSecondaryURL),
+			Mode:          c.String(FlagVaaSMultiMode),
+		},
+		Events: EventsConfig{
+			WebhookURL: c.String(FlagEventWebhookURL),
+			SlackURL:   c.String(FlagSlackWebhookURL),
+		},
+		DCInference: DCConfig{
+			Sources:         c.StringSlice(FlagDCSource),
+			Mapping:         c.StringSlice(FlagDCMapping),
+			HostnamePattern: c.String(FlagDCHostnamePattern),
+		},
+		Audit: AuditConfig{
+			UserAgent:     c.String(FlagUserAgent),
+			Headers:       c.StringSlice(FlagHeader),
+			Log:           c.String(FlagAuditLog),
+			LogMaxSize:    c.Int(FlagAuditLogMaxSize),
+			LogMaxBackups: c.Int(FlagAuditLogMaxBackups),
+		},
+		DebugHTTP: DebugHTTPConfig{
+			Enabled:   c.Bool(FlagDebugHTTP),
+			BodyLimit: c.Int(FlagDebugHTTPBodyLimit),
+		},
+
+		RequestTimeout:        c.Duration(FlagRequestTimeout),
+		OperationTimeout:      c.Duration(FlagOperationTimeout),
+		DialTimeout:           c.Duration(FlagDialTimeout),
+		TLSHandshakeTimeout:   c.Duration(FlagTLSHandshakeTimeout),
+		MaxConcurrentRequests: c.Int(FlagMaxConcurrentRequests),
+		RateLimit: RateLimitConfig{
+			RPS:   c.Float64(FlagRateLimit),
+			Burst: c.Int(FlagRateLimitBurst),
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Threshold: c.Int(FlagCircuitBreakerThreshold),
+			Cooldown:  c.Duration(FlagCircuitBreakerCooldown),
+		},
+		LockRetry: LockRetryConfig{
+			Attempts: c.Int(FlagLockRetryAttempts),
+			Delay:    c.Duration(FlagLockRetryDelay),
+		},
+		Registry: RegistryConfig{
+			Kind: c.String(FlagRegistry),
+			File: c.String(FlagRegistryFile),
+		},
+		Consul: ConsulConfig{
+			URL:        c.String(FlagConsulURL),
+			Token:      c.String(FlagConsulToken),
+			Node:       c.String(FlagConsulNode),
+			Datacenter: c.String(FlagConsulDatacenter),
+		},
+		Availability: AvailabilityConfig{
+			OnUnavailable:   c.String(FlagOnUnavailable),
+			MaintenanceWait: c.Duration(FlagMaintenanceWait),
+		},
+		Resolution: AddressConfig{
+			Source:       c.String(FlagAddressSource),
+			PreferFamily: c.String(FlagPreferFamily),
+			DNSServer:    c.String(FlagDNSServer),
+		},
+		TLS: TLSConfig{
+			CACertFile:         c.String(FlagCACert),
+			ClientCertFile:     c.String(FlagClientCert),
+			ClientKeyFile:      c.String(FlagClientKey),
+			InsecureSkipVerify: c.Bool(FlagInsecureSkipVerify),
+		},
+		Proxy: ProxyConfig{
+			URL:    c.String(FlagProxyURL),
+			Auth:   c.String(FlagProxyAuth),
+			Socket: c.String(FlagVaaSSocket),
+		},
+		Log: LogConfig{
+			Format:        c.String(FlagLogFormat),
+			Level:         c.String(FlagLogLevel),
+			CorrelationID: c.String(FlagCorrelationID),
+		},
+		Cache: CacheConfig{
+			TTL:      c.Duration(FlagCacheTTL),
+			File:     c.String(FlagCacheFile),
+			Disabled: c.Bool(FlagNoCache),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    c.String(FlagOTLPEndpoint),
+			Headers:     c.StringSlice(FlagOTLPHeader),
+			ServiceName: c.String(FlagTraceServiceName),
+			TraceParent: c.String(FlagTraceParent),
+		},
+	}
+	config.SetDirectors(c.StringSlice(FlagDirector))
+	return config
+}
+
+// SetDirectors sets Directors, every director the backend is registered with, from repeated or comma-separated
+// values, and Director to the first of them
+func (config *CommonConfig) SetDirectors(values []string) {
+	config.Directors = nil
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Directors = append(config.Directors, name)
+			}
+		}
+	}
+
+	config.Director = ""
+	if len(config.Directors) > 0 {
+		config.Director = config.Directors[0]
+	}
+}
+
+// ResolveDirectors expands templates in names of directors with environment variables and values of sources,
+// e.g. labels of a Marathon application, later sources overriding earlier ones. See package resolver.
+func (config *CommonConfig) ResolveDirectors(sources ...resolver.Values) error {
+	r := resolver.New(append([]resolver.Values{resolver.Environment()}, sources...)...)
+	directors, err := r.ResolveAll(config.Directors)
+	if err != nil {
+		return fmt.Errorf("could not resolve director: %w", err)
+	}
+	config.SetDirectors(directors)
+	return nil
+}
+
+// forEachDirector runs action with config limited to each director of config, logging the outcome for every one.
+// Failures are returned together as a *vaas.DirectorsError once all directors have been handled.
+// Every director gets its own state file, named after the director.
+func forEachDirector(ctx context.Context, config CommonConfig, action func(CommonConfig) error) error {
+	if len(config.Directors) <= 1 {
+		return action(config)
+	}
+
+	results := make([]vaas.DirectorResult, len(config.Directors))
+	for i, director := range config.Directors {
+		directorConfig := config
+		directorConfig.Director = director
+		directorConfig.Directors = []string{director}
+		if config.StateFile != "" {
+			directorConfig.StateFile = config.StateFile + "." + director
+		}
+
+		results[i] = vaas.DirectorResult{Director: director, Err: action(directorConfig)}
+		logger := log.WithContext(ctx).WithField(FlagDirector, director)
+		if results[i].Err != nil {
+			logger.Errorf("Failed: %s", results[i].Err)
+		} else {
+			logger.Info("Succeeded")
+		}
+	}
+	return vaas.NewDirectorsError(results)
+}
+
+// getCLIParameters returns common values of an action subcommand, with the VaaS secret key read
+func getCLIParameters(c *cli.Context) (CommonConfig, error) {
+	config := getCommonParameters(c.Parent().Parent())
+	if config.Address == "" {
+		config.Address = k8s.DownwardAddress()
+	}
+	address, err := resolveAddress(context.Background(), config.Address, config.Resolution)
+	if err != nil {
+		return config, err
+	}
+	config.Address = address
+
+	if err := config.ResolveDirectors(); err != nil {
+		return config, configError{err}
+	}
+	if config.Director == "" {
+		return config, configError{errors.New("no VaaS director specified")}
+	}
+	if err := config.Availability.validate(); err != nil {
+		return config, err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return config, err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return config, err
+	}
+	if err := config.Audit.validate(); err != nil {
+		return config, err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return config, configError{fmt.Errorf("error reading VaaS secret key: %s", err)}
+	}
+	return config, nil
+}
+
+// listingValidators keep director and DC list pages revalidated by every client of the process
+var listingValidators = vaas.NewValidatorCache(vaas.DefaultValidatorCacheSize)
+
+// inFlightRequests counts VaaS requests of every client of the process, see WaitIdle
+var inFlightRequests = vaas.NewInFlight()
+
+// WaitIdle blocks until no VaaS request of the process is in progress or ctx is done
+func WaitIdle(ctx context.Context) error {
+	return inFlightRequests.WaitIdle(ctx)
+}
+
+// newAPIClient creates a VaaS API client configured from config
+func newAPIClient(config CommonConfig) vaas.Client {
+	options := []vaas.Option{
+		vaas.WithAPIVersion(config.APIVersion),
+		vaas.WithRetryPolicy(vaas.DefaultRetryPolicy),
+		vaas.WithLockRetryPolicy(config.LockRetry.policy()),
+		vaas.WithTaskPolling(vaas.DefaultTaskPollInterval, config.AsyncTimeout),
+		vaas.WithMetrics(clientMetrics),
+		vaas.WithInFlight(inFlightRequests),
+		vaas.WithDeduplication(),
+		vaas.WithTimeout(config.RequestTimeout),
+		vaas.WithOperationTimeout(config.OperationTimeout),
+		vaas.WithDialTimeout(config.DialTimeout),
+		vaas.WithTLSHandshakeTimeout(config.TLSHandshakeTimeout),
+		vaas.WithMaxConcurrentRequests(config.MaxConcurrentRequests),
+		vaas.WithRateLimit(config.RateLimit.RPS, config.RateLimit.Burst),
+	}
+	if config.CircuitBreaker.Threshold > 0 {
+		breaker := vaas.NewCircuitBreaker(config.CircuitBreaker.Threshold, config.CircuitBreaker.Cooldown)
+		options = append(options, vaas.WithCircuitBreaker(breaker))
+	}
+	if !config.Cache.Disabled && config.Cache.TTL > 0 {
+		options = append(options, vaas.WithLookupCache(vaas.NewLookupCache(config.Cache.TTL, config.Cache.File)))
+		options = append(options, vaas.WithDCCacheTTL(config.Cache.TTL))
+	} else {
+		options = append(options, vaas.WithDCCacheTTL(0))
+	}
+	if !config.Cache.Disabled {
+		options = append(options, vaas.WithConditionalRequests(listingValidators))
+	}
+	options = append(options, config.TLS.options()...)
+	options = append(options, config.Proxy.options()...)
+	options = append(options, config.Audit.options()...)
+	options = append(options, config.MultiVaaS.failoverOptions()...)
+	if auth := config.vaultAuthenticator(); auth != nil {
+		options = append(options, vaas.WithAuthenticator(auth))
+	} else if auth := config.credentialsAuthenticator(); auth != nil {
+		options = append(options, vaas.WithAuthenticator(auth))
+	}
+	if config.StrictDecode {
+		options = append(options, vaas.WithStrictDecoding())
+	}
+	if config.SchemaCheck {
+		options = append(options, vaas.WithSchemaCheck())
+	}
+	if config.DryRun {
+		options = append(options, vaas.WithDryRun())
+	}
+	if config.DebugHTTP.Enabled {
+		options = append(options, vaas.WithMiddleware(vaas.DumpMiddleware(log.StandardLogger(), config.DebugHTTP.BodyLimit)))
+	}
+	if faults := os.Getenv(EnvFaultInjection); faults != "" {
+		options = append(options, vaas.WithFaults(faults))
+	}
+	return vaas.NewClient(config.VaaSURL, config.VaaSUser, config.VaaSKey, options...)
+}
+
+func (config TLSConfig) options() []vaas.Option {
+	var options []vaas.Option
+	if config.CACertFile != "" {
+		options = append(options, vaas.WithCACertFile(config.CACertFile))
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		options = append(options, vaas.WithClientCert(config.ClientCertFile, config.ClientKeyFile))
+	}
+	if config.InsecureSkipVerify {
+		options = append(options, vaas.WithInsecureSkipVerify())
+	}
+	return options
+}
+
+func (config ProxyConfig) options() []vaas.Option {
+	var options []vaas.Option
+	if config.URL != "" {
+		options = append(options, vaas.WithProxy(config.URL))
+	}
+	if config.Auth != "" {
+		username, password := config.Auth, ""
+		if i := strings.Index(config.Auth, ":"); i >= 0 {
+			username, password = config.Auth[:i], config.Auth[i+1:]
+		}
+		options = append(options, vaas.WithProxyAuth(username, password))
+	}
+	if config.Socket != "" {
+		options = append(options, vaas.WithUnixSocket(config.Socket))
+	}
+	return options
+}
+
+// readVaaSKey reads the VaaS credentials, unless backends are registered elsewhere and VaaS is not used
+func (config *CommonConfig) readVaaSKey() error {
+	if !config.Registry.usesVaaS() {
+		return nil
+	}
+	if config.Vault.enabled() {
+		_, err := config.Vault.provider(config.VaaSUser)
+		return err
 	}
+	return config.readCredentials()
 }
 
-// GetSecretFromFile reads a value from provided file
+// GetSecretFromFile reads the VaaS key from provided file, trimming surrounding whitespace
 func (config *CommonConfig) GetSecretFromFile(secretFile string) error {
-	secret, err := ioutil.ReadFile(secretFile)
+	key, err := secret.ReadFile("VaaS key", secretFile)
 	if err != nil {
-		return fmt.Errorf("unable to read secret from file: %s, %s", secretFile, err)
+		return err
 	}
-	config.VaaSKey = string(secret)
+	config.VaaSKey = key
 	return nil
 }
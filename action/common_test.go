@@ -1,11 +1,16 @@
 package action
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
 )
 
 func TestGetSecretFromFileCorrectly(t *testing.T) {
@@ -25,3 +30,55 @@ func TestGetSecretFromFileCorrectly(t *testing.T) {
 	err = os.Remove(config.VaaSKeyFile)
 	require.NoError(t, err)
 }
+
+func TestSetDirectorsSplitsRepeatedAndCommaSeparatedValues(t *testing.T) {
+	config := CommonConfig{}
+
+	config.SetDirectors([]string{"internal, external", "", "legacy"})
+
+	require.Equal(t, []string{"internal", "external", "legacy"}, config.Directors)
+	require.Equal(t, "internal", config.Director)
+}
+
+func TestResolveDirectorsExpandsTemplates(t *testing.T) {
+	require.NoError(t, os.Setenv("VAAS_TEST_ENV", "prod"))
+	defer os.Unsetenv("VAAS_TEST_ENV")
+	config := CommonConfig{}
+	config.SetDirectors([]string{"{{.AppName}}-{{.VAAS_TEST_ENV}},static"})
+
+	require.NoError(t, config.ResolveDirectors(map[string]string{"AppName": "shop"}))
+
+	require.Equal(t, []string{"shop-prod", "static"}, config.Directors)
+	require.Equal(t, "shop-prod", config.Director)
+	config.SetDirectors([]string{"{{.Missing}}"})
+	require.Error(t, config.ResolveDirectors())
+}
+
+func TestForEachDirectorReportsFailuresOfEveryDirector(t *testing.T) {
+	config := CommonConfig{StateFile: "/tmp/vaas.id"}
+	config.SetDirectors([]string{"internal,external,legacy"})
+	var stateFiles []string
+
+	err := forEachDirector(context.Background(), config, func(config CommonConfig) error {
+		stateFiles = append(stateFiles, config.StateFile)
+		if config.Director == "internal" {
+			return nil
+		}
+		return vaas.ErrDirectorNotFound
+	})
+
+	var directorsError *vaas.DirectorsError
+	require.True(t, errors.As(err, &directorsError))
+	require.Len(t, directorsError.Failures, 2)
+	require.Equal(t, "external", directorsError.Failures[0].Director)
+	require.Equal(t, "legacy", directorsError.Failures[1].Director)
+	require.Equal(t, []string{"/tmp/vaas.id.internal", "/tmp/vaas.id.external", "/tmp/vaas.id.legacy"}, stateFiles)
+}
+
+func TestLockRetryPolicyKeepsAtLeastOneAttempt(t *testing.T) {
+	policy := LockRetryConfig{Attempts: 0, Delay: 2 * time.Second}.policy()
+
+	require.Equal(t, 1, policy.MaxAttempts)
+	require.Equal(t, 2*time.Second, policy.BaseDelay)
+	require.Equal(t, vaas.DefaultLockRetryPolicy.Jitter, policy.Jitter)
+}
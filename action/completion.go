@@ -0,0 +1,230 @@
+package action
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// CompletionName is the CLI name of this action
+	CompletionName = "completion"
+	// completionCacheTTL is how long director names and DC symbols listed for completion are reused
+	completionCacheTTL = 5 * time.Minute
+	// completionTimeout limits listing director names and DC symbols in VaaS, so that completion does not hang
+	completionTimeout = 3 * time.Second
+)
+
+// errAborted is returned when the user does not confirm a destructive action
+var errAborted = errors.New("aborted, not confirmed")
+
+// completionScripts are scripts of shells calling the hook with --generate-bash-completion for completions
+var completionScripts = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Parse(`_{{.Function}}_complete() {
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    opts=$("${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion 2>/dev/null)
+    COMPREPLY=($(compgen -W "${opts}" -- "${cur}"))
+    return 0
+}
+
+complete -F _{{.Function}}_complete {{.Name}}
+`)),
+	"zsh": template.Must(template.New("zsh").Parse(`#compdef {{.Name}}
+
+_{{.Function}}_complete() {
+    local -a opts
+    opts=("${(@f)$("${words[@]:0:$((CURRENT-1))}" --generate-bash-completion 2>/dev/null)}")
+    compadd -a opts
+}
+
+compdef _{{.Function}}_complete {{.Name}}
+`)),
+	"fish": template.Must(template.New("fish").Parse(`function __{{.Function}}_complete
+    set -l words (commandline -opc)
+    command $words[1] $words[2..-1] --generate-bash-completion 2>/dev/null
+end
+
+complete -c {{.Name}} -f -a '(__{{.Function}}_complete)'
+`)),
+}
+
+// CompletionCLI prints the completion script of the shell given as argument, one of bash, zsh and fish, for the
+// hook under the name it was run with
+func CompletionCLI(c *cli.Context) error {
+	shell := c.Args().First()
+	script, ok := completionScripts[shell]
+	if !ok {
+		return configError{fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)}
+	}
+	name := filepath.Base(os.Args[0])
+	return script.Execute(c.App.Writer, struct{ Name, Function string }{
+		Name:     name,
+		Function: strings.NewReplacer("-", "_", ".", "_").Replace(name),
+	})
+}
+
+// Complete prints completions of the command line being completed in c: director names or DC symbols listed by
+// VaaS after flags taking them, otherwise subcommands and flags of the command
+func Complete(c *cli.Context) {
+	previous := ""
+	if len(os.Args) > 2 {
+		previous = os.Args[len(os.Args)-2]
+	}
+	lister := completionLister{config: getCommonParameters(rootContext(c)), ttl: completionCacheTTL}
+	if dir, err := os.UserCacheDir(); err == nil {
+		lister.cacheDir = filepath.Join(dir, "vaas-hook")
+	}
+	for _, word := range completeWords(c, previous, lister.list) {
+		fmt.Fprintln(c.App.Writer, word)
+	}
+}
+
+// completeWords returns completions of the word following previous in c, using list for values of flags
+func completeWords(c *cli.Context, previous string, list func(flag string) []string) []string {
+	if flag := strings.TrimLeft(previous, "-"); flag != previous && (flag == FlagDirector || flag == FlagDC) {
+		return list(flag)
+	}
+
+	commands, flags := c.App.Commands, c.App.Flags
+	if c.Command.Name != "" {
+		commands, flags = c.Command.Subcommands, c.Command.Flags
+	}
+	var words []string
+	for _, command := range commands {
+		if !command.Hidden {
+			words = append(words, command.Names()...)
+		}
+	}
+	for _, flag := range flags {
+		if flag.GetName() == cli.BashCompletionFlag.GetName() {
+			continue
+		}
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 1 {
+				words = append(words, "-"+name)
+			} else {
+				words = append(words, "--"+name)
+			}
+		}
+	}
+	return words
+}
+
+// rootContext returns the context of the application c belongs to, holding its global flags
+func rootContext(c *cli.Context) *cli.Context {
+	for c.Parent() != nil {
+		c = c.Parent()
+	}
+	return c
+}
+
+// completionLister lists director names and DC symbols in VaaS for completion, caching them in cacheDir for ttl
+type completionLister struct {
+	config   CommonConfig
+	cacheDir string
+	ttl      time.Duration
+}
+
+// list returns names of values of flag, FlagDirector or FlagDC, or none if VaaS cannot be queried
+func (l completionLister) list(flag string) []string {
+	file := ""
+	if l.cacheDir != "" {
+		file = filepath.Join(l.cacheDir, fmt.Sprintf("%s-%x", flag, sha1.Sum([]byte(l.config.VaaSURL))))
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) < l.ttl {
+			if data, err := ioutil.ReadFile(file); err == nil {
+				return strings.Fields(string(data))
+			}
+		}
+	}
+
+	if l.config.VaaSURL == "" || l.config.readVaaSKey() != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	names, err := listNames(ctx, newAPIClient(l.config), flag)
+	if err != nil {
+		log.Debugf("Cannot list %s names for completion: %s", flag, err)
+		return nil
+	}
+	if file != "" && os.MkdirAll(l.cacheDir, 0700) == nil {
+		_ = ioutil.WriteFile(file, []byte(strings.Join(names, "\n")+"\n"), 0600)
+	}
+	return names
+}
+
+// listNames returns names of directors, or symbols of DCs for FlagDC, defined in VaaS
+func listNames(ctx context.Context, client vaas.Client, flag string) ([]string, error) {
+	var names []string
+	if flag == FlagDC {
+		dcs, err := client.ListDCs(ctx)
+		for _, dc := range dcs {
+			names = append(names, dc.Symbol)
+		}
+		return names, err
+	}
+	directors, err := client.ListDirectors(ctx)
+	for _, director := range directors {
+		names = append(names, director.Name)
+	}
+	return names, err
+}
+
+// stdinTerminal reports whether standard input is a terminal a user can answer prompts on
+var stdinTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// confirm asks on the terminal whether to go on with a destructive action, failing with errAborted unless the
+// user agrees. Without a terminal, e.g. in hooks, or with --yes the action goes on without asking.
+func confirm(config CommonConfig, question string) error {
+	if config.AssumeYes || !stdinTerminal() {
+		return nil
+	}
+	yes, err := ask(os.Stdin, os.Stderr, question)
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return errAborted
+	}
+	return nil
+}
+
+// ask writes question to out and reports whether the answer read from in is yes
+func ask(in io.Reader, out io.Writer, question string) (bool, error) {
+	if _, err := fmt.Fprintf(out, "%s [y/N] ", question); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
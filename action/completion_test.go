@@ -0,0 +1,101 @@
+package action
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func TestCompleteWordsListsCommandsAndFlags(t *testing.T) {
+	app := cli.NewApp()
+	app.Commands = []cli.Command{{Name: "register"}, {Name: "hidden", Hidden: true}}
+	app.Flags = []cli.Flag{cli.StringFlag{Name: FlagDirector}, cli.BoolFlag{Name: "debug, d"}, cli.BashCompletionFlag}
+	c := cli.NewContext(app, flag.NewFlagSet("test", flag.ContinueOnError), nil)
+
+	words := completeWords(c, "register", func(string) []string { return nil })
+	assert.Equal(t, []string{"register", "--director", "--debug", "-d"}, words)
+
+	c.Command = cli.Command{Name: "prune", Flags: []cli.Flag{cli.BoolFlag{Name: FlagConfirm}}}
+	assert.Equal(t, []string{"--confirm"}, completeWords(c, "prune", nil))
+}
+
+func TestCompleteWordsListsValuesOfDirectorAndDCFlags(t *testing.T) {
+	c := cli.NewContext(cli.NewApp(), flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	list := func(flag string) []string { return []string{flag + "1", flag + "2"} }
+
+	assert.Equal(t, []string{"director1", "director2"}, completeWords(c, "--"+FlagDirector, list))
+	assert.Equal(t, []string{"dc1", "dc2"}, completeWords(c, "-"+FlagDC, list))
+}
+
+func TestCompletionListerCachesNamesListedByVaaS(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"meta": {"total_count": 2}, "objects": [{"id": 1, "name": "front"}, {"id": 2, "name": "api"}]}`))
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "completion")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lister := completionLister{
+		config:   CommonConfig{VaaSURL: ts.URL, VaaSUser: "user", VaaSKey: "key"},
+		cacheDir: dir,
+		ttl:      time.Minute,
+	}
+	assert.Equal(t, []string{"front", "api"}, lister.list(FlagDirector))
+	assert.Equal(t, []string{"front", "api"}, lister.list(FlagDirector))
+	assert.Equal(t, 1, requests)
+
+	lister.ttl = 0
+	assert.Equal(t, []string{"front", "api"}, lister.list(FlagDirector))
+	assert.Equal(t, 2, requests)
+}
+
+func TestCompletionCLIPrintsScriptOfShell(t *testing.T) {
+	var output bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &output
+	app.Commands = []cli.Command{{Name: CompletionName, Action: CompletionCLI}}
+
+	require.NoError(t, app.Run([]string{"vaas-hook", CompletionName, "bash"}))
+	assert.Contains(t, output.String(), "--generate-bash-completion")
+	assert.Contains(t, output.String(), "complete -F")
+
+	err := app.Run([]string{"vaas-hook", CompletionName, "tcsh"})
+	assert.EqualError(t, err, `unsupported shell "tcsh", expected bash, zsh or fish`)
+}
+
+func TestAskAcceptsOnlyYes(t *testing.T) {
+	var output bytes.Buffer
+	yes, err := ask(strings.NewReader("y\n"), &output, "Delete route 1?")
+	require.NoError(t, err)
+	assert.True(t, yes)
+	assert.Equal(t, "Delete route 1? [y/N] ", output.String())
+
+	for _, answer := range []string{"n\n", "\n", "", "sure\n"} {
+		yes, err := ask(strings.NewReader(answer), ioutil.Discard, "Delete route 1?")
+		require.NoError(t, err)
+		assert.False(t, yes, answer)
+	}
+}
+
+func TestConfirmDoesNotAskWithoutTerminalOrWithYes(t *testing.T) {
+	defer func(terminal func() bool) { stdinTerminal = terminal }(stdinTerminal)
+
+	stdinTerminal = func() bool { return false }
+	assert.NoError(t, confirm(CommonConfig{}, "Delete route 1?"))
+
+	stdinTerminal = func() bool { return true }
+	assert.NoError(t, confirm(CommonConfig{AssumeYes: true}, "Delete route 1?"))
+}
@@ -0,0 +1,94 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/consul"
+)
+
+const (
+	// FlagConsulURL Consul HTTP API backends are mirrored to, empty to not mirror them
+	FlagConsulURL = "consul-url"
+	// EnvConsulURL Consul HTTP API backends are mirrored to, empty to not mirror them
+	EnvConsulURL = "VAAS_CONSUL_URL"
+	// FlagConsulToken ACL token of Consul requests
+	FlagConsulToken = "consul-token"
+	// EnvConsulToken ACL token of Consul requests
+	EnvConsulToken = "VAAS_CONSUL_TOKEN"
+	// FlagConsulNode Consul catalog node backends are registered at, the hostname when empty
+	FlagConsulNode = "consul-node"
+	// EnvConsulNode Consul catalog node backends are registered at, the hostname when empty
+	EnvConsulNode = "VAAS_CONSUL_NODE"
+	// FlagConsulDatacenter Consul datacenter of the catalog, the one of the agent when empty
+	FlagConsulDatacenter = "consul-datacenter"
+	// EnvConsulDatacenter Consul datacenter of the catalog, the one of the agent when empty
+	EnvConsulDatacenter = "VAAS_CONSUL_DATACENTER"
+)
+
+// ConsulConfig represents Consul mirroring flag values
+type ConsulConfig struct {
+	URL        string
+	Token      string
+	Node       string
+	Datacenter string
+}
+
+// newConsulClient creates a client of the catalog node given in config, named after the host by default
+func (config ConsulConfig) newConsulClient() *consul.Client {
+	node := config.Node
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	return consul.NewClient(config.URL, config.Token, node, config.Datacenter)
+}
+
+// consulMirror registers backends of Registry as services of a Consul catalog too, named after their directors
+type consulMirror struct {
+	Registry
+	client *consul.Client
+}
+
+func (m consulMirror) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	if err := m.Registry.Register(ctx, config, rc); err != nil {
+		return err
+	}
+
+	tags := rc.Tags
+	if config.Canary {
+		tags = append(tags, canaryTagOf(rc))
+	}
+	service := consul.Service{
+		ID:      consulServiceID(config),
+		Service: config.Director,
+		Address: config.Address,
+		Port:    config.Port,
+		Tags:    tags,
+	}
+	if err := m.client.Register(ctx, service); err != nil {
+		return err
+	}
+	log.WithContext(ctx).Infof("Mirrored address %q port %d as Consul service %s", config.Address, config.Port, service.ID)
+	return nil
+}
+
+// Deregister removes the backend from Consul even when Registry fails, so that both are cleaned up where possible
+func (m consulMirror) Deregister(ctx context.Context, config CommonConfig) error {
+	err := m.Registry.Deregister(ctx, config)
+	consulErr := m.client.Deregister(ctx, consulServiceID(config))
+	if consulErr == nil {
+		log.WithContext(ctx).Infof("Removed Consul service %s", consulServiceID(config))
+	}
+	if err != nil {
+		return err
+	}
+	return consulErr
+}
+
+// consulServiceID identifies the backend of config among services of its Consul node
+func consulServiceID(config CommonConfig) string {
+	return fmt.Sprintf("%s-%s-%d", config.Director, config.Address, config.Port)
+}
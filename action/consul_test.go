@@ -0,0 +1,91 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+// consulServer records bodies of Consul catalog requests by path
+func consulServer(t *testing.T) (*httptest.Server, func(path string) []map[string]interface{}) {
+	var mu sync.Mutex
+	requests := map[string][]map[string]interface{}{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], body)
+		mu.Unlock()
+		_, err := w.Write([]byte("true"))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func(path string) []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+}
+
+func TestConsulMirrorRegistersServiceAfterVaaS(t *testing.T) {
+	ts, requests := consulServer(t)
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	mirror := consulMirror{Registry: newVaaSRegistry(client), client: ConsulConfig{URL: ts.URL, Node: "node1"}.newConsulClient()}
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, Canary: true}
+
+	require.NoError(t, mirror.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{"web"}}))
+
+	require.Len(t, client.Backends(), 1)
+	registrations := requests("/v1/catalog/register")
+	require.Len(t, registrations, 1)
+	assert.Equal(t, "node1", registrations[0]["Node"])
+	service := registrations[0]["Service"].(map[string]interface{})
+	assert.Equal(t, "director-127.0.0.1-80", service["ID"])
+	assert.Equal(t, "director", service["Service"])
+	assert.Equal(t, float64(80), service["Port"])
+	assert.Equal(t, []interface{}{"web", "canary"}, service["Tags"])
+}
+
+func TestConsulMirrorSkipsConsulWhenRegistrationFails(t *testing.T) {
+	ts, requests := consulServer(t)
+	mirror := consulMirror{Registry: newVaaSRegistry(vaastest.NewClient()), client: ConsulConfig{URL: ts.URL}.newConsulClient()}
+
+	err := mirror.Register(context.Background(), CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80},
+		RegisterConfig{Weight: 1, DC: "dc1"})
+
+	require.Error(t, err)
+	assert.Empty(t, requests("/v1/catalog/register"))
+}
+
+func TestConsulMirrorDeregistersFromConsulWhenVaaSFails(t *testing.T) {
+	ts, requests := consulServer(t)
+	client := vaastest.NewClient()
+	client.FailOn("DeleteBackendByAddress", assert.AnError)
+	mirror := consulMirror{Registry: newVaaSRegistry(client), client: ConsulConfig{URL: ts.URL, Node: "node1"}.newConsulClient()}
+
+	err := mirror.Deregister(context.Background(), CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80})
+
+	require.True(t, errors.Is(err, assert.AnError), err)
+	deregistrations := requests("/v1/catalog/deregister")
+	require.Len(t, deregistrations, 1)
+	assert.Equal(t, "director-127.0.0.1-80", deregistrations[0]["ServiceID"])
+}
+
+func TestNewRegistryMirrorsToConsul(t *testing.T) {
+	registry := newRegistry(CommonConfig{Registry: RegistryConfig{Kind: RegistryNoop}, Consul: ConsulConfig{URL: "http://localhost:8500"}})
+
+	mirror, ok := registry.(consulMirror)
+	require.True(t, ok)
+	assert.Equal(t, noopRegistry{}, mirror.Registry)
+}
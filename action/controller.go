@@ -0,0 +1,519 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ControllerName is the CLI name of this action
+	ControllerName = "controller"
+	// FlagNamespace namespace of Pods handled by the controller, all namespaces when empty
+	FlagNamespace = "namespace"
+	// EnvNamespace namespace of Pods handled by the controller, all namespaces when empty
+	EnvNamespace = "VAAS_CONTROLLER_NAMESPACE"
+	// FlagResyncPeriod how often the controller lists all Pods to catch up with changes it missed
+	FlagResyncPeriod = "resync-period"
+	// EnvResyncPeriod how often the controller lists all Pods to catch up with changes it missed
+	EnvResyncPeriod = "VAAS_CONTROLLER_RESYNC_PERIOD"
+	// ControllerTag marks backends registered by a controller watching all namespaces, and followed by ":" and
+	// the namespace backends registered by a controller watching one
+	ControllerTag = "controller"
+	// PodTagPrefix starts the tag recording the UID of the Pod of a backend registered by a controller
+	PodTagPrefix = "pod:"
+
+	defaultResyncPeriod = 5 * time.Minute
+	// controllerRetryDelay is the wait before Pods are listed again after the Kubernetes API failed
+	controllerRetryDelay = 5 * time.Second
+)
+
+// GetControllerFlags returns a list of flags available for this action
+func GetControllerFlags() []cli.Flag {
+	flags := []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagNamespace,
+			Usage:  "namespace of Pods registered by the controller, all namespaces when empty",
+			EnvVar: EnvNamespace,
+		},
+		cli.DurationFlag{
+			Name:   FlagResyncPeriod,
+			Usage:  "how often all Pods are listed to catch up with changes that were missed",
+			Value:  defaultResyncPeriod,
+			EnvVar: EnvResyncPeriod,
+		},
+	}
+	return append(flags, GetRegisterFlags()...)
+}
+
+// ControllerCLI registers ready Pods annotated with k8s.AnnotationDirector and deregisters them once they are not,
+// until ctx is done. Register flags are defaults of Pods, which may override weight, DC and time profile.
+func ControllerCLI(ctx context.Context, c *cli.Context) error {
+	config := getCommonParameters(c.Parent())
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return fmt.Errorf("error reading VaaS secret key: %s", err)
+	}
+
+	source, err := k8s.NewPodSource()
+	if err != nil {
+		return fmt.Errorf("could not connect to Kubernetes API: %s", err)
+	}
+
+	ctrl := newController(newRegistry(config), source, config, func(director string) RegisterConfig {
+		return getRegisterParameters(c, director)
+	})
+	ctrl.namespace = c.String(FlagNamespace)
+	ctrl.resyncPeriod = c.Duration(FlagResyncPeriod)
+	if config.Registry.usesVaaS() {
+		ctrl.client = newAPIClient(config)
+	}
+	flushTracesEvery(ctx)
+	return ctrl.run(ctx)
+}
+
+// controller reconciles backends of Pods in the registry
+type controller struct {
+	registry     Registry
+	source       k8s.PodSource
+	config       CommonConfig
+	namespace    string
+	resyncPeriod time.Duration
+	// registerConfig returns defaults of registration in director
+	registerConfig func(director string) RegisterConfig
+	// client lists backends registered by a previous run of the controller, nil when they are not in VaaS
+	client vaas.Client
+	// adopted is set once backends registered by a previous run are in backends
+	adopted bool
+
+	// mu guards backends, which Pod operations change concurrently
+	mu sync.Mutex
+	// backends are registered backends by Pod UID
+	backends map[string]*podBackend
+	// operations runs operations on Pods off the event loop, see dispatch
+	operations podOperations
+}
+
+// podBackend is a backend of a Pod, or of a Docker container
+type podBackend struct {
+	config       CommonConfig
+	registration podRegistration
+	// failed is set when the backend may not be fully registered, so that registration is retried
+	failed bool
+}
+
+// podRegistration holds values of a Pod, or of a Docker container, overriding defaults of registration
+type podRegistration struct {
+	Weight      int
+	DC          string
+	TimeProfile string
+	InstanceTag string
+	// ControllerTag and PodTag mark backends of Pods registered by the controller, they are empty for containers
+	ControllerTag string
+	PodTag        string
+}
+
+// apply returns rc with values of the Pod
+func (pr podRegistration) apply(rc RegisterConfig) RegisterConfig {
+	if pr.Weight != 0 {
+		rc.Weight = pr.Weight
+	}
+	if pr.DC != "" {
+		rc.DC = pr.DC
+	}
+	if pr.TimeProfile != "" {
+		rc.TimeProfile = pr.TimeProfile
+	}
+	rc.Tags = append(rc.Tags, pr.InstanceTag)
+	for _, tag := range []string{pr.ControllerTag, pr.PodTag} {
+		if tag != "" {
+			rc.Tags = append(rc.Tags, tag)
+		}
+	}
+	return rc
+}
+
+func newController(registry Registry, source k8s.PodSource, config CommonConfig,
+	registerConfig func(director string) RegisterConfig) *controller {
+	// Pods have backends of their own, so a single state file cannot record them
+	config.StateFile = ""
+	return &controller{
+		registry:       registry,
+		source:         source,
+		config:         config,
+		resyncPeriod:   defaultResyncPeriod,
+		registerConfig: registerConfig,
+		backends:       map[string]*podBackend{},
+	}
+}
+
+// run lists and watches Pods until ctx is done. Backends stay registered once it returns,
+// as Pods keep running while the controller is restarted.
+func (ctrl *controller) run(ctx context.Context) error {
+	defer ctrl.operations.wait()
+	for ctx.Err() == nil {
+		err := ctrl.adopt(ctx)
+		var version string
+		if err == nil {
+			version, err = ctrl.resync(ctx)
+		}
+		if err == nil {
+			err = ctrl.watch(ctx, version)
+		}
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		log.Errorf("Listing Pods or their backends failed, retrying in %s: %s", controllerRetryDelay, err)
+		select {
+		case <-time.After(controllerRetryDelay):
+		case <-ctx.Done():
+		}
+	}
+	return nil
+}
+
+// adopt takes over backends that a previous run of the controller registered in VaaS, so that resync deregisters
+// those of Pods deleted while no controller was running
+func (ctrl *controller) adopt(ctx context.Context) error {
+	if ctrl.client == nil || ctrl.adopted {
+		return nil
+	}
+	backends, err := ctrl.client.SearchBackends(ctx, vaas.BackendQuery{Tag: ctrl.controllerTag()})
+	if err != nil {
+		return err
+	}
+	directors, err := ctrl.client.ListDirectors(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(directors))
+	for _, director := range directors {
+		names[director.ResourceURI] = director.Name
+	}
+
+	adopted := map[string]*podBackend{}
+	for _, backend := range backends {
+		uid, director := podUIDOf(backend.Tags), names[backend.DirectorURL]
+		if uid == "" || director == "" {
+			continue
+		}
+		pod := adopted[uid]
+		if pod == nil {
+			pod = &podBackend{config: ctrl.config}
+			pod.config.SetDirectors(nil)
+			pod.config.Address, pod.config.Port = backend.Address, backend.Port
+			adopted[uid] = pod
+		}
+		if !hasDirector(pod.config, director) {
+			pod.config.SetDirectors(append(pod.config.Directors, director))
+		}
+	}
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	for uid, backend := range adopted {
+		if ctrl.backends[uid] == nil {
+			ctrl.backends[uid] = backend
+		}
+	}
+	log.Infof("Took over backends of %d Pods registered before", len(adopted))
+	ctrl.adopted = true
+	return nil
+}
+
+// controllerTag returns the tag of backends registered by controllers watching the namespace of ctrl
+func (ctrl *controller) controllerTag() string {
+	if ctrl.namespace == "" {
+		return ControllerTag
+	}
+	return ControllerTag + ":" + ctrl.namespace
+}
+
+// podUIDOf returns the UID of the Pod recorded in tags of its backend, empty when there is none
+func podUIDOf(tags []string) string {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, PodTagPrefix) {
+			return strings.TrimPrefix(tag, PodTagPrefix)
+		}
+	}
+	return ""
+}
+
+// resync reconciles all Pods, deregistering backends of Pods that are gone, and returns the version to watch from
+func (ctrl *controller) resync(ctx context.Context) (string, error) {
+	pods, version, err := ctrl.source.ListPods(ctx, ctrl.namespace)
+	if err != nil {
+		return "", err
+	}
+
+	seen := map[string]bool{}
+	for _, pod := range pods {
+		seen[pod.GetMetadata().GetUid()] = true
+		ctrl.dispatchReconcile(ctx, pod)
+	}
+	ctrl.mu.Lock()
+	var gone []string
+	for uid := range ctrl.backends {
+		if !seen[uid] {
+			gone = append(gone, uid)
+		}
+	}
+	ctrl.mu.Unlock()
+	for _, uid := range gone {
+		ctrl.dispatchDeregister(ctx, uid)
+	}
+	return version, nil
+}
+
+// watch reconciles changed Pods until the watch ends after the resync period
+func (ctrl *controller) watch(ctx context.Context, version string) error {
+	watcher, err := ctrl.source.WatchPods(ctx, ctrl.namespace, version, ctrl.resyncPeriod)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for {
+		eventType, pod, err := watcher.Next()
+		if err != nil {
+			// The API server ends watches after their timeout, which is when Pods are listed again
+			log.Debugf("Watch ended: %s", err)
+			return nil
+		}
+
+		switch eventType {
+		case k8s.EventAdded, k8s.EventModified:
+			ctrl.dispatchReconcile(ctx, pod)
+		case k8s.EventDeleted:
+			ctrl.dispatchDeregister(ctx, pod.GetMetadata().GetUid())
+		case k8s.EventError:
+			// Usually the watched version is too old, so Pods are listed again
+			log.Debug("Watch failed")
+			return nil
+		}
+	}
+}
+
+// dispatchReconcile reconciles pod off the event loop, as its health check may take long
+func (ctrl *controller) dispatchReconcile(ctx context.Context, pod *k8s.PodInfo) {
+	ctrl.operations.dispatch(newOperation(ctx), pod.GetMetadata().GetUid(), func(ctx context.Context) {
+		ctrl.reconcile(ctx, pod)
+	})
+}
+
+// dispatchDeregister deregisters the backend of Pod with uid off the event loop
+func (ctrl *controller) dispatchDeregister(ctx context.Context, uid string) {
+	ctrl.operations.dispatch(newOperation(ctx), uid, func(ctx context.Context) {
+		ctrl.deregister(ctx, uid)
+	})
+}
+
+// reconcile registers the backend of pod when it is annotated and ready, and deregisters it otherwise
+func (ctrl *controller) reconcile(ctx context.Context, pod *k8s.PodInfo) {
+	uid := pod.GetMetadata().GetUid()
+	logger := log.WithContext(ctx).WithField("pod", pod.GetMetadata().GetNamespace()+"/"+pod.GetName())
+
+	wanted, err := ctrl.backendOf(pod)
+	if err != nil {
+		logger.Warnf("Not registering pod: %s", err)
+	}
+	current := ctrl.backend(uid)
+	if wanted == nil {
+		if current != nil {
+			ctrl.deregister(ctx, uid)
+		}
+		return
+	}
+
+	if current != nil && sameBackend(current.config, wanted.config) {
+		if !current.failed && current.registration == wanted.registration && current.config.Canary == wanted.config.Canary {
+			return
+		}
+	} else if current != nil {
+		if ctrl.deregister(ctx, uid); ctrl.backend(uid) != nil {
+			logger.Warnf("Previous backend %s:%d is left in VaaS", current.config.Address, current.config.Port)
+		}
+	}
+
+	logger.Infof("Registering %s:%d", wanted.config.Address, wanted.config.Port)
+	err = forEachDirector(ctx, wanted.config, func(config CommonConfig) error {
+		return ctrl.registry.Register(ctx, config, wanted.registration.apply(ctrl.registerConfig(config.Director)))
+	})
+	if err = ctrl.config.Availability.skipWhenUnavailable(err); err != nil {
+		logger.Errorf("Registration failed, retrying on next change or resync: %s", err)
+		wanted.failed = true
+	}
+	ctrl.setBackend(uid, wanted)
+}
+
+// deregister removes the backend of Pod with uid, if any, keeping it to retry on next resync when that fails
+func (ctrl *controller) deregister(ctx context.Context, uid string) {
+	backend := ctrl.backend(uid)
+	if backend == nil {
+		return
+	}
+
+	err := forEachDirector(ctx, backend.config, func(config CommonConfig) error {
+		return ctrl.registry.Deregister(ctx, config)
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("Deregistering %s:%d failed, retrying on next resync: %s", backend.config.Address, backend.config.Port, err)
+		return
+	}
+	ctrl.setBackend(uid, nil)
+}
+
+// backend returns the registered backend of Pod with uid, nil when it has none
+func (ctrl *controller) backend(uid string) *podBackend {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	return ctrl.backends[uid]
+}
+
+// setBackend records backend as the registered backend of Pod with uid, forgetting it when backend is nil
+func (ctrl *controller) setBackend(uid string, backend *podBackend) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	if backend == nil {
+		delete(ctrl.backends, uid)
+		return
+	}
+	ctrl.backends[uid] = backend
+}
+
+// backendOf returns the backend pod should have, nil when it should not be registered
+func (ctrl *controller) backendOf(pod *k8s.PodInfo) (*podBackend, error) {
+	directors := pod.GetRegisterDirector()
+	if directors == "" || !pod.IsReady() {
+		return nil, nil
+	}
+	endpoint, err := pod.GetRegisterEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	config := ctrl.config
+	config.SetDirectors([]string{directors})
+	if err := config.ResolveDirectors(pod.Values()); err != nil {
+		return nil, err
+	}
+	config.Address = endpoint.Address
+	config.Port = endpoint.Port
+	config.Canary = config.Canary || pod.FindAnnotation("canary")
+	config.DCInference.NodeName = pod.GetNodeName()
+
+	registration := podRegistration{
+		TimeProfile:   pod.GetTimeProfile(),
+		InstanceTag:   createInstanceTag(pod, config.Port),
+		ControllerTag: ctrl.controllerTag(),
+		PodTag:        PodTagPrefix + pod.GetMetadata().GetUid(),
+	}
+	if weight, err := pod.GetWeight(); err == nil {
+		registration.Weight = weight
+	}
+	if dc, err := pod.GetDataCenter(); err == nil {
+		registration.DC = dc
+	}
+	return &podBackend{config: config, registration: registration}, nil
+}
+
+// newOperation returns ctx of an operation on a single Pod, correlating its log lines and VaaS requests
+func newOperation(ctx context.Context) context.Context {
+	return logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+}
+
+// sameBackend tells whether configs describe the same backends in VaaS, in directors listed in any order
+func sameBackend(a, b CommonConfig) bool {
+	return a.Address == b.Address && a.Port == b.Port && sameDirectors(a.Directors, b.Directors)
+}
+
+// hasDirector tells whether director is among directors of config
+func hasDirector(config CommonConfig, director string) bool {
+	for _, name := range config.Directors {
+		if name == director {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDirectors(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// podOperations runs operations on Pods, each Pod's in order of dispatch but concurrently with those of other Pods,
+// so that a slow health check or VaaS request of a Pod holds up neither the watch nor the other Pods
+type podOperations struct {
+	mu sync.Mutex
+	// running are Pod UIDs with an operation in progress, followed by the latest operation dispatched meanwhile
+	running map[string]func()
+	// wg counts Pods with operations in progress. Only the goroutine dispatching operations adds to it and waits.
+	wg sync.WaitGroup
+}
+
+// dispatch runs operation on Pod with uid once its operation in progress, if any, is done. Operations of the Pod
+// dispatched meanwhile are replaced by the latest one, which alone reflects its current state.
+func (ops *podOperations) dispatch(ctx context.Context, uid string, operation func(context.Context)) {
+	next := func() { operation(ctx) }
+	ops.mu.Lock()
+	defer ops.mu.Unlock()
+	if ops.running == nil {
+		ops.running = map[string]func(){}
+	}
+	if _, ok := ops.running[uid]; ok {
+		ops.running[uid] = next
+		return
+	}
+
+	ops.running[uid] = nil
+	ops.wg.Add(1)
+	go func() {
+		defer ops.wg.Done()
+		for next != nil {
+			next()
+			ops.mu.Lock()
+			if next = ops.running[uid]; next == nil {
+				delete(ops.running, uid)
+			} else {
+				ops.running[uid] = nil
+			}
+			ops.mu.Unlock()
+		}
+	}()
+}
+
+// wait blocks until all dispatched operations are done
+func (ops *podOperations) wait() {
+	ops.wg.Wait()
+}
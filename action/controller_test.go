@@ -0,0 +1,253 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+type podEvent struct {
+	eventType string
+	pod       *k8s.PodInfo
+}
+
+// fakePodSource lists pods and then returns events, ending the watch once they run out
+type fakePodSource struct {
+	pods   []*k8s.PodInfo
+	events []podEvent
+}
+
+func (s *fakePodSource) ListPods(ctx context.Context, namespace string) ([]*k8s.PodInfo, string, error) {
+	return s.pods, "1", nil
+}
+
+func (s *fakePodSource) WatchPods(ctx context.Context, namespace, version string, timeout time.Duration) (k8s.PodWatcher, error) {
+	return s, nil
+}
+
+func (s *fakePodSource) Next() (string, *k8s.PodInfo, error) {
+	if len(s.events) == 0 {
+		return "", nil, errors.New("watch ended")
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event.eventType, event.pod, nil
+}
+
+func (s *fakePodSource) Close() error {
+	return nil
+}
+
+func controllerTestPod(uid, ip string, ready bool) *k8s.PodInfo {
+	phase, readyType, status := "Running", "Ready", "False"
+	if ready {
+		status = "True"
+	}
+	name := "pod-" + uid
+	return &k8s.PodInfo{Pod: &corev1.Pod{
+		Metadata: &metav1.ObjectMeta{
+			Name: &name,
+			Uid:  &uid,
+			Annotations: map[string]string{
+				k8s.AnnotationDirector: "director",
+				k8s.AnnotationPort:     "8080",
+			},
+		},
+		Spec: &corev1.PodSpec{},
+		Status: &corev1.PodStatus{
+			PodIP:      &ip,
+			Phase:      &phase,
+			Conditions: []*corev1.PodCondition{{Type: &readyType, Status: &status}},
+		},
+	}}
+}
+
+func newTestController(client *vaastest.Client, source k8s.PodSource) *controller {
+	return newController(newVaaSRegistry(client), source, CommonConfig{}, func(director string) RegisterConfig {
+		return RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}
+	})
+}
+
+func TestControllerRegistersReadyAnnotatedPods(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	unannotated := controllerTestPod("3", "10.0.0.3", true)
+	delete(unannotated.Metadata.Annotations, k8s.AnnotationDirector)
+	ctrl := newTestController(client, &fakePodSource{pods: []*k8s.PodInfo{
+		controllerTestPod("1", "10.0.0.1", true),
+		controllerTestPod("2", "10.0.0.2", false),
+		unannotated,
+	}})
+
+	_, err := ctrl.resync(context.Background())
+	ctrl.operations.wait()
+
+	require.NoError(t, err)
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	require.Equal(t, "10.0.0.1", backends[0].Address)
+	require.Equal(t, 8080, backends[0].Port)
+	require.Contains(t, backends[0].Tags, "instance:pod-1_8080")
+}
+
+func TestControllerDeregistersDeletedAndUnreadyPods(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	first, second := controllerTestPod("1", "10.0.0.1", true), controllerTestPod("2", "10.0.0.2", true)
+	evicted := controllerTestPod("2", "10.0.0.2", true)
+	evicted.Metadata.DeletionTimestamp = &metav1.Time{}
+	source := &fakePodSource{
+		pods: []*k8s.PodInfo{first, second},
+		events: []podEvent{
+			{k8s.EventModified, evicted},
+			{k8s.EventDeleted, first},
+		},
+	}
+	ctrl := newTestController(client, source)
+
+	version, err := ctrl.resync(context.Background())
+	ctrl.operations.wait()
+	require.NoError(t, err)
+	require.Len(t, client.Backends(), 2)
+
+	require.NoError(t, ctrl.watch(context.Background(), version))
+	ctrl.operations.wait()
+	require.Empty(t, client.Backends())
+	require.Empty(t, ctrl.backends)
+}
+
+func TestControllerResyncDeregistersPodsGoneUnnoticed(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	source := &fakePodSource{pods: []*k8s.PodInfo{controllerTestPod("1", "10.0.0.1", true)}}
+	ctrl := newTestController(client, source)
+	_, err := ctrl.resync(context.Background())
+	ctrl.operations.wait()
+	require.NoError(t, err)
+
+	source.pods = nil
+	_, err = ctrl.resync(context.Background())
+	ctrl.operations.wait()
+
+	require.NoError(t, err)
+	require.Empty(t, client.Backends())
+}
+
+func TestControllerMovesBackendWhenPodEndpointChanges(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	source := &fakePodSource{
+		pods:   []*k8s.PodInfo{controllerTestPod("1", "10.0.0.1", true)},
+		events: []podEvent{{k8s.EventModified, controllerTestPod("1", "10.0.0.9", true)}},
+	}
+	ctrl := newTestController(client, source)
+
+	version, err := ctrl.resync(context.Background())
+	ctrl.operations.wait()
+	require.NoError(t, err)
+	require.NoError(t, ctrl.watch(context.Background(), version))
+	ctrl.operations.wait()
+
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	require.Equal(t, "10.0.0.9", backends[0].Address)
+}
+
+func TestControllerRetriesFailedRegistration(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	source := &fakePodSource{pods: []*k8s.PodInfo{controllerTestPod("1", "10.0.0.1", true)}}
+	ctrl := newTestController(client, source)
+
+	_, err := ctrl.resync(context.Background())
+	ctrl.operations.wait()
+	require.NoError(t, err)
+	require.Empty(t, client.Backends())
+
+	client.AddDirector("director")
+	_, err = ctrl.resync(context.Background())
+	ctrl.operations.wait()
+
+	require.NoError(t, err)
+	require.Len(t, client.Backends(), 1)
+}
+
+func TestControllerTakesOverBackendsRegisteredBeforeRestart(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	cfg := CommonConfig{Director: "director", Directors: []string{"director"}, Address: "10.0.0.9", Port: 80}
+	require.NoError(t, register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}))
+	source := &fakePodSource{pods: []*k8s.PodInfo{
+		controllerTestPod("1", "10.0.0.1", true),
+		controllerTestPod("2", "10.0.0.2", true),
+	}}
+	previous := newTestController(client, source)
+	_, err := previous.resync(context.Background())
+	require.NoError(t, err)
+	previous.operations.wait()
+	require.Len(t, client.Backends(), 3)
+
+	source.pods = source.pods[:1]
+	ctrl := newTestController(client, source)
+	ctrl.client = client
+	require.NoError(t, ctrl.adopt(context.Background()))
+	_, err = ctrl.resync(context.Background())
+	require.NoError(t, err)
+	ctrl.operations.wait()
+
+	var addresses []string
+	for _, backend := range client.Backends() {
+		addresses = append(addresses, backend.Address)
+	}
+	require.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.9"}, addresses)
+}
+
+// blockingRegistry holds up registrations of address until release is closed
+type blockingRegistry struct {
+	Registry
+	address string
+	release chan struct{}
+}
+
+func (r blockingRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	if config.Address == r.address {
+		<-r.release
+	}
+	return r.Registry.Register(ctx, config, rc)
+}
+
+func TestControllerRegistersPodsWhileRegistrationOfAnotherIsPending(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	source := &fakePodSource{pods: []*k8s.PodInfo{
+		controllerTestPod("1", "10.0.0.1", true),
+		controllerTestPod("2", "10.0.0.2", true),
+	}}
+	registry := blockingRegistry{Registry: newVaaSRegistry(client), address: "10.0.0.1", release: make(chan struct{})}
+	ctrl := newController(registry, source, CommonConfig{}, func(director string) RegisterConfig {
+		return RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}
+	})
+
+	_, err := ctrl.resync(context.Background())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(client.Backends()) == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, "10.0.0.2", client.Backends()[0].Address)
+
+	close(registry.release)
+	ctrl.operations.wait()
+	require.Len(t, client.Backends(), 2)
+}
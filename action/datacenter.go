@@ -0,0 +1,90 @@
+package action
+
+import (
+	"context"
+	"os"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/datacenter"
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/validation"
+)
+
+const (
+	// FlagDCSource source the DC is inferred from when not given: node, aws, gcp or hostname, can be repeated
+	FlagDCSource = "dc-source"
+	// EnvDCSource sources the DC is inferred from when not given, separated by commas
+	EnvDCSource = "VAAS_DC_SOURCE"
+	// FlagDCMapping value=dc entry mapping zones, regions or hostname parts to DC symbols, can be repeated
+	FlagDCMapping = "dc-mapping"
+	// EnvDCMapping value=dc entries mapping zones, regions or hostname parts to DC symbols, separated by commas
+	EnvDCMapping = "VAAS_DC_MAPPING"
+	// FlagDCHostnamePattern regular expression whose dc group, or first group, of the hostname is the DC
+	FlagDCHostnamePattern = "dc-hostname-pattern"
+	// EnvDCHostnamePattern regular expression whose dc group, or first group, of the hostname is the DC
+	EnvDCHostnamePattern = "VAAS_DC_HOSTNAME_PATTERN"
+)
+
+// DCConfig represents flag values inferring the DC of backends registered without one
+type DCConfig struct {
+	Sources         []string
+	Mapping         []string
+	HostnamePattern string
+	// NodeName is the Kubernetes Node of the backend, the one given by k8s.NodeNameEnvVar when empty
+	NodeName string
+}
+
+// dcHostname returns the hostname the DC is inferred from, replaced in tests
+var dcHostname = os.Hostname
+
+func (config DCConfig) enabled() bool {
+	return len(config.Sources) > 0
+}
+
+// infer returns the DC told by the first of sources of config, failing as invalid configuration when config is
+func (config DCConfig) infer(ctx context.Context) (string, error) {
+	options, err := config.options()
+	if err != nil {
+		return "", err
+	}
+	dc, err := datacenter.Infer(ctx, options)
+	if err != nil {
+		return "", err
+	}
+	log.WithContext(ctx).Infof("Inferred DC %q", dc)
+	return dc, nil
+}
+
+func (config DCConfig) options() (datacenter.Options, error) {
+	var errs validation.Errors
+	for _, source := range config.Sources {
+		errs.Add(FlagDCSource, source, datacenter.ValidateSource(source))
+	}
+	mapping, err := datacenter.ParseMapping(config.Mapping)
+	errs.Add(FlagDCMapping, config.Mapping, err)
+	var pattern *regexp.Regexp
+	if config.HostnamePattern != "" {
+		pattern, err = regexp.Compile(config.HostnamePattern)
+		errs.Add(FlagDCHostnamePattern, config.HostnamePattern, err)
+	}
+	if err := errs.Err(); err != nil {
+		return datacenter.Options{}, configError{err}
+	}
+
+	nodeName := config.NodeName
+	return datacenter.Options{
+		Sources:         config.Sources,
+		Mapping:         mapping,
+		HostnamePattern: pattern,
+		Hostname:        dcHostname,
+		NodeZone: func(ctx context.Context) (string, error) {
+			labels, err := k8s.GetNodeLabels(ctx, nodeName)
+			if err != nil {
+				return "", err
+			}
+			return k8s.NodeZone(labels)
+		},
+	}, nil
+}
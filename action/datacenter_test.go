@@ -0,0 +1,44 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/datacenter"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestRegisterInfersMissingDC(t *testing.T) {
+	defer func(hostname func() (string, error)) { dcHostname = hostname }(dcHostname)
+	dcHostname = func() (string, error) { return "web-1.waw1.example.com", nil }
+	client := vaastest.NewClient()
+	dc := client.AddDC("dc1")
+	client.AddDirector("director")
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, DCInference: DCConfig{
+		Sources:         []string{datacenter.SourceHostname},
+		Mapping:         []string{"waw=dc1"},
+		HostnamePattern: `^[^.]+\.([a-z0-9]+)\.`,
+	}}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1})
+
+	require.NoError(t, err)
+	require.Len(t, client.Backends(), 1)
+	require.Equal(t, dc, client.Backends()[0].DC)
+}
+
+func TestRegisterRejectsInvalidDCInference(t *testing.T) {
+	client := vaastest.NewClient()
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, DCInference: DCConfig{
+		Sources: []string{"azure"},
+		Mapping: []string{"waw"},
+	}}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1})
+
+	var invalid configError
+	require.True(t, errors.As(err, &invalid), err)
+	require.Empty(t, client.Backends())
+}
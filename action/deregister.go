@@ -1,8 +1,10 @@
 package action
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -15,50 +17,71 @@ const (
 	// DeregisterName is the CLI name of this action
 	DeregisterName = "deregister"
 	// FlagBackendID represents a known backend id that is to be deregistered
-	FlagBackendID = "backend-id, id"
+	FlagBackendID = "backend-id"
+	// flagBackendIDNames are names of the backend ID flag, looked up by FlagBackendID
+	flagBackendIDNames = FlagBackendID + ", id"
+	// FlagProtectLastBackend refuses to deregister the only remaining backend of a director
+	FlagProtectLastBackend = "protect-last-backend"
+	// EnvProtectLastBackend refuses to deregister the only remaining backend of a director
+	EnvProtectLastBackend = "VAAS_PROTECT_LAST_BACKEND"
+	// FlagForce deregisters the last backend of a director despite FlagProtectLastBackend
+	FlagForce = "force"
+	// EnvForce deregisters the last backend of a director despite FlagProtectLastBackend
+	EnvForce = "VAAS_FORCE"
 )
 
-// DeregisterCLI removes a backend from VaaS using CLI data
-func DeregisterCLI(c *cli.Context) error {
-	config := getCommonParameters(c.Parent().Parent())
+// ErrLastBackend is returned when deregistration would remove the only remaining backend of a director
+var ErrLastBackend = errors.New("refusing to remove the last backend of director")
 
-	if config.Director == "" {
-		return errors.New("no VaaS director specified")
-	}
-
-	err := config.GetSecretFromFile(config.VaaSKeyFile)
+// DeregisterCLI removes a backend from the registry using CLI data, or from VaaS by its id
+func DeregisterCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getCLIParameters(c)
 	if err != nil {
-		return fmt.Errorf("error reading VaaS secret key: %s", err)
+		return err
 	}
 
-	apiClient := vaas.NewClient(config.VaaSURL, config.VaaSUser, config.VaaSKey)
 	backendID := c.Int(FlagBackendID)
+	question := fmt.Sprintf("Deregister backend %s:%d from %s?", config.Address, config.Port, strings.Join(config.Directors, ", "))
+	if backendID != 0 {
+		question = fmt.Sprintf("Deregister backend %d from %s?", backendID, strings.Join(config.Directors, ", "))
+	}
+	if err := confirm(config, question); err != nil {
+		return err
+	}
 	if backendID == 0 {
-		bid, err := apiClient.FindBackendID(config.Director, config.Address, config.Port)
-		if err != nil {
-			return fmt.Errorf("could not determine backend ID: %s", err)
-		}
-		backendID = bid
+		registry := newRegistry(config)
+		return forEachDirector(ctx, config, func(config CommonConfig) error {
+			return registry.Deregister(ctx, config)
+		})
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
 	}
 
-	if backendID != 0 {
-		if err := apiClient.DeleteBackend(backendID); err != nil {
-			return fmt.Errorf("could not deregister: %s", err)
-		}
+	apiClient := newAPIClient(config)
 
-		log.WithField(FlagBackendID, backendID).
-			Info("Successfully scheduled backend for deletion via VaaS")
-		return nil
+	if err := protectLastBackend(ctx, apiClient, config, withID(backendID)); err != nil {
+		return err
+	}
+	if err := deleteBackend(ctx, apiClient, config, backendID); err != nil {
+		return fmt.Errorf("could not deregister: %w", err)
 	}
-	return errors.New("backend ID not provided")
+
+	log.WithContext(ctx).WithField(FlagBackendID, backendID).
+		Info("Successfully scheduled backend for deletion via VaaS")
+	recordBackend(ctx, BackendResult{Director: config.Director, Address: config.Address, Port: config.Port, BackendID: backendID})
+	return removeStateOf(config.StateFile, backendID)
 }
 
-// DeregisterK8s configures a VaaS client from K8s data and removes a backend
-func DeregisterK8s(podInfo *k8s.PodInfo, config CommonConfig) (err error) {
-	config.Address = podInfo.GetPodIP()
-	config.Port = podInfo.GetDefaultPort()
-	config.Director, err = overrideValue(config.Director, podInfo.GetDirector(), "Director")
+// DeregisterK8s configures a registry from K8s data and removes the backend from it
+func DeregisterK8s(ctx context.Context, podInfo *k8s.PodInfo, config CommonConfig) (err error) {
+	endpoint, err := podInfo.GetEndpoint()
 	if err != nil {
+		return fmt.Errorf("could not resolve backend endpoint: %s", err)
+	}
+	config.Address = endpoint.Address
+	config.Port = endpoint.Port
+	if err = overrideDirectors(&config, podInfo); err != nil {
 		return
 	}
 	config.VaaSURL, err = overrideValue(config.VaaSURL, podInfo.GetVaaSURL(), "VaaS URL")
@@ -69,30 +92,109 @@ func DeregisterK8s(podInfo *k8s.PodInfo, config CommonConfig) (err error) {
 	if err != nil {
 		return
 	}
-	err = config.GetSecretFromFile(config.VaaSKeyFile)
+	err = config.readVaaSKey()
 	if err != nil {
 		return fmt.Errorf("error reading VaaS secret key: %s", err)
 	}
 
-	apiClient := vaas.NewClient(config.VaaSURL, config.VaaSUser, config.VaaSKey)
+	registry := newRegistry(config)
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		return registry.Deregister(ctx, config)
+	})
+}
+
+// deregister removes the backend recorded in the state file or, without a record, backends with address and port
+func deregister(ctx context.Context, client vaas.Client, config CommonConfig) (err error) {
+	ctx, span := startBackendSpan(ctx, "deregister", config)
+	defer func() { span.End(err) }()
 
-	backendID, err := apiClient.FindBackendID(config.Director, config.Address, config.Port)
-	if err != nil {
-		return fmt.Errorf("could not determine backend ID: %s", err)
+	if err := protectLastBackend(ctx, client, config, atEndpointOf(config)); err != nil {
+		return err
 	}
-	log.Infof("Deregistering backend %d from director %s", backendID, config.Director)
-	if err := apiClient.DeleteBackend(backendID); err != nil {
-		return fmt.Errorf("could not deregister: %s", err)
+	if found, err := deregisterFromState(ctx, client, config); found {
+		return err
 	}
+	return deregisterByAddress(ctx, client, config)
+}
 
+// deregisterByAddress removes backends with address and port from config, so no stored backend ID is needed
+func deregisterByAddress(ctx context.Context, client vaas.Client, config CommonConfig) error {
+	log.WithContext(ctx).Infof("Deregistering address %q port %d from director %s", config.Address, config.Port, config.Director)
+	var err error
+	if config.AsyncTimeout > 0 {
+		err = client.DeleteBackendByAddressAndWait(ctx, config.Director, config.Address, config.Port)
+	} else {
+		err = client.DeleteBackendByAddress(ctx, config.Director, config.Address, config.Port)
+	}
+	if err != nil {
+		return fmt.Errorf("could not deregister: %w", err)
+	}
+
+	log.WithContext(ctx).Info("Successfully scheduled backend for deletion via VaaS")
+	recordBackend(ctx, backendResult(config, nil))
 	return nil
 }
 
+// deleteBackend removes a backend, waiting for VaaS to apply it if configured to
+func deleteBackend(ctx context.Context, client vaas.Client, config CommonConfig, backendID int) error {
+	if config.AsyncTimeout > 0 {
+		return client.DeleteBackendAndWait(ctx, backendID)
+	}
+	return client.DeleteBackend(ctx, backendID)
+}
+
+// protectLastBackend refuses to remove backends matched by removed when no other backend would be left in the
+// director of config, dropping all traffic of the service, unless config does not protect it or forces removal
+func protectLastBackend(ctx context.Context, client vaas.Client, config CommonConfig, removed func(vaas.Backend) bool) error {
+	if !config.ProtectLastBackend {
+		return nil
+	}
+	director, err := client.FindDirector(ctx, config.Director)
+	if err != nil {
+		return fmt.Errorf("could not count backends of director %s: %w", config.Director, err)
+	}
+	backends, err := client.ListBackends(ctx, director)
+	if err != nil {
+		return fmt.Errorf("could not count backends of director %s: %w", config.Director, err)
+	}
+
+	last := false
+	for _, backend := range backends {
+		if !removed(backend) {
+			return nil
+		}
+		last = true
+	}
+	if !last {
+		return nil
+	}
+	if config.Force {
+		log.WithContext(ctx).Warnf("Removing the last backend of director %s, forced with --%s", config.Director, FlagForce)
+		return nil
+	}
+	return fmt.Errorf("%w %s, which would drop all its traffic; pass --%s to remove it anyway",
+		ErrLastBackend, config.Director, FlagForce)
+}
+
+// withID matches the backend with id
+func withID(id int) func(vaas.Backend) bool {
+	return func(backend vaas.Backend) bool {
+		return backend.ID != nil && int(*backend.ID) == id
+	}
+}
+
+// atEndpointOf matches backends at the address and port of config
+func atEndpointOf(config CommonConfig) func(vaas.Backend) bool {
+	return func(backend vaas.Backend) bool {
+		return vaas.SameAddress(backend.Address, config.Address) && backend.Port == config.Port
+	}
+}
+
 // GetDeregisterFlags returns a list of flags available for this action
 func GetDeregisterFlags() []cli.Flag {
 	return []cli.Flag{
 		cli.IntFlag{
-			Name:  FlagBackendID,
+			Name:  flagBackendIDNames,
 			Usage: "known backend id that is to be deregistered",
 		},
 	}
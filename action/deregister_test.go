@@ -0,0 +1,57 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestDeregisterByAddressRemovesBackendWithoutID(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.1", Port: 80}, &director)
+	require.NoError(t, err)
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err = deregisterByAddress(context.Background(), client, cfg)
+
+	require.NoError(t, err)
+	require.Empty(t, client.Backends())
+}
+
+func TestDeregisterByAddressFailsWhenBackendIsMissing(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDirector("director")
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := deregisterByAddress(context.Background(), client, cfg)
+
+	require.Error(t, err)
+}
+
+func TestDeregisterProtectsLastBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.1", Port: 80}, &director)
+	require.NoError(t, err)
+	_, err = client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.2", Port: 80}, &director)
+	require.NoError(t, err)
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, ProtectLastBackend: true}
+	require.NoError(t, deregister(context.Background(), client, cfg))
+	require.Len(t, client.Backends(), 1)
+
+	cfg.Address = "127.0.0.2"
+	err = deregister(context.Background(), client, cfg)
+	require.True(t, errors.Is(err, ErrLastBackend))
+	require.Len(t, client.Backends(), 1)
+
+	cfg.Force = true
+	require.NoError(t, deregister(context.Background(), client, cfg))
+	require.Empty(t, client.Backends())
+}
@@ -0,0 +1,263 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/docker"
+)
+
+const (
+	// DockerName is the CLI name of this action
+	DockerName = "docker"
+	// FlagDockerHost address of the Docker engine API whose containers are registered
+	FlagDockerHost = "docker-host"
+	// EnvDockerHost address of the Docker engine API whose containers are registered
+	EnvDockerHost = "DOCKER_HOST"
+	// EnvDockerResyncPeriod how often all containers are listed to catch up with changes that were missed
+	EnvDockerResyncPeriod = "VAAS_DOCKER_RESYNC_PERIOD"
+)
+
+// GetDockerFlags returns a list of flags available for this action
+func GetDockerFlags() []cli.Flag {
+	flags := []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagDockerHost,
+			Usage:  "address of the Docker engine API, e.g. tcp://127.0.0.1:2375",
+			Value:  docker.DefaultHost,
+			EnvVar: EnvDockerHost,
+		},
+		cli.DurationFlag{
+			Name:   FlagResyncPeriod,
+			Usage:  "how often all containers are listed to catch up with changes that were missed",
+			Value:  defaultResyncPeriod,
+			EnvVar: EnvDockerResyncPeriod,
+		},
+	}
+	return append(flags, GetRegisterFlags()...)
+}
+
+// DockerCLI registers running containers labeled with docker.LabelDirector and deregisters them once they stop,
+// until ctx is done. Register flags are defaults of containers, which may override weight and DC. Published ports
+// are registered at the global --addr, the address of the Docker host.
+func DockerCLI(ctx context.Context, c *cli.Context) error {
+	config := getCommonParameters(c.Parent())
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return fmt.Errorf("error reading VaaS secret key: %s", err)
+	}
+
+	source, err := docker.NewClient(c.String(FlagDockerHost))
+	if err != nil {
+		return configError{err}
+	}
+
+	daemon := newDockerDaemon(newRegistry(config), source, config, func(director string) RegisterConfig {
+		return getRegisterParameters(c, director)
+	})
+	daemon.resyncPeriod = c.Duration(FlagResyncPeriod)
+	flushTracesEvery(ctx)
+	return daemon.run(ctx)
+}
+
+// dockerDaemon reconciles backends of containers in the registry
+type dockerDaemon struct {
+	registry     Registry
+	source       docker.ContainerSource
+	config       CommonConfig
+	resyncPeriod time.Duration
+	// registerConfig returns defaults of registration in director
+	registerConfig func(director string) RegisterConfig
+	// backends are registered backends by container ID
+	backends map[string]*podBackend
+}
+
+func newDockerDaemon(registry Registry, source docker.ContainerSource, config CommonConfig,
+	registerConfig func(director string) RegisterConfig) *dockerDaemon {
+	// Containers have backends of their own, so a single state file cannot record them
+	config.StateFile = ""
+	return &dockerDaemon{
+		registry:       registry,
+		source:         source,
+		config:         config,
+		resyncPeriod:   defaultResyncPeriod,
+		registerConfig: registerConfig,
+		backends:       map[string]*podBackend{},
+	}
+}
+
+// run lists containers and streams their events until ctx is done. Backends stay registered once it returns,
+// as containers keep running while the hook is restarted.
+func (d *dockerDaemon) run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		// Events are streamed from before the resync, so that none happening meanwhile is missed
+		since := time.Now()
+		err := d.resync(ctx)
+		if err == nil {
+			err = d.watch(ctx, since)
+		}
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		log.Errorf("Docker engine API failed, retrying in %s: %s", controllerRetryDelay, err)
+		select {
+		case <-time.After(controllerRetryDelay):
+		case <-ctx.Done():
+		}
+	}
+	return nil
+}
+
+// resync reconciles all running containers, deregistering backends of containers that are gone
+func (d *dockerDaemon) resync(ctx context.Context) error {
+	containers, err := d.source.ListContainers(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, container := range containers {
+		seen[container.ID] = true
+		d.reconcile(newOperation(ctx), container)
+	}
+	for id := range d.backends {
+		if !seen[id] {
+			d.deregister(newOperation(ctx), id)
+		}
+	}
+	return nil
+}
+
+// watch registers started containers and deregisters died ones until the stream ends after the resync period
+func (d *dockerDaemon) watch(ctx context.Context, since time.Time) error {
+	events, err := d.source.Events(ctx, since, time.Now().Add(d.resyncPeriod))
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	for {
+		event, err := events.Next()
+		if err != nil {
+			// The engine ends the stream at its until time, which is when containers are listed again
+			log.Debugf("Event stream ended: %s", err)
+			return nil
+		}
+
+		operation := newOperation(ctx)
+		switch event.Action {
+		case docker.EventStart:
+			containers, err := d.source.ListContainers(operation, event.Actor.ID)
+			if err != nil {
+				return err
+			}
+			for _, container := range containers {
+				d.reconcile(operation, container)
+			}
+		case docker.EventDie:
+			d.deregister(operation, event.Actor.ID)
+		}
+	}
+}
+
+// reconcile registers the backend of container when it is labeled, moving it when its endpoint changed
+func (d *dockerDaemon) reconcile(ctx context.Context, container docker.Container) {
+	logger := log.WithContext(ctx).WithField("container", container.Name())
+
+	wanted, err := d.backendOf(container)
+	if err != nil {
+		logger.Warnf("Not registering container: %s", err)
+	}
+	current := d.backends[container.ID]
+	if wanted == nil {
+		if current != nil {
+			d.deregister(ctx, container.ID)
+		}
+		return
+	}
+
+	if current != nil && sameBackend(current.config, wanted.config) {
+		if !current.failed && current.registration == wanted.registration {
+			return
+		}
+	} else if current != nil {
+		if d.deregister(ctx, container.ID); d.backends[container.ID] != nil {
+			logger.Warnf("Previous backend %s:%d is left in VaaS", current.config.Address, current.config.Port)
+		}
+	}
+
+	logger.Infof("Registering %s:%d", wanted.config.Address, wanted.config.Port)
+	err = forEachDirector(ctx, wanted.config, func(config CommonConfig) error {
+		return d.registry.Register(ctx, config, wanted.registration.apply(d.registerConfig(config.Director)))
+	})
+	if err = d.config.Availability.skipWhenUnavailable(err); err != nil {
+		logger.Errorf("Registration failed, retrying on next resync: %s", err)
+		wanted.failed = true
+	}
+	d.backends[container.ID] = wanted
+}
+
+// deregister removes the backend of the container with id, if any, keeping it to retry on next resync when that fails
+func (d *dockerDaemon) deregister(ctx context.Context, id string) {
+	backend := d.backends[id]
+	if backend == nil {
+		return
+	}
+
+	log.WithContext(ctx).Infof("Deregistering %s:%d", backend.config.Address, backend.config.Port)
+	err := forEachDirector(ctx, backend.config, func(config CommonConfig) error {
+		return d.registry.Deregister(ctx, config)
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("Deregistering %s:%d failed, retrying on next resync: %s", backend.config.Address, backend.config.Port, err)
+		return
+	}
+	delete(d.backends, id)
+}
+
+// backendOf returns the backend container should have, nil when it is not labeled with docker.LabelDirector
+func (d *dockerDaemon) backendOf(container docker.Container) (*podBackend, error) {
+	directors := container.Labels[docker.LabelDirector]
+	if directors == "" {
+		return nil, nil
+	}
+	address, port, err := container.Endpoint(d.config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	config := d.config
+	config.SetDirectors([]string{directors})
+	if err := config.ResolveDirectors(container.Values()); err != nil {
+		return nil, err
+	}
+	config.Address = address
+	config.Port = port
+
+	registration := podRegistration{
+		DC:          container.Labels[docker.LabelDC],
+		InstanceTag: fmt.Sprintf(InstanceFormat, container.Name(), port),
+	}
+	if value, ok := container.Labels[docker.LabelWeight]; ok {
+		weight, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s label: %q", docker.LabelWeight, value)
+		}
+		registration.Weight = weight
+	}
+	return &podBackend{config: config, registration: registration}, nil
+}
@@ -0,0 +1,125 @@
+package action
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/docker"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+// fakeContainerSource lists containers and then returns events, ending the stream once they run out
+type fakeContainerSource struct {
+	containers []docker.Container
+	events     []docker.Event
+}
+
+func (s *fakeContainerSource) ListContainers(ctx context.Context, id string) ([]docker.Container, error) {
+	var containers []docker.Container
+	for _, container := range s.containers {
+		if id == "" || container.ID == id {
+			containers = append(containers, container)
+		}
+	}
+	return containers, nil
+}
+
+func (s *fakeContainerSource) Events(ctx context.Context, since, until time.Time) (docker.EventStream, error) {
+	return s, nil
+}
+
+func (s *fakeContainerSource) Next() (docker.Event, error) {
+	if len(s.events) == 0 {
+		return docker.Event{}, io.EOF
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func (s *fakeContainerSource) Close() error {
+	return nil
+}
+
+func dockerTestContainer(id, ip string) docker.Container {
+	container := docker.Container{
+		ID:     id,
+		Names:  []string{"/container-" + id},
+		Labels: map[string]string{docker.LabelDirector: "director", docker.LabelPort: "8080", docker.LabelWeight: "3"},
+		Ports:  []docker.Port{{PrivatePort: 8080, Type: "tcp"}},
+	}
+	container.NetworkSettings.Networks = map[string]docker.Network{"bridge": {IPAddress: ip}}
+	return container
+}
+
+func dockerTestEvent(action, id string) docker.Event {
+	event := docker.Event{Type: "container", Action: action}
+	event.Actor.ID = id
+	return event
+}
+
+func newTestDockerDaemon(client *vaastest.Client, source docker.ContainerSource) *dockerDaemon {
+	return newDockerDaemon(newVaaSRegistry(client), source, CommonConfig{}, func(director string) RegisterConfig {
+		return RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}
+	})
+}
+
+func TestDockerDaemonRegistersLabeledContainers(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	unlabeled := dockerTestContainer("2", "172.17.0.3")
+	delete(unlabeled.Labels, docker.LabelDirector)
+	daemon := newTestDockerDaemon(client, &fakeContainerSource{containers: []docker.Container{
+		dockerTestContainer("1", "172.17.0.2"),
+		unlabeled,
+	}})
+
+	require.NoError(t, daemon.resync(context.Background()))
+
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	require.Equal(t, "172.17.0.2", backends[0].Address)
+	require.Equal(t, 8080, backends[0].Port)
+	require.Equal(t, 3, *backends[0].Weight)
+	require.Contains(t, backends[0].Tags, "instance:container-1_8080")
+}
+
+func TestDockerDaemonFollowsStartAndDieEvents(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	source := &fakeContainerSource{
+		containers: []docker.Container{dockerTestContainer("1", "172.17.0.2")},
+		events:     []docker.Event{dockerTestEvent(docker.EventStart, "2")},
+	}
+	daemon := newTestDockerDaemon(client, source)
+	require.NoError(t, daemon.resync(context.Background()))
+
+	source.containers = append(source.containers, dockerTestContainer("2", "172.17.0.3"))
+	require.NoError(t, daemon.watch(context.Background(), time.Now()))
+	require.Len(t, client.Backends(), 2)
+
+	source.events = []docker.Event{dockerTestEvent(docker.EventDie, "1"), dockerTestEvent(docker.EventDie, "2")}
+	require.NoError(t, daemon.watch(context.Background(), time.Now()))
+	require.Empty(t, client.Backends())
+	require.Empty(t, daemon.backends)
+}
+
+func TestDockerDaemonResyncDeregistersContainersGoneUnnoticed(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	source := &fakeContainerSource{containers: []docker.Container{dockerTestContainer("1", "172.17.0.2")}}
+	daemon := newTestDockerDaemon(client, source)
+	require.NoError(t, daemon.resync(context.Background()))
+
+	source.containers = nil
+	require.NoError(t, daemon.resync(context.Background()))
+
+	require.Empty(t, client.Backends())
+}
@@ -0,0 +1,87 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// EnableName is the CLI name of the action bringing a disabled backend back into rotation
+	EnableName = "enable"
+	// DisableName is the CLI name of the action taking a backend out of rotation without deleting it
+	DisableName = "disable"
+)
+
+// GetEnableFlags returns a list of flags available for the enable and disable actions
+func GetEnableFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  flagBackendIDNames,
+			Usage: "known backend id which is to be enabled or disabled",
+		},
+	}
+}
+
+// EnableCLI enables a backend in VaaS using CLI data
+func EnableCLI(ctx context.Context, c *cli.Context) error {
+	return setEnabledCLI(ctx, c, true)
+}
+
+// DisableCLI disables a backend in VaaS using CLI data, keeping it registered with its weight and ID
+func DisableCLI(ctx context.Context, c *cli.Context) error {
+	return setEnabledCLI(ctx, c, false)
+}
+
+func setEnabledCLI(ctx context.Context, c *cli.Context, enabled bool) error {
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	if backendID := c.Int(FlagBackendID); backendID != 0 {
+		return setEnabled(ctx, apiClient, config, backendID, enabled)
+	}
+
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		backendID, err := apiClient.FindBackendID(ctx, config.Director, config.Address, config.Port)
+		if err != nil {
+			return fmt.Errorf("could not determine backend ID: %s", err)
+		}
+		return setEnabled(ctx, apiClient, config, backendID, enabled)
+	})
+}
+
+// setEnabled enables or disables a backend. Disabling the last enabled backend of the director of config is
+// refused like its deregistration, when config protects it.
+func setEnabled(ctx context.Context, client vaas.Client, config CommonConfig, backendID int, enabled bool) error {
+	logger := log.WithContext(ctx).WithField(FlagBackendID, backendID)
+	if enabled {
+		if err := client.EnableBackend(ctx, backendID); err != nil {
+			return fmt.Errorf("could not enable backend: %w", err)
+		}
+		logger.Info("Backend enabled")
+		return nil
+	}
+
+	if config.Director != "" {
+		disabled := func(backend vaas.Backend) bool { return withID(backendID)(backend) || !backend.IsEnabled() }
+		if err := protectLastBackend(ctx, client, config, disabled); err != nil {
+			return err
+		}
+	}
+	if err := client.DisableBackend(ctx, backendID); err != nil {
+		return fmt.Errorf("could not disable backend: %w", err)
+	}
+	logger.Info("Backend disabled")
+	return nil
+}
@@ -0,0 +1,48 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestDisableAndEnableKeepBackendRegistered(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.1", Port: 80}, &director)
+	require.NoError(t, err)
+	backendID := int(*client.Backends()[0].ID)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+
+	require.NoError(t, setEnabled(context.Background(), client, cfg, backendID, false))
+	require.Len(t, client.Backends(), 1)
+	assert.False(t, client.Backends()[0].IsEnabled())
+
+	require.NoError(t, setEnabled(context.Background(), client, cfg, backendID, true))
+	require.Len(t, client.Backends(), 1)
+	assert.True(t, client.Backends()[0].IsEnabled())
+	assert.Equal(t, backendID, int(*client.Backends()[0].ID))
+}
+
+func TestDisableProtectsLastEnabledBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	for _, address := range []string{"127.0.0.1", "127.0.0.2"} {
+		_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: address, Port: 80}, &director)
+		require.NoError(t, err)
+	}
+	first, second := int(*client.Backends()[0].ID), int(*client.Backends()[1].ID)
+	cfg := CommonConfig{Director: "director", ProtectLastBackend: true}
+
+	require.NoError(t, setEnabled(context.Background(), client, cfg, first, false))
+	err := setEnabled(context.Background(), client, cfg, second, false)
+
+	require.True(t, errors.Is(err, ErrLastBackend), err)
+	assert.True(t, client.Backends()[1].IsEnabled())
+}
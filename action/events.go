@@ -0,0 +1,94 @@
+package action
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/events"
+)
+
+const (
+	// FlagEventWebhookURL URL registration events are POSTed to as JSON, empty to not send them
+	FlagEventWebhookURL = "event-webhook-url"
+	// EnvEventWebhookURL URL registration events are POSTed to as JSON, empty to not send them
+	EnvEventWebhookURL = "VAAS_EVENT_WEBHOOK_URL"
+	// FlagSlackWebhookURL Slack-compatible incoming webhook registration events are posted to as messages
+	FlagSlackWebhookURL = "slack-webhook-url"
+	// EnvSlackWebhookURL Slack-compatible incoming webhook registration events are posted to as messages
+	EnvSlackWebhookURL = "VAAS_SLACK_WEBHOOK_URL"
+)
+
+// EventsConfig represents registration event flag values
+type EventsConfig struct {
+	WebhookURL string
+	SlackURL   string
+}
+
+// notifier returns the notifier of webhooks given in config, or nil when none is given
+func (config EventsConfig) notifier() events.Notifier {
+	var notifiers events.Notifiers
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, events.NewWebhook(config.WebhookURL))
+	}
+	if config.SlackURL != "" {
+		notifiers = append(notifiers, events.NewSlack(config.SlackURL))
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return notifiers
+}
+
+// notifyingRegistry sends events of backends registered and deregistered by Registry, and of its failures.
+// Failing to deliver an event is only logged, so that notifications never fail registrations.
+type notifyingRegistry struct {
+	Registry
+	notifier events.Notifier
+	source   string
+}
+
+func newNotifyingRegistry(registry Registry, notifier events.Notifier) Registry {
+	source, _ := os.Hostname()
+	return notifyingRegistry{Registry: registry, notifier: notifier, source: source}
+}
+
+func (r notifyingRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	err := r.Registry.Register(ctx, config, rc)
+	r.notify(ctx, config, RegisterName, events.Registered, err)
+	return err
+}
+
+func (r notifyingRegistry) Deregister(ctx context.Context, config CommonConfig) error {
+	err := r.Registry.Deregister(ctx, config)
+	r.notify(ctx, config, DeregisterName, events.Deregistered, err)
+	return err
+}
+
+// notify sends the event of action, of type Failed when err is set. Dry runs change nothing, so send none.
+func (r notifyingRegistry) notify(ctx context.Context, config CommonConfig, action, eventType string, err error) {
+	if config.DryRun {
+		return
+	}
+	event := events.Event{
+		Type:     eventType,
+		Action:   action,
+		Director: config.Director,
+		Address:  config.Address,
+		Port:     config.Port,
+		Source:   r.source,
+		Time:     time.Now().UTC(),
+	}
+	if err != nil {
+		event.Type = events.Failed
+		event.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, events.DefaultTimeout)
+	defer cancel()
+	if notifyErr := r.notifier.Notify(ctx, event); notifyErr != nil {
+		log.WithContext(ctx).Warnf("Could not notify of %s event: %s", event.Type, notifyErr)
+	}
+}
@@ -0,0 +1,95 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/events"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+// recordingNotifier records events, failing with err
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []events.Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event events.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func TestNotifyingRegistrySendsRegisteredAndDeregisteredEvents(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	notifier := &recordingNotifier{}
+	registry := notifyingRegistry{Registry: newVaaSRegistry(client), notifier: notifier, source: "host1"}
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1"}))
+	require.NoError(t, registry.Deregister(context.Background(), config))
+
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, events.Registered, notifier.events[0].Type)
+	assert.Equal(t, RegisterName, notifier.events[0].Action)
+	assert.Equal(t, "director", notifier.events[0].Director)
+	assert.Equal(t, "127.0.0.1", notifier.events[0].Address)
+	assert.Equal(t, 80, notifier.events[0].Port)
+	assert.Equal(t, "host1", notifier.events[0].Source)
+	assert.False(t, notifier.events[0].Time.IsZero())
+	assert.Equal(t, events.Deregistered, notifier.events[1].Type)
+	assert.Equal(t, DeregisterName, notifier.events[1].Action)
+}
+
+func TestNotifyingRegistrySendsFailedEvent(t *testing.T) {
+	client := vaastest.NewClient()
+	client.FailOn("DeleteBackendByAddress", assert.AnError)
+	notifier := &recordingNotifier{}
+	registry := notifyingRegistry{Registry: newVaaSRegistry(client), notifier: notifier}
+
+	err := registry.Deregister(context.Background(), CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80})
+
+	require.True(t, errors.Is(err, assert.AnError), err)
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, events.Failed, notifier.events[0].Type)
+	assert.Equal(t, DeregisterName, notifier.events[0].Action)
+	assert.Equal(t, err.Error(), notifier.events[0].Error)
+}
+
+func TestNotifyingRegistryIgnoresNotificationFailures(t *testing.T) {
+	notifier := &recordingNotifier{err: assert.AnError}
+	registry := notifyingRegistry{Registry: noopRegistry{}, notifier: notifier}
+
+	err := registry.Register(context.Background(), CommonConfig{Director: "director"}, RegisterConfig{})
+
+	require.NoError(t, err)
+	assert.Len(t, notifier.events, 1)
+}
+
+func TestNotifyingRegistrySendsNoEventsOnDryRun(t *testing.T) {
+	notifier := &recordingNotifier{}
+	registry := notifyingRegistry{Registry: noopRegistry{}, notifier: notifier}
+
+	require.NoError(t, registry.Deregister(context.Background(), CommonConfig{Director: "director", DryRun: true}))
+
+	assert.Empty(t, notifier.events)
+}
+
+func TestNewRegistryNotifiesWebhooks(t *testing.T) {
+	registry := newRegistry(CommonConfig{Registry: RegistryConfig{Kind: RegistryNoop},
+		Events: EventsConfig{WebhookURL: "http://localhost/events", SlackURL: "http://localhost/slack"}})
+
+	notifying, ok := registry.(notifyingRegistry)
+	require.True(t, ok)
+	assert.Equal(t, noopRegistry{}, notifying.Registry)
+	assert.Len(t, notifying.notifier, 2)
+}
@@ -0,0 +1,166 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/health"
+)
+
+const (
+	// FlagHealthCheck kind of probe run before registration: tcp, http, grpc or exec, empty for none
+	FlagHealthCheck = "health-check"
+	// FlagCheckType is an alias of FlagHealthCheck
+	FlagCheckType = "check-type"
+	// FlagHealthCheckPath path requested by the http probe
+	FlagHealthCheckPath = "health-check-path"
+	// FlagHealthCheckStatus status expected from the http probe
+	FlagHealthCheckStatus = "health-check-status"
+	// FlagHealthCheckGRPCService service checked by the grpc probe, empty for the whole server
+	FlagHealthCheckGRPCService = "health-check-grpc-service"
+	// FlagHealthCheckGRPCTLS connects the grpc probe with TLS
+	FlagHealthCheckGRPCTLS = "health-check-grpc-tls"
+	// FlagHealthCheckCommand command run by the exec probe
+	FlagHealthCheckCommand = "health-check-command"
+	// FlagHealthCheckAttempts how many times the probe is run before registration fails
+	FlagHealthCheckAttempts = "health-check-attempts"
+	// FlagHealthCheckInterval delay between probes
+	FlagHealthCheckInterval = "health-check-interval"
+	// FlagHealthCheckTimeout limit of a single probe
+	FlagHealthCheckTimeout = "health-check-timeout"
+
+	// HealthCheckTCP probes the backend with a TCP connection
+	HealthCheckTCP = "tcp"
+	// HealthCheckHTTP probes the backend with an HTTP GET request
+	HealthCheckHTTP = "http"
+	// HealthCheckGRPC probes the backend with the gRPC health checking protocol
+	HealthCheckGRPC = "grpc"
+	// HealthCheckExec probes the backend with a command
+	HealthCheckExec = "exec"
+)
+
+// HealthCheckConfig represents health check flag values
+type HealthCheckConfig struct {
+	Kind        string
+	Path        string
+	Status      int
+	GRPCService string
+	GRPCTLS     bool
+	Command     string
+	Policy      health.Policy
+}
+
+// GetHealthCheckFlags returns flags configuring the probe run before registration
+func GetHealthCheckFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagHealthCheck + ", " + FlagCheckType,
+			Usage: "register only once the backend passes a tcp, http, grpc or exec probe",
+		},
+		cli.StringFlag{
+			Name:  FlagHealthCheckPath,
+			Usage: "path requested by the http probe",
+			Value: "/",
+		},
+		cli.IntFlag{
+			Name:  FlagHealthCheckStatus,
+			Usage: "status expected from the http probe",
+			Value: http.StatusOK,
+		},
+		cli.StringFlag{
+			Name:  FlagHealthCheckGRPCService,
+			Usage: "service checked by the grpc probe, the whole server when empty",
+		},
+		cli.BoolFlag{
+			Name:  FlagHealthCheckGRPCTLS,
+			Usage: "connect the grpc probe with TLS, without verifying the certificate",
+		},
+		cli.StringFlag{
+			Name:  FlagHealthCheckCommand,
+			Usage: "shell command run by the exec probe, healthy when it exits with 0",
+		},
+		cli.IntFlag{
+			Name:  FlagHealthCheckAttempts,
+			Usage: "how many times the probe is run before registration fails",
+			Value: 30,
+		},
+		cli.DurationFlag{
+			Name:  FlagHealthCheckInterval,
+			Usage: "delay between probes",
+			Value: 2 * time.Second,
+		},
+		cli.DurationFlag{
+			Name:  FlagHealthCheckTimeout,
+			Usage: "limit of a single probe",
+			Value: time.Second,
+		},
+	}
+}
+
+func getHealthCheckParameters(c *cli.Context) *HealthCheckConfig {
+	if c.String(FlagHealthCheck) == "" {
+		return nil
+	}
+	return &HealthCheckConfig{
+		Kind:        c.String(FlagHealthCheck),
+		Path:        c.String(FlagHealthCheckPath),
+		Status:      c.Int(FlagHealthCheckStatus),
+		GRPCService: c.String(FlagHealthCheckGRPCService),
+		GRPCTLS:     c.Bool(FlagHealthCheckGRPCTLS),
+		Command:     c.String(FlagHealthCheckCommand),
+		Policy: health.Policy{
+			Attempts: c.Int(FlagHealthCheckAttempts),
+			Interval: c.Duration(FlagHealthCheckInterval),
+			Timeout:  c.Duration(FlagHealthCheckTimeout),
+		},
+	}
+}
+
+// probe returns the probe of the backend at address and port of config
+func (hc *HealthCheckConfig) probe(config CommonConfig) (health.Probe, error) {
+	address := net.JoinHostPort(config.Address, strconv.Itoa(config.Port))
+	switch hc.Kind {
+	case HealthCheckTCP:
+		return health.TCP(address), nil
+	case HealthCheckHTTP:
+		return health.HTTP("http://"+address+"/"+strings.TrimPrefix(hc.Path, "/"), hc.Status), nil
+	case HealthCheckGRPC:
+		return health.GRPC(address, hc.GRPCService, hc.GRPCTLS), nil
+	case HealthCheckExec:
+		if hc.Command == "" {
+			return nil, fmt.Errorf("no --%s for %s health check", FlagHealthCheckCommand, HealthCheckExec)
+		}
+		return health.Exec(hc.Command), nil
+	}
+	return nil, fmt.Errorf("invalid --%s %q, expected %s, %s, %s or %s",
+		FlagHealthCheck, hc.Kind, HealthCheckTCP, HealthCheckHTTP, HealthCheckGRPC, HealthCheckExec)
+}
+
+// waitHealthy blocks until the backend of config passes its health check, if it has any
+func waitHealthy(ctx context.Context, config CommonConfig, hc *HealthCheckConfig) (err error) {
+	if hc == nil {
+		return nil
+	}
+	probe, err := hc.probe(config)
+	if err != nil {
+		return err
+	}
+	ctx, span := startBackendSpan(ctx, "health check", config)
+	span.SetAttribute("health.check", hc.Kind)
+	defer func() { span.End(err) }()
+
+	log.WithContext(ctx).Infof("Waiting for %s health check of address %q port %d", hc.Kind, config.Address, config.Port)
+	if err := health.Wait(ctx, probe, hc.Policy); err != nil {
+		return fmt.Errorf("backend %s:%d %s", config.Address, config.Port, err)
+	}
+	log.WithContext(ctx).Info("Backend is healthy")
+	return nil
+}
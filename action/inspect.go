@@ -0,0 +1,288 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ListName is the CLI name of this action
+	ListName = "list"
+	// ShowName is the CLI name of this action
+	ShowName = "show"
+	// SearchName is the CLI name of this action
+	SearchName = "search"
+
+	// FlagSearchAddress address of backends searched for
+	FlagSearchAddress = "address"
+	// FlagDirectorRegex regular expression matching names of directors of backends searched for
+	FlagDirectorRegex = "director-regex"
+)
+
+// GetListBackendsFlags returns a list of flags available for listing backends
+func GetListBackendsFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  FlagDirector,
+			Usage: "director whose backends are listed, can be repeated, the global --director when not given",
+		},
+	}
+}
+
+// GetShowBackendFlags returns a list of flags available for showing a backend
+func GetShowBackendFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  flagBackendIDNames,
+			Usage: "id of the backend to show",
+		},
+	}
+}
+
+// GetSearchBackendsFlags returns a list of flags available for searching backends
+func GetSearchBackendsFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagSearchAddress,
+			Usage: "address of backends",
+		},
+		cli.IntFlag{
+			Name:  FlagPort,
+			Usage: "port of backends",
+		},
+		cli.StringFlag{
+			Name:  FlagTag,
+			Usage: "tag of backends",
+		},
+		cli.StringFlag{
+			Name:  FlagDC,
+			Usage: "symbol of the DC of backends",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorRegex,
+			Usage: "regular expression matching names of directors of backends, e.g. ^shop-",
+		},
+	}
+}
+
+// ListBackendsCLI prints backends of directors given in CLI data, as a table or JSON with --output=json
+func ListBackendsCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+	if directors := c.StringSlice(FlagDirector); len(directors) > 0 {
+		config.SetDirectors(directors)
+	}
+	if config.Director == "" {
+		return configError{errors.New("no VaaS director specified")}
+	}
+
+	apiClient := newAPIClient(config)
+	var backends []vaas.Backend
+	err = forEachDirector(ctx, config, func(config CommonConfig) error {
+		director, err := apiClient.FindDirector(ctx, config.Director)
+		if err != nil {
+			return err
+		}
+		directorBackends, err := apiClient.ListBackends(ctx, director)
+		if err != nil {
+			return err
+		}
+		backends = append(backends, directorBackends...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return printBackends(c.App.Writer, config.Output, backends)
+}
+
+// ListDirectorsCLI prints every director defined in VaaS, as a table or JSON with --output=json
+func ListDirectorsCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+
+	directors, err := newAPIClient(config).ListDirectors(ctx)
+	if err != nil {
+		return err
+	}
+	return printDirectors(c.App.Writer, config.Output, directors)
+}
+
+// ListDCsCLI prints every DC, as a table or JSON with --output=json
+func ListDCsCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+
+	dcs, err := newAPIClient(config).ListDCs(ctx)
+	if err != nil {
+		return err
+	}
+	return printDCs(c.App.Writer, config.Output, dcs)
+}
+
+// SearchBackendsCLI prints backends of every director matching filters given in CLI data, as a table or JSON with
+// --output=json
+func SearchBackendsCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+	query, err := getSearchParameters(c)
+	if err != nil {
+		return err
+	}
+
+	backends, err := newAPIClient(config).SearchBackends(ctx, query)
+	if err != nil {
+		return err
+	}
+	return printBackends(c.App.Writer, config.Output, backends)
+}
+
+func getSearchParameters(c *cli.Context) (vaas.BackendQuery, error) {
+	query := vaas.BackendQuery{
+		Address: c.String(FlagSearchAddress),
+		Port:    c.Int(FlagPort),
+		Tag:     c.String(FlagTag),
+		DC:      c.String(FlagDC),
+	}
+	if pattern := c.String(FlagDirectorRegex); pattern != "" {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return query, configError{fmt.Errorf("invalid --%s %q: %s", FlagDirectorRegex, pattern, err)}
+		}
+		query.DirectorRegex = regex
+	}
+	return query, nil
+}
+
+// ShowBackendCLI prints a backend given by its id, as a table or JSON with --output=json
+func ShowBackendCLI(ctx context.Context, c *cli.Context) error {
+	backendID := c.Int(FlagBackendID)
+	if backendID == 0 {
+		return configError{fmt.Errorf("no --%s specified", FlagBackendID)}
+	}
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+
+	backend, err := newAPIClient(config).GetBackend(ctx, backendID)
+	if err != nil {
+		return err
+	}
+	return printBackend(c.App.Writer, config.Output, backend)
+}
+
+// getInspectParameters returns common values of an inspection subcommand, which needs no backend or director
+func getInspectParameters(c *cli.Context) (CommonConfig, error) {
+	config := getCommonParameters(c.Parent().Parent())
+	if err := config.ResolveDirectors(); err != nil {
+		return config, configError{err}
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return config, err
+	}
+	switch config.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		return config, configError{fmt.Errorf("invalid --%s %q, expected %s or %s", FlagOutput, config.Output, OutputText, OutputJSON)}
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return config, configError{fmt.Errorf("error reading VaaS secret key: %s", err)}
+	}
+	return config, nil
+}
+
+func printBackends(w io.Writer, output string, backends []vaas.Backend) error {
+	if output == OutputJSON {
+		return printJSON(w, backends)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tADDRESS\tPORT\tWEIGHT\tDC\tDIRECTOR\tTAGS")
+	for _, backend := range backends {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", formatID(backend.ID), backend.Address, backend.Port,
+			formatWeight(backend.Weight), backend.DC.Symbol, backend.DirectorURL, strings.Join(backend.Tags, ","))
+	}
+	return table.Flush()
+}
+
+func printDirectors(w io.Writer, output string, directors []vaas.Director) error {
+	if output == OutputJSON {
+		return printJSON(w, directors)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tNAME\tSERVICE\tMODE\tPROTOCOL\tBACKENDS\tCLUSTERS")
+	for _, director := range directors {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n", director.ID, director.Name, director.Service, director.Mode,
+			director.Protocol, len(director.BackendURLs), strings.Join(director.Clusters, ","))
+	}
+	return table.Flush()
+}
+
+func printDCs(w io.Writer, output string, dcs []vaas.DC) error {
+	if output == OutputJSON {
+		return printJSON(w, dcs)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tSYMBOL\tNAME")
+	for _, dc := range dcs {
+		fmt.Fprintf(table, "%d\t%s\t%s\n", dc.ID, dc.Symbol, dc.Name)
+	}
+	return table.Flush()
+}
+
+func printBackend(w io.Writer, output string, backend *vaas.Backend) error {
+	if output == OutputJSON {
+		return printJSON(w, backend)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "ID\t%s\n", formatID(backend.ID))
+	fmt.Fprintf(table, "ADDRESS\t%s\n", backend.Address)
+	fmt.Fprintf(table, "PORT\t%d\n", backend.Port)
+	fmt.Fprintf(table, "WEIGHT\t%s\n", formatWeight(backend.Weight))
+	fmt.Fprintf(table, "DC\t%s\n", backend.DC.Symbol)
+	fmt.Fprintf(table, "DIRECTOR\t%s\n", backend.DirectorURL)
+	fmt.Fprintf(table, "TAGS\t%s\n", strings.Join(backend.Tags, ","))
+	fmt.Fprintf(table, "TIMEOUTS\tconnect %vs, first byte %vs, between bytes %vs\n",
+		backend.ConnectTimeout, backend.FirstByteTimeout, backend.BetweenBytesTimeout)
+	fmt.Fprintf(table, "MAX CONNECTIONS\t%d\n", backend.MaxConnections)
+	fmt.Fprintf(table, "RESOURCE URI\t%s\n", backend.ResourceURI)
+	return table.Flush()
+}
+
+func printJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func formatID(id *vaas.ID) string {
+	if id == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *id)
+}
+
+func formatWeight(weight *int) string {
+	if weight == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *weight)
+}
@@ -0,0 +1,59 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestPrintBackendsAsTable(t *testing.T) {
+	id, weight := vaas.ID(7), 50
+	backends := []vaas.Backend{
+		{ID: &id, Address: "127.0.0.1", Port: 80, Weight: &weight, DC: vaas.DC{Symbol: "dc1"},
+			DirectorURL: "/api/v0.1/director/1/", Tags: []string{"web", "canary"}},
+		{Address: "127.0.0.2", Port: 8080},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printBackends(&out, OutputText, backends))
+
+	assert.Equal(t, "ID  ADDRESS    PORT  WEIGHT  DC   DIRECTOR               TAGS\n"+
+		"7   127.0.0.1  80    50      dc1  /api/v0.1/director/1/  web,canary\n"+
+		"-   127.0.0.2  8080  -                                   \n", out.String())
+}
+
+func TestPrintDirectorsAsJSON(t *testing.T) {
+	directors := []vaas.Director{{ID: 1, Name: "director", BackendURLs: []string{"/api/v0.1/backend/7/"}}}
+
+	var out bytes.Buffer
+	require.NoError(t, printDirectors(&out, OutputJSON, directors))
+
+	var printed []vaas.Director
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, directors, printed)
+}
+
+func TestPrintDCsAsTable(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printDCs(&out, OutputText, []vaas.DC{{ID: 1, Symbol: "dc1", Name: "First"}}))
+
+	assert.Equal(t, "ID  SYMBOL  NAME\n1   dc1     First\n", out.String())
+}
+
+func TestPrintBackendShowsEveryField(t *testing.T) {
+	id, weight := vaas.ID(7), 50
+	backend := &vaas.Backend{ID: &id, Address: "127.0.0.1", Port: 80, Weight: &weight, ConnectTimeout: 0.5,
+		MaxConnections: 5, ResourceURI: "/api/v0.1/backend/7/"}
+
+	var out bytes.Buffer
+	require.NoError(t, printBackend(&out, OutputText, backend))
+
+	assert.Contains(t, out.String(), "ID               7\n")
+	assert.Contains(t, out.String(), "TIMEOUTS         connect 0.5s, first byte 0s, between bytes 0s\n")
+	assert.Contains(t, out.String(), "RESOURCE URI     /api/v0.1/backend/7/\n")
+}
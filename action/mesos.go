@@ -0,0 +1,105 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/mesos"
+)
+
+// FlagPortName selects the port of a Mesos task by index or name, the port name label or the first port when empty
+const FlagPortName = "port-name"
+
+// GetMesosFlags returns flags selecting the backend of a Mesos task
+func GetMesosFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagPortName,
+			Usage: "index or name of the task port to register, the " + mesos.LabelPortName + " label or the first port when empty",
+		},
+	}
+}
+
+// GetRegisterMesosFlags returns a list of flags available for this action with Mesos data
+func GetRegisterMesosFlags() []cli.Flag {
+	return append(GetRegisterFlags(), GetMesosFlags()...)
+}
+
+// RegisterMesos configures a registry from Marathon task data and registers the backend in it
+func RegisterMesos(ctx context.Context, c *cli.Context, taskInfo *mesos.TaskInfo, config CommonConfig) error {
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	config, err := getMesosParameters(c, taskInfo, config)
+	if err != nil {
+		return err
+	}
+
+	registry := newRegistry(config)
+	return config.Availability.run(ctx, func() error {
+		return forEachDirector(ctx, config, func(config CommonConfig) error {
+			rc := getRegisterParameters(c, config.Director)
+			if weight, err := taskInfo.GetWeight(); err == nil {
+				rc.Weight = weight
+			}
+			if dc := taskInfo.GetDataCenter(); dc != "" {
+				rc.DC = dc
+			}
+			rc.Tags = append(rc.Tags, fmt.Sprintf(InstanceFormat, taskInfo.GetTaskID(), config.Port))
+			return registry.Register(ctx, config, rc)
+		})
+	})
+}
+
+// DeregisterMesos configures a registry from Marathon task data and removes the backend from it
+func DeregisterMesos(ctx context.Context, c *cli.Context, taskInfo *mesos.TaskInfo, config CommonConfig) error {
+	config, err := getMesosParameters(c, taskInfo, config)
+	if err != nil {
+		return err
+	}
+
+	registry := newRegistry(config)
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		return registry.Deregister(ctx, config)
+	})
+}
+
+// getMesosParameters sets the backend of config to the task port at the agent address.
+// The director label overrides directors of config, which default to one named after the application.
+func getMesosParameters(c *cli.Context, taskInfo *mesos.TaskInfo, config CommonConfig) (CommonConfig, error) {
+	config.Address = taskInfo.GetAddress()
+	if config.Address == "" {
+		return config, errors.New("could not resolve backend address, HOST is not set")
+	}
+	address, err := resolveAddress(context.Background(), config.Address, config.Resolution)
+	if err != nil {
+		return config, err
+	}
+	config.Address = address
+	port, err := taskInfo.GetPort(c.String(FlagPortName))
+	if err != nil {
+		return config, fmt.Errorf("could not resolve backend port: %s", err)
+	}
+	config.Port = port
+
+	if director := taskInfo.GetLabel(mesos.LabelDirector); director != "" || config.Director == "" {
+		config.SetDirectors([]string{taskInfo.GetDirector()})
+	}
+	if err := config.ResolveDirectors(taskInfo.Values()); err != nil {
+		return config, err
+	}
+
+	if err := config.readVaaSKey(); err != nil {
+		return config, fmt.Errorf("error reading VaaS secret key: %s", err)
+	}
+	return config, nil
+}
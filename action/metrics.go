@@ -0,0 +1,39 @@
+package action
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+// metricsJob is the Pushgateway job name of metrics pushed by the hook
+const metricsJob = "vaas_hook"
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+	clientMetrics   = mustNewMetrics(metricsRegistry)
+)
+
+func mustNewMetrics(registerer prometheus.Registerer) *vaas.Metrics {
+	metrics, err := vaas.NewMetrics(registerer)
+	if err != nil {
+		panic(err)
+	}
+	return metrics
+}
+
+// PushMetrics pushes metrics of VaaS requests to the Pushgateway set in config, if any
+func PushMetrics(config CommonConfig) error {
+	if config.PushGateway == "" {
+		return nil
+	}
+
+	pusher := push.New(config.PushGateway, metricsJob).Gatherer(metricsRegistry)
+	if hostname, err := os.Hostname(); err == nil {
+		pusher = pusher.Grouping("instance", hostname)
+	}
+	return pusher.Push()
+}
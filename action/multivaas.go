@@ -0,0 +1,165 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagVaaSSecondaryURL address of another VaaS endpoint, e.g. in another region, can be repeated
+	FlagVaaSSecondaryURL = "vaas-secondary-url"
+	// EnvVaaSSecondaryURLs addresses of other VaaS endpoints, separated by commas
+	EnvVaaSSecondaryURLs = "VAAS_SECONDARY_URLS"
+	// FlagVaaSMultiMode how secondary VaaS endpoints are used, MultiModeFailover or MultiModeAll
+	FlagVaaSMultiMode = "vaas-multi-mode"
+	// EnvVaaSMultiMode how secondary VaaS endpoints are used, MultiModeFailover or MultiModeAll
+	EnvVaaSMultiMode = "VAAS_MULTI_MODE"
+
+	// MultiModeFailover sends requests to secondary endpoints, in order, while the ones before are unavailable
+	MultiModeFailover = "failover"
+	// MultiModeAll registers and deregisters backends in every endpoint, each being a VaaS of its own
+	MultiModeAll = "all"
+)
+
+// MultiVaaSConfig represents flag values of VaaS endpoints used besides the one of --vaas-url
+type MultiVaaSConfig struct {
+	SecondaryURLs []string
+	Mode          string
+}
+
+func (config MultiVaaSConfig) validate() error {
+	switch config.Mode {
+	case "", MultiModeFailover, MultiModeAll:
+		return nil
+	}
+	return configError{fmt.Errorf("invalid --%s %q, expected %s or %s",
+		FlagVaaSMultiMode, config.Mode, MultiModeFailover, MultiModeAll)}
+}
+
+// fansOut tells whether backends are registered in every VaaS endpoint
+func (config MultiVaaSConfig) fansOut() bool {
+	return config.Mode == MultiModeAll && len(config.SecondaryURLs) > 0
+}
+
+// failoverOptions returns options of a client failing over to secondary endpoints, none when it fans out
+func (config MultiVaaSConfig) failoverOptions() []vaas.Option {
+	if config.Mode == MultiModeAll || len(config.SecondaryURLs) == 0 {
+		return nil
+	}
+	return []vaas.Option{vaas.WithFailoverHosts(config.SecondaryURLs...)}
+}
+
+// endpointConfigs returns config limited to each VaaS endpoint, the one of --vaas-url first. Every secondary
+// endpoint gets its own state file, named after its host, as backend IDs differ between VaaS instances.
+func (config CommonConfig) endpointConfigs() []CommonConfig {
+	configs := []CommonConfig{config}
+	configs[0].MultiVaaS = MultiVaaSConfig{}
+	for i, vaasURL := range config.MultiVaaS.SecondaryURLs {
+		endpointConfig := configs[0]
+		endpointConfig.VaaSURL = vaasURL
+		if config.StateFile != "" {
+			endpointConfig.StateFile = config.StateFile + "." + endpointName(vaasURL, i+1)
+		}
+		configs = append(configs, endpointConfig)
+	}
+	return configs
+}
+
+// endpointName returns the host of vaasURL, or its position among endpoints when it has none
+func endpointName(vaasURL string, position int) string {
+	if u, err := url.Parse(vaasURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return strconv.Itoa(position)
+}
+
+// multiRegistry registers backends in every VaaS endpoint, handling each even if another fails
+type multiRegistry struct {
+	endpoints  []string
+	registries []Registry
+}
+
+func newMultiRegistry(config CommonConfig) Registry {
+	registry := multiRegistry{}
+	for i, endpointConfig := range config.endpointConfigs() {
+		registry.endpoints = append(registry.endpoints, endpointName(endpointConfig.VaaSURL, i))
+		registry.registries = append(registry.registries, newVaaSRegistry(newAPIClient(endpointConfig)))
+	}
+	return registry
+}
+
+func (r multiRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	return r.each(ctx, config, func(registry Registry, config CommonConfig) error {
+		return registry.Register(ctx, config, rc)
+	})
+}
+
+func (r multiRegistry) Deregister(ctx context.Context, config CommonConfig) error {
+	return r.each(ctx, config, func(registry Registry, config CommonConfig) error {
+		return registry.Deregister(ctx, config)
+	})
+}
+
+// Find returns the backend of config from the first endpoint having it
+func (r multiRegistry) Find(ctx context.Context, config CommonConfig) (*vaas.Backend, error) {
+	var err error
+	for i, endpointConfig := range config.endpointConfigs() {
+		var backend *vaas.Backend
+		if backend, err = r.registries[i].Find(ctx, endpointConfig); err == nil {
+			return backend, nil
+		}
+	}
+	return nil, err
+}
+
+// each runs action in the registry of every endpoint, logging the outcome of every one. Failures are returned
+// together as an *endpointsError once all endpoints have been handled.
+func (r multiRegistry) each(ctx context.Context, config CommonConfig, action func(Registry, CommonConfig) error) error {
+	failures := &endpointsError{}
+	for i, endpointConfig := range config.endpointConfigs() {
+		logger := log.WithContext(ctx).WithField(FlagVaaSURL, r.endpoints[i])
+		if err := action(r.registries[i], endpointConfig); err != nil {
+			logger.Errorf("Failed: %s", err)
+			failures.endpoints = append(failures.endpoints, r.endpoints[i])
+			failures.errs = append(failures.errs, err)
+			continue
+		}
+		logger.Info("Succeeded")
+	}
+	if len(failures.errs) == 0 {
+		return nil
+	}
+	return failures
+}
+
+// endpointsError aggregates failures of an action in several VaaS endpoints, in their order
+type endpointsError struct {
+	endpoints []string
+	errs      []error
+}
+
+func (e *endpointsError) Error() string {
+	messages := make([]string, 0, len(e.errs))
+	for i, err := range e.errs {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.endpoints[i], err))
+	}
+	return fmt.Sprintf("%d VaaS endpoints failed: %s", len(e.errs), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the failures matches target
+func (e *endpointsError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestMultiRegistryRegistersInEveryEndpoint(t *testing.T) {
+	primary, secondary := vaastest.NewClient(), vaastest.NewClient()
+	for _, client := range []*vaastest.Client{primary, secondary} {
+		client.AddDC("dc1")
+		client.AddDirector("director")
+	}
+	registry := multiRegistry{
+		endpoints:  []string{"vaas.eu", "vaas.us"},
+		registries: []Registry{newVaaSRegistry(primary), newVaaSRegistry(secondary)},
+	}
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80,
+		MultiVaaS: MultiVaaSConfig{SecondaryURLs: []string{"https://vaas.us"}, Mode: MultiModeAll}}
+
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1"}))
+	assert.Len(t, primary.Backends(), 1)
+	assert.Len(t, secondary.Backends(), 1)
+
+	require.NoError(t, registry.Deregister(context.Background(), config))
+	assert.Empty(t, primary.Backends())
+	assert.Empty(t, secondary.Backends())
+}
+
+func TestMultiRegistryHandlesEveryEndpointDespiteFailures(t *testing.T) {
+	primary, secondary := vaastest.NewClient(), vaastest.NewClient()
+	secondary.AddDC("dc1")
+	secondary.AddDirector("director")
+	registry := multiRegistry{
+		endpoints:  []string{"vaas.eu", "vaas.us"},
+		registries: []Registry{newVaaSRegistry(primary), newVaaSRegistry(secondary)},
+	}
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80,
+		MultiVaaS: MultiVaaSConfig{SecondaryURLs: []string{"https://vaas.us"}, Mode: MultiModeAll}}
+
+	err := registry.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 VaaS endpoints failed: vaas.eu: ")
+	assert.Equal(t, ExitCodeConfig, ExitCode(err))
+	assert.Len(t, secondary.Backends(), 1)
+	backend, err := registry.Find(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, secondary.Backends()[0].ID, backend.ID)
+}
+
+func TestEndpointConfigsGetOwnStateFiles(t *testing.T) {
+	config := CommonConfig{VaaSURL: "https://vaas.eu", StateFile: "/run/vaas/state",
+		MultiVaaS: MultiVaaSConfig{SecondaryURLs: []string{"https://vaas.us:8443/"}, Mode: MultiModeAll}}
+
+	configs := config.endpointConfigs()
+
+	require.Len(t, configs, 2)
+	assert.Equal(t, "https://vaas.eu", configs[0].VaaSURL)
+	assert.Equal(t, "/run/vaas/state", configs[0].StateFile)
+	assert.Equal(t, "https://vaas.us:8443/", configs[1].VaaSURL)
+	assert.Equal(t, "/run/vaas/state.vaas.us:8443", configs[1].StateFile)
+	assert.False(t, configs[1].MultiVaaS.fansOut())
+}
+
+func TestMultiVaaSConfigValidate(t *testing.T) {
+	assert.NoError(t, MultiVaaSConfig{Mode: MultiModeAll}.validate())
+	assert.True(t, errors.Is(MultiVaaSConfig{Mode: "random"}.validate(), ErrInvalidConfig))
+	assert.Empty(t, MultiVaaSConfig{SecondaryURLs: []string{"https://vaas.us"}, Mode: MultiModeAll}.failoverOptions())
+	assert.Len(t, MultiVaaSConfig{SecondaryURLs: []string{"https://vaas.us"}}.failoverOptions(), 1)
+}
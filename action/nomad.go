@@ -0,0 +1,123 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/nomad"
+)
+
+// GetNomadFlags returns flags selecting the backend of a Nomad allocation
+func GetNomadFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagPortName,
+			Usage: "label of the allocation port to register, the " + nomad.MetaPort + " meta value or the only port when empty",
+		},
+	}
+}
+
+// GetRegisterNomadFlags returns a list of flags available for this action with Nomad data
+func GetRegisterNomadFlags() []cli.Flag {
+	return append(GetRegisterFlags(), GetNomadFlags()...)
+}
+
+// GetAgentNomadFlags returns a list of flags available for the agent with Nomad data
+func GetAgentNomadFlags() []cli.Flag {
+	return append(GetAgentFlags(), GetNomadFlags()...)
+}
+
+// RegisterNomad configures a registry from Nomad allocation data and registers the backend in it
+func RegisterNomad(ctx context.Context, c *cli.Context, allocInfo *nomad.AllocInfo, config CommonConfig) error {
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	config, err := getNomadParameters(c, allocInfo, config)
+	if err != nil {
+		return err
+	}
+
+	registry := newRegistry(config)
+	return config.Availability.run(ctx, func() error {
+		return forEachDirector(ctx, config, func(config CommonConfig) error {
+			return registry.Register(ctx, config, getNomadRegisterParameters(c, allocInfo, config))
+		})
+	})
+}
+
+// DeregisterNomad configures a registry from Nomad allocation data and removes the backend from it
+func DeregisterNomad(ctx context.Context, c *cli.Context, allocInfo *nomad.AllocInfo, config CommonConfig) error {
+	config, err := getNomadParameters(c, allocInfo, config)
+	if err != nil {
+		return err
+	}
+
+	registry := newRegistry(config)
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		return registry.Deregister(ctx, config)
+	})
+}
+
+// AgentNomad registers a backend using Nomad allocation data, ramping up its weight if configured to,
+// and drains and deregisters it once ctx is done, e.g. run as a poststart sidecar task
+func AgentNomad(ctx context.Context, c *cli.Context, allocInfo *nomad.AllocInfo, config CommonConfig) error {
+	config, err := getNomadParameters(c, allocInfo, config)
+	if err != nil {
+		return err
+	}
+	ramp, err := GetRampParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	defer superviseBySystemd(ctx)()
+	return runAgent(ctx, newAPIClient(config), config, getNomadRegisterParameters(c, allocInfo, config),
+		c.Duration(FlagDrainPeriod), ramp, GetHeartbeatParameters(c))
+}
+
+// getNomadRegisterParameters returns registration in the director of config, with weight and DC of meta values
+func getNomadRegisterParameters(c *cli.Context, allocInfo *nomad.AllocInfo, config CommonConfig) RegisterConfig {
+	rc := getRegisterParameters(c, config.Director)
+	if weight, err := allocInfo.GetWeight(); err == nil {
+		rc.Weight = weight
+	}
+	if dc := allocInfo.GetDataCenter(); dc != "" {
+		rc.DC = dc
+	}
+	rc.Tags = append(rc.Tags, fmt.Sprintf(InstanceFormat, allocInfo.GetAllocID(), config.Port))
+	return rc
+}
+
+// getNomadParameters sets the backend of config to the host port of the allocation at its address.
+// The director meta value overrides directors of config, which default to one named after the job.
+func getNomadParameters(c *cli.Context, allocInfo *nomad.AllocInfo, config CommonConfig) (CommonConfig, error) {
+	address, port, err := allocInfo.GetEndpoint(c.String(FlagPortName))
+	if err != nil {
+		return config, fmt.Errorf("could not resolve backend endpoint: %s", err)
+	}
+	config.Address = address
+	config.Port = port
+
+	if director := allocInfo.GetMeta(nomad.MetaDirector); director != "" || config.Director == "" {
+		config.SetDirectors([]string{allocInfo.GetDirector()})
+	}
+	if err := config.ResolveDirectors(allocInfo.Values()); err != nil {
+		return config, err
+	}
+
+	if err := config.readVaaSKey(); err != nil {
+		return config, fmt.Errorf("error reading VaaS secret key: %s", err)
+	}
+	return config, nil
+}
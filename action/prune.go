@@ -0,0 +1,146 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// PruneName is the CLI name of this action
+	PruneName = "prune"
+	// FlagAddressPrefix selects backends whose address starts with it, e.g. 10.1. for a subnet
+	FlagAddressPrefix = "address-prefix"
+	// FlagRegisteredBefore selects backends created in VaaS before an RFC 3339 time, or a duration ago
+	FlagRegisteredBefore = "registered-before"
+	// FlagConfirm confirms deleting selected backends, which are only listed with --dry-run
+	FlagConfirm = "confirm"
+)
+
+// GetPruneFlags returns a list of flags available for this action
+func GetPruneFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagTag,
+			Usage: "delete backends having this tag",
+		},
+		cli.StringFlag{
+			Name:  FlagAddressPrefix,
+			Usage: "delete backends whose address starts with this prefix, e.g. 10.1.",
+		},
+		cli.StringFlag{
+			Name:  FlagRegisteredBefore,
+			Usage: "delete backends created in VaaS before this RFC 3339 time, or this duration ago, e.g. 24h",
+		},
+		cli.BoolFlag{
+			Name:  FlagConfirm,
+			Usage: "delete the selected backends, which --" + FlagDryRun + " only lists",
+		},
+	}
+}
+
+// PruneCLI deletes backends of directors given in CLI data which match the filter of CLI data, printing them as
+// a table or JSON with --output=json. Backends are only listed with --dry-run, and deleted with --confirm, --yes
+// or once confirmed on the terminal.
+func PruneCLI(ctx context.Context, c *cli.Context) error {
+	filter, err := getBackendFilter(c, time.Now())
+	if err != nil {
+		return err
+	}
+	config := getCommonParameters(c.Parent())
+	if err := config.ResolveDirectors(); err != nil {
+		return configError{err}
+	}
+	if config.Director == "" {
+		return configError{errors.New("no VaaS director specified")}
+	}
+	if !config.DryRun && !c.Bool(FlagConfirm) && !config.AssumeYes {
+		if !stdinTerminal() {
+			return configError{fmt.Errorf("pass --%s to delete backends, or --%s to list them", FlagConfirm, FlagDryRun)}
+		}
+		question := fmt.Sprintf("Delete backends of %s matching the filter?", strings.Join(config.Directors, ", "))
+		if err := confirm(config, question); err != nil {
+			return err
+		}
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return configError{fmt.Errorf("error reading VaaS secret key: %s", err)}
+	}
+
+	apiClient := newAPIClient(config)
+	var pruned []vaas.Backend
+	err = forEachDirector(ctx, config, func(config CommonConfig) error {
+		backends, err := prune(ctx, apiClient, config, filter)
+		pruned = append(pruned, backends...)
+		return err
+	})
+	if printErr := printBackends(c.App.Writer, config.Output, pruned); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// prune deletes backends of the director of config matching filter, or with DryRun only returns them
+func prune(ctx context.Context, client vaas.Client, config CommonConfig, filter vaas.BackendFilter) ([]vaas.Backend, error) {
+	logger := log.WithContext(ctx).WithField(FlagDirector, config.Director)
+	if !config.DryRun {
+		deleted, err := client.DeleteBackendsWhere(ctx, config.Director, filter)
+		logger.Infof("Deleted %d backends", len(deleted))
+		return deleted, err
+	}
+
+	director, err := client.FindDirector(ctx, config.Director)
+	if err != nil {
+		return nil, err
+	}
+	backends, err := client.ListBackends(ctx, director)
+	if err != nil {
+		return nil, err
+	}
+	selected := filter.Select(backends)
+	logger.Infof("Dry run, %d backends would be deleted", len(selected))
+	return selected, nil
+}
+
+// getBackendFilter returns the filter of CLI data, measuring durations of --registered-before back from now.
+// An empty filter is rejected, as it would delete every backend.
+func getBackendFilter(c *cli.Context, now time.Time) (vaas.BackendFilter, error) {
+	filter := vaas.BackendFilter{
+		Tag:           c.String(FlagTag),
+		AddressPrefix: c.String(FlagAddressPrefix),
+	}
+	if before := c.String(FlagRegisteredBefore); before != "" {
+		registeredBefore, err := parseRegisteredBefore(before, now)
+		if err != nil {
+			return filter, configError{err}
+		}
+		filter.RegisteredBefore = registeredBefore
+	}
+	if filter.IsEmpty() {
+		return filter, configError{fmt.Errorf("no --%s, --%s or --%s specified, refusing to delete every backend",
+			FlagTag, FlagAddressPrefix, FlagRegisteredBefore)}
+	}
+	return filter, nil
+}
+
+// parseRegisteredBefore parses an RFC 3339 time, or a duration before now
+func parseRegisteredBefore(value string, now time.Time) (time.Time, error) {
+	if before, err := time.Parse(time.RFC3339, value); err == nil {
+		return before, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return time.Time{}, fmt.Errorf("invalid --%s %q, expected an RFC 3339 time or a positive duration", FlagRegisteredBefore, value)
+	}
+	return now.Add(-age), nil
+}
@@ -0,0 +1,53 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestPruneDeletesMatchingBackendsUnlessDryRun(t *testing.T) {
+	client := vaastest.NewClient()
+	dc := client.AddDC("dc1")
+	director := client.AddDirector("director")
+	for _, address := range []string{"10.1.0.1", "10.2.0.1", "10.1.0.2"} {
+		_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: address, Port: 80, DC: dc,
+			DirectorURL: director.ResourceURI}, &director)
+		require.NoError(t, err)
+	}
+	filter := vaas.BackendFilter{AddressPrefix: "10.1."}
+
+	selected, err := prune(context.Background(), client, CommonConfig{Director: "director", DryRun: true}, filter)
+	require.NoError(t, err)
+	assert.Len(t, selected, 2)
+	assert.Len(t, client.Backends(), 3)
+
+	deleted, err := prune(context.Background(), client, CommonConfig{Director: "director"}, filter)
+	require.NoError(t, err)
+	assert.Len(t, deleted, 2)
+	require.Len(t, client.Backends(), 1)
+	assert.Equal(t, "10.2.0.1", client.Backends()[0].Address)
+}
+
+func TestParseRegisteredBefore(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	before, err := parseRegisteredBefore("24h", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), before)
+
+	before, err = parseRegisteredBefore("2026-02-01T00:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), before)
+
+	_, err = parseRegisteredBefore("-1h", now)
+	assert.Error(t, err)
+	_, err = parseRegisteredBefore("yesterday", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// RampName is the CLI name of this action
+	RampName = "ramp"
+	// FlagRampSteps comma separated percentages of the weight a backend is stepped through, e.g. 1,25,50,100
+	FlagRampSteps = "ramp-steps"
+	// EnvRampSteps comma separated percentages of the weight a backend is stepped through, e.g. 1,25,50,100
+	EnvRampSteps = "VAAS_RAMP_STEPS"
+	// FlagRampDuration how long stepping the weight of a backend up to its target takes
+	FlagRampDuration = "ramp-duration"
+	// EnvRampDuration how long stepping the weight of a backend up to its target takes
+	EnvRampDuration = "VAAS_RAMP_DURATION"
+
+	defaultRampDuration = 5 * time.Minute
+)
+
+// RampConfig represents ramp-up flag values, percentages of the target weight spread evenly over Duration
+type RampConfig struct {
+	Steps    []int
+	Duration time.Duration
+}
+
+// GetRampFlags returns flags configuring the weight ramp-up of a backend
+func GetRampFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagRampSteps,
+			Usage:  "comma separated percentages of the weight the backend is stepped through, ending with 100, e.g. 1,25,50,100",
+			EnvVar: EnvRampSteps,
+		},
+		cli.DurationFlag{
+			Name:   FlagRampDuration,
+			Usage:  "how long stepping the weight of the backend up to its target takes",
+			Value:  defaultRampDuration,
+			EnvVar: EnvRampDuration,
+		},
+	}
+}
+
+// GetRampParameters returns ramp-up values given in CLI data, without steps when there is no ramp-up
+func GetRampParameters(c *cli.Context) (RampConfig, error) {
+	ramp := RampConfig{Duration: c.Duration(FlagRampDuration)}
+	steps, err := parseRampSteps(c.String(FlagRampSteps))
+	if err != nil {
+		return ramp, configError{fmt.Errorf("invalid --%s: %s", FlagRampSteps, err)}
+	}
+	ramp.Steps = steps
+	return ramp, nil
+}
+
+// parseRampSteps parses increasing percentages ending with 100, none when value is empty
+func parseRampSteps(value string) ([]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var steps []int
+	for _, field := range strings.Split(value, ",") {
+		step, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a percentage", field)
+		}
+		if step <= 0 || step > 100 || (len(steps) > 0 && step <= steps[len(steps)-1]) {
+			return nil, fmt.Errorf("steps must be increasing percentages between 1 and 100, got %s", value)
+		}
+		steps = append(steps, step)
+	}
+	if steps[len(steps)-1] != 100 {
+		return nil, fmt.Errorf("last step must be 100, got %d", steps[len(steps)-1])
+	}
+	return steps, nil
+}
+
+// enabled tells whether the weight is ramped up
+func (ramp RampConfig) enabled() bool {
+	return len(ramp.Steps) > 1
+}
+
+// weights returns weights of the steps up to target, each at least 1 so that every step gets traffic
+func (ramp RampConfig) weights(target int) []int {
+	weights := make([]int, len(ramp.Steps))
+	for i, step := range ramp.Steps {
+		weights[i] = (target*step + 99) / 100
+		if weights[i] < 1 {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// interval returns the time between steps
+func (ramp RampConfig) interval() time.Duration {
+	return ramp.Duration / time.Duration(len(ramp.Steps)-1)
+}
+
+// GetRampCLIFlags returns a list of flags available for this action
+func GetRampCLIFlags() []cli.Flag {
+	return append([]cli.Flag{
+		cli.IntFlag{
+			Name:  FlagWeight,
+			Usage: fmt.Sprintf("target weight of the backend, between %d and %d", vaas.MinWeight, vaas.MaxWeight),
+		},
+		cli.IntFlag{
+			Name:  flagBackendIDNames,
+			Usage: "known backend id whose weight is ramped up",
+		},
+	}, GetRampFlags()...)
+}
+
+// RampCLI steps up the weight of a registered backend to the weight given in CLI data
+func RampCLI(ctx context.Context, c *cli.Context) error {
+	if !c.IsSet(FlagWeight) {
+		return errors.New("no weight specified")
+	}
+	ramp, err := GetRampParameters(c)
+	if err != nil {
+		return err
+	}
+	if !ramp.enabled() {
+		return configError{fmt.Errorf("no --%s to ramp up through specified", FlagRampSteps)}
+	}
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	backendIDs := []int{c.Int(FlagBackendID)}
+	if backendIDs[0] == 0 {
+		backendIDs = nil
+		err := forEachDirector(ctx, config, func(config CommonConfig) error {
+			backendID, err := apiClient.FindBackendID(ctx, config.Director, config.Address, config.Port)
+			if err != nil {
+				return fmt.Errorf("could not determine backend ID: %s", err)
+			}
+			backendIDs = append(backendIDs, backendID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	weights := ramp.weights(c.Int(FlagWeight))
+	for _, backendID := range backendIDs {
+		if err := setWeight(ctx, apiClient, backendID, weights[0]); err != nil {
+			return err
+		}
+	}
+	return rampUp(ctx, apiClient, backendIDs, weights, ramp.interval())
+}
+
+// rampUp updates weight of backends to each of weights after the first one, every interval.
+// It stops early, with an error, once ctx is done.
+func rampUp(ctx context.Context, client vaas.Client, backendIDs []int, weights []int, interval time.Duration) error {
+	for _, weight := range weights[1:] {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		weight := weight
+		for _, backendID := range backendIDs {
+			if err := client.UpdateBackend(ctx, backendID, vaas.BackendPatch{Weight: &weight}); err != nil {
+				return fmt.Errorf("could not ramp up weight: %w", err)
+			}
+			log.WithContext(ctx).WithField(FlagBackendID, backendID).Infof("Backend weight ramped up to %d", weight)
+		}
+	}
+	return nil
+}
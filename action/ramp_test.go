@@ -0,0 +1,77 @@
+package action
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestParseRampSteps(t *testing.T) {
+	steps, err := parseRampSteps("1, 25,50,100")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 25, 50, 100}, steps)
+
+	steps, err = parseRampSteps("")
+	require.NoError(t, err)
+	assert.Nil(t, steps)
+
+	for _, value := range []string{"1,x,100", "50,25,100", "0,100", "1,50", "1,150"} {
+		_, err := parseRampSteps(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRampWeightsRoundUpToAtLeastOne(t *testing.T) {
+	ramp := RampConfig{Steps: []int{1, 25, 50, 100}, Duration: 3 * time.Minute}
+
+	assert.Equal(t, []int{1, 25, 50, 100}, ramp.weights(100))
+	assert.Equal(t, []int{1, 3, 5, 10}, ramp.weights(10))
+	assert.Equal(t, []int{1, 1, 1, 1}, ramp.weights(0))
+	assert.Equal(t, time.Minute, ramp.interval())
+}
+
+func TestAgentRampsUpWeightAfterRegistering(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	ramp := RampConfig{Steps: []int{10, 50, 100}, Duration: 2 * time.Millisecond}
+	go func() {
+		done <- runAgent(ctx, client, cfg, RegisterConfig{Weight: 40, DC: "dc1", Tags: []string{}}, 0, ramp, HeartbeatConfig{})
+	}()
+
+	require.Eventually(t, func() bool {
+		backends := client.Backends()
+		return len(backends) == 1 && *backends[0].Weight == 40
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	var updates []string
+	for _, call := range client.Calls() {
+		if call == "UpdateBackend" || call == "AddBackend" {
+			updates = append(updates, call)
+		}
+	}
+	assert.Equal(t, []string{"AddBackend", "UpdateBackend", "UpdateBackend"}, updates)
+}
+
+func TestRampUpStopsOnceContextIsDone(t *testing.T) {
+	client := vaastest.NewClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := rampUp(ctx, client, []int{1}, []int{1, 50, 100}, time.Hour)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, client.Calls())
+}
@@ -0,0 +1,380 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/address"
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/mesos"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ReconcileName is the CLI name of this action
+	ReconcileName = "reconcile"
+	// FlagGracePeriod how long a backend has to be orphaned before reconcile deletes it
+	FlagGracePeriod = "grace-period"
+	// EnvGracePeriod how long a backend has to be orphaned before reconcile deletes it
+	EnvGracePeriod = "VAAS_RECONCILE_GRACE_PERIOD"
+	// FlagReconcileState file recording when backends were first found orphaned, so that runs share the grace period
+	FlagReconcileState = "reconcile-state"
+	// EnvReconcileState file recording when backends were first found orphaned, so that runs share the grace period
+	EnvReconcileState = "VAAS_RECONCILE_STATE"
+	// FlagReconcileInterval how often reconcile runs until it is stopped, once when zero
+	FlagReconcileInterval = "reconcile-interval"
+	// EnvReconcileInterval how often reconcile runs until it is stopped, once when zero
+	EnvReconcileInterval = "VAAS_RECONCILE_INTERVAL"
+	// FlagHeartbeatTTL how long since their last agent heartbeat backends are deleted, 0 to keep them
+	FlagHeartbeatTTL = "heartbeat-ttl"
+	// EnvHeartbeatTTL how long since their last agent heartbeat backends are deleted, 0 to keep them
+	EnvHeartbeatTTL = "VAAS_RECONCILE_HEARTBEAT_TTL"
+	// EnvReconcileNamespace namespace of the Kubernetes Service whose Endpoints are live backends
+	EnvReconcileNamespace = "VAAS_RECONCILE_NAMESPACE"
+	// FlagService Kubernetes Service whose Endpoints are live backends, the director when empty
+	FlagService = "service"
+	// EnvService Kubernetes Service whose Endpoints are live backends, the director when empty
+	EnvService = "VAAS_RECONCILE_SERVICE"
+	// FlagMarathonURL Marathon API listing live tasks, e.g. http://marathon.example.com:8080
+	FlagMarathonURL = "marathon-url"
+	// EnvMarathonURL Marathon API listing live tasks, e.g. http://marathon.example.com:8080
+	EnvMarathonURL = "VAAS_MARATHON_URL"
+	// FlagAppID Marathon application whose tasks are live backends
+	FlagAppID = "app-id"
+	// EnvAppID Marathon application whose tasks are live backends
+	EnvAppID = "VAAS_MARATHON_APP_ID"
+	// FlagInventory JSON file listing live backends, in the format of the file registry
+	FlagInventory = "inventory"
+	// EnvInventory JSON file listing live backends, in the format of the file registry
+	EnvInventory = "VAAS_RECONCILE_INVENTORY"
+
+	defaultGracePeriod = 10 * time.Minute
+)
+
+// liveBackends returns backends of the director of config which exist according to a source of truth, under the
+// addresses and ports registration stores them with
+type liveBackends func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error)
+
+// ReconcileConfig represents reconcile flag values
+type ReconcileConfig struct {
+	GracePeriod time.Duration
+	StateFile   string
+	Interval    time.Duration
+	// HeartbeatTTL is how long since their last heartbeat backends with heartbeat tags are deleted, 0 to keep them
+	HeartbeatTTL time.Duration
+}
+
+// getReconcileFlags returns flags shared by reconcile subcommands, followed by extra ones
+func getReconcileFlags(extra ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		cli.DurationFlag{
+			Name:   FlagGracePeriod,
+			Usage:  "how long a backend has to be orphaned before it is deleted",
+			Value:  defaultGracePeriod,
+			EnvVar: EnvGracePeriod,
+		},
+		cli.StringFlag{
+			Name:   FlagReconcileState,
+			Usage:  "file recording when backends were first found orphaned, needed by a grace period of separate runs",
+			EnvVar: EnvReconcileState,
+		},
+		cli.DurationFlag{
+			Name:   FlagReconcileInterval,
+			Usage:  "how often to reconcile until stopped, once when zero",
+			EnvVar: EnvReconcileInterval,
+		},
+		cli.DurationFlag{
+			Name:   FlagHeartbeatTTL,
+			Usage:  "delete backends whose last agent heartbeat is older than this, even if live, 0 to keep them",
+			EnvVar: EnvHeartbeatTTL,
+		},
+	}, extra...)
+}
+
+// GetReconcileK8sFlags returns a list of flags available for reconciling with Kubernetes
+func GetReconcileK8sFlags() []cli.Flag {
+	return getReconcileFlags(
+		cli.StringFlag{
+			Name:   FlagNamespace,
+			Usage:  "namespace of the Service",
+			Value:  "default",
+			EnvVar: EnvReconcileNamespace,
+		},
+		cli.StringFlag{
+			Name:   FlagService,
+			Usage:  "Service whose Endpoints are live backends, the director when empty",
+			EnvVar: EnvService,
+		},
+	)
+}
+
+// GetReconcileMesosFlags returns a list of flags available for reconciling with Marathon
+func GetReconcileMesosFlags() []cli.Flag {
+	return getReconcileFlags(
+		cli.StringFlag{
+			Name:   FlagMarathonURL,
+			Usage:  "Marathon API listing live tasks, e.g. http://marathon.example.com:8080",
+			EnvVar: EnvMarathonURL,
+		},
+		cli.StringFlag{
+			Name:   FlagAppID,
+			Usage:  "Marathon application whose tasks are live backends, e.g. /group/app",
+			EnvVar: EnvAppID,
+		},
+	)
+}
+
+// GetReconcileFileFlags returns a list of flags available for reconciling with an inventory file
+func GetReconcileFileFlags() []cli.Flag {
+	return getReconcileFlags(
+		cli.StringFlag{
+			Name:   FlagInventory,
+			Usage:  "JSON file listing live backends as objects with director, address and port",
+			EnvVar: EnvInventory,
+		},
+	)
+}
+
+// ReconcileK8s deletes backends of directors which are not Endpoints of the Kubernetes Service
+func ReconcileK8s(ctx context.Context, c *cli.Context) error {
+	namespace, service := c.String(FlagNamespace), c.String(FlagService)
+	source, err := k8s.NewEndpointsSource()
+	if err != nil {
+		return fmt.Errorf("could not connect to Kubernetes API: %s", err)
+	}
+	return reconcileCLI(ctx, c, func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+		name := service
+		if name == "" {
+			name = config.Director
+		}
+		return serviceBackends(ctx, source, namespace, name)
+	})
+}
+
+// serviceBackends returns backends of Endpoints of service, including those of Pods registered under a hostPort
+func serviceBackends(ctx context.Context, source k8s.EndpointsSource, namespace, service string) ([]vaas.Backend, error) {
+	endpoints, err := source.ListServiceEndpoints(ctx, namespace, service)
+	if err != nil {
+		return nil, err
+	}
+	backends := make([]vaas.Backend, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		backends = append(backends, vaas.Backend{Address: vaas.NormalizeAddress(endpoint.Address), Port: endpoint.Port})
+	}
+	return backends, nil
+}
+
+// ReconcileMesos deletes backends of directors which are not tasks of the Marathon application
+func ReconcileMesos(ctx context.Context, c *cli.Context) error {
+	marathonURL, appID := c.String(FlagMarathonURL), c.String(FlagAppID)
+	if marathonURL == "" || appID == "" {
+		return configError{fmt.Errorf("--%s and --%s are required", FlagMarathonURL, FlagAppID)}
+	}
+	return reconcileCLI(ctx, c, func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+		tasks, err := mesos.ListAppTasks(ctx, marathonURL, appID)
+		if err != nil {
+			return nil, err
+		}
+		return taskBackends(ctx, tasks, config.Resolution)
+	})
+}
+
+// taskBackends returns backends of every port of tasks at the IPs their hosts resolve to, as registration stores
+// them. Local interfaces are those of the host running reconcile, so hosts are resolved through DNS instead.
+func taskBackends(ctx context.Context, tasks []mesos.Task, resolution AddressConfig) ([]vaas.Backend, error) {
+	if resolution.Source == address.SourceInterface || resolution.Source == address.SourceFirstInterface {
+		resolution.Source = address.SourceDNS
+	}
+	addresses := map[string]string{}
+	var backends []vaas.Backend
+	for _, task := range tasks {
+		resolved, ok := addresses[task.Host]
+		if !ok {
+			var err error
+			if resolved, err = resolveAddress(ctx, task.Host, resolution); err != nil {
+				return nil, err
+			}
+			addresses[task.Host] = resolved
+		}
+		for _, port := range task.Ports {
+			backends = append(backends, vaas.Backend{Address: resolved, Port: port})
+		}
+	}
+	return backends, nil
+}
+
+// ReconcileFile deletes backends of directors which are not listed by the inventory file
+func ReconcileFile(ctx context.Context, c *cli.Context) error {
+	path := c.String(FlagInventory)
+	if path == "" {
+		return configError{fmt.Errorf("no --%s specified", FlagInventory)}
+	}
+	return reconcileCLI(ctx, c, func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+		return readInventory(path, config.Director)
+	})
+}
+
+// reconcileCLI prunes directors given in CLI data with backends of live, once or every interval until ctx is done
+func reconcileCLI(ctx context.Context, c *cli.Context, live liveBackends) error {
+	config, err := getInspectParameters(c)
+	if err != nil {
+		return err
+	}
+	if config.Director == "" {
+		return configError{errors.New("no VaaS director specified")}
+	}
+	rc := ReconcileConfig{
+		GracePeriod: c.Duration(FlagGracePeriod),
+		StateFile:   c.String(FlagReconcileState),
+		Interval:    c.Duration(FlagReconcileInterval),
+
+		HeartbeatTTL: c.Duration(FlagHeartbeatTTL),
+	}
+
+	apiClient := newAPIClient(config)
+	firstSeen, err := readReconcileState(rc.StateFile)
+	if err != nil {
+		return err
+	}
+	for {
+		err := reconcile(ctx, apiClient, config, live, rc, firstSeen)
+		if rc.Interval <= 0 {
+			return err
+		}
+		if err != nil {
+			log.WithContext(ctx).Errorf("Reconcile failed: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(rc.Interval):
+		}
+	}
+}
+
+// reconcile prunes every director of config, recording when orphans were first seen in firstSeen and its state file.
+// With a heartbeat TTL, backends whose agents have gone silent for longer are deleted too.
+func reconcile(ctx context.Context, client vaas.Client, config CommonConfig, live liveBackends, rc ReconcileConfig,
+	firstSeen map[string]map[string]time.Time) error {
+	err := forEachDirector(ctx, config, func(config CommonConfig) error {
+		backends, err := live(ctx, config)
+		if err != nil {
+			return fmt.Errorf("could not list live backends: %w", err)
+		}
+		if firstSeen[config.Director] == nil {
+			firstSeen[config.Director] = map[string]time.Time{}
+		}
+		report, err := vaas.Prune(ctx, client, config.Director, backends, vaas.PruneOptions{
+			ReconcileOptions: vaas.ReconcileOptions{DryRun: config.DryRun},
+			GracePeriod:      rc.GracePeriod,
+			FirstSeen:        firstSeen[config.Director],
+		})
+		pruned := 0
+		for _, change := range report.Changes {
+			if change.Err == nil {
+				pruned++
+				result := BackendResult{Director: config.Director, Address: change.Backend.Address,
+					Port: change.Backend.Port, ResourceURI: change.Backend.ResourceURI}
+				if change.Backend.ID != nil {
+					result.BackendID = int(*change.Backend.ID)
+				}
+				recordBackend(ctx, result)
+			}
+		}
+		log.WithContext(ctx).WithField(FlagDirector, config.Director).Infof("Pruned %d orphaned backends", pruned)
+		if rc.HeartbeatTTL <= 0 {
+			return err
+		}
+
+		silent, silentErr := prune(ctx, client, config, vaas.BackendFilter{HeartbeatBefore: time.Now().Add(-rc.HeartbeatTTL)})
+		for _, backend := range silent {
+			result := BackendResult{Director: config.Director, Address: backend.Address, Port: backend.Port,
+				ResourceURI: backend.ResourceURI}
+			if backend.ID != nil {
+				result.BackendID = int(*backend.ID)
+			}
+			recordBackend(ctx, result)
+		}
+		if err == nil {
+			err = silentErr
+		}
+		return err
+	})
+	if stateErr := writeReconcileState(rc.StateFile, firstSeen); stateErr != nil {
+		log.WithContext(ctx).Warnf("Could not record orphaned backends: %s", stateErr)
+	}
+	return err
+}
+
+// readInventory returns backends of director listed in the inventory file at path, all of them for entries without director
+func readInventory(path, director string) ([]vaas.Backend, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read inventory file: %s", err)
+	}
+	var entries []fileBackend
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse inventory file %s: %s", path, err)
+	}
+
+	var backends []vaas.Backend
+	for _, entry := range entries {
+		if entry.Director == "" || entry.Director == director {
+			backends = append(backends, vaas.Backend{Address: entry.Address, Port: entry.Port})
+		}
+	}
+	return backends, nil
+}
+
+// readReconcileState returns times backends were first found orphaned by director, none without a state file
+func readReconcileState(path string) (map[string]map[string]time.Time, error) {
+	firstSeen := map[string]map[string]time.Time{}
+	if path == "" {
+		return firstSeen, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return firstSeen, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read reconcile state file: %s", err)
+	}
+	if err := json.Unmarshal(data, &firstSeen); err != nil {
+		return nil, fmt.Errorf("unable to parse reconcile state file %s: %s", path, err)
+	}
+	return firstSeen, nil
+}
+
+// writeReconcileState replaces the state file at path, if any, so that it is never left partially written
+func writeReconcileState(path string, firstSeen map[string]map[string]time.Time) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(firstSeen, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write reconcile state file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write reconcile state file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write reconcile state file: %s", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,142 @@
+package action
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/address"
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/mesos"
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestReconcileDeletesOrphansPastGracePeriod(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	for _, address := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: address, Port: 80}, &director)
+		require.NoError(t, err)
+	}
+	live := func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+		return []vaas.Backend{{Address: "10.0.0.1", Port: 80}}, nil
+	}
+	dir, err := ioutil.TempDir("", "reconcile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "reconcile.json")
+	firstSeen := map[string]map[string]time.Time{"director": {"10.0.0.2:80": time.Now().Add(-time.Hour)}}
+	config := CommonConfig{Director: "director", Directors: []string{"director"}}
+
+	err = reconcile(context.Background(), client, config, live, ReconcileConfig{GracePeriod: time.Minute, StateFile: statePath}, firstSeen)
+
+	require.NoError(t, err)
+	var addresses []string
+	for _, backend := range client.Backends() {
+		addresses = append(addresses, backend.Address)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, addresses)
+	assert.Contains(t, firstSeen["director"], "10.0.0.3:80")
+
+	recorded, err := readReconcileState(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3:80"}, keysOf(recorded["director"]))
+}
+
+func TestReconcileDeletesBackendsSilentPastHeartbeatTTL(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	tags := map[string][]string{
+		"10.0.0.1": {vaas.HeartbeatTag(time.Now())},
+		"10.0.0.2": {vaas.HeartbeatTag(time.Now().Add(-time.Hour))},
+		"10.0.0.3": nil,
+	}
+	for _, address := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: address, Port: 80, Tags: tags[address]}, &director)
+		require.NoError(t, err)
+	}
+	live := func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+		return []vaas.Backend{{Address: "10.0.0.1", Port: 80}, {Address: "10.0.0.2", Port: 80}, {Address: "10.0.0.3", Port: 80}}, nil
+	}
+	config := CommonConfig{Director: "director", Directors: []string{"director"}}
+
+	err := reconcile(context.Background(), client, config, live, ReconcileConfig{HeartbeatTTL: time.Minute},
+		map[string]map[string]time.Time{})
+
+	require.NoError(t, err)
+	var addresses []string
+	for _, backend := range client.Backends() {
+		addresses = append(addresses, backend.Address)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, addresses)
+}
+
+type fakeEndpointsSource []k8s.Endpoint
+
+func (s fakeEndpointsSource) ListServiceEndpoints(ctx context.Context, namespace, service string) ([]k8s.Endpoint, error) {
+	return s, nil
+}
+
+func TestReconcileKeepsLiveBackendsRegisteredUnderResolvedAddressesAndHostPorts(t *testing.T) {
+	newAddressResolver = func(config AddressConfig) (address.Resolver, error) {
+		return fakeResolver{"agent.example.com": "10.0.0.5"}, nil
+	}
+	defer func() { newAddressResolver = defaultAddressResolver }()
+
+	for name, live := range map[string]liveBackends{
+		"marathon": func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+			return taskBackends(ctx, []mesos.Task{{Host: "agent.example.com", Ports: []int{31000}}}, config.Resolution)
+		},
+		"kubernetes": func(ctx context.Context, config CommonConfig) ([]vaas.Backend, error) {
+			source := fakeEndpointsSource{{Address: "10.1.0.7", Port: 8080}, {Address: "10.0.0.5", Port: 31000}}
+			return serviceBackends(ctx, source, "default", config.Director)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := vaastest.NewClient()
+			director := client.AddDirector("director")
+			_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "10.0.0.5", Port: 31000}, &director)
+			require.NoError(t, err)
+			config := CommonConfig{Director: "director", Directors: []string{"director"},
+				Resolution: AddressConfig{Source: address.SourceFirstInterface}}
+
+			err = reconcile(context.Background(), client, config, live, ReconcileConfig{}, map[string]map[string]time.Time{})
+
+			require.NoError(t, err)
+			require.Len(t, client.Backends(), 1)
+			assert.Equal(t, "10.0.0.5", client.Backends()[0].Address)
+		})
+	}
+}
+
+func TestReadInventoryFiltersByDirector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inventory.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[
+		{"director": "director", "address": "10.0.0.1", "port": 80},
+		{"director": "other", "address": "10.0.0.2", "port": 80},
+		{"address": "10.0.0.3", "port": 8080}
+	]`), 0600))
+
+	backends, err := readInventory(path, "director")
+
+	require.NoError(t, err)
+	assert.Equal(t, []vaas.Backend{{Address: "10.0.0.1", Port: 80}, {Address: "10.0.0.3", Port: 8080}}, backends)
+}
+
+func keysOf(values map[string]time.Time) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
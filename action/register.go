@@ -1,6 +1,7 @@
 package action
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -22,55 +23,265 @@ const (
 	FlagDC = "dc"
 	// EnvDC Environment var containing datacenter short name as defined in VaaS
 	EnvDC = "CLOUD_DC"
+	// FlagTag represents a tag of the backend, can be repeated
+	FlagTag = "tag"
+	// FlagMergeTags keeps tags of an already registered backend, adding tags of the backend to them
+	FlagMergeTags = "merge-tags"
 	// InstanceFormat represents a backend instance tag
 	InstanceFormat = "instance:%s_%d"
+	// FlagCanaryTagName represents the tag marking canary backends
+	FlagCanaryTagName = "canary-tag"
+	// FlagTimeProfile represents the name of a VaaS time profile applied to the backend
+	FlagTimeProfile = "time-profile"
+	// FlagCreateDirector creates the director if it does not exist in VaaS
+	FlagCreateDirector = "create-director"
+	// FlagDirectorService represents the service of a created director
+	FlagDirectorService = "director-service"
+	// FlagDirectorCluster represents resource URIs of clusters of a created director
+	FlagDirectorCluster = "director-cluster"
+	// FlagDirectorMode represents the load balancing mode of a created director
+	FlagDirectorMode = "director-mode"
+	// FlagDirectorProtocol represents the protocol of a created director
+	FlagDirectorProtocol = "director-protocol"
+	// FlagDirectorRouter represents the router of a created director
+	FlagDirectorRouter = "director-router"
+	// FlagDirectorProbe represents the name of the health check probe of a created director
+	FlagDirectorProbe = "director-probe"
+	// FlagDirectorProbeURL represents the URL checked by the probe of a created director, which is created if missing
+	FlagDirectorProbeURL = "director-probe-url"
+	// FlagDirectorServiceMesh makes a created director reachable via the service mesh
+	FlagDirectorServiceMesh = "director-service-mesh"
+	// FlagDirectorServiceMeshLabel represents the service mesh label of a created director
+	FlagDirectorServiceMeshLabel = "director-service-mesh-label"
+	// FlagDirectorServiceTag represents the service tag selecting instances of a created director in the mesh
+	FlagDirectorServiceTag = "director-service-tag"
+	// FlagRecover reconciles the state file with VaaS before registration
+	FlagRecover = "recover"
+	// EnvRecover reconciles the state file with VaaS before registration
+	EnvRecover = "VAAS_RECOVER"
 
-	canaryTag = "canary"
+	defaultCanaryTag = "canary"
 )
 
+// GetRecoverFlag returns the flag reconciling the state file with VaaS before registration
+func GetRecoverFlag() cli.Flag {
+	return cli.BoolFlag{
+		Name:   FlagRecover,
+		Usage:  "reconcile the state file with VaaS before registration, removing backends left by previous runs",
+		EnvVar: EnvRecover,
+	}
+}
+
 // GetRegisterFlags returns a list of flags available for this action
 func GetRegisterFlags() []cli.Flag {
-	return []cli.Flag{
+	flags := []cli.Flag{
+		GetRecoverFlag(),
 		cli.IntFlag{
 			Name:  FlagWeight,
-			Usage: "initial weight of this backend",
+			Usage: fmt.Sprintf("initial weight of this backend, between %d and %d", vaas.MinWeight, vaas.MaxWeight),
 			Value: 1,
 		},
+		cli.StringSliceFlag{
+			Name:  FlagTag,
+			Usage: "tag of this backend, can be repeated",
+		},
+		cli.BoolFlag{
+			Name:  FlagMergeTags,
+			Usage: "when the backend is already registered, add tags to its current ones instead of replacing them",
+		},
+		cli.StringFlag{
+			Name:  FlagCanaryTagName,
+			Usage: "tag added to the backend when it is a canary",
+			Value: defaultCanaryTag,
+		},
 		cli.StringFlag{
 			Name:   FlagDC,
-			Usage:  "datacenter short name as defined in VaaS",
+			Usage:  "datacenter short name as defined in VaaS, inferred with --" + FlagDCSource + " when empty",
 			EnvVar: EnvDC,
 		},
+		cli.StringFlag{
+			Name:  FlagTimeProfile,
+			Usage: "name of a VaaS time profile applied to the backend instead of inheriting one from the director",
+		},
+		cli.BoolFlag{
+			Name:  FlagCreateDirector,
+			Usage: "create the director if it does not exist in VaaS",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorService,
+			Usage: "service of a created director, defaults to its name",
+		},
+		cli.StringSliceFlag{
+			Name:  FlagDirectorCluster,
+			Usage: "resource URI of a cluster of a created director, can be repeated",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorMode,
+			Usage: "load balancing mode of a created director",
+			Value: vaas.ModeRoundRobin,
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorProtocol,
+			Usage: "protocol of a created director",
+			Value: vaas.ProtocolHTTP,
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorRouter,
+			Usage: "router of a created director",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorProbe,
+			Usage: "name of the VaaS health check probe of a created director",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorProbeURL,
+			Usage: "URL checked by the probe of a created director, e.g. /status/ping, to create the probe if it does not exist",
+		},
+		cli.BoolFlag{
+			Name:  FlagDirectorServiceMesh,
+			Usage: "make a created director reachable via the service mesh",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorServiceMeshLabel,
+			Usage: "service mesh label of a created director, its service when empty",
+		},
+		cli.StringFlag{
+			Name:  FlagDirectorServiceTag,
+			Usage: "service tag selecting instances of a created director in the service mesh",
+		},
 	}
+	flags = append(flags, GetVerifyFlags()...)
+	return append(flags, GetHealthCheckFlags()...)
+}
+
+// RegisterConfig represents register specific values
+type RegisterConfig struct {
+	Weight int
+	DC     string
+	Tags   []string
+	// MergeTags adds Tags to tags of an already registered backend instead of replacing them
+	MergeTags bool
+	// CanaryTag is added to Tags of canary backends, defaults to "canary"
+	CanaryTag string
+	// TimeProfile is the name of a time profile applied to the backend, if set
+	TimeProfile string
+	// NewDirector is created when the director is not found in VaaS, if set
+	NewDirector *vaas.Director
+	// NewDirectorProbe is found by name, or created when missing and it has an URL, and attached to NewDirector
+	NewDirectorProbe *vaas.Probe
+	// Recover reconciles the state file with VaaS before registration
+	Recover bool
+	// HealthCheck has to pass before the backend is registered, if set
+	HealthCheck *HealthCheckConfig
+	// Verify makes registration wait until VaaS reports the added backend, if set
+	Verify *vaas.VerifyOptions
+	// WaitForReload makes registration wait until the VCL with the backend is live on all Varnish servers, if set
+	WaitForReload *vaas.ReloadOptions
 }
 
-// RegisterCLI configures a VaaS client from CLI data and runs register()
-func RegisterCLI(c *cli.Context) error {
-	config := getCommonParameters(c.Parent().Parent())
+func getRegisterParameters(c *cli.Context, director string) RegisterConfig {
+	config := RegisterConfig{
+		Weight:    c.Int(FlagWeight),
+		DC:        c.String(FlagDC),
+		Tags:      append([]string{}, c.StringSlice(FlagTag)...),
+		MergeTags: c.Bool(FlagMergeTags),
+		CanaryTag: c.String(FlagCanaryTagName),
+
+		TimeProfile: c.String(FlagTimeProfile),
+		Recover:     c.Bool(FlagRecover),
+		HealthCheck: getHealthCheckParameters(c),
+		Verify:      getVerifyParameters(c),
+
+		WaitForReload: getReloadParameters(c),
+	}
+	if c.Bool(FlagCreateDirector) {
+		service := c.String(FlagDirectorService)
+		if service == "" {
+			service = director
+		}
+		config.NewDirector = &vaas.Director{
+			Name:     director,
+			Service:  service,
+			Clusters: c.StringSlice(FlagDirectorCluster),
+			Mode:     c.String(FlagDirectorMode),
+			Protocol: c.String(FlagDirectorProtocol),
+			Router:   c.String(FlagDirectorRouter),
 
-	if config.Director == "" {
-		return errors.New("no VaaS director specified")
+			ServiceMeshLabel: c.String(FlagDirectorServiceMeshLabel),
+			ServiceTag:       c.String(FlagDirectorServiceTag),
+		}
+		if c.Bool(FlagDirectorServiceMesh) {
+			reachable := true
+			config.NewDirector.ReachableViaServiceMesh = &reachable
+		}
+		if probe := c.String(FlagDirectorProbe); probe != "" {
+			config.NewDirectorProbe = &vaas.Probe{Name: probe, URL: c.String(FlagDirectorProbeURL)}
+		}
 	}
-	err := config.GetSecretFromFile(config.VaaSKeyFile)
+	return config
+}
+
+// RegisterCLI configures a registry from CLI data and registers the backend in it
+func RegisterCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getCLIParameters(c)
 	if err != nil {
-		return fmt.Errorf("error reading VaaS secret key: %s", err)
+		return err
 	}
 
-	apiClient := vaas.NewClient(config.VaaSURL, config.VaaSUser, config.VaaSKey)
-	weight := c.Int(FlagWeight)
-	dcName := c.String(FlagDC)
+	registry := newRegistry(config)
 
-	return register(apiClient, config, weight, dcName, []string{})
+	return config.Availability.run(ctx, func() error {
+		return forEachDirector(ctx, config, func(config CommonConfig) error {
+			return registry.Register(ctx, config, getRegisterParameters(c, config.Director))
+		})
+	})
 }
 
-// RegisterK8s configures a VaaS client from K8s data and runs register()
-func RegisterK8s(podInfo *k8s.PodInfo, config CommonConfig) (err error) {
-	config.Address = podInfo.GetPodIP()
-	config.Port = podInfo.GetDefaultPort()
-	config.Canary = config.Canary || podInfo.FindAnnotation("canary")
+// GetRegisterK8sFlags returns a list of flags available for this action with K8s data
+func GetRegisterK8sFlags() []cli.Flag {
+	flags := append([]cli.Flag{GetRecoverFlag()}, GetVerifyFlags()...)
+	return append(flags, GetHealthCheckFlags()...)
+}
+
+// RegisterK8s configures a registry from K8s data and registers the backend in it
+func RegisterK8s(ctx context.Context, c *cli.Context, podInfo *k8s.PodInfo, config CommonConfig) error {
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	config, registerConfig, err := getK8sRegisterParameters(podInfo, config)
+	if err != nil {
+		return err
+	}
 
-	config.Director, err = overrideValue(config.Director, podInfo.GetDirector(), "Director")
+	registerConfig.Recover = c.Bool(FlagRecover)
+	registerConfig.HealthCheck = getHealthCheckParameters(c)
+	registerConfig.Verify = getVerifyParameters(c)
+	registerConfig.WaitForReload = getReloadParameters(c)
+	registry := newRegistry(config)
+	return config.Availability.run(ctx, func() error {
+		return forEachDirector(ctx, config, func(config CommonConfig) error {
+			return registry.Register(ctx, config, registerConfig)
+		})
+	})
+}
+
+func getK8sRegisterParameters(podInfo *k8s.PodInfo, config CommonConfig) (_ CommonConfig, _ RegisterConfig, err error) {
+	endpoint, err := podInfo.GetEndpoint()
 	if err != nil {
+		err = fmt.Errorf("could not resolve backend endpoint: %s", err)
+		return
+	}
+	config.Address = endpoint.Address
+	config.Port = endpoint.Port
+	config.Canary = config.Canary || podInfo.FindAnnotation("canary")
+
+	if err = overrideDirectors(&config, podInfo); err != nil {
 		return
 	}
 	config.VaaSURL, err = overrideValue(config.VaaSURL, podInfo.GetVaaSURL(), "VaaS URL")
@@ -82,12 +293,12 @@ func RegisterK8s(podInfo *k8s.PodInfo, config CommonConfig) (err error) {
 		return
 	}
 
-	err = config.GetSecretFromFile(config.VaaSKeyFile)
+	err = config.readVaaSKey()
 	if err != nil {
-		return fmt.Errorf("error reading VaaS secret key: %s", err)
+		err = fmt.Errorf("error reading VaaS secret key: %s", err)
+		return
 	}
 
-	apiClient := vaas.NewClient(config.VaaSURL, config.VaaSUser, config.VaaSKey)
 	weight, err := podInfo.GetWeight()
 	if err != nil {
 		log.Errorf("unusable weight %q found: %s", weight, err)
@@ -99,19 +310,37 @@ func RegisterK8s(podInfo *k8s.PodInfo, config CommonConfig) (err error) {
 	if err != nil {
 		log.Errorf("unusable DC name found %q: %s", dcName, err)
 	}
+	config.DCInference.NodeName = podInfo.GetNodeName()
 	dcName, err = overrideValue(dcName, podDC, "DC")
-	if err != nil {
+	if err != nil && !config.DCInference.enabled() {
 		return
 	}
 
-	tags := []string{
-		createInstanceTag(podInfo),
+	registerConfig := RegisterConfig{
+		Weight: weight,
+		DC:     dcName,
+		Tags: []string{
+			createInstanceTag(podInfo, config.Port),
+		},
+		TimeProfile: podInfo.GetTimeProfile(),
 	}
-	return register(apiClient, config, weight, dcName, tags)
+	return config, registerConfig, nil
 }
 
-func createInstanceTag(info *k8s.PodInfo) string {
-	return fmt.Sprintf(InstanceFormat, info.GetName(), info.GetDefaultPort())
+func createInstanceTag(info *k8s.PodInfo, port int) string {
+	return fmt.Sprintf(InstanceFormat, info.GetName(), port)
+}
+
+// overrideDirectors replaces directors of config with those of the director annotation, if it is set
+func overrideDirectors(config *CommonConfig, podInfo *k8s.PodInfo) error {
+	director, err := overrideValue(config.Director, podInfo.GetDirector(), "Director")
+	if err != nil {
+		return err
+	}
+	if director != config.Director {
+		config.SetDirectors([]string{director})
+	}
+	return config.ResolveDirectors(podInfo.Values())
 }
 
 func overrideValue(oldValue, override, name string) (string, error) {
@@ -126,19 +355,74 @@ func overrideValue(oldValue, override, name string) (string, error) {
 }
 
 // register adds a backend to VaaS
-func register(client vaas.Client, cfg CommonConfig, weight int, dcName string, tags []string) (err error) {
+func register(ctx context.Context, client vaas.Client, cfg CommonConfig, rc RegisterConfig) (err error) {
+	ctx, span := startBackendSpan(ctx, "register", cfg)
+	defer func() { span.End(err) }()
+
+	if rc.DC == "" && cfg.DCInference.enabled() {
+		if rc.DC, err = cfg.DCInference.infer(ctx); err != nil {
+			return err
+		}
+	}
+	if err := validateRegistration(cfg, rc); err != nil {
+		return err
+	}
+
+	if rc.Recover {
+		if err := recoverState(ctx, client, cfg); err != nil {
+			return fmt.Errorf("failed recovering state: %w", err)
+		}
+	}
+	if err := waitHealthy(ctx, cfg, rc.HealthCheck); err != nil {
+		return err
+	}
+
+	tags := rc.Tags
 	if cfg.Canary {
-		tags = append(tags, canaryTag)
+		tags = append(tags, canaryTagOf(rc))
 	}
 
-	dc, err := client.GetDC(dcName)
+	dc, err := validateDC(ctx, client, rc.DC)
 	if err != nil {
-		return fmt.Errorf("failed getting DC info: %s", err)
+		return err
 	}
 
-	director, err := client.FindDirector(cfg.Director)
+	cluster, err := getCluster(ctx, client, cfg.Cluster)
+	if err != nil {
+		return fmt.Errorf("failed getting cluster info: %w", err)
+	}
+	director, err := findOrCreateDirector(ctx, client, cfg.Director, withCluster(rc.NewDirector, cluster), rc.NewDirectorProbe)
+	if err != nil {
+		return fmt.Errorf("failed finding Director: %w", err)
+	}
+	if cluster != nil && !director.InCluster(cluster) {
+		return fmt.Errorf("director %s is not served by cluster %s", director.Name, cluster.Name)
+	}
+	profile, err := getTimeProfile(ctx, client, rc.TimeProfile)
 	if err != nil {
-		return fmt.Errorf("failed finding Director: %s", err)
+		return fmt.Errorf("failed getting time profile: %w", err)
+	}
+	weight := rc.Weight
+
+	existing, err := client.FindBackend(ctx, director, cfg.Address, cfg.Port)
+	if err == nil && existing.ID != nil {
+		log.WithContext(ctx).Infof("Updating address %q port %d in director %q (%d)", cfg.Address, cfg.Port, director.Name, director.ID)
+		if rc.MergeTags {
+			tags = vaas.MergeTags(existing.Tags, tags)
+		}
+		patch := vaas.BackendPatch{Weight: &weight, Tags: tags, TimeProfile: profile}
+		if err := client.UpdateBackend(ctx, int(*existing.ID), patch); err != nil {
+			return err
+		}
+		saveState(ctx, client, cfg, director, existing)
+		if err := waitForReload(ctx, client, director, "", rc); err != nil {
+			return err
+		}
+		recordBackend(ctx, backendResult(cfg, existing))
+		return nil
+	}
+	if err != nil && !errors.Is(err, vaas.ErrBackendNotFound) {
+		return fmt.Errorf("failed finding backend: %w", err)
 	}
 
 	backend := vaas.Backend{
@@ -152,12 +436,110 @@ func register(client vaas.Client, cfg CommonConfig, weight int, dcName string, t
 		Tags:               tags,
 		ResourceURI:        "",
 	}
-	log.Infof("Adding address %q port %d to director %q (%d)", cfg.Address, cfg.Port, director.Name, director.ID)
-	backendID, err := client.AddBackend(&backend, director)
+	if profile != nil {
+		backend.ApplyTimeProfile(profile)
+	}
+	log.WithContext(ctx).Infof("Adding address %q port %d to director %q (%d)", cfg.Address, cfg.Port, director.Name, director.ID)
+	var result vaas.RegistrationResult
+	if cfg.AsyncTimeout > 0 {
+		result, err = client.AddBackendAndWait(ctx, &backend, director)
+	} else {
+		result, err = client.AddBackend(ctx, &backend, director)
+	}
 
-	if err == nil {
-		log.Infof("Received VaaS backend id: %s", backendID)
+	if err != nil {
+		return err
 	}
+	log.WithContext(ctx).WithField(FlagBackendID, result.ID).Infof("Received VaaS backend %s", result.ResourceURI)
+	saveState(ctx, client, cfg, director, &backend)
+	if rc.Verify != nil {
+		if err := vaas.VerifyBackend(ctx, client, director, cfg.Address, cfg.Port, *rc.Verify); err != nil {
+			return err
+		}
+	}
+	if err := waitForReload(ctx, client, director, backend.TaskURI, rc); err != nil {
+		return err
+	}
+	recordBackend(ctx, backendResult(cfg, &backend))
+	return nil
+}
 
-	return
+// waitForReload waits until VCL of director is live on all Varnish servers when rc asks for it, reading the reload
+// status from the task at taskURI if VaaS reports it there
+func waitForReload(ctx context.Context, client vaas.Client, director *vaas.Director, taskURI string, rc RegisterConfig) error {
+	if rc.WaitForReload == nil {
+		return nil
+	}
+	return vaas.WaitForReload(ctx, client, director, taskURI, *rc.WaitForReload)
+}
+
+// findOrCreateDirector finds director by name, creating newDirector with probe, if any, if it does not exist and is set
+func findOrCreateDirector(ctx context.Context, client vaas.Client, name string, newDirector *vaas.Director,
+	probe *vaas.Probe) (*vaas.Director, error) {
+	director, err := client.FindDirector(ctx, name)
+	if newDirector == nil || !errors.Is(err, vaas.ErrDirectorNotFound) {
+		return director, err
+	}
+
+	if probe != nil {
+		found, err := findOrCreateProbe(ctx, client, probe)
+		if err != nil {
+			return nil, err
+		}
+		newDirector.Probe = found.ResourceURI
+	}
+
+	log.WithContext(ctx).Infof("Creating director %q", newDirector.Name)
+	if err := client.CreateDirector(ctx, newDirector); err != nil {
+		return nil, err
+	}
+	return newDirector, nil
+}
+
+// getCluster finds logical cluster by name, returning nil when name is empty
+func getCluster(ctx context.Context, client vaas.Client, name string) (*vaas.Cluster, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return client.GetCluster(ctx, name)
+}
+
+// withCluster returns a copy of newDirector served by cluster as well, or newDirector itself when either is nil
+func withCluster(newDirector *vaas.Director, cluster *vaas.Cluster) *vaas.Director {
+	if newDirector == nil || cluster == nil || newDirector.InCluster(cluster) {
+		return newDirector
+	}
+	director := *newDirector
+	director.Clusters = append(append([]string{}, newDirector.Clusters...), cluster.ResourceURI)
+	return &director
+}
+
+// findOrCreateProbe finds probe by name, creating it if it does not exist and has an URL
+func findOrCreateProbe(ctx context.Context, client vaas.Client, probe *vaas.Probe) (*vaas.Probe, error) {
+	found, err := client.GetProbe(ctx, probe.Name)
+	if probe.URL == "" || !errors.Is(err, vaas.ErrProbeNotFound) {
+		return found, err
+	}
+
+	log.WithContext(ctx).Infof("Creating probe %q of %s", probe.Name, probe.URL)
+	created := *probe
+	if err := client.CreateProbe(ctx, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func canaryTagOf(rc RegisterConfig) string {
+	if rc.CanaryTag == "" {
+		return defaultCanaryTag
+	}
+	return rc.CanaryTag
+}
+
+// getTimeProfile finds time profile by name, returning nil when name is empty
+func getTimeProfile(ctx context.Context, client vaas.Client, name string) (*vaas.TimeProfile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return client.GetTimeProfile(ctx, name)
 }
@@ -1,9 +1,16 @@
 package action
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/health"
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
 )
 
 func TestIfOverrides(t *testing.T) {
@@ -33,3 +40,227 @@ func TestIfOverrideErrorsOnEmpty(t *testing.T) {
 	require.Equal(t, "no value for value", err.Error())
 	require.Equal(t, "", result)
 }
+
+func TestFindOrCreateDirectorCreatesMissingDirector(t *testing.T) {
+	client := vaastest.NewClient()
+	newDirector := &vaas.Director{Name: "new", Service: "new", Mode: vaas.ModeRoundRobin}
+
+	director, err := findOrCreateDirector(context.Background(), client, "new", newDirector, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, "new", director.Name)
+	require.NotZero(t, director.ID)
+	require.Equal(t, []string{"FindDirector", "CreateDirector"}, client.Calls())
+}
+
+func TestFindOrCreateDirectorDoesNotCreateWhenDisabled(t *testing.T) {
+	client := vaastest.NewClient()
+
+	_, err := findOrCreateDirector(context.Background(), client, "new", nil, nil)
+
+	require.True(t, errors.Is(err, vaas.ErrDirectorNotFound))
+	require.Equal(t, []string{"FindDirector"}, client.Calls())
+}
+
+func TestFindOrCreateDirectorAttachesExistingProbe(t *testing.T) {
+	client := vaastest.NewClient()
+	probe := client.AddProbe(vaas.Probe{Name: "ping", URL: "/status/ping"})
+	newDirector := &vaas.Director{Name: "new", Service: "new"}
+
+	director, err := findOrCreateDirector(context.Background(), client, "new", newDirector, &vaas.Probe{Name: "ping"})
+
+	require.NoError(t, err)
+	require.Equal(t, probe.ResourceURI, director.Probe)
+	require.Equal(t, []string{"FindDirector", "GetProbe", "CreateDirector"}, client.Calls())
+}
+
+func TestFindOrCreateDirectorCreatesMissingProbe(t *testing.T) {
+	client := vaastest.NewClient()
+	newDirector := &vaas.Director{Name: "new", Service: "new"}
+
+	director, err := findOrCreateDirector(context.Background(), client, "new", newDirector,
+		&vaas.Probe{Name: "ping", URL: "/status/ping"})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, director.Probe)
+	require.Equal(t, []string{"FindDirector", "GetProbe", "CreateProbe", "CreateDirector"}, client.Calls())
+}
+
+func TestFindOrCreateDirectorFailsForMissingProbeWithoutURL(t *testing.T) {
+	client := vaastest.NewClient()
+
+	_, err := findOrCreateDirector(context.Background(), client, "new", &vaas.Director{Name: "new"}, &vaas.Probe{Name: "ping"})
+
+	require.True(t, errors.Is(err, vaas.ErrProbeNotFound), err)
+	require.Equal(t, []string{"FindDirector", "GetProbe"}, client.Calls())
+}
+
+func TestRegisterUpdatesExistingBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	dc := client.AddDC("dc1")
+	director := client.AddDirector("director")
+	weight := 1
+	_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.1", Port: 80, DC: dc, Weight: &weight}, &director)
+	require.NoError(t, err)
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, Canary: true}
+	err = register(context.Background(), client, cfg, RegisterConfig{Weight: 5, DC: "dc1", Tags: []string{}})
+
+	require.NoError(t, err)
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	require.Equal(t, 5, backends[0].GetWeight())
+	require.Equal(t, []string{"canary"}, backends[0].Tags)
+}
+
+func TestRegisterMergesTagsOfExistingBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	dc := client.AddDC("dc1")
+	director := client.AddDirector("director")
+	weight := 1
+	backend := &vaas.Backend{Address: "127.0.0.1", Port: 80, DC: dc, Weight: &weight, Tags: []string{"blue", "routed"}}
+	_, err := client.AddBackend(context.Background(), backend, &director)
+	require.NoError(t, err)
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err = register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{"routed", "green"}, MergeTags: true})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"blue", "routed", "green"}, client.Backends()[0].Tags)
+}
+
+func TestRegisterChecksClusterOfDirector(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	cluster := client.AddCluster("prod")
+	client.AddCluster("test")
+	require.NoError(t, client.CreateDirector(context.Background(), &vaas.Director{Name: "director", Clusters: []string{cluster.ResourceURI}}))
+	rc := RegisterConfig{Weight: 1, DC: "dc1"}
+
+	cfg := CommonConfig{Director: "director", Cluster: "prod", Address: "127.0.0.1", Port: 80}
+	require.NoError(t, register(context.Background(), client, cfg, rc))
+	cfg.Cluster = "test"
+	err := register(context.Background(), client, cfg, rc)
+
+	require.EqualError(t, err, "director director is not served by cluster test")
+	require.Len(t, client.Backends(), 1)
+}
+
+func TestRegisterCreatesDirectorInCluster(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	cluster := client.AddCluster("prod")
+	newDirector := &vaas.Director{Name: "director", Clusters: []string{"/api/v0.1/cluster/99/"}}
+
+	cfg := CommonConfig{Director: "director", Cluster: "prod", Address: "127.0.0.1", Port: 80}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", NewDirector: newDirector})
+
+	require.NoError(t, err)
+	directors, err := client.ListClusterDirectors(context.Background(), &cluster)
+	require.NoError(t, err)
+	require.Len(t, directors, 1)
+	require.Equal(t, []string{"/api/v0.1/cluster/99/", cluster.ResourceURI}, directors[0].Clusters)
+}
+
+func TestRegisterTagsCanaryWithCustomTag(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, Canary: true}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}, CanaryTag: "early"})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"early"}, client.Backends()[0].Tags)
+}
+
+func TestRegisterRejectsWeightOutOfRange(t *testing.T) {
+	client := vaastest.NewClient()
+
+	err := register(context.Background(), client, CommonConfig{}, RegisterConfig{Weight: vaas.MaxWeight + 1})
+
+	require.Error(t, err)
+	require.Empty(t, client.Calls())
+}
+
+func TestRegisterAppliesTimeProfile(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	client.AddTimeProfile(vaas.TimeProfile{Name: "slow", FirstByteTimeout: 60})
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}, TimeProfile: "slow"})
+
+	require.NoError(t, err)
+	require.Equal(t, vaas.Seconds(60), client.Backends()[0].FirstByteTimeout)
+}
+
+func TestRegisterWaitsForHealthCheck(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	hc := &HealthCheckConfig{Kind: HealthCheckExec, Command: "exit 1", Policy: health.Policy{Attempts: 2}}
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", HealthCheck: hc})
+
+	require.Error(t, err)
+	require.Empty(t, client.Calls())
+
+	hc.Command = "true"
+	require.NoError(t, register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", HealthCheck: hc}))
+	require.Len(t, client.Backends(), 1)
+}
+
+// disablingClient reports every backend it finds as disabled
+type disablingClient struct {
+	*vaastest.Client
+}
+
+func (c disablingClient) FindBackend(ctx context.Context, director *vaas.Director, address string, port int) (*vaas.Backend, error) {
+	backend, err := c.Client.FindBackend(ctx, director, address, port)
+	if err == nil {
+		disabled := false
+		backend.Enabled = &disabled
+	}
+	return backend, err
+}
+
+func TestRegisterVerifiesAddedBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	verify := &vaas.VerifyOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond, DirectorList: true}
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	require.NoError(t, register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Verify: verify}))
+
+	cfg.Port = 81
+	err := register(context.Background(), disablingClient{client}, cfg, RegisterConfig{Weight: 1, DC: "dc1", Verify: verify})
+	require.True(t, errors.Is(err, vaas.ErrNotVerified))
+	require.Len(t, client.Backends(), 2)
+}
+
+func TestRegisterWaitsForReloadOnAllVarnishServers(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	reload := &vaas.ReloadOptions{Interval: time.Millisecond, Timeout: time.Second}
+	client.SetReloadStatus("director", vaas.ReloadStatus{Servers: []vaas.ServerReload{
+		{Server: "varnish-1", Status: vaas.TaskSuccess},
+		{Server: "varnish-2", Status: vaas.TaskFailure, Error: "VCL compilation failed"},
+	}})
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", WaitForReload: reload})
+	require.True(t, errors.Is(err, vaas.ErrReloadFailed))
+	require.Equal(t, ExitCodeTaskFailed, ExitCode(err))
+
+	client.SetReloadStatus("director", vaas.ReloadStatus{Servers: []vaas.ServerReload{
+		{Server: "varnish-1", Status: vaas.TaskSuccess},
+		{Server: "varnish-2", Status: vaas.TaskSuccess},
+	}})
+	require.NoError(t, register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", WaitForReload: reload}))
+	require.Contains(t, client.Calls(), "GetReloadStatus")
+}
@@ -0,0 +1,307 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagRegistry where backends are registered, RegistryVaaS, RegistryNoop or RegistryFile
+	FlagRegistry = "registry"
+	// EnvRegistry where backends are registered, RegistryVaaS, RegistryNoop or RegistryFile
+	EnvRegistry = "VAAS_REGISTRY"
+	// FlagRegistryFile JSON file backends are registered in with RegistryFile
+	FlagRegistryFile = "registry-file"
+	// EnvRegistryFile JSON file backends are registered in with RegistryFile
+	EnvRegistryFile = "VAAS_REGISTRY_FILE"
+
+	// RegistryVaaS registers backends in VaaS
+	RegistryVaaS = "vaas"
+	// RegistryNoop only logs registrations, e.g. for development environments without VaaS
+	RegistryNoop = "noop"
+	// RegistryFile records backends in a local JSON file, e.g. for development environments without VaaS
+	RegistryFile = "file"
+)
+
+// Registry keeps backends of directors, VaaS being the one used in production
+type Registry interface {
+	// Register adds the backend of config to its director, or updates it if it exists
+	Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error
+	// Deregister removes the backend of config from its director
+	Deregister(ctx context.Context, config CommonConfig) error
+	// Find returns the backend of config, or an error matching vaas.ErrBackendNotFound
+	Find(ctx context.Context, config CommonConfig) (*vaas.Backend, error)
+}
+
+// RegistryConfig represents registry flag values
+type RegistryConfig struct {
+	Kind string
+	File string
+}
+
+func (config RegistryConfig) validate() error {
+	switch config.Kind {
+	case "", RegistryVaaS, RegistryNoop:
+		return nil
+	case RegistryFile:
+		if config.File == "" {
+			return configError{fmt.Errorf("--%s=%s needs --%s", FlagRegistry, RegistryFile, FlagRegistryFile)}
+		}
+		return nil
+	}
+	return configError{fmt.Errorf("invalid --%s %q, expected %s, %s or %s",
+		FlagRegistry, config.Kind, RegistryVaaS, RegistryNoop, RegistryFile)}
+}
+
+// usesVaaS tells whether backends are registered in VaaS, so that VaaS credentials are needed
+func (config RegistryConfig) usesVaaS() bool {
+	return config.Kind == "" || config.Kind == RegistryVaaS
+}
+
+// requireVaaS fails actions only VaaS supports, such as changing weights, when another registry is selected
+func (config RegistryConfig) requireVaaS() error {
+	if config.usesVaaS() {
+		return nil
+	}
+	return configError{fmt.Errorf("action needs --%s=%s, not %s", FlagRegistry, RegistryVaaS, config.Kind)}
+}
+
+// newRegistry creates the registry selected in config, talking to VaaS with a client configured from config.
+// Backends are mirrored to Consul when config gives its URL, and their events sent to webhooks config gives.
+func newRegistry(config CommonConfig) Registry {
+	var registry Registry
+	switch config.Registry.Kind {
+	case RegistryNoop:
+		registry = noopRegistry{}
+	case RegistryFile:
+		registry = newFileRegistry(config.Registry.File)
+	default:
+		if config.MultiVaaS.fansOut() {
+			registry = newMultiRegistry(config)
+			break
+		}
+		registry = newVaaSRegistry(newAPIClient(config))
+	}
+	if config.Consul.URL != "" {
+		registry = consulMirror{Registry: registry, client: config.Consul.newConsulClient()}
+	}
+	if notifier := config.Events.notifier(); notifier != nil {
+		registry = newNotifyingRegistry(registry, notifier)
+	}
+	return registry
+}
+
+// vaasRegistry registers backends in VaaS
+type vaasRegistry struct {
+	client vaas.Client
+}
+
+func newVaaSRegistry(client vaas.Client) Registry {
+	return vaasRegistry{client: client}
+}
+
+func (r vaasRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	return register(ctx, r.client, config, rc)
+}
+
+func (r vaasRegistry) Deregister(ctx context.Context, config CommonConfig) error {
+	return deregister(ctx, r.client, config)
+}
+
+func (r vaasRegistry) Find(ctx context.Context, config CommonConfig) (*vaas.Backend, error) {
+	director, err := r.client.FindDirector(ctx, config.Director)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.FindBackend(ctx, director, config.Address, config.Port)
+}
+
+// noopRegistry logs registrations without keeping backends anywhere
+type noopRegistry struct{}
+
+func (noopRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	log.WithContext(ctx).Infof("Registry %s: not adding address %q port %d to director %q with weight %d",
+		RegistryNoop, config.Address, config.Port, config.Director, rc.Weight)
+	recordBackend(ctx, backendResult(config, nil))
+	return nil
+}
+
+func (noopRegistry) Deregister(ctx context.Context, config CommonConfig) error {
+	log.WithContext(ctx).Infof("Registry %s: not removing address %q port %d from director %q",
+		RegistryNoop, config.Address, config.Port, config.Director)
+	recordBackend(ctx, backendResult(config, nil))
+	return nil
+}
+
+func (noopRegistry) Find(ctx context.Context, config CommonConfig) (*vaas.Backend, error) {
+	return nil, fmt.Errorf("%w: registry %s keeps no backends", vaas.ErrBackendNotFound, RegistryNoop)
+}
+
+// fileRegistry records backends in a JSON file, replaced as a whole on every change
+type fileRegistry struct {
+	path string
+	mu   *sync.Mutex
+}
+
+// fileBackend is a backend recorded by fileRegistry
+type fileBackend struct {
+	ID       int      `json:"id"`
+	Director string   `json:"director"`
+	Address  string   `json:"address"`
+	Port     int      `json:"port"`
+	DC       string   `json:"dc,omitempty"`
+	Weight   int      `json:"weight"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func newFileRegistry(path string) Registry {
+	return fileRegistry{path: path, mu: &sync.Mutex{}}
+}
+
+func (r fileRegistry) Register(ctx context.Context, config CommonConfig, rc RegisterConfig) error {
+	if rc.Weight != vaas.ClampWeight(rc.Weight) {
+		return configError{fmt.Errorf("weight %d out of range, must be between %d and %d", rc.Weight, vaas.MinWeight, vaas.MaxWeight)}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backends, err := r.read()
+	if err != nil {
+		return err
+	}
+	backend := fileBackend{Director: config.Director, Address: config.Address, Port: config.Port,
+		DC: rc.DC, Weight: rc.Weight, Tags: rc.Tags}
+	if i := findFileBackend(backends, config); i >= 0 {
+		backend.ID = backends[i].ID
+		backends[i] = backend
+	} else {
+		backend.ID = nextFileBackendID(backends)
+		backends = append(backends, backend)
+	}
+	if err := r.write(backends); err != nil {
+		return err
+	}
+
+	log.WithContext(ctx).Infof("Registry %s: recorded address %q port %d in director %q in %s",
+		RegistryFile, config.Address, config.Port, config.Director, r.path)
+	recordBackend(ctx, BackendResult{Director: config.Director, Address: config.Address, Port: config.Port, BackendID: backend.ID})
+	return nil
+}
+
+func (r fileRegistry) Deregister(ctx context.Context, config CommonConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backends, err := r.read()
+	if err != nil {
+		return err
+	}
+	i := findFileBackend(backends, config)
+	if i < 0 {
+		return fmt.Errorf("could not deregister: %w: no address %s port %d in director %s",
+			vaas.ErrBackendNotFound, config.Address, config.Port, config.Director)
+	}
+	removed := backends[i]
+	if err := r.write(append(backends[:i], backends[i+1:]...)); err != nil {
+		return err
+	}
+
+	log.WithContext(ctx).Infof("Registry %s: removed address %q port %d from director %q in %s",
+		RegistryFile, config.Address, config.Port, config.Director, r.path)
+	recordBackend(ctx, BackendResult{Director: config.Director, Address: config.Address, Port: config.Port, BackendID: removed.ID})
+	return nil
+}
+
+func (r fileRegistry) Find(ctx context.Context, config CommonConfig) (*vaas.Backend, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backends, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	i := findFileBackend(backends, config)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no address %s port %d in director %s",
+			vaas.ErrBackendNotFound, config.Address, config.Port, config.Director)
+	}
+	id, weight := vaas.ID(backends[i].ID), backends[i].Weight
+	return &vaas.Backend{
+		ID:      &id,
+		Address: backends[i].Address,
+		DC:      vaas.DC{Symbol: backends[i].DC},
+		Port:    backends[i].Port,
+		Weight:  &weight,
+		Tags:    backends[i].Tags,
+	}, nil
+}
+
+// read returns backends recorded in the file, none when it does not exist yet
+func (r fileRegistry) read() ([]fileBackend, error) {
+	data, err := ioutil.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read registry file: %s", err)
+	}
+
+	var backends []fileBackend
+	if err := json.Unmarshal(data, &backends); err != nil {
+		return nil, fmt.Errorf("unable to parse registry file %s: %s", r.path, err)
+	}
+	return backends, nil
+}
+
+// write replaces the file with backends, so that it is never left partially written
+func (r fileRegistry) write(backends []fileBackend) error {
+	if backends == nil {
+		backends = []fileBackend{}
+	}
+	data, err := json.MarshalIndent(backends, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write registry file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write registry file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write registry file: %s", err)
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+func findFileBackend(backends []fileBackend, config CommonConfig) int {
+	for i, backend := range backends {
+		if backend.Director == config.Director && backend.Address == config.Address && backend.Port == config.Port {
+			return i
+		}
+	}
+	return -1
+}
+
+func nextFileBackendID(backends []fileBackend) int {
+	id := 0
+	for _, backend := range backends {
+		if backend.ID > id {
+			id = backend.ID
+		}
+	}
+	return id + 1
+}
@@ -0,0 +1,78 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestVaaSRegistryRegistersInVaaS(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	registry := newVaaSRegistry(client)
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1"}))
+	backend, err := registry.Find(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, client.Backends()[0].ID, backend.ID)
+
+	require.NoError(t, registry.Deregister(context.Background(), config))
+	assert.Empty(t, client.Backends())
+}
+
+func TestFileRegistryRecordsBackends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registry.json")
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	other := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 81}
+
+	registry := newFileRegistry(path)
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 1, DC: "dc1"}))
+	require.NoError(t, registry.Register(context.Background(), other, RegisterConfig{Weight: 1}))
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 5, Tags: []string{"canary"}}))
+
+	backend, err := newFileRegistry(path).Find(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, vaas.ID(1), *backend.ID)
+	assert.Equal(t, 5, *backend.Weight)
+	assert.Equal(t, []string{"canary"}, backend.Tags)
+
+	require.NoError(t, registry.Deregister(context.Background(), config))
+	_, err = registry.Find(context.Background(), config)
+	assert.True(t, errors.Is(err, vaas.ErrBackendNotFound), err)
+	assert.True(t, errors.Is(registry.Deregister(context.Background(), config), vaas.ErrBackendNotFound))
+	_, err = registry.Find(context.Background(), other)
+	assert.NoError(t, err)
+}
+
+func TestNoopRegistryKeepsNothing(t *testing.T) {
+	registry := newRegistry(CommonConfig{Registry: RegistryConfig{Kind: RegistryNoop}})
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+
+	require.NoError(t, registry.Register(context.Background(), config, RegisterConfig{Weight: 1}))
+	require.NoError(t, registry.Deregister(context.Background(), config))
+	_, err := registry.Find(context.Background(), config)
+	assert.True(t, errors.Is(err, vaas.ErrBackendNotFound), err)
+}
+
+func TestRegistryConfigValidate(t *testing.T) {
+	assert.NoError(t, RegistryConfig{}.validate())
+	assert.NoError(t, RegistryConfig{Kind: RegistryFile, File: "registry.json"}.validate())
+	assert.True(t, errors.Is(RegistryConfig{Kind: RegistryFile}.validate(), ErrInvalidConfig))
+	assert.True(t, errors.Is(RegistryConfig{Kind: "consul"}.validate(), ErrInvalidConfig))
+	assert.True(t, errors.Is(RegistryConfig{Kind: RegistryNoop}.requireVaaS(), ErrInvalidConfig))
+	assert.NoError(t, RegistryConfig{}.requireVaaS())
+}
@@ -0,0 +1,171 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagOutput format of the result printed to stdout, OutputText or OutputJSON
+	FlagOutput = "output"
+	// EnvOutput format of the result printed to stdout, OutputText or OutputJSON
+	EnvOutput = "VAAS_OUTPUT"
+
+	// OutputText prints no result, leaving the outcome to logs and the exit code
+	OutputText = "text"
+	// OutputJSON prints the result as a JSON object
+	OutputJSON = "json"
+)
+
+// Exit codes of the hook, telling wrapper scripts why an action failed
+const (
+	// ExitCodeError is the exit code of failures not covered by other codes
+	ExitCodeError = 1
+	// ExitCodeConfig is the exit code of invalid flag values
+	ExitCodeConfig = 2
+	// ExitCodeAuth is the exit code of VaaS rejecting the credentials
+	ExitCodeAuth = 3
+	// ExitCodeDirectorNotFound is the exit code of a missing VaaS director
+	ExitCodeDirectorNotFound = 4
+	// ExitCodeConflict is the exit code of VaaS rejecting a duplicate backend
+	ExitCodeConflict = 5
+	// ExitCodeUnavailable is the exit code of VaaS being unreachable or failing
+	ExitCodeUnavailable = 6
+	// ExitCodeTaskFailed is the exit code of a failed asynchronous VaaS task or VCL reload
+	ExitCodeTaskFailed = 7
+	// ExitCodeLastBackend is the exit code of refusing to deregister the last backend of a director
+	ExitCodeLastBackend = 8
+	// ExitCodeMaintenance is the exit code of the hook failing because VaaS is in maintenance, EX_TEMPFAIL
+	ExitCodeMaintenance = 75
+)
+
+// ErrInvalidConfig matches errors of invalid flag values
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// configError marks err as caused by invalid flag values, keeping its message
+type configError struct {
+	err error
+}
+
+func (e configError) Error() string        { return e.err.Error() }
+func (e configError) Unwrap() error        { return e.err }
+func (e configError) Is(target error) bool { return target == ErrInvalidConfig }
+
+// ExitCode returns the process exit code for an action failing with err
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, vaas.ErrMaintenance):
+		return ExitCodeMaintenance
+	case errors.Is(err, ErrInvalidConfig):
+		return ExitCodeConfig
+	case errors.Is(err, vaas.ErrUnauthorized):
+		return ExitCodeAuth
+	case errors.Is(err, vaas.ErrDirectorNotFound):
+		return ExitCodeDirectorNotFound
+	case errors.Is(err, vaas.ErrConflict):
+		return ExitCodeConflict
+	case errors.Is(err, vaas.ErrTaskFailed), errors.Is(err, vaas.ErrReloadFailed):
+		return ExitCodeTaskFailed
+	case errors.Is(err, ErrLastBackend):
+		return ExitCodeLastBackend
+	case unavailable(err):
+		return ExitCodeUnavailable
+	}
+	return ExitCodeError
+}
+
+// unavailable tells whether err is caused by VaaS not answering or failing to serve the request
+func unavailable(err error) bool {
+	var apiError *vaas.APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode >= http.StatusInternalServerError
+	}
+	var urlError *url.Error
+	return errors.Is(err, vaas.ErrCircuitOpen) || errors.As(err, &urlError)
+}
+
+// Result is the outcome of an action, printed with --output=json
+type Result struct {
+	Action   string          `json:"action"`
+	Backends []BackendResult `json:"backends"`
+	Duration float64         `json:"duration_seconds"`
+	Error    string          `json:"error,omitempty"`
+	ExitCode int             `json:"exit_code"`
+
+	mu sync.Mutex
+}
+
+// BackendResult describes a backend an action registered or deregistered
+type BackendResult struct {
+	Director    string `json:"director"`
+	Address     string `json:"address"`
+	Port        int    `json:"port"`
+	BackendID   int    `json:"backend_id,omitempty"`
+	ResourceURI string `json:"resource_uri,omitempty"`
+	TaskURI     string `json:"task_uri,omitempty"`
+}
+
+type resultKey struct{}
+
+// recordBackend adds backend to the result of the action running with ctx, if any
+func recordBackend(ctx context.Context, backend BackendResult) {
+	result, ok := ctx.Value(resultKey{}).(*Result)
+	if !ok {
+		return
+	}
+	result.mu.Lock()
+	defer result.mu.Unlock()
+	result.Backends = append(result.Backends, backend)
+}
+
+// backendResult describes backend of config in VaaS
+func backendResult(config CommonConfig, backend *vaas.Backend) BackendResult {
+	result := BackendResult{Director: config.Director, Address: config.Address, Port: config.Port}
+	if backend != nil {
+		if backend.ID != nil {
+			result.BackendID = int(*backend.ID)
+		}
+		result.ResourceURI = backend.ResourceURI
+		result.TaskURI = backend.TaskURI
+	}
+	return result
+}
+
+// RunWithResult runs action called name, printing its result to w in given output format.
+// The error of action is returned unchanged.
+func RunWithResult(ctx context.Context, w io.Writer, output, name string, action func(context.Context) error) error {
+	switch output {
+	case "", OutputText:
+		return action(ctx)
+	case OutputJSON:
+	default:
+		return configError{fmt.Errorf("invalid --%s %q, expected %s or %s", FlagOutput, output, OutputText, OutputJSON)}
+	}
+
+	result := &Result{Action: name, Backends: []BackendResult{}}
+	start := time.Now()
+	err := action(context.WithValue(ctx, resultKey{}, result))
+
+	result.mu.Lock()
+	defer result.mu.Unlock()
+	result.Duration = time.Since(start).Seconds()
+	result.ExitCode = ExitCode(err)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if encodeErr := json.NewEncoder(w).Encode(result); encodeErr != nil && err == nil {
+		return encodeErr
+	}
+	return err
+}
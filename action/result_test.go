@@ -0,0 +1,90 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), ExitCodeError},
+		{configError{errors.New("no VaaS director specified")}, ExitCodeConfig},
+		{&vaas.APIError{StatusCode: http.StatusUnauthorized}, ExitCodeAuth},
+		{fmt.Errorf("failed finding Director: %w", vaas.ErrDirectorNotFound), ExitCodeDirectorNotFound},
+		{&vaas.APIError{StatusCode: http.StatusConflict}, ExitCodeConflict},
+		{&vaas.APIError{StatusCode: http.StatusBadGateway}, ExitCodeUnavailable},
+		{&url.Error{Op: "Get", URL: "http://vaas", Err: errors.New("connection refused")}, ExitCodeUnavailable},
+		{vaas.ErrCircuitOpen, ExitCodeUnavailable},
+		{fmt.Errorf("%w: task info", vaas.ErrTaskFailed), ExitCodeTaskFailed},
+		{fmt.Errorf("%w director", ErrLastBackend), ExitCodeLastBackend},
+		{&vaas.APIError{StatusCode: http.StatusServiceUnavailable, Maintenance: true}, ExitCodeMaintenance},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ExitCode(tt.err), "%v", tt.err)
+	}
+}
+
+func TestRunWithResultPrintsRegisteredBackends(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	config := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, AsyncTimeout: 1}
+	var out bytes.Buffer
+
+	err := RunWithResult(context.Background(), &out, OutputJSON, RegisterName, func(ctx context.Context) error {
+		return register(ctx, client, config, RegisterConfig{Weight: 1, DC: "dc1"})
+	})
+
+	require.NoError(t, err)
+	var result Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, RegisterName, result.Action)
+	assert.Equal(t, 0, result.ExitCode)
+	require.Len(t, result.Backends, 1)
+	backend := client.Backends()[0]
+	assert.Equal(t, int(*backend.ID), result.Backends[0].BackendID)
+	assert.Equal(t, backend.ResourceURI, result.Backends[0].ResourceURI)
+	assert.NotEmpty(t, result.Backends[0].TaskURI)
+	assert.Equal(t, "director", result.Backends[0].Director)
+}
+
+func TestRunWithResultPrintsFailure(t *testing.T) {
+	var out bytes.Buffer
+
+	err := RunWithResult(context.Background(), &out, OutputJSON, DeregisterName, func(ctx context.Context) error {
+		return vaas.ErrDirectorNotFound
+	})
+
+	require.Equal(t, vaas.ErrDirectorNotFound, err)
+	var result Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, ExitCodeDirectorNotFound, result.ExitCode)
+	assert.Equal(t, vaas.ErrDirectorNotFound.Error(), result.Error)
+	assert.Empty(t, result.Backends)
+}
+
+func TestRunWithResultPrintsNothingAsText(t *testing.T) {
+	var out bytes.Buffer
+
+	require.NoError(t, RunWithResult(context.Background(), &out, OutputText, RegisterName, func(context.Context) error { return nil }))
+	assert.Empty(t, out.String())
+
+	err := RunWithResult(context.Background(), &out, "yaml", RegisterName, func(context.Context) error { return nil })
+	assert.True(t, errors.Is(err, ErrInvalidConfig), err)
+}
@@ -0,0 +1,213 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// RouteName is the CLI name of this action
+	RouteName = "route"
+	// FlagRouteCondition represents the VCL condition of requests matched by a route
+	FlagRouteCondition = "condition"
+	// FlagRoutePriority represents the priority of a route, lower priorities are matched first
+	FlagRoutePriority = "priority"
+	// FlagRouteAction represents the action of a route
+	FlagRouteAction = "action"
+	// FlagRouteCluster represents the resource URI of a cluster a route applies in, can be repeated
+	FlagRouteCluster = "cluster"
+	// FlagRouteID represents a known route id
+	FlagRouteID = "route-id"
+)
+
+// DefaultRoutePriority is the priority of routes created without one
+const DefaultRoutePriority = 50
+
+// GetRouteCreateFlags returns a list of flags available for creating routes
+func GetRouteCreateFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagRouteCondition,
+			Usage: `VCL condition of requests sent to the director, e.g. 'req.url ~ "^/api"'`,
+		},
+		cli.IntFlag{
+			Name:  FlagRoutePriority,
+			Usage: "priority of the route, routes with lower priorities are matched first",
+			Value: DefaultRoutePriority,
+		},
+		cli.StringFlag{
+			Name:  FlagRouteAction,
+			Usage: fmt.Sprintf("action of the route, %s or %s", vaas.RouteActionPass, vaas.RouteActionPipe),
+			Value: vaas.RouteActionPass,
+		},
+		cli.StringSliceFlag{
+			Name:  FlagRouteCluster,
+			Usage: "resource URI of a cluster the route applies in, can be repeated",
+		},
+	}
+}
+
+// GetRouteDeleteFlags returns a list of flags available for deleting routes
+func GetRouteDeleteFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  FlagRouteID,
+			Usage: "known route id to delete",
+		},
+		cli.StringFlag{
+			Name:  FlagRouteCondition,
+			Usage: "condition of routes of the director to delete",
+		},
+	}
+}
+
+// RouteListCLI prints routes to directors given in CLI data
+func RouteListCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		return listRoutes(ctx, apiClient, config.Director, c.App.Writer)
+	})
+}
+
+// RouteCreateCLI creates a route to directors given in CLI data
+func RouteCreateCLI(ctx context.Context, c *cli.Context) error {
+	condition := c.String(FlagRouteCondition)
+	if condition == "" {
+		return errors.New("no route condition specified")
+	}
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		route := vaas.Route{
+			Condition: condition,
+			Priority:  c.Int(FlagRoutePriority),
+			Action:    c.String(FlagRouteAction),
+			Clusters:  c.StringSlice(FlagRouteCluster),
+		}
+		return createRoute(ctx, apiClient, config.Director, &route)
+	})
+}
+
+// RouteDeleteCLI deletes a route by id, or routes of directors given in CLI data by condition
+func RouteDeleteCLI(ctx context.Context, c *cli.Context) error {
+	routeID, condition := c.Int(FlagRouteID), c.String(FlagRouteCondition)
+	if routeID == 0 && condition == "" {
+		return errors.New("no route id or condition specified")
+	}
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	question := fmt.Sprintf("Delete routes to %s matching %q?", strings.Join(config.Directors, ", "), condition)
+	if routeID != 0 {
+		question = fmt.Sprintf("Delete route %d?", routeID)
+	}
+	if err := confirm(config, question); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	if routeID != 0 {
+		if err := apiClient.DeleteRoute(ctx, routeID); err != nil {
+			return err
+		}
+		log.WithContext(ctx).WithField(FlagRouteID, routeID).Info("Route deleted")
+		return nil
+	}
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		return deleteRoutes(ctx, apiClient, config.Director, condition)
+	})
+}
+
+// listRoutes writes a table of routes to director, ordered as VaaS returns them
+func listRoutes(ctx context.Context, client vaas.Client, directorName string, w io.Writer) error {
+	director, err := client.FindDirector(ctx, directorName)
+	if err != nil {
+		return err
+	}
+	routes, err := client.ListRoutes(ctx, director)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tPRIORITY\tACTION\tDIRECTOR\tCLUSTERS\tCONDITION")
+	for _, route := range routes {
+		fmt.Fprintf(table, "%d\t%d\t%s\t%s\t%s\t%s\n", route.ID, route.Priority, route.Action, directorName,
+			strings.Join(route.Clusters, ","), route.Condition)
+	}
+	return table.Flush()
+}
+
+func createRoute(ctx context.Context, client vaas.Client, directorName string, route *vaas.Route) error {
+	director, err := client.FindDirector(ctx, directorName)
+	if err != nil {
+		return err
+	}
+	route.Director = director.ResourceURI
+	if err := client.CreateRoute(ctx, route); err != nil {
+		return err
+	}
+	log.WithContext(ctx).WithFields(log.Fields{FlagDirector: directorName, FlagRouteID: route.ID}).
+		Infof("Route created for %s", route.Condition)
+	return nil
+}
+
+// deleteRoutes deletes every route to director with given condition
+func deleteRoutes(ctx context.Context, client vaas.Client, directorName, condition string) error {
+	director, err := client.FindDirector(ctx, directorName)
+	if err != nil {
+		return err
+	}
+	routes, err := client.ListRoutes(ctx, director)
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, route := range routes {
+		if route.Condition != condition {
+			continue
+		}
+		if err := client.DeleteRoute(ctx, int(route.ID)); err != nil {
+			return err
+		}
+		deleted++
+		log.WithContext(ctx).WithFields(log.Fields{FlagDirector: directorName, FlagRouteID: route.ID}).Info("Route deleted")
+	}
+	if deleted == 0 {
+		return fmt.Errorf("no route of director %s with condition %s", directorName, condition)
+	}
+	return nil
+}
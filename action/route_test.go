@@ -0,0 +1,43 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestCreateAndListRoutesOfDirector(t *testing.T) {
+	client := vaastest.NewClient()
+	blue := client.AddDirector("blue")
+	client.AddDirector("green")
+	require.NoError(t, createRoute(context.Background(), client, "blue", &vaas.Route{Condition: "req.url ~ \"^/\"", Priority: 10, Action: vaas.RouteActionPass}))
+	require.NoError(t, createRoute(context.Background(), client, "green", &vaas.Route{Condition: "req.url ~ \"^/api\"", Priority: 20, Action: vaas.RouteActionPass}))
+
+	var out bytes.Buffer
+	require.NoError(t, listRoutes(context.Background(), client, "blue", &out))
+
+	assert.Equal(t, blue.ResourceURI, client.Routes()[0].Director)
+	assert.Equal(t, "ID  PRIORITY  ACTION  DIRECTOR  CLUSTERS  CONDITION\n"+
+		"3   10        pass    blue                req.url ~ \"^/\"\n", out.String())
+}
+
+func TestDeleteRoutesByCondition(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDirector("blue")
+	for _, condition := range []string{"req.url ~ \"^/\"", "req.url ~ \"^/api\""} {
+		require.NoError(t, createRoute(context.Background(), client, "blue", &vaas.Route{Condition: condition}))
+	}
+
+	require.NoError(t, deleteRoutes(context.Background(), client, "blue", "req.url ~ \"^/api\""))
+	err := deleteRoutes(context.Background(), client, "blue", "req.url ~ \"^/api\"")
+
+	assert.Error(t, err)
+	require.Len(t, client.Routes(), 1)
+	assert.Equal(t, "req.url ~ \"^/\"", client.Routes()[0].Condition)
+}
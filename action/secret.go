@@ -0,0 +1,146 @@
+package action
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/allegro/vaas-registration-hook/secret"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagAPIKeyFile is an alias of FlagSecretKeyFile
+	FlagAPIKeyFile = "api-key-file"
+	// FlagUserFile file to read the user for Auth from
+	FlagUserFile = "user-file"
+	// EnvVaaSUserFile file to read the user for Auth from
+	EnvVaaSUserFile = "VAAS_USER_FILE"
+	// FlagSecretDir directory a Kubernetes Secret with the username and api-key keys is mounted at
+	FlagSecretDir = "secret-dir"
+	// EnvSecretDir directory a Kubernetes Secret with the username and api-key keys is mounted at
+	EnvSecretDir = "VAAS_SECRET_DIR"
+	// FlagSecretReloadInterval how often credentials read from files are read again, 0 to read them once
+	FlagSecretReloadInterval = "secret-reload-interval"
+	// EnvSecretReloadInterval how often credentials read from files are read again, 0 to read them once
+	EnvSecretReloadInterval = "VAAS_SECRET_RELOAD_INTERVAL"
+
+	// DefaultSecretReloadInterval is how often credentials read from files are read again by default
+	DefaultSecretReloadInterval = 30 * time.Second
+)
+
+// CredentialsConfig represents flag values locating VaaS credentials besides the user, key and key file
+type CredentialsConfig struct {
+	UserFile       string
+	SecretDir      string
+	ReloadInterval time.Duration
+}
+
+// keyFile returns the file the VaaS key is read from, empty when it is given directly
+func (config *CommonConfig) keyFile() string {
+	if config.VaaSKeyFile != "" {
+		return config.VaaSKeyFile
+	}
+	if config.Credentials.SecretDir != "" {
+		return secret.Path(config.Credentials.SecretDir, secret.KeyAPIKey)
+	}
+	return ""
+}
+
+// userFile returns the file the VaaS user is read from, empty when it is given directly.
+// A Kubernetes Secret does not have to hold the user, which may then be given directly.
+func (config *CommonConfig) userFile() string {
+	if config.Credentials.UserFile != "" {
+		return config.Credentials.UserFile
+	}
+	if config.Credentials.SecretDir != "" {
+		path := secret.Path(config.Credentials.SecretDir, secret.KeyUsername)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// readCredentials reads the VaaS user and key from their files, if given, and validates them
+func (config *CommonConfig) readCredentials() error {
+	if path := config.userFile(); path != "" {
+		user, err := secret.ReadFile("VaaS user", path)
+		if err != nil {
+			return err
+		}
+		config.VaaSUser = user
+	} else if config.VaaSUser != "" {
+		user, err := secret.Clean("VaaS user", config.VaaSUser)
+		if err != nil {
+			return err
+		}
+		config.VaaSUser = user
+	}
+
+	if path := config.keyFile(); path != "" {
+		return config.GetSecretFromFile(path)
+	}
+	key, err := secret.Clean("VaaS key", config.VaaSKey)
+	if err != nil {
+		return fmt.Errorf("%s, expected --%s, --%s or %s", err, FlagSecretKeyFile, FlagSecretDir, EnvVaaSKey)
+	}
+	config.VaaSKey = key
+	return nil
+}
+
+// credentialsAuthenticator returns an Authenticator sending credentials of config read again from their files
+// every reload interval, and at once when VaaS rejects them; nil when no credentials are read from files
+// or they are read only once
+func (config *CommonConfig) credentialsAuthenticator() vaas.Authenticator {
+	interval := config.Credentials.ReloadInterval
+	userFile, keyFile := config.userFile(), config.keyFile()
+	if interval <= 0 || (userFile == "" && keyFile == "") {
+		return nil
+	}
+
+	auth := &fileCredentials{username: config.VaaSUser, apiKey: config.VaaSKey}
+	if userFile != "" {
+		auth.user = secret.NewFile("VaaS user", userFile, interval)
+	}
+	if keyFile != "" {
+		auth.key = secret.NewFile("VaaS key", keyFile, interval)
+	}
+	return auth
+}
+
+// fileCredentials sends the VaaS user and key in an "Authorization: ApiKey" header, taking those read from
+// files from their secret.File and the others from fixed values
+type fileCredentials struct {
+	user     *secret.File
+	key      *secret.File
+	username string
+	apiKey   string
+}
+
+func (a *fileCredentials) Authenticate(request *http.Request) error {
+	username, apiKey := a.username, a.apiKey
+	var err error
+	if a.user != nil {
+		if username, err = a.user.Value(); err != nil {
+			return err
+		}
+	}
+	if a.key != nil {
+		if apiKey, err = a.key.Value(); err != nil {
+			return err
+		}
+	}
+	return vaas.APIKeyHeader(username, apiKey).Authenticate(request)
+}
+
+// Invalidate makes credentials read from files be read again before the next request
+func (a *fileCredentials) Invalidate() {
+	if a.user != nil {
+		a.user.Invalidate()
+	}
+	if a.key != nil {
+		a.key.Invalidate()
+	}
+}
@@ -0,0 +1,64 @@
+package action
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/secret"
+)
+
+func TestReadCredentialsFromSecretDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, secret.KeyUsername), []byte("admin\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, secret.KeyAPIKey), []byte("secret\n"), 0600))
+	config := CommonConfig{VaaSUser: "ignored", Credentials: CredentialsConfig{SecretDir: dir}}
+
+	require.NoError(t, config.readCredentials())
+
+	assert.Equal(t, "admin", config.VaaSUser)
+	assert.Equal(t, "secret", config.VaaSKey)
+}
+
+func TestReadCredentialsValidatesKeyGivenDirectly(t *testing.T) {
+	config := CommonConfig{VaaSUser: " admin ", VaaSKey: "secret\n"}
+	require.NoError(t, config.readCredentials())
+	assert.Equal(t, "admin", config.VaaSUser)
+	assert.Equal(t, "secret", config.VaaSKey)
+
+	config.VaaSKey = ""
+	assert.Error(t, config.readCredentials())
+}
+
+func TestCredentialsAuthenticatorRereadsRejectedKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, secret.KeyAPIKey)
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("old"), 0600))
+	config := CommonConfig{VaaSUser: "admin", VaaSKeyFile: keyFile, Credentials: CredentialsConfig{ReloadInterval: DefaultSecretReloadInterval}}
+	auth := config.credentialsAuthenticator()
+	require.NotNil(t, auth)
+	request, _ := http.NewRequest(http.MethodGet, "http://vaas", nil)
+
+	require.NoError(t, auth.Authenticate(request))
+	assert.Equal(t, "ApiKey admin:old", request.Header.Get("Authorization"))
+
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("new"), 0600))
+	auth.(*fileCredentials).Invalidate()
+	require.NoError(t, auth.Authenticate(request))
+	assert.Equal(t, "ApiKey admin:new", request.Header.Get("Authorization"))
+}
+
+func TestCredentialsAuthenticatorIsNotUsedWithoutFiles(t *testing.T) {
+	config := CommonConfig{VaaSUser: "admin", VaaSKey: "secret", Credentials: CredentialsConfig{ReloadInterval: DefaultSecretReloadInterval}}
+
+	assert.Nil(t, config.credentialsAuthenticator())
+}
@@ -0,0 +1,270 @@
+package action
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/tracing"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ServeName is the CLI name of this action
+	ServeName = "serve"
+	// FlagListen address the HTTP server listens on
+	FlagListen = "listen"
+	// EnvListen address the HTTP server listens on
+	EnvListen = "VAAS_SERVE_LISTEN"
+	// FlagServeToken bearer token requests to the HTTP server have to carry
+	FlagServeToken = "serve-token"
+	// EnvServeToken bearer token requests to the HTTP server have to carry
+	EnvServeToken = "VAAS_SERVE_TOKEN"
+
+	// RegisterPath is the HTTP path registering a backend
+	RegisterPath = "/register"
+	// DeregisterPath is the HTTP path deregistering a backend
+	DeregisterPath = "/deregister"
+	// HealthzPath is the HTTP path reporting that the server is up, without authentication
+	HealthzPath = "/healthz"
+
+	defaultListen = ":8090"
+	// shutdownTimeout limits how long requests in progress are waited for once ctx is done
+	shutdownTimeout = time.Minute
+)
+
+// GetServeFlags returns a list of flags available for this action
+func GetServeFlags() []cli.Flag {
+	flags := []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagListen,
+			Usage:  "address the HTTP server listens on",
+			Value:  defaultListen,
+			EnvVar: EnvListen,
+		},
+		cli.StringFlag{
+			Name:   FlagServeToken,
+			Usage:  "bearer token requests have to carry in the Authorization header",
+			EnvVar: EnvServeToken,
+		},
+	}
+	return append(flags, GetRegisterFlags()...)
+}
+
+// ServeCLI serves registration requests over HTTP until ctx is done.
+// Register flags are defaults of requests, which give the backend and may override its weight, DC and tags.
+func ServeCLI(ctx context.Context, c *cli.Context) error {
+	config := getCommonParameters(c.Parent())
+	if err := config.Availability.validate(); err != nil {
+		return err
+	}
+	if err := config.Registry.validate(); err != nil {
+		return err
+	}
+	if err := config.MultiVaaS.validate(); err != nil {
+		return err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return fmt.Errorf("error reading VaaS secret key: %s", err)
+	}
+
+	token := c.String(FlagServeToken)
+	if token == "" {
+		return fmt.Errorf("no --%s specified", FlagServeToken)
+	}
+
+	handler := newServeHandler(newRegistry(config), config, token, func(director string) RegisterConfig {
+		return getRegisterParameters(c, director)
+	})
+	flushTracesEvery(ctx)
+	return serve(ctx, c.String(FlagListen), handler, "", "")
+}
+
+// serve runs an HTTP server at address until ctx is done, then waits for requests in progress.
+// It serves HTTPS when certFile and keyFile are set.
+func serve(ctx context.Context, address string, handler http.Handler, certFile, keyFile string) error {
+	server := &http.Server{Addr: address, Handler: handler}
+	errs := make(chan error, 1)
+	go func() {
+		if certFile != "" || keyFile != "" {
+			errs <- server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errs <- server.ListenAndServe()
+		}
+	}()
+	log.Infof("Serving requests on %s", address)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// serveHandler registers and deregisters backends given by query parameters of requests
+type serveHandler struct {
+	registry Registry
+	config   CommonConfig
+	token    string
+	// registerConfig returns defaults of registration in director
+	registerConfig func(director string) RegisterConfig
+}
+
+func newServeHandler(registry Registry, config CommonConfig, token string,
+	registerConfig func(director string) RegisterConfig) http.Handler {
+	// Requests handle backends of their own, so a single state file cannot record them
+	config.StateFile = ""
+	handler := &serveHandler{registry: registry, config: config, token: token, registerConfig: registerConfig}
+
+	mux := http.NewServeMux()
+	mux.Handle(RegisterPath, handler.authorized(handler.register))
+	mux.Handle(DeregisterPath, handler.authorized(handler.deregister))
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// authorized lets through GET and POST requests carrying the token
+func (h *serveHandler) authorized(next func(http.ResponseWriter, *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Operations of a request are correlated by the ID of the caller, if any
+		correlationID := r.Header.Get(logging.HeaderRequestID)
+		if correlationID == "" {
+			correlationID = logging.NewCorrelationID()
+		}
+		w.Header().Set(logging.HeaderRequestID, correlationID)
+		ctx := logging.WithCorrelationID(tracing.Extract(r.Context(), r.Header), correlationID)
+		ctx, span := tracing.Start(ctx, "serve "+r.URL.Path, tracing.KindServer)
+		r = r.WithContext(ctx)
+
+		err := next(w, r)
+		span.End(err)
+		if err != nil {
+			log.WithContext(r.Context()).WithField("path", r.URL.Path).Errorf("Request failed: %s", err)
+			http.Error(w, err.Error(), statusOf(err))
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+}
+
+func (h *serveHandler) register(w http.ResponseWriter, r *http.Request) error {
+	config, err := h.backendConfig(r)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	weight := 0
+	if value := query.Get(FlagWeight); value != "" {
+		if weight, err = strconv.Atoi(value); err != nil {
+			return badRequest{fmt.Errorf("invalid %s %q", FlagWeight, value)}
+		}
+	}
+
+	err = forEachDirector(r.Context(), config, func(config CommonConfig) error {
+		rc := h.registerConfig(config.Director)
+		if weight != 0 {
+			rc.Weight = weight
+		}
+		if dc := query.Get(FlagDC); dc != "" {
+			rc.DC = dc
+		}
+		rc.Tags = append(rc.Tags, query[FlagTag]...)
+		return h.registry.Register(r.Context(), config, rc)
+	})
+	return h.config.Availability.skipWhenUnavailable(err)
+}
+
+func (h *serveHandler) deregister(w http.ResponseWriter, r *http.Request) error {
+	config, err := h.backendConfig(r)
+	if err != nil {
+		return err
+	}
+	return forEachDirector(r.Context(), config, func(config CommonConfig) error {
+		return h.registry.Deregister(r.Context(), config)
+	})
+}
+
+// backendConfig returns the handler config with directors, address and port of the backend given by r
+func (h *serveHandler) backendConfig(r *http.Request) (CommonConfig, error) {
+	query := r.URL.Query()
+	config := h.config
+	if directors := query[FlagDirector]; len(directors) > 0 {
+		config.SetDirectors(directors)
+	}
+	if address := query.Get(FlagAddress); address != "" {
+		config.Address = address
+	}
+	if value := query.Get(FlagPort); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return config, badRequest{fmt.Errorf("invalid %s %q", FlagPort, value)}
+		}
+		config.Port = port
+	}
+	if value := query.Get(FlagCanaryTag); value != "" {
+		canary, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, badRequest{fmt.Errorf("invalid %s %q", FlagCanaryTag, value)}
+		}
+		config.Canary = canary
+	}
+
+	switch {
+	case config.Director == "":
+		return config, badRequest{errors.New("no VaaS director specified")}
+	case config.Address == "":
+		return config, badRequest{errors.New("no backend address specified")}
+	case config.Port == 0:
+		return config, badRequest{errors.New("no backend port specified")}
+	}
+	address, err := resolveAddress(r.Context(), config.Address, config.Resolution)
+	if err != nil {
+		return config, badRequest{err}
+	}
+	config.Address = address
+	return config, nil
+}
+
+// badRequest is an error caused by parameters of a request
+type badRequest struct {
+	error
+}
+
+func statusOf(err error) int {
+	var invalid badRequest
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.Is(err, vaas.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
@@ -0,0 +1,102 @@
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func newTestServeHandler(client *vaastest.Client) http.Handler {
+	config := CommonConfig{StateFile: IDFileLoc}
+	return newServeHandler(newVaaSRegistry(client), config, "secret", func(director string) RegisterConfig {
+		return RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{"default"}}
+	})
+}
+
+func serveRequest(handler http.Handler, target, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServeRegistersAndDeregistersBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDC("dc2")
+	client.AddDirector("director")
+	handler := newTestServeHandler(client)
+
+	response := serveRequest(handler, "/register?director=director&addr=127.0.0.1&port=80&weight=5&dc=dc2&tag=pod", "secret")
+
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	assert.Equal(t, "127.0.0.1", backends[0].Address)
+	assert.Equal(t, 5, *backends[0].Weight)
+	assert.Equal(t, "dc2", backends[0].DC.Symbol)
+	assert.Equal(t, []string{"default", "pod"}, backends[0].Tags)
+
+	response = serveRequest(handler, "/deregister?director=director&addr=127.0.0.1&port=80", "secret")
+
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.Empty(t, client.Backends())
+}
+
+func TestServeRejectsUnauthorizedRequests(t *testing.T) {
+	client := vaastest.NewClient()
+	handler := newTestServeHandler(client)
+
+	assert.Equal(t, http.StatusUnauthorized, serveRequest(handler, "/register?director=d&addr=127.0.0.1&port=80", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveRequest(handler, "/register?director=d&addr=127.0.0.1&port=80", "wrong").Code)
+	assert.Equal(t, http.StatusOK, serveRequest(handler, HealthzPath, "").Code)
+	assert.Empty(t, client.Calls())
+}
+
+func TestServeValidatesParameters(t *testing.T) {
+	client := vaastest.NewClient()
+	handler := newTestServeHandler(client)
+
+	for _, target := range []string{
+		"/register?addr=127.0.0.1&port=80",
+		"/register?director=d&port=80",
+		"/register?director=d&addr=127.0.0.1&port=http",
+		"/register?director=d&addr=127.0.0.1&port=80&weight=heavy",
+		"/deregister?director=d&addr=127.0.0.1",
+	} {
+		assert.Equal(t, http.StatusBadRequest, serveRequest(handler, target, "secret").Code, target)
+	}
+	assert.Empty(t, client.Calls())
+}
+
+func TestServeReportsVaaSFailures(t *testing.T) {
+	client := vaastest.NewClient()
+	handler := newTestServeHandler(client)
+
+	response := serveRequest(handler, "/register?director=missing&addr=127.0.0.1&port=80", "secret")
+
+	assert.Equal(t, http.StatusBadGateway, response.Code)
+}
+
+func TestServeCorrelatesRequests(t *testing.T) {
+	handler := newTestServeHandler(vaastest.NewClient())
+
+	response := serveRequest(handler, "/deregister?director=d&addr=127.0.0.1&port=80", "secret")
+	assert.NotEmpty(t, response.Header().Get(logging.HeaderRequestID))
+
+	request := httptest.NewRequest(http.MethodGet, "/deregister?director=d&addr=127.0.0.1&port=80", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	request.Header.Set(logging.HeaderRequestID, "caller-id")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, "caller-id", recorder.Header().Get(logging.HeaderRequestID))
+}
@@ -0,0 +1,167 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// SidecarName is the CLI name of this action
+	SidecarName = "sidecar"
+	// FlagReadinessFile file written by the application once it is ready and removed when it is not
+	FlagReadinessFile = "readiness-file"
+	// EnvReadinessFile file written by the application once it is ready and removed when it is not
+	EnvReadinessFile = "VAAS_READINESS_FILE"
+	// FlagReadinessInterval how often the readiness file is checked
+	FlagReadinessInterval = "readiness-interval"
+	// EnvReadinessInterval how often the readiness file is checked
+	EnvReadinessInterval = "VAAS_READINESS_INTERVAL"
+
+	defaultReadinessInterval = time.Second
+)
+
+// SidecarConfig holds the readiness file the sidecar watches
+type SidecarConfig struct {
+	ReadinessFile string
+	Interval      time.Duration
+}
+
+// GetSidecarReadinessFlags returns flags configuring the readiness file watched by this action
+func GetSidecarReadinessFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagReadinessFile,
+			Usage:  "file the application creates once ready and removes when not, the backend is registered meanwhile",
+			EnvVar: EnvReadinessFile,
+		},
+		cli.DurationFlag{
+			Name:   FlagReadinessInterval,
+			Usage:  "how often to check the readiness file",
+			Value:  defaultReadinessInterval,
+			EnvVar: EnvReadinessInterval,
+		},
+	}
+}
+
+// GetSidecarDrainFlags returns flags configuring this action besides registration
+func GetSidecarDrainFlags() []cli.Flag {
+	return append(GetSidecarReadinessFlags(), GetAgentDrainFlags()...)
+}
+
+// GetSidecarFlags returns a list of flags available for this action
+func GetSidecarFlags() []cli.Flag {
+	return append(GetRegisterFlags(), GetSidecarDrainFlags()...)
+}
+
+// GetSidecarParameters returns the readiness file configured by flags of c
+func GetSidecarParameters(c *cli.Context) (SidecarConfig, error) {
+	sidecar := SidecarConfig{ReadinessFile: c.String(FlagReadinessFile), Interval: c.Duration(FlagReadinessInterval)}
+	if sidecar.ReadinessFile == "" {
+		return sidecar, configError{fmt.Errorf("--%s is required", FlagReadinessFile)}
+	}
+	if sidecar.Interval <= 0 {
+		sidecar.Interval = defaultReadinessInterval
+	}
+	return sidecar, nil
+}
+
+// SidecarCLI registers a backend using CLI data while the readiness file exists,
+// draining and deregistering it when the file is removed or ctx is done
+func SidecarCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+	ramp, err := GetRampParameters(c)
+	if err != nil {
+		return err
+	}
+	sidecar, err := GetSidecarParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	defer superviseBySystemd(ctx)()
+	return runSidecar(ctx, newAPIClient(config), config, getRegisterParameters(c, config.Director), sidecar,
+		c.Duration(FlagDrainPeriod), ramp, GetHeartbeatParameters(c))
+}
+
+// SidecarK8s registers a backend using K8s data while the readiness file exists,
+// draining and deregistering it when the file is removed or ctx is done
+func SidecarK8s(ctx context.Context, podInfo *k8s.PodInfo, config CommonConfig, sidecar SidecarConfig,
+	drainPeriod time.Duration, ramp RampConfig, hb HeartbeatConfig) error {
+	config, registerConfig, err := getK8sRegisterParameters(podInfo, config)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+	defer superviseBySystemd(ctx)()
+	return runSidecar(ctx, newAPIClient(config), config, registerConfig, sidecar, drainPeriod, ramp, hb)
+}
+
+// runSidecar runs the agent, see runAgent, each time the readiness file appears, until it disappears again.
+// It returns once ctx is done, the backend being deregistered by then.
+func runSidecar(ctx context.Context, client vaas.Client, config CommonConfig, rc RegisterConfig, sidecar SidecarConfig,
+	drainPeriod time.Duration, ramp RampConfig, hb HeartbeatConfig) error {
+	logger := log.WithContext(ctx).WithField(FlagReadinessFile, sidecar.ReadinessFile)
+	for {
+		logger.Info("Waiting for readiness file to appear")
+		if !sidecar.waitFor(ctx, true) {
+			return nil
+		}
+
+		logger.Info("Readiness file appeared, registering backend")
+		readyCtx, unready := context.WithCancel(ctx)
+		go func() {
+			if sidecar.waitFor(readyCtx, false) {
+				logger.Info("Readiness file disappeared, deregistering backend")
+			}
+			unready()
+		}()
+		err := runAgent(readyCtx, client, config, rc, drainPeriod, ramp, hb)
+		unready()
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// waitFor checks the readiness file every interval until it exists, or does not when exists is false, reporting
+// whether it did before ctx was done
+func (s SidecarConfig) waitFor(ctx context.Context, exists bool) bool {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if s.ready() == exists {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// ready reports whether the readiness file exists
+func (s SidecarConfig) ready() bool {
+	_, err := os.Stat(s.ReadinessFile)
+	return err == nil
+}
@@ -0,0 +1,59 @@
+package action
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestSidecarRegistersBackendWhileReadinessFileExists(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	dir, err := ioutil.TempDir("", "sidecar")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	sidecar := SidecarConfig{ReadinessFile: filepath.Join(dir, "ready"), Interval: time.Millisecond}
+	done := make(chan error)
+	go func() {
+		done <- runSidecar(ctx, client, cfg, RegisterConfig{Weight: 1, DC: "dc1"}, sidecar, 0, RampConfig{}, HeartbeatConfig{})
+	}()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, ioutil.WriteFile(sidecar.ReadinessFile, nil, 0600))
+		require.Eventually(t, func() bool { return len(client.Backends()) == 1 }, time.Second, 10*time.Millisecond)
+		require.NoError(t, os.Remove(sidecar.ReadinessFile))
+		require.Eventually(t, func() bool { return len(client.Backends()) == 0 }, time.Second, 10*time.Millisecond)
+	}
+
+	require.NoError(t, ioutil.WriteFile(sidecar.ReadinessFile, nil, 0600))
+	require.Eventually(t, func() bool { return len(client.Backends()) == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, client.Backends())
+}
+
+func TestSidecarRequiresReadinessFile(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range GetSidecarReadinessFlags() {
+		f.Apply(set)
+	}
+
+	_, err := GetSidecarParameters(cli.NewContext(cli.NewApp(), set, nil))
+
+	assert.EqualError(t, err, "--readiness-file is required")
+}
@@ -0,0 +1,234 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// ExportName is the CLI name of this action
+	ExportName = "export"
+	// ImportName is the CLI name of this action
+	ImportName = "import"
+	// FlagSnapshotFile file a snapshot of backends is written to or read from, stdout or stdin when empty or -
+	FlagSnapshotFile = "file"
+	// FlagSnapshotFormat format of an exported snapshot, yaml or json, taken from the file extension when empty
+	FlagSnapshotFormat = "format"
+	// FlagAllowEmpty imports a snapshot without backends, deleting every backend of the director
+	FlagAllowEmpty = "allow-empty"
+
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// GetExportFlags returns a list of flags available for this action
+func GetExportFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagDirector,
+			Usage: "director whose backends are exported, the global --director when not given",
+		},
+		cli.StringFlag{
+			Name:  FlagSnapshotFile,
+			Usage: "file the snapshot is written to, stdout when not given",
+		},
+		cli.StringFlag{
+			Name:  FlagSnapshotFormat,
+			Usage: "format of the snapshot, yaml or json, taken from the extension of --" + FlagSnapshotFile + " by default",
+		},
+	}
+}
+
+// GetImportFlags returns a list of flags available for this action
+func GetImportFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  FlagDirector,
+			Usage: "director backends are imported into, the global --director or the one of the snapshot when not given",
+		},
+		cli.StringFlag{
+			Name:  FlagSnapshotFile,
+			Usage: "YAML or JSON file the snapshot is read from, stdin when not given",
+		},
+		cli.BoolFlag{
+			Name:  FlagAllowEmpty,
+			Usage: "import a snapshot without backends, deleting every backend of the director",
+		},
+	}
+}
+
+// ExportCLI writes a snapshot of backends of the director given in CLI data, to migrate or restore them with import
+func ExportCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getSnapshotParameters(c)
+	if err != nil {
+		return err
+	}
+	if config.Director == "" {
+		return configError{errors.New("no VaaS director specified")}
+	}
+	file := c.String(FlagSnapshotFile)
+	format, err := snapshotFormat(c.String(FlagSnapshotFormat), file)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := vaas.ExportSnapshot(ctx, newAPIClient(config), config.Director)
+	if err != nil {
+		return err
+	}
+	data, err := marshalSnapshot(snapshot, format)
+	if err != nil {
+		return err
+	}
+	if file == "" || file == "-" {
+		_, err = c.App.Writer.Write(data)
+		return err
+	}
+	log.Infof("Exporting %d backends of director %s to %s", len(snapshot.Backends), snapshot.Director, file)
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// ImportCLI makes backends of the director given in CLI data match a snapshot, adding missing, updating changed
+// and deleting extra backends, and prints the changes as a table or JSON with --output=json
+func ImportCLI(ctx context.Context, c *cli.Context) error {
+	config, err := getSnapshotParameters(c)
+	if err != nil {
+		return err
+	}
+	snapshot, err := readSnapshot(c.String(FlagSnapshotFile), os.Stdin)
+	if err != nil {
+		return configError{err}
+	}
+	director := config.Director
+	if director == "" {
+		director = snapshot.Director
+	}
+	if director == "" {
+		return configError{errors.New("no VaaS director specified")}
+	}
+
+	if !config.DryRun {
+		question := fmt.Sprintf("Make backends of %s match the snapshot, deleting extra ones?", director)
+		if err := confirm(config, question); err != nil {
+			return err
+		}
+	}
+	options := vaas.ImportOptions{DryRun: config.DryRun, AllowEmpty: c.Bool(FlagAllowEmpty)}
+	report, err := vaas.ImportSnapshot(ctx, newAPIClient(config), director, snapshot, options)
+	if errors.Is(err, vaas.ErrEmptySnapshot) {
+		return configError{fmt.Errorf("%s, pass --%s to delete every backend of director %s", err, FlagAllowEmpty, director)}
+	}
+	if printErr := printChanges(c.App.Writer, config.Output, report.Changes); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// getSnapshotParameters returns common values of export and import, with the director of their --director flag
+func getSnapshotParameters(c *cli.Context) (CommonConfig, error) {
+	config := getCommonParameters(c.Parent())
+	if director := c.String(FlagDirector); director != "" {
+		config.SetDirectors([]string{director})
+	}
+	if err := config.ResolveDirectors(); err != nil {
+		return config, configError{err}
+	}
+	if len(config.Directors) > 1 {
+		return config, configError{errors.New("snapshots are made of a single director")}
+	}
+	if err := config.Registry.requireVaaS(); err != nil {
+		return config, err
+	}
+	if err := config.readVaaSKey(); err != nil {
+		return config, configError{fmt.Errorf("error reading VaaS secret key: %s", err)}
+	}
+	return config, nil
+}
+
+// snapshotFormat returns format, or the one of the extension of file when empty
+func snapshotFormat(format, file string) (string, error) {
+	if format == "" {
+		format = formatYAML
+		if filepath.Ext(file) == ".json" {
+			format = formatJSON
+		}
+	}
+	if format != formatYAML && format != formatJSON {
+		return "", configError{fmt.Errorf("invalid --%s %q, expected %s or %s", FlagSnapshotFormat, format, formatYAML, formatJSON)}
+	}
+	return format, nil
+}
+
+func marshalSnapshot(snapshot vaas.Snapshot, format string) ([]byte, error) {
+	if format == formatJSON {
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		return append(data, '\n'), err
+	}
+	return yaml.Marshal(snapshot)
+}
+
+// readSnapshot reads a YAML or JSON snapshot from file, or from stdin when file is empty or -
+func readSnapshot(file string, stdin io.Reader) (vaas.Snapshot, error) {
+	var snapshot vaas.Snapshot
+	if err := readDocument(file, stdin, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot: %s", err)
+	}
+	return snapshot, nil
+}
+
+// readDocument decodes a YAML or JSON document from file, or from stdin when file is empty or -, into v
+func readDocument(file string, stdin io.Reader, v interface{}) error {
+	var data []byte
+	var err error
+	if file == "" || file == "-" {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, v)
+	}
+	return yaml.UnmarshalStrict(data, v)
+}
+
+func printChanges(w io.Writer, output string, changes []vaas.ReconcileChange) error {
+	type change struct {
+		Action  string `json:"action"`
+		Address string `json:"address"`
+		Port    int    `json:"port"`
+		Error   string `json:"error,omitempty"`
+	}
+	printed := make([]change, 0, len(changes))
+	for _, c := range changes {
+		printed = append(printed, change{Action: c.Action, Address: c.Backend.Address, Port: c.Backend.Port})
+		if c.Err != nil {
+			printed[len(printed)-1].Error = c.Err.Error()
+		}
+	}
+	if output == OutputJSON {
+		return printJSON(w, printed)
+	}
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ACTION\tADDRESS\tPORT\tERROR")
+	for _, c := range printed {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\n", c.Action, c.Address, c.Port, c.Error)
+	}
+	return table.Flush()
+}
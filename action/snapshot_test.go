@@ -0,0 +1,66 @@
+package action
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestSnapshotRoundTripsInYAMLAndJSON(t *testing.T) {
+	weight := 5
+	snapshot := vaas.Snapshot{
+		Director: "director",
+		Exported: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Backends: []vaas.SnapshotBackend{{Address: "10.0.0.1", Port: 80, DC: "dc1", Weight: &weight, Tags: []string{"a"},
+			ConnectTimeout: 0.5}},
+	}
+
+	for _, format := range []string{formatYAML, formatJSON} {
+		data, err := marshalSnapshot(snapshot, format)
+		require.NoError(t, err)
+
+		read, err := readSnapshot("-", bytes.NewReader(data))
+
+		require.NoError(t, err, format)
+		assert.Equal(t, snapshot, read, format)
+	}
+}
+
+func TestReadSnapshotRejectsUnknownFields(t *testing.T) {
+	_, err := readSnapshot("", strings.NewReader("director: director\nbackend:\n  - address: 10.0.0.1\n"))
+
+	assert.Error(t, err)
+}
+
+func TestSnapshotFormatFollowsFileExtension(t *testing.T) {
+	format, err := snapshotFormat("", "backends.json")
+	require.NoError(t, err)
+	assert.Equal(t, formatJSON, format)
+
+	format, err = snapshotFormat("", "backends.yml")
+	require.NoError(t, err)
+	assert.Equal(t, formatYAML, format)
+
+	_, err = snapshotFormat("xml", "")
+	var invalid configError
+	assert.True(t, errors.As(err, &invalid), err)
+}
+
+func TestPrintChangesAsTable(t *testing.T) {
+	changes := []vaas.ReconcileChange{
+		{Action: vaas.ActionAdd, Backend: vaas.Backend{Address: "10.0.0.1", Port: 80}},
+		{Action: vaas.ActionDelete, Backend: vaas.Backend{Address: "10.0.0.2", Port: 80}, Err: errors.New("gone")},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printChanges(&out, OutputText, changes))
+
+	assert.Equal(t, "ACTION  ADDRESS   PORT  ERROR\nadd     10.0.0.1  80    \ndelete  10.0.0.2  80    gone\n", out.String())
+}
@@ -0,0 +1,218 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+// State describes a backend registered by the hook, persisted so that deregistration does not need to find it
+type State struct {
+	BackendID    int       `json:"backend_id"`
+	Director     string    `json:"director"`
+	Address      string    `json:"address"`
+	Port         int       `json:"port"`
+	ResourceURI  string    `json:"resource_uri"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// matches tells whether state describes the backend of config
+func (state *State) matches(config CommonConfig) bool {
+	return state.Director == config.Director && state.Address == config.Address && state.Port == config.Port
+}
+
+// readState reads state from path, returning nil without error when there is no state file
+func readState(path string) (*State, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state file: %s", err)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unable to parse state file %s: %s", path, err)
+	}
+	return state, nil
+}
+
+// writeState replaces state file at path, so that it is never left partially written
+func writeState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write state file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write state file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write state file: %s", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// removeState removes state file at path, if any
+func removeState(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove state file: %s", err)
+	}
+	return nil
+}
+
+// removeStateOf removes state file at path if it records backend with given ID
+func removeStateOf(path string, backendID int) error {
+	state, err := readState(path)
+	if err != nil {
+		return err
+	}
+	if state == nil || state.BackendID != backendID {
+		return nil
+	}
+	return removeState(path)
+}
+
+// saveState records backend registered in director in the state file of config, if any.
+// Registration succeeded already, so failures are only logged.
+func saveState(ctx context.Context, client vaas.Client, config CommonConfig, director *vaas.Director, backend *vaas.Backend) {
+	if config.StateFile == "" {
+		return
+	}
+
+	if backend.ID == nil {
+		found, err := client.FindBackend(ctx, director, config.Address, config.Port)
+		if err != nil {
+			log.WithContext(ctx).Warnf("Backend registered, but its ID is unknown and was not saved: %s", err)
+			return
+		}
+		backend = found
+	}
+
+	state := State{
+		BackendID:    int(*backend.ID),
+		Director:     config.Director,
+		Address:      config.Address,
+		Port:         config.Port,
+		ResourceURI:  backend.ResourceURI,
+		RegisteredAt: time.Now().UTC(),
+	}
+	if err := writeState(config.StateFile, state); err != nil {
+		log.WithContext(ctx).Warnf("Backend registered, but its state was not saved: %s", err)
+	}
+}
+
+// deregisterFromState removes the backend recorded in the state file of config.
+// It reports false when there is no record of the backend of config, so that it has to be found otherwise.
+func deregisterFromState(ctx context.Context, client vaas.Client, config CommonConfig) (bool, error) {
+	if config.StateFile == "" {
+		return false, nil
+	}
+	state, err := readState(config.StateFile)
+	if err != nil {
+		log.WithContext(ctx).Warnf("Ignoring state file: %s", err)
+		return false, nil
+	}
+	if state == nil || !state.matches(config) {
+		return false, nil
+	}
+
+	if err := deleteBackend(ctx, client, config, state.BackendID); err != nil {
+		return true, fmt.Errorf("could not deregister: %w", err)
+	}
+	log.WithContext(ctx).WithField(FlagBackendID, state.BackendID).Info("Successfully scheduled backend for deletion via VaaS")
+	recordBackend(ctx, BackendResult{Director: config.Director, Address: config.Address, Port: config.Port, BackendID: state.BackendID})
+	return true, removeState(config.StateFile)
+}
+
+// isRecordedBackend tells whether VaaS holds the backend recorded in state under its ID at the address, port and
+// director of state, so that a state file written by another task or outdated never removes a backend it does not describe
+func isRecordedBackend(ctx context.Context, client vaas.Client, state *State) (bool, error) {
+	director, err := client.FindDirector(ctx, state.Director)
+	if errors.Is(err, vaas.ErrDirectorNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	backend, err := client.FindBackend(ctx, director, state.Address, state.Port)
+	if errors.Is(err, vaas.ErrBackendNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return backend.ID != nil && int(*backend.ID) == state.BackendID, nil
+}
+
+// recoverState reconciles the state file of config with VaaS before registration.
+// A backend recorded for another director or endpoint was left behind by a previous run and is removed, unless VaaS
+// holds another backend under its ID; a record of a backend no longer in VaaS is dropped, and one with an outdated ID
+// is corrected.
+func recoverState(ctx context.Context, client vaas.Client, config CommonConfig) error {
+	if config.StateFile == "" {
+		return nil
+	}
+	state, err := readState(config.StateFile)
+	if err != nil || state == nil {
+		return err
+	}
+	logger := log.WithContext(ctx).WithField(FlagBackendID, state.BackendID)
+
+	if !state.matches(config) {
+		recorded, err := isRecordedBackend(ctx, client, state)
+		if err != nil {
+			return fmt.Errorf("could not verify backend left by a previous run: %w", err)
+		}
+		if !recorded {
+			logger.Warnf("Not removing backend %s:%d of director %s recorded in state file, VaaS holds another backend under its ID",
+				state.Address, state.Port, state.Director)
+			return nil
+		}
+		logger.Infof("Removing backend %s:%d left in director %s by a previous run", state.Address, state.Port, state.Director)
+		if err := deleteBackend(ctx, client, config, state.BackendID); err != nil {
+			return fmt.Errorf("could not remove backend left by a previous run: %w", err)
+		}
+		return removeState(config.StateFile)
+	}
+
+	director, err := client.FindDirector(ctx, state.Director)
+	if err != nil {
+		return fmt.Errorf("could not verify state file: %w", err)
+	}
+	backend, err := client.FindBackend(ctx, director, state.Address, state.Port)
+	if errors.Is(err, vaas.ErrBackendNotFound) {
+		logger.Info("Backend recorded in state file is no longer in VaaS")
+		return removeState(config.StateFile)
+	}
+	if err != nil {
+		return fmt.Errorf("could not verify state file: %w", err)
+	}
+
+	if backend.ID != nil && int(*backend.ID) != state.BackendID {
+		logger.Infof("Backend recorded in state file has ID %d in VaaS", *backend.ID)
+		state.BackendID = int(*backend.ID)
+		state.ResourceURI = backend.ResourceURI
+		return writeState(config.StateFile, *state)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package action
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func stateFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "vaas-state")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "vaas.id")
+}
+
+func TestRegisterSavesStateUsedByDeregister(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, StateFile: stateFile(t)}
+
+	require.NoError(t, register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc1", Tags: []string{}}))
+
+	state, err := readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, int(*client.Backends()[0].ID), state.BackendID)
+	require.Equal(t, "director", state.Director)
+	require.NotEmpty(t, state.ResourceURI)
+	require.False(t, state.RegisteredAt.IsZero())
+
+	require.NoError(t, deregister(context.Background(), client, cfg))
+
+	require.Empty(t, client.Backends())
+	require.NotContains(t, client.Calls(), "DeleteBackendByAddress")
+	state, err = readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
+
+func TestDeregisterIgnoresStateOfAnotherBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	_, err := client.AddBackend(context.Background(), &vaas.Backend{Address: "127.0.0.1", Port: 80}, &director)
+	require.NoError(t, err)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, StateFile: stateFile(t)}
+	require.NoError(t, writeState(cfg.StateFile, State{BackendID: 99, Director: "director", Address: "127.0.0.2", Port: 80}))
+
+	require.NoError(t, deregister(context.Background(), client, cfg))
+
+	require.Empty(t, client.Backends())
+	require.Contains(t, client.Calls(), "DeleteBackendByAddress")
+	state, err := readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, 99, state.BackendID)
+}
+
+func TestRecoverRemovesBackendLeftByPreviousRun(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	stale := &vaas.Backend{Address: "127.0.0.2", Port: 80}
+	_, err := client.AddBackend(context.Background(), stale, &director)
+	require.NoError(t, err)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, StateFile: stateFile(t)}
+	require.NoError(t, writeState(cfg.StateFile, State{BackendID: int(*stale.ID), Director: "director", Address: "127.0.0.2", Port: 80}))
+
+	require.NoError(t, recoverState(context.Background(), client, cfg))
+
+	require.Empty(t, client.Backends())
+	state, err := readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
+
+func TestRecoverKeepsBackendOfAnotherTaskUnderRecordedID(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	other := &vaas.Backend{Address: "127.0.0.3", Port: 80}
+	_, err := client.AddBackend(context.Background(), other, &director)
+	require.NoError(t, err)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, StateFile: stateFile(t)}
+	require.NoError(t, writeState(cfg.StateFile, State{BackendID: int(*other.ID), Director: "director", Address: "127.0.0.2", Port: 80}))
+
+	require.NoError(t, recoverState(context.Background(), client, cfg))
+
+	require.Len(t, client.Backends(), 1)
+	require.Equal(t, "127.0.0.3", client.Backends()[0].Address)
+}
+
+func TestRecoverCorrectsOrDropsRecordedBackend(t *testing.T) {
+	client := vaastest.NewClient()
+	director := client.AddDirector("director")
+	backend := &vaas.Backend{Address: "127.0.0.1", Port: 80}
+	_, err := client.AddBackend(context.Background(), backend, &director)
+	require.NoError(t, err)
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80, StateFile: stateFile(t)}
+	require.NoError(t, writeState(cfg.StateFile, State{BackendID: 99, Director: "director", Address: "127.0.0.1", Port: 80}))
+
+	require.NoError(t, recoverState(context.Background(), client, cfg))
+
+	state, err := readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, int(*backend.ID), state.BackendID)
+
+	require.NoError(t, client.DeleteBackend(context.Background(), int(*backend.ID)))
+	require.NoError(t, recoverState(context.Background(), client, cfg))
+
+	state, err = readState(cfg.StateFile)
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
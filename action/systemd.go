@@ -0,0 +1,67 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/systemd"
+)
+
+// sdNotify notifies systemd of the state of the hook when it runs as a Type=notify unit
+var sdNotify = systemd.Notify
+
+// notifySystemd sends states to systemd, only logging failures
+func notifySystemd(ctx context.Context, states ...string) {
+	if _, err := sdNotify(states...); err != nil {
+		log.WithContext(ctx).Warnf("Could not notify systemd: %s", err)
+	}
+}
+
+// superviseBySystemd notifies systemd that the hook is stopping once ctx is done, and its watchdog at half the
+// interval of the unit until the returned function is called, so that systemd restarts the hook once it hangs
+func superviseBySystemd(ctx context.Context) func() {
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.WithContext(ctx).Warnf("Systemd watchdog disabled: %s", err)
+	}
+	var watchdog <-chan time.Time
+	stop := func() {}
+	if interval > 0 {
+		log.WithContext(ctx).Debugf("Notifying systemd watchdog every %s", interval/2)
+		ticker := time.NewTicker(interval / 2)
+		watchdog, stop = ticker.C, ticker.Stop
+		notifySystemd(ctx, systemd.Watchdog)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer stop()
+		stopping := ctx.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-stopping:
+				notifySystemd(ctx, systemd.Stopping)
+				stopping = nil
+			case <-watchdog:
+				notifySystemd(ctx, systemd.Watchdog)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// DeregisterSystemd removes a backend from the registry using CLI data, run as ExecStopPost of the systemd unit of
+// its service. It logs how the service stopped, deregistering it whether it stopped cleanly or failed.
+func DeregisterSystemd(ctx context.Context, c *cli.Context) error {
+	if info := systemd.GetStopInfo(); info != nil {
+		log.WithContext(ctx).Infof("Service stopped with result %s", info)
+	} else {
+		log.WithContext(ctx).Warn("Not run as ExecStopPost of a systemd unit, deregistering anyway")
+	}
+	return DeregisterCLI(ctx, c)
+}
@@ -0,0 +1,84 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// TagName is the CLI name of this action
+	TagName = "tag"
+	// FlagAddTag represents a tag added to the backend, can be repeated
+	FlagAddTag = "add-tag"
+	// FlagRemoveTag represents a tag removed from the backend, can be repeated
+	FlagRemoveTag = "remove-tag"
+)
+
+// GetTagFlags returns a list of flags available for this action
+func GetTagFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  FlagAddTag,
+			Usage: "tag added to the backend, can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  FlagRemoveTag,
+			Usage: "tag removed from the backend, can be repeated",
+		},
+		cli.IntFlag{
+			Name:  flagBackendIDNames,
+			Usage: "known backend id whose tags are to be changed",
+		},
+	}
+}
+
+// TagCLI adds and removes tags of a backend in VaaS using CLI data
+func TagCLI(ctx context.Context, c *cli.Context) error {
+	added, removed := c.StringSlice(FlagAddTag), c.StringSlice(FlagRemoveTag)
+	if len(added) == 0 && len(removed) == 0 {
+		return errors.New("no tags to add or remove specified")
+	}
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	if backendID := c.Int(FlagBackendID); backendID != 0 {
+		return changeTags(ctx, apiClient, backendID, added, removed)
+	}
+
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		backendID, err := apiClient.FindBackendID(ctx, config.Director, config.Address, config.Port)
+		if err != nil {
+			return fmt.Errorf("could not determine backend ID: %w", err)
+		}
+		return changeTags(ctx, apiClient, backendID, added, removed)
+	})
+}
+
+// changeTags removes tags from the backend after adding them, so a tag both added and removed is removed
+func changeTags(ctx context.Context, client vaas.Client, backendID int, added, removed []string) error {
+	if len(added) > 0 {
+		if err := client.AddBackendTags(ctx, backendID, added...); err != nil {
+			return fmt.Errorf("could not add tags: %w", err)
+		}
+	}
+	if len(removed) > 0 {
+		if err := client.RemoveBackendTags(ctx, backendID, removed...); err != nil {
+			return fmt.Errorf("could not remove tags: %w", err)
+		}
+	}
+	log.WithContext(ctx).WithField(FlagBackendID, backendID).Infof("Backend tags changed, added %v, removed %v", added, removed)
+	return nil
+}
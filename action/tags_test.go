@@ -0,0 +1,35 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+)
+
+func TestChangeTagsAddsAndRemovesTags(t *testing.T) {
+	client := vaastest.NewClient()
+	dc := client.AddDC("dc1")
+	director := client.AddDirector("director")
+	backend := &vaas.Backend{Address: "127.0.0.1", Port: 80, DC: dc, Tags: []string{"blue", "canary"}}
+	_, err := client.AddBackend(context.Background(), backend, &director)
+	require.NoError(t, err)
+
+	err = changeTags(context.Background(), client, int(*backend.ID), []string{"green", "blue"}, []string{"canary"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blue", "green"}, client.Backends()[0].Tags)
+}
+
+func TestChangeTagsFailsForMissingBackend(t *testing.T) {
+	client := vaastest.NewClient()
+
+	err := changeTags(context.Background(), client, 7, []string{"green"}, nil)
+
+	assert.True(t, errors.Is(err, vaas.ErrBackendNotFound), err)
+}
@@ -0,0 +1,61 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/tracing"
+)
+
+// traceFlushInterval is how often long-running commands export spans
+const traceFlushInterval = 10 * time.Second
+
+// ConfigureTracing makes spans exported to the OTLP collector set in config, if any, and returns ctx continuing
+// the trace of the parent span set in config, e.g. of the pod startup
+func ConfigureTracing(ctx context.Context, config CommonConfig) (context.Context, error) {
+	if config.Tracing.TraceParent != "" {
+		parent, err := tracing.ParseTraceParent(config.Tracing.TraceParent)
+		if err != nil {
+			log.Warnf("Starting a new trace: %s", err)
+		} else {
+			ctx = tracing.ContextWithSpanContext(ctx, parent)
+		}
+	}
+
+	if config.Tracing.Endpoint == "" {
+		return ctx, nil
+	}
+	exporter, err := tracing.NewOTLPExporter(config.Tracing.Endpoint, config.Tracing.ServiceName, config.Tracing.Headers)
+	if err != nil {
+		return ctx, err
+	}
+	tracing.SetTracer(tracing.NewTracer(exporter))
+	return ctx, nil
+}
+
+// FlushTraces exports spans recorded so far, if spans are exported
+func FlushTraces(ctx context.Context) error {
+	tracer := tracing.GetTracer()
+	if tracer == nil {
+		return nil
+	}
+	return tracer.Flush(ctx)
+}
+
+// flushTracesEvery exports spans of long-running commands periodically until ctx is done
+func flushTracesEvery(ctx context.Context) {
+	if tracer := tracing.GetTracer(); tracer != nil {
+		go tracer.FlushEvery(ctx, traceFlushInterval)
+	}
+}
+
+// startBackendSpan starts a span of an operation on the backend of config
+func startBackendSpan(ctx context.Context, name string, config CommonConfig) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name, tracing.KindInternal)
+	span.SetAttribute("vaas.director", config.Director)
+	span.SetAttribute("vaas.backend.address", config.Address)
+	span.SetAttribute("vaas.backend.port", config.Port)
+	return ctx, span
+}
@@ -0,0 +1,37 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/validation"
+)
+
+// validateRegistration checks the backend to register without calling VaaS, reporting all invalid fields,
+// keyed by their flags, at once
+func validateRegistration(cfg CommonConfig, rc RegisterConfig) error {
+	var errs validation.Errors
+	errs.Add(FlagAddress, cfg.Address, validation.Address(cfg.Address))
+	errs.Add(FlagPort, cfg.Port, validation.Port(cfg.Port))
+	errs.Add(FlagDirector, cfg.Director, validation.DirectorName(cfg.Director))
+	errs.Add(FlagWeight, rc.Weight, validation.Weight(rc.Weight))
+	errs.Add(FlagDC, rc.DC, validation.DCSymbol(rc.DC))
+	if err := errs.Err(); err != nil {
+		return configError{err}
+	}
+	return nil
+}
+
+// validateDC returns the DC of given symbol, failing as invalid configuration when VaaS does not know it
+func validateDC(ctx context.Context, client vaas.Client, symbol string) (*vaas.DC, error) {
+	dc, err := client.GetDC(ctx, symbol)
+	if errors.Is(err, vaas.ErrDCNotFound) {
+		return nil, configError{validation.Errors{{Field: FlagDC, Value: symbol, Message: "not found in VaaS"}}}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed getting DC info: %w", err)
+	}
+	return dc, nil
+}
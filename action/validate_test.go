@@ -0,0 +1,44 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas/vaastest"
+	"github.com/allegro/vaas-registration-hook/validation"
+)
+
+func TestRegisterReportsAllInvalidFieldsWithoutCallingVaaS(t *testing.T) {
+	client := vaastest.NewClient()
+
+	cfg := CommonConfig{Director: "my director", Address: "10.0.0.1:80", Port: 0}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: -1, DC: ""})
+
+	require.True(t, errors.Is(err, ErrInvalidConfig))
+	var fieldErrors validation.Errors
+	require.True(t, errors.As(err, &fieldErrors))
+	fields := []string{}
+	for _, fieldError := range fieldErrors {
+		fields = append(fields, fieldError.Field)
+	}
+	assert.Equal(t, []string{FlagAddress, FlagPort, FlagDirector, FlagWeight, FlagDC}, fields)
+	assert.Empty(t, client.Calls())
+}
+
+func TestRegisterRejectsUnknownDC(t *testing.T) {
+	client := vaastest.NewClient()
+	client.AddDC("dc1")
+	client.AddDirector("director")
+
+	cfg := CommonConfig{Director: "director", Address: "127.0.0.1", Port: 80}
+	err := register(context.Background(), client, cfg, RegisterConfig{Weight: 1, DC: "dc2"})
+
+	require.True(t, errors.Is(err, ErrInvalidConfig))
+	assert.EqualError(t, err, `invalid input: dc "dc2": not found in VaaS`)
+	assert.Equal(t, 2, ExitCode(err))
+	assert.Empty(t, client.Backends())
+}
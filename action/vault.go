@@ -0,0 +1,113 @@
+package action
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vault"
+)
+
+const (
+	// FlagVaultAddress address of Vault VaaS credentials are read from
+	FlagVaultAddress = "vault-addr"
+	// EnvVaultAddress address of Vault VaaS credentials are read from
+	EnvVaultAddress = "VAULT_ADDR"
+	// FlagVaultNamespace Vault Enterprise namespace
+	FlagVaultNamespace = "vault-namespace"
+	// EnvVaultNamespace Vault Enterprise namespace
+	EnvVaultNamespace = "VAULT_NAMESPACE"
+	// FlagVaultCACert PEM file of certificate authorities trusted for Vault
+	FlagVaultCACert = "vault-ca-cert"
+	// EnvVaultCACert PEM file of certificate authorities trusted for Vault
+	EnvVaultCACert = "VAULT_CACERT"
+	// FlagVaultAuth Vault auth method: token, approle or kubernetes
+	FlagVaultAuth = "vault-auth"
+	// EnvVaultAuth Vault auth method: token, approle or kubernetes
+	EnvVaultAuth = "VAAS_VAULT_AUTH"
+	// FlagVaultAuthMount path the Vault auth method is mounted at, its name when empty
+	FlagVaultAuthMount = "vault-auth-mount"
+	// EnvVaultAuthMount path the Vault auth method is mounted at, its name when empty
+	EnvVaultAuthMount = "VAAS_VAULT_AUTH_MOUNT"
+	// FlagVaultToken Vault token of the token auth method
+	FlagVaultToken = "vault-token"
+	// EnvVaultToken Vault token of the token auth method
+	EnvVaultToken = "VAULT_TOKEN"
+	// FlagVaultRoleID role ID of the approle auth method
+	FlagVaultRoleID = "vault-role-id"
+	// EnvVaultRoleID role ID of the approle auth method
+	EnvVaultRoleID = "VAAS_VAULT_ROLE_ID"
+	// FlagVaultSecretID secret ID of the approle auth method
+	FlagVaultSecretID = "vault-secret-id"
+	// EnvVaultSecretID secret ID of the approle auth method
+	EnvVaultSecretID = "VAAS_VAULT_SECRET_ID"
+	// FlagVaultRole role of the kubernetes auth method
+	FlagVaultRole = "vault-role"
+	// EnvVaultRole role of the kubernetes auth method
+	EnvVaultRole = "VAAS_VAULT_ROLE"
+	// FlagVaultPath API path of the Vault secret holding VaaS credentials, e.g. secret/data/vaas
+	FlagVaultPath = "vault-path"
+	// EnvVaultPath API path of the Vault secret holding VaaS credentials, e.g. secret/data/vaas
+	EnvVaultPath = "VAAS_VAULT_PATH"
+	// FlagVaultUsernameField field of the Vault secret holding the VaaS user
+	FlagVaultUsernameField = "vault-username-field"
+	// FlagVaultKeyField field of the Vault secret holding the VaaS key
+	FlagVaultKeyField = "vault-key-field"
+)
+
+// VaultConfig represents flag values reading VaaS credentials from Vault
+type VaultConfig struct {
+	Address       string
+	Namespace     string
+	CACertFile    string
+	Auth          string
+	AuthMount     string
+	Token         string
+	RoleID        string
+	SecretID      string
+	Role          string
+	Path          string
+	UsernameField string
+	KeyField      string
+}
+
+// enabled returns whether VaaS credentials are read from Vault
+func (config VaultConfig) enabled() bool {
+	return config.Path != ""
+}
+
+// provider returns the Vault provider of VaaS credentials, given the VaaS user used when the secret has none
+func (config VaultConfig) provider(username string) (*vault.Provider, error) {
+	provider, err := vault.New(vault.Config{
+		Address:       config.Address,
+		Namespace:     config.Namespace,
+		CACertFile:    config.CACertFile,
+		Auth:          config.Auth,
+		AuthMount:     config.AuthMount,
+		Token:         config.Token,
+		RoleID:        config.RoleID,
+		SecretID:      config.SecretID,
+		Role:          config.Role,
+		SecretPath:    config.Path,
+		UsernameField: config.UsernameField,
+		APIKeyField:   config.KeyField,
+		Username:      username,
+	})
+	if err != nil {
+		return nil, configError{fmt.Errorf("invalid Vault configuration: %s", err)}
+	}
+	return provider, nil
+}
+
+// vaultAuthenticator returns an Authenticator sending VaaS credentials read from Vault, one failing every
+// request when the Vault configuration is invalid, or nil when credentials are not read from Vault.
+func (config *CommonConfig) vaultAuthenticator() vaas.Authenticator {
+	if !config.Vault.enabled() {
+		return nil
+	}
+	provider, err := config.Vault.provider(config.VaaSUser)
+	if err != nil {
+		return vaas.AuthenticatorFunc(func(*http.Request) error { return err })
+	}
+	return provider
+}
@@ -0,0 +1,28 @@
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vault"
+)
+
+func TestVaultCredentialsReplaceKey(t *testing.T) {
+	config := CommonConfig{VaaSUser: "admin", Vault: VaultConfig{Address: "http://vault:8200", Token: "s.token", Path: "secret/data/vaas"}}
+
+	require.NoError(t, config.readVaaSKey())
+	assert.Empty(t, config.VaaSKey)
+	assert.IsType(t, &vault.Provider{}, config.vaultAuthenticator())
+}
+
+func TestInvalidVaultConfigIsConfigError(t *testing.T) {
+	config := CommonConfig{Vault: VaultConfig{Address: "http://vault:8200", Auth: vault.AuthAppRole, Path: "secret/data/vaas"}}
+
+	err := config.readVaaSKey()
+
+	require.True(t, errors.Is(err, ErrInvalidConfig))
+	assert.Contains(t, err.Error(), "no AppRole role ID or secret ID")
+}
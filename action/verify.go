@@ -0,0 +1,95 @@
+package action
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// FlagVerify queries VaaS after registration until the backend appears and is enabled
+	FlagVerify = "verify"
+	// EnvVerify queries VaaS after registration until the backend appears and is enabled
+	EnvVerify = "VAAS_VERIFY"
+	// FlagVerifyTimeout how long the registered backend has to appear before registration fails
+	FlagVerifyTimeout = "verify-timeout"
+	// EnvVerifyTimeout how long the registered backend has to appear before registration fails
+	EnvVerifyTimeout = "VAAS_VERIFY_TIMEOUT"
+	// FlagVerifyInterval delay between queries verifying the registered backend
+	FlagVerifyInterval = "verify-interval"
+	// FlagVerifyDirectorList requires the registered backend to be listed among backends of its director as well
+	FlagVerifyDirectorList = "verify-director-list"
+	// FlagWaitForReload waits after registration until the VCL with the backend is live on all Varnish servers
+	FlagWaitForReload = "wait-for-reload"
+	// EnvWaitForReload waits after registration until the VCL with the backend is live on all Varnish servers
+	EnvWaitForReload = "VAAS_WAIT_FOR_RELOAD"
+	// FlagReloadTimeout how long the VCL has to become live before registration fails
+	FlagReloadTimeout = "reload-timeout"
+	// EnvReloadTimeout how long the VCL has to become live before registration fails
+	EnvReloadTimeout = "VAAS_RELOAD_TIMEOUT"
+	// FlagReloadInterval delay between queries of the VCL reload status
+	FlagReloadInterval = "reload-interval"
+)
+
+// GetVerifyFlags returns flags verifying the backend, and waiting for it to be live, after registration
+func GetVerifyFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:   FlagVerify,
+			Usage:  "after registration, query VaaS until the backend appears and is enabled",
+			EnvVar: EnvVerify,
+		},
+		cli.DurationFlag{
+			Name:   FlagVerifyTimeout,
+			Usage:  "how long the registered backend has to appear before registration fails",
+			EnvVar: EnvVerifyTimeout,
+			Value:  vaas.DefaultVerifyTimeout,
+		},
+		cli.DurationFlag{
+			Name:  FlagVerifyInterval,
+			Usage: "delay between queries verifying the registered backend",
+			Value: vaas.DefaultVerifyInterval,
+		},
+		cli.BoolFlag{
+			Name:  FlagVerifyDirectorList,
+			Usage: "require the registered backend to be listed among backends of its director as well",
+		},
+		cli.BoolFlag{
+			Name:   FlagWaitForReload,
+			Usage:  "after registration, wait until the VCL with the backend is live on all Varnish servers",
+			EnvVar: EnvWaitForReload,
+		},
+		cli.DurationFlag{
+			Name:   FlagReloadTimeout,
+			Usage:  "how long the VCL with the registered backend has to become live before registration fails",
+			EnvVar: EnvReloadTimeout,
+			Value:  vaas.DefaultReloadTimeout,
+		},
+		cli.DurationFlag{
+			Name:  FlagReloadInterval,
+			Usage: "delay between queries of the VCL reload status",
+			Value: vaas.DefaultReloadInterval,
+		},
+	}
+}
+
+func getVerifyParameters(c *cli.Context) *vaas.VerifyOptions {
+	if !c.Bool(FlagVerify) {
+		return nil
+	}
+	return &vaas.VerifyOptions{
+		Interval:     c.Duration(FlagVerifyInterval),
+		Timeout:      c.Duration(FlagVerifyTimeout),
+		DirectorList: c.Bool(FlagVerifyDirectorList),
+	}
+}
+
+func getReloadParameters(c *cli.Context) *vaas.ReloadOptions {
+	if !c.Bool(FlagWaitForReload) {
+		return nil
+	}
+	return &vaas.ReloadOptions{
+		Interval: c.Duration(FlagReloadInterval),
+		Timeout:  c.Duration(FlagReloadTimeout),
+	}
+}
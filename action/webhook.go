@@ -0,0 +1,110 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/webhook"
+)
+
+const (
+	// WebhookName is the CLI name of this action
+	WebhookName = "webhook"
+	// EnvWebhookListen address the admission webhook listens on
+	EnvWebhookListen = "VAAS_WEBHOOK_LISTEN"
+	// FlagTLSCert file with the certificate the admission webhook serves
+	FlagTLSCert = "tls-cert"
+	// EnvTLSCert file with the certificate the admission webhook serves
+	EnvTLSCert = "VAAS_WEBHOOK_TLS_CERT"
+	// FlagTLSKey file with the key of the certificate the admission webhook serves
+	FlagTLSKey = "tls-key"
+	// EnvTLSKey file with the key of the certificate the admission webhook serves
+	EnvTLSKey = "VAAS_WEBHOOK_TLS_KEY"
+	// FlagInjectImage image of the init container copying the hook binary into Pods
+	FlagInjectImage = "inject-image"
+	// EnvInjectImage image of the init container copying the hook binary into Pods
+	EnvInjectImage = "VAAS_INJECT_IMAGE"
+	// FlagInjectBinary path of the hook binary in the injected image
+	FlagInjectBinary = "inject-binary"
+	// FlagInjectArg global flag given to every injected hook, can be repeated
+	FlagInjectArg = "inject-arg"
+
+	// MutatePath is the HTTP path of the admission webhook
+	MutatePath = "/mutate"
+
+	defaultWebhookListen = ":8443"
+)
+
+// GetWebhookFlags returns a list of flags available for this action
+func GetWebhookFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   FlagListen,
+			Usage:  "address the admission webhook listens on",
+			Value:  defaultWebhookListen,
+			EnvVar: EnvWebhookListen,
+		},
+		cli.StringFlag{
+			Name:   FlagTLSCert,
+			Usage:  "file with the certificate served to the Kubernetes API server",
+			EnvVar: EnvTLSCert,
+		},
+		cli.StringFlag{
+			Name:   FlagTLSKey,
+			Usage:  "file with the key of the served certificate",
+			EnvVar: EnvTLSKey,
+		},
+		cli.StringFlag{
+			Name:   FlagInjectImage,
+			Usage:  "image holding the hook binary, run as init container of Pods",
+			EnvVar: EnvInjectImage,
+		},
+		cli.StringFlag{
+			Name:  FlagInjectBinary,
+			Usage: "path of the hook binary in the injected image",
+			Value: webhook.DefaultBinary,
+		},
+		cli.StringSliceFlag{
+			Name:  FlagInjectArg,
+			Usage: "global flag given to every injected hook, e.g. --inject-arg=--vaas-url=http://vaas, can be repeated",
+		},
+	}
+}
+
+// WebhookCLI serves a mutating admission webhook injecting the hook into annotated Pods until ctx is done
+func WebhookCLI(ctx context.Context, c *cli.Context) error {
+	if c.String(FlagInjectImage) == "" {
+		return fmt.Errorf("no --%s specified", FlagInjectImage)
+	}
+	if c.String(FlagTLSCert) == "" || c.String(FlagTLSKey) == "" {
+		return errors.New("the Kubernetes API server calls admission webhooks over HTTPS, " +
+			"--" + FlagTLSCert + " and --" + FlagTLSKey + " are required")
+	}
+
+	injector := &webhook.Injector{
+		Image:         c.String(FlagInjectImage),
+		Binary:        c.String(FlagInjectBinary),
+		Args:          c.StringSlice(FlagInjectArg),
+		RegisterFlags: flagNames(GetRegisterK8sFlags()),
+	}
+	mux := http.NewServeMux()
+	mux.Handle(MutatePath, injector)
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	return serve(ctx, c.String(FlagListen), mux, c.String(FlagTLSCert), c.String(FlagTLSKey))
+}
+
+// flagNames returns names of flags, without their short aliases
+func flagNames(flags []cli.Flag) []string {
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, strings.TrimSpace(strings.Split(flag.GetName(), ",")[0]))
+	}
+	return names
+}
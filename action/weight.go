@@ -0,0 +1,66 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+// SetWeightName is the CLI name of this action
+const SetWeightName = "set-weight"
+
+// GetSetWeightFlags returns a list of flags available for this action
+func GetSetWeightFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.IntFlag{
+			Name:  FlagWeight,
+			Usage: fmt.Sprintf("new weight of the backend, between %d and %d", vaas.MinWeight, vaas.MaxWeight),
+		},
+		cli.IntFlag{
+			Name:  flagBackendIDNames,
+			Usage: "known backend id whose weight is to be changed",
+		},
+	}
+}
+
+// SetWeightCLI changes weight of a backend in VaaS using CLI data
+func SetWeightCLI(ctx context.Context, c *cli.Context) error {
+	if !c.IsSet(FlagWeight) {
+		return errors.New("no weight specified")
+	}
+	config, err := getCLIParameters(c)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Registry.requireVaaS(); err != nil {
+		return err
+	}
+
+	apiClient := newAPIClient(config)
+	weight := c.Int(FlagWeight)
+	if backendID := c.Int(FlagBackendID); backendID != 0 {
+		return setWeight(ctx, apiClient, backendID, weight)
+	}
+
+	return forEachDirector(ctx, config, func(config CommonConfig) error {
+		backendID, err := apiClient.FindBackendID(ctx, config.Director, config.Address, config.Port)
+		if err != nil {
+			return fmt.Errorf("could not determine backend ID: %s", err)
+		}
+		return setWeight(ctx, apiClient, backendID, weight)
+	})
+}
+
+func setWeight(ctx context.Context, client vaas.Client, backendID int, weight int) error {
+	if err := client.SetBackendWeight(ctx, backendID, weight); err != nil {
+		return fmt.Errorf("could not set weight: %s", err)
+	}
+	log.WithContext(ctx).WithField(FlagBackendID, backendID).Infof("Backend weight set to %d", weight)
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package address resolves the IP address a backend is registered under from a hostname, the name of a network
+// interface or the first interface of the host.
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// IP families preferred when a name resolves to addresses of both
+const (
+	// FamilyAny takes the first address found
+	FamilyAny = "any"
+	// FamilyIPv4 prefers IPv4 addresses
+	FamilyIPv4 = "ipv4"
+	// FamilyIPv6 prefers IPv6 addresses
+	FamilyIPv6 = "ipv6"
+)
+
+// Sources of addresses
+const (
+	// SourceAuto keeps IP literals and resolves names of local interfaces, or hostnames through DNS otherwise
+	SourceAuto = "auto"
+	// SourceDNS resolves hostnames through DNS
+	SourceDNS = "dns"
+	// SourceInterface takes the address of the interface with given name, e.g. eth0
+	SourceInterface = "interface"
+	// SourceFirstInterface takes the address of the first interface which is up and not a loopback, ignoring the name
+	SourceFirstInterface = "first-interface"
+)
+
+// dnsTimeout limits queries to the DNS server given in Options
+const dnsTimeout = 5 * time.Second
+
+// Resolver turns a name into an IP address
+type Resolver interface {
+	Resolve(ctx context.Context, name string) (net.IP, error)
+}
+
+// Options configure resolvers
+type Options struct {
+	// Family is the preferred IP family, any when empty
+	Family string
+	// DNSServer is the host:port of the DNS server hostnames are resolved with, the system resolver when empty
+	DNSServer string
+}
+
+// New returns a Resolver taking addresses from source, auto when empty
+func New(source string, options Options) (Resolver, error) {
+	switch options.Family {
+	case "", FamilyAny, FamilyIPv4, FamilyIPv6:
+	default:
+		return nil, fmt.Errorf("unknown IP family %q, expected %s, %s or %s", options.Family, FamilyAny, FamilyIPv4, FamilyIPv6)
+	}
+
+	dns := NewDNSResolver(options.DNSServer, options.Family)
+	switch source {
+	case "", SourceAuto:
+		return auto{dns: dns, interfaces: NewInterfaceResolver(options.Family)}, nil
+	case SourceDNS:
+		return dns, nil
+	case SourceInterface:
+		return NewInterfaceResolver(options.Family), nil
+	case SourceFirstInterface:
+		return NewFirstInterfaceResolver(options.Family), nil
+	}
+	return nil, fmt.Errorf("unknown address source %q, expected %s, %s, %s or %s",
+		source, SourceAuto, SourceDNS, SourceInterface, SourceFirstInterface)
+}
+
+// auto keeps IP literals, and resolves other names as interfaces when the host has one with that name
+type auto struct {
+	dns        Resolver
+	interfaces Resolver
+}
+
+func (r auto) Resolve(ctx context.Context, name string) (net.IP, error) {
+	if ip := net.ParseIP(name); ip != nil {
+		return ip, nil
+	}
+	if _, err := findInterface(name); err == nil {
+		return r.interfaces.Resolve(ctx, name)
+	}
+	return r.dns.Resolve(ctx, name)
+}
+
+// NewDNSResolver returns a Resolver looking hostnames up at server, given as host:port, or with the system
+// resolver when server is empty
+func NewDNSResolver(server, family string) Resolver {
+	resolver := net.DefaultResolver
+	if server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{Timeout: dnsTimeout}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return dnsResolver{lookup: resolver.LookupIPAddr, family: family}
+}
+
+type dnsResolver struct {
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	family string
+}
+
+func (r dnsResolver) Resolve(ctx context.Context, name string) (net.IP, error) {
+	if ip := net.ParseIP(name); ip != nil {
+		return ip, nil
+	}
+	addresses, err := r.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		ips = append(ips, address.IP)
+	}
+	return pick(ips, r.family, name)
+}
+
+// netInterface is a network interface of the host with its IP addresses
+type netInterface struct {
+	Name  string
+	Flags net.Flags
+	IPs   []net.IP
+}
+
+// listInterfaces returns interfaces of the host, replaced in tests
+var listInterfaces = func() ([]netInterface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]netInterface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		addresses, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list addresses of %s: %w", iface.Name, err)
+		}
+		var ips []net.IP
+		for _, address := range addresses {
+			if network, ok := address.(*net.IPNet); ok {
+				ips = append(ips, network.IP)
+			}
+		}
+		result = append(result, netInterface{Name: iface.Name, Flags: iface.Flags, IPs: ips})
+	}
+	return result, nil
+}
+
+func findInterface(name string) (netInterface, error) {
+	interfaces, err := listInterfaces()
+	if err != nil {
+		return netInterface{}, err
+	}
+	for _, iface := range interfaces {
+		if iface.Name == name {
+			return iface, nil
+		}
+	}
+	return netInterface{}, fmt.Errorf("no network interface %s", name)
+}
+
+// NewInterfaceResolver returns a Resolver taking the address of the interface with the name it is given
+func NewInterfaceResolver(family string) Resolver {
+	return interfaceResolver{family: family}
+}
+
+type interfaceResolver struct {
+	family string
+}
+
+func (r interfaceResolver) Resolve(ctx context.Context, name string) (net.IP, error) {
+	iface, err := findInterface(name)
+	if err != nil {
+		return nil, err
+	}
+	return pick(globalIPs(iface.IPs), r.family, name)
+}
+
+// NewFirstInterfaceResolver returns a Resolver taking the address of the first interface which is up and not a
+// loopback, whatever name it is given
+func NewFirstInterfaceResolver(family string) Resolver {
+	return firstInterfaceResolver{family: family}
+}
+
+type firstInterfaceResolver struct {
+	family string
+}
+
+func (r firstInterfaceResolver) Resolve(ctx context.Context, _ string) (net.IP, error) {
+	interfaces, err := listInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if ip, err := pick(globalIPs(iface.IPs), r.family, iface.Name); err == nil {
+			return ip, nil
+		}
+	}
+	return nil, errors.New("no interface which is up and not a loopback has an IP address")
+}
+
+// globalIPs leaves out loopback and link-local addresses, which other hosts cannot reach
+func globalIPs(ips []net.IP) []net.IP {
+	var global []net.IP
+	for _, ip := range ips {
+		if !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+			global = append(global, ip)
+		}
+	}
+	return global
+}
+
+// pick returns the first of ips in the preferred family, or the first one when there is none of that family
+func pick(ips []net.IP, family, name string) (net.IP, error) {
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no IP addresses", name)
+	}
+	for _, ip := range ips {
+		isIPv4 := ip.To4() != nil
+		if (family == FamilyIPv4 && isIPv4) || (family == FamilyIPv6 && !isIPv4) {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
+}
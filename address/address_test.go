@@ -0,0 +1,83 @@
+package address
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeInterfaces(t *testing.T, interfaces ...netInterface) {
+	original := listInterfaces
+	listInterfaces = func() ([]netInterface, error) { return interfaces, nil }
+	t.Cleanup(func() { listInterfaces = original })
+}
+
+func ips(values ...string) []net.IP {
+	var result []net.IP
+	for _, value := range values {
+		result = append(result, net.ParseIP(value))
+	}
+	return result
+}
+
+func TestDNSResolverPrefersFamily(t *testing.T) {
+	lookup := func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("2001:db8::1")}}, nil
+	}
+
+	for family, expected := range map[string]string{"": "10.0.0.1", FamilyAny: "10.0.0.1", FamilyIPv4: "10.0.0.1", FamilyIPv6: "2001:db8::1"} {
+		ip, err := dnsResolver{lookup: lookup, family: family}.Resolve(context.Background(), "backend.example.com")
+		require.NoError(t, err, family)
+		assert.Equal(t, expected, ip.String(), family)
+	}
+}
+
+func TestInterfaceResolverSkipsLinkLocalAddresses(t *testing.T) {
+	fakeInterfaces(t, netInterface{Name: "eth0", Flags: net.FlagUp, IPs: ips("fe80::1", "10.0.0.2", "2001:db8::2")})
+
+	ip, err := NewInterfaceResolver(FamilyIPv6).Resolve(context.Background(), "eth0")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::2", ip.String())
+
+	_, err = NewInterfaceResolver("").Resolve(context.Background(), "eth1")
+	assert.EqualError(t, err, "no network interface eth1")
+}
+
+func TestFirstInterfaceResolverSkipsLoopbackAndDownInterfaces(t *testing.T) {
+	fakeInterfaces(t,
+		netInterface{Name: "lo", Flags: net.FlagUp | net.FlagLoopback, IPs: ips("127.0.0.1")},
+		netInterface{Name: "eth0", IPs: ips("10.0.0.1")},
+		netInterface{Name: "eth1", Flags: net.FlagUp, IPs: ips("10.0.0.2")},
+	)
+
+	ip, err := NewFirstInterfaceResolver(FamilyAny).Resolve(context.Background(), "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip.String())
+}
+
+func TestAutoResolvesInterfaceNamesBeforeDNS(t *testing.T) {
+	fakeInterfaces(t, netInterface{Name: "eth0", Flags: net.FlagUp, IPs: ips("10.0.0.2")})
+	resolver := auto{
+		dns: dnsResolver{lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.3")}}, nil
+		}},
+		interfaces: NewInterfaceResolver(""),
+	}
+
+	for name, expected := range map[string]string{"eth0": "10.0.0.2", "backend.example.com": "10.0.0.3", "10.0.0.4": "10.0.0.4"} {
+		ip, err := resolver.Resolve(context.Background(), name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, ip.String(), name)
+	}
+}
+
+func TestNewRejectsUnknownOptions(t *testing.T) {
+	_, err := New("ldap", Options{})
+	assert.EqualError(t, err, `unknown address source "ldap", expected auto, dns, interface or first-interface`)
+	_, err = New(SourceDNS, Options{Family: "ipv5"})
+	assert.EqualError(t, err, `unknown IP family "ipv5", expected any, ipv4 or ipv6`)
+}
@@ -1,37 +1,62 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"sort"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 
 	"github.com/allegro/vaas-registration-hook/action"
+	"github.com/allegro/vaas-registration-hook/address"
+	"github.com/allegro/vaas-registration-hook/config"
+	"github.com/allegro/vaas-registration-hook/docker"
 	"github.com/allegro/vaas-registration-hook/k8s"
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/mesos"
+	"github.com/allegro/vaas-registration-hook/nomad"
+	"github.com/allegro/vaas-registration-hook/signals"
+	"github.com/allegro/vaas-registration-hook/vaas"
+	"github.com/allegro/vaas-registration-hook/vault"
+	"github.com/allegro/vaas-registration-hook/webhook"
 )
 
 const (
 	// AppName is the name of this software
 	AppName = "vaas-registration-hook"
+
+	// traceFlushTimeout limits exporting spans on exit
+	traceFlushTimeout = 5 * time.Second
+	// terminationGracePeriod limits waiting for in-flight VaaS requests before cancelling them on termination
+	terminationGracePeriod = 10 * time.Second
 )
 
 var (
 	// Version holds the version of this software
 	Version string
+	// Commit holds the revision this software was built from
+	Commit string
 	// Config contains configuration obtained from various sources
 	Config action.CommonConfig
 
+	// configFile is the path of the configuration file; fileValues are the values read from it
+	configFile string
+	fileValues config.Values
+
 	app *cli.App
+	// ctx is cancelled once the hook is asked to terminate, aborting in-flight VaaS requests
+	ctx context.Context
 )
 
 func init() {
-	// ensure we will always have logs in logfmt format
-	formatter := &log.TextFormatter{
-		DisableColors:    false,
-		QuoteEmptyFields: true,
+	// ensure we will always have logs in logfmt format until flags select another one
+	if err := logging.Configure(logging.FormatText, ""); err != nil {
+		log.Fatal(err)
 	}
-	log.SetFormatter(formatter)
 	log.Printf("Initializing %s %s", AppName, Version)
 
 	Config = action.CommonConfig{}
@@ -42,29 +67,98 @@ func init() {
 	app.HideVersion = false
 	app.Usage = "Binary hook for (de)registering in VaaS."
 	app.Flags = getCommonFlags()
-	app.Commands = getCommands()
+	app.Commands = withCompletion(withConfigFile(getCommands()))
+	app.EnableBashCompletion = true
+	app.BashComplete = action.Complete
 	sort.Sort(cli.CommandsByName(app.Commands))
 }
 
+// waitForVaaSRequests lets VaaS requests in progress finish for at most terminationGracePeriod
+func waitForVaaSRequests() {
+	ctx, cancel := context.WithTimeout(context.Background(), terminationGracePeriod)
+	defer cancel()
+	if err := action.WaitIdle(ctx); err != nil {
+		log.Warnf("Cancelling unfinished VaaS requests: %s", err)
+	}
+}
+
 func main() {
+	var cancel context.CancelFunc
+	ctx, cancel = signals.WithGracefulTermination(context.Background(), waitForVaaSRequests)
+	defer cancel()
+
 	app.Action = func(c *cli.Context) error {
 		log.Println("No action specified, exiting. For usage see --help.")
 		return nil
 	}
 
 	app.Before = func(c *cli.Context) error {
+		if err := loadConfigFile(c); err != nil {
+			return err
+		}
+		Config.SetDirectors(c.StringSlice(action.FlagDirector))
+		if err := logging.Configure(Config.Log.Format, Config.Log.Level); err != nil {
+			return err
+		}
 		if Config.Debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
+		correlationID := Config.Log.CorrelationID
+		if correlationID == "" {
+			correlationID = logging.NewCorrelationID()
+		}
+		logging.SetDefaultCorrelationID(correlationID)
+		ctx = logging.WithCorrelationID(ctx, correlationID)
+
+		Config.Tracing.Headers = c.StringSlice(action.FlagOTLPHeader)
+		Config.MultiVaaS.SecondaryURLs = c.StringSlice(action.FlagVaaSSecondaryURL)
+		Config.DCInference.Sources = c.StringSlice(action.FlagDCSource)
+		Config.DCInference.Mapping = c.StringSlice(action.FlagDCMapping)
+		Config.Audit.Headers = c.StringSlice(action.FlagHeader)
+		var err error
+		ctx, err = action.ConfigureTracing(ctx, Config)
+		return err
+	}
+
+	app.After = func(c *cli.Context) error {
+		if err := action.PushMetrics(Config); err != nil {
+			log.Warnf("Failed pushing metrics: %s", err)
+		}
+		// ctx may be cancelled already, spans of the run are exported anyway
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), traceFlushTimeout)
+		defer cancelFlush()
+		if err := action.FlushTraces(flushCtx); err != nil {
+			log.Warnf("Failed exporting spans: %s", err)
+		}
 		return nil
 	}
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Error(err)
+		os.Exit(action.ExitCode(err))
 	}
 }
 
+// loadConfigFile reads the configuration file, if any, and applies it to global flags
+func loadConfigFile(c *cli.Context) error {
+	if configFile == "" {
+		return nil
+	}
+
+	values, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	fileValues = values
+	return config.Apply(c, c.App.Flags, fileValues)
+}
+
+// applyConfigFile applies the configuration file to flags of a subcommand
+func applyConfigFile(c *cli.Context) error {
+	return config.Apply(c, c.Command.Flags, fileValues)
+}
+
 func getCommonFlags() []cli.Flag {
 	return []cli.Flag{
 		cli.BoolFlag{
@@ -73,40 +167,207 @@ func getCommonFlags() []cli.Flag {
 			Destination: &Config.Debug,
 			EnvVar:      action.EnvDebug,
 		},
+		cli.BoolFlag{
+			Name:        action.FlagDebugHTTP,
+			Usage:       "log every VaaS request and response with headers and bodies, credentials masked",
+			Destination: &Config.DebugHTTP.Enabled,
+			EnvVar:      action.EnvDebugHTTP,
+		},
+		cli.IntFlag{
+			Name:        action.FlagDebugHTTPBodyLimit,
+			Usage:       "bytes of each request and response body logged with --" + action.FlagDebugHTTP,
+			Value:       vaas.DefaultDumpBodyLimit,
+			Destination: &Config.DebugHTTP.BodyLimit,
+			EnvVar:      action.EnvDebugHTTPBodyLimit,
+		},
 		cli.StringFlag{
 			Name:        action.FlagVaaSURL,
 			Usage:       "address of the VaaS endpoint",
 			Destination: &Config.VaaSURL,
 			EnvVar:      action.EnvVaaSURL,
 		},
+		cli.StringFlag{
+			Name:        action.FlagVaaSSocket,
+			Usage:       "Unix domain socket VaaS is reached through, e.g. of an API gateway sidecar, instead of the host of --" + action.FlagVaaSURL,
+			Destination: &Config.Proxy.Socket,
+			EnvVar:      action.EnvVaaSSocket,
+		},
+		cli.StringSliceFlag{
+			Name:   action.FlagVaaSSecondaryURL,
+			Usage:  "address of another VaaS endpoint, e.g. in another region, tried in order or registered in too with --" + action.FlagVaaSMultiMode + ", can be repeated",
+			EnvVar: action.EnvVaaSSecondaryURLs,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaaSMultiMode,
+			Usage:       "how secondary VaaS endpoints are used: " + action.MultiModeFailover + " when the ones before are unavailable, or " + action.MultiModeAll + " to register in every one",
+			Value:       action.MultiModeFailover,
+			Destination: &Config.MultiVaaS.Mode,
+			EnvVar:      action.EnvVaaSMultiMode,
+		},
+		cli.StringFlag{
+			Name:        action.FlagAPIVersion,
+			Usage:       fmt.Sprintf("version of VaaS API, one of %s, or auto to use the newest one VaaS offers", strings.Join(vaas.SupportedAPIVersions(), ", ")),
+			Value:       vaas.DefaultAPIVersion,
+			Destination: &Config.APIVersion,
+			EnvVar:      action.EnvAPIVersion,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagStrictDecode,
+			Usage:       "reject VaaS responses with fields unknown to the hook",
+			Destination: &Config.StrictDecode,
+			EnvVar:      action.EnvStrictDecode,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagSchemaCheck,
+			Usage:       "warn once about every field of VaaS responses unknown to the hook, to notice changes of the VaaS API",
+			Destination: &Config.SchemaCheck,
+			EnvVar:      action.EnvSchemaCheck,
+		},
 		cli.StringFlag{
 			Name:        action.FlagUser,
 			Usage:       "user for Auth",
 			Destination: &Config.VaaSUser,
 			EnvVar:      action.EnvVaaSUser,
 		},
+		cli.StringFlag{
+			Name:        action.FlagUserFile,
+			Usage:       "file to read user for Auth from",
+			Destination: &Config.Credentials.UserFile,
+			EnvVar:      action.EnvVaaSUserFile,
+		},
 		cli.StringFlag{
 			Name:        action.FlagSecretKey,
-			Usage:       "client key for Auth",
+			Usage:       "client key for Auth, visible in process listings when given on the command line",
 			Destination: &Config.VaaSKey,
 			EnvVar:      action.EnvVaaSKey,
 		},
 		cli.StringFlag{
-			Name:        action.FlagSecretKeyFile,
+			Name:        action.FlagSecretKeyFile + ", " + action.FlagAPIKeyFile,
 			Usage:       "file to read client key for Auth from",
 			Destination: &Config.VaaSKeyFile,
 			EnvVar:      action.EnvVaaSKeyFile,
 		},
 		cli.StringFlag{
-			Name:        action.FlagDirector,
-			Usage:       "VaaS director to register this backend with",
-			Destination: &Config.Director,
+			Name:        action.FlagSecretDir,
+			Usage:       "directory a Kubernetes Secret is mounted at, holding client key for Auth as api-key and optionally user as username",
+			Destination: &Config.Credentials.SecretDir,
+			EnvVar:      action.EnvSecretDir,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagSecretReloadInterval,
+			Usage:       "how often long-running commands read user and client key from their files again, 0 to read them once",
+			Value:       action.DefaultSecretReloadInterval,
+			Destination: &Config.Credentials.ReloadInterval,
+			EnvVar:      action.EnvSecretReloadInterval,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultAddress,
+			Usage:       "address of Vault to read client key for Auth from, e.g. https://vault.example.com:8200",
+			Destination: &Config.Vault.Address,
+			EnvVar:      action.EnvVaultAddress,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultNamespace,
+			Usage:       "Vault Enterprise namespace",
+			Destination: &Config.Vault.Namespace,
+			EnvVar:      action.EnvVaultNamespace,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultCACert,
+			Usage:       "PEM file of certificate authorities trusted for Vault",
+			Destination: &Config.Vault.CACertFile,
+			EnvVar:      action.EnvVaultCACert,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultAuth,
+			Usage:       "Vault auth method: token, approle or kubernetes",
+			Value:       vault.AuthToken,
+			Destination: &Config.Vault.Auth,
+			EnvVar:      action.EnvVaultAuth,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultAuthMount,
+			Usage:       "path the Vault auth method is mounted at, its name when empty",
+			Destination: &Config.Vault.AuthMount,
+			EnvVar:      action.EnvVaultAuthMount,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultToken,
+			Usage:       "Vault token of the token auth method, visible in process listings when given on the command line",
+			Destination: &Config.Vault.Token,
+			EnvVar:      action.EnvVaultToken,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultRoleID,
+			Usage:       "role ID of the approle auth method",
+			Destination: &Config.Vault.RoleID,
+			EnvVar:      action.EnvVaultRoleID,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultSecretID,
+			Usage:       "secret ID of the approle auth method",
+			Destination: &Config.Vault.SecretID,
+			EnvVar:      action.EnvVaultSecretID,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultRole,
+			Usage:       "role of the kubernetes auth method",
+			Destination: &Config.Vault.Role,
+			EnvVar:      action.EnvVaultRole,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultPath,
+			Usage:       "API path of the Vault secret holding user and client key for Auth, e.g. secret/data/vaas",
+			Destination: &Config.Vault.Path,
+			EnvVar:      action.EnvVaultPath,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultUsernameField,
+			Usage:       "field of the Vault secret holding user for Auth",
+			Value:       vault.DefaultUsernameField,
+			Destination: &Config.Vault.UsernameField,
+		},
+		cli.StringFlag{
+			Name:        action.FlagVaultKeyField,
+			Usage:       "field of the Vault secret holding client key for Auth",
+			Value:       vault.DefaultAPIKeyField,
+			Destination: &Config.Vault.KeyField,
+		},
+		cli.StringSliceFlag{
+			Name:  action.FlagDirector,
+			Usage: "VaaS director to register this backend with, can be repeated or list directors separated by commas",
+		},
+		cli.StringFlag{
+			Name:        action.FlagCluster,
+			Usage:       "logical VaaS cluster the director has to be served by, in multi-cluster VaaS deployments",
+			Destination: &Config.Cluster,
+			EnvVar:      action.EnvCluster,
 		},
 		cli.StringFlag{
 			Name:        action.FlagAddress,
-			Usage:       "IP address of this backend",
+			Usage:       "IP address of this backend, IPv6 with or without brackets, or a hostname resolved to one",
 			Destination: &Config.Address,
 		},
+		cli.StringFlag{
+			Name:        action.FlagPreferFamily,
+			Usage:       "IP family of the address a hostname given by --addr resolves to: any, ipv4 or ipv6",
+			Value:       address.FamilyAny,
+			EnvVar:      action.EnvPreferFamily,
+			Destination: &Config.Resolution.PreferFamily,
+		},
+		cli.StringFlag{
+			Name:        action.FlagAddressSource,
+			Usage:       "how --addr is resolved: auto keeps IP literals and resolves interface names or hostnames, dns, interface, or first-interface to take the first interface which is up and not a loopback",
+			Value:       address.SourceAuto,
+			EnvVar:      action.EnvAddressSource,
+			Destination: &Config.Resolution.Source,
+		},
+		cli.StringFlag{
+			Name:        action.FlagDNSServer,
+			Usage:       "host:port of the DNS server hostnames given by --addr are resolved with, the system resolver when empty",
+			EnvVar:      action.EnvDNSServer,
+			Destination: &Config.Resolution.DNSServer,
+		},
 		cli.IntFlag{
 			Name:        action.FlagPort,
 			Usage:       "port of this backend",
@@ -117,6 +378,386 @@ func getCommonFlags() []cli.Flag {
 			Destination: &Config.Canary,
 			Usage:       "this backend is a canary",
 		},
+		cli.StringFlag{
+			Name:        action.FlagConfigFile,
+			Usage:       "YAML or JSON file with values of flags not given on command line or in environment",
+			Destination: &configFile,
+			EnvVar:      action.EnvConfigFile,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagDryRun,
+			Usage:       "log changes that would be sent to VaaS without applying them",
+			Destination: &Config.DryRun,
+			EnvVar:      action.EnvDryRun,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagYes,
+			Usage:       "answer yes to confirmation prompts of destructive actions, asked only on a terminal",
+			Destination: &Config.AssumeYes,
+			EnvVar:      action.EnvYes,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagProtectLastBackend,
+			Usage:       "refuse to deregister the only remaining backend of a director, which would drop all its traffic",
+			Destination: &Config.ProtectLastBackend,
+			EnvVar:      action.EnvProtectLastBackend,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagForce,
+			Usage:       "deregister the last backend of a director despite --" + action.FlagProtectLastBackend,
+			Destination: &Config.Force,
+			EnvVar:      action.EnvForce,
+		},
+		cli.StringFlag{
+			Name:        action.FlagRegistry,
+			Usage:       "where backends are registered: vaas, noop to only log them, or file to record them in --registry-file",
+			Value:       action.RegistryVaaS,
+			Destination: &Config.Registry.Kind,
+			EnvVar:      action.EnvRegistry,
+		},
+		cli.StringFlag{
+			Name:        action.FlagRegistryFile,
+			Usage:       "JSON file backends are recorded in with --registry=file",
+			Destination: &Config.Registry.File,
+			EnvVar:      action.EnvRegistryFile,
+		},
+		cli.StringFlag{
+			Name:        action.FlagConsulURL,
+			Usage:       "Consul HTTP API registered backends are mirrored to as services named after directors, e.g. http://localhost:8500",
+			Destination: &Config.Consul.URL,
+			EnvVar:      action.EnvConsulURL,
+		},
+		cli.StringFlag{
+			Name:        action.FlagConsulToken,
+			Usage:       "ACL token of Consul requests",
+			Destination: &Config.Consul.Token,
+			EnvVar:      action.EnvConsulToken,
+		},
+		cli.StringFlag{
+			Name:        action.FlagConsulNode,
+			Usage:       "Consul catalog node backends are registered at, the hostname when empty",
+			Destination: &Config.Consul.Node,
+			EnvVar:      action.EnvConsulNode,
+		},
+		cli.StringFlag{
+			Name:        action.FlagConsulDatacenter,
+			Usage:       "Consul datacenter of the catalog, the one of the agent when empty",
+			Destination: &Config.Consul.Datacenter,
+			EnvVar:      action.EnvConsulDatacenter,
+		},
+		cli.StringFlag{
+			Name:        action.FlagEventWebhookURL,
+			Usage:       "URL registered, deregistered and failed events are POSTed to as JSON",
+			Destination: &Config.Events.WebhookURL,
+			EnvVar:      action.EnvEventWebhookURL,
+		},
+		cli.StringFlag{
+			Name:        action.FlagSlackWebhookURL,
+			Usage:       "Slack-compatible incoming webhook registered, deregistered and failed events are posted to as messages",
+			Destination: &Config.Events.SlackURL,
+			EnvVar:      action.EnvSlackWebhookURL,
+		},
+		cli.StringSliceFlag{
+			Name:   action.FlagDCSource,
+			Usage:  "infer the DC of backends registered without one from node (topology labels of the Kubernetes Node), aws or gcp (zone in instance metadata) or hostname (--dc-hostname-pattern), trying sources in order",
+			EnvVar: action.EnvDCSource,
+		},
+		cli.StringSliceFlag{
+			Name:   action.FlagDCMapping,
+			Usage:  "value=dc mapping an inferred zone, region or hostname part to a DC symbol, exactly or by prefix, e.g. europe-west1=dc1",
+			EnvVar: action.EnvDCMapping,
+		},
+		cli.StringFlag{
+			Name:        action.FlagDCHostnamePattern,
+			Usage:       "regular expression matching the hostname, whose group named dc, or first group, is the DC",
+			Destination: &Config.DCInference.HostnamePattern,
+			EnvVar:      action.EnvDCHostnamePattern,
+		},
+		cli.StringFlag{
+			Name:        action.FlagOutput,
+			Usage:       "format of the result printed to stdout: text prints none, json prints backends, duration and exit code",
+			Value:       action.OutputText,
+			Destination: &Config.Output,
+			EnvVar:      action.EnvOutput,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagAsyncTimeout,
+			Usage:       "wait up to this long for VaaS to apply changes, 0 to not wait",
+			Destination: &Config.AsyncTimeout,
+			EnvVar:      action.EnvAsyncTimeout,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagRequestTimeout,
+			Usage:       "limit of a single VaaS request, 0 for no limit",
+			Value:       vaas.DefaultTimeout,
+			Destination: &Config.RequestTimeout,
+			EnvVar:      action.EnvRequestTimeout,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagOperationTimeout,
+			Usage:       "limit of a VaaS call together with its retries and task polling, 0 for no limit",
+			Destination: &Config.OperationTimeout,
+			EnvVar:      action.EnvOperationTimeout,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagDialTimeout,
+			Usage:       "limit of opening a connection to VaaS, 0 for no limit",
+			Value:       vaas.DefaultDialTimeout,
+			Destination: &Config.DialTimeout,
+			EnvVar:      action.EnvDialTimeout,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagTLSHandshakeTimeout,
+			Usage:       "limit of the TLS handshake with VaaS, 0 for no limit",
+			Value:       vaas.DefaultTLSHandshakeTimeout,
+			Destination: &Config.TLSHandshakeTimeout,
+			EnvVar:      action.EnvTLSHandshakeTimeout,
+		},
+		cli.IntFlag{
+			Name:        action.FlagMaxConcurrentRequests,
+			Usage:       "maximum number of VaaS requests sent at once, e.g. by the controller, 0 for no limit",
+			Destination: &Config.MaxConcurrentRequests,
+			EnvVar:      action.EnvMaxConcurrentRequests,
+		},
+		cli.Float64Flag{
+			Name:        action.FlagRateLimit,
+			Usage:       "maximum average number of VaaS requests per second, 0 for no limit",
+			Destination: &Config.RateLimit.RPS,
+			EnvVar:      action.EnvRateLimit,
+		},
+		cli.IntFlag{
+			Name:        action.FlagRateLimitBurst,
+			Usage:       "number of VaaS requests sent at once before the rate limit applies",
+			Value:       1,
+			Destination: &Config.RateLimit.Burst,
+			EnvVar:      action.EnvRateLimitBurst,
+		},
+		cli.IntFlag{
+			Name:        action.FlagCircuitBreakerThreshold,
+			Usage:       "consecutive VaaS failures after which requests fail fast, 0 to never fail fast",
+			Value:       5,
+			Destination: &Config.CircuitBreaker.Threshold,
+			EnvVar:      action.EnvCircuitBreakerThreshold,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagCircuitBreakerCooldown,
+			Usage:       "how long VaaS requests fail fast before VaaS is probed again",
+			Value:       30 * time.Second,
+			Destination: &Config.CircuitBreaker.Cooldown,
+			EnvVar:      action.EnvCircuitBreakerCooldown,
+		},
+		cli.IntFlag{
+			Name:        action.FlagLockRetryAttempts,
+			Usage:       "attempts to add a backend while VaaS rejects it for a concurrent change of its director, 1 to not retry",
+			Value:       vaas.DefaultLockRetryPolicy.MaxAttempts,
+			Destination: &Config.LockRetry.Attempts,
+			EnvVar:      action.EnvLockRetryAttempts,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagLockRetryDelay,
+			Usage:       "delay before retrying such a backend, doubled on every retry and randomized by half",
+			Value:       vaas.DefaultLockRetryPolicy.BaseDelay,
+			Destination: &Config.LockRetry.Delay,
+			EnvVar:      action.EnvLockRetryDelay,
+		},
+		cli.StringFlag{
+			Name:        action.FlagOnUnavailable,
+			Usage:       "what registration does once VaaS requests fail fast or VaaS is in maintenance: fail, or skip with a warning",
+			Value:       action.OnUnavailableFail,
+			Destination: &Config.Availability.OnUnavailable,
+			EnvVar:      action.EnvOnUnavailable,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagMaintenanceWait,
+			Usage:       "how long registration waits for VaaS maintenance to end, 0 to not wait",
+			Destination: &Config.Availability.MaintenanceWait,
+			EnvVar:      action.EnvMaintenanceWait,
+		},
+		cli.StringFlag{
+			Name:        action.FlagStateFile,
+			Usage:       "file of this task recording the registered backend for deregistration, empty to not record it",
+			Destination: &Config.StateFile,
+			EnvVar:      action.EnvStateFile,
+		},
+		cli.StringFlag{
+			Name:        action.FlagPushGateway,
+			Usage:       "Prometheus Pushgateway URL to push metrics of VaaS requests to",
+			Destination: &Config.PushGateway,
+			EnvVar:      action.EnvPushGateway,
+		},
+		cli.StringFlag{
+			Name:        action.FlagLogFormat,
+			Usage:       "format of log lines: text or json",
+			Value:       logging.FormatText,
+			Destination: &Config.Log.Format,
+			EnvVar:      action.EnvLogFormat,
+		},
+		cli.StringFlag{
+			Name:        action.FlagLogLevel,
+			Usage:       "minimum level of logged lines: debug, info, warning or error",
+			Value:       "info",
+			Destination: &Config.Log.Level,
+			EnvVar:      action.EnvLogLevel,
+		},
+		cli.StringFlag{
+			Name:        action.FlagCorrelationID,
+			Usage:       "ID correlating log lines and VaaS requests (X-Request-ID) of this run, random when empty",
+			Destination: &Config.Log.CorrelationID,
+			EnvVar:      action.EnvCorrelationID,
+		},
+		cli.StringFlag{
+			Name:        action.FlagOTLPEndpoint,
+			Usage:       "OpenTelemetry collector address receiving spans over OTLP/HTTP, e.g. http://otel-collector:4318",
+			Destination: &Config.Tracing.Endpoint,
+			EnvVar:      action.EnvOTLPEndpoint,
+		},
+		cli.StringSliceFlag{
+			Name:   action.FlagOTLPHeader,
+			Usage:  "key=value header of OTLP export requests, can be repeated",
+			EnvVar: action.EnvOTLPHeaders,
+		},
+		cli.StringFlag{
+			Name:        action.FlagTraceServiceName,
+			Usage:       "service name of exported spans",
+			Value:       AppName,
+			Destination: &Config.Tracing.ServiceName,
+			EnvVar:      action.EnvTraceServiceName,
+		},
+		cli.StringFlag{
+			Name:        action.FlagTraceParent,
+			Usage:       "W3C traceparent of the span, e.g. of pod startup, spans of this run are children of",
+			Destination: &Config.Tracing.TraceParent,
+			EnvVar:      action.EnvTraceParent,
+		},
+		cli.DurationFlag{
+			Name:        action.FlagCacheTTL,
+			Usage:       "how long directors and DCs found in VaaS are cached, 0 to not cache them",
+			Value:       vaas.DefaultCacheTTL,
+			Destination: &Config.Cache.TTL,
+			EnvVar:      action.EnvCacheTTL,
+		},
+		cli.StringFlag{
+			Name:        action.FlagCacheFile,
+			Usage:       "file caching directors and DCs across runs, e.g. of hooks of several containers, empty to cache in memory only",
+			Destination: &Config.Cache.File,
+			EnvVar:      action.EnvCacheFile,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagNoCache,
+			Usage:       "look up directors and DCs in VaaS, bypassing the cache",
+			Destination: &Config.Cache.Disabled,
+			EnvVar:      action.EnvNoCache,
+		},
+		cli.StringFlag{
+			Name:        action.FlagCACert,
+			Usage:       "file with CA certificates to verify VaaS with",
+			Destination: &Config.TLS.CACertFile,
+			EnvVar:      action.EnvCACert,
+		},
+		cli.StringFlag{
+			Name:        action.FlagClientCert,
+			Usage:       "file with client certificate for Auth",
+			Destination: &Config.TLS.ClientCertFile,
+			EnvVar:      action.EnvClientCert,
+		},
+		cli.StringFlag{
+			Name:        action.FlagClientKey,
+			Usage:       "file with client certificate key for Auth",
+			Destination: &Config.TLS.ClientKeyFile,
+			EnvVar:      action.EnvClientKey,
+		},
+		cli.BoolFlag{
+			Name:        action.FlagInsecureSkipVerify,
+			Usage:       "do not verify VaaS certificate, for lab environments only",
+			Destination: &Config.TLS.InsecureSkipVerify,
+		},
+		cli.StringFlag{
+			Name:        action.FlagProxyURL,
+			Usage:       "HTTP(S) or SOCKS5 proxy VaaS is reached through, e.g. http://proxy.example.com:3128 or socks5://proxy.example.com:1080, overriding HTTP_PROXY, HTTPS_PROXY and NO_PROXY",
+			Destination: &Config.Proxy.URL,
+			EnvVar:      action.EnvProxyURL,
+		},
+		cli.StringFlag{
+			Name:        action.FlagProxyAuth,
+			Usage:       "user:password authenticating to the proxy",
+			Destination: &Config.Proxy.Auth,
+			EnvVar:      action.EnvProxyAuth,
+		},
+		cli.StringFlag{
+			Name:        action.FlagUserAgent,
+			Usage:       "User-Agent of VaaS requests, e.g. naming the pipeline registering backends in VaaS audit logs",
+			Value:       userAgent(),
+			Destination: &Config.Audit.UserAgent,
+			EnvVar:      action.EnvUserAgent,
+		},
+		cli.StringSliceFlag{
+			Name:   action.FlagHeader,
+			Usage:  "name=value header added to every VaaS request, e.g. X-Deploy-ID=42 or X-Initiator=ci, can be repeated",
+			EnvVar: action.EnvHeaders,
+		},
+		cli.StringFlag{
+			Name:        action.FlagAuditLog,
+			Usage:       "file appending a JSON line for every request adding, changing or deleting VaaS resources, with its outcome",
+			Destination: &Config.Audit.Log,
+			EnvVar:      action.EnvAuditLog,
+		},
+		cli.IntFlag{
+			Name:        action.FlagAuditLogMaxSize,
+			Usage:       "size in megabytes the audit log is rotated at, 0 to never rotate it",
+			Value:       action.DefaultAuditLogMaxSize,
+			Destination: &Config.Audit.LogMaxSize,
+			EnvVar:      action.EnvAuditLogMaxSize,
+		},
+		cli.IntFlag{
+			Name:        action.FlagAuditLogMaxBackups,
+			Usage:       "number of rotated audit logs kept",
+			Value:       action.DefaultAuditLogMaxBackups,
+			Destination: &Config.Audit.LogMaxBackups,
+			EnvVar:      action.EnvAuditLogMaxBackups,
+		},
+	}
+}
+
+// userAgent names this software with its version and commit, e.g. vaas-registration-hook/1.2.3 (commit abc123)
+func userAgent() string {
+	version := Version
+	if version == "" {
+		version = "unknown"
+	}
+	if Commit == "" {
+		return AppName + "/" + version
+	}
+	return fmt.Sprintf("%s/%s (commit %s)", AppName, version, Commit)
+}
+
+// withConfigFile makes commands without subcommands, and subcommands of others, apply the configuration file to their flags
+func withConfigFile(commands []cli.Command) []cli.Command {
+	for i := range commands {
+		if len(commands[i].Subcommands) == 0 {
+			commands[i].Before = applyConfigFile
+		}
+		for j := range commands[i].Subcommands {
+			commands[i].Subcommands[j].Before = applyConfigFile
+		}
+	}
+	return commands
+}
+
+// withCompletion completes command lines of commands and their subcommands with action.Complete
+func withCompletion(commands []cli.Command) []cli.Command {
+	for i := range commands {
+		commands[i].BashComplete = action.Complete
+		commands[i].Subcommands = withCompletion(commands[i].Subcommands)
+	}
+	return commands
+}
+
+// withResult runs the action called name with the global context, printing its result in the format of --output
+func withResult(name string, run func(context.Context, *cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		return action.RunWithResult(ctx, c.App.Writer, Config.Output, name, func(ctx context.Context) error {
+			return run(ctx, c)
+		})
 	}
 }
 
@@ -129,16 +770,16 @@ func getCommands() []cli.Command {
 				{
 					Name:  "cli",
 					Usage: "register using data from command line/env",
-					Action: func(c *cli.Context) error {
+					Action: withResult(action.RegisterName, func(ctx context.Context, c *cli.Context) error {
 						log.Print("Registering services using data from command line/env")
-						return action.RegisterCLI(c)
-					},
+						return action.RegisterCLI(ctx, c)
+					}),
 					Flags: action.GetRegisterFlags(),
 				},
 				{
 					Name:  "k8s",
 					Usage: "register using data from Kubernetes API",
-					Action: func(c *cli.Context) error {
+					Action: withResult(action.RegisterName, func(ctx context.Context, c *cli.Context) error {
 						log.Print("Registering services using data from Kubernetes API")
 
 						podInfo, err := k8s.GetPodInfo()
@@ -148,8 +789,369 @@ func getCommands() []cli.Command {
 						}
 						log.Info("K8s Pod environment detected")
 
-						return action.RegisterK8s(podInfo, Config)
+						return action.RegisterK8s(ctx, c, podInfo, Config)
+					}),
+					Flags: action.GetRegisterK8sFlags(),
+				},
+				{
+					Name:  "mesos",
+					Usage: "register using data from Marathon task environment",
+					Action: withResult(action.RegisterName, func(ctx context.Context, c *cli.Context) error {
+						log.Print("Registering services using data from Marathon task environment")
+
+						taskInfo, err := mesos.GetTaskInfo()
+						if err != nil {
+							log.Errorf("Marathon task not detected: %s", err)
+							return nil
+						}
+						log.Info("Marathon task environment detected")
+
+						return action.RegisterMesos(ctx, c, taskInfo, Config)
+					}),
+					Flags: action.GetRegisterMesosFlags(),
+				},
+				{
+					Name:  "nomad",
+					Usage: "register using data from Nomad allocation environment",
+					Action: withResult(action.RegisterName, func(ctx context.Context, c *cli.Context) error {
+						log.Print("Registering services using data from Nomad allocation environment")
+
+						allocInfo, err := nomad.GetAllocInfo()
+						if err != nil {
+							log.Errorf("Nomad allocation not detected: %s", err)
+							return nil
+						}
+						log.Info("Nomad allocation environment detected")
+
+						return action.RegisterNomad(ctx, c, allocInfo, Config)
+					}),
+					Flags: action.GetRegisterNomadFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.AgentName,
+			Usage: "register a backend with VaaS and deregister it gracefully on SIGTERM",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "run agent using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Running agent using data from command line/env")
+						return action.AgentCLI(ctx, c)
+					},
+					Flags: action.GetAgentFlags(),
+				},
+				{
+					Name:  "k8s",
+					Usage: "run agent using data from Kubernetes API",
+					Action: func(c *cli.Context) error {
+						log.Print("Running agent using data from Kubernetes API")
+
+						podInfo, err := k8s.GetPodInfo()
+						if err != nil {
+							log.Errorf("K8s Pod not detected: %s", err)
+							return nil
+						}
+						log.Info("K8s Pod environment detected")
+
+						ramp, err := action.GetRampParameters(c)
+						if err != nil {
+							return err
+						}
+						return action.AgentK8s(ctx, podInfo, Config, c.Duration(action.FlagDrainPeriod), ramp,
+							action.GetHeartbeatParameters(c))
+					},
+					Flags: action.GetAgentDrainFlags(),
+				},
+				{
+					Name:  "nomad",
+					Usage: "run agent using data from Nomad allocation environment",
+					Action: func(c *cli.Context) error {
+						log.Print("Running agent using data from Nomad allocation environment")
+
+						allocInfo, err := nomad.GetAllocInfo()
+						if err != nil {
+							log.Errorf("Nomad allocation not detected: %s", err)
+							return nil
+						}
+						log.Info("Nomad allocation environment detected")
+
+						return action.AgentNomad(ctx, c, allocInfo, Config)
 					},
+					Flags: action.GetAgentNomadFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.SidecarName,
+			Usage: "register a backend with VaaS while a readiness file exists and deregister it gracefully otherwise",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "run sidecar using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Running sidecar using data from command line/env")
+						return action.SidecarCLI(ctx, c)
+					},
+					Flags: action.GetSidecarFlags(),
+				},
+				{
+					Name:  "k8s",
+					Usage: "run sidecar using data from Kubernetes API",
+					Action: func(c *cli.Context) error {
+						log.Print("Running sidecar using data from Kubernetes API")
+
+						sidecar, err := action.GetSidecarParameters(c)
+						if err != nil {
+							return err
+						}
+						podInfo, err := k8s.GetPodInfo()
+						if err != nil {
+							log.Errorf("K8s Pod not detected: %s", err)
+							return nil
+						}
+						log.Info("K8s Pod environment detected")
+
+						ramp, err := action.GetRampParameters(c)
+						if err != nil {
+							return err
+						}
+						return action.SidecarK8s(ctx, podInfo, Config, sidecar, c.Duration(action.FlagDrainPeriod), ramp,
+							action.GetHeartbeatParameters(c))
+					},
+					Flags: action.GetSidecarDrainFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.SetWeightName,
+			Usage: "change weight of a backend registered with VaaS",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "change weight using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Changing backend weight using data from command line/env")
+						return action.SetWeightCLI(ctx, c)
+					},
+					Flags: action.GetSetWeightFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.EnableName,
+			Usage: "bring a disabled backend back into rotation",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "enable backend using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Enabling backend using data from command line/env")
+						return action.EnableCLI(ctx, c)
+					},
+					Flags: action.GetEnableFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.DisableName,
+			Usage: "take a backend out of rotation without deregistering it",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "disable backend using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Disabling backend using data from command line/env")
+						return action.DisableCLI(ctx, c)
+					},
+					Flags: action.GetEnableFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.ListName,
+			Usage: "list backends and directors defined in VaaS",
+			Subcommands: []cli.Command{
+				{
+					Name:  "backends",
+					Usage: "list backends of the director",
+					Action: func(c *cli.Context) error {
+						return action.ListBackendsCLI(ctx, c)
+					},
+					Flags: action.GetListBackendsFlags(),
+				},
+				{
+					Name:  "directors",
+					Usage: "list every director",
+					Action: func(c *cli.Context) error {
+						return action.ListDirectorsCLI(ctx, c)
+					},
+				},
+				{
+					Name:  "dcs",
+					Usage: "list every DC",
+					Action: func(c *cli.Context) error {
+						return action.ListDCsCLI(ctx, c)
+					},
+				},
+			},
+		},
+		{
+			Name:  action.SearchName,
+			Usage: "search objects defined in VaaS",
+			Subcommands: []cli.Command{
+				{
+					Name:  "backends",
+					Usage: "search backends of every director by address, port, tag, DC and director name",
+					Action: func(c *cli.Context) error {
+						return action.SearchBackendsCLI(ctx, c)
+					},
+					Flags: action.GetSearchBackendsFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.ShowName,
+			Usage: "show objects defined in VaaS",
+			Subcommands: []cli.Command{
+				{
+					Name:  "backend",
+					Usage: "show a backend by id",
+					Action: func(c *cli.Context) error {
+						return action.ShowBackendCLI(ctx, c)
+					},
+					Flags: action.GetShowBackendFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.ReconcileName,
+			Usage: "delete backends of directors which are gone from a source of truth",
+			Subcommands: []cli.Command{
+				{
+					Name:  "k8s",
+					Usage: "keep backends which are Endpoints of a Kubernetes Service",
+					Action: withResult(action.ReconcileName, func(ctx context.Context, c *cli.Context) error {
+						return action.ReconcileK8s(ctx, c)
+					}),
+					Flags: action.GetReconcileK8sFlags(),
+				},
+				{
+					Name:  "mesos",
+					Usage: "keep backends which are tasks of a Marathon application",
+					Action: withResult(action.ReconcileName, func(ctx context.Context, c *cli.Context) error {
+						return action.ReconcileMesos(ctx, c)
+					}),
+					Flags: action.GetReconcileMesosFlags(),
+				},
+				{
+					Name:  "file",
+					Usage: "keep backends listed by an inventory file",
+					Action: withResult(action.ReconcileName, func(ctx context.Context, c *cli.Context) error {
+						return action.ReconcileFile(ctx, c)
+					}),
+					Flags: action.GetReconcileFileFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.PruneName,
+			Usage: "delete backends of directors matching a tag, address prefix or creation time, listing them with --" + action.FlagDryRun,
+			Action: func(c *cli.Context) error {
+				log.Print("Pruning backends using data from command line/env")
+				return action.PruneCLI(ctx, c)
+			},
+			Flags: action.GetPruneFlags(),
+		},
+		{
+			Name:  action.ExportName,
+			Usage: "write a YAML or JSON snapshot of backends of a director",
+			Action: func(c *cli.Context) error {
+				return action.ExportCLI(ctx, c)
+			},
+			Flags: action.GetExportFlags(),
+		},
+		{
+			Name:  action.ImportName,
+			Usage: "make backends of a director match a snapshot, adding missing, updating changed and deleting extra ones",
+			Action: func(c *cli.Context) error {
+				return action.ImportCLI(ctx, c)
+			},
+			Flags: action.GetImportFlags(),
+		},
+		{
+			Name:      action.CompletionName,
+			Usage:     "print the completion script of a shell, bash, zsh or fish, completing subcommands, flags, directors and DCs",
+			ArgsUsage: "bash|zsh|fish",
+			Action:    action.CompletionCLI,
+		},
+		{
+			Name:  action.ApplyName,
+			Usage: "make directors and their backends match a YAML or JSON desired state, printing the plan of changes",
+			Action: func(c *cli.Context) error {
+				return action.ApplyCLI(ctx, c)
+			},
+			Flags: action.GetApplyFlags(),
+		},
+		{
+			Name:  action.RampName,
+			Usage: "step up the weight of a backend registered with VaaS to its target",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "ramp up weight using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Ramping up backend weight using data from command line/env")
+						return action.RampCLI(ctx, c)
+					},
+					Flags: action.GetRampCLIFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.TagName,
+			Usage: "add and remove tags of a backend registered with VaaS",
+			Subcommands: []cli.Command{
+				{
+					Name:  "cli",
+					Usage: "change tags using data from command line/env",
+					Action: func(c *cli.Context) error {
+						log.Print("Changing backend tags using data from command line/env")
+						return action.TagCLI(ctx, c)
+					},
+					Flags: action.GetTagFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.RouteName,
+			Usage: "list, create and delete routing rules sending requests to VaaS directors",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list routes to the director",
+					Action: func(c *cli.Context) error {
+						return action.RouteListCLI(ctx, c)
+					},
+				},
+				{
+					Name:  "create",
+					Usage: "create a route to the director",
+					Action: func(c *cli.Context) error {
+						log.Print("Creating route using data from command line/env")
+						return action.RouteCreateCLI(ctx, c)
+					},
+					Flags: action.GetRouteCreateFlags(),
+				},
+				{
+					Name:  "delete",
+					Usage: "delete a route by id or routes to the director by condition",
+					Action: func(c *cli.Context) error {
+						log.Print("Deleting routes using data from command line/env")
+						return action.RouteDeleteCLI(ctx, c)
+					},
+					Flags: action.GetRouteDeleteFlags(),
 				},
 			},
 		},
@@ -160,16 +1162,16 @@ func getCommands() []cli.Command {
 				{
 					Name:  "cli",
 					Usage: "Deregister using data from command line/env",
-					Action: func(c *cli.Context) error {
+					Action: withResult(action.DeregisterName, func(ctx context.Context, c *cli.Context) error {
 						log.Print("Deregistering services using data from command line/env")
-						return action.DeregisterCLI(c)
-					},
+						return action.DeregisterCLI(ctx, c)
+					}),
 					Flags: action.GetDeregisterFlags(),
 				},
 				{
 					Name:  "k8s",
 					Usage: "Deregister using data from Kubernetes API",
-					Action: func(c *cli.Context) error {
+					Action: withResult(action.DeregisterName, func(ctx context.Context, c *cli.Context) error {
 						log.Print("Deregistering services using data from Kubernetes API")
 
 						podInfo, err := k8s.GetPodInfo()
@@ -179,10 +1181,89 @@ func getCommands() []cli.Command {
 						}
 						log.Info("K8s Pod environment detected")
 
-						return action.DeregisterK8s(podInfo, Config)
-					},
+						return action.DeregisterK8s(ctx, podInfo, Config)
+					}),
 				},
+				{
+					Name:  "mesos",
+					Usage: "Deregister using data from Marathon task environment",
+					Action: withResult(action.DeregisterName, func(ctx context.Context, c *cli.Context) error {
+						log.Print("Deregistering services using data from Marathon task environment")
+
+						taskInfo, err := mesos.GetTaskInfo()
+						if err != nil {
+							log.Errorf("Marathon task not detected: %s", err)
+							return nil
+						}
+						log.Info("Marathon task environment detected")
+
+						return action.DeregisterMesos(ctx, c, taskInfo, Config)
+					}),
+					Flags: action.GetMesosFlags(),
+				},
+				{
+					Name:  "nomad",
+					Usage: "Deregister using data from Nomad allocation environment",
+					Action: withResult(action.DeregisterName, func(ctx context.Context, c *cli.Context) error {
+						log.Print("Deregistering services using data from Nomad allocation environment")
+
+						allocInfo, err := nomad.GetAllocInfo()
+						if err != nil {
+							log.Errorf("Nomad allocation not detected: %s", err)
+							return nil
+						}
+						log.Info("Nomad allocation environment detected")
+
+						return action.DeregisterNomad(ctx, c, allocInfo, Config)
+					}),
+					Flags: action.GetNomadFlags(),
+				},
+				{
+					Name:  "systemd",
+					Usage: "Deregister using data from command line/env, run as ExecStopPost of a systemd unit",
+					Action: withResult(action.DeregisterName, func(ctx context.Context, c *cli.Context) error {
+						log.Print("Deregistering services using data from command line/env after systemd stopped the service")
+						return action.DeregisterSystemd(ctx, c)
+					}),
+					Flags: action.GetDeregisterFlags(),
+				},
+			},
+		},
+		{
+			Name:  action.ServeName,
+			Usage: "serve HTTP endpoints registering and deregistering backends, e.g. for Kubernetes httpGet hooks",
+			Action: func(c *cli.Context) error {
+				log.Print("Serving registration requests over HTTP")
+				return action.ServeCLI(ctx, c)
+			},
+			Flags: action.GetServeFlags(),
+		},
+		{
+			Name:  action.ControllerName,
+			Usage: "register ready Pods annotated with " + k8s.AnnotationDirector + " and deregister them once they are not",
+			Action: func(c *cli.Context) error {
+				log.Print("Running controller using data from Kubernetes API")
+				return action.ControllerCLI(ctx, c)
+			},
+			Flags: action.GetControllerFlags(),
+		},
+		{
+			Name:  action.DockerName,
+			Usage: "register running Docker containers labeled with " + docker.LabelDirector + " and deregister them once they stop",
+			Action: func(c *cli.Context) error {
+				log.Print("Running Docker events listener")
+				return action.DockerCLI(ctx, c)
+			},
+			Flags: action.GetDockerFlags(),
+		},
+		{
+			Name:  action.WebhookName,
+			Usage: "serve a mutating admission webhook injecting the hook into Pods annotated with " + webhook.AnnotationInject,
+			Action: func(c *cli.Context) error {
+				log.Print("Serving admission webhook")
+				return action.WebhookCLI(ctx, c)
 			},
+			Flags: action.GetWebhookFlags(),
 		},
 	}
 }
@@ -0,0 +1,60 @@
+// Package config provides flag values from a YAML or JSON configuration file.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// Values maps flag names to values read from a configuration file.
+type Values map[string]interface{}
+
+// Load reads values from a YAML file at path. JSON files are read as well, since JSON is a subset of YAML.
+func Load(path string) (Values, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %s", err)
+	}
+
+	values := Values{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %s", path, err)
+	}
+	return values, nil
+}
+
+// Apply sets flags of c given neither on the command line nor through their environment variables
+// to values, so the precedence is: command line, environment, configuration file, flag defaults.
+// Lists set repeatable flags once per element.
+func Apply(c *cli.Context, flags []cli.Flag, values Values) error {
+	for _, flag := range flags {
+		name := strings.TrimSpace(strings.Split(flag.GetName(), ",")[0])
+		value, ok := values[name]
+		if !ok || value == nil || c.IsSet(name) {
+			continue
+		}
+
+		if err := set(c, name, value); err != nil {
+			return fmt.Errorf("invalid value of %s in config file: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func set(c *cli.Context, name string, value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok {
+		return c.Set(name, fmt.Sprint(value))
+	}
+
+	for _, element := range list {
+		if err := c.Set(name, fmt.Sprint(element)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
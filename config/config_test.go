@@ -0,0 +1,104 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "vaas-hook-config")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", "director: director1\nweight: 5\ntag:\n  - a\n  - b\n")
+
+	values, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "director1", values["director"])
+	assert.Equal(t, 5, values["weight"])
+	assert.Equal(t, []interface{}{"a", "b"}, values["tag"])
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := writeFile(t, "config.json", `{"director": "director1", "weight": 5}`)
+
+	values, err := Load(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "director1", values["director"])
+	assert.Equal(t, 5, values["weight"])
+}
+
+func TestLoadFailsOnMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(os.TempDir(), "vaas-hook-missing.yaml"))
+
+	require.Error(t, err)
+}
+
+func TestApplyPrecedence(t *testing.T) {
+	require.NoError(t, os.Setenv("VAAS_HOOK_TEST_DC", "env-dc"))
+	defer func() { _ = os.Unsetenv("VAAS_HOOK_TEST_DC") }()
+
+	values := Values{
+		"director": "file-director",
+		"dc":       "file-dc",
+		"weight":   5,
+		"tag":      []interface{}{"a", "b"},
+		"user":     "file-user",
+	}
+
+	var director, dc, user string
+	var weight int
+	var tags []string
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{Name: "director", Destination: &director},
+		cli.StringFlag{Name: "dc", EnvVar: "VAAS_HOOK_TEST_DC", Destination: &dc},
+		cli.IntFlag{Name: "weight", Value: 1, Destination: &weight},
+		cli.StringSliceFlag{Name: "tag"},
+		cli.StringFlag{Name: "user, u", Destination: &user},
+	}
+	app.Before = func(c *cli.Context) error {
+		return Apply(c, c.App.Flags, values)
+	}
+	app.Action = func(c *cli.Context) error {
+		tags = c.StringSlice("tag")
+		return nil
+	}
+
+	err := app.Run([]string{"vaas-hook", "--director", "cli-director"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "cli-director", director)
+	assert.Equal(t, "env-dc", dc)
+	assert.Equal(t, 5, weight)
+	assert.Equal(t, []string{"a", "b"}, tags)
+	assert.Equal(t, "file-user", user)
+}
+
+func TestApplyFailsOnInvalidValue(t *testing.T) {
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{cli.IntFlag{Name: "weight"}}
+	app.Before = func(c *cli.Context) error {
+		return Apply(c, c.App.Flags, Values{"weight": "heavy"})
+	}
+	app.Action = func(c *cli.Context) error { return nil }
+
+	err := app.Run([]string{"vaas-hook"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "weight")
+}
@@ -0,0 +1,125 @@
+// Package consul registers services in a Consul catalog over its HTTP API.
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	catalogRegisterPath   = "/v1/catalog/register"
+	catalogDeregisterPath = "/v1/catalog/deregister"
+	tokenHeader           = "X-Consul-Token"
+)
+
+// DefaultTimeout limits every request to Consul
+const DefaultTimeout = 10 * time.Second
+
+// Service is an instance of a service at a node of the catalog
+type Service struct {
+	ID      string   `json:"ID"`
+	Service string   `json:"Service"`
+	Address string   `json:"Address,omitempty"`
+	Port    int      `json:"Port,omitempty"`
+	Tags    []string `json:"Tags,omitempty"`
+}
+
+// Client registers services in the Consul catalog of a single node
+type Client struct {
+	url        string
+	token      string
+	node       string
+	datacenter string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client registering services of node at Consul given by url, e.g. http://localhost:8500.
+// The token is sent with every request unless empty, and an empty datacenter is the one of the agent.
+func NewClient(url, token, node, datacenter string) *Client {
+	return &Client{
+		url:        strings.TrimSuffix(url, "/"),
+		token:      token,
+		node:       node,
+		datacenter: datacenter,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// catalogRegistration is the body of catalog register requests
+type catalogRegistration struct {
+	Node           string   `json:"Node"`
+	Address        string   `json:"Address"`
+	Datacenter     string   `json:"Datacenter,omitempty"`
+	Service        *Service `json:"Service"`
+	SkipNodeUpdate bool     `json:"SkipNodeUpdate"`
+}
+
+// catalogDeregistration is the body of catalog deregister requests
+type catalogDeregistration struct {
+	Node       string `json:"Node"`
+	Datacenter string `json:"Datacenter,omitempty"`
+	ServiceID  string `json:"ServiceID"`
+}
+
+// Register adds service to the node, or updates it if the node has a service with the same ID.
+// The node is created at the address of service when it does not exist.
+func (c *Client) Register(ctx context.Context, service Service) error {
+	registration := catalogRegistration{
+		Node:           c.node,
+		Address:        service.Address,
+		Datacenter:     c.datacenter,
+		Service:        &service,
+		SkipNodeUpdate: true,
+	}
+	if err := c.put(ctx, catalogRegisterPath, registration); err != nil {
+		return fmt.Errorf("cannot register service %s in Consul: %w", service.ID, err)
+	}
+	return nil
+}
+
+// Deregister removes service with given ID from the node
+func (c *Client) Deregister(ctx context.Context, serviceID string) error {
+	deregistration := catalogDeregistration{Node: c.node, Datacenter: c.datacenter, ServiceID: serviceID}
+	if err := c.put(ctx, catalogDeregisterPath, deregistration); err != nil {
+		return fmt.Errorf("cannot deregister service %s from Consul: %w", serviceID, err)
+	}
+	return nil
+}
+
+func (c *Client) put(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPut, c.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		request.Header.Set(tokenHeader, c.token)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	message, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	// Consul answers false when the change was not applied, e.g. as it is denied by ACLs in permissive mode
+	if strings.TrimSpace(string(message)) == "false" {
+		return fmt.Errorf("%s not applied", path)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterPutsServiceInCatalog(t *testing.T) {
+	var registration catalogRegistration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, catalogRegisterPath, r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get(tokenHeader))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&registration))
+		_, err := w.Write([]byte("true"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL+"/", "secret", "node1", "dc1")
+
+	err := client.Register(context.Background(),
+		Service{ID: "director-127.0.0.1-80", Service: "director", Address: "127.0.0.1", Port: 80, Tags: []string{"canary"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "node1", registration.Node)
+	assert.Equal(t, "dc1", registration.Datacenter)
+	assert.Equal(t, "127.0.0.1", registration.Address)
+	assert.True(t, registration.SkipNodeUpdate)
+	assert.Equal(t, "director", registration.Service.Service)
+	assert.Equal(t, []string{"canary"}, registration.Service.Tags)
+}
+
+func TestDeregisterRemovesServiceFromCatalog(t *testing.T) {
+	var deregistration catalogDeregistration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, catalogDeregisterPath, r.URL.Path)
+		assert.Empty(t, r.Header.Get(tokenHeader))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&deregistration))
+		_, err := w.Write([]byte("true"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "", "node1", "")
+
+	require.NoError(t, client.Deregister(context.Background(), "director-127.0.0.1-80"))
+	assert.Equal(t, catalogDeregistration{Node: "node1", ServiceID: "director-127.0.0.1-80"}, deregistration)
+}
+
+func TestRequestsFailOnErrorsAndUnappliedChanges(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == catalogRegisterPath {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		_, err := w.Write([]byte("false"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "", "node1", "")
+
+	assert.EqualError(t, client.Register(context.Background(), Service{ID: "id", Service: "director"}),
+		"cannot register service id in Consul: 403 Forbidden: Permission denied")
+	assert.EqualError(t, client.Deregister(context.Background(), "id"),
+		"cannot deregister service id from Consul: /v1/catalog/deregister not applied")
+}
@@ -0,0 +1,243 @@
+// Package datacenter infers the VaaS DC symbol of a backend from metadata of the node it runs on: topology labels
+// of its Kubernetes Node, the zone reported by the AWS or GCP metadata endpoint, or a pattern of its hostname.
+// Values found are turned into DC symbols with a mapping table.
+package datacenter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sources of DCs
+const (
+	// SourceNode takes the zone, or region, label of the Kubernetes Node
+	SourceNode = "node"
+	// SourceAWS takes the availability zone from the EC2 instance metadata endpoint
+	SourceAWS = "aws"
+	// SourceGCP takes the zone from the Compute Engine metadata server
+	SourceGCP = "gcp"
+	// SourceHostname takes a part of the hostname matched by a pattern
+	SourceHostname = "hostname"
+)
+
+// Default metadata endpoints of cloud providers
+const (
+	DefaultAWSMetadataURL = "http://169.254.169.254"
+	DefaultGCPMetadataURL = "http://metadata.google.internal"
+)
+
+// metadataTimeout limits requests to metadata endpoints, which answer at once on instances of their cloud only
+const metadataTimeout = 2 * time.Second
+
+// ErrNotInferred is returned when no source tells the DC
+var ErrNotInferred = errors.New("could not infer DC")
+
+// Options configure Infer
+type Options struct {
+	// Sources are tried in order until one tells a value
+	Sources []string
+	// Mapping turns values of sources into DC symbols, matching them exactly or by their longest mapped prefix,
+	// e.g. "europe-west1" maps zone "europe-west1-b". Values are used as DC symbols when it is empty.
+	Mapping map[string]string
+	// HostnamePattern matches the hostname with SourceHostname. Its group named dc, or else its first group, is
+	// the value; the whole match without groups.
+	HostnamePattern *regexp.Regexp
+
+	// NodeZone returns the topology zone, or region, of the Kubernetes Node with SourceNode
+	NodeZone func(ctx context.Context) (string, error)
+	// Hostname returns the hostname, os.Hostname when nil
+	Hostname func() (string, error)
+	// AWSURL and GCPURL are metadata endpoints, the default ones when empty
+	AWSURL string
+	GCPURL string
+	// HTTPClient requests metadata endpoints, one with a short timeout when nil
+	HTTPClient *http.Client
+}
+
+// Infer returns the DC symbol told by the first source of options that tells one, mapped with their Mapping.
+// It returns an error matching ErrNotInferred, with failures of every source, when none does.
+func Infer(ctx context.Context, options Options) (string, error) {
+	if len(options.Sources) == 0 {
+		return "", fmt.Errorf("%w: no DC sources", ErrNotInferred)
+	}
+	var failures []string
+	for _, source := range options.Sources {
+		value, err := options.lookup(ctx, source)
+		if err == nil {
+			var dc string
+			if dc, err = options.mapValue(value); err == nil {
+				return dc, nil
+			}
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", source, err))
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotInferred, strings.Join(failures, "; "))
+}
+
+// ValidateSource returns an error for an unknown source
+func ValidateSource(source string) error {
+	switch source {
+	case SourceNode, SourceAWS, SourceGCP, SourceHostname:
+		return nil
+	}
+	return fmt.Errorf("is not one of %s, %s, %s or %s", SourceNode, SourceAWS, SourceGCP, SourceHostname)
+}
+
+// ParseMapping parses "value=dc" entries of a mapping table
+func ParseMapping(entries []string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid DC mapping %q, expected value=dc", entry)
+		}
+		mapping[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return mapping, nil
+}
+
+func (o Options) lookup(ctx context.Context, source string) (string, error) {
+	switch source {
+	case SourceNode:
+		return o.nodeZone(ctx)
+	case SourceAWS:
+		return o.awsZone(ctx)
+	case SourceGCP:
+		return o.gcpZone(ctx)
+	case SourceHostname:
+		return o.hostnameValue()
+	}
+	return "", ValidateSource(source)
+}
+
+// mapValue returns the DC of value in Mapping, or value itself without a mapping
+func (o Options) mapValue(value string) (string, error) {
+	if len(o.Mapping) == 0 {
+		return value, nil
+	}
+	if dc, ok := o.Mapping[value]; ok {
+		return dc, nil
+	}
+	prefixes := make([]string, 0, len(o.Mapping))
+	for prefix := range o.Mapping {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return o.Mapping[prefix], nil
+		}
+	}
+	return "", fmt.Errorf("%q has no DC in the mapping", value)
+}
+
+func (o Options) nodeZone(ctx context.Context) (string, error) {
+	if o.NodeZone == nil {
+		return "", errors.New("not running in Kubernetes")
+	}
+	return o.NodeZone(ctx)
+}
+
+// awsZone asks the instance metadata endpoint with a session token, as required by IMDSv2
+func (o Options) awsZone(ctx context.Context) (string, error) {
+	base := strings.TrimSuffix(firstNonEmpty(o.AWSURL, DefaultAWSMetadataURL), "/")
+	token, err := o.metadata(ctx, http.MethodPut, base+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return "", err
+	}
+	return o.metadata(ctx, http.MethodGet, base+"/latest/meta-data/placement/availability-zone",
+		map[string]string{"X-aws-ec2-metadata-token": token})
+}
+
+// gcpZone asks the metadata server, which reports zones as projects/<number>/zones/<zone>
+func (o Options) gcpZone(ctx context.Context) (string, error) {
+	base := strings.TrimSuffix(firstNonEmpty(o.GCPURL, DefaultGCPMetadataURL), "/")
+	zone, err := o.metadata(ctx, http.MethodGet, base+"/computeMetadata/v1/instance/zone",
+		map[string]string{"Metadata-Flavor": "Google"})
+	if err != nil {
+		return "", err
+	}
+	return zone[strings.LastIndex(zone, "/")+1:], nil
+}
+
+func (o Options) metadata(ctx context.Context, method, url string, headers map[string]string) (string, error) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	request = request.WithContext(ctx)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	httpClient := o.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: metadataTimeout}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("metadata endpoint unavailable: %s", err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata endpoint returned %s", response.Status)
+	}
+	value := strings.TrimSpace(string(body))
+	if value == "" {
+		return "", errors.New("metadata endpoint returned no value")
+	}
+	return value, nil
+}
+
+func (o Options) hostnameValue() (string, error) {
+	if o.HostnamePattern == nil {
+		return "", errors.New("no hostname pattern")
+	}
+	hostname := o.Hostname
+	if hostname == nil {
+		hostname = os.Hostname
+	}
+	name, err := hostname()
+	if err != nil {
+		return "", err
+	}
+
+	match := o.HostnamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", fmt.Errorf("hostname %q does not match %s", name, o.HostnamePattern)
+	}
+	for i, group := range o.HostnamePattern.SubexpNames() {
+		if group == "dc" && match[i] != "" {
+			return match[i], nil
+		}
+	}
+	for _, group := range match[1:] {
+		if group != "" {
+			return group, nil
+		}
+	}
+	return match[0], nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
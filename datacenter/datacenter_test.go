@@ -0,0 +1,101 @@
+package datacenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferMapsNodeZoneByLongestPrefix(t *testing.T) {
+	dc, err := Infer(context.Background(), Options{
+		Sources: []string{SourceNode},
+		Mapping: map[string]string{"europe": "dc0", "europe-west1": "dc1", "europe-west1-c": "dc2"},
+		NodeZone: func(ctx context.Context) (string, error) {
+			return "europe-west1-b", nil
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "dc1", dc)
+}
+
+func TestInferTriesSourcesInOrder(t *testing.T) {
+	dc, err := Infer(context.Background(), Options{
+		Sources: []string{SourceNode, SourceHostname},
+		NodeZone: func(ctx context.Context) (string, error) {
+			return "", errors.New("forbidden")
+		},
+		HostnamePattern: regexp.MustCompile(`^web-\d+\.(?P<dc>[a-z0-9]+)\.`),
+		Hostname:        func() (string, error) { return "web-12.dc3.example.com", nil },
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "dc3", dc)
+}
+
+func TestInferFailsWhenNoSourceTellsDC(t *testing.T) {
+	_, err := Infer(context.Background(), Options{
+		Sources:         []string{SourceNode, SourceHostname},
+		Mapping:         map[string]string{"dc1": "dc1"},
+		HostnamePattern: regexp.MustCompile(`\.(dc\d)\.`),
+		Hostname:        func() (string, error) { return "web-12.dc3.example.com", nil },
+	})
+
+	require.True(t, errors.Is(err, ErrNotInferred), err)
+	assert.EqualError(t, err, `could not infer DC: node: not running in Kubernetes; hostname: "dc3" has no DC in the mapping`)
+}
+
+func TestInferTakesAWSAvailabilityZoneWithSessionToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			assert.Equal(t, "60", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			_, _ = w.Write([]byte("token"))
+		case r.URL.Path == "/latest/meta-data/placement/availability-zone" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+			_, _ = w.Write([]byte("eu-central-1a"))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	dc, err := Infer(context.Background(), Options{
+		Sources: []string{SourceAWS},
+		Mapping: map[string]string{"eu-central-1": "fra"},
+		AWSURL:  server.URL,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "fra", dc)
+}
+
+func TestInferTakesGCPZone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/zone" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("projects/123456/zones/europe-west1-b"))
+	}))
+	defer server.Close()
+
+	dc, err := Infer(context.Background(), Options{Sources: []string{SourceGCP}, GCPURL: server.URL})
+
+	require.NoError(t, err)
+	assert.Equal(t, "europe-west1-b", dc)
+}
+
+func TestParseMappingRejectsEntriesWithoutDC(t *testing.T) {
+	mapping, err := ParseMapping([]string{"europe-west1=dc1", " us-east1 = dc2 "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"europe-west1": "dc1", "us-east1": "dc2"}, mapping)
+
+	_, err = ParseMapping([]string{"europe-west1="})
+	assert.Error(t, err)
+}
@@ -0,0 +1,284 @@
+// Package docker lists and watches containers of a Docker engine through its API, so that containers carrying
+// VaaS labels can be registered as they start and deregistered as they stop.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LabelDirector marks containers registered by the hook, listing their directors separated by commas
+	LabelDirector = "vaas.director"
+	// LabelPort selects the container port registered by the hook, the only exposed port when it is not set
+	LabelPort = "vaas.port"
+	// LabelDC is the DC of the backend of the container
+	LabelDC = "vaas.dc"
+	// LabelWeight is the weight of the backend of the container
+	LabelWeight = "vaas.weight"
+
+	// DefaultHost is the socket the Docker engine listens on by default
+	DefaultHost = "unix:///var/run/docker.sock"
+	// apiVersion of the Docker engine API, supported since Docker 1.12
+	apiVersion = "v1.24"
+	// requestTimeout limits requests to the Docker engine other than streaming events
+	requestTimeout = 30 * time.Second
+)
+
+// Actions of container events
+const (
+	EventStart = "start"
+	EventDie   = "die"
+)
+
+// Port is a port exposed by a container, published on the host when PublicPort is set
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+// Network is a network a container is attached to
+type Network struct {
+	IPAddress string `json:"IPAddress"`
+}
+
+// Container is a running container as listed by the Docker engine API
+type Container struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	Ports           []Port            `json:"Ports"`
+	NetworkSettings struct {
+		Networks map[string]Network `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// Name returns the name of the container, its short ID when it has none
+func (c Container) Name() string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+// Values returns labels of the container and its ContainerName, for director templates
+func (c Container) Values() map[string]string {
+	values := map[string]string{}
+	for key, value := range c.Labels {
+		values[key] = value
+	}
+	values["ContainerName"] = c.Name()
+	return values
+}
+
+// Endpoint resolves the address and port of the container port selected by the vaas.port label. A port published
+// on all interfaces of the host is served at hostAddress, an unpublished one at the IP of the container.
+func (c Container) Endpoint(hostAddress string) (string, int, error) {
+	port, err := c.registeredPort()
+	if err != nil {
+		return "", 0, err
+	}
+	for _, exposed := range c.Ports {
+		if exposed.PrivatePort != port || exposed.PublicPort == 0 || exposed.Type == "udp" {
+			continue
+		}
+		if exposed.IP != "" && exposed.IP != "0.0.0.0" && exposed.IP != "::" {
+			return exposed.IP, exposed.PublicPort, nil
+		}
+		if hostAddress == "" {
+			return "", 0, fmt.Errorf("container %s publishes port %d on the host, whose address is unknown", c.Name(), port)
+		}
+		return hostAddress, exposed.PublicPort, nil
+	}
+
+	networks := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	for _, name := range networks {
+		if address := c.NetworkSettings.Networks[name].IPAddress; address != "" {
+			return address, port, nil
+		}
+	}
+	return "", 0, fmt.Errorf("container %s has no IP", c.Name())
+}
+
+func (c Container) registeredPort() (int, error) {
+	if value, ok := c.Labels[LabelPort]; ok {
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 {
+			return 0, fmt.Errorf("invalid %s label of container %s: %q", LabelPort, c.Name(), value)
+		}
+		return port, nil
+	}
+	ports := map[int]bool{}
+	for _, exposed := range c.Ports {
+		if exposed.Type != "udp" {
+			ports[exposed.PrivatePort] = true
+		}
+	}
+	if len(ports) != 1 {
+		return 0, fmt.Errorf("container %s exposes %d ports, select one with the %s label", c.Name(), len(ports), LabelPort)
+	}
+	for port := range ports {
+		return port, nil
+	}
+	return 0, nil
+}
+
+// Event is a change of a container reported by the Docker engine
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// EventStream returns events one by one
+type EventStream interface {
+	// Next blocks until an event happens and returns it, io.EOF once the stream ends
+	Next() (Event, error)
+	Close() error
+}
+
+// ContainerSource lists and watches containers
+type ContainerSource interface {
+	// ListContainers returns running containers labeled with LabelDirector, only the one with id if it is set
+	ListContainers(ctx context.Context, id string) ([]Container, error)
+	// Events streams start and die events of containers labeled with LabelDirector between since and until
+	Events(ctx context.Context, since, until time.Time) (EventStream, error)
+}
+
+// NewClient returns a ContainerSource using the Docker engine API at host, e.g. unix:///var/run/docker.sock or
+// tcp://docker.example.com:2375, DefaultHost when empty
+func NewClient(host string) (ContainerSource, error) {
+	if host == "" {
+		host = DefaultHost
+	}
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %q: %s", host, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &client{http: &http.Client{Transport: transport}}
+	switch hostURL.Scheme {
+	case "unix":
+		socket := hostURL.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}
+		client.base = "http://docker"
+	case "tcp", "http":
+		client.base = "http://" + hostURL.Host
+	case "https":
+		client.base = "https://" + hostURL.Host
+	default:
+		return nil, fmt.Errorf("invalid Docker host %q, expected one like %s or tcp://docker.example.com:2375", host, DefaultHost)
+	}
+	client.base += "/" + apiVersion
+	return client, nil
+}
+
+type client struct {
+	http *http.Client
+	base string
+}
+
+// ListContainers returns running containers labeled with LabelDirector
+func (c *client) ListContainers(ctx context.Context, id string) ([]Container, error) {
+	filters := map[string][]string{"label": {LabelDirector}, "status": {"running"}}
+	if id != "" {
+		filters["id"] = []string{id}
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	response, err := c.get(ctx, "/containers/json", filters, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %s", err)
+	}
+	defer response.Body.Close()
+
+	var containers []Container
+	if err := json.NewDecoder(response.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("unable to decode containers: %s", err)
+	}
+	return containers, nil
+}
+
+// Events streams start and die events of containers labeled with LabelDirector
+func (c *client) Events(ctx context.Context, since, until time.Time) (EventStream, error) {
+	filters := map[string][]string{
+		"type":  {"container"},
+		"event": {EventStart, EventDie},
+		"label": {LabelDirector},
+	}
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(since.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	response, err := c.get(ctx, "/events", filters, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to stream events: %s", err)
+	}
+	return &eventStream{body: response.Body, decoder: json.NewDecoder(response.Body)}, nil
+}
+
+func (c *client) get(ctx context.Context, path string, filters map[string][]string, query url.Values) (*http.Response, error) {
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("filters", string(encoded))
+	request, err := http.NewRequest(http.MethodGet, c.base+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.http.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		message, _ := ioutil.ReadAll(response.Body)
+		return nil, fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return response, nil
+}
+
+type eventStream struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+// Next returns the next event
+func (s *eventStream) Next() (Event, error) {
+	var event Event
+	err := s.decoder.Decode(&event)
+	return event, err
+}
+
+// Close ends the stream
+func (s *eventStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testContainer() Container {
+	container := Container{
+		ID:     "0123456789abcdef",
+		Names:  []string{"/web"},
+		Labels: map[string]string{LabelDirector: "director", LabelPort: "80"},
+		Ports:  []Port{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"}},
+	}
+	container.NetworkSettings.Networks = map[string]Network{"bridge": {IPAddress: "172.17.0.2"}}
+	return container
+}
+
+func TestEndpointOfPublishedPortIsOnHost(t *testing.T) {
+	address, port, err := testContainer().Endpoint("192.168.0.10")
+
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.0.10", address)
+	assert.Equal(t, 8080, port)
+
+	_, _, err = testContainer().Endpoint("")
+	assert.Error(t, err)
+}
+
+func TestEndpointOfUnpublishedPortIsOnContainerIP(t *testing.T) {
+	container := testContainer()
+	container.Ports = []Port{{PrivatePort: 80, Type: "tcp"}}
+	delete(container.Labels, LabelPort)
+
+	address, port, err := container.Endpoint("192.168.0.10")
+
+	require.NoError(t, err)
+	assert.Equal(t, "172.17.0.2", address)
+	assert.Equal(t, 80, port)
+}
+
+func TestEndpointRequiresPortLabelOfContainerExposingMany(t *testing.T) {
+	container := testContainer()
+	container.Ports = append(container.Ports, Port{PrivatePort: 443, Type: "tcp"})
+	delete(container.Labels, LabelPort)
+
+	_, _, err := container.Endpoint("192.168.0.10")
+
+	assert.EqualError(t, err, "container web exposes 2 ports, select one with the vaas.port label")
+}
+
+func TestClientListsLabeledContainersAndStreamsEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		assert.NoError(t, json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters))
+		assert.Equal(t, []string{LabelDirector}, filters["label"])
+		switch r.URL.Path {
+		case "/v1.24/containers/json":
+			assert.Equal(t, []string{"running"}, filters["status"])
+			_ = json.NewEncoder(w).Encode([]Container{testContainer()})
+		case "/v1.24/events":
+			assert.Equal(t, []string{EventStart, EventDie}, filters["event"])
+			assert.NotEmpty(t, r.URL.Query().Get("until"))
+			_, _ = w.Write([]byte(`{"Type": "container", "Action": "start", "Actor": {"ID": "0123"}}
+{"Type": "container", "Action": "die", "Actor": {"ID": "0123"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient("tcp://" + strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+
+	containers, err := client.ListContainers(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	assert.Equal(t, "web", containers[0].Name())
+
+	events, err := client.Events(context.Background(), time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	defer events.Close()
+	var actions []string
+	for {
+		event, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{EventStart, EventDie}, actions)
+}
+
+func TestNewClientRejectsUnknownScheme(t *testing.T) {
+	_, err := NewClient("ftp://docker.example.com")
+
+	assert.Error(t, err)
+}
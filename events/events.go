@@ -0,0 +1,148 @@
+// Package events notifies webhooks, such as deploy dashboards or Slack channels, of backends being registered in
+// and deregistered from VaaS.
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Types of events.
+const (
+	Registered   = "registered"
+	Deregistered = "deregistered"
+	Failed       = "failed"
+)
+
+// DefaultTimeout limits every notification request
+const DefaultTimeout = 10 * time.Second
+
+// Event is a change of VaaS membership of a backend, or a failure to change it
+type Event struct {
+	Type     string    `json:"type"`
+	Action   string    `json:"action"`
+	Director string    `json:"director"`
+	Address  string    `json:"address"`
+	Port     int       `json:"port"`
+	Error    string    `json:"error,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Notifier delivers events
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Notifiers delivers events to every notifier, even if another fails
+type Notifiers []Notifier
+
+// Notify delivers event to every notifier, returning their failures together
+func (n Notifiers) Notify(ctx context.Context, event Event) error {
+	var messages []string
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, event); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%d of %d notifications failed: %s", len(messages), len(n), strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// Webhook POSTs events as JSON to a URL
+type Webhook struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhook creates a Webhook POSTing events to url
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, httpClient: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// Notify POSTs event as JSON
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	return post(ctx, w.httpClient, w.url, event)
+}
+
+// Slack POSTs events as messages to a Slack-compatible incoming webhook, e.g. of Slack or Mattermost
+type Slack struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewSlack creates a Slack notifier posting messages to the incoming webhook at url
+func NewSlack(url string) *Slack {
+	return &Slack{url: url, httpClient: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// slackMessage is the body of incoming webhook requests
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify posts event as a message
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	return post(ctx, s.httpClient, s.url, slackMessage{Text: Text(event)})
+}
+
+// Text describes event in a sentence, e.g. for chat messages
+func Text(event Event) string {
+	backend := fmt.Sprintf("%s:%d", event.Address, event.Port)
+	if strings.Contains(event.Address, ":") {
+		backend = fmt.Sprintf("[%s]:%d", event.Address, event.Port)
+	}
+	var text string
+	switch event.Type {
+	case Registered:
+		text = fmt.Sprintf("Backend %s registered in director %s", backend, event.Director)
+	case Deregistered:
+		text = fmt.Sprintf("Backend %s deregistered from director %s", backend, event.Director)
+	default:
+		text = fmt.Sprintf("Failed to %s backend %s in director %s: %s", event.Action, backend, event.Director, event.Error)
+	}
+	if event.Source != "" {
+		text += " by " + event.Source
+	}
+	return text
+}
+
+// post sends body to target as JSON. Errors name only the host of target, as paths of incoming webhooks are secret.
+func post(ctx context.Context, httpClient *http.Client, target string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if err != nil {
+		return fmt.Errorf("cannot notify %s: %w", request.URL.Host, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("cannot notify %s: %s: %s", request.URL.Host, response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookPostsEventAsJSON(t *testing.T) {
+	var received Event
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer ts.Close()
+	event := Event{Type: Registered, Action: "register", Director: "director", Address: "127.0.0.1", Port: 80,
+		Source: "host1", Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	require.NoError(t, NewWebhook(ts.URL).Notify(context.Background(), event))
+
+	assert.Equal(t, event, received)
+}
+
+func TestSlackPostsEventText(t *testing.T) {
+	var received slackMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer ts.Close()
+
+	err := NewSlack(ts.URL).Notify(context.Background(),
+		Event{Type: Failed, Action: "deregister", Director: "director", Address: "::1", Port: 80, Error: "timeout", Source: "host1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Failed to deregister backend [::1]:80 in director director: timeout by host1", received.Text)
+}
+
+func TestNotifyFailsOnErrorStatusWithoutRevealingPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	err := NewSlack(ts.URL+"/services/secret").Notify(context.Background(), Event{Type: Registered})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found: no such hook")
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestNotifiersNotifyAllDespiteFailures(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/failing" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	notifiers := Notifiers{NewWebhook(ts.URL + "/failing"), NewWebhook(ts.URL + "/ok")}
+
+	err := notifiers.Notify(context.Background(), Event{Type: Deregistered})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 2 notifications failed")
+	assert.Equal(t, 2, calls)
+}
+
+func TestTextDescribesEvents(t *testing.T) {
+	assert.Equal(t, "Backend 127.0.0.1:80 registered in director director",
+		Text(Event{Type: Registered, Director: "director", Address: "127.0.0.1", Port: 80}))
+	assert.Equal(t, "Backend 127.0.0.1:80 deregistered from director director by host1",
+		Text(Event{Type: Deregistered, Director: "director", Address: "127.0.0.1", Port: 80, Source: "host1"}))
+}
@@ -4,13 +4,11 @@ go 1.14
 
 require (
 	github.com/ericchiang/k8s v1.2.0
-	github.com/golang/protobuf v1.2.0 // indirect
-	github.com/sirupsen/logrus v1.3.0
-	github.com/stretchr/testify v1.3.0
+	github.com/prometheus/client_golang v1.5.1
+	github.com/sirupsen/logrus v1.4.2
+	github.com/stretchr/testify v1.4.0
 	github.com/urfave/cli v1.20.0
-	golang.org/x/crypto v0.0.0-20190123085648-057139ce5d2b // indirect
-	golang.org/x/net v0.0.0-20190119204137-ed066c81e75e // indirect
-	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a // indirect
-	golang.org/x/sys v0.0.0-20190123074212-c6b37f3e9285 // indirect
-	golang.org/x/text v0.3.0 // indirect
+	golang.org/x/net v0.0.0-20190613194153-d28f0bde5980
+	golang.org/x/sys v0.0.0-20200122134326-e047566fdf82
+	gopkg.in/yaml.v2 v2.4.0
 )
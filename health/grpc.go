@@ -0,0 +1,192 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// grpcHealthCheckPath is the method of the gRPC health checking protocol, grpc.health.v1.Health/Check
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// servingStatuses are names of grpc.health.v1.HealthCheckResponse.ServingStatus values
+var servingStatuses = map[uint64]string{0: "UNKNOWN", 1: "SERVING", 2: "NOT_SERVING", 3: "SERVICE_UNKNOWN"}
+
+const statusServing = 1
+
+// GRPC returns a probe succeeding once the gRPC health checking protocol at address reports service as SERVING.
+// An empty service checks the server as a whole. With useTLS the connection is encrypted, but like kubelet probes
+// the certificate is not verified.
+func GRPC(address, service string, useTLS bool) Probe {
+	return ProbeFunc(func(ctx context.Context) error {
+		conn, err := dialGRPC(ctx, address, useTLS)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		status, err := checkGRPC(ctx, conn, address, service, useTLS)
+		if err != nil {
+			return err
+		}
+		if status != statusServing {
+			return fmt.Errorf("gRPC service %q is %s", service, servingStatusName(status))
+		}
+		return nil
+	})
+}
+
+// dialGRPC connects to address, negotiating HTTP/2 with TLS when useTLS is set
+func dialGRPC(ctx context.Context, address string, useTLS bool) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil || !useTLS {
+		return conn, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{http2.NextProtoTLS}})
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// checkGRPC calls the health checking method over conn and returns the reported serving status
+func checkGRPC(ctx context.Context, conn net.Conn, address, service string, useTLS bool) (uint64, error) {
+	transport := &http2.Transport{AllowHTTP: !useTLS}
+	clientConn, err := transport.NewClientConn(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	url := scheme + "://" + address + grpcHealthCheckPath
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(grpcFrame(encodeHealthCheckRequest(service))))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+
+	response, err := clientConn.RoundTrip(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("gRPC health check responded with HTTP %d", response.StatusCode)
+	}
+
+	// Errors are reported in trailers, or in headers of responses without a body
+	status, message := response.Trailer.Get("Grpc-Status"), response.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = response.Header.Get("Grpc-Status"), response.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return 0, fmt.Errorf("gRPC health check failed with status %s: %s", status, message)
+	}
+
+	payload, err := grpcMessage(body)
+	if err != nil {
+		return 0, err
+	}
+	return decodeHealthCheckResponse(payload)
+}
+
+// encodeHealthCheckRequest encodes grpc.health.v1.HealthCheckRequest, whose field 1 is the service name
+func encodeHealthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	message := []byte{1<<3 | 2}
+	message = appendUvarint(message, uint64(len(service)))
+	return append(message, service...)
+}
+
+// decodeHealthCheckResponse decodes the status, field 1, of grpc.health.v1.HealthCheckResponse
+func decodeHealthCheckResponse(message []byte) (uint64, error) {
+	var status uint64
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, errors.New("malformed gRPC health check response")
+		}
+		message = message[n:]
+
+		switch key & 7 {
+		case 0:
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return 0, errors.New("malformed gRPC health check response")
+			}
+			message = message[n:]
+			if key>>3 == 1 {
+				status = value
+			}
+		case 2:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return 0, errors.New("malformed gRPC health check response")
+			}
+			message = message[n+int(length):]
+		default:
+			return 0, fmt.Errorf("unexpected wire type %d in gRPC health check response", key&7)
+		}
+	}
+	return status, nil
+}
+
+// grpcFrame prefixes message with the gRPC message header: no compression and the message length
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// grpcMessage returns the single message framed in body
+func grpcMessage(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("empty gRPC health check response")
+	}
+	if body[0] != 0 {
+		return nil, errors.New("compressed gRPC health check response")
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) < uint64(length) {
+		return nil, errors.New("truncated gRPC health check response")
+	}
+	return body[5 : 5+length], nil
+}
+
+func appendUvarint(data []byte, value uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(data, buf[:binary.PutUvarint(buf, value)]...)
+}
+
+func servingStatusName(status uint64) string {
+	if name, ok := servingStatuses[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", status)
+}
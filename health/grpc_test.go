@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// grpcHealthServer answers health checks of services with given serving statuses, unknown services with NOT_FOUND
+func grpcHealthServer(statuses map[string]uint64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != grpcHealthCheckPath || r.Header.Get("Content-Type") != "application/grpc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		message, _ := grpcMessage(body)
+		service := ""
+		if len(message) > 2 {
+			service = string(message[2:])
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write(grpcFrame([]byte{1 << 3, byte(status)}))
+		w.Header().Set("Grpc-Status", "0")
+	})
+}
+
+func TestGRPCProbe(t *testing.T) {
+	handler := grpcHealthServer(map[string]uint64{"": statusServing, "db": 2})
+	ts := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer ts.Close()
+	address := strings.TrimPrefix(ts.URL, "http://")
+
+	require.NoError(t, GRPC(address, "", false).Check(context.Background()))
+
+	err := GRPC(address, "db", false).Check(context.Background())
+	require.EqualError(t, err, `gRPC service "db" is NOT_SERVING`)
+
+	err = GRPC(address, "cache", false).Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 5: unknown service")
+}
+
+func TestGRPCProbeWithTLS(t *testing.T) {
+	ts := httptest.NewUnstartedServer(grpcHealthServer(map[string]uint64{"": statusServing}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	require.NoError(t, GRPC(strings.TrimPrefix(ts.URL, "https://"), "", true).Check(context.Background()))
+}
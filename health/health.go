@@ -0,0 +1,114 @@
+// Package health probes whether an application is ready to receive traffic before it is registered.
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Probe checks once whether an application responds.
+type Probe interface {
+	Check(ctx context.Context) error
+}
+
+// ProbeFunc adapts a function to Probe.
+type ProbeFunc func(ctx context.Context) error
+
+// Check implements Probe.
+func (f ProbeFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// TCP returns a probe succeeding once a TCP connection to address can be established.
+func TCP(address string) Probe {
+	return ProbeFunc(func(ctx context.Context) error {
+		dialer := &net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTP returns a probe succeeding once a GET request to url is answered with expectedStatus.
+func HTTP(url string, expectedStatus int) Probe {
+	client := &http.Client{}
+	return ProbeFunc(func(ctx context.Context) error {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+
+		if response.StatusCode != expectedStatus {
+			return fmt.Errorf("GET %s responded with HTTP %d, expected %d", url, response.StatusCode, expectedStatus)
+		}
+		return nil
+	})
+}
+
+// Exec returns a probe succeeding once command, run with /bin/sh -c, exits with status 0.
+func Exec(command string) Probe {
+	return ProbeFunc(func(ctx context.Context) error {
+		output, err := exec.CommandContext(ctx, "/bin/sh", "-c", command).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%q failed: %s: %s", command, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	})
+}
+
+// Policy describes how a probe is retried until it succeeds.
+type Policy struct {
+	// Attempts is the total number of checks made, at least one.
+	Attempts int
+	// Interval is the delay between checks.
+	Interval time.Duration
+	// Timeout limits a single check, if set.
+	Timeout time.Duration
+}
+
+// Wait checks probe until it succeeds, returning the error of the last check once policy attempts are used up.
+func Wait(ctx context.Context, probe Probe, policy Policy) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = check(ctx, probe, policy.Timeout); err == nil {
+			return nil
+		}
+		if attempt >= policy.Attempts {
+			return fmt.Errorf("not healthy after %d attempts: %s", attempt, err)
+		}
+
+		log.Debugf("Health check failed (attempt %d of %d), retrying in %s: %s", attempt, policy.Attempts, policy.Interval, err)
+		select {
+		case <-time.After(policy.Interval):
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for health check: %w", ctx.Err())
+		}
+	}
+}
+
+func check(ctx context.Context, probe Probe, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return probe.Check(ctx)
+}
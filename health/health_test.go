@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = Policy{Attempts: 3, Interval: time.Millisecond, Timeout: time.Second}
+
+func TestTCPProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+
+	require.NoError(t, TCP(address).Check(context.Background()))
+
+	require.NoError(t, listener.Close())
+	require.Error(t, TCP(address).Check(context.Background()))
+}
+
+func TestHTTPProbeExpectsStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	require.NoError(t, HTTP(ts.URL+"/ready", http.StatusOK).Check(context.Background()))
+
+	err := HTTP(ts.URL+"/", http.StatusOK).Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 503")
+}
+
+func TestExecProbe(t *testing.T) {
+	require.NoError(t, Exec("true").Check(context.Background()))
+
+	err := Exec("echo booting; exit 1").Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "booting")
+}
+
+func TestWaitRetriesUntilProbeSucceeds(t *testing.T) {
+	checks := 0
+	probe := ProbeFunc(func(ctx context.Context) error {
+		checks++
+		if checks < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+
+	require.NoError(t, Wait(context.Background(), probe, testPolicy))
+	assert.Equal(t, 3, checks)
+}
+
+func TestWaitGivesUpAfterAttempts(t *testing.T) {
+	checks := 0
+	probe := ProbeFunc(func(ctx context.Context) error {
+		checks++
+		return errors.New("down")
+	})
+
+	err := Wait(context.Background(), probe, testPolicy)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "down")
+	assert.Equal(t, 3, checks)
+}
+
+func TestWaitLimitsEachCheck(t *testing.T) {
+	probe := ProbeFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := Wait(context.Background(), probe, Policy{Attempts: 1, Timeout: 10 * time.Millisecond})
+
+	require.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+const (
+	// keyPortName selects a named container port to register
+	keyPortName = "vaasPortName"
+
+	// PodIPEnvVar holds status.podIP exposed through the downward API
+	PodIPEnvVar = "KUBERNETES_POD_IP"
+	// HostIPEnvVar holds status.hostIP exposed through the downward API
+	HostIPEnvVar = "KUBERNETES_HOST_IP"
+)
+
+// Endpoint is the address and port under which a Pod serves traffic
+type Endpoint struct {
+	Address string
+	Port    int
+}
+
+// GetPortName returns the name of the container port to register, empty for the first port
+func (pi PodInfo) GetPortName() string {
+	return pi.GetAnnotation(keyPortName)
+}
+
+// GetEndpoint resolves the address and port of the container port selected by the vaasPortName annotation.
+// Ports mapped to a hostPort are reachable at the node IP, others at the Pod IP.
+// IPs missing from the Pod status fall back to the downward API environment.
+func (pi PodInfo) GetEndpoint() (Endpoint, error) {
+	port, err := pi.findPort(pi.GetPortName())
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	if port.GetHostPort() > 0 {
+		return Endpoint{
+			Address: firstNonEmpty(pi.GetStatus().GetHostIP(), os.Getenv(HostIPEnvVar)),
+			Port:    int(port.GetHostPort()),
+		}, nil
+	}
+	return Endpoint{
+		Address: firstNonEmpty(pi.GetPodIP(), os.Getenv(PodIPEnvVar)),
+		Port:    int(port.GetContainerPort()),
+	}, nil
+}
+
+// findPort returns the container port with given name, or the first port when name is empty
+func (pi PodInfo) findPort(name string) (*corev1.ContainerPort, error) {
+	for _, container := range pi.GetSpec().GetContainers() {
+		for _, port := range container.GetPorts() {
+			if name == "" || port.GetName() == name {
+				return port, nil
+			}
+		}
+	}
+	if name == "" {
+		return nil, errors.New("pod has no container ports")
+	}
+	return nil, fmt.Errorf("pod has no container port named %q", name)
+}
+
+// DownwardAddress returns the Pod IP exposed through the downward API, empty outside of Kubernetes
+func DownwardAddress() string {
+	return os.Getenv(PodIPEnvVar)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
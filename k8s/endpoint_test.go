@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+func TestGetEndpointUsesNamedPort(t *testing.T) {
+	pod := endpointTestPod()
+	pod.Metadata.Annotations[keyPortName] = "admin"
+
+	endpoint, err := PodInfo{pod}.GetEndpoint()
+
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "10.0.0.2", Port: 9090}, endpoint)
+}
+
+func TestGetEndpointUsesHostPortMapping(t *testing.T) {
+	pod := endpointTestPod()
+	pod.Metadata.Annotations[keyPortName] = "http"
+
+	endpoint, err := PodInfo{pod}.GetEndpoint()
+
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "192.168.0.1", Port: 31080}, endpoint)
+}
+
+func TestGetEndpointFallsBackToDownwardAPI(t *testing.T) {
+	pod := endpointTestPod()
+	pod.Status = &corev1.PodStatus{}
+	pod.Metadata.Annotations[keyPortName] = "admin"
+	require.NoError(t, os.Setenv(PodIPEnvVar, "10.0.0.3"))
+	defer os.Unsetenv(PodIPEnvVar)
+
+	endpoint, err := PodInfo{pod}.GetEndpoint()
+
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3", endpoint.Address)
+}
+
+func TestGetEndpointFailsOnUnknownPortName(t *testing.T) {
+	pod := endpointTestPod()
+	pod.Metadata.Annotations[keyPortName] = "missing"
+
+	_, err := PodInfo{pod}.GetEndpoint()
+
+	require.EqualError(t, err, `pod has no container port named "missing"`)
+}
+
+func endpointTestPod() *corev1.Pod {
+	pod := testPod()
+	podIP, hostIP := "10.0.0.2", "192.168.0.1"
+	pod.Status = &corev1.PodStatus{PodIP: &podIP, HostIP: &hostIP}
+
+	httpName, adminName := "http", "admin"
+	httpPort, hostPort, adminPort := int32(8080), int32(31080), int32(9090)
+	pod.Spec.Containers = []*corev1.Container{
+		{
+			Ports: []*corev1.ContainerPort{
+				{Name: &httpName, ContainerPort: &httpPort, HostPort: &hostPort},
+				{Name: &adminName, ContainerPort: &adminPort},
+			},
+		},
+	}
+	return pod
+}
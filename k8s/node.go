@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+// NodeNameEnvVar names the Node of the Pod, exposed through the downward API from spec.nodeName
+const NodeNameEnvVar = "KUBERNETES_NODE_NAME"
+
+// Topology labels of Nodes, in order of precedence
+var (
+	zoneLabels   = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+	regionLabels = []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"}
+)
+
+// GetNodeName returns the name of the Node the Pod is scheduled on
+func (pi PodInfo) GetNodeName() string {
+	return pi.GetSpec().GetNodeName()
+}
+
+// GetNodeLabels returns labels of the Node with given name, or of the Node given by NodeNameEnvVar when empty
+func GetNodeLabels(ctx context.Context, name string) (map[string]string, error) {
+	if name == "" {
+		name = os.Getenv(NodeNameEnvVar)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no Node name, expose spec.nodeName as %s", NodeNameEnvVar)
+	}
+	k8sClient, err := k8s.NewInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &corev1.Node{}
+	if err := k8sClient.Get(ctx, "", name, node); err != nil {
+		return nil, fmt.Errorf("unable to get node %s from API: %s", name, err)
+	}
+	return node.GetMetadata().GetLabels(), nil
+}
+
+// NodeZone returns the zone of a Node with given labels, or its region when it has no zone label
+func NodeZone(labels map[string]string) (string, error) {
+	for _, label := range append(append([]string{}, zoneLabels...), regionLabels...) {
+		if value := labels[label]; value != "" {
+			return value, nil
+		}
+	}
+	return "", errors.New("node has no topology zone or region label")
+}
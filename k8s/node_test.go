@@ -0,0 +1,24 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeZonePrefersZoneOverRegion(t *testing.T) {
+	zone, err := NodeZone(map[string]string{
+		"topology.kubernetes.io/region":          "europe-west1",
+		"failure-domain.beta.kubernetes.io/zone": "europe-west1-b",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "europe-west1-b", zone)
+}
+
+func TestNodeZoneFailsWithoutTopologyLabels(t *testing.T) {
+	_, err := NodeZone(map[string]string{"kubernetes.io/hostname": "node1"})
+
+	assert.Error(t, err)
+}
@@ -16,6 +16,8 @@ const (
 	keyWeight   = "podWeight"
 	keyVaaSUser = "vaasUser"
 	keyVaaSURL  = "vaasUrl"
+
+	keyTimeProfile = "vaasTimeProfile"
 )
 
 // PodInfo describes a k8s Pod
@@ -122,6 +124,11 @@ func (pi PodInfo) GetVaaSUser() string {
 	return pi.GetAnnotation(keyVaaSUser)
 }
 
+// GetTimeProfile returns the name of a VaaS time profile applied to the backend
+func (pi PodInfo) GetTimeProfile() string {
+	return pi.GetAnnotation(keyTimeProfile)
+}
+
 // GetPodIP returns a Pod IP address
 func (pi PodInfo) GetPodIP() string {
 	return pi.GetStatus().GetPodIP()
@@ -142,6 +149,40 @@ func (pi PodInfo) GetName() string {
 	return pi.Metadata.GetName()
 }
 
+// appNameLabels are Pod labels naming its application, in order of precedence
+var appNameLabels = []string{"app.kubernetes.io/name", "app"}
+
+// Values returns labels and annotations of the Pod, annotations overriding labels, for templates of names.
+// They also hold the Pod name as PodName, its namespace as Namespace, the environment annotation as Env and its
+// application name label as AppName, when set.
+func (pi PodInfo) Values() map[string]string {
+	values := map[string]string{}
+	for key, value := range pi.GetMetadata().GetLabels() {
+		values[key] = value
+	}
+	for key, value := range pi.GetMetadata().GetAnnotations() {
+		values[key] = value
+	}
+
+	known := map[string]string{
+		"PodName":   pi.GetName(),
+		"Namespace": pi.GetMetadata().GetNamespace(),
+		"Env":       pi.GetAnnotation(keyEnv),
+	}
+	for _, label := range appNameLabels {
+		if name := pi.GetMetadata().GetLabels()[label]; name != "" {
+			known["AppName"] = name
+			break
+		}
+	}
+	for key, value := range known {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
 // GetPodInfo fetches k8s PodInfo for the current Pod
 func GetPodInfo() (*PodInfo, error) {
 	ctx := context.Background()
@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesHoldLabelsAnnotationsAndPodData(t *testing.T) {
+	pod := testPod()
+	name, namespace := "shop-1234", "prod"
+	pod.Metadata.Name, pod.Metadata.Namespace = &name, &namespace
+	pod.Metadata.Labels["app"] = "legacy-name"
+	pod.Metadata.Labels["app.kubernetes.io/name"] = "shop"
+	pod.Metadata.Labels["team"] = "labels"
+	pod.Metadata.Annotations["team"] = "annotations"
+	pod.Metadata.Annotations[keyEnv] = "production"
+
+	values := PodInfo{pod}.Values()
+
+	require.Equal(t, "shop", values["AppName"])
+	require.Equal(t, "production", values["Env"])
+	require.Equal(t, "shop-1234", values["PodName"])
+	require.Equal(t, "prod", values["Namespace"])
+	require.Equal(t, "annotations", values["team"])
+	require.Equal(t, "legacy-name", values["app"])
+}
+
+func TestValuesOmitMissingPodData(t *testing.T) {
+	values := PodInfo{testPod()}.Values()
+
+	_, ok := values["Env"]
+	require.False(t, ok)
+	_, ok = values["AppName"]
+	require.False(t, ok)
+}
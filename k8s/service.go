@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+// EndpointsSource lists where Services serve traffic
+type EndpointsSource interface {
+	// ListServiceEndpoints returns addresses and ports of the Endpoints of service in namespace, ready or not.
+	// Ports of Pods mapped to a hostPort are returned at the node IP as well, as registration uses those.
+	ListServiceEndpoints(ctx context.Context, namespace, service string) ([]Endpoint, error)
+}
+
+// NewEndpointsSource returns an EndpointsSource using the Kubernetes API of the cluster the hook runs in
+func NewEndpointsSource() (EndpointsSource, error) {
+	k8sClient, err := k8s.NewInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+	return &endpointsSource{k8sClient: k8sClient}, nil
+}
+
+type endpointsSource struct {
+	k8sClient *k8s.Client
+}
+
+// ListServiceEndpoints returns addresses and ports of service, along with hostPorts of its Pods
+func (s *endpointsSource) ListServiceEndpoints(ctx context.Context, namespace, service string) ([]Endpoint, error) {
+	endpoints := &corev1.Endpoints{}
+	if err := s.k8sClient.Get(ctx, namespace, service, endpoints); err != nil {
+		return nil, fmt.Errorf("unable to get endpoints of service %s: %s", service, err)
+	}
+
+	pods := map[string]*corev1.Pod{}
+	for _, subset := range endpoints.GetSubsets() {
+		for _, address := range append(subset.GetAddresses(), subset.GetNotReadyAddresses()...) {
+			name := address.GetTargetRef().GetName()
+			if address.GetTargetRef().GetKind() != "Pod" || pods[name] != nil {
+				continue
+			}
+			pod := &corev1.Pod{}
+			if err := s.k8sClient.Get(ctx, namespace, name, pod); err != nil {
+				return nil, fmt.Errorf("unable to get pod %s of service %s: %s", name, service, err)
+			}
+			pods[name] = pod
+		}
+	}
+	return endpointsOf(endpoints, pods), nil
+}
+
+// endpointsOf returns every address of endpoints, not ready ones included as their Pods still exist, with every port.
+// Ports which containers of pods, by name, map to a hostPort are also returned at the node IP of the Pod.
+func endpointsOf(endpoints *corev1.Endpoints, pods map[string]*corev1.Pod) []Endpoint {
+	var result []Endpoint
+	for _, subset := range endpoints.GetSubsets() {
+		addresses := append(subset.GetAddresses(), subset.GetNotReadyAddresses()...)
+		for _, address := range addresses {
+			pod := pods[address.GetTargetRef().GetName()]
+			for _, port := range subset.GetPorts() {
+				result = append(result, Endpoint{Address: address.GetIp(), Port: int(port.GetPort())})
+				if hostPort := hostPortOf(pod, port.GetPort()); hostPort > 0 && pod.GetStatus().GetHostIP() != "" {
+					result = append(result, Endpoint{Address: pod.GetStatus().GetHostIP(), Port: int(hostPort)})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// hostPortOf returns the hostPort containers of pod map containerPort to, 0 when none does or pod is nil
+func hostPortOf(pod *corev1.Pod, containerPort int32) int32 {
+	for _, container := range pod.GetSpec().GetContainers() {
+		for _, port := range container.GetPorts() {
+			if port.GetContainerPort() == containerPort && port.GetHostPort() > 0 {
+				return port.GetHostPort()
+			}
+		}
+	}
+	return 0
+}
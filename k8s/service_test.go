@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+func TestEndpointsOfIncludesNotReadyAddresses(t *testing.T) {
+	ready, notReady := "10.0.0.1", "10.0.0.2"
+	httpPort, adminPort := int32(8080), int32(9090)
+	endpoints := &corev1.Endpoints{Subsets: []*corev1.EndpointSubset{{
+		Addresses:         []*corev1.EndpointAddress{{Ip: &ready}},
+		NotReadyAddresses: []*corev1.EndpointAddress{{Ip: &notReady}},
+		Ports:             []*corev1.EndpointPort{{Port: &httpPort}, {Port: &adminPort}},
+	}}}
+
+	require.Equal(t, []Endpoint{
+		{Address: "10.0.0.1", Port: 8080},
+		{Address: "10.0.0.1", Port: 9090},
+		{Address: "10.0.0.2", Port: 8080},
+		{Address: "10.0.0.2", Port: 9090},
+	}, endpointsOf(endpoints, nil))
+}
+
+func TestEndpointsOfIncludesHostPortsOfPods(t *testing.T) {
+	podIP, hostIP, podName, kind := "10.0.0.1", "192.168.0.1", "web-1", "Pod"
+	containerPort, hostPort := int32(8080), int32(31080)
+	endpoints := &corev1.Endpoints{Subsets: []*corev1.EndpointSubset{{
+		Addresses: []*corev1.EndpointAddress{{Ip: &podIP, TargetRef: &corev1.ObjectReference{Kind: &kind, Name: &podName}}},
+		Ports:     []*corev1.EndpointPort{{Port: &containerPort}},
+	}}}
+	pod := &corev1.Pod{
+		Spec: &corev1.PodSpec{Containers: []*corev1.Container{{
+			Ports: []*corev1.ContainerPort{{ContainerPort: &containerPort, HostPort: &hostPort}},
+		}}},
+		Status: &corev1.PodStatus{HostIP: &hostIP},
+	}
+
+	require.Equal(t, []Endpoint{
+		{Address: "10.0.0.1", Port: 8080},
+		{Address: "192.168.0.1", Port: 31080},
+	}, endpointsOf(endpoints, map[string]*corev1.Pod{podName: pod}))
+}
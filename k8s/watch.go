@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+const (
+	// AnnotationDirector marks Pods registered by the controller, listing their directors separated by commas
+	AnnotationDirector = "vaas.register/director"
+	// AnnotationPort selects the container port registered by the controller, by number or name
+	AnnotationPort = "vaas.register/port"
+)
+
+// Types of Pod changes
+const (
+	EventAdded    = k8s.EventAdded
+	EventModified = k8s.EventModified
+	EventDeleted  = k8s.EventDeleted
+	EventError    = k8s.EventError
+)
+
+// PodWatcher returns changes of Pods one by one
+type PodWatcher interface {
+	// Next blocks until a Pod changes and returns the type of the change and the Pod
+	Next() (string, *PodInfo, error)
+	Close() error
+}
+
+// PodSource lists and watches Pods
+type PodSource interface {
+	// ListPods returns Pods of namespace, all namespaces when empty, and the version to watch them from
+	ListPods(ctx context.Context, namespace string) ([]*PodInfo, string, error)
+	// WatchPods watches Pods of namespace changed after resourceVersion, ending the watch after timeout
+	WatchPods(ctx context.Context, namespace, resourceVersion string, timeout time.Duration) (PodWatcher, error)
+}
+
+// NewPodSource returns a PodSource using the Kubernetes API of the cluster the hook runs in
+func NewPodSource() (PodSource, error) {
+	k8sClient, err := k8s.NewInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+	return &podSource{k8sClient: k8sClient}, nil
+}
+
+type podSource struct {
+	k8sClient *k8s.Client
+}
+
+// ListPods returns Pods of namespace
+func (s *podSource) ListPods(ctx context.Context, namespace string) ([]*PodInfo, string, error) {
+	list := &corev1.PodList{}
+	if err := s.k8sClient.List(ctx, namespace, list); err != nil {
+		return nil, "", fmt.Errorf("unable to list pods: %s", err)
+	}
+
+	pods := make([]*PodInfo, 0, len(list.GetItems()))
+	for _, pod := range list.GetItems() {
+		pods = append(pods, &PodInfo{pod})
+	}
+	return pods, list.GetMetadata().GetResourceVersion(), nil
+}
+
+// WatchPods watches Pods of namespace
+func (s *podSource) WatchPods(ctx context.Context, namespace, resourceVersion string, timeout time.Duration) (PodWatcher, error) {
+	watcher, err := s.k8sClient.Watch(ctx, namespace, &corev1.Pod{}, k8s.ResourceVersion(resourceVersion), k8s.Timeout(timeout))
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch pods: %s", err)
+	}
+	return &podWatcher{watcher: watcher}, nil
+}
+
+type podWatcher struct {
+	watcher *k8s.Watcher
+}
+
+// Next returns the next change of a Pod
+func (w *podWatcher) Next() (string, *PodInfo, error) {
+	pod := &corev1.Pod{}
+	eventType, err := w.watcher.Next(pod)
+	if err != nil {
+		return "", nil, err
+	}
+	return eventType, &PodInfo{pod}, nil
+}
+
+// Close ends the watch
+func (w *podWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// GetRegisterDirector returns directors of the Pod registered by the controller, empty when it is not
+func (pi PodInfo) GetRegisterDirector() string {
+	return pi.GetAnnotation(AnnotationDirector)
+}
+
+// IsReady tells whether the Pod is running, ready and not being deleted, so it can receive traffic
+func (pi PodInfo) IsReady() bool {
+	if pi.GetMetadata().GetDeletionTimestamp() != nil || pi.GetStatus().GetPhase() != "Running" {
+		return false
+	}
+	for _, condition := range pi.GetStatus().GetConditions() {
+		if condition.GetType() == "Ready" {
+			return condition.GetStatus() == "True"
+		}
+	}
+	return false
+}
+
+// GetRegisterEndpoint resolves the address and port of the container port selected by the vaas.register/port
+// annotation, the first port when it is not set. Unlike GetEndpoint it does not read the environment, which
+// belongs to the controller rather than the Pod, and a port number does not have to be declared by containers.
+func (pi PodInfo) GetRegisterEndpoint() (Endpoint, error) {
+	value := pi.GetAnnotation(AnnotationPort)
+	var port *corev1.ContainerPort
+	if number, err := strconv.Atoi(value); err == nil {
+		port = pi.findPortNumber(int32(number))
+	} else if port, err = pi.findPort(value); err != nil {
+		return Endpoint{}, err
+	}
+
+	endpoint := Endpoint{Address: pi.GetPodIP(), Port: int(port.GetContainerPort())}
+	if port.GetHostPort() > 0 {
+		endpoint = Endpoint{Address: pi.GetStatus().GetHostIP(), Port: int(port.GetHostPort())}
+	}
+	if endpoint.Address == "" {
+		return Endpoint{}, fmt.Errorf("pod %s has no IP", pi.GetName())
+	}
+	return endpoint, nil
+}
+
+// findPortNumber returns the container port with given number, served at the Pod IP when no container declares it
+func (pi PodInfo) findPortNumber(number int32) *corev1.ContainerPort {
+	for _, container := range pi.GetSpec().GetContainers() {
+		for _, port := range container.GetPorts() {
+			if port.GetContainerPort() == number {
+				return port
+			}
+		}
+	}
+	return &corev1.ContainerPort{ContainerPort: k8s.Int32(number)}
+}
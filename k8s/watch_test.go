@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+)
+
+func TestGetRegisterEndpointSelectsPortByNumberOrName(t *testing.T) {
+	pod := endpointTestPod()
+
+	pod.Metadata.Annotations[AnnotationPort] = "admin"
+	endpoint, err := PodInfo{pod}.GetRegisterEndpoint()
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "10.0.0.2", Port: 9090}, endpoint)
+
+	pod.Metadata.Annotations[AnnotationPort] = "8080"
+	endpoint, err = PodInfo{pod}.GetRegisterEndpoint()
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "192.168.0.1", Port: 31080}, endpoint)
+
+	pod.Metadata.Annotations[AnnotationPort] = "7000"
+	endpoint, err = PodInfo{pod}.GetRegisterEndpoint()
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "10.0.0.2", Port: 7000}, endpoint)
+}
+
+func TestGetRegisterEndpointRequiresPodIP(t *testing.T) {
+	pod := endpointTestPod()
+	pod.Status = &corev1.PodStatus{}
+	pod.Metadata.Annotations[AnnotationPort] = "admin"
+
+	_, err := PodInfo{pod}.GetRegisterEndpoint()
+
+	require.Error(t, err)
+}
+
+func TestIsReady(t *testing.T) {
+	running, ready, notReady := "Running", "Ready", "False"
+	trueStatus := "True"
+	pod := testPod()
+	require.False(t, PodInfo{pod}.IsReady())
+
+	pod.Status = &corev1.PodStatus{
+		Phase:      &running,
+		Conditions: []*corev1.PodCondition{{Type: &ready, Status: &trueStatus}},
+	}
+	require.True(t, PodInfo{pod}.IsReady())
+
+	pod.Metadata.DeletionTimestamp = &metav1.Time{}
+	require.False(t, PodInfo{pod}.IsReady())
+
+	pod.Metadata.DeletionTimestamp = nil
+	pod.Status.Conditions[0].Status = &notReady
+	require.False(t, PodInfo{pod}.IsReady())
+}
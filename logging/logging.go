@@ -0,0 +1,121 @@
+// Package logging configures log output of the hook and correlates log lines and VaaS requests of an operation.
+//
+// An operation, such as registering a backend, carries its correlation ID in its context. Lines logged with
+// log.WithContext(ctx) get the ID of ctx and others the default ID of the process, set with SetDefaultCorrelationID.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatText logs lines in logfmt
+	FormatText = "text"
+	// FormatJSON logs lines as JSON objects
+	FormatJSON = "json"
+
+	// FieldCorrelationID is the log field holding the correlation ID
+	FieldCorrelationID = "correlation_id"
+	// HeaderRequestID is the HTTP header carrying the correlation ID to VaaS
+	HeaderRequestID = "X-Request-ID"
+
+	// correlationIDLen is the number of random bytes of a correlation ID
+	correlationIDLen = 16
+)
+
+type correlationIDKey struct{}
+
+var (
+	installHook sync.Once
+	defaultIDMu sync.RWMutex
+	defaultID   string
+)
+
+// Configure sets the format, FormatText or FormatJSON, and the level of log lines,
+// and makes them carry correlation IDs
+func Configure(format, level string) error {
+	switch format {
+	case "", FormatText:
+		log.SetFormatter(&log.TextFormatter{QuoteEmptyFields: true})
+	case FormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid log format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+
+	if level != "" {
+		parsed, err := log.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		log.SetLevel(parsed)
+	}
+
+	installHook.Do(func() { log.AddHook(correlationHook{}) })
+	return nil
+}
+
+// NewCorrelationID returns a random correlation ID
+func NewCorrelationID() string {
+	id := make([]byte, correlationIDLen)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// WithCorrelationID returns ctx of an operation with given correlation ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of ctx, empty when it has none
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// SetDefaultCorrelationID sets the correlation ID of lines logged without one in their context
+func SetDefaultCorrelationID(id string) {
+	defaultIDMu.Lock()
+	defer defaultIDMu.Unlock()
+	defaultID = id
+}
+
+func defaultCorrelationID() string {
+	defaultIDMu.RLock()
+	defer defaultIDMu.RUnlock()
+	return defaultID
+}
+
+// correlationHook adds the correlation ID of their context, or the default one, to log lines
+type correlationHook struct{}
+
+// Levels implements log.Hook
+func (correlationHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements log.Hook
+func (correlationHook) Fire(entry *log.Entry) error {
+	if _, ok := entry.Data[FieldCorrelationID]; ok {
+		return nil
+	}
+	id := CorrelationID(entry.Context)
+	if id == "" {
+		id = defaultCorrelationID()
+	}
+	if id != "" {
+		entry.Data[FieldCorrelationID] = id
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureJSON(t *testing.T, logLine func()) map[string]interface{} {
+	require.NoError(t, Configure(FormatJSON, "info"))
+	var output bytes.Buffer
+	defer log.SetOutput(log.StandardLogger().Out)
+	log.SetOutput(&output)
+	defer SetDefaultCorrelationID("")
+
+	logLine()
+
+	line := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), &line), output.String())
+	return line
+}
+
+func TestLinesCarryCorrelationIDOfContext(t *testing.T) {
+	line := captureJSON(t, func() {
+		SetDefaultCorrelationID("process")
+		log.WithContext(WithCorrelationID(context.Background(), "operation")).Info("registered")
+	})
+
+	assert.Equal(t, "registered", line["msg"])
+	assert.Equal(t, "operation", line[FieldCorrelationID])
+}
+
+func TestLinesWithoutContextCarryDefaultCorrelationID(t *testing.T) {
+	line := captureJSON(t, func() {
+		SetDefaultCorrelationID("process")
+		log.Info("starting")
+	})
+
+	assert.Equal(t, "process", line[FieldCorrelationID])
+}
+
+func TestConfigureRejectsUnknownFormatAndLevel(t *testing.T) {
+	assert.Error(t, Configure("xml", ""))
+	assert.Error(t, Configure(FormatText, "loud"))
+	require.NoError(t, Configure(FormatText, "info"))
+}
+
+func TestNewCorrelationIDIsUnique(t *testing.T) {
+	id := NewCorrelationID()
+
+	assert.Len(t, id, 2*correlationIDLen)
+	assert.NotEqual(t, id, NewCorrelationID())
+}
@@ -0,0 +1,53 @@
+package mesos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// marathonTimeout limits requests to the Marathon API
+const marathonTimeout = 10 * time.Second
+
+// Task is a running task of a Marathon application as listed by the Marathon API
+type Task struct {
+	ID    string `json:"id"`
+	Host  string `json:"host"`
+	Ports []int  `json:"ports"`
+}
+
+type taskList struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// ListAppTasks returns tasks of the application with appID, e.g. /group/app, from Marathon at marathonURL,
+// e.g. http://marathon.example.com:8080. Credentials can be given in the URL.
+func ListAppTasks(ctx context.Context, marathonURL, appID string) ([]Task, error) {
+	url := fmt.Sprintf("%s/v2/apps/%s/tasks", strings.TrimSuffix(marathonURL, "/"), strings.Trim(appID, "/"))
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := (&http.Client{Timeout: marathonTimeout}).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tasks of %s: %s", appID, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(response.Body)
+		return nil, fmt.Errorf("unable to list tasks of %s: %s: %s", appID, response.Status, strings.TrimSpace(string(message)))
+	}
+	var list taskList
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to decode tasks of %s: %s", appID, err)
+	}
+	return list.Tasks, nil
+}
@@ -0,0 +1,30 @@
+package mesos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAppTasks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/apps/group/app/tasks" {
+			http.NotFound(w, r)
+			return
+		}
+		_, err := w.Write([]byte(`{"tasks": [{"id": "group_app.1", "host": "10.0.0.5", "ports": [31000, 31001]}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	tasks, err := ListAppTasks(context.Background(), ts.URL+"/", "/group/app")
+	require.NoError(t, err)
+	require.Equal(t, []Task{{ID: "group_app.1", Host: "10.0.0.5", Ports: []int{31000, 31001}}}, tasks)
+
+	_, err = ListAppTasks(context.Background(), ts.URL, "/missing")
+	require.EqualError(t, err, "unable to list tasks of /missing: 404 Not Found: 404 page not found")
+}
@@ -0,0 +1,179 @@
+// Package mesos reads backend data of a Marathon application from the environment of its Mesos task.
+package mesos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables set by Marathon
+const (
+	appIDEnvVar  = "MARATHON_APP_ID"
+	taskIDEnvVar = "MESOS_TASK_ID"
+	hostEnvVar   = "HOST"
+	portEnvVar   = "PORT"
+	labelPrefix  = "MARATHON_APP_LABEL_"
+)
+
+// Labels of a Marathon application read by the hook
+const (
+	// LabelDirector names the VaaS director, derived from the application ID when not set
+	LabelDirector = "VAAS_DIRECTOR"
+	// LabelPortName selects the port to register by name or index, the first port when not set
+	LabelPortName = "VAAS_PORT_NAME"
+	// LabelPortNames maps port names to indexes, e.g. "http=0,admin=1", for Marathon versions not setting PORT_<NAME>
+	LabelPortNames = "VAAS_PORT_NAMES"
+	// LabelWeight is the initial weight of the backend
+	LabelWeight = "VAAS_WEIGHT"
+	// LabelDC is the datacenter short name as defined in VaaS
+	LabelDC = "VAAS_DC"
+)
+
+// TaskInfo describes a Mesos task of a Marathon application
+type TaskInfo struct {
+	env map[string]string
+}
+
+// GetTaskInfo returns TaskInfo of the current task, failing when it does not run on Marathon
+func GetTaskInfo() (*TaskInfo, error) {
+	info := newTaskInfo(os.Environ())
+	if info.GetAppID() == "" {
+		return nil, fmt.Errorf("%s is not set", appIDEnvVar)
+	}
+	return info, nil
+}
+
+func newTaskInfo(environ []string) *TaskInfo {
+	env := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		if i := strings.Index(variable, "="); i > 0 {
+			env[variable[:i]] = variable[i+1:]
+		}
+	}
+	return &TaskInfo{env: env}
+}
+
+// GetAppID returns the Marathon application ID, e.g. /group/app
+func (ti TaskInfo) GetAppID() string {
+	return ti.env[appIDEnvVar]
+}
+
+// GetTaskID returns the Mesos task ID
+func (ti TaskInfo) GetTaskID() string {
+	return ti.env[taskIDEnvVar]
+}
+
+// GetLabel returns the application label with given name, empty when it is not set
+func (ti TaskInfo) GetLabel(name string) string {
+	return ti.env[labelPrefix+name]
+}
+
+// GetAddress returns the address of the agent running the task
+func (ti TaskInfo) GetAddress() string {
+	return ti.env[hostEnvVar]
+}
+
+// GetDirector returns the director of the VaaS director label, or the application ID with group separators
+// replaced by underscores, e.g. group_app for /group/app
+func (ti TaskInfo) GetDirector() string {
+	if director := ti.GetLabel(LabelDirector); director != "" {
+		return director
+	}
+	return strings.Replace(strings.Trim(ti.GetAppID(), "/"), "/", "_", -1)
+}
+
+// Values returns labels of the application, for templates of names. They also hold the application ID as AppID,
+// its last segment as AppName, e.g. app for /group/app, and the task ID as TaskID.
+func (ti TaskInfo) Values() map[string]string {
+	values := map[string]string{}
+	for variable, value := range ti.env {
+		if strings.HasPrefix(variable, labelPrefix) {
+			values[strings.TrimPrefix(variable, labelPrefix)] = value
+		}
+	}
+
+	appID := ti.GetAppID()
+	values["AppID"] = appID
+	values["AppName"] = appID[strings.LastIndex(appID, "/")+1:]
+	values["TaskID"] = ti.GetTaskID()
+	return values
+}
+
+// GetPort returns the host port selected by name, which is a port index or a port name,
+// or by the port name label when name is empty. Without either the first port is returned.
+func (ti TaskInfo) GetPort(name string) (int, error) {
+	if name == "" {
+		name = ti.GetLabel(LabelPortName)
+	}
+	if name == "" {
+		name = "0"
+	}
+
+	variable, err := ti.portVariable(name)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := ti.env[variable]
+	if !ok {
+		return 0, fmt.Errorf("task has no port %s, %s is not set", name, variable)
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", variable, value, err)
+	}
+	return port, nil
+}
+
+// portVariable returns the variable holding the port with given index or name
+func (ti TaskInfo) portVariable(name string) (string, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return portEnvVar + name, nil
+	}
+
+	variable := portEnvVar + "_" + strings.ToUpper(name)
+	if _, ok := ti.env[variable]; ok {
+		return variable, nil
+	}
+	index, err := ti.portIndex(name)
+	if err != nil {
+		return "", err
+	}
+	return portEnvVar + strconv.Itoa(index), nil
+}
+
+// portIndex looks up the index of the port with given name in the port names label
+func (ti TaskInfo) portIndex(name string) (int, error) {
+	mapping := ti.GetLabel(LabelPortNames)
+	for _, entry := range strings.Split(mapping, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] != name {
+			continue
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid index of port %s in %s label: %s", name, LabelPortNames, err)
+		}
+		return index, nil
+	}
+	if mapping == "" {
+		return 0, fmt.Errorf("task has no port named %q and no %s label", name, LabelPortNames)
+	}
+	return 0, fmt.Errorf("task has no port named %q", name)
+}
+
+// GetWeight returns the weight of the weight label
+func (ti TaskInfo) GetWeight() (int, error) {
+	weight := ti.GetLabel(LabelWeight)
+	if weight == "" {
+		return 0, errors.New("weight label is empty, label: " + LabelWeight)
+	}
+	return strconv.Atoi(weight)
+}
+
+// GetDataCenter returns the datacenter of the DC label
+func (ti TaskInfo) GetDataCenter() string {
+	return ti.GetLabel(LabelDC)
+}
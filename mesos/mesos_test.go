@@ -0,0 +1,61 @@
+package mesos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testTaskInfo(environ ...string) *TaskInfo {
+	return newTaskInfo(append([]string{
+		"MARATHON_APP_ID=/group/app",
+		"MESOS_TASK_ID=group_app.1234",
+		"HOST=10.0.0.5",
+		"PORT0=31000",
+		"PORT1=31001",
+	}, environ...))
+}
+
+func TestGetDirectorDefaultsToAppID(t *testing.T) {
+	require.Equal(t, "group_app", testTaskInfo().GetDirector())
+	require.Equal(t, "director1", testTaskInfo("MARATHON_APP_LABEL_VAAS_DIRECTOR=director1").GetDirector())
+}
+
+func TestGetPortByIndexOrName(t *testing.T) {
+	info := testTaskInfo("PORT_ADMIN=31001", "MARATHON_APP_LABEL_VAAS_PORT_NAMES=http=0, metrics=1")
+
+	for name, expected := range map[string]int{"": 31000, "1": 31001, "admin": 31001, "http": 31000, "metrics": 31001} {
+		port, err := info.GetPort(name)
+		require.NoError(t, err, name)
+		require.Equal(t, expected, port, name)
+	}
+
+	_, err := info.GetPort("missing")
+	require.EqualError(t, err, `task has no port named "missing"`)
+	_, err = info.GetPort("2")
+	require.EqualError(t, err, "task has no port 2, PORT2 is not set")
+}
+
+func TestGetPortUsesPortNameLabel(t *testing.T) {
+	port, err := testTaskInfo("MARATHON_APP_LABEL_VAAS_PORT_NAME=1").GetPort("")
+
+	require.NoError(t, err)
+	require.Equal(t, 31001, port)
+}
+
+func TestGetTaskInfoRequiresMarathon(t *testing.T) {
+	_, err := GetTaskInfo()
+
+	require.Error(t, err)
+}
+
+func TestValuesHoldLabelsAndTaskData(t *testing.T) {
+	values := testTaskInfo("MARATHON_APP_LABEL_ENV=prod").Values()
+
+	require.Equal(t, map[string]string{
+		"ENV":     "prod",
+		"AppID":   "/group/app",
+		"AppName": "app",
+		"TaskID":  "group_app.1234",
+	}, values)
+}
@@ -0,0 +1,170 @@
+// Package nomad reads backend data of a Nomad job from the environment of its allocation.
+package nomad
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment variables set by Nomad
+const (
+	allocIDEnvVar    = "NOMAD_ALLOC_ID"
+	allocIndexEnvVar = "NOMAD_ALLOC_INDEX"
+	jobNameEnvVar    = "NOMAD_JOB_NAME"
+	groupNameEnvVar  = "NOMAD_GROUP_NAME"
+	taskNameEnvVar   = "NOMAD_TASK_NAME"
+	namespaceEnvVar  = "NOMAD_NAMESPACE"
+	regionEnvVar     = "NOMAD_REGION"
+	dcEnvVar         = "NOMAD_DC"
+	ipPrefix         = "NOMAD_IP_"
+	hostPortPrefix   = "NOMAD_HOST_PORT_"
+	metaPrefix       = "NOMAD_META_"
+)
+
+// Meta keys of a Nomad job, group or task read by the hook
+const (
+	// MetaDirector names the VaaS director, the job name when not set
+	MetaDirector = "VAAS_DIRECTOR"
+	// MetaPort selects the label of the port to register, the only port of the allocation when not set
+	MetaPort = "VAAS_PORT"
+	// MetaWeight is the initial weight of the backend
+	MetaWeight = "VAAS_WEIGHT"
+	// MetaDC is the datacenter short name as defined in VaaS
+	MetaDC = "VAAS_DC"
+)
+
+// AllocInfo describes a Nomad allocation of a job
+type AllocInfo struct {
+	env map[string]string
+}
+
+// GetAllocInfo returns AllocInfo of the current allocation, failing when it does not run on Nomad
+func GetAllocInfo() (*AllocInfo, error) {
+	info := newAllocInfo(os.Environ())
+	if info.GetAllocID() == "" {
+		return nil, fmt.Errorf("%s is not set", allocIDEnvVar)
+	}
+	return info, nil
+}
+
+func newAllocInfo(environ []string) *AllocInfo {
+	env := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		if i := strings.Index(variable, "="); i > 0 {
+			env[variable[:i]] = variable[i+1:]
+		}
+	}
+	return &AllocInfo{env: env}
+}
+
+// GetAllocID returns the ID of the allocation
+func (ai AllocInfo) GetAllocID() string {
+	return ai.env[allocIDEnvVar]
+}
+
+// GetJobName returns the name of the job
+func (ai AllocInfo) GetJobName() string {
+	return ai.env[jobNameEnvVar]
+}
+
+// GetMeta returns the meta value with given key, empty when it is not set. Nomad sets meta values under their
+// key both as given and upper-cased, so keys are matched either way.
+func (ai AllocInfo) GetMeta(key string) string {
+	if value, ok := ai.env[metaPrefix+key]; ok {
+		return value
+	}
+	return ai.env[metaPrefix+strings.ToUpper(key)]
+}
+
+// GetDirector returns the director of the VaaS director meta value, or the job name
+func (ai AllocInfo) GetDirector() string {
+	if director := ai.GetMeta(MetaDirector); director != "" {
+		return director
+	}
+	return ai.GetJobName()
+}
+
+// Values returns meta values of the allocation, for templates of names. They also hold JobName, GroupName,
+// TaskName, AllocID, AllocIndex, Namespace, Region and Datacenter of the allocation.
+func (ai AllocInfo) Values() map[string]string {
+	values := map[string]string{}
+	for variable, value := range ai.env {
+		if strings.HasPrefix(variable, metaPrefix) {
+			values[strings.TrimPrefix(variable, metaPrefix)] = value
+		}
+	}
+
+	known := map[string]string{
+		"JobName":    jobNameEnvVar,
+		"GroupName":  groupNameEnvVar,
+		"TaskName":   taskNameEnvVar,
+		"AllocID":    allocIDEnvVar,
+		"AllocIndex": allocIndexEnvVar,
+		"Namespace":  namespaceEnvVar,
+		"Region":     regionEnvVar,
+		"Datacenter": dcEnvVar,
+	}
+	for name, variable := range known {
+		values[name] = ai.env[variable]
+	}
+	return values
+}
+
+// GetEndpoint returns the address and host port of the port with given label, or with the label of the port meta
+// value when label is empty. Without either the only port of the allocation is returned.
+func (ai AllocInfo) GetEndpoint(label string) (string, int, error) {
+	if label == "" {
+		label = ai.GetMeta(MetaPort)
+	}
+	if label == "" {
+		labels := ai.portLabels()
+		if len(labels) != 1 {
+			return "", 0, fmt.Errorf("allocation has %d ports %v, select one with the %s meta value", len(labels), labels, MetaPort)
+		}
+		label = labels[0]
+	}
+
+	value, ok := ai.env[hostPortPrefix+label]
+	if !ok {
+		return "", 0, fmt.Errorf("allocation has no port %s, %s is not set", label, hostPortPrefix+label)
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid %s %q: %s", hostPortPrefix+label, value, err)
+	}
+	address := ai.env[ipPrefix+label]
+	if address == "" {
+		return "", 0, fmt.Errorf("allocation has no address of port %s, %s is not set", label, ipPrefix+label)
+	}
+	return address, port, nil
+}
+
+// portLabels returns labels of ports of the allocation
+func (ai AllocInfo) portLabels() []string {
+	labels := []string{}
+	for variable := range ai.env {
+		if strings.HasPrefix(variable, hostPortPrefix) {
+			labels = append(labels, strings.TrimPrefix(variable, hostPortPrefix))
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// GetWeight returns the weight of the weight meta value
+func (ai AllocInfo) GetWeight() (int, error) {
+	weight := ai.GetMeta(MetaWeight)
+	if weight == "" {
+		return 0, errors.New("weight meta value is empty, key: " + MetaWeight)
+	}
+	return strconv.Atoi(weight)
+}
+
+// GetDataCenter returns the datacenter of the DC meta value
+func (ai AllocInfo) GetDataCenter() string {
+	return ai.GetMeta(MetaDC)
+}
@@ -0,0 +1,81 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testAllocInfo(environ ...string) *AllocInfo {
+	return newAllocInfo(append([]string{
+		"NOMAD_ALLOC_ID=5c3e8b2a-1f4d-4e1b-9a7e-0c2d1e6f3b4a",
+		"NOMAD_ALLOC_INDEX=0",
+		"NOMAD_JOB_NAME=web",
+		"NOMAD_GROUP_NAME=frontend",
+		"NOMAD_TASK_NAME=vaas-hook",
+		"NOMAD_IP_http=10.0.0.5",
+		"NOMAD_HOST_PORT_http=24567",
+		"NOMAD_PORT_http=80",
+	}, environ...))
+}
+
+func TestGetDirectorDefaultsToJobName(t *testing.T) {
+	require.Equal(t, "web", testAllocInfo().GetDirector())
+	require.Equal(t, "director1", testAllocInfo("NOMAD_META_VAAS_DIRECTOR=director1").GetDirector())
+}
+
+func TestGetEndpointOfOnlyPortOrLabel(t *testing.T) {
+	address, port, err := testAllocInfo().GetEndpoint("")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5", address)
+	require.Equal(t, 24567, port)
+
+	info := testAllocInfo("NOMAD_IP_admin=10.0.0.5", "NOMAD_HOST_PORT_admin=24568")
+	_, _, err = info.GetEndpoint("")
+	require.EqualError(t, err, "allocation has 2 ports [admin http], select one with the VAAS_PORT meta value")
+
+	_, port, err = info.GetEndpoint("admin")
+	require.NoError(t, err)
+	require.Equal(t, 24568, port)
+
+	_, port, err = testAllocInfo("NOMAD_IP_admin=10.0.0.5", "NOMAD_HOST_PORT_admin=24568", "NOMAD_META_VAAS_PORT=admin").GetEndpoint("")
+	require.NoError(t, err)
+	require.Equal(t, 24568, port)
+
+	_, _, err = info.GetEndpoint("metrics")
+	require.EqualError(t, err, "allocation has no port metrics, NOMAD_HOST_PORT_metrics is not set")
+}
+
+func TestGetAllocInfoRequiresNomad(t *testing.T) {
+	_, err := GetAllocInfo()
+
+	require.Error(t, err)
+}
+
+func TestValuesHoldMetaAndAllocationData(t *testing.T) {
+	values := testAllocInfo("NOMAD_META_ENV=prod").Values()
+
+	require.Equal(t, map[string]string{
+		"ENV":        "prod",
+		"JobName":    "web",
+		"GroupName":  "frontend",
+		"TaskName":   "vaas-hook",
+		"AllocID":    "5c3e8b2a-1f4d-4e1b-9a7e-0c2d1e6f3b4a",
+		"AllocIndex": "0",
+		"Namespace":  "",
+		"Region":     "",
+		"Datacenter": "",
+	}, values)
+}
+
+func TestGetWeightAndDataCenter(t *testing.T) {
+	info := testAllocInfo("NOMAD_META_VAAS_WEIGHT=3", "NOMAD_META_VAAS_DC=dc2")
+
+	weight, err := info.GetWeight()
+	require.NoError(t, err)
+	require.Equal(t, 3, weight)
+	require.Equal(t, "dc2", info.GetDataCenter())
+
+	_, err = testAllocInfo().GetWeight()
+	require.Error(t, err)
+}
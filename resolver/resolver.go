@@ -0,0 +1,97 @@
+// Package resolver expands Go templates in names given to the hook, e.g. of directors, with values of its environment,
+// such as environment variables, labels of its Marathon application or annotations of its Kubernetes Pod.
+//
+// Templates refer to values by name, e.g. "{{.AppName}}-{{.ENV}}", or with index when names are not identifiers,
+// e.g. `{{index . "app.kubernetes.io/name"}}`. Referring to a missing value is an error, while get returns missing
+// values as empty, e.g. to give them a fallback with `{{get "ENV" | default "prod"}}`. Functions lower, upper and
+// replace are also available, and env reading environment variables as consul-template does, e.g.
+// `{{env "NOMAD_JOB_NAME"}}`, so that templates are shared with template stanzas of Nomad jobs.
+package resolver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Values are named values templates refer to
+type Values map[string]string
+
+// Resolver expands templates with Values
+type Resolver struct {
+	values Values
+}
+
+// New creates a Resolver of templates with values of sources, later sources overriding earlier ones
+func New(sources ...Values) *Resolver {
+	values := Values{}
+	for _, source := range sources {
+		for name, value := range source {
+			values[name] = value
+		}
+	}
+	return &Resolver{values: values}
+}
+
+// Environment returns environment variables of the hook
+func Environment() Values {
+	values := Values{}
+	for _, variable := range os.Environ() {
+		if i := strings.Index(variable, "="); i > 0 {
+			values[variable[:i]] = variable[i+1:]
+		}
+	}
+	return values
+}
+
+var functions = template.FuncMap{
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	// env reads an environment variable like in consul-template, e.g. in template stanzas of Nomad jobs
+	"env":     os.Getenv,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+}
+
+// IsTemplate tells whether text has template expressions to resolve
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// Resolve expands the template text, returning text without template expressions as is
+func (r *Resolver) Resolve(text string) (string, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+
+	get := template.FuncMap{"get": func(name string) string { return r.values[name] }}
+	parsed, err := template.New("name").Funcs(functions).Funcs(get).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %s", text, err)
+	}
+	var resolved bytes.Buffer
+	if err := parsed.Execute(&resolved, r.values); err != nil {
+		return "", fmt.Errorf("could not resolve %q: %s", text, err)
+	}
+	return resolved.String(), nil
+}
+
+// ResolveAll expands every template of texts
+func (r *Resolver) ResolveAll(texts []string) ([]string, error) {
+	resolved := make([]string, 0, len(texts))
+	for _, text := range texts {
+		value, err := r.Resolve(text)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, value)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExpandsValues(t *testing.T) {
+	r := New(Values{"AppName": "env-app", "ENV": "prod"}, Values{"AppName": "shop", "app.kubernetes.io/part-of": "Store"})
+
+	for template, expected := range map[string]string{
+		"{{.AppName}}-{{.ENV}}":                            "shop-prod",
+		`{{index . "app.kubernetes.io/part-of" | lower}}`:  "store",
+		`{{get "REGION" | default "eu"}}_{{.ENV | upper}}`: "eu_PROD",
+		`{{replace "-" "_" "a-b"}}`:                        "a_b",
+		"plain":                                            "plain",
+	} {
+		resolved, err := r.Resolve(template)
+		require.NoError(t, err, template)
+		assert.Equal(t, expected, resolved, template)
+	}
+}
+
+func TestResolveFailsOnMissingValues(t *testing.T) {
+	r := New(Values{"ENV": "prod"})
+
+	_, err := r.Resolve("{{.AppName}}-{{.ENV}}")
+	assert.Error(t, err)
+	_, err = r.Resolve("{{.ENV")
+	assert.Error(t, err)
+	_, err = r.ResolveAll([]string{"ok", "{{.Missing}}"})
+	assert.Error(t, err)
+}
+
+func TestEnvironment(t *testing.T) {
+	require.NoError(t, os.Setenv("RESOLVER_TEST", "a=b"))
+	defer os.Unsetenv("RESOLVER_TEST")
+
+	assert.Equal(t, "a=b", Environment()["RESOLVER_TEST"])
+}
+
+func TestResolveReadsEnvironmentLikeConsulTemplate(t *testing.T) {
+	require.NoError(t, os.Setenv("NOMAD_JOB_NAME", "web"))
+	defer os.Unsetenv("NOMAD_JOB_NAME")
+
+	resolved, err := New().Resolve(`{{env "NOMAD_JOB_NAME"}}-{{env "NOMAD_META_MISSING" | default "prod"}}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, "web-prod", resolved)
+}
@@ -0,0 +1,107 @@
+// Package secret reads credentials from files, such as keys of a mounted Kubernetes Secret, and re-reads them
+// when the files change, so that long-running processes pick rotated credentials up without a restart.
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Keys of a Kubernetes Secret holding VaaS credentials, mounted as files of a directory
+const (
+	// KeyUsername is the key of the VaaS username
+	KeyUsername = "username"
+	// KeyAPIKey is the key of the VaaS API key
+	KeyAPIKey = "api-key"
+)
+
+// Path returns the path of key in the directory a Kubernetes Secret is mounted at
+func Path(dir, key string) string {
+	return filepath.Join(dir, key)
+}
+
+// Clean trims whitespace, such as the trailing newline of files, around value of the secret with name and
+// rejects it when it is empty or contains control characters, which no header could carry
+func Clean(name, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", name)
+	}
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%s contains control characters", name)
+	}
+	return value, nil
+}
+
+// ReadFile reads the secret with name from path, cleaned with Clean
+func ReadFile(name, path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s from file: %w", name, err)
+	}
+	value, err := Clean(name, string(data))
+	if err != nil {
+		return "", fmt.Errorf("%s in %s", err, path)
+	}
+	return value, nil
+}
+
+// File is a secret read from a file and re-read once its last read is older than an interval, so that a
+// rotated secret is used without a restart. Kubernetes updates mounted Secrets by swapping a symbolic link,
+// so changes are detected by reading the file again rather than by watching it. It is safe for concurrent use.
+type File struct {
+	name     string
+	path     string
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	value  string
+	readAt time.Time
+	stale  bool
+}
+
+// NewFile returns the secret with name read from path, re-read every interval, or only once when it is 0
+func NewFile(name, path string, interval time.Duration) *File {
+	return &File{name: name, path: path, interval: interval, now: time.Now}
+}
+
+// Value returns the secret, reading the file when it was not read yet or its last read is older than the
+// interval. When a file read before cannot be read again the last value is kept, as a rotation may be under way.
+func (f *File) Value() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if f.value != "" && !f.stale && (f.interval <= 0 || now.Sub(f.readAt) < f.interval) {
+		return f.value, nil
+	}
+	value, err := ReadFile(f.name, f.path)
+	if err != nil {
+		if f.value == "" {
+			return "", err
+		}
+		log.Warnf("Keeping the last %s: %s", f.name, err)
+		f.readAt, f.stale = now, false
+		return f.value, nil
+	}
+	if f.value != "" && value != f.value {
+		log.Infof("Reloaded %s from %s", f.name, f.path)
+	}
+	f.value, f.readAt, f.stale = value, now, false
+	return value, nil
+}
+
+// Invalidate makes the next Value read the file again, e.g. once VaaS rejected the secret
+func (f *File) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stale = true
+}
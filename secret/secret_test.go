@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestCleanTrimsAndValidates(t *testing.T) {
+	value, err := Clean("key", "  secret token\n")
+	require.NoError(t, err)
+	assert.Equal(t, "secret token", value)
+
+	_, err = Clean("key", " \n")
+	assert.EqualError(t, err, "key is empty")
+	_, err = Clean("key", "first\nsecond")
+	assert.EqualError(t, err, "key contains control characters")
+}
+
+func TestReadFileReportsPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, KeyAPIKey)
+	writeFile(t, path, "\n")
+
+	_, err = ReadFile("VaaS key", path)
+
+	assert.EqualError(t, err, "VaaS key is empty in "+path)
+}
+
+func TestFileIsReadAgainAfterInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, KeyAPIKey)
+	writeFile(t, path, "old\n")
+	now := time.Now()
+	file := NewFile("VaaS key", path, time.Minute)
+	file.now = func() time.Time { return now }
+
+	value, err := file.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+
+	writeFile(t, path, "new\n")
+	value, _ = file.Value()
+	assert.Equal(t, "old", value)
+
+	now = now.Add(time.Minute)
+	value, _ = file.Value()
+	assert.Equal(t, "new", value)
+}
+
+func TestFileKeepsLastValueWhenUnreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, KeyAPIKey)
+	writeFile(t, path, "old")
+	file := NewFile("VaaS key", path, 0)
+	_, err = file.Value()
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(path))
+	file.Invalidate()
+	value, err := file.Value()
+
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+}
+
+func TestFileFailsWhenNeverRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, err = NewFile("VaaS key", filepath.Join(dir, KeyAPIKey), 0).Value()
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+// Package signals cancels operations of the hook when the platform asks it to stop, so that the agent and the
+// HTTP server drain and deregister backends before exiting.
+//
+// On Linux and macOS the hook stops on SIGINT and SIGTERM. On Windows it stops on Ctrl+C, on the close, logoff and
+// shutdown events of its console, and on stop and shutdown requests of the service control manager when it runs
+// as a Windows service.
+package signals
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithTermination returns a context cancelled once the platform asks the process to stop
+func WithTermination(parent context.Context) (context.Context, context.CancelFunc) {
+	return WithGracefulTermination(parent, nil)
+}
+
+// WithGracefulTermination is WithTermination calling drain, when not nil, before it cancels the context, so that
+// requests in progress can finish first
+func WithGracefulTermination(parent context.Context, drain func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, terminationSignals...)
+	requests := make(chan string, 1)
+	watchPlatform(ctx, requests)
+	go func() {
+		defer signal.Stop(signals)
+		var reason string
+		select {
+		case sig := <-signals:
+			reason = sig.String()
+		case reason = <-requests:
+		case <-ctx.Done():
+			return
+		}
+		log.Warnf("Received %s, cancelling VaaS requests", reason)
+		if drain != nil {
+			drain()
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
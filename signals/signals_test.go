@@ -0,0 +1,54 @@
+//go:build !windows
+// +build !windows
+
+package signals
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTerminationIsCancelledBySIGTERM(t *testing.T) {
+	ctx, cancel := WithTermination(context.Background())
+	defer cancel()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled by SIGTERM")
+	}
+}
+
+func TestWithTerminationIsCancelledByCancel(t *testing.T) {
+	ctx, cancel := WithTermination(context.Background())
+	cancel()
+
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestWithGracefulTerminationDrainsBeforeCancelling(t *testing.T) {
+	drained := make(chan struct{})
+	var ctx context.Context
+	ctx, cancel := WithGracefulTermination(context.Background(), func() {
+		assert.NoError(t, ctx.Err())
+		close(drained)
+	})
+	defer cancel()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled by SIGTERM")
+	}
+	<-drained
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package signals
+
+import (
+	"context"
+	"os"
+	"syscall"
+)
+
+// terminationSignals stop the process
+var terminationSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// watchPlatform sends reasons of other requests to stop the process to requests until ctx is done, there are
+// none besides signals on this platform
+func watchPlatform(context.Context, chan<- string) {}
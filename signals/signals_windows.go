@@ -0,0 +1,69 @@
+package signals
+
+import (
+	"context"
+	"os"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceName names the service to the service control manager, which ignores it for services of own processes
+const serviceName = "vaas-hook"
+
+// terminationSignals stop the process: Ctrl+C, and SIGTERM which Go raises on close, logoff and shutdown events
+// of the console
+var terminationSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// watchPlatform sends reasons of other requests to stop the process to requests until ctx is done: stop and
+// shutdown requests of the service control manager, if the process runs as a Windows service
+func watchPlatform(ctx context.Context, requests chan<- string) {
+	interactive, err := svc.IsAnInteractiveSession()
+	if err != nil {
+		log.Warnf("Cannot tell whether running as a Windows service: %s", err)
+		return
+	}
+	if interactive {
+		return
+	}
+	go func() {
+		if err := svc.Run(serviceName, &service{ctx: ctx, requests: requests}); err != nil {
+			log.Warnf("Cannot run as a Windows service: %s", err)
+		}
+	}()
+}
+
+// service reports the process running to the service control manager until it asks the process to stop
+type service struct {
+	ctx      context.Context
+	requests chan<- string
+}
+
+// Execute implements svc.Handler.
+func (s *service) Execute(_ []string, changes <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case change := <-changes:
+			switch change.Cmd {
+			case svc.Interrogate:
+				status <- change.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				reason := "Windows service stop request"
+				if change.Cmd == svc.Shutdown {
+					reason = "Windows shutdown"
+				}
+				select {
+				case s.requests <- reason:
+				default:
+				}
+				return false, 0
+			}
+		case <-s.ctx.Done():
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package systemd integrates the hook with systemd units of services running on bare metal or VMs: it notifies
+// systemd of readiness, stopping and liveness of a Type=notify unit and reads how the service of a unit stopped
+// when run as its ExecStopPost command.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Ready tells systemd the service finished starting up
+	Ready = "READY=1"
+	// Stopping tells systemd the service is shutting down
+	Stopping = "STOPPING=1"
+	// Watchdog renews the watchdog timestamp of the service
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns a state describing the service to systemd, e.g. in systemctl status
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// Notify sends states, e.g. Ready, to systemd over $NOTIFY_SOCKET, reporting whether they were sent. Outside of a
+// Type=notify unit the socket is not set and nothing is sent.
+func Notify(states ...string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// abstract socket namespace
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("could not connect to systemd notify socket: %s", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, fmt.Errorf("could not notify systemd: %s", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns how often systemd expects Watchdog notifications of this process, set by WatchdogSec= of
+// its unit, or 0 if the watchdog is disabled
+func WatchdogInterval() (time.Duration, error) {
+	value := os.Getenv("WATCHDOG_USEC")
+	if value == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", value)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// StopInfo describes how the service of a unit stopped, as given by systemd to its ExecStopPost commands
+type StopInfo struct {
+	// Result is e.g. success, exit-code, signal or watchdog
+	Result string
+	// ExitCode is e.g. exited, killed or dumped
+	ExitCode string
+	// ExitStatus is the exit code or the name of the signal the service was killed with
+	ExitStatus string
+}
+
+// GetStopInfo returns how the service stopped, or nil when not run by systemd after stopping a service
+func GetStopInfo() *StopInfo {
+	info := &StopInfo{
+		Result:     os.Getenv("SERVICE_RESULT"),
+		ExitCode:   os.Getenv("EXIT_CODE"),
+		ExitStatus: os.Getenv("EXIT_STATUS"),
+	}
+	if info.Result == "" {
+		return nil
+	}
+	return info
+}
+
+func (i StopInfo) String() string {
+	if i.ExitCode == "" {
+		return i.Result
+	}
+	return fmt.Sprintf("%s (%s %s)", i.Result, i.ExitCode, i.ExitStatus)
+}
@@ -0,0 +1,75 @@
+//go:build !windows
+// +build !windows
+
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifySendsStatesToNotifySocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, os.Setenv("NOTIFY_SOCKET", socket))
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	sent, err := Notify(Ready, Status("Backend registered"))
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	message := make([]byte, 128)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(message)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1\nSTATUS=Backend registered", string(message[:n]))
+}
+
+func TestNotifyDoesNothingOutsideOfNotifyUnit(t *testing.T) {
+	sent, err := Notify(Ready)
+
+	require.NoError(t, err)
+	assert.False(t, sent)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	require.NoError(t, os.Setenv("WATCHDOG_USEC", "30000000"))
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	interval, err := WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+
+	require.NoError(t, os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1)))
+	defer os.Unsetenv("WATCHDOG_PID")
+	interval, err = WatchdogInterval()
+	require.NoError(t, err)
+	assert.Zero(t, interval)
+}
+
+func TestGetStopInfo(t *testing.T) {
+	assert.Nil(t, GetStopInfo())
+
+	require.NoError(t, os.Setenv("SERVICE_RESULT", "exit-code"))
+	defer os.Unsetenv("SERVICE_RESULT")
+	require.NoError(t, os.Setenv("EXIT_CODE", "exited"))
+	defer os.Unsetenv("EXIT_CODE")
+	require.NoError(t, os.Setenv("EXIT_STATUS", "1"))
+	defer os.Unsetenv("EXIT_STATUS")
+
+	assert.Equal(t, &StopInfo{Result: "exit-code", ExitCode: "exited", ExitStatus: "1"}, GetStopInfo())
+	assert.Equal(t, "exit-code (exited 1)", GetStopInfo().String())
+}
@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OTLPTracesPath is the path of the OTLP/HTTP traces endpoint, appended to the collector address
+	OTLPTracesPath = "/v1/traces"
+
+	instrumentationScope = "github.com/allegro/vaas-registration-hook"
+	statusCodeOK         = 1
+	statusCodeError      = 2
+)
+
+// OTLPExporter sends spans to an OpenTelemetry collector with the OTLP/HTTP JSON protocol
+type OTLPExporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter creates an exporter sending spans of service serviceName to the collector at endpoint,
+// e.g. http://otel-collector:4318, with the key=value headers, e.g. for authentication
+func NewOTLPExporter(endpoint, serviceName string, headers []string) (*OTLPExporter, error) {
+	parsed := make(map[string]string, len(headers))
+	for _, header := range headers {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q, expected key=value", header)
+		}
+		parsed[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return &OTLPExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + OTLPTracesPath,
+		headers:     parsed,
+		serviceName: serviceName,
+		client:      &http.Client{},
+	}, nil
+}
+
+// Export implements Exporter
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	message, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("OTLP collector responded with %s: %s", response.Status, message)
+	}
+	return nil
+}
+
+// Messages of the OTLP/HTTP JSON protocol, see opentelemetry-proto trace/v1/trace.proto
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		TraceState        string          `json:"traceState,omitempty"`
+		Name              string          `json:"name"`
+		Kind              Kind            `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		converted = append(converted, otlpSpanOf(span))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{attributeOf("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentationScope}, Spans: converted}},
+	}}}
+}
+
+func otlpSpanOf(span SpanData) otlpSpan {
+	converted := otlpSpan{
+		TraceID:           hex.EncodeToString(span.Context.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.Context.SpanID[:]),
+		TraceState:        span.Context.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Status:            otlpStatus{Code: statusCodeOK},
+	}
+	if span.ParentID != (SpanID{}) {
+		converted.ParentSpanID = hex.EncodeToString(span.ParentID[:])
+	}
+	for _, key := range sortedKeys(span.Attributes) {
+		converted.Attributes = append(converted.Attributes, attributeOf(key, span.Attributes[key]))
+	}
+	if span.Error != "" {
+		converted.Status = otlpStatus{Code: statusCodeError, Message: span.Error}
+	}
+	return converted
+}
+
+// attributeOf converts value to an OTLP AnyValue, integers are encoded as strings like in OTLP JSON
+func attributeOf(key string, value interface{}) otlpAttribute {
+	var converted map[string]interface{}
+	switch v := value.(type) {
+	case string:
+		converted = map[string]interface{}{"stringValue": v}
+	case bool:
+		converted = map[string]interface{}{"boolValue": v}
+	case int:
+		converted = map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		converted = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		converted = map[string]interface{}{"doubleValue": v}
+	default:
+		converted = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpAttribute{Key: key, Value: converted}
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
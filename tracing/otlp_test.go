@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporterSendsSpans(t *testing.T) {
+	var body map[string]interface{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, OTLPTracesPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer collector.Close()
+	exporter, err := NewOTLPExporter(collector.URL+"/", "hook", []string{"Authorization=secret"})
+	require.NoError(t, err)
+
+	sc, err := ParseTraceParent(traceParent)
+	require.NoError(t, err)
+	start := time.Unix(1, 0)
+	err = exporter.Export(context.Background(), []SpanData{{
+		Name:       "register",
+		Kind:       KindInternal,
+		Context:    sc,
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: map[string]interface{}{"vaas.backend.port": 80},
+		Error:      "failed",
+	}})
+
+	require.NoError(t, err)
+	resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	resource := resourceSpans["resource"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"key": "service.name", "value": map[string]interface{}{"stringValue": "hook"},
+	}}, resource["attributes"])
+	span := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0]
+	assert.Equal(t, map[string]interface{}{
+		"traceId":           "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanId":            "00f067aa0ba902b7",
+		"name":              "register",
+		"kind":              float64(KindInternal),
+		"startTimeUnixNano": "1000000000",
+		"endTimeUnixNano":   "2000000000",
+		"attributes": []interface{}{map[string]interface{}{
+			"key": "vaas.backend.port", "value": map[string]interface{}{"intValue": "80"},
+		}},
+		"status": map[string]interface{}{"code": float64(statusCodeError), "message": "failed"},
+	}, span)
+}
+
+func TestOTLPExporterReportsCollectorErrors(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+	exporter, err := NewOTLPExporter(collector.URL, "hook", nil)
+	require.NoError(t, err)
+
+	err = exporter.Export(context.Background(), []SpanData{{Name: "register"}})
+
+	assert.Error(t, err)
+	_, err = NewOTLPExporter(collector.URL, "hook", []string{"no-value"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxQueuedSpans limits spans waiting for export, later ones are dropped until the queue is flushed
+const maxQueuedSpans = 2048
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Tracer queues finished spans until they are flushed to its exporter
+type Tracer struct {
+	exporter Exporter
+
+	mu      sync.Mutex
+	queue   []SpanData
+	dropped int
+}
+
+// NewTracer creates a Tracer exporting spans with exporter
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+func (t *Tracer) record(span SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, span)
+}
+
+// Flush exports queued spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warnf("Dropped %d spans exceeding the export queue", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.Export(ctx, spans)
+}
+
+// FlushEvery flushes queued spans every interval until ctx is done, for long-running commands
+func (t *Tracer) FlushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				log.Warnf("Failed exporting spans: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer sets the tracer recording spans started with Start, nil to not record them
+func SetTracer(tracer *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = tracer
+}
+
+// GetTracer returns the tracer recording spans, nil when spans are not recorded
+func GetTracer() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracer
+}
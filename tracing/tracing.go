@@ -0,0 +1,208 @@
+// Package tracing records spans of registration flows and propagates their trace context in W3C traceparent headers.
+//
+// Spans are compatible with OpenTelemetry: they are exported with the OTLP/HTTP JSON protocol, see NewOTLPExporter,
+// so they show up in pod-startup traces next to spans of other instrumented components. Without a tracer set
+// with SetTracer spans are not recorded, but trace context given by the caller is still propagated.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderTraceParent is the W3C Trace Context header carrying the trace and parent span IDs
+	HeaderTraceParent = "traceparent"
+	// HeaderTraceState is the W3C Trace Context header carrying vendor specific trace data
+	HeaderTraceState = "tracestate"
+
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// Kind tells the role of a span in a trace, with values of OTLP SpanKind
+type Kind int
+
+// Kinds of spans
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span in a trace
+type SpanID [8]byte
+
+// SpanContext identifies a span and tells whether it is recorded
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid tells whether sc identifies a span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// TraceParent returns the traceparent header value of sc
+func (sc SpanContext) TraceParent() string {
+	flags := 0
+	if sc.Sampled {
+		flags = flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion, hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceParent parses a traceparent header value, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if err := decodeHex(sc.TraceID[:], parts[1]); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace ID in traceparent %q", value)
+	}
+	if err := decodeHex(sc.SpanID[:], parts[2]); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid parent ID in traceparent %q", value)
+	}
+	if err := decodeHex(flags[:], parts[3]); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid flags in traceparent %q", value)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q, IDs must not be zero", value)
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+	return sc, nil
+}
+
+func decodeHex(dst []byte, value string) error {
+	if len(value) != 2*len(dst) || strings.ToLower(value) != value {
+		return fmt.Errorf("expected %d lowercase hex digits", 2*len(dst))
+	}
+	_, err := hex.Decode(dst, []byte(value))
+	return err
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns ctx whose spans are children of the span of sc, e.g. of a remote caller
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the context of the current span of ctx, invalid when it has none
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// Inject sets trace context headers of the current span of ctx, if any
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(HeaderTraceParent, sc.TraceParent())
+	if sc.TraceState != "" {
+		header.Set(HeaderTraceState, sc.TraceState)
+	}
+}
+
+// Extract returns ctx continuing the trace of header, ctx itself when header has no valid trace context
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceParent(header.Get(HeaderTraceParent))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = header.Get(HeaderTraceState)
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// SpanData describes a finished span
+type SpanData struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	ParentID   SpanID
+	Start, End time.Time
+	Attributes map[string]interface{}
+	// Error is the message of the error the span ended with, empty when it succeeded
+	Error string
+}
+
+// Span is an operation of a trace, recorded once it ends
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Start starts a span named name as a child of the current span of ctx, or of a new trace.
+// It returns ctx with the new span as the current one.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		sc.Sampled = true
+	}
+	sc.SpanID = newSpanID()
+
+	span := &Span{data: SpanData{Name: name, Kind: kind, Context: sc, ParentID: parent.SpanID, Start: time.Now()}}
+	if sc.Sampled {
+		span.tracer = GetTracer()
+	}
+	return ContextWithSpanContext(ctx, sc), span
+}
+
+// SetAttribute sets the attribute key of span to value, a string, bool, integer or float
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = map[string]interface{}{}
+	}
+	s.data.Attributes[key] = value
+}
+
+// End finishes span, failed when err is not nil, and queues it for export. Further calls do nothing.
+func (s *Span) End(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Error = err.Error()
+	}
+	if s.tracer != nil {
+		s.tracer.record(s.data)
+	}
+}
+
+func newTraceID() (id TraceID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type recordingExporter struct {
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func withTracer(t *testing.T) *recordingExporter {
+	exporter := &recordingExporter{}
+	SetTracer(NewTracer(exporter))
+	t.Cleanup(func() { SetTracer(nil) })
+	return exporter
+}
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent(traceParent)
+
+	require.NoError(t, err)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, traceParent, sc.TraceParent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceParent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSpansAreChildrenOfExtractedParent(t *testing.T) {
+	exporter := withTracer(t)
+	header := http.Header{}
+	header.Set(HeaderTraceParent, traceParent)
+	header.Set(HeaderTraceState, "vendor=value")
+	parent := SpanContextFromContext(Extract(context.Background(), header))
+
+	ctx, span := Start(Extract(context.Background(), header), "register", KindInternal)
+	span.SetAttribute("vaas.director", "d1")
+	_, child := Start(ctx, "VaaS GET", KindClient)
+	child.End(errors.New("failed"))
+	span.End(nil)
+	span.End(nil)
+	require.NoError(t, GetTracer().Flush(context.Background()))
+
+	require.Len(t, exporter.spans, 2)
+	registered, request := exporter.spans[1], exporter.spans[0]
+	assert.Equal(t, parent.TraceID, registered.Context.TraceID)
+	assert.Equal(t, parent.SpanID, registered.ParentID)
+	assert.Equal(t, "vendor=value", registered.Context.TraceState)
+	assert.Equal(t, map[string]interface{}{"vaas.director": "d1"}, registered.Attributes)
+	assert.Equal(t, registered.Context.SpanID, request.ParentID)
+	assert.Equal(t, "failed", request.Error)
+
+	injected := http.Header{}
+	Inject(ctx, injected)
+	assert.Equal(t, SpanContextFromContext(ctx).TraceParent(), injected.Get(HeaderTraceParent))
+	assert.Equal(t, "vendor=value", injected.Get(HeaderTraceState))
+}
+
+func TestSpansAreNotRecordedWhenNotSampled(t *testing.T) {
+	exporter := withTracer(t)
+	header := http.Header{}
+	header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	ctx, span := Start(Extract(context.Background(), header), "register", KindInternal)
+	span.End(nil)
+	require.NoError(t, GetTracer().Flush(context.Background()))
+
+	assert.Empty(t, exporter.spans)
+	assert.False(t, SpanContextFromContext(ctx).Sampled)
+}
+
+func TestContextIsPropagatedWithoutTracer(t *testing.T) {
+	SetTracer(nil)
+
+	ctx, span := Start(context.Background(), "register", KindInternal)
+	span.End(nil)
+	header := http.Header{}
+	Inject(ctx, header)
+
+	assert.NotEmpty(t, header.Get(HeaderTraceParent))
+	Inject(context.Background(), header)
+}
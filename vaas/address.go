@@ -0,0 +1,30 @@
+package vaas
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// NormalizeAddress returns address the way VaaS stores it: IP literals in canonical form, IPv6 without brackets or
+// zone, e.g. "2001:db8::1" for "[2001:DB8:0::1]". Other addresses, such as hostnames, are returned as they are.
+func NormalizeAddress(address string) string {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(address), "["), "]")
+	if i := strings.LastIndex(trimmed, "%"); i > 0 && strings.Contains(trimmed, ":") {
+		trimmed = trimmed[:i]
+	}
+	if ip := net.ParseIP(trimmed); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// SameAddress tells whether a and b are the same address, comparing IP literals by value
+func SameAddress(a, b string) bool {
+	return a == b || NormalizeAddress(a) == NormalizeAddress(b)
+}
+
+// HostPort joins address and port, bracketing IPv6 literals, e.g. "[2001:db8::1]:80"
+func HostPort(address string, port int) string {
+	return net.JoinHostPort(NormalizeAddress(address), strconv.Itoa(port))
+}
@@ -0,0 +1,28 @@
+package vaas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	for address, expected := range map[string]string{
+		"10.0.0.1":              "10.0.0.1",
+		"2001:DB8:0::1":         "2001:db8::1",
+		"[2001:db8::1]":         "2001:db8::1",
+		"fe80::1%eth0":          "fe80::1",
+		"::ffff:10.0.0.1":       "10.0.0.1",
+		"backend.example.com":   "backend.example.com",
+		"[backend.example.com]": "[backend.example.com]",
+	} {
+		assert.Equal(t, expected, NormalizeAddress(address), address)
+	}
+}
+
+func TestHostPortBracketsIPv6(t *testing.T) {
+	assert.Equal(t, "10.0.0.1:80", HostPort("10.0.0.1", 80))
+	assert.Equal(t, "[2001:db8::1]:80", HostPort("[2001:DB8::1]", 80))
+	assert.True(t, SameAddress("2001:db8::1", "[2001:0db8::0001]"))
+	assert.False(t, SameAddress("2001:db8::1", "2001:db8::2"))
+}
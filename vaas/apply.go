@@ -0,0 +1,336 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DesiredState describes directors and their backends, which NewPlan plans to converge VaaS to.
+type DesiredState struct {
+	Directors []DesiredDirector `json:"directors" yaml:"directors"`
+}
+
+// DesiredDirector is a director of a DesiredState. Service, Mode, Protocol and Clusters are only used to create
+// the director when it does not exist, which is planned only when Service is set.
+type DesiredDirector struct {
+	Name     string   `json:"name" yaml:"name"`
+	Service  string   `json:"service,omitempty" yaml:"service,omitempty"`
+	Mode     string   `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Protocol string   `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Clusters []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+
+	Backends []DesiredBackend `json:"backends" yaml:"backends"`
+}
+
+// DesiredBackend is a backend of a DesiredDirector, referring to its DC by symbol. Leaving Weight or Tags unset
+// keeps their current values.
+type DesiredBackend struct {
+	Address string   `json:"address" yaml:"address"`
+	Port    int      `json:"port" yaml:"port"`
+	DC      string   `json:"dc" yaml:"dc"`
+	Weight  *int     `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Tags    []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// ErrEmptyDirector is returned by NewPlan for a desired director without backends, which would delete every backend.
+var ErrEmptyDirector = errors.New("director has no backends")
+
+// Validate checks that directors of s are named once and their backends have an address, port and DC, given once.
+func (s DesiredState) Validate() error {
+	directors := map[string]bool{}
+	for i, director := range s.Directors {
+		if director.Name == "" {
+			return fmt.Errorf("director %d has no name", i+1)
+		}
+		if directors[director.Name] {
+			return fmt.Errorf("director %s is given more than once", director.Name)
+		}
+		directors[director.Name] = true
+
+		backends := map[string]bool{}
+		for _, backend := range director.Backends {
+			key := HostPort(backend.Address, backend.Port)
+			switch {
+			case backend.Address == "":
+				return fmt.Errorf("director %s has a backend without address", director.Name)
+			case backend.Port <= 0 || backend.Port > 65535:
+				return fmt.Errorf("backend %s of director %s has invalid port %d", backend.Address, director.Name, backend.Port)
+			case backend.DC == "":
+				return fmt.Errorf("backend %s of director %s has no DC", key, director.Name)
+			case backends[key]:
+				return fmt.Errorf("backend %s of director %s is given more than once", key, director.Name)
+			}
+			if backend.Weight != nil {
+				if err := validateWeight(*backend.Weight); err != nil {
+					return fmt.Errorf("backend %s of director %s: %w", key, director.Name, err)
+				}
+			}
+			backends[key] = true
+		}
+	}
+	return nil
+}
+
+// PlanOptions configures NewPlan.
+type PlanOptions struct {
+	// AllowEmpty plans directors without backends, deleting every backend of them.
+	AllowEmpty bool
+}
+
+// Plan lists changes converging VaaS to a DesiredState, made with NewPlan and applied with Apply.
+type Plan struct {
+	Directors []DirectorPlan
+}
+
+// DirectorPlan lists changes of backends of a single director.
+type DirectorPlan struct {
+	// Name of the director.
+	Name string
+	// Create is the director created before adding its backends, nil when it exists.
+	Create *Director
+	// Err is the error of creating the director.
+	Err error
+	// Changes are made in order of additions, updates and deletions.
+	Changes []PlanChange
+
+	director *Director
+}
+
+// PlanChange is a planned change of a backend. For updates, Backend is the changed one and Current the one
+// registered in VaaS.
+type PlanChange struct {
+	Action  string
+	Backend Backend
+	Current *Backend
+	Err     error
+}
+
+// Count returns the number of planned additions, updates and deletions.
+func (p Plan) Count() (add, update, remove int) {
+	for _, director := range p.Directors {
+		for _, change := range director.Changes {
+			switch change.Action {
+			case ActionAdd:
+				add++
+			case ActionUpdate:
+				update++
+			case ActionDelete:
+				remove++
+			}
+		}
+	}
+	return add, update, remove
+}
+
+// Empty tells whether p neither creates directors nor changes backends.
+func (p Plan) Empty() bool {
+	for _, director := range p.Directors {
+		if director.Create != nil || len(director.Changes) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPlan compares state with VaaS and returns the changes converging VaaS to it, matching backends by address
+// and port. Backends of directors which are not in state are left untouched.
+func NewPlan(ctx context.Context, client Client, state DesiredState, opts PlanOptions) (Plan, error) {
+	var plan Plan
+	dcs := map[string]DC{}
+	for _, desired := range state.Directors {
+		if len(desired.Backends) == 0 && !opts.AllowEmpty {
+			return plan, fmt.Errorf("cannot plan director %s: %w", desired.Name, ErrEmptyDirector)
+		}
+		directorPlan, err := planDirector(ctx, client, desired, dcs)
+		if err != nil {
+			return plan, fmt.Errorf("cannot plan director %s: %w", desired.Name, err)
+		}
+		plan.Directors = append(plan.Directors, directorPlan)
+	}
+	return plan, nil
+}
+
+func planDirector(ctx context.Context, client Client, desired DesiredDirector, dcs map[string]DC) (DirectorPlan, error) {
+	plan := DirectorPlan{Name: desired.Name}
+	director, err := client.FindDirector(ctx, desired.Name)
+	var actual []Backend
+	switch {
+	case errors.Is(err, ErrDirectorNotFound) && desired.Service != "":
+		plan.Create = &Director{Name: desired.Name, Service: desired.Service, Mode: desired.Mode,
+			Protocol: desired.Protocol, Clusters: desired.Clusters}
+		director = plan.Create
+	case err != nil:
+		return plan, err
+	default:
+		if actual, err = client.ListBackends(ctx, director); err != nil {
+			return plan, err
+		}
+	}
+	plan.director = director
+
+	backends := make([]Backend, 0, len(desired.Backends))
+	for _, backend := range desired.Backends {
+		dc, ok := dcs[backend.DC]
+		if !ok {
+			found, err := client.GetDC(ctx, backend.DC)
+			if err != nil {
+				return plan, err
+			}
+			dc = *found
+			dcs[backend.DC] = dc
+		}
+		backends = append(backends, Backend{Address: backend.Address, Port: backend.Port, DC: dc,
+			Weight: backend.Weight, Tags: backend.Tags})
+	}
+
+	current := make(map[ID]Backend, len(actual))
+	for _, backend := range actual {
+		if backend.ID != nil {
+			current[*backend.ID] = backend
+		}
+	}
+	var adds, updates, deletes []PlanChange
+	for _, change := range diffBackends(director, actual, backends, planChanged) {
+		switch change.Action {
+		case ActionAdd:
+			adds = append(adds, PlanChange{Action: ActionAdd, Backend: change.Backend})
+		case ActionUpdate:
+			update := PlanChange{Action: ActionUpdate, Backend: change.Backend}
+			if change.Backend.ID != nil {
+				existing := current[*change.Backend.ID]
+				update.Backend, update.Current = mergeBackend(existing, change.Backend), &existing
+			}
+			updates = append(updates, update)
+		case ActionDelete:
+			deletes = append(deletes, PlanChange{Action: ActionDelete, Backend: change.Backend})
+		}
+	}
+	plan.Changes = append(append(adds, updates...), deletes...)
+	return plan, nil
+}
+
+// planChanged tells whether current differs from desired in a field of DesiredBackend
+func planChanged(current, desired Backend) bool {
+	return needsUpdate(current, desired) || current.DC.Symbol != desired.DC.Symbol
+}
+
+// mergeBackend returns current with the DC, and the weight and tags set in desired
+func mergeBackend(current, desired Backend) Backend {
+	merged := current
+	merged.DC = desired.DC
+	if desired.Weight != nil {
+		merged.Weight = desired.Weight
+	}
+	if desired.Tags != nil {
+		merged.Tags = desired.Tags
+	}
+	return merged
+}
+
+// Apply makes the changes of p, creating directors first and deleting backends after adding and updating the
+// other ones of their director, so that it keeps serving traffic. Backends are added with a single AddBackends
+// call per director, and updated in place unless their DC changes. Failed changes are recorded in p and
+// summarized in the returned error.
+func (p Plan) Apply(ctx context.Context, client Client) error {
+	var failed, total int
+	var first string
+	for i := range p.Directors {
+		director := &p.Directors[i]
+		applyDirectorPlan(ctx, client, director)
+		if director.Err != nil && first == "" {
+			first = fmt.Sprintf("create director %s: %s", director.Name, director.Err)
+		}
+		for _, change := range director.Changes {
+			total++
+			if change.Err == nil {
+				continue
+			}
+			failed++
+			if first == "" {
+				first = fmt.Sprintf("%s %s in director %s: %s", change.Action,
+					HostPort(change.Backend.Address, change.Backend.Port), director.Name, change.Err)
+			}
+		}
+	}
+	if first != "" {
+		return fmt.Errorf("%d of %d changes failed, first: %s", failed, total, first)
+	}
+	return nil
+}
+
+func applyDirectorPlan(ctx context.Context, client Client, plan *DirectorPlan) {
+	if plan.Create != nil {
+		log.WithContext(ctx).Infof("Creating director %q", plan.Name)
+		if plan.Err = client.CreateDirector(ctx, plan.Create); plan.Err != nil {
+			for i := range plan.Changes {
+				plan.Changes[i].Err = plan.Err
+			}
+			return
+		}
+		plan.director = plan.Create
+	}
+
+	var added []*Backend
+	var addedIndexes []int
+	for i := range plan.Changes {
+		if plan.Changes[i].Action == ActionAdd {
+			plan.Changes[i].Backend.DirectorURL = plan.director.ResourceURI
+			added = append(added, &plan.Changes[i].Backend)
+			addedIndexes = append(addedIndexes, i)
+		}
+	}
+	if len(added) > 0 {
+		log.WithContext(ctx).Infof("Adding %d backends to director %s", len(added), plan.Name)
+		_, err := client.AddBackends(ctx, added, plan.director)
+		var bulkErr *BulkError
+		switch {
+		case errors.As(err, &bulkErr):
+			for _, failure := range bulkErr.Failures {
+				plan.Changes[addedIndexes[failure.Index]].Err = failure.Err
+			}
+		case err != nil:
+			for _, i := range addedIndexes {
+				plan.Changes[i].Err = err
+			}
+		}
+	}
+
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		if change.Action == ActionAdd {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			change.Err = err
+			continue
+		}
+		log.WithField("action", change.Action).WithField("backend", backendKey(change.Backend)).Info("Applying backend")
+		change.Err = applyPlanChange(ctx, client, *change)
+	}
+}
+
+// applyPlanChange updates backend of change in place, or replaces it when its DC changes, or deletes it
+func applyPlanChange(ctx context.Context, client Client, change PlanChange) error {
+	backend := change.Backend
+	if backend.ID == nil {
+		return fmt.Errorf("backend %s has no ID", backendKey(backend))
+	}
+	if change.Action == ActionDelete {
+		return client.DeleteBackend(ctx, int(*backend.ID))
+	}
+	if change.Current == nil || change.Current.DC.Symbol != backend.DC.Symbol {
+		return client.UpsertBackend(ctx, &backend)
+	}
+
+	var patch BackendPatch
+	if change.Current.GetWeight() != backend.GetWeight() {
+		patch.Weight = backend.Weight
+	}
+	if !sameTags(change.Current.Tags, backend.Tags) {
+		patch.Tags = append([]string{}, backend.Tags...)
+	}
+	return client.UpdateBackend(ctx, int(*backend.ID), patch)
+}
@@ -0,0 +1,154 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesiredStateValidate(t *testing.T) {
+	weight := 1000
+	for name, state := range map[string]DesiredState{
+		"unnamed director":   {Directors: []DesiredDirector{{}}},
+		"repeated director":  {Directors: []DesiredDirector{{Name: "a"}, {Name: "a"}}},
+		"backend without DC": {Directors: []DesiredDirector{{Name: "a", Backends: []DesiredBackend{{Address: "10.0.0.1", Port: 80}}}}},
+		"invalid port":       {Directors: []DesiredDirector{{Name: "a", Backends: []DesiredBackend{{Address: "10.0.0.1", DC: "dc1"}}}}},
+		"invalid weight": {Directors: []DesiredDirector{{Name: "a", Backends: []DesiredBackend{
+			{Address: "10.0.0.1", Port: 80, DC: "dc1", Weight: &weight}}}}},
+		"repeated backend": {Directors: []DesiredDirector{{Name: "a", Backends: []DesiredBackend{
+			{Address: "10.0.0.1", Port: 80, DC: "dc1"}, {Address: "10.0.0.1", Port: 80, DC: "dc1"}}}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, state.Validate())
+		})
+	}
+
+	valid := DesiredState{Directors: []DesiredDirector{{Name: "a", Backends: []DesiredBackend{{Address: "10.0.0.1", Port: 80, DC: "dc1"}}}}}
+	assert.NoError(t, valid.Validate())
+}
+
+func TestPlanAddsUpdatesAndDeletesBackends(t *testing.T) {
+	dc := DC{ID: 1, Symbol: "dc1", ResourceURI: "/api/v0.1/dc/1/"}
+	unchanged, changed, extra := reconcileBackend(1, "10.0.0.1", 1), reconcileBackend(2, "10.0.0.2", 1), reconcileBackend(3, "10.0.0.3", 1)
+	unchanged.DC, changed.DC, extra.DC = dc, dc, dc
+	server := &reconcileServer{backends: []Backend{unchanged, changed, extra}}
+	ts := httptest.NewServer(snapshotServer(server))
+	defer ts.Close()
+
+	five := 5
+	state := DesiredState{Directors: []DesiredDirector{{Name: "director", Backends: []DesiredBackend{
+		{Address: "10.0.0.1", Port: 80, DC: "dc1"},
+		{Address: "10.0.0.2", Port: 80, DC: "dc1", Weight: &five},
+		{Address: "10.0.0.4", Port: 80, DC: "dc1", Tags: []string{"c"}},
+	}}}}
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+
+	plan, err := NewPlan(context.Background(), client, state, PlanOptions{})
+
+	require.NoError(t, err)
+	assert.Empty(t, server.requests)
+	require.Len(t, plan.Directors, 1)
+	changes := plan.Directors[0].Changes
+	require.Len(t, changes, 3)
+	assert.Equal(t, ActionAdd, changes[0].Action)
+	assert.Equal(t, ActionUpdate, changes[1].Action)
+	assert.Equal(t, 1, changes[1].Current.GetWeight())
+	assert.Equal(t, 5, changes[1].Backend.GetWeight())
+	assert.Equal(t, []string{"a", "b"}, changes[1].Backend.Tags)
+	assert.Equal(t, ActionDelete, changes[2].Action)
+	add, update, remove := plan.Count()
+	assert.Equal(t, [3]int{1, 1, 1}, [3]int{add, update, remove})
+
+	require.NoError(t, plan.Apply(context.Background(), client))
+
+	assert.Equal(t, []string{
+		"POST /api/v0.1/backend/",
+		"PATCH /api/v0.1/backend/2/",
+		"DELETE /api/v0.1/backend/3/",
+	}, server.requests)
+}
+
+func TestPlanReplacesBackendsChangingDC(t *testing.T) {
+	changed := reconcileBackend(2, "10.0.0.2", 1)
+	changed.DC = DC{ID: 2, Symbol: "dc2"}
+	server := &reconcileServer{backends: []Backend{changed}}
+	ts := httptest.NewServer(snapshotServer(server))
+	defer ts.Close()
+
+	state := DesiredState{Directors: []DesiredDirector{{Name: "director", Backends: []DesiredBackend{
+		{Address: "10.0.0.2", Port: 80, DC: "dc1"},
+	}}}}
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+
+	plan, err := NewPlan(context.Background(), client, state, PlanOptions{})
+	require.NoError(t, err)
+	require.NoError(t, plan.Apply(context.Background(), client))
+
+	assert.Equal(t, []string{"PUT /api/v0.1/backend/2/"}, server.requests)
+}
+
+func TestPlanRefusesDirectorsWithoutBackends(t *testing.T) {
+	client := NewClient("http://vaas.invalid", "username", "api-key")
+
+	_, err := NewPlan(context.Background(), client, DesiredState{Directors: []DesiredDirector{{Name: "director"}}}, PlanOptions{})
+
+	require.True(t, errors.Is(err, ErrEmptyDirector), err)
+}
+
+func TestPlanCreatesMissingDirectors(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.URL.Path == apiDcPath:
+			_, _ = w.Write([]byte(`{"objects": [{"id": 1, "symbol": "dc1", "resource_uri": "/api/v0.1/dc/1/"}]}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"objects": []}`))
+		case r.URL.Path == apiDirectorPath:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 7, "name": "director", "resource_uri": "/api/v0.1/director/7/"}`))
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(mockAddBackendResponse)
+		}
+	}))
+	defer ts.Close()
+
+	state := DesiredState{Directors: []DesiredDirector{{Name: "director", Service: "service", Backends: []DesiredBackend{
+		{Address: "10.0.0.1", Port: 80, DC: "dc1"},
+	}}}}
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+
+	plan, err := NewPlan(context.Background(), client, state, PlanOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, plan.Directors[0].Create)
+	assert.Empty(t, requests)
+
+	require.NoError(t, plan.Apply(context.Background(), client))
+
+	assert.Equal(t, []string{"POST " + apiDirectorPath, "POST " + apiBackendPath}, requests)
+}
+
+func TestPlanFailsForMissingDirectorWithoutService(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"objects": []}`))
+	}))
+	defer ts.Close()
+
+	state := DesiredState{Directors: []DesiredDirector{{Name: "director", Backends: []DesiredBackend{
+		{Address: "10.0.0.1", Port: 80, DC: "dc1"},
+	}}}}
+	_, err := NewPlan(context.Background(), NewClient(ts.URL, "username", "api-key"), state, PlanOptions{})
+
+	require.True(t, errors.Is(err, ErrDirectorNotFound), err)
+}
@@ -0,0 +1,210 @@
+package vaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+)
+
+// auditBodyLimit limits request bodies recorded in the audit log
+const auditBodyLimit = 4096
+
+// Outcomes of audited requests
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditRecord is a line of the audit log, describing an attempt at a request changing VaaS
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Operation is e.g. AddBackend, UpdateBackend or DeleteBackend, or the method and resource of other changes
+	Operation  string          `json:"operation"`
+	Method     string          `json:"method"`
+	URL        string          `json:"url"`
+	BackendID  int             `json:"backend_id,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Status     int             `json:"status,omitempty"`
+	Outcome    string          `json:"outcome"`
+	Error      string          `json:"error,omitempty"`
+	TaskURI    string          `json:"task_uri,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+}
+
+// AuditLog appends AuditRecords as JSON lines to a file, rotating it to path.1 and so on once it grows over
+// maxSize bytes, keeping maxBackups rotated files. It is safe for concurrent use. Processes sharing a file append
+// whole lines, but should not rotate it.
+type AuditLog struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if needed. A maxSize of 0 never rotates it.
+func OpenAuditLog(path string, maxSize int64, maxBackups int) (*AuditLog, error) {
+	l := &AuditLog{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Write appends record to the log
+func (l *AuditLog) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate renames the log to path.1, shifting older backups and dropping those beyond maxBackups
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	backup := func(i int) string { return l.path + "." + strconv.Itoa(i) }
+	_ = os.Remove(backup(l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(backup(i), backup(i+1))
+	}
+	if l.maxBackups > 0 {
+		if err := os.Rename(l.path, backup(1)); err != nil {
+			return fmt.Errorf("cannot rotate audit log: %w", err)
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return fmt.Errorf("cannot rotate audit log: %w", err)
+	}
+	return l.open()
+}
+
+// Close closes the log file
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// WithAuditLog records every attempt at a request changing VaaS in auditLog, with its outcome. Failing to write a
+// record is logged, but does not fail the request.
+func WithAuditLog(auditLog *AuditLog) Option {
+	return WithMiddleware(AuditMiddleware(auditLog))
+}
+
+// AuditMiddleware records requests changing VaaS in auditLog. Requests only reading VaaS are not recorded.
+func AuditMiddleware(auditLog *AuditLog) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Method == http.MethodGet || request.Method == http.MethodHead || request.Method == http.MethodOptions {
+				return next.RoundTrip(request)
+			}
+
+			record := newAuditRecord(request)
+			start := time.Now()
+			response, err := next.RoundTrip(request)
+			record.DurationMS = time.Since(start).Milliseconds()
+			record.Outcome = AuditSuccess
+			if failure := failureOf(response, err); failure != nil {
+				record.Outcome, record.Error = AuditFailure, redactError(failure).Error()
+			}
+			if response != nil {
+				record.Status = response.StatusCode
+				if response.StatusCode == http.StatusAccepted {
+					record.TaskURI = response.Header.Get("Location")
+				}
+			}
+			if writeErr := auditLog.Write(record); writeErr != nil {
+				log.WithContext(request.Context()).Warnf("Could not write audit log: %s", writeErr)
+			}
+			return response, err
+		})
+	}
+}
+
+func newAuditRecord(request *http.Request) AuditRecord {
+	record := AuditRecord{
+		Time:      time.Now().UTC(),
+		Method:    request.Method,
+		URL:       redactURL(request.URL),
+		RequestID: request.Header.Get(logging.HeaderRequestID),
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(request.URL.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	resource := ""
+	if len(segments) > 0 {
+		resource = segments[len(segments)-1]
+		if id, err := strconv.Atoi(resource); err == nil && len(segments) > 1 {
+			resource = segments[len(segments)-2]
+			if resource == "backend" {
+				record.BackendID = id
+			}
+		}
+	}
+	record.Operation = request.Method + " " + resource
+	if resource == "backend" {
+		switch request.Method {
+		case http.MethodPost:
+			record.Operation = "AddBackend"
+		case http.MethodPatch, http.MethodPut:
+			record.Operation = "UpdateBackend"
+		case http.MethodDelete:
+			record.Operation = "DeleteBackend"
+		}
+	}
+
+	if request.GetBody != nil {
+		if body, err := request.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			if len(data) <= auditBodyLimit && json.Valid(data) {
+				record.Request = json.RawMessage(bytes.TrimSpace(data))
+			}
+		}
+	}
+	return record
+}
@@ -0,0 +1,92 @@
+package vaas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+)
+
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestAuditLogRecordsChangesOfBackends(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.Header().Set("Location", "/api/v0.1/task/abc/")
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPatch:
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLog, err := OpenAuditLog(filepath.Join(dir, "audit.log"), 0, 0)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithAuditLog(auditLog), WithTaskPolling(0, 0))
+	ctx := logging.WithCorrelationID(context.Background(), "operation-1")
+	require.NoError(t, client.DeleteBackend(ctx, 42))
+	require.Error(t, client.SetBackendWeight(ctx, 42, 3))
+	_, _ = client.ListDirectors(ctx)
+
+	records := readAuditLog(t, filepath.Join(dir, "audit.log"))
+	require.Len(t, records, 2)
+	assert.Equal(t, "DeleteBackend", records[0].Operation)
+	assert.Equal(t, 42, records[0].BackendID)
+	assert.Equal(t, "operation-1", records[0].RequestID)
+	assert.Equal(t, AuditSuccess, records[0].Outcome)
+	assert.Equal(t, "/api/v0.1/task/abc/", records[0].TaskURI)
+	assert.Equal(t, "UpdateBackend", records[1].Operation)
+	assert.Equal(t, AuditFailure, records[1].Outcome)
+	assert.Equal(t, http.StatusConflict, records[1].Status)
+	assert.JSONEq(t, `{"weight": 3}`, string(records[1].Request))
+}
+
+func TestAuditLogRotatesOverMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	auditLog, err := OpenAuditLog(path, 200, 2)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, auditLog.Write(AuditRecord{Operation: "DeleteBackend", BackendID: i, Outcome: AuditSuccess}))
+	}
+
+	require.Len(t, readAuditLog(t, path), 1)
+	assert.Equal(t, 4, readAuditLog(t, path)[0].BackendID)
+	assert.Equal(t, 3, readAuditLog(t, path+".1")[0].BackendID)
+	assert.Equal(t, 2, readAuditLog(t, path+".2")[0].BackendID)
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,205 @@
+package vaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const authorizationHeader = "Authorization"
+
+// tokenExpiryMargin is how long before its expiry an OAuth2 token is already refreshed,
+// so that it does not expire while a request is on its way.
+const tokenExpiryMargin = 30 * time.Second
+
+// Authenticator adds credentials to requests sent to VaaS.
+// It is called before every attempt of a request, so it may refresh credentials that expire.
+type Authenticator interface {
+	Authenticate(request *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to Authenticator.
+type AuthenticatorFunc func(request *http.Request) error
+
+// Authenticate implements Authenticator.
+func (f AuthenticatorFunc) Authenticate(request *http.Request) error {
+	return f(request)
+}
+
+// WithAuthenticator replaces the default authentication, an "Authorization: ApiKey" header
+// with username and API key given to NewClient.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(c *defaultClient) {
+		c.auth = authenticator
+	}
+}
+
+// WithQueryCredentials sends username and API key given to NewClient in username and api_key query parameters,
+// for VaaS deployments behind proxies that drop the Authorization header. Credentials then reach access logs
+// of VaaS and any proxy in between, so the header, used by default, is preferred.
+func WithQueryCredentials() Option {
+	return func(c *defaultClient) {
+		c.auth = queryAuthenticator(c.username, c.apiKey)
+	}
+}
+
+// WithBearerToken authenticates requests with a static "Authorization: Bearer" token.
+func WithBearerToken(token string) Option {
+	return WithAuthenticator(BearerToken(token))
+}
+
+// WithClientCredentials authenticates requests with tokens obtained in OAuth2 client credentials flow.
+// Tokens are requested with the client HTTP client unless credentials set one.
+func WithClientCredentials(credentials ClientCredentials) Option {
+	return func(c *defaultClient) {
+		if credentials.HTTPClient == nil {
+			credentials.HTTPClient = c.httpClient
+		}
+		c.auth = NewClientCredentialsAuthenticator(credentials)
+	}
+}
+
+// queryAuthenticator passes username and API key in query parameters.
+func queryAuthenticator(username, apiKey string) Authenticator {
+	return AuthenticatorFunc(func(request *http.Request) error {
+		query := request.URL.Query()
+		query.Set("username", username)
+		query.Set("api_key", apiKey)
+		request.URL.RawQuery = query.Encode()
+		return nil
+	})
+}
+
+// APIKeyHeader returns an Authenticator sending username and API key in an "Authorization: ApiKey" header,
+// understood by tastypie ApiKeyAuthentication on which VaaS API is built.
+func APIKeyHeader(username, apiKey string) Authenticator {
+	return AuthenticatorFunc(func(request *http.Request) error {
+		request.Header.Set(authorizationHeader, fmt.Sprintf("ApiKey %s:%s", username, apiKey))
+		return nil
+	})
+}
+
+// BearerToken returns an Authenticator sending token in an "Authorization: Bearer" header.
+func BearerToken(token string) Authenticator {
+	return AuthenticatorFunc(func(request *http.Request) error {
+		request.Header.Set(authorizationHeader, "Bearer "+token)
+		return nil
+	})
+}
+
+// ClientCredentials configures OAuth2 client credentials flow.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient requests tokens, http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// ClientCredentialsAuthenticator sends OAuth2 bearer tokens obtained with client credentials.
+// A token is reused until shortly before it expires, or until VaaS rejects it, and then requested again.
+type ClientCredentialsAuthenticator struct {
+	credentials ClientCredentials
+	now         func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewClientCredentialsAuthenticator creates an Authenticator for OAuth2 client credentials flow.
+func NewClientCredentialsAuthenticator(credentials ClientCredentials) *ClientCredentialsAuthenticator {
+	if credentials.HTTPClient == nil {
+		credentials.HTTPClient = http.DefaultClient
+	}
+	return &ClientCredentialsAuthenticator{credentials: credentials, now: time.Now}
+}
+
+// Authenticate implements Authenticator, requesting a new token when there is no valid one.
+func (a *ClientCredentialsAuthenticator) Authenticate(request *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token == "" || (!a.expires.IsZero() && !a.now().Before(a.expires)) {
+		if err := a.refresh(request); err != nil {
+			return err
+		}
+	}
+	request.Header.Set(authorizationHeader, "Bearer "+a.token)
+	return nil
+}
+
+// Invalidate drops the current token, so that the next request obtains a new one.
+func (a *ClientCredentialsAuthenticator) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// tokenResponse represents an OAuth2 access token response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (a *ClientCredentialsAuthenticator) refresh(request *http.Request) error {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(a.credentials.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.credentials.Scopes, " "))
+	}
+
+	tokenRequest, err := http.NewRequestWithContext(request.Context(), http.MethodPost, a.credentials.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	tokenRequest.Header.Set(contentTypeHeader, "application/x-www-form-urlencoded")
+	tokenRequest.Header.Set(acceptHeader, applicationJSON)
+	tokenRequest.SetBasicAuth(url.QueryEscape(a.credentials.ClientID), url.QueryEscape(a.credentials.ClientSecret))
+
+	response, err := a.credentials.HTTPClient.Do(tokenRequest)
+	if err != nil {
+		return fmt.Errorf("unable to obtain OAuth2 token: %w", err)
+	}
+	defer response.Body.Close()
+
+	rawResponse, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read OAuth2 token: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: OAuth2 token request failed (HTTP %d): %s",
+			ErrUnauthorized, response.StatusCode, strings.TrimSpace(string(rawResponse)))
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(rawResponse, &token); err != nil {
+		return fmt.Errorf("unable to parse OAuth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("%w: OAuth2 token response has no access token", ErrUnauthorized)
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return fmt.Errorf("unsupported OAuth2 token type %q", token.TokenType)
+	}
+
+	a.token = token.AccessToken
+	a.expires = time.Time{}
+	if token.ExpiresIn > 0 {
+		a.expires = a.now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+	}
+	return nil
+}
+
+// invalidator is implemented by authenticators whose credentials can be dropped once VaaS rejects them.
+type invalidator interface {
+	Invalidate()
+}
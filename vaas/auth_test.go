@@ -0,0 +1,145 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dcServer serves a single DC to requests whose Authorization header is accepted by authorized.
+func dcServer(t *testing.T, authorized func(header string) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r.Header.Get(authorizationHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+}
+
+func TestClientSendsAPIKeyHeaderByDefault(t *testing.T) {
+	ts := dcServer(t, func(header string) bool { return header == "ApiKey username:api-key" })
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+}
+
+func TestClientSendsQueryCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(authorizationHeader))
+		assert.Equal(t, "username", r.URL.Query().Get("username"))
+		assert.Equal(t, "api-key", r.URL.Query().Get("api_key"))
+		_, err := w.Write([]byte(`{"objects": []}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithQueryCredentials())
+
+	require.NoError(t, client.ValidateCredentials(context.Background()))
+}
+
+func TestClientSendsBearerToken(t *testing.T) {
+	ts := dcServer(t, func(header string) bool { return header == "Bearer token" })
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "", "", WithBearerToken("token"))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+}
+
+// tokenServer issues tokens numbered from 1, valid for expiresIn seconds.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var issued int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client", clientID)
+		assert.Equal(t, "secret", secret)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "vaas:read vaas:write", r.PostForm.Get("scope"))
+
+		n := atomic.AddInt32(&issued, 1)
+		data, _ := json.Marshal(tokenResponse{AccessToken: fmt.Sprintf("token-%d", n), TokenType: "bearer", ExpiresIn: expiresIn})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	return ts, &issued
+}
+
+func TestClientCredentialsReusesTokenUntilExpiry(t *testing.T) {
+	tokens, issued := tokenServer(t, 60)
+	defer tokens.Close()
+	ts := dcServer(t, func(header string) bool { return header != "" })
+	defer ts.Close()
+
+	now := time.Now()
+	auth := NewClientCredentialsAuthenticator(ClientCredentials{
+		TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"vaas:read", "vaas:write"},
+	})
+	auth.now = func() time.Time { return now }
+	client := NewClient(ts.URL, "", "", WithAuthenticator(auth), WithDCCacheTTL(0))
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+
+	now = now.Add(time.Minute)
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+}
+
+func TestClientCredentialsRefreshesRejectedToken(t *testing.T) {
+	tokens, issued := tokenServer(t, 0)
+	defer tokens.Close()
+	ts := dcServer(t, func(header string) bool { return header == "Bearer token-2" })
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "", "", WithClientCredentials(ClientCredentials{
+		TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"vaas:read", "vaas:write"},
+	}))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+	require.True(t, errors.Is(err, ErrUnauthorized))
+
+	_, err = client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+}
+
+func TestClientCredentialsFailsWhenTokenIsRefused(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+	}))
+	defer tokens.Close()
+
+	client := NewClient("http://vaas.invalid", "", "", WithClientCredentials(ClientCredentials{TokenURL: tokens.URL}))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.True(t, errors.Is(err, ErrUnauthorized))
+	assert.Contains(t, err.Error(), "invalid_client")
+}
@@ -0,0 +1,224 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UpsertBackend creates or replaces backend in VaaS, keeping its ID when one is set.
+// Backends without ID are created with POST under an ID assigned by VaaS. Backends with ID are sent with PUT
+// to their detail path, which creates them under that ID or replaces an existing one. This relies on the
+// create-or-replace semantics of tastypie, on which VaaS API v0.1 is built, and may not be available when
+// VaaS restricts PUT on backends. Any representation returned by VaaS is decoded back into backend.
+func (c *defaultClient) UpsertBackend(ctx context.Context, backend *Backend) error {
+	endpoint, err := c.endpoint(ctx, backendPath)
+	if err != nil {
+		return err
+	}
+	method, url := http.MethodPost, endpoint
+	if backend.ID != nil {
+		method, url = http.MethodPut, fmt.Sprintf("%s%d/", endpoint, *backend.ID)
+	}
+
+	request, err := c.newRequest(ctx, method, url, backend)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.do(request)
+	if err != nil {
+		return err
+	}
+
+	rawResponse, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if location := response.Header.Get("Location"); location != "" {
+		backend.ResourceURI = location
+	}
+	if len(rawResponse) > 0 {
+		if err := c.unmarshal(response, rawResponse, backend); err != nil {
+			return err
+		}
+	}
+
+	logger := log.WithField(vaasBackendIDKey, backend.ResourceURI)
+	if backend.ID != nil {
+		logger = log.WithField(vaasBackendIDKey, *backend.ID)
+	}
+	if response.StatusCode == http.StatusCreated {
+		logger.Info("Backend created in VaaS")
+	} else {
+		logger.Info("Backend replaced in VaaS")
+	}
+	return nil
+}
+
+// EnsureBackend adds backend to director unless VaaS reports it already exists, so it is safe to call repeatedly.
+// It returns whether the backend was created. Either way backend is filled with its representation in VaaS.
+// Other errors are returned as they are, without looking the backend up.
+func (c *defaultClient) EnsureBackend(ctx context.Context, backend *Backend, director *Director) (bool, error) {
+	return c.ensureBackendOnce(ctx, backend, director)
+}
+
+func (c *defaultClient) ensureBackend(ctx context.Context, backend *Backend, director *Director) (bool, error) {
+	endpoint, err := c.endpoint(ctx, backendPath)
+	if err != nil {
+		return false, err
+	}
+	request, err := c.newRequest(ctx, http.MethodPost, endpoint, backend)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := c.doAddBackend(request)
+	if err == nil {
+		err = c.decodeResponse(response, backend)
+	}
+	if errors.Is(err, ErrConflict) {
+		existing, findErr := c.FindBackend(ctx, director, backend.Address, backend.Port)
+		if findErr != nil {
+			return false, fmt.Errorf("%s, but it could not be found: %w", err, findErr)
+		}
+		*backend = *existing
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if location := response.Header.Get("Location"); location != "" {
+		backend.ResourceURI = location
+	}
+	return true, nil
+}
+
+// DeleteBackendByAddress removes every backend with given address and port from director with given name,
+// so that deregistration does not depend on a backend ID stored at registration time.
+// It returns ErrBackendNotFound when there is no such backend.
+func (c *defaultClient) DeleteBackendByAddress(ctx context.Context, director string, address string, port int) error {
+	return c.deleteBackendsByAddress(ctx, director, address, port, c.DeleteBackend)
+}
+
+// DeleteBackendByAddressAndWait is DeleteBackendByAddress waiting until VaaS applies every removal.
+func (c *defaultClient) DeleteBackendByAddressAndWait(ctx context.Context, director string, address string, port int) error {
+	return c.deleteBackendsByAddress(ctx, director, address, port, c.DeleteBackendAndWait)
+}
+
+func (c *defaultClient) deleteBackendsByAddress(ctx context.Context, directorName string, address string, port int,
+	deleteByID func(context.Context, int) error) error {
+	director, err := c.FindDirector(ctx, directorName)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("address", NormalizeAddress(address))
+	query.Set("director", fmt.Sprintf("%d", director.ID))
+	query.Set("port", fmt.Sprintf("%d", port))
+	backends, err := c.listBackends(ctx, query)
+	if err != nil {
+		return fmt.Errorf("backend list fetch failed: %w", err)
+	}
+
+	deleted := 0
+	for _, backend := range backends {
+		if backend.ID == nil || !SameAddress(backend.Address, address) || backend.Port != port {
+			continue
+		}
+		if err := deleteByID(ctx, int(*backend.ID)); err != nil {
+			return err
+		}
+		log.WithField(vaasBackendIDKey, *backend.ID).Info("Backend removed from VaaS")
+		deleted++
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: no backend %s in director %s", ErrBackendNotFound, HostPort(address, port), directorName)
+	}
+	return nil
+}
+
+// BackendPatch represents changes applied to an existing backend with UpdateBackend.
+// Nil fields are left unchanged; a non-nil empty Tags clears backend tags.
+type BackendPatch struct {
+	Weight             *int
+	Tags               []string
+	Enabled            *bool
+	InheritTimeProfile *bool
+	// TimeProfile sets limits and timeouts of the profile on backend, see Backend.ApplyTimeProfile.
+	TimeProfile *TimeProfile
+}
+
+// MarshalJSON encodes only the fields of patch that are set.
+func (p BackendPatch) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{}
+	if p.Weight != nil {
+		body["weight"] = *p.Weight
+	}
+	if p.Tags != nil {
+		body["tags"] = p.Tags
+	}
+	if p.Enabled != nil {
+		body["enabled"] = *p.Enabled
+	}
+	if p.InheritTimeProfile != nil {
+		body["inherit_time_profile"] = *p.InheritTimeProfile
+	}
+	if p.TimeProfile != nil {
+		body["inherit_time_profile"] = false
+		body["max_connections"] = p.TimeProfile.MaxConnections
+		body["connect_timeout"] = p.TimeProfile.ConnectTimeout
+		body["first_byte_timeout"] = p.TimeProfile.FirstByteTimeout
+		body["between_bytes_timeout"] = p.TimeProfile.BetweenBytesTimeout
+	}
+	return json.Marshal(body)
+}
+
+// Apply sets fields of patch on backend.
+func (p BackendPatch) Apply(backend *Backend) {
+	if p.Weight != nil {
+		backend.SetWeight(*p.Weight)
+	}
+	if p.Tags != nil {
+		backend.Tags = append([]string{}, p.Tags...)
+	}
+	if p.Enabled != nil {
+		enabled := *p.Enabled
+		backend.Enabled = &enabled
+	}
+	if p.InheritTimeProfile != nil {
+		backend.InheritTimeProfile = *p.InheritTimeProfile
+	}
+	if p.TimeProfile != nil {
+		backend.ApplyTimeProfile(p.TimeProfile)
+	}
+}
+
+// UpdateBackend changes an existing backend in place with PATCH.
+func (c *defaultClient) UpdateBackend(ctx context.Context, id int, patch BackendPatch) error {
+	if patch.Weight != nil {
+		if err := validateWeight(*patch.Weight); err != nil {
+			return err
+		}
+	}
+
+	endpoint, err := c.endpoint(ctx, backendPath)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodPatch, fmt.Sprintf("%s%d/", endpoint, id), patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(request)
+	return err
+}
@@ -0,0 +1,149 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertBackendWithIDIsPut(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/v0.1/backend/42/", r.URL.Path)
+
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var backend Backend
+		require.NoError(t, json.Unmarshal(rawRequest, &backend))
+		assert.Equal(t, ID(42), *backend.ID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+	backend.ID = NewID(42)
+
+	err := NewClient(ts.URL, "username", "api-key").UpsertBackend(context.Background(), backend)
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(42), *backend.ID)
+}
+
+func TestUpsertBackendDoesNotSendCreated(t *testing.T) {
+	var sent map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(rawRequest, &sent))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	var backend Backend
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 42, "address": "127.0.0.1", "port": 80,
+		"created": "2026-03-01T10:00:00"}`), &backend))
+
+	require.NoError(t, NewClient(ts.URL, "username", "api-key").UpsertBackend(context.Background(), &backend))
+
+	require.NotNil(t, backend.Created)
+	assert.Equal(t, float64(42), sent["id"])
+	assert.NotContains(t, sent, "created")
+}
+
+func TestUpsertBackendWithoutIDIsPost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, apiBackendPath, r.URL.Path)
+
+		w.Header().Set("Location", "/api/v0.1/backend/1/")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write(mockAddBackendResponse)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+
+	err := NewClient(ts.URL, "username", "api-key").UpsertBackend(context.Background(), backend)
+
+	require.NoError(t, err)
+	require.NotNil(t, backend.ID)
+	assert.Equal(t, ID(1), *backend.ID)
+	assert.Equal(t, "/api/v0.1/backend/1/", backend.ResourceURI)
+}
+
+func TestUpsertBackendFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+	backend.ID = NewID(42)
+
+	assert.Error(t, NewClient(ts.URL, "username", "api-key").UpsertBackend(context.Background(), backend))
+}
+
+func TestUpdateBackendPatchesOnlySetFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/api/v0.1/backend/42/", r.URL.Path)
+
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"tags":[],"inherit_time_profile":true}`, string(rawRequest))
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	inherit := true
+	patch := BackendPatch{Tags: []string{}, InheritTimeProfile: &inherit}
+
+	err := NewClient(ts.URL, "username", "api-key").UpdateBackend(context.Background(), 42, patch)
+
+	require.NoError(t, err)
+}
+
+func TestUpdateBackendRejectsWeightOutOfRange(t *testing.T) {
+	weight := MaxWeight + 1
+
+	err := NewClient("http://localhost", "username", "api-key").UpdateBackend(context.Background(), 42, BackendPatch{Weight: &weight})
+
+	require.Error(t, err)
+}
+
+func TestDeleteBackendByAddressRemovesEveryMatch(t *testing.T) {
+	var deleted []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == apiDirectorPath:
+			data, _ := json.Marshal(DirectorList{Objects: []Director{*createDirector(1)}})
+			_, err := w.Write(data)
+			assert.NoError(t, err)
+		case r.URL.Path == apiBackendPath:
+			assert.Equal(t, "1", r.URL.Query().Get("director"))
+			first, second := createBackend(), createBackend()
+			first.ID, second.ID = NewID(3), NewID(4)
+			data, _ := json.Marshal(BackendList{Objects: []Backend{*first, *second}})
+			_, err := w.Write(data)
+			assert.NoError(t, err)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	err := NewClient(ts.URL, "username", "api-key").DeleteBackendByAddress(context.Background(), "director", "127.0.0.1", 8080)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/api/v0.1/backend/3/", "/api/v0.1/backend/4/"}, deleted)
+}
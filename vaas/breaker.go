@@ -0,0 +1,90 @@
+package vaas
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker stops sending requests to VaaS once it fails repeatedly, so that callers fail fast
+// instead of waiting for every request to time out. After a cooldown a single probe request is let through;
+// its success closes the breaker again, its failure keeps it open for another cooldown.
+// Transport errors and 5xx responses are failures; other responses, including 4xx ones, are successes.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a breaker opening after threshold consecutive failures for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// WithCircuitBreaker makes the client send requests through breaker, possibly shared with other clients.
+// Requests rejected by an open breaker fail with ErrCircuitOpen and are not retried.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(c *defaultClient) {
+		c.breaker = breaker
+	}
+}
+
+// Open tells whether the breaker currently rejects requests.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && (b.probing || b.now().Sub(b.openedAt) < b.cooldown)
+}
+
+// allow returns ErrCircuitOpen unless a request may be sent now.
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing {
+		return fmt.Errorf("%w: probe request in progress", ErrCircuitOpen)
+	}
+	if wait := b.cooldown - b.now().Sub(b.openedAt); wait > 0 {
+		return fmt.Errorf("%w after %d consecutive failures, retry in %s", ErrCircuitOpen, b.failures, wait.Round(time.Millisecond))
+	}
+	b.probing = true
+	return nil
+}
+
+// record updates the breaker with the outcome of a request it allowed.
+// Requests cancelled by the caller tell nothing about VaaS and are not counted.
+func (b *CircuitBreaker) record(request *http.Request, response *http.Response, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil && request.Context().Err() != nil {
+		return
+	}
+	if err == nil && response != nil && response.StatusCode < http.StatusInternalServerError {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
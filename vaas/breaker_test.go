@@ -0,0 +1,74 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveFailuresAndProbes(t *testing.T) {
+	attempts := 0
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	client := NewClient(ts.URL, "username", "api-key", WithCircuitBreaker(breaker), WithRetryPolicy(testRetryPolicy))
+
+	err := client.DeleteBackend(context.Background(), 1)
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 2, attempts)
+	assert.True(t, breaker.Open())
+
+	err = client.DeleteBackend(context.Background(), 1)
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 2, attempts)
+
+	now = now.Add(time.Minute)
+	status = http.StatusNoContent
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, 3, attempts)
+	assert.False(t, breaker.Open())
+}
+
+func TestCircuitBreakerReopensWhenProbeFails(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	failure := &http.Response{StatusCode: http.StatusInternalServerError}
+
+	require.NoError(t, breaker.allow())
+	breaker.record(request, failure, nil)
+	require.True(t, errors.Is(breaker.allow(), ErrCircuitOpen))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, breaker.allow())
+	require.True(t, errors.Is(breaker.allow(), ErrCircuitOpen), "only one probe at a time")
+	breaker.record(request, failure, nil)
+
+	require.True(t, errors.Is(breaker.allow(), ErrCircuitOpen))
+}
+
+func TestCircuitBreakerIgnoresClientErrorsAndCancellation(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	breaker.record(cancelled, nil, context.Canceled)
+	breaker.record(httptest.NewRequest(http.MethodGet, "/", nil), &http.Response{StatusCode: http.StatusNotFound}, nil)
+
+	assert.False(t, breaker.Open())
+}
@@ -0,0 +1,165 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultBulkConcurrency is the number of requests AddBackends sends in parallel by default.
+const DefaultBulkConcurrency = 4
+
+// WithBulkConcurrency sets the number of requests AddBackends sends in parallel.
+func WithBulkConcurrency(concurrency int) Option {
+	return func(c *defaultClient) {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		c.bulkConcurrency = concurrency
+	}
+}
+
+// BulkFailure represents a backend that could not be processed in a bulk operation.
+type BulkFailure struct {
+	// Index is the position of Backend in the slice passed to the bulk operation.
+	Index   int
+	Backend *Backend
+	Err     error
+}
+
+// BulkError aggregates failures of a bulk operation, ordered by index.
+type BulkError struct {
+	Failures []BulkFailure
+}
+
+// Error implements error.
+func (e *BulkError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, fmt.Sprintf("%s: %s", HostPort(failure.Backend.Address, failure.Backend.Port), failure.Err))
+	}
+	return fmt.Sprintf("%d backends failed: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the failures matches target.
+func (e *BulkError) Is(target error) bool {
+	for _, failure := range e.Failures {
+		if errors.Is(failure.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddBackends adds backends to director in parallel, with at most WithBulkConcurrency requests at once.
+// It returns resource URIs in order of backends, empty for failed ones, and a *BulkError if any of them failed.
+func (c *defaultClient) AddBackends(ctx context.Context, backends []*Backend, director *Director) ([]string, error) {
+	uris := make([]string, len(backends))
+	errs := make([]error, len(backends))
+
+	slots := make(chan struct{}, c.bulkConcurrency)
+	var wg sync.WaitGroup
+	for i := range backends {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			var result RegistrationResult
+			result, errs[i] = c.AddBackend(ctx, backends[i], director)
+			uris[i] = result.ResourceURI
+		}(i)
+	}
+	wg.Wait()
+
+	return uris, newBulkError(backends, errs)
+}
+
+// newBulkError returns a *BulkError for non-nil errs, or nil if there are none.
+func newBulkError(backends []*Backend, errs []error) error {
+	bulkError := &BulkError{}
+	for i, err := range errs {
+		if err != nil {
+			bulkError.Failures = append(bulkError.Failures, BulkFailure{Index: i, Backend: backends[i], Err: err})
+		}
+	}
+	if len(bulkError.Failures) == 0 {
+		return nil
+	}
+	return bulkError
+}
+
+// DirectorResult is the outcome of an operation on a backend in one of several directors.
+type DirectorResult struct {
+	Director    string
+	ResourceURI string
+	Err         error
+}
+
+// DirectorsError aggregates failures of an operation on several directors, in their order.
+type DirectorsError struct {
+	Failures []DirectorResult
+}
+
+// Error implements error.
+func (e *DirectorsError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, fmt.Sprintf("%s: %s", failure.Director, failure.Err))
+	}
+	return fmt.Sprintf("%d directors failed: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the failures matches target.
+func (e *DirectorsError) Is(target error) bool {
+	for _, failure := range e.Failures {
+		if errors.Is(failure.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddBackendToDirectors adds backend at the same address and port to every director with given name, one by one.
+// It returns a result for every director, in their order, and a *DirectorsError if any of them failed.
+// Backend itself is left unchanged; each director gets a copy.
+func (c *defaultClient) AddBackendToDirectors(ctx context.Context, backend *Backend, directors []string) ([]DirectorResult, error) {
+	results := make([]DirectorResult, len(directors))
+	for i, name := range directors {
+		results[i].Director = name
+		director, err := c.FindDirector(ctx, name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		added := *backend
+		added.DirectorURL = director.ResourceURI
+		var result RegistrationResult
+		result, results[i].Err = c.AddBackend(ctx, &added, director)
+		results[i].ResourceURI = result.ResourceURI
+	}
+	return results, NewDirectorsError(results)
+}
+
+// NewDirectorsError returns a *DirectorsError for failed results, or nil if there are none.
+func NewDirectorsError(results []DirectorResult) error {
+	directorsError := &DirectorsError{}
+	for _, result := range results {
+		if result.Err != nil {
+			directorsError.Failures = append(directorsError.Failures, result)
+		}
+	}
+	if len(directorsError.Failures) == 0 {
+		return nil
+	}
+	return directorsError
+}
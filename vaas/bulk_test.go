@@ -0,0 +1,100 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBackendsLimitsConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("Location", "/api/v0.1/backend/1/")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write(mockAddBackendResponse)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	backends := []*Backend{createBackend(), createBackend(), createBackend(), createBackend(), createBackend()}
+	client := NewClient(ts.URL, "username", "api-key", WithBulkConcurrency(2))
+
+	uris, err := client.AddBackends(context.Background(), backends, createDirector(1))
+
+	require.NoError(t, err)
+	require.Len(t, uris, len(backends))
+	for _, uri := range uris {
+		assert.Equal(t, "/api/v0.1/backend/1/", uri)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestAddBackendsAggregatesErrorsPerBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	backends := []*Backend{createBackend(), createBackend()}
+
+	uris, err := NewClient(ts.URL, "username", "api-key").AddBackends(context.Background(), backends, createDirector(1))
+
+	var bulkError *BulkError
+	require.True(t, errors.As(err, &bulkError))
+	require.Len(t, bulkError.Failures, 2)
+	assert.Equal(t, 0, bulkError.Failures[0].Index)
+	assert.Equal(t, 1, bulkError.Failures[1].Index)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.Equal(t, []string{"", ""}, uris)
+}
+
+func TestAddBackendToDirectorsReportsResultPerDirector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("name") == "internal":
+			_, err := w.Write([]byte(`{"objects": [{"id": 1, "name": "internal", "resource_uri": "/api/v0.1/director/1/"}]}`))
+			assert.NoError(t, err)
+		case r.Method == http.MethodGet:
+			_, err := w.Write([]byte(`{"objects": []}`))
+			assert.NoError(t, err)
+		default:
+			w.Header().Set("Location", "/api/v0.1/backend/1/")
+			w.WriteHeader(http.StatusCreated)
+			_, err := w.Write(mockAddBackendResponse)
+			assert.NoError(t, err)
+		}
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+	results, err := NewClient(ts.URL, "username", "api-key").
+		AddBackendToDirectors(context.Background(), backend, []string{"internal", "external"})
+
+	var directorsError *DirectorsError
+	require.True(t, errors.As(err, &directorsError))
+	require.Len(t, directorsError.Failures, 1)
+	assert.Equal(t, "external", directorsError.Failures[0].Director)
+	assert.True(t, errors.Is(err, ErrDirectorNotFound))
+	require.Len(t, results, 2)
+	assert.Equal(t, "/api/v0.1/backend/1/", results[0].ResourceURI)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "external", results[1].Director)
+	assert.Equal(t, "directorURL", backend.DirectorURL)
+}
@@ -0,0 +1,173 @@
+package vaas
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultCacheTTL is how long directors and DCs are cached by default.
+const DefaultCacheTTL = time.Minute
+
+// LookupCache keeps directors and DCs found by the client for a TTL, as they change rarely but are looked up on
+// every registration. Only found objects are cached, so a missing director is looked up again once created.
+// With a file the cache is shared by short-lived processes, e.g. hooks of several containers of a host.
+// It is safe for concurrent use.
+type LookupCache struct {
+	ttl  time.Duration
+	file string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Expires  time.Time `json:"expires"`
+	Director *Director `json:"director,omitempty"`
+	DC       *DC       `json:"dc,omitempty"`
+}
+
+// NewLookupCache creates a cache keeping lookups for ttl, in file as well when it is not empty.
+func NewLookupCache(ttl time.Duration, file string) *LookupCache {
+	return &LookupCache{ttl: ttl, file: file, now: time.Now, entries: map[string]cacheEntry{}}
+}
+
+// WithLookupCache makes the client cache results of FindDirector and GetDC in cache.
+// Creating, updating or deleting directors with the client invalidates cached directors.
+func WithLookupCache(cache *LookupCache) Option {
+	return func(c *defaultClient) {
+		c.cache = cache
+	}
+}
+
+func directorCacheKey(host, name string) string {
+	return host + " director " + name
+}
+
+func dcCacheKey(host, name string) string {
+	return host + " dc " + name
+}
+
+// get returns a copy of the entry cached under key, if it did not expire
+func (c *LookupCache) get(key string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.Expires) {
+		return cacheEntry{}, false
+	}
+	if entry.Director != nil {
+		director := *entry.Director
+		entry.Director = &director
+	}
+	if entry.DC != nil {
+		dc := *entry.DC
+		entry.DC = &dc
+	}
+	return entry, true
+}
+
+func (c *LookupCache) putDirector(key string, director Director) {
+	c.put(key, cacheEntry{Director: &director})
+}
+
+func (c *LookupCache) putDC(key string, dc DC) {
+	c.put(key, cacheEntry{DC: &dc})
+}
+
+func (c *LookupCache) put(key string, entry cacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	entry.Expires = c.now().Add(c.ttl)
+	c.entries[key] = entry
+	c.save()
+}
+
+// invalidateDirectors drops cached directors, as their IDs or names may have changed
+func (c *LookupCache) invalidateDirectors() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	for key, entry := range c.entries {
+		if entry.Director != nil {
+			delete(c.entries, key)
+		}
+	}
+	c.save()
+}
+
+// load reads entries of the cache file once, ignoring a missing or corrupted file
+func (c *LookupCache) load() {
+	if c.loaded || c.file == "" {
+		return
+	}
+	c.loaded = true
+
+	data, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugf("Ignoring cache file: %s", err)
+		}
+		return
+	}
+	entries := map[string]cacheEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Debugf("Ignoring corrupted cache file %s: %s", c.file, err)
+		return
+	}
+	now := c.now()
+	for key, entry := range entries {
+		if now.Before(entry.Expires) {
+			c.entries[key] = entry
+		}
+	}
+}
+
+// save replaces the cache file with current entries, so that concurrent readers never see a partial file
+func (c *LookupCache) save() {
+	if c.file == "" {
+		return
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		log.Debugf("Could not encode cache: %s", err)
+		return
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err != nil {
+		log.Debugf("Could not write cache file: %s", err)
+		return
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), c.file)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		log.Debugf("Could not write cache file: %s", err)
+	}
+}
@@ -0,0 +1,137 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupServer(t *testing.T, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		var err error
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, err = w.Write([]byte(`{"id": 2, "name": "other"}`))
+		case r.URL.Path == apiDirectorPath:
+			_, err = w.Write([]byte(`{"objects": [{"id": 1, "name": "director"}]}`))
+		case r.URL.Path == apiDcPath:
+			_, err = w.Write([]byte(`{"objects": [{"id": 1, "name": "First", "symbol": "dc1"}]}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		assert.NoError(t, err)
+	}))
+}
+
+func TestLookupCacheServesRepeatedLookups(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithLookupCache(NewLookupCache(time.Minute, "")))
+
+	for i := 0; i < 2; i++ {
+		director, err := client.FindDirector(context.Background(), "director")
+		require.NoError(t, err)
+		assert.Equal(t, ID(1), director.ID)
+		dc, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+		assert.Equal(t, ID(1), dc.ID)
+	}
+
+	assert.Equal(t, []string{"GET " + apiDirectorPath, "GET " + apiDcPath}, requests)
+}
+
+func TestLookupCacheExpires(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	now := time.Now()
+	cache := NewLookupCache(time.Minute, "")
+	cache.now = func() time.Time { return now }
+	client := NewClient(ts.URL, "username", "api-key", WithLookupCache(cache))
+
+	_, err := client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+
+	assert.Len(t, requests, 2)
+}
+
+func TestLookupCacheDoesNotCacheMissingDirectors(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithLookupCache(NewLookupCache(time.Minute, "")))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.FindDirector(context.Background(), "missing")
+		assert.True(t, errors.Is(err, ErrDirectorNotFound), err)
+	}
+
+	assert.Len(t, requests, 2)
+}
+
+func TestLookupCacheIsInvalidatedByDirectorChanges(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithLookupCache(NewLookupCache(time.Minute, "")))
+
+	_, err := client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	require.NoError(t, client.CreateDirector(context.Background(), &Director{Name: "other"}))
+	_, err = client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"GET " + apiDirectorPath, "POST " + apiDirectorPath, "GET " + apiDirectorPath}, requests)
+}
+
+func TestLookupCacheIsSharedThroughFile(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "vaas-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cache.json")
+
+	for i := 0; i < 2; i++ {
+		client := NewClient(ts.URL, "username", "api-key", WithLookupCache(NewLookupCache(time.Minute, file)))
+		director, err := client.FindDirector(context.Background(), "director")
+		require.NoError(t, err)
+		assert.Equal(t, "director", director.Name)
+	}
+
+	assert.Len(t, requests, 1)
+}
+
+func TestLookupCacheIgnoresCorruptedFile(t *testing.T) {
+	var requests []string
+	ts := lookupServer(t, &requests)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "vaas-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cache.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte("{"), 0600))
+	client := NewClient(ts.URL, "username", "api-key", WithLookupCache(NewLookupCache(time.Minute, file)))
+
+	_, err = client.FindDirector(context.Background(), "director")
+
+	require.NoError(t, err)
+	assert.Len(t, requests, 1)
+}
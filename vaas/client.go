@@ -2,20 +2,42 @@ package vaas
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/tracing"
+)
+
+// Resource paths, relative to the prefix of an API version
+const (
+	apiRootPath     = "/api/"
+	backendPath     = "/backend/"
+	dcPath          = "/dc/"
+	directorPath    = "/director/"
+	timeProfilePath = "/time_profile/"
 )
 
+// Resource paths in the default API version
 const (
-	apiPrefixPath   = "/api/v0.1"
-	apiBackendPath  = apiPrefixPath + "/backend/"
-	apiDcPath       = apiPrefixPath + "/dc/"
-	apiDirectorPath = apiPrefixPath + "/director/"
+	apiPrefixPath   = apiRootPath + DefaultAPIVersion
+	apiBackendPath  = apiPrefixPath + backendPath
+	apiDcPath       = apiPrefixPath + dcPath
+	apiDirectorPath = apiPrefixPath + directorPath
+
+	apiTimeProfilePath = apiPrefixPath + timeProfilePath
 )
 
 const vaasBackendIDKey = "vaas-backend-id"
@@ -25,11 +47,12 @@ const (
 	acceptHeader      = "Accept"
 	preferHeader      = "Prefer"
 	applicationJSON   = "application/json"
+	respondAsync      = "respond-async"
 )
 
 // Backend represents JSON structure of backend in VaaS API.
 type Backend struct {
-	ID                 *int     `json:"id,omitempty"`
+	ID                 *ID      `json:"id,omitempty"`
 	Address            string   `json:"address,omitempty"`
 	DirectorURL        string   `json:"director,omitempty"`
 	DC                 DC       `json:"dc,omitempty"`
@@ -38,6 +61,20 @@ type Backend struct {
 	Weight             *int     `json:"weight,omitempty"`
 	Tags               []string `json:"tags,omitempty"`
 	ResourceURI        string   `json:"resource_uri,omitempty"`
+	// Enabled is false for backends disabled in VaaS, which leaves backends enabled when it is not sent.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Created is when VaaS created the backend, if it reports it; it is read-only in VaaS and never encoded.
+	Created *Timestamp `json:"created,omitempty"`
+
+	MaxConnections      int     `json:"max_connections,omitempty"`
+	ConnectTimeout      Seconds `json:"connect_timeout,omitempty"`
+	FirstByteTimeout    Seconds `json:"first_byte_timeout,omitempty"`
+	BetweenBytesTimeout Seconds `json:"between_bytes_timeout,omitempty"`
+
+	// TaskURI is the URI of the VaaS task that added the backend, set by AddBackendAndWait; it is not sent to VaaS.
+	TaskURI string `json:"-"`
+	// Extra are fields of the backend in VaaS which are not modeled above, sent back with it.
+	Extra Fields `json:"-"`
 }
 
 // BackendList represents JSON structure of Backend list used in responses in VaaS API.
@@ -48,7 +85,7 @@ type BackendList struct {
 
 // DC represents JSON structure of DC in VaaS API.
 type DC struct {
-	ID          int    `json:"id,omitempty"`
+	ID          ID     `json:"id,omitempty"`
 	Name        string `json:"name,omitempty"`
 	ResourceURI string `json:"resource_uri,omitempty"`
 	Symbol      string `json:"symbol,omitempty"`
@@ -61,11 +98,31 @@ type DCList struct {
 }
 
 // Director represents JSON structure of Director in VaaS API.
+// Probe, TimeProfile and Clusters hold resource URIs of related objects.
 type Director struct {
-	ID          int      `json:"id,omitempty"`
-	BackendURLs []string `json:"backends,omitempty"`
-	Name        string   `json:"name,omitempty"`
-	ResourceURI string   `json:"resource_uri,omitempty"`
+	ID              ID       `json:"id,omitempty"`
+	BackendURLs     []string `json:"backends,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Service         string   `json:"service,omitempty"`
+	Clusters        []string `json:"cluster,omitempty"`
+	Mode            string   `json:"mode,omitempty"`
+	Protocol        string   `json:"protocol,omitempty"`
+	Router          string   `json:"router,omitempty"`
+	RouteExpression string   `json:"route_expression,omitempty"`
+	Probe           string   `json:"probe,omitempty"`
+	TimeProfile     string   `json:"time_profile,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+	ResourceURI     string   `json:"resource_uri,omitempty"`
+
+	// ReachableViaServiceMesh routes traffic of the director through the service mesh instead of its backends.
+	ReachableViaServiceMesh *bool `json:"reachable_via_service_mesh,omitempty"`
+	// ServiceMeshLabel is the label of the service in the mesh, the service of the director when empty.
+	ServiceMeshLabel string `json:"service_mesh_label,omitempty"`
+	// ServiceTag selects the service instances of the director in the mesh.
+	ServiceTag string `json:"service_tag,omitempty"`
+
+	// Extra are fields of the director in VaaS which are not modeled above, sent back with it.
+	Extra Fields `json:"-"`
 }
 
 // DirectorList represents JSON structure of Director list used in responses in VaaS API.
@@ -86,178 +143,302 @@ type Meta struct {
 // Task represents JSON structure of a VaaS task in API.
 type Task struct {
 	Info        string `json:"info,omitempty"`
+	Status      string `json:"status,omitempty"`
 	ResourceURI string `json:"resource_uri,omitempty"`
 }
 
 // Client is an interface for VaaS API.
+// All methods accept a context, which bounds their requests and cancels them once done.
 type Client interface {
-	FindDirector(string) (*Director, error)
-	FindDirectorID(string) (int, error)
-	AddBackend(*Backend, *Director) (string, error)
-	DeleteBackend(int) error
-	GetDC(string) (*DC, error)
-	FindBackend(director *Director, address string, port int) (*Backend, error)
-	FindBackendID(director string, address string, port int) (int, error)
+	FindDirector(ctx context.Context, name string) (*Director, error)
+	FindDirectorID(ctx context.Context, name string) (int, error)
+	CreateDirector(ctx context.Context, director *Director) error
+	UpdateDirector(ctx context.Context, director *Director) error
+	DeleteDirector(ctx context.Context, id int) error
+	ListDirectors(ctx context.Context) ([]Director, error)
+	AddBackend(ctx context.Context, backend *Backend, director *Director) (RegistrationResult, error)
+	EnsureBackend(ctx context.Context, backend *Backend, director *Director) (bool, error)
+	UpsertBackend(ctx context.Context, backend *Backend) error
+	UpdateBackend(ctx context.Context, id int, patch BackendPatch) error
+	AddBackendAndWait(ctx context.Context, backend *Backend, director *Director) (RegistrationResult, error)
+	AddBackends(ctx context.Context, backends []*Backend, director *Director) ([]string, error)
+	AddBackendToDirectors(ctx context.Context, backend *Backend, directors []string) ([]DirectorResult, error)
+	DeleteBackend(ctx context.Context, id int) error
+	DeleteBackendAndWait(ctx context.Context, id int) error
+	DeleteBackendByAddress(ctx context.Context, director string, address string, port int) error
+	DeleteBackendByAddressAndWait(ctx context.Context, director string, address string, port int) error
+	DeleteBackendsWhere(ctx context.Context, director string, filter BackendFilter) ([]Backend, error)
+	GetTask(ctx context.Context, uri string) (*Task, error)
+	GetReloadStatus(ctx context.Context, director *Director) (*ReloadStatus, error)
+	SetBackendWeight(ctx context.Context, id int, weight int) error
+	EnableBackend(ctx context.Context, id int) error
+	DisableBackend(ctx context.Context, id int) error
+	GetBackend(ctx context.Context, id int) (*Backend, error)
+	AddBackendTags(ctx context.Context, id int, tags ...string) error
+	RemoveBackendTags(ctx context.Context, id int, tags ...string) error
+	GetDC(ctx context.Context, name string) (*DC, error)
+	ListDCs(ctx context.Context) ([]DC, error)
+	ListTimeProfiles(ctx context.Context) ([]TimeProfile, error)
+	GetTimeProfile(ctx context.Context, name string) (*TimeProfile, error)
+	FindBackend(ctx context.Context, director *Director, address string, port int) (*Backend, error)
+	FindBackendID(ctx context.Context, director string, address string, port int) (int, error)
+	ListBackends(ctx context.Context, director *Director) ([]Backend, error)
+	ListAllBackends(ctx context.Context) ([]Backend, error)
+	IterateBackends(ctx context.Context, director *Director) BackendIterator
+	SearchBackends(ctx context.Context, query BackendQuery) ([]Backend, error)
+	ListClusters(ctx context.Context) ([]Cluster, error)
+	GetCluster(ctx context.Context, name string) (*Cluster, error)
+	ListClusterDirectors(ctx context.Context, cluster *Cluster) ([]Director, error)
+	ListClusterBackends(ctx context.Context, cluster *Cluster) ([]Backend, error)
+	ListProbes(ctx context.Context) ([]Probe, error)
+	GetProbe(ctx context.Context, name string) (*Probe, error)
+	CreateProbe(ctx context.Context, probe *Probe) error
+	ListRoutes(ctx context.Context, director *Director) ([]Route, error)
+	CreateRoute(ctx context.Context, route *Route) error
+	DeleteRoute(ctx context.Context, id int) error
+	ListRedirects(ctx context.Context) ([]Redirect, error)
+	CreateRedirect(ctx context.Context, redirect *Redirect) error
+	DeleteRedirect(ctx context.Context, id int) error
+	ValidateCredentials(ctx context.Context) error
+	WaitIdle(ctx context.Context) error
 }
 
 // DefaultClient is a REST client for VaaS API.
+// It is safe for concurrent use and meant to be shared: options are applied only by NewClient, and state kept
+// between requests (tokens, rate limiter, circuit breaker, cache, connection pool) is synchronized.
 type defaultClient struct {
 	httpClient *http.Client
+	transport  *http.Transport
+	dialer     *net.Dialer
+	proxyURL   *url.URL
+	proxyAuth  *url.Userinfo
 	username   string
 	apiKey     string
+	auth       Authenticator
 	host       string
+	accept     string
+	// userAgent and headers are set on every request, for VaaS audit logs
+	userAgent  string
+	headers    http.Header
+	maxPages   int
+	pageLimit  int
+	pageOffset int
+	retry      RetryPolicy
+	lockRetry  RetryPolicy
+	limiter    *RateLimiter
+	breaker    *CircuitBreaker
+	tasks      *TaskWatcher
+	configErr  error
+	inFlight   *InFlight
+
+	timingReporter  TimingReporter
+	bulkConcurrency int
+	dryRun          bool
+	middlewares     []Middleware
+	// operationTimeout limits a call together with its retries and task polling, 0 for no limit
+	operationTimeout time.Duration
+	cache            *LookupCache
+	executor         *executor
+	metrics          *Metrics
+	// flights share requests of concurrent identical calls, see WithDeduplication
+	flights *flightGroup
+	// fieldCheck reports or rejects response fields unknown to the client
+	fieldCheck fieldCheck
+	// validators revalidate director and DC list pages, see WithConditionalRequests
+	validators *ValidatorCache
+	// dcs caches DCs found by symbol for dcTTL, shared by clients of the process unless WithDCCacheTTL is 0
+	dcs   *dcCache
+	dcTTL time.Duration
+
+	versionMu  sync.Mutex
+	apiVersion string
 }
 
 // FindDirector finds Director by name.
-func (c *defaultClient) FindDirector(name string) (*Director, error) {
-	request, err := c.newRequest("GET", c.host+apiDirectorPath, nil)
-	if err != nil {
-		return nil, err
+func (c *defaultClient) FindDirector(ctx context.Context, name string) (_ *Director, err error) {
+	ctx, span := tracing.Start(ctx, "VaaS FindDirector", tracing.KindInternal)
+	span.SetAttribute(attributeDirector, name)
+	defer func() { span.End(err) }()
+
+	key := directorCacheKey(c.host, name)
+	if entry, ok := c.cache.get(key); ok && entry.Director != nil {
+		span.SetAttribute(attributeCached, true)
+		return entry.Director, nil
 	}
 
-	query := request.URL.Query()
-	query.Add("name", name)
-	request.URL.RawQuery = query.Encode()
+	query := url.Values{}
+	query.Set("name", name)
 
-	var directorList DirectorList
-	if _, err = c.doRequest(request, &directorList); err != nil {
+	directors, err := c.listDirectors(ctx, query)
+	if err != nil {
 		return nil, err
 	}
 
-	for _, director := range directorList.Objects {
+	for _, director := range directors {
 		if director.Name == name {
+			c.cache.putDirector(key, director)
 			return &director, nil
 		}
 	}
 
-	return nil, fmt.Errorf("no Director with name %s found", name)
+	return nil, fmt.Errorf("%w: no Director with name %s", ErrDirectorNotFound, name)
 }
 
 // FindDirectorID finds Director ID by name.
-func (c *defaultClient) FindDirectorID(name string) (int, error) {
-	director, err := c.FindDirector(name)
+func (c *defaultClient) FindDirectorID(ctx context.Context, name string) (int, error) {
+	director, err := c.FindDirector(ctx, name)
 	if err != nil {
-		return 0, fmt.Errorf("cannot determine director ID: %s", err)
+		return 0, fmt.Errorf("cannot determine director ID: %w", err)
 	}
-	return director.ID, nil
+	return int(director.ID), nil
 }
 
-// AddBackend adds backend in VaaS director.
-func (c *defaultClient) AddBackend(backend *Backend, director *Director) (string, error) {
-	request, err := c.newRequest("POST", c.host+apiBackendPath, backend)
-	if err != nil {
-		return "", err
-	}
+// RegistrationResult identifies a backend added to VaaS.
+type RegistrationResult struct {
+	// ID of the backend, 0 when VaaS reported neither the ID nor a resource URI containing it.
+	ID int
+	// ResourceURI of the backend.
+	ResourceURI string
+	// TaskURI is the URI of the VaaS task that added the backend, set by AddBackendAndWait.
+	TaskURI string
+}
 
-	response, err := c.doRequest(request, backend)
-	if err != nil {
-		backend, newErr := c.FindBackend(director, backend.Address, backend.Port)
-		if newErr != nil {
-			log.Errorf("failed finding backend: %s", err)
-			return "", err
+// NewRegistrationResult returns the result of adding backend, filling its ID from its resource URI when VaaS
+// did not report it.
+func NewRegistrationResult(backend *Backend) RegistrationResult {
+	result := RegistrationResult{ResourceURI: backend.ResourceURI, TaskURI: backend.TaskURI}
+	if backend.ID == nil {
+		if id, err := BackendIDFromURI(backend.ResourceURI); err == nil {
+			backend.ID = NewID(id)
 		}
-		return backend.ResourceURI, nil
 	}
-
-	return response.Header.Get("Location"), nil
+	if backend.ID != nil {
+		result.ID = int(*backend.ID)
+	}
+	return result
 }
 
-// DeleteBacked removes backend with given id from VaaS director.
-func (c *defaultClient) DeleteBackend(id int) error {
-	request, err := c.newRequest("DELETE", fmt.Sprintf("%s%s%d/", c.host, apiBackendPath, id), nil)
-	if err != nil {
-		return err
-	}
+// AddBackend adds backend in VaaS director, see EnsureBackend.
+// It returns the ID and resource URI of the backend, also when it already existed.
+func (c *defaultClient) AddBackend(ctx context.Context, backend *Backend, director *Director) (_ RegistrationResult, err error) {
+	ctx, span := startBackendSpan(ctx, "VaaS AddBackend", backend, director)
+	defer func() { span.End(err) }()
 
-	request.Header.Set(preferHeader, "respond-async")
-	response, err := c.do(request)
-	if response != nil && response.StatusCode == http.StatusNotFound {
-		log.WithField(vaasBackendIDKey, id).Warn("Tried to remove a non-existent backend")
-		return nil
+	if _, err := c.EnsureBackend(ctx, backend, director); err != nil {
+		return RegistrationResult{}, err
 	}
+	return NewRegistrationResult(backend), nil
+}
+
+// DeleteBackend removes backend with given id from VaaS director.
+func (c *defaultClient) DeleteBackend(ctx context.Context, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "VaaS DeleteBackend", tracing.KindInternal)
+	span.SetAttribute(attributeBackendID, id)
+	defer func() { span.End(err) }()
 
+	_, err = c.deleteBackend(ctx, id)
 	return err
 }
 
-// GetDC finds DC by name.
-func (c *defaultClient) GetDC(name string) (*DC, error) {
-	request, err := c.newRequest("GET", c.host+apiDcPath, nil)
+// deleteBackend schedules removal of backend and returns URI of the task VaaS created for it, if any.
+func (c *defaultClient) deleteBackend(ctx context.Context, id int) (string, error) {
+	endpoint, err := c.endpoint(ctx, backendPath)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	var dcList DCList
-	if _, err := c.doRequest(request, &dcList); err != nil {
-		return nil, err
+	request, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("%s%d/", endpoint, id), nil)
+	if err != nil {
+		return "", err
 	}
 
-	for _, dc := range dcList.Objects {
-		if dc.Symbol == name {
-			return &dc, nil
-		}
+	request.Header.Set(preferHeader, respondAsync)
+	response, err := c.do(request)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		log.WithField(vaasBackendIDKey, id).Warn("Tried to remove a non-existent backend")
+		return "", nil
 	}
-
-	return nil, fmt.Errorf("no DC with name %s found", name)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusAccepted {
+		return "", nil
+	}
+	return response.Header.Get("Location"), nil
 }
 
-func (c *defaultClient) FindBackendID(director string, address string, port int) (int, error) {
-	directorFound, err := c.FindDirector(director)
+// FindBackendID finds ID of backend registered in director (by name) under given address and port.
+func (c *defaultClient) FindBackendID(ctx context.Context, director string, address string, port int) (int, error) {
+	directorFound, err := c.FindDirector(ctx, director)
 	if err != nil {
-		return 0, fmt.Errorf("cannot determine director ID: %s", err)
+		return 0, fmt.Errorf("cannot determine director ID: %w", err)
 	}
 
-	backend, err := c.FindBackend(directorFound, address, port)
+	backend, err := c.FindBackend(ctx, directorFound, address, port)
 	if err != nil {
-		return 0, errors.New("backend not found")
+		return 0, err
+	}
+	if backend.ID == nil {
+		return 0, fmt.Errorf("%w: backend %s has no ID", ErrBackendNotFound, HostPort(address, port))
 	}
-	return *backend.ID, nil
+	return int(*backend.ID), nil
 }
 
-func (c *defaultClient) FindBackend(director *Director, address string, port int) (*Backend, error) {
-	request, err := c.newRequest("GET", c.host+apiBackendPath, nil)
+// FindBackend finds backend registered in director under given address and port.
+// When VaaS holds duplicates, the one with the lowest ID is returned, so repeated lookups are reproducible.
+func (c *defaultClient) FindBackend(ctx context.Context, director *Director, address string, port int) (*Backend, error) {
+	query := url.Values{}
+	query.Set("address", NormalizeAddress(address))
+	query.Set("director", fmt.Sprintf("%d", director.ID))
+	query.Set("port", fmt.Sprintf("%d", port))
+
+	backends, err := c.listBackends(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("could not create backend list request: %s", err)
+		return nil, fmt.Errorf("backend list fetch failed: %w", err)
 	}
 
-	query := request.URL.Query()
-	query.Add("address", address)
-	query.Add("director", fmt.Sprintf("%d", director.ID))
-	query.Add("port", fmt.Sprintf("%d", port))
-	request.URL.RawQuery = query.Encode()
-
-	var backendList BackendList
-	if _, err := c.doRequest(request, &backendList); err != nil {
-		return nil, fmt.Errorf("backend list fetch failed: %s", err)
+	var matches []Backend
+	for _, backend := range backends {
+		log.WithContext(ctx).Debugf("Backend found: %+v", backend)
+		if SameAddress(backend.Address, address) && backend.Port == port {
+			matches = append(matches, backend)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: no backend %s in director %s", ErrBackendNotFound, HostPort(address, port), director.Name)
 	}
 
-	for _, backend := range backendList.Objects {
-		log.Debugf("Backend found: %+v\n", backend)
-		if backend.Address == address && backend.Port == port {
-			return &backend, nil
-		}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return lessByID(matches[i].ID, matches[j].ID)
+	})
+	return &matches[0], nil
+}
+
+// lessByID orders backends by ascending ID, placing backends without an ID last.
+func lessByID(a, b *ID) bool {
+	if a == nil || b == nil {
+		return a != nil
 	}
-	return nil, errors.New("backend not found")
+	return *a < *b
 }
 
-func (c *defaultClient) newRequest(method, url string, body interface{}) (*http.Request, error) {
+func (c *defaultClient) newRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest(method, url, bytes.NewBuffer(jsonBody))
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 
-	request.Header.Set(acceptHeader, applicationJSON)
+	request.Header.Set(acceptHeader, c.accept)
 	request.Header.Set(contentTypeHeader, applicationJSON)
 
-	query := request.URL.Query()
-	query.Add("username", c.username)
-	query.Add("api_key", c.apiKey)
-	request.URL.RawQuery = query.Encode()
-
 	return request, nil
 }
 
@@ -266,50 +447,185 @@ func (c *defaultClient) doRequest(request *http.Request, v interface{}) (*http.R
 	if err != nil {
 		return response, err
 	}
+	return response, c.decodeResponse(response, v)
+}
 
+// decodeResponse reads the body of response into v, or discards it when v is nil
+func (c *defaultClient) decodeResponse(response *http.Response, v interface{}) error {
 	rawResponse, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return response, err
+		return err
 	}
 
 	if v == nil {
-		return response, nil
+		return nil
 	}
-	if err := json.Unmarshal(rawResponse, v); err != nil {
-		return response, err
+	if err := c.checkContentType(response); err != nil {
+		return err
 	}
+	return c.unmarshal(response, rawResponse, v)
+}
 
-	return response, nil
+// ValidateCredentials makes a harmless authenticated request to check that VaaS accepts client credentials.
+// It returns ErrUnauthorized when the credentials are rejected.
+func (c *defaultClient) ValidateCredentials(ctx context.Context) error {
+	endpoint, err := c.endpoint(ctx, dcPath)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	query := request.URL.Query()
+	query.Set("limit", "1")
+	request.URL.RawQuery = query.Encode()
+
+	_, err = c.do(request)
+	return err
 }
 
-func (c *defaultClient) do(request *http.Request) (*http.Response, error) {
-	response, err := c.httpClient.Do(request)
+// checkContentType ensures that a response to a request for a non-default representation can be decoded as JSON.
+func (c *defaultClient) checkContentType(response *http.Response) error {
+	if c.accept == applicationJSON {
+		return nil
+	}
 
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get(contentTypeHeader))
 	if err != nil {
+		return fmt.Errorf("unable to parse response content type: %s", err)
+	}
+	if mediaType != applicationJSON && !strings.HasSuffix(mediaType, "+json") {
+		return fmt.Errorf("unsupported response content type %q, requested %q", mediaType, c.accept)
+	}
+	return nil
+}
+
+// WaitIdle blocks until all requests issued by the client have finished or the context expires.
+func (c *defaultClient) WaitIdle(ctx context.Context) error {
+	return c.inFlight.WaitIdle(ctx)
+}
+
+func (c *defaultClient) do(request *http.Request) (*http.Response, error) {
+	c.inFlight.add()
+	defer c.inFlight.done()
+
+	if c.dryRun && request.Method != http.MethodGet {
+		return skipRequest(request)
+	}
+	if c.operationTimeout <= 0 {
+		return c.doWithRetries(request)
+	}
+
+	ctx, cancel := c.withOperationTimeout(request.Context())
+	response, err := c.doWithRetries(request.WithContext(ctx))
+	if response == nil {
+		cancel()
 		return response, err
 	}
+	response.Body = cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, err
+}
+
+func (c *defaultClient) send(request *http.Request) (response *http.Response, err error) {
+	if err := c.limiter.Wait(request.Context()); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	c.setStaticHeaders(request)
+	if err := c.auth.Authenticate(request); err != nil {
+		return nil, err
+	}
+	if id := logging.CorrelationID(request.Context()); id != "" && request.Header.Get(logging.HeaderRequestID) == "" {
+		request.Header.Set(logging.HeaderRequestID, id)
+	}
+
+	ctx, span := tracing.Start(request.Context(), "VaaS "+request.Method, tracing.KindClient)
+	span.SetAttribute("http.method", request.Method)
+	span.SetAttribute("http.url", redactURL(request.URL))
+	defer func() {
+		if response != nil {
+			span.SetAttribute("http.status_code", response.StatusCode)
+		}
+		span.End(err)
+	}()
+	request = request.WithContext(ctx)
+	tracing.Inject(ctx, request.Header)
+
+	request, tracer := c.traced(request)
+	if queueErr := c.executor.run(ctx, func() {
+		response, err = c.httpClient.Do(request)
+	}); queueErr != nil {
+		c.breaker.record(request, nil, queueErr)
+		return nil, queueErr
+	}
+	c.breaker.record(request, response, err)
+	c.reportTiming(request, tracer)
 
+	if err != nil {
+		return response, redactError(err)
+	}
+
+	if isStreamed(request.Context()) && response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return response, nil
+	}
+
+	// Read the whole body up front so that the connection goes back to the pool whatever callers do with it.
+	rawResponse, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(rawResponse))
+
+	if response.StatusCode == http.StatusUnauthorized {
+		if auth, ok := c.auth.(invalidator); ok {
+			auth.Invalidate()
+		}
+	}
+	if response.StatusCode == http.StatusNotModified && isConditional(request) {
+		return response, err
+	}
 	if response.StatusCode < 200 || response.StatusCode > 299 {
-		message := ""
-		rawResponse, err := ioutil.ReadAll(response.Body)
 		if err != nil {
-			message = fmt.Sprintf("Additional error reading raw response: %s", err.Error())
-		} else {
-			message = string(rawResponse)
+			rawResponse = []byte(fmt.Sprintf("Additional error reading raw response: %s", err.Error()))
 		}
-		return response, fmt.Errorf("VaaS API error at %s (HTTP %d): %s",
-			request.URL, response.StatusCode, message)
+		return response, newAPIError(response, redactURL(request.URL), rawResponse)
 	}
 
-	return response, nil
+	return response, err
 }
 
 // NewClient creates new REST client for VaaS API.
-func NewClient(hostname string, username string, apiKey string) Client {
-	return &defaultClient{
-		httpClient: http.DefaultClient,
-		username:   username,
-		apiKey:     apiKey,
-		host:       hostname,
+// The client is safe for concurrent use by multiple goroutines, so a process should create one and reuse it.
+func NewClient(hostname string, username string, apiKey string, options ...Option) Client {
+	hostname, socket := unixSocketURL(hostname)
+	client := &defaultClient{
+		username:  username,
+		apiKey:    apiKey,
+		host:      hostname,
+		accept:    applicationJSON,
+		maxPages:  DefaultMaxPages,
+		retry:     RetryPolicy{MaxAttempts: 1},
+		lockRetry: RetryPolicy{MaxAttempts: 1},
+
+		apiVersion: DefaultAPIVersion,
+
+		bulkConcurrency: DefaultBulkConcurrency,
+		inFlight:        NewInFlight(),
+
+		dcs:   processDCs,
+		dcTTL: DefaultCacheTTL,
+	}
+	client.auth = APIKeyHeader(username, apiKey)
+	client.ownTransport()
+	if socket != "" {
+		WithUnixSocket(socket)(client)
+	}
+	client.tasks = NewTaskWatcher(client, DefaultTaskPollInterval, DefaultTaskTimeout)
+	for _, option := range options {
+		option(client)
 	}
+	client.applyMiddlewares()
+	return client
 }
@@ -1,14 +1,20 @@
 package vaas
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/logging"
+	"github.com/allegro/vaas-registration-hook/tracing"
 )
 
 func TestNoFailureWhenFindingDirectorByName(t *testing.T) {
@@ -16,7 +22,7 @@ func TestNoFailureWhenFindingDirectorByName(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, applicationJSON, r.Header.Get(contentTypeHeader))
 		assert.Equal(t, applicationJSON, r.Header.Get(acceptHeader))
-		if r.URL.Path == "/api/v0.1/director/" && r.URL.RawQuery == "api_key=api-key&name=director&username=username" && r.Method == "GET" {
+		if r.URL.Path == "/api/v0.1/director/" && r.URL.RawQuery == "name=director" && r.Method == "GET" {
 			var dList = DirectorList{
 				Objects: []Director{*createDirector(expectedID)},
 			}
@@ -37,7 +43,7 @@ func TestNoFailureWhenFindingDirectorByName(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	directorID, err := client.FindDirectorID("director")
+	directorID, err := client.FindDirectorID(context.Background(), "director")
 
 	require.NoError(t, err)
 	assert.Equal(t, expectedID, directorID)
@@ -53,7 +59,7 @@ func TestFailureWhenFindingDirectorByName(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	_, err := client.FindDirectorID("director")
+	_, err := client.FindDirectorID(context.Background(), "director")
 
 	require.Error(t, err)
 }
@@ -68,7 +74,7 @@ func TestBackendRegistrationFailureAfterVaasServerError(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	_, err := client.AddBackend(createBackend(), createDirector(123))
+	_, err := client.AddBackend(context.Background(), createBackend(), createDirector(123))
 
 	assert.Error(t, err)
 }
@@ -79,7 +85,8 @@ func TestBackendRegistrationWhenBackendExists(t *testing.T) {
 		assert.Equal(t, applicationJSON, r.Header.Get(contentTypeHeader))
 		assert.Equal(t, applicationJSON, r.Header.Get(acceptHeader))
 		if r.Method == http.MethodPost {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			http.Error(w, "IntegrityError: Duplicate entry '127.0.0.1-8080-123' for key 'address'", http.StatusInternalServerError)
+			return
 		}
 		var bList = BackendList{
 			Objects: []Backend{*createBackendWithUri(backendURI)},
@@ -98,10 +105,115 @@ func TestBackendRegistrationWhenBackendExists(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	backendResp, err := client.AddBackend(createBackend(), createDirector(123))
+	backendResp, err := client.AddBackend(context.Background(), createBackend(), createDirector(123))
 
 	assert.NoError(t, err)
-	assert.Equal(t, backendURI, backendResp)
+	assert.Equal(t, backendURI, backendResp.ResourceURI)
+}
+
+func TestBackendRegistrationDoesNotMaskServerErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		data, _ := json.Marshal(BackendList{Objects: []Backend{*createBackendWithUri("backendURI")}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	_, err := client.AddBackend(context.Background(), createBackend(), createDirector(123))
+
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrConflict))
+}
+
+func TestEnsureBackendReportsExistingBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		existing := createBackendWithUri("/api/v0.1/backend/5/")
+		existing.ID = NewID(5)
+		data, _ := json.Marshal(BackendList{Objects: []Backend{*existing}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+
+	created, err := NewClient(ts.URL, "username", "api-key").EnsureBackend(context.Background(), backend, createDirector(123))
+
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, ID(5), *backend.ID)
+}
+
+func TestEnsureBackendReportsCreatedBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write(mockAddBackendResponse)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	backend := createBackend()
+
+	created, err := NewClient(ts.URL, "username", "api-key").EnsureBackend(context.Background(), backend, createDirector(1))
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "/api/v0.1/backend/1/", backend.ResourceURI)
+}
+
+func TestFindBackendReturnsLowestIDAmongDuplicates(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		other := createBackend()
+		other.Port = 9090
+		duplicates := []Backend{*createBackend(), *createBackend(), *createBackend(), *other}
+		duplicates[0].ID = NewID(7)
+		duplicates[2].ID = NewID(3)
+		duplicates[3].ID = NewID(1)
+		data, _ := json.Marshal(BackendList{Objects: duplicates})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	backend, err := client.FindBackend(context.Background(), createDirector(123), "127.0.0.1", 8080)
+
+	require.NoError(t, err)
+	require.NotNil(t, backend.ID)
+	assert.Equal(t, ID(3), *backend.ID)
+}
+
+func TestFindBackendMatchesIPv6Literals(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2001:db8::1", r.URL.Query().Get("address"))
+		backend := createBackend()
+		backend.Address = "2001:db8::1"
+		data, _ := json.Marshal(BackendList{Objects: []Backend{*backend}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	backend, err := client.FindBackend(context.Background(), createDirector(123), "[2001:DB8::1]", 8080)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", backend.Address)
+
+	_, err = client.FindBackend(context.Background(), createDirector(123), "2001:db8::1", 9090)
+	assert.EqualError(t, err, "backend not found: no backend [2001:db8::1]:9090 in director director")
 }
 
 func TestBackendRemovalFailureAfterVaasServerError(t *testing.T) {
@@ -114,7 +226,7 @@ func TestBackendRemovalFailureAfterVaasServerError(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	err := client.DeleteBackend(123)
+	err := client.DeleteBackend(context.Background(), 123)
 
 	assert.Error(t, err)
 }
@@ -129,7 +241,7 @@ func TestFindingDCWithNameFailureAfterVaasServerError(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	_, err := client.GetDC("dc6")
+	_, err := client.GetDC(context.Background(), "dc6")
 
 	assert.Error(t, err)
 }
@@ -164,10 +276,10 @@ func TestIfBackendLocationIsSetFromVaasResponseHeader(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	location, err := client.AddBackend(createBackend(), createDirector(123))
+	result, err := client.AddBackend(context.Background(), createBackend(), createDirector(123))
 
 	require.NoError(t, err)
-	assert.Equal(t, "location", location)
+	assert.Equal(t, "location", result.ResourceURI)
 }
 
 func TestNoFailureWhenRemovingExistingBackendInVaas(t *testing.T) {
@@ -184,7 +296,7 @@ func TestNoFailureWhenRemovingExistingBackendInVaas(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	err := client.DeleteBackend(123)
+	err := client.DeleteBackend(context.Background(), 123)
 
 	assert.NoError(t, err)
 }
@@ -203,11 +315,149 @@ func TestNoFailureWhenRemovingNonExistingBackendInVaas(t *testing.T) {
 
 	client := NewClient(ts.URL, "username", "api-key")
 
-	err := client.DeleteBackend(123)
+	err := client.DeleteBackend(context.Background(), 123)
 
 	assert.NoError(t, err)
 }
 
+func TestWaitIdleBlocksUntilInFlightRequestsFinish(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	deleted := make(chan error)
+	go func() {
+		deleted <- client.DeleteBackend(context.Background(), 123)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, client.WaitIdle(ctx))
+
+	close(release)
+	require.NoError(t, <-deleted)
+	assert.NoError(t, client.WaitIdle(context.Background()))
+}
+
+func TestWaitIdleOfSharedInFlightWaitsForEveryClient(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	inFlight := NewInFlight()
+	busy := NewClient(ts.URL, "username", "api-key", WithInFlight(inFlight))
+	idle := NewClient(ts.URL, "username", "api-key", WithInFlight(inFlight))
+
+	deleted := make(chan error)
+	go func() {
+		deleted <- busy.DeleteBackend(context.Background(), 123)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(idle.WaitIdle(ctx), context.DeadlineExceeded))
+
+	close(release)
+	require.NoError(t, <-deleted)
+	assert.NoError(t, inFlight.WaitIdle(context.Background()))
+}
+
+func TestCustomAcceptHeaderIsNegotiated(t *testing.T) {
+	vendorJSON := "application/vnd.vaas+json"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, vendorJSON, r.Header.Get(acceptHeader))
+		w.Header().Set(contentTypeHeader, vendorJSON+"; charset=utf-8")
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithAccept(vendorJSON))
+
+	dc, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(1), dc.ID)
+}
+
+func TestFailureWhenNegotiatedContentTypeIsNotJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeader, "text/html")
+		_, err := w.Write([]byte("{}"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithAccept("application/vnd.vaas+json"))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	assert.Error(t, err)
+}
+
+func TestValidateCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		switch r.Header.Get(authorizationHeader) {
+		case "ApiKey username:api-key":
+			_, err := w.Write([]byte(`{"objects": []}`))
+			assert.NoError(t, err)
+		case "ApiKey username:forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "ApiKey username:broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	assert.NoError(t, NewClient(ts.URL, "username", "api-key").ValidateCredentials(context.Background()))
+
+	err := NewClient(ts.URL, "username", "wrong").ValidateCredentials(context.Background())
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+
+	err = NewClient(ts.URL, "username", "forbidden").ValidateCredentials(context.Background())
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+
+	err = NewClient(ts.URL, "username", "broken").ValidateCredentials(context.Background())
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUnauthorized))
+}
+
+func TestRequestIsCancelledWithContext(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewClient(ts.URL, "username", "api-key")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.FindDirector(ctx, "director")
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
 func createBackend() *Backend {
 	return createBackendWithUri("uri")
 }
@@ -222,9 +472,9 @@ func createBackendWithUri(backendURI string) *Backend {
 	}
 }
 
-func createDirector(ID int) *Director {
+func createDirector(id int) *Director {
 	return &Director{
-		ID: ID,
+		ID:   ID(id),
 		Name: "director",
 	}
 }
@@ -257,3 +507,41 @@ var mockAddBackendResponse = []byte(`{
    },
    "weight":1
 }`)
+
+func TestClientSendsCorrelationIDAsRequestID(t *testing.T) {
+	var requestIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get(logging.HeaderRequestID))
+		_, err := w.Write([]byte(`{"objects": []}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	require.NoError(t, client.ValidateCredentials(logging.WithCorrelationID(context.Background(), "operation-1")))
+	require.NoError(t, client.ValidateCredentials(context.Background()))
+
+	assert.Equal(t, []string{"operation-1", ""}, requestIDs)
+}
+
+func TestClientPropagatesTraceContext(t *testing.T) {
+	var traceParents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get(tracing.HeaderTraceParent))
+		_, err := w.Write([]byte(`{"objects": [{"id": 1, "name": "d1"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+	ctx, span := tracing.Start(context.Background(), "register", tracing.KindInternal)
+	defer span.End(nil)
+
+	_, err := client.FindDirector(ctx, "d1")
+
+	require.NoError(t, err)
+	require.Len(t, traceParents, 1)
+	parent, err := tracing.ParseTraceParent(traceParents[0])
+	require.NoError(t, err)
+	assert.Equal(t, tracing.SpanContextFromContext(ctx).TraceID, parent.TraceID)
+	assert.NotEqual(t, tracing.SpanContextFromContext(ctx).SpanID, parent.SpanID)
+}
@@ -0,0 +1,113 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const clusterPath = "/cluster/"
+
+// Cluster represents JSON structure of a logical cluster of Varnish servers in VaaS API.
+// Directors are served by the clusters listed in their Clusters.
+type Cluster struct {
+	ID          ID     `json:"id"`
+	Name        string `json:"name,omitempty"`
+	ResourceURI string `json:"resource_uri,omitempty"`
+}
+
+// ClusterList represents JSON structure of Cluster list used in responses in VaaS API.
+type ClusterList struct {
+	Meta    Meta      `json:"meta,omitempty"`
+	Objects []Cluster `json:"objects,omitempty"`
+}
+
+func (l *ClusterList) nextPage() *string { return l.Meta.Next }
+
+// InCluster tells whether director is served by cluster.
+func (d *Director) InCluster(cluster *Cluster) bool {
+	for _, uri := range d.Clusters {
+		if sameResource(uri, cluster.ResourceURI) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameResource compares resource URIs by path, as VaaS returns them either absolute or relative to its host
+func sameResource(a, b string) bool {
+	return strings.TrimSuffix(resourcePath(a), "/") == strings.TrimSuffix(resourcePath(b), "/")
+}
+
+func resourcePath(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil {
+		return parsed.Path
+	}
+	return uri
+}
+
+// ListClusters returns every logical cluster defined in VaaS.
+func (c *defaultClient) ListClusters(ctx context.Context) ([]Cluster, error) {
+	clusters, err := c.listClusters(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cluster list fetch failed: %w", err)
+	}
+	return clusters, nil
+}
+
+// GetCluster finds logical cluster by name.
+func (c *defaultClient) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	query := url.Values{}
+	query.Set("name", name)
+
+	clusters, err := c.listClusters(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("cluster list fetch failed: %w", err)
+	}
+	for _, cluster := range clusters {
+		if cluster.Name == name {
+			return &cluster, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no cluster with name %s", ErrClusterNotFound, name)
+}
+
+// ListClusterDirectors returns every director served by cluster.
+func (c *defaultClient) ListClusterDirectors(ctx context.Context, cluster *Cluster) ([]Director, error) {
+	query := url.Values{}
+	query.Set("cluster", fmt.Sprintf("%d", cluster.ID))
+
+	directors, err := c.listDirectors(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("director list fetch failed: %w", err)
+	}
+	return directors, nil
+}
+
+// ListClusterBackends returns every backend of directors served by cluster, director by director.
+func (c *defaultClient) ListClusterBackends(ctx context.Context, cluster *Cluster) ([]Backend, error) {
+	directors, err := c.ListClusterDirectors(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var backends []Backend
+	for i := range directors {
+		directorBackends, err := c.ListBackends(ctx, &directors[i])
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, directorBackends...)
+	}
+	return backends, nil
+}
+
+func (c *defaultClient) listClusters(ctx context.Context, query url.Values) ([]Cluster, error) {
+	var clusters []Cluster
+	err := c.listAll(ctx, clusterPath, query, func() (listPage, func()) {
+		page := &ClusterList{}
+		return page, func() { clusters = append(clusters, page.Objects...) }
+	})
+	return clusters, err
+}
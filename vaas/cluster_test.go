@@ -0,0 +1,70 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectorInCluster(t *testing.T) {
+	director := &Director{Clusters: []string{"http://vaas.example.com/api/v0.1/cluster/1/"}}
+
+	assert.True(t, director.InCluster(&Cluster{ResourceURI: "/api/v0.1/cluster/1/"}))
+	assert.True(t, director.InCluster(&Cluster{ResourceURI: "/api/v0.1/cluster/1"}))
+	assert.False(t, director.InCluster(&Cluster{ResourceURI: "/api/v0.1/cluster/11/"}))
+}
+
+func clusterServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body string
+		switch r.URL.Path {
+		case apiPrefixPath + clusterPath:
+			body = `{"objects": [{"id": 1, "name": "prod", "resource_uri": "/api/v0.1/cluster/1/"}]}`
+		case apiDirectorPath:
+			assert.Equal(t, "1", r.URL.Query().Get("cluster"))
+			body = `{"objects": [{"id": 2, "name": "first"}, {"id": 3, "name": "second"}]}`
+		case apiBackendPath:
+			body = `{"objects": [{"id": ` + r.URL.Query().Get("director") + `, "address": "127.0.0.1"}]}`
+		default:
+			t.Errorf("unexpected %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+}
+
+func TestGetCluster(t *testing.T) {
+	ts := clusterServer(t)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	cluster, err := client.GetCluster(context.Background(), "prod")
+	require.NoError(t, err)
+	_, err = client.GetCluster(context.Background(), "test")
+
+	assert.Equal(t, &Cluster{ID: 1, Name: "prod", ResourceURI: "/api/v0.1/cluster/1/"}, cluster)
+	assert.True(t, errors.Is(err, ErrClusterNotFound), err)
+}
+
+func TestListClusterDirectorsAndBackends(t *testing.T) {
+	ts := clusterServer(t)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+	cluster := &Cluster{ID: 1, Name: "prod"}
+
+	directors, err := client.ListClusterDirectors(context.Background(), cluster)
+	require.NoError(t, err)
+	backends, err := client.ListClusterBackends(context.Background(), cluster)
+	require.NoError(t, err)
+
+	assert.Len(t, directors, 2)
+	require.Len(t, backends, 2)
+	assert.Equal(t, NewID(2), backends[0].ID)
+	assert.Equal(t, NewID(3), backends[1].ID)
+}
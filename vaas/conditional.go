@@ -0,0 +1,143 @@
+package vaas
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultValidatorCacheSize is how many pages a ValidatorCache keeps by default.
+const DefaultValidatorCacheSize = 1000
+
+const (
+	etagHeader            = "ETag"
+	lastModifiedHeader    = "Last-Modified"
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+)
+
+// ValidatorCache keeps pages of director and DC lists along with the ETag and Last-Modified validators VaaS
+// sent with them, so that the client asks VaaS to send a page only when it changed since. Unlike LookupCache it
+// never serves a page VaaS did not confirm, it only saves transferring unchanged ones, e.g. during waves of
+// registrations. Pages without validators are not kept. It is safe for concurrent use by many clients.
+type ValidatorCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]validatedPage
+}
+
+type validatedPage struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// NewValidatorCache creates a cache keeping up to size pages, DefaultValidatorCacheSize if size is not positive.
+func NewValidatorCache(size int) *ValidatorCache {
+	if size <= 0 {
+		size = DefaultValidatorCacheSize
+	}
+	return &ValidatorCache{size: size, entries: map[string]validatedPage{}}
+}
+
+// WithConditionalRequests makes the client list directors and DCs with conditional requests, revalidating
+// pages kept in cache instead of fetching them again.
+func WithConditionalRequests(cache *ValidatorCache) Option {
+	return func(c *defaultClient) {
+		c.validators = cache
+	}
+}
+
+func (c *ValidatorCache) get(key string) (validatedPage, bool) {
+	if c == nil {
+		return validatedPage{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, ok := c.entries[key]
+	return page, ok
+}
+
+// put keeps body of response under key, if VaaS sent any validators of it
+func (c *ValidatorCache) put(key string, response *http.Response, body []byte) {
+	page := validatedPage{
+		etag:         response.Header.Get(etagHeader),
+		lastModified: response.Header.Get(lastModifiedHeader),
+		header:       response.Header.Clone(),
+		body:         body,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if page.etag == "" && page.lastModified == "" {
+		delete(c.entries, key)
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		// Evict any page, as they are cheap to fetch again and the cache only needs to stay bounded.
+		for evicted := range c.entries {
+			delete(c.entries, evicted)
+			break
+		}
+	}
+	c.entries[key] = page
+}
+
+// setConditions makes request fetch the page only if it changed since it was cached
+func (p validatedPage) setConditions(header http.Header) {
+	if p.etag != "" {
+		header.Set(ifNoneMatchHeader, p.etag)
+	}
+	if p.lastModified != "" {
+		header.Set(ifModifiedSinceHeader, p.lastModified)
+	}
+}
+
+// isConditional tells whether request asks VaaS to respond 304 Not Modified when the page did not change
+func isConditional(request *http.Request) bool {
+	return request.Header.Get(ifNoneMatchHeader) != "" || request.Header.Get(ifModifiedSinceHeader) != ""
+}
+
+// doConditional sends GET request for a list page and decodes it into v, revalidating the page kept in
+// validators instead of transferring it again.
+func (c *defaultClient) doConditional(request *http.Request, v interface{}) error {
+	key := request.Header.Get(acceptHeader) + " " + request.URL.String()
+	cached, ok := c.validators.get(key)
+	if ok {
+		cached.setConditions(request.Header)
+	}
+
+	response, err := c.do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		if !ok {
+			return fmt.Errorf("VaaS responded %d to unconditional request %s", response.StatusCode, redactURL(request.URL))
+		}
+		log.WithContext(request.Context()).Debugf("%s not modified, using cached page", redactURL(request.URL))
+		page := *response
+		page.StatusCode = http.StatusOK
+		page.Header = cached.header
+		page.Body = ioutil.NopCloser(bytes.NewReader(cached.body))
+		return c.decodeResponse(&page, v)
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := c.decodeResponse(response, v); err != nil {
+		return err
+	}
+	c.validators.put(key, response, body)
+	return nil
+}
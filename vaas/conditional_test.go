@@ -0,0 +1,117 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lastModified = "Wed, 14 Oct 2026 10:00:00 GMT"
+
+// validatingServer serves directors with ETag of version and DCs last modified at lastModified, responding
+// 304 to requests whose validators match. It records statuses it responded with.
+func validatingServer(t *testing.T, version *string, statuses *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var body string
+		switch r.URL.Path {
+		case apiDirectorPath:
+			etag := `"` + *version + `"`
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				*statuses = append(*statuses, http.StatusNotModified)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			body = `{"objects": [{"id": 1, "name": "director", "service": "` + *version + `"}]}`
+		case apiDcPath:
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				*statuses = append(*statuses, http.StatusNotModified)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			body = `{"objects": [{"id": 1, "name": "First", "symbol": "dc1"}]}`
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		*statuses = append(*statuses, http.StatusOK)
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+}
+
+func TestConditionalRequestsRevalidateDirectorsAndDCs(t *testing.T) {
+	version := "v1"
+	var statuses []int
+	ts := validatingServer(t, &version, &statuses)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithConditionalRequests(NewValidatorCache(0)), WithDCCacheTTL(0))
+
+	for i := 0; i < 2; i++ {
+		director, err := client.FindDirector(context.Background(), "director")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", director.Service)
+		dc, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+		assert.Equal(t, ID(1), dc.ID)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusNotModified, http.StatusNotModified}, statuses)
+}
+
+func TestConditionalRequestsFetchChangedPages(t *testing.T) {
+	version := "v1"
+	var statuses []int
+	ts := validatingServer(t, &version, &statuses)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithConditionalRequests(NewValidatorCache(0)))
+
+	_, err := client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	version = "v2"
+	director, err := client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", director.Service)
+	director, err = client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", director.Service)
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusNotModified}, statuses)
+}
+
+func TestRequestsAreUnconditionalWithoutValidatorCache(t *testing.T) {
+	version := "v1"
+	var statuses []int
+	ts := validatingServer(t, &version, &statuses)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	for i := 0; i < 2; i++ {
+		_, err := client.FindDirector(context.Background(), "director")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, statuses)
+}
+
+func TestValidatorCacheKeepsOnlyValidatedPagesUpToSize(t *testing.T) {
+	cache := NewValidatorCache(1)
+	validated := &http.Response{Header: http.Header{"Etag": []string{`"v1"`}}}
+
+	cache.put("unvalidated", &http.Response{Header: http.Header{}}, []byte("{}"))
+	_, ok := cache.get("unvalidated")
+	assert.False(t, ok)
+
+	cache.put("first", validated, []byte("{}"))
+	cache.put("second", validated, []byte("{}"))
+	_, ok = cache.get("first")
+	assert.False(t, ok)
+	page, ok := cache.get("second")
+	require.True(t, ok)
+	assert.Equal(t, `"v1"`, page.etag)
+}
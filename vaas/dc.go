@@ -0,0 +1,149 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// processDCs caches DCs for every client of the process, as they practically never change but every
+// registration looks its DC up.
+var processDCs = newDCCache()
+
+// WithDCCacheTTL sets how long DCs found by GetDC and ListDCs are cached by symbol in the process,
+// DefaultCacheTTL by default. With 0 every GetDC asks VaaS.
+func WithDCCacheTTL(ttl time.Duration) Option {
+	return func(c *defaultClient) {
+		c.dcTTL = ttl
+	}
+}
+
+// GetDC finds DC by its symbol, asking VaaS to filter DCs by it. Found DCs are cached in the process, and
+// concurrent lookups of the same DC share a single request.
+func (c *defaultClient) GetDC(ctx context.Context, name string) (*DC, error) {
+	key := dcCacheKey(c.host, name)
+	if entry, ok := c.cache.get(key); ok && entry.DC != nil {
+		return entry.DC, nil
+	}
+
+	dc, err := c.dcs.lookup(ctx, key, c.dcTTL, func() (*DC, error) {
+		return c.findDC(ctx, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.cache.putDC(key, *dc)
+	return dc, nil
+}
+
+// findDC lists DCs with given symbol, scanning them as VaaS versions without the filter return every DC
+func (c *defaultClient) findDC(ctx context.Context, name string) (*DC, error) {
+	query := url.Values{}
+	query.Set("symbol", name)
+	dcs, err := c.listDCs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range dcs {
+		if dc.Symbol == name {
+			return &dc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no DC with name %s", ErrDCNotFound, name)
+}
+
+// ListDCs returns every DC defined in VaaS, following pagination, and caches them for GetDC.
+func (c *defaultClient) ListDCs(ctx context.Context) ([]DC, error) {
+	dcs, err := c.listDCs(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range dcs {
+		c.dcs.put(dcCacheKey(c.host, dc.Symbol), dc, c.dcTTL)
+	}
+	return dcs, nil
+}
+
+// dcCache keeps found DCs until they expire, and the lookups in progress so that concurrent ones are shared.
+// It is safe for concurrent use.
+type dcCache struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]dcCacheEntry
+	inFlight map[string]*dcLookup
+}
+
+type dcCacheEntry struct {
+	dc      DC
+	expires time.Time
+}
+
+// dcLookup is a lookup in progress, whose result is set before done is closed
+type dcLookup struct {
+	done chan struct{}
+	dc   *DC
+	err  error
+}
+
+func newDCCache() *dcCache {
+	return &dcCache{now: time.Now, entries: map[string]dcCacheEntry{}, inFlight: map[string]*dcLookup{}}
+}
+
+// lookup returns the DC cached under key, or the one find returns, caching it for ttl. Callers asking while
+// another one looks the same key up wait for its result.
+func (c *dcCache) lookup(ctx context.Context, key string, ttl time.Duration, find func() (*DC, error)) (*DC, error) {
+	if ttl <= 0 {
+		return find()
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expires) {
+		c.mu.Unlock()
+		dc := entry.dc
+		return &dc, nil
+	}
+	if pending, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if pending.err != nil {
+			return nil, pending.err
+		}
+		dc := *pending.dc
+		return &dc, nil
+	}
+	pending := &dcLookup{done: make(chan struct{})}
+	c.inFlight[key] = pending
+	c.mu.Unlock()
+
+	pending.dc, pending.err = find()
+
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	if pending.err == nil {
+		c.entries[key] = dcCacheEntry{dc: *pending.dc, expires: c.now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(pending.done)
+
+	if pending.err != nil {
+		return nil, pending.err
+	}
+	dc := *pending.dc
+	return &dc, nil
+}
+
+func (c *dcCache) put(key string, dc DC, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = dcCacheEntry{dc: dc, expires: c.now().Add(ttl)}
+}
@@ -0,0 +1,121 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// symbolServer serves DCs filtered by the symbol query parameter, counting requests
+func symbolServer(t *testing.T, requests *int32, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		assert.Equal(t, apiDcPath, r.URL.Path)
+		time.Sleep(delay)
+		var err error
+		switch r.URL.Query().Get("symbol") {
+		case "dc1":
+			_, err = w.Write([]byte(`{"objects": [{"id": 1, "symbol": "dc1"}]}`))
+		case "":
+			_, err = w.Write([]byte(`{"objects": [{"id": 1, "symbol": "dc1"}, {"id": 2, "symbol": "dc2"}]}`))
+		default:
+			_, err = w.Write([]byte(`{"objects": []}`))
+		}
+		assert.NoError(t, err)
+	}))
+}
+
+func TestGetDCFiltersBySymbolAndCachesInProcess(t *testing.T) {
+	var requests int32
+	ts := symbolServer(t, &requests, 0)
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		dc, err := NewClient(ts.URL, "username", "api-key").GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+		assert.Equal(t, ID(1), dc.ID)
+	}
+	_, err := NewClient(ts.URL, "username", "api-key").GetDC(context.Background(), "dc3")
+
+	require.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestGetDCSharesConcurrentLookups(t *testing.T) {
+	var requests int32
+	ts := symbolServer(t, &requests, 20*time.Millisecond)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dc, err := client.GetDC(context.Background(), "dc1")
+			assert.NoError(t, err)
+			assert.Equal(t, "dc1", dc.Symbol)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestListDCsFillsDCCache(t *testing.T) {
+	var requests int32
+	ts := symbolServer(t, &requests, 0)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	dcs, err := client.ListDCs(context.Background())
+	require.NoError(t, err)
+	dc, err := client.GetDC(context.Background(), "dc2")
+
+	require.NoError(t, err)
+	assert.Len(t, dcs, 2)
+	assert.Equal(t, ID(2), dc.ID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDCCacheTTLZeroLooksEveryDCUp(t *testing.T) {
+	var requests int32
+	ts := symbolServer(t, &requests, 0)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestDCCacheExpires(t *testing.T) {
+	cache := newDCCache()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	finds := 0
+	find := func() (*DC, error) {
+		finds++
+		return &DC{ID: 1, Symbol: "dc1"}, nil
+	}
+
+	_, err := cache.lookup(context.Background(), "dc1", time.Minute, find)
+	require.NoError(t, err)
+	_, err = cache.lookup(context.Background(), "dc1", time.Minute, find)
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = cache.lookup(context.Background(), "dc1", time.Minute, find)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, finds)
+}
@@ -0,0 +1,148 @@
+package vaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithStrictDecoding makes the client reject VaaS responses with fields it does not model with ErrUnknownFields,
+// as json.Decoder.DisallowUnknownFields would, also within backends and directors, which otherwise keep such
+// fields in Extra. By default unknown fields are accepted.
+func WithStrictDecoding() Option {
+	return func(c *defaultClient) {
+		c.fieldCheck.strict = true
+	}
+}
+
+// WithSchemaCheck makes the client warn about fields of VaaS responses it does not model, once per field, so
+// that changes of the VaaS API are noticed before the client depends on them. Responses are still decoded.
+func WithSchemaCheck() Option {
+	return func(c *defaultClient) {
+		c.fieldCheck.report = true
+	}
+}
+
+// fieldCheck looks for fields of VaaS responses unknown to the client
+type fieldCheck struct {
+	strict bool
+	report bool
+
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+// unmarshal decodes raw body of response into v, adapted to types of the client by the schema of its API version
+func (c *defaultClient) unmarshal(response *http.Response, raw []byte, v interface{}) error {
+	raw, err := c.schema().normalize(raw, v)
+	if err != nil {
+		return err
+	}
+	if err := c.fieldCheck.check(response, raw, v); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// check reports fields of raw body of response which v does not model, failing if decoding strictly
+func (f *fieldCheck) check(response *http.Response, raw []byte, v interface{}) error {
+	if !f.strict && !f.report {
+		return nil
+	}
+	unknown := map[string]bool{}
+	unknownFieldPaths(raw, reflect.TypeOf(v), "", unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(unknown))
+	for path := range unknown {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	if f.strict {
+		return fmt.Errorf("%w: %s", ErrUnknownFields, strings.Join(paths, ", "))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reported == nil {
+		f.reported = map[string]bool{}
+	}
+	for _, path := range paths {
+		key := reflect.TypeOf(v).String() + " " + path
+		if f.reported[key] {
+			continue
+		}
+		f.reported[key] = true
+		logger := log.WithField("field", path)
+		if response != nil && response.Request != nil {
+			logger = logger.WithContext(response.Request.Context()).WithField("path", response.Request.URL.Path)
+		}
+		logger.Warn("VaaS response has a field unknown to the client, the VaaS API may have changed")
+	}
+	return nil
+}
+
+// unknownFieldPaths adds to unknown paths of fields of JSON data, found at path, which type t does not model.
+// Values which are not JSON objects or arrays where t expects them, e.g. of types decoding strings, are skipped.
+func unknownFieldPaths(data []byte, t reflect.Type, path string, unknown map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return
+		}
+		fields := jsonFields(t)
+		for name, value := range object {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			field, ok := fields[name]
+			if !ok {
+				unknown[fieldPath] = true
+				continue
+			}
+			unknownFieldPaths(value, field, fieldPath, unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return
+		}
+		for _, item := range items {
+			unknownFieldPaths(item, t.Elem(), path+"[]", unknown)
+		}
+	}
+}
+
+// jsonFields returns types of exported fields of struct type t by their JSON names
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if name != "-" {
+			fields[name] = field.Type
+		}
+	}
+	return fields
+}
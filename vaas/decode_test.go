@@ -0,0 +1,73 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const driftedDCList = `{"meta": {"total_count": 1, "region": "eu"},
+	"objects": [{"id": 1, "symbol": "dc1", "name": "DC 1", "zone": "a"}, {"id": 2, "symbol": "dc2", "zone": "b"}]}`
+
+func driftedDCServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(driftedDCList))
+	}))
+}
+
+func TestClientDecodesUnknownFieldsLeniently(t *testing.T) {
+	ts := driftedDCServer()
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+	dc, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "dc1", dc.Symbol)
+}
+
+func TestClientWithStrictDecodingRejectsUnknownFields(t *testing.T) {
+	ts := driftedDCServer()
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0), WithStrictDecoding())
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.True(t, errors.Is(err, ErrUnknownFields), err)
+	assert.Contains(t, err.Error(), "meta.region, objects[].zone")
+}
+
+func TestClientWithSchemaCheckWarnsAboutUnknownFieldsOnce(t *testing.T) {
+	ts := driftedDCServer()
+	defer ts.Close()
+	hook := test.NewGlobal()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0), WithSchemaCheck())
+	for i := 0; i < 2; i++ {
+		dc, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+		assert.Equal(t, "dc1", dc.Symbol)
+	}
+
+	var fields []interface{}
+	for _, entry := range hook.AllEntries() {
+		fields = append(fields, entry.Data["field"])
+	}
+	assert.Equal(t, []interface{}{"meta.region", "objects[].zone"}, fields)
+}
+
+func TestUnknownFieldPathsWithinBackends(t *testing.T) {
+	unknown := map[string]bool{}
+	raw := `{"objects": [{"address": "127.0.0.1", "port": 8080, "weight_hint": 3, "dc": {"symbol": "dc1"}}]}`
+
+	unknownFieldPaths([]byte(raw), reflect.TypeOf(&BackendList{}), "", unknown)
+
+	assert.Equal(t, map[string]bool{"objects[].weight_hint": true}, unknown)
+}
@@ -0,0 +1,92 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// WithDeduplication makes concurrent calls of EnsureBackend and AddBackend adding an identical backend to the
+// same director send a single request, whose outcome they all share, so that a storm of reconciliations of the
+// same Pod does not POST it many times. Callers that shared the request of another one report the backend as
+// not created. Deduplicated calls are counted by WithMetrics.
+func WithDeduplication() Option {
+	return func(c *defaultClient) {
+		c.flights = &flightGroup{}
+	}
+}
+
+// flightGroup runs a single call of a function per key at once, sharing its outcome with concurrent callers
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// do runs call unless another call with key is in flight, in which case it waits for its outcome, or for ctx
+// to be done, and reports it shared
+func (g *flightGroup) do(ctx context.Context, key string, call func() (interface{}, error)) (_ interface{}, shared bool, _ error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, true, f.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = call()
+	return f.value, false, f.err
+}
+
+type ensureOutcome struct {
+	backend Backend
+	created bool
+}
+
+// ensureBackendOnce is ensureBackend sharing a request with concurrent calls adding an identical backend
+func (c *defaultClient) ensureBackendOnce(ctx context.Context, backend *Backend, director *Director) (bool, error) {
+	if c.flights == nil {
+		return c.ensureBackend(ctx, backend, director)
+	}
+	body, err := json.Marshal(backend)
+	if err != nil {
+		return false, err
+	}
+
+	key := director.ResourceURI + "\x00" + string(body)
+	value, shared, err := c.flights.do(ctx, key, func() (interface{}, error) {
+		added := *backend
+		created, err := c.ensureBackend(ctx, &added, director)
+		return ensureOutcome{backend: added, created: created}, err
+	})
+	if shared {
+		c.metrics.deduplicate("EnsureBackend")
+	}
+	if err != nil {
+		return false, err
+	}
+	outcome := value.(ensureOutcome)
+	*backend = outcome.backend
+	backend.Tags = append([]string(nil), outcome.backend.Tags...)
+	return outcome.created && !shared, nil
+}
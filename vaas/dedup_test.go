@@ -0,0 +1,82 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicationSharesConcurrentIdenticalEnsureBackend(t *testing.T) {
+	var posts int32
+	arrived, release := make(chan struct{}, 1), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(mockAddBackendResponse)
+	}))
+	defer ts.Close()
+
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	client := NewClient(ts.URL, "username", "api-key", WithDeduplication(), WithMetrics(metrics))
+
+	const callers = 4
+	created := make([]bool, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	ensure := func(i int) {
+		defer wg.Done()
+		created[i], errs[i] = client.EnsureBackend(context.Background(), createBackend(), createDirector(1))
+	}
+	wg.Add(callers)
+	go ensure(0)
+	<-arrived
+	for i := 1; i < callers; i++ {
+		go ensure(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&posts))
+	for i := range errs {
+		require.NoError(t, errs[i])
+	}
+	assert.Equal(t, []bool{true, false, false, false}, created)
+	assert.Equal(t, float64(callers-1), testutil.ToFloat64(metrics.deduplicated.WithLabelValues("EnsureBackend")))
+}
+
+func TestDeduplicationKeepsDifferentBackendsApart(t *testing.T) {
+	var posts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(mockAddBackendResponse)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDeduplication())
+	for _, port := range []int{80, 81, 80} {
+		backend := createBackend()
+		backend.Port = port
+		created, err := client.EnsureBackend(context.Background(), backend, createDirector(1))
+		require.NoError(t, err)
+		assert.True(t, created)
+	}
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&posts))
+}
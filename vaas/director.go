@@ -0,0 +1,106 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Load balancing modes of a director.
+const (
+	ModeRoundRobin = "round-robin"
+	ModeRandom     = "random"
+	ModeHash       = "hash"
+)
+
+// Protocols of a director.
+const (
+	ProtocolHTTP  = "HTTP"
+	ProtocolHTTPS = "HTTPS"
+)
+
+// directorBody is the JSON body of director requests. It omits the ID of directors that do not have one yet.
+type directorBody struct {
+	*Director
+	ID *ID `json:"id,omitempty"`
+}
+
+func newDirectorBody(director *Director) directorBody {
+	body := directorBody{Director: director}
+	if director.ID != 0 {
+		body.ID = &director.ID
+	}
+	return body
+}
+
+// MarshalJSON encodes the director of body with its Extra fields, overriding its ID.
+func (b directorBody) MarshalJSON() ([]byte, error) {
+	type plain Director
+	data, err := json.Marshal(struct {
+		plain
+		ID *ID `json:"id,omitempty"`
+	}{plain(*b.Director), b.ID})
+	if err != nil {
+		return nil, err
+	}
+	return withFields(data, b.Extra)
+}
+
+// CreateDirector creates director in VaaS, filling its ID and resource URI from the response.
+func (c *defaultClient) CreateDirector(ctx context.Context, director *Director) error {
+	defer c.cache.invalidateDirectors()
+	endpoint, err := c.endpoint(ctx, directorPath)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodPost, endpoint, newDirectorBody(director))
+	if err != nil {
+		return err
+	}
+
+	response, err := c.doRequest(request, director)
+	if err != nil {
+		return fmt.Errorf("cannot create director %s: %w", director.Name, err)
+	}
+	if director.ResourceURI == "" {
+		director.ResourceURI = response.Header.Get("Location")
+	}
+	return nil
+}
+
+// UpdateDirector replaces director with given one, matching them by ID.
+func (c *defaultClient) UpdateDirector(ctx context.Context, director *Director) error {
+	defer c.cache.invalidateDirectors()
+	endpoint, err := c.endpoint(ctx, directorPath)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodPut, fmt.Sprintf("%s%d/", endpoint, director.ID), newDirectorBody(director))
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.do(request); err != nil {
+		return fmt.Errorf("cannot update director %s: %w", director.Name, err)
+	}
+	return nil
+}
+
+// DeleteDirector removes director with given id from VaaS.
+func (c *defaultClient) DeleteDirector(ctx context.Context, id int) error {
+	defer c.cache.invalidateDirectors()
+	endpoint, err := c.endpoint(ctx, directorPath)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodDelete, fmt.Sprintf("%s%d/", endpoint, id), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.do(request); err != nil {
+		return fmt.Errorf("cannot delete director %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDirectorOmitsIDAndFillsItFromResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, apiDirectorPath, r.URL.Path)
+
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rawRequest, &body))
+		assert.NotContains(t, body, "id")
+		assert.Equal(t, ModeRoundRobin, body["mode"])
+		assert.Equal(t, "/api/v0.1/probe/1/", body["probe"])
+
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte(`{"id": 5, "name": "director", "resource_uri": "/api/v0.1/director/5/"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	director := &Director{Name: "director", Mode: ModeRoundRobin, Protocol: ProtocolHTTP, Probe: "/api/v0.1/probe/1/"}
+
+	err := NewClient(ts.URL, "username", "api-key").CreateDirector(context.Background(), director)
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(5), director.ID)
+	assert.Equal(t, "/api/v0.1/director/5/", director.ResourceURI)
+}
+
+func TestUpdateAndDeleteDirector(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodPut {
+			rawRequest, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(rawRequest), `"id":5`)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	require.NoError(t, client.UpdateDirector(context.Background(), &Director{ID: 5, Name: "director", Mode: ModeHash}))
+	require.NoError(t, client.DeleteDirector(context.Background(), 5))
+
+	assert.Equal(t, []string{"PUT /api/v0.1/director/5/", "DELETE /api/v0.1/director/5/"}, requests)
+}
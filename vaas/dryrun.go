@@ -0,0 +1,54 @@
+package vaas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithDryRun makes the client log requests that would change VaaS instead of sending them.
+// GET requests are still sent, so lookups of directors, DCs and backends keep working.
+// Skipped requests succeed with the request body echoed back as the response.
+func WithDryRun() Option {
+	return func(c *defaultClient) {
+		c.dryRun = true
+	}
+}
+
+// skipRequest logs request and returns the response a dry run pretends VaaS sent.
+func skipRequest(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.GetBody != nil {
+		reader, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		if body, err = ioutil.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	log.WithField("method", request.Method).
+		WithField("url", redactURL(request.URL)).
+		WithField("body", string(body)).
+		Info("Dry run, request not sent to VaaS")
+
+	response := &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    request,
+	}
+	switch request.Method {
+	case http.MethodPost:
+		response.StatusCode = http.StatusCreated
+	case http.MethodDelete:
+		response.StatusCode = http.StatusNoContent
+		response.Body = ioutil.NopCloser(bytes.NewReader(nil))
+	}
+	response.Status = http.StatusText(response.StatusCode)
+	return response, nil
+}
@@ -0,0 +1,46 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunDoesNotSendChanges(t *testing.T) {
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDryRun())
+	backend := createBackend()
+
+	_, err := client.AddBackend(context.Background(), backend, createDirector(1))
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+
+	assert.Empty(t, methods)
+	assert.Equal(t, "127.0.0.1", backend.Address)
+}
+
+func TestDryRunStillSendsLookups(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		data, err := json.Marshal(DirectorList{Objects: []Director{*createDirector(1)}})
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	_, err := NewClient(ts.URL, "username", "api-key", WithDryRun()).FindDirector(context.Background(), "director")
+
+	require.NoError(t, err)
+}
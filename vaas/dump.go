@@ -0,0 +1,148 @@
+package vaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDumpBodyLimit is the number of bytes of a body DumpMiddleware logs by default
+const DefaultDumpBodyLimit = 4096
+
+// secretFields are parts of names of JSON fields, form values and headers whose values are masked in dumps
+var secretFields = []string{"authorization", "password", "secret", "token", "api_key", "api-key", "apikey", "cookie"}
+
+// DumpMiddleware logs every request sent to VaaS and its response to logger, including headers and bodies, so
+// that rejected requests can be debugged. Credentials in URLs, headers, JSON and form bodies are masked, and a
+// body is logged up to bodyLimit bytes, DefaultDumpBodyLimit when not positive.
+func DumpMiddleware(logger log.FieldLogger, bodyLimit int) Middleware {
+	if bodyLimit <= 0 {
+		bodyLimit = DefaultDumpBodyLimit
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			requestBody, err := peekRequestBody(request)
+			if err != nil {
+				return nil, err
+			}
+			entry := logger.WithField("method", request.Method).WithField("url", redactURL(request.URL))
+			entry.WithField("headers", dumpHeaders(request.Header)).
+				WithField("body", dumpBody(requestBody, request.Header, bodyLimit)).
+				Info("VaaS request")
+
+			start := time.Now()
+			response, err := next.RoundTrip(request)
+			entry = entry.WithField("duration", time.Since(start))
+			if err != nil {
+				entry.WithError(redactError(err)).Info("VaaS request failed")
+				return response, err
+			}
+			responseBody, err := ioutil.ReadAll(response.Body)
+			response.Body.Close()
+			response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+			if err != nil {
+				return response, err
+			}
+			entry.WithField("status", response.StatusCode).
+				WithField("headers", dumpHeaders(response.Header)).
+				WithField("body", dumpBody(responseBody, response.Header, bodyLimit)).
+				Info("VaaS response")
+			return response, nil
+		})
+	}
+}
+
+// peekRequestBody returns the body of request, leaving it to be sent
+func peekRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// dumpHeaders returns headers as name: value lines sorted by name, with secret values masked
+func dumpHeaders(headers http.Header) string {
+	lines := make([]string, 0, len(headers))
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		if isSecretField(name) {
+			value = redacted
+		}
+		lines = append(lines, name+": "+value)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// dumpBody returns body with secret values masked, cut to limit bytes
+func dumpBody(body []byte, headers http.Header, limit int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	dump := string(body)
+	var document interface{}
+	if json.Unmarshal(body, &document) == nil {
+		if document == nil {
+			return ""
+		}
+		if masked, err := json.Marshal(redactJSON(document)); err == nil {
+			dump = string(masked)
+		}
+	} else if strings.HasPrefix(headers.Get(contentTypeHeader), "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(dump); err == nil {
+			for name := range values {
+				if isSecretField(name) {
+					values.Set(name, redacted)
+				}
+			}
+			dump = values.Encode()
+		}
+	}
+	if len(dump) > limit {
+		return fmt.Sprintf("%s... (%d more bytes)", dump[:limit], len(dump)-limit)
+	}
+	return dump
+}
+
+// redactJSON masks values of secret fields in a decoded JSON document
+func redactJSON(document interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for name, field := range value {
+			if isSecretField(name) {
+				value[name] = redacted
+			} else {
+				value[name] = redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJSON(item)
+		}
+	}
+	return document
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretFields {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
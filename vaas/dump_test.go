@@ -0,0 +1,58 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpMiddlewareLogsRedactedRequestAndResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var backend map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&backend))
+		assert.Equal(t, "192.168.0.10", backend["address"])
+		w.Header().Set(contentTypeHeader, applicationJSON)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"backend": {"address": ["Enter a valid IPv4 or IPv6 address."]}, "token": "abc"}`))
+	}))
+	defer ts.Close()
+
+	logger, hook := test.NewNullLogger()
+	client := NewClient(ts.URL, "username", "api-key", WithMiddleware(DumpMiddleware(logger, 0)))
+
+	_, err := client.AddBackend(context.Background(), &Backend{Address: "192.168.0.10", Port: 80}, &Director{})
+
+	require.Error(t, err)
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "VaaS request", entries[0].Message)
+	assert.Equal(t, http.MethodPost, entries[0].Data["method"])
+	assert.Contains(t, entries[0].Data["body"], `"address":"192.168.0.10"`)
+	assert.Contains(t, entries[0].Data["headers"], "Authorization: REDACTED")
+	assert.NotContains(t, entries[0].Data["headers"], "api-key")
+	assert.Equal(t, "VaaS response", entries[1].Message)
+	assert.Equal(t, http.StatusBadRequest, entries[1].Data["status"])
+	assert.Contains(t, entries[1].Data["body"], "Enter a valid IPv4 or IPv6 address.")
+	assert.Contains(t, entries[1].Data["body"], `"token":"REDACTED"`)
+}
+
+func TestDumpBodyCutsLongBodies(t *testing.T) {
+	dump := dumpBody([]byte(strings.Repeat("x", 20)), http.Header{}, 8)
+
+	assert.Equal(t, "xxxxxxxx... (12 more bytes)", dump)
+}
+
+func TestDumpBodyRedactsFormValues(t *testing.T) {
+	headers := http.Header{contentTypeHeader: []string{"application/x-www-form-urlencoded"}}
+
+	dump := dumpBody([]byte("grant_type=client_credentials&client_secret=s3cret"), headers, DefaultDumpBodyLimit)
+
+	assert.Equal(t, "client_secret=REDACTED&grant_type=client_credentials", dump)
+}
@@ -0,0 +1,158 @@
+package vaas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors returned by the client, to be matched with errors.Is.
+var (
+	// ErrUnauthorized is returned when VaaS rejects the client credentials.
+	ErrUnauthorized = errors.New("VaaS credentials rejected")
+	// ErrDirectorNotFound is returned when no director has given name.
+	ErrDirectorNotFound = errors.New("director not found")
+	// ErrBackendNotFound is returned when no backend matches a lookup.
+	ErrBackendNotFound = errors.New("backend not found")
+	// ErrDCNotFound is returned when no DC has given symbol.
+	ErrDCNotFound = errors.New("DC not found")
+	// ErrTimeProfileNotFound is returned when no time profile has given name.
+	ErrTimeProfileNotFound = errors.New("time profile not found")
+	// ErrProbeNotFound is returned when no probe has given name.
+	ErrProbeNotFound = errors.New("probe not found")
+	// ErrClusterNotFound is returned when no logical cluster has given name.
+	ErrClusterNotFound = errors.New("cluster not found")
+	// ErrConflict matches API errors reporting that the object being created already exists.
+	ErrConflict = errors.New("object already exists in VaaS")
+	// ErrCircuitOpen is returned without sending a request while the circuit breaker is open.
+	ErrCircuitOpen = errors.New("VaaS circuit breaker open")
+	// ErrTaskFailed is returned when an asynchronous VaaS task fails.
+	ErrTaskFailed = errors.New("VaaS task failed")
+	// ErrMaintenance matches API errors reporting that VaaS is down for maintenance.
+	ErrMaintenance = errors.New("VaaS in maintenance")
+	// ErrDirectorLocked matches API errors reporting that a concurrent change of the director, such as VCL
+	// generation for another backend added at the same time, made VaaS reject the request.
+	ErrDirectorLocked = errors.New("director changed concurrently in VaaS")
+	// ErrInvalidResourceURI is returned when a resource URI does not end with an ID of the expected resource.
+	ErrInvalidResourceURI = errors.New("invalid VaaS resource URI")
+	// ErrUnknownFields is returned by clients decoding strictly when a VaaS response has fields they do not model.
+	ErrUnknownFields = errors.New("VaaS response has unknown fields")
+)
+
+// MaintenanceHeader is set by VaaS on responses served while it is down for maintenance.
+const MaintenanceHeader = "X-VaaS-Maintenance"
+
+// APIError is returned when VaaS API responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	URL        string
+	// Message is the error reported by VaaS, or the raw response body if it could not be parsed.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+	// Maintenance tells that VaaS responded with its maintenance banner.
+	Maintenance bool
+	// RetryAfter is the delay VaaS asked for in the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("VaaS API error at %s (HTTP %d): %s", e.URL, e.StatusCode, e.Message)
+}
+
+// Is makes authentication failures match ErrUnauthorized, duplicates match ErrConflict, concurrent changes
+// of a director match ErrDirectorLocked and maintenance banners match ErrMaintenance.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		return e.isConflict()
+	case ErrDirectorLocked:
+		return e.isDirectorLocked()
+	case ErrMaintenance:
+		return e.Maintenance
+	}
+	return false
+}
+
+// duplicateMessages are fragments of errors VaaS reports when a unique constraint is violated.
+// Older VaaS versions report them as HTTP 400 or 500 instead of 409.
+var duplicateMessages = []string{"duplicate entry", "already exists", "unique constraint", "integrityerror"}
+
+func (e *APIError) isConflict() bool {
+	if e.isDirectorLocked() {
+		return false
+	}
+	if e.StatusCode == http.StatusConflict {
+		return true
+	}
+	if e.StatusCode != http.StatusBadRequest && e.StatusCode != http.StatusInternalServerError {
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	for _, duplicate := range duplicateMessages {
+		if strings.Contains(message, duplicate) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockMessages are fragments of errors VaaS reports when a concurrent change of a director, usually VCL
+// generation, conflicts with the request. They come with HTTP 409, or 400 or 500 from validation and the database.
+var lockMessages = []string{"deadlock", "lock wait timeout", "could not obtain lock", "optimistic lock",
+	"concurrent", "vcl generation", "vcl is being generated", "was modified"}
+
+func (e *APIError) isDirectorLocked() bool {
+	switch e.StatusCode {
+	case http.StatusLocked:
+		return true
+	case http.StatusConflict, http.StatusPreconditionFailed, http.StatusBadRequest, http.StatusInternalServerError:
+	default:
+		return false
+	}
+	message := strings.ToLower(e.Message)
+	for _, lock := range lockMessages {
+		if strings.Contains(message, lock) {
+			return true
+		}
+	}
+	return false
+}
+
+// tastypieError represents JSON structure of errors reported by VaaS API.
+type tastypieError struct {
+	ErrorMessage string `json:"error_message"`
+	Error        string `json:"error"`
+}
+
+func newAPIError(response *http.Response, url string, body []byte) *APIError {
+	apiError := &APIError{
+		StatusCode: response.StatusCode,
+		URL:        url,
+		Message:    string(body),
+		Body:       body,
+	}
+	if response.StatusCode == http.StatusServiceUnavailable {
+		apiError.Maintenance = response.Header.Get(MaintenanceHeader) != "" ||
+			strings.Contains(strings.ToLower(string(body)), "maintenance")
+	}
+	if delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+		apiError.RetryAfter = delay
+	}
+
+	var parsed tastypieError
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if parsed.ErrorMessage != "" {
+			apiError.Message = parsed.ErrorMessage
+		} else if parsed.Error != "" {
+			apiError.Message = parsed.Error
+		}
+	}
+	return apiError
+}
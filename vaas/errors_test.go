@@ -0,0 +1,113 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIErrorCarriesStatusAndParsedMessage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(`{"error_message": "invalid dc", "traceback": "..."}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	var apiError *APIError
+	require.True(t, errors.As(err, &apiError))
+	assert.Equal(t, http.StatusBadRequest, apiError.StatusCode)
+	assert.Equal(t, "invalid dc", apiError.Message)
+	assert.Contains(t, apiError.URL, apiDcPath)
+	assert.False(t, errors.Is(err, ErrUnauthorized))
+}
+
+func TestAPIErrorMatchesErrUnauthorized(t *testing.T) {
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusUnauthorized}, ErrUnauthorized))
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusForbidden}, ErrUnauthorized))
+	assert.False(t, errors.Is(&APIError{StatusCode: http.StatusNotFound}, ErrUnauthorized))
+}
+
+func TestAPIErrorMatchesErrConflict(t *testing.T) {
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusConflict}, ErrConflict))
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusBadRequest, Message: "Backend with this address already exists."}, ErrConflict))
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusInternalServerError, Message: "Duplicate entry '1' for key"}, ErrConflict))
+	assert.False(t, errors.Is(&APIError{StatusCode: http.StatusInternalServerError, Message: "Internal server error"}, ErrConflict))
+	assert.False(t, errors.Is(&APIError{StatusCode: http.StatusNotFound, Message: "already exists"}, ErrConflict))
+}
+
+func TestAPIErrorMatchesErrDirectorLocked(t *testing.T) {
+	locked := &APIError{StatusCode: http.StatusConflict, Message: "Director was modified during VCL generation"}
+	assert.True(t, errors.Is(locked, ErrDirectorLocked))
+	assert.False(t, errors.Is(locked, ErrConflict))
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusInternalServerError, Message: "Deadlock found when trying to get lock"}, ErrDirectorLocked))
+	assert.True(t, errors.Is(&APIError{StatusCode: http.StatusLocked}, ErrDirectorLocked))
+	assert.False(t, errors.Is(&APIError{StatusCode: http.StatusConflict, Message: "already exists"}, ErrDirectorLocked))
+	assert.False(t, errors.Is(&APIError{StatusCode: http.StatusNotFound, Message: "concurrent"}, ErrDirectorLocked))
+}
+
+func TestAPIErrorDetectsMaintenance(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		body   string
+		want   bool
+	}{
+		{"header", http.StatusServiceUnavailable, "planned", "", true},
+		{"banner", http.StatusServiceUnavailable, "", "<h1>VaaS is under maintenance</h1>", true},
+		{"plain unavailable", http.StatusServiceUnavailable, "", "upstream connect error", false},
+		{"other status", http.StatusInternalServerError, "planned", "maintenance", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set(MaintenanceHeader, tt.header)
+				}
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(tt.status)
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+			client := NewClient(ts.URL, "username", "api-key")
+
+			_, err := client.GetDC(context.Background(), "dc1")
+
+			var apiError *APIError
+			require.True(t, errors.As(err, &apiError))
+			assert.Equal(t, tt.want, errors.Is(err, ErrMaintenance))
+			assert.Equal(t, 2*time.Minute, apiError.RetryAfter)
+		})
+	}
+}
+
+func TestNotFoundErrorsAreTyped(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"objects": []}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	_, err := client.FindDirectorID(context.Background(), "director")
+	assert.True(t, errors.Is(err, ErrDirectorNotFound))
+
+	_, err = client.GetDC(context.Background(), "dc1")
+	assert.True(t, errors.Is(err, ErrDCNotFound))
+
+	_, err = client.FindBackend(context.Background(), createDirector(1), "127.0.0.1", 80)
+	assert.True(t, errors.Is(err, ErrBackendNotFound))
+}
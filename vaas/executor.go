@@ -0,0 +1,94 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// executorIdleTimeout is how long a worker of an executor waits for a request before it stops.
+const executorIdleTimeout = 30 * time.Second
+
+// WithMaxConcurrentRequests sends requests of the client to VaaS from a pool of at most parallelism workers,
+// however many goroutines use the client, so that a process registering many backends at once does not flood
+// VaaS or exhaust its connections. Requests wait for a free worker until their context expires.
+// Zero means no limit, the default.
+func WithMaxConcurrentRequests(parallelism int) Option {
+	return func(c *defaultClient) {
+		c.executor = nil
+		if parallelism > 0 {
+			c.executor = newExecutor(parallelism)
+		}
+	}
+}
+
+// WithTransport makes the client send requests with transport, so that several clients share its connection pool,
+// e.g. clients of the same VaaS with different credentials. Options configuring the transport of the client
+// (connection pool, TLS, Unix socket) given after it change the shared transport.
+func WithTransport(transport *http.Transport) Option {
+	return func(c *defaultClient) {
+		c.transport = transport
+		c.httpClient = &http.Client{Transport: transport, Timeout: c.httpClient.Timeout}
+	}
+}
+
+// executor runs jobs on a pool of workers started on demand, up to its parallelism.
+// Workers stop after executorIdleTimeout without jobs, so an idle client holds no goroutines.
+type executor struct {
+	jobs    chan func()
+	workers chan struct{}
+	idle    time.Duration
+}
+
+func newExecutor(parallelism int) *executor {
+	return &executor{
+		jobs:    make(chan func()),
+		workers: make(chan struct{}, parallelism),
+		idle:    executorIdleTimeout,
+	}
+}
+
+// run runs job on a worker and waits until it is done. It returns the context error, without running job,
+// when the context expires before a worker is free. A nil executor runs job right away.
+func (e *executor) run(ctx context.Context, job func()) error {
+	if e == nil {
+		job()
+		return nil
+	}
+
+	done := make(chan struct{})
+	wrapped := func() {
+		defer close(done)
+		job()
+	}
+	// Prefer an idle worker to starting a new one.
+	select {
+	case e.jobs <- wrapped:
+		<-done
+		return nil
+	default:
+	}
+	select {
+	case e.jobs <- wrapped:
+	case e.workers <- struct{}{}:
+		go e.work(wrapped)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return nil
+}
+
+// work runs job and then jobs sent by run until none comes for the idle timeout
+func (e *executor) work(job func()) {
+	defer func() { <-e.workers }()
+
+	for {
+		job()
+		select {
+		case job = <-e.jobs:
+		case <-time.After(e.idle):
+			return
+		}
+	}
+}
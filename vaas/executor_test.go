@@ -0,0 +1,153 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorLimitsParallelism(t *testing.T) {
+	e := newExecutor(3)
+	var running, maxRunning int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, e.run(context.Background(), func() {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), maxRunning)
+}
+
+func TestExecutorGivesUpWhenContextExpires(t *testing.T) {
+	e := newExecutor(1)
+	release := make(chan struct{})
+	go func() {
+		assert.NoError(t, e.run(context.Background(), func() { <-release }))
+	}()
+	defer close(release)
+	require.Eventually(t, func() bool { return len(e.workers) == 1 }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := e.run(ctx, func() { ran = true })
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.False(t, ran)
+}
+
+func TestExecutorStopsIdleWorkers(t *testing.T) {
+	e := newExecutor(2)
+	e.idle = time.Millisecond
+
+	require.NoError(t, e.run(context.Background(), func() {}))
+
+	assert.Eventually(t, func() bool { return len(e.workers) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestClientIsSafeForConcurrentUse(t *testing.T) {
+	var requests, inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"objects": [{"id": 1, "name": %q, "symbol": "dc1"}]}`, r.URL.Query().Get("name"))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key",
+		WithMaxConcurrentRequests(4),
+		WithRateLimit(10000, 100),
+		WithCircuitBreaker(NewCircuitBreaker(5, time.Second)),
+		WithLookupCache(NewLookupCache(time.Minute, "")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			director, err := client.FindDirector(context.Background(), fmt.Sprintf("director-%d", i%10))
+			assert.NoError(t, err)
+			if assert.NotNil(t, director) {
+				assert.Equal(t, fmt.Sprintf("director-%d", i%10), director.Name)
+			}
+			_, err = client.GetDC(context.Background(), "dc1")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInFlight, int32(4))
+	assert.LessOrEqual(t, requests, int32(100))
+}
+
+func TestClientsShareTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	first := NewClient(ts.URL, "first", "api-key", WithTransport(transport), WithTimeout(time.Second))
+	second := NewClient(ts.URL, "second", "api-key", WithTimeout(time.Second), WithTransport(transport))
+
+	assert.Same(t, transport, first.(*defaultClient).httpClient.Transport)
+	assert.Same(t, transport, second.(*defaultClient).httpClient.Transport)
+	assert.Equal(t, time.Second, second.(*defaultClient).httpClient.Timeout)
+	assert.NoError(t, first.ValidateCredentials(context.Background()))
+	assert.NoError(t, second.ValidateCredentials(context.Background()))
+}
+
+func BenchmarkClientConcurrentRequests(b *testing.B) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"objects": [{"id": 1, "name": "director"}]}`))
+	}))
+	defer ts.Close()
+
+	for _, parallelism := range []int{0, 4, 16, 64} {
+		b.Run(fmt.Sprintf("max-concurrent-requests=%d", parallelism), func(b *testing.B) {
+			client := NewClient(ts.URL, "username", "api-key",
+				WithMaxConcurrentRequests(parallelism), WithMaxIdleConnsPerHost(64))
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.FindDirector(context.Background(), "director"); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
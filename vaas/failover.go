@@ -0,0 +1,73 @@
+package vaas
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithFailoverHosts makes the client send a request to hosts, in order, when the client host and every host before
+// is unreachable or answers with a 5xx status, e.g. while VaaS of one region is down or in maintenance.
+// Hosts must serve the same VaaS, such as its replicas in other regions, as resource URIs returned by one of them
+// are sent to the others.
+func WithFailoverHosts(hosts ...string) Option {
+	return func(c *defaultClient) {
+		if len(hosts) == 0 {
+			return
+		}
+		c.middlewares = append(c.middlewares, FailoverMiddleware(c.host, hosts...))
+	}
+}
+
+// FailoverMiddleware sends requests to primary to hosts, in order, when it and every host before is unreachable or
+// answers with a 5xx status. Requests whose body can not be sent again are not failed over.
+func FailoverMiddleware(primary string, hosts ...string) Middleware {
+	primary = strings.TrimSuffix(primary, "/")
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			response, err := next.RoundTrip(request)
+			target := request.URL.String()
+			if !strings.HasPrefix(target, primary) || (request.Body != nil && request.GetBody == nil) {
+				return response, err
+			}
+			for _, host := range hosts {
+				if !failsOver(response, err) || request.Context().Err() != nil {
+					break
+				}
+				failover, failoverErr := rewindTo(request, strings.TrimSuffix(host, "/")+strings.TrimPrefix(target, primary))
+				if failoverErr != nil {
+					break
+				}
+				log.WithContext(request.Context()).Warnf("VaaS request %s %s failed, failing over to %s: %s",
+					request.Method, redactURL(request.URL), redactURL(failover.URL), failureOf(response, err))
+				if response != nil {
+					response.Body.Close()
+				}
+				response, err = next.RoundTrip(failover)
+			}
+			return response, err
+		})
+	}
+}
+
+// failsOver tells whether a request that got response or err is sent to the next host
+func failsOver(response *http.Response, err error) bool {
+	return err != nil || response.StatusCode >= http.StatusInternalServerError
+}
+
+// rewindTo returns request, with its body rewound, sent to target instead
+func rewindTo(request *http.Request, target string) (*http.Request, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	failover, err := rewind(request)
+	if err != nil {
+		return nil, err
+	}
+	failover.URL = u
+	failover.Host = u.Host
+	return failover, nil
+}
@@ -0,0 +1,54 @@
+package vaas
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverHostsServeRequestsWhilePrimaryFails(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	var path, body string
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer secondary.Close()
+
+	client := NewClient(primary.URL, "username", "api-key", WithFailoverHosts(down.URL, secondary.URL+"/"))
+	weight := 5
+
+	require.NoError(t, client.UpdateBackend(context.Background(), 42, BackendPatch{Weight: &weight}))
+	assert.Equal(t, "/api/v0.1/backend/42/", path)
+	assert.JSONEq(t, `{"weight": 5}`, body)
+}
+
+func TestFailoverHostsSkipClientErrors(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer primary.Close()
+	var called bool
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer secondary.Close()
+
+	client := NewClient(primary.URL, "username", "api-key", WithFailoverHosts(secondary.URL))
+
+	_, err := client.GetBackend(context.Background(), 42)
+	require.Error(t, err)
+	assert.False(t, called)
+}
@@ -0,0 +1,141 @@
+package vaas
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultFaultLatency = time.Second
+	defaultFaultStatus  = http.StatusServiceUnavailable
+)
+
+// errInjectedReset is the cause of connections FaultMiddleware resets
+var errInjectedReset = errors.New("connection reset by peer (injected fault)")
+
+// FaultConfig describes faults FaultMiddleware injects into requests, each with the probability of its rate,
+// from 0 to 1
+type FaultConfig struct {
+	// LatencyRate delays requests by a random duration up to MaxLatency
+	LatencyRate float64
+	MaxLatency  time.Duration
+	// ErrorRate answers requests with ErrorStatus without sending them
+	ErrorRate   float64
+	ErrorStatus int
+	// ResetRate fails requests as if VaaS reset the connection
+	ResetRate float64
+}
+
+// ParseFaultConfig parses faults given like "latency=0.2:2s,error=0.1:503,reset=0.05", each with its rate and
+// optionally the maximum latency, 1s by default, or the status of errors, 503 by default
+func ParseFaultConfig(spec string) (FaultConfig, error) {
+	config := FaultConfig{MaxLatency: defaultFaultLatency, ErrorStatus: defaultFaultStatus}
+	for _, fault := range strings.Split(spec, ",") {
+		fault = strings.TrimSpace(fault)
+		if fault == "" {
+			continue
+		}
+		parts := strings.SplitN(fault, "=", 2)
+		if len(parts) != 2 {
+			return config, fmt.Errorf("invalid fault %q, expected kind=rate", fault)
+		}
+		value := strings.SplitN(parts[1], ":", 2)
+		rate, err := strconv.ParseFloat(value[0], 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return config, fmt.Errorf("invalid rate of fault %q, expected a number from 0 to 1", fault)
+		}
+		param := ""
+		if len(value) == 2 {
+			param = value[1]
+		}
+
+		switch parts[0] {
+		case "latency":
+			config.LatencyRate = rate
+			if param != "" {
+				if config.MaxLatency, err = time.ParseDuration(param); err != nil || config.MaxLatency <= 0 {
+					return config, fmt.Errorf("invalid latency of fault %q", fault)
+				}
+			}
+		case "error":
+			config.ErrorRate = rate
+			if param != "" {
+				if config.ErrorStatus, err = strconv.Atoi(param); err != nil || config.ErrorStatus < 400 || config.ErrorStatus > 599 {
+					return config, fmt.Errorf("invalid status of fault %q, expected 4xx or 5xx", fault)
+				}
+			}
+		case "reset":
+			config.ResetRate = rate
+		default:
+			return config, fmt.Errorf("unknown fault %q, expected latency, error or reset", parts[0])
+		}
+	}
+	return config, nil
+}
+
+func (config FaultConfig) String() string {
+	return fmt.Sprintf("latency=%g:%s,error=%g:%d,reset=%g",
+		config.LatencyRate, config.MaxLatency, config.ErrorRate, config.ErrorStatus, config.ResetRate)
+}
+
+// WithFaults injects faults given like ParseFaultConfig parses them into requests of the client, for game days
+// verifying that retries, circuit breaking and draining hold up. It is not meant for regular use.
+func WithFaults(spec string) Option {
+	return func(c *defaultClient) {
+		config, err := ParseFaultConfig(spec)
+		if err != nil {
+			c.optionError(err)
+			return
+		}
+		log.Warnf("Injecting faults into VaaS requests: %s", config)
+		c.middlewares = append(c.middlewares, FaultMiddleware(config))
+	}
+}
+
+// FaultMiddleware randomly delays, fails or resets requests as config describes, logging every injected fault
+func FaultMiddleware(config FaultConfig) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			logger := log.WithContext(request.Context()).
+				WithField("method", request.Method).
+				WithField("url", redactURL(request.URL))
+
+			if config.LatencyRate > 0 && rand.Float64() < config.LatencyRate {
+				latency := time.Duration(rand.Int63n(int64(config.MaxLatency)) + 1)
+				logger.Warnf("Injecting latency of %s", latency)
+				select {
+				case <-time.After(latency):
+				case <-request.Context().Done():
+					return nil, request.Context().Err()
+				}
+			}
+			if config.ResetRate > 0 && rand.Float64() < config.ResetRate {
+				logger.Warn("Injecting connection reset")
+				return nil, &net.OpError{Op: "read", Net: "tcp", Err: errInjectedReset}
+			}
+			if config.ErrorRate > 0 && rand.Float64() < config.ErrorRate {
+				logger.Warnf("Injecting HTTP %d response", config.ErrorStatus)
+				return &http.Response{
+					Status:     fmt.Sprintf("%d %s", config.ErrorStatus, http.StatusText(config.ErrorStatus)),
+					StatusCode: config.ErrorStatus,
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+					Header:     http.Header{"Content-Type": {"text/plain"}},
+					Body:       ioutil.NopCloser(strings.NewReader("injected fault")),
+					Request:    request,
+				}, nil
+			}
+			return next.RoundTrip(request)
+		})
+	}
+}
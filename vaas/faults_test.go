@@ -0,0 +1,75 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFaultConfig(t *testing.T) {
+	config, err := ParseFaultConfig("latency=0.2:2s, error=0.1:500,reset=0.05")
+
+	require.NoError(t, err)
+	assert.Equal(t, FaultConfig{LatencyRate: 0.2, MaxLatency: 2 * time.Second, ErrorRate: 0.1, ErrorStatus: 500, ResetRate: 0.05}, config)
+
+	config, err = ParseFaultConfig("error=1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, config.ErrorStatus)
+
+	for _, spec := range []string{"latency", "error=2", "error=1:200", "latency=1:soon", "drop=0.1"} {
+		_, err := ParseFaultConfig(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestFaultMiddlewareInjectsErrors(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithFaults("error=1"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}))
+	err := client.DeleteBackend(context.Background(), 42)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func TestFaultMiddlewareResetsConnections(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithFaults("reset=1"))
+	err := client.DeleteBackend(context.Background(), 42)
+
+	require.True(t, errors.Is(err, errInjectedReset), err)
+}
+
+func TestFaultMiddlewareDelaysRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithFaults("latency=1:20ms"))
+	require.NoError(t, client.DeleteBackend(context.Background(), 42))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, client.DeleteBackend(ctx, 42))
+}
+
+func TestWithFaultsRejectsInvalidSpec(t *testing.T) {
+	client := NewClient("http://vaas.example.com", "username", "api-key", WithFaults("drop=1"))
+
+	assert.Error(t, client.DeleteBackend(context.Background(), 42))
+}
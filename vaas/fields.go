@@ -0,0 +1,109 @@
+package vaas
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Fields are JSON fields of a VaaS object which this client does not model, e.g. ones of newer VaaS versions.
+// They are kept when the object is decoded and sent back with it, so that replacing it does not drop them.
+type Fields map[string]json.RawMessage
+
+// modeled JSON field names of objects keeping their other Fields
+var (
+	backendFields  = jsonNames(reflect.TypeOf(Backend{}))
+	directorFields = jsonNames(reflect.TypeOf(Director{}))
+)
+
+// UnmarshalJSON decodes backend, keeping fields it does not model in Extra.
+func (b *Backend) UnmarshalJSON(data []byte) error {
+	type plain Backend
+	if err := json.Unmarshal(data, (*plain)(b)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, backendFields)
+	b.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes backend together with its Extra fields. Created is left out, as VaaS does not take it.
+func (b Backend) MarshalJSON() ([]byte, error) {
+	type plain Backend
+	b.Created = nil
+	data, err := json.Marshal(plain(b))
+	if err != nil {
+		return nil, err
+	}
+	return withFields(data, b.Extra)
+}
+
+// UnmarshalJSON decodes director, keeping fields it does not model in Extra.
+func (d *Director) UnmarshalJSON(data []byte) error {
+	type plain Director
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+	extra, err := unknownFields(data, directorFields)
+	d.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes director together with its Extra fields.
+func (d Director) MarshalJSON() ([]byte, error) {
+	type plain Director
+	data, err := json.Marshal(plain(d))
+	if err != nil {
+		return nil, err
+	}
+	return withFields(data, d.Extra)
+}
+
+// unknownFields returns fields of the JSON object data other than known ones, nil when there are none
+func unknownFields(data []byte, known map[string]bool) (Fields, error) {
+	var all Fields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name := range all {
+		if known[name] {
+			delete(all, name)
+		}
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// withFields adds fields missing from the JSON object data, which is returned unchanged when there are none
+func withFields(data []byte, fields Fields) ([]byte, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	var all Fields
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if _, ok := all[name]; !ok {
+			all[name] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+// jsonNames returns JSON names of fields of struct type t
+func jsonNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
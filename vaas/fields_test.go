@@ -0,0 +1,62 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendKeepsUnmodeledFields(t *testing.T) {
+	data := []byte(`{"id": 42, "address": "127.0.0.1", "port": 80, "enabled": false,
+		"ssl": true, "via": "/api/v0.1/backend/7/", "headers": {"X-Mesh": "on"}}`)
+
+	var backend Backend
+	require.NoError(t, json.Unmarshal(data, &backend))
+
+	assert.Equal(t, "127.0.0.1", backend.Address)
+	assert.False(t, *backend.Enabled)
+	assert.Len(t, backend.Extra, 3)
+	encoded, err := json.Marshal(backend)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 42, "address": "127.0.0.1", "port": 80, "enabled": false, "dc": {},
+		"ssl": true, "via": "/api/v0.1/backend/7/", "headers": {"X-Mesh": "on"}}`, string(encoded))
+}
+
+func TestBackendWithoutUnmodeledFieldsEncodesAsBefore(t *testing.T) {
+	var backend Backend
+	require.NoError(t, json.Unmarshal([]byte(`{"address": "127.0.0.1", "port": 80}`), &backend))
+
+	assert.Nil(t, backend.Extra)
+	encoded, err := json.Marshal(backend)
+	require.NoError(t, err)
+	assert.Equal(t, `{"address":"127.0.0.1","dc":{},"port":80}`, string(encoded))
+}
+
+func TestUpdateDirectorSendsBackUnmodeledFields(t *testing.T) {
+	var sent map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(rawRequest, &sent))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	var director Director
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 5, "name": "director", "reachable_via_service_mesh": true,
+		"service_mesh_label": "web", "virtual": true}`), &director))
+
+	require.NoError(t, NewClient(ts.URL, "username", "api-key").UpdateDirector(context.Background(), &director))
+
+	assert.True(t, *director.ReachableViaServiceMesh)
+	assert.Equal(t, "web", director.ServiceMeshLabel)
+	assert.Equal(t, float64(5), sent["id"])
+	assert.Equal(t, true, sent["reachable_via_service_mesh"])
+	assert.Equal(t, "web", sent["service_mesh_label"])
+	assert.Equal(t, true, sent["virtual"])
+}
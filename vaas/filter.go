@@ -0,0 +1,148 @@
+package vaas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestampLayouts are layouts of timestamps in VaaS API, which leaves out the zone of UTC times
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"}
+
+// ErrEmptyFilter is returned by DeleteBackendsWhere for a filter matching every backend.
+var ErrEmptyFilter = errors.New("backend filter matches every backend")
+
+// Timestamp represents a time in VaaS API, encoded in RFC 3339 or, for UTC, without zone.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes Timestamp from a JSON string in any of the layouts VaaS uses.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, string(data)); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid VaaS timestamp %s", data)
+}
+
+// BackendFilter selects backends having all of its set fields, e.g. for DeleteBackendsWhere.
+type BackendFilter struct {
+	// Tag the backend has.
+	Tag string
+	// AddressPrefix the address of the backend starts with, e.g. "10.1." for a subnet.
+	AddressPrefix string
+	// RegisteredBefore is a time the backend was created before. Backends for which VaaS reports no creation
+	// time never match.
+	RegisteredBefore time.Time
+	// HeartbeatBefore is a time the last heartbeat tag of the backend is older than, see WithHeartbeat. Backends
+	// without heartbeat tags never match.
+	HeartbeatBefore time.Time
+}
+
+// IsEmpty returns whether filter has no field set, matching every backend.
+func (f BackendFilter) IsEmpty() bool {
+	return f.Tag == "" && f.AddressPrefix == "" && f.RegisteredBefore.IsZero() && f.HeartbeatBefore.IsZero()
+}
+
+// Matches returns whether backend has all the set fields of filter.
+func (f BackendFilter) Matches(backend Backend) bool {
+	if f.Tag != "" && !hasTag(backend.Tags, f.Tag) {
+		return false
+	}
+	if f.AddressPrefix != "" && !strings.HasPrefix(NormalizeAddress(backend.Address), f.AddressPrefix) {
+		return false
+	}
+	if !f.RegisteredBefore.IsZero() && (backend.Created == nil || !backend.Created.Before(f.RegisteredBefore)) {
+		return false
+	}
+	if !f.HeartbeatBefore.IsZero() {
+		if last, ok := LastHeartbeat(backend.Tags); !ok || !last.Before(f.HeartbeatBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns backends matching filter, in their order.
+func (f BackendFilter) Select(backends []Backend) []Backend {
+	var selected []Backend
+	for _, backend := range backends {
+		if f.Matches(backend) {
+			selected = append(selected, backend)
+		}
+	}
+	return selected
+}
+
+// DeleteBackendsWhere deletes backends of director matching filter in parallel, with at most WithBulkConcurrency
+// requests at once. It returns deleted backends and a *BulkError if any of them could not be deleted, and refuses
+// an empty filter with ErrEmptyFilter.
+func (c *defaultClient) DeleteBackendsWhere(ctx context.Context, director string, filter BackendFilter) ([]Backend, error) {
+	if filter.IsEmpty() {
+		return nil, ErrEmptyFilter
+	}
+	dir, err := c.FindDirector(ctx, director)
+	if err != nil {
+		return nil, err
+	}
+	backends, err := c.ListBackends(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := filter.Select(backends)
+	errs := make([]error, len(matching))
+	slots := make(chan struct{}, c.bulkConcurrency)
+	var wg sync.WaitGroup
+	for i := range matching {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if matching[i].ID == nil {
+				errs[i] = fmt.Errorf("%w: no ID of %s", ErrBackendNotFound, HostPort(matching[i].Address, matching[i].Port))
+				return
+			}
+			errs[i] = c.DeleteBackend(ctx, int(*matching[i].ID))
+		}(i)
+	}
+	wg.Wait()
+
+	deleted := make([]Backend, 0, len(matching))
+	failed := make([]*Backend, len(matching))
+	for i := range matching {
+		failed[i] = &matching[i]
+		if errs[i] == nil {
+			deleted = append(deleted, matching[i])
+		}
+	}
+	return deleted, newBulkError(failed, errs)
+}
@@ -0,0 +1,43 @@
+package vaas
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampUnmarshalsVaaSLayouts(t *testing.T) {
+	for _, data := range []string{`"2026-03-01T10:00:00Z"`, `"2026-03-01T10:00:00"`, `"2026-03-01T10:00:00.000000"`, `"2026-03-01 10:00:00"`} {
+		var timestamp Timestamp
+		require.NoError(t, json.Unmarshal([]byte(data), &timestamp), data)
+		assert.True(t, timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)), data)
+	}
+	var timestamp Timestamp
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &timestamp))
+}
+
+func TestBackendFilterMatchesAllSetFields(t *testing.T) {
+	created := &Timestamp{time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)}
+	backend := Backend{Address: "10.1.0.7", Tags: []string{"canary", "dc1"}, Created: created}
+
+	assert.True(t, BackendFilter{Tag: "canary"}.Matches(backend))
+	assert.True(t, BackendFilter{Tag: "canary", AddressPrefix: "10.1."}.Matches(backend))
+	assert.False(t, BackendFilter{Tag: "canary", AddressPrefix: "10.2."}.Matches(backend))
+	assert.True(t, BackendFilter{RegisteredBefore: created.Add(time.Hour)}.Matches(backend))
+	assert.False(t, BackendFilter{RegisteredBefore: created.Add(-time.Hour)}.Matches(backend))
+	assert.False(t, BackendFilter{RegisteredBefore: created.Add(time.Hour)}.Matches(Backend{Address: "10.1.0.8"}))
+	assert.True(t, BackendFilter{}.IsEmpty())
+}
+
+func TestBackendFilterMatchesSilentBackends(t *testing.T) {
+	beat := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	backend := Backend{Address: "10.1.0.7", Tags: []string{"canary", HeartbeatTag(beat)}}
+
+	assert.True(t, BackendFilter{HeartbeatBefore: beat.Add(time.Minute)}.Matches(backend))
+	assert.False(t, BackendFilter{HeartbeatBefore: beat}.Matches(backend))
+	assert.False(t, BackendFilter{HeartbeatBefore: beat.Add(time.Minute)}.Matches(Backend{Tags: []string{"canary"}}))
+	assert.False(t, BackendFilter{HeartbeatBefore: beat}.IsEmpty())
+}
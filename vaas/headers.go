@@ -0,0 +1,58 @@
+package vaas
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const userAgentHeader = "User-Agent"
+
+// tokenChars are the characters besides letters and digits allowed in header names by RFC 7230
+const tokenChars = "!#$%&'*+-.^_`|~"
+
+// WithUserAgent sets the User-Agent header of every request, e.g. naming the tool and the version registering
+// backends, so that VaaS access logs tell who made a change. Defaults to the one of net/http.
+func WithUserAgent(userAgent string) Option {
+	return func(c *defaultClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a static header to every request, e.g. X-Deploy-ID or X-Initiator attributing changes in VaaS
+// audit logs to a deployment pipeline. Headers set by the client itself, such as credentials, take precedence.
+func WithHeader(name, value string) Option {
+	return func(c *defaultClient) {
+		if !validHeaderName(name) {
+			c.optionError(fmt.Errorf("invalid header name %q", name))
+			return
+		}
+		if c.headers == nil {
+			c.headers = http.Header{}
+		}
+		c.headers.Add(name, value)
+	}
+}
+
+// setStaticHeaders sets the User-Agent and static headers of the client on request, before authentication and
+// request IDs are set
+func (c *defaultClient) setStaticHeaders(request *http.Request) {
+	for name, values := range c.headers {
+		request.Header[name] = append([]string(nil), values...)
+	}
+	if c.userAgent != "" {
+		request.Header.Set(userAgentHeader, c.userAgent)
+	}
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune(tokenChars, r)) {
+			return false
+		}
+	}
+	return true
+}
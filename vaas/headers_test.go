@@ -0,0 +1,43 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendsUserAgentAndStaticHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "vaas-hook/1.2.3 (commit abc123)", r.Header.Get(userAgentHeader))
+		assert.Equal(t, "deploy-42", r.Header.Get("X-Deploy-ID"))
+		assert.Equal(t, []string{"ci", "pipeline"}, r.Header.Values("X-Initiator"))
+		assert.Equal(t, "ApiKey username:api-key", r.Header.Get(authorizationHeader))
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0),
+		WithUserAgent("vaas-hook/1.2.3 (commit abc123)"),
+		WithHeader("X-Deploy-ID", "deploy-42"),
+		WithHeader("X-Initiator", "ci"), WithHeader("x-initiator", "pipeline"),
+		WithHeader(authorizationHeader, "forged"))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+}
+
+func TestClientRejectsInvalidHeaderName(t *testing.T) {
+	client := NewClient("http://vaas.invalid", "username", "api-key", WithDCCacheTTL(0), WithHeader("X Deploy", "42"))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	assert.EqualError(t, err, `invalid header name "X Deploy"`)
+}
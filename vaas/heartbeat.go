@@ -0,0 +1,45 @@
+package vaas
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeartbeatTagPrefix starts the tag recording the last heartbeat of a backend, followed by a Unix time in seconds.
+const HeartbeatTagPrefix = "heartbeat-"
+
+// HeartbeatTag returns the tag recording a heartbeat at t.
+func HeartbeatTag(t time.Time) string {
+	return HeartbeatTagPrefix + strconv.FormatInt(t.Unix(), 10)
+}
+
+// LastHeartbeat returns the latest heartbeat recorded in tags, and false when they record none.
+func LastHeartbeat(tags []string) (time.Time, bool) {
+	var last time.Time
+	found := false
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, HeartbeatTagPrefix) {
+			continue
+		}
+		seconds, err := strconv.ParseInt(strings.TrimPrefix(tag, HeartbeatTagPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(seconds, 0); !found || t.After(last) {
+			last, found = t, true
+		}
+	}
+	return last, found
+}
+
+// WithHeartbeat returns tags with their heartbeat tags replaced by the one of a heartbeat at t.
+func WithHeartbeat(tags []string, t time.Time) []string {
+	updated := make([]string, 0, len(tags)+1)
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, HeartbeatTagPrefix) {
+			updated = append(updated, tag)
+		}
+	}
+	return append(updated, HeartbeatTag(t))
+}
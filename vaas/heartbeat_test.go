@@ -0,0 +1,26 @@
+package vaas
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHeartbeatReplacesHeartbeatTags(t *testing.T) {
+	first := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+
+	tags := WithHeartbeat(WithHeartbeat([]string{"canary"}, first), second)
+
+	assert.Equal(t, []string{"canary", "heartbeat-1772359260"}, tags)
+	last, ok := LastHeartbeat(tags)
+	assert.True(t, ok)
+	assert.True(t, last.Equal(second))
+}
+
+func TestLastHeartbeatIgnoresOtherTags(t *testing.T) {
+	_, ok := LastHeartbeat([]string{"canary", "heartbeat-soon"})
+
+	assert.False(t, ok)
+}
@@ -0,0 +1,71 @@
+package vaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ID represents an identifier of an object in VaaS API.
+// Some VaaS versions encode IDs as JSON strings instead of numbers, so ID accepts both when decoding.
+type ID int
+
+// UnmarshalJSON decodes ID from either a JSON number or a JSON string containing a number.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var raw string
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		data = []byte(raw)
+	}
+
+	value, err := strconv.Atoi(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid VaaS ID %s: %s", data, err)
+	}
+	*id = ID(value)
+	return nil
+}
+
+// NewID returns a pointer to ID with given value, for use in optional ID fields.
+func NewID(value int) *ID {
+	id := ID(value)
+	return &id
+}
+
+// BackendIDFromURI returns the ID of a backend from its resource URI or a Location header naming it, such as
+// /api/v0.1/backend/42/ or http://vaas.example.com/api/v0.1/backend/42/.
+func BackendIDFromURI(uri string) (int, error) {
+	return resourceID(uri, "backend")
+}
+
+// DirectorIDFromURI returns the ID of a director from its resource URI or a Location header naming it.
+func DirectorIDFromURI(uri string) (int, error) {
+	return resourceID(uri, "director")
+}
+
+// resourceID returns the positive ID ending the path of uri, which has to follow the path segment of resource
+func resourceID(uri string, resource string) (int, error) {
+	parsed, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: %s", ErrInvalidResourceURI, uri, err)
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] != resource {
+		return 0, fmt.Errorf("%w %q: not a %s", ErrInvalidResourceURI, uri, resource)
+	}
+	id, err := strconv.Atoi(segments[len(segments)-1])
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w %q: no %s ID", ErrInvalidResourceURI, uri, resource)
+	}
+	return id, nil
+}
@@ -0,0 +1,92 @@
+package vaas
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDDecodingFromNumberAndString(t *testing.T) {
+	var backends []Backend
+	err := json.Unmarshal([]byte(`[{"id": 12}, {"id": "13"}, {"id": null}, {}]`), &backends)
+
+	require.NoError(t, err)
+	require.Len(t, backends, 4)
+	assert.Equal(t, ID(12), *backends[0].ID)
+	assert.Equal(t, ID(13), *backends[1].ID)
+	assert.Nil(t, backends[2].ID)
+	assert.Nil(t, backends[3].ID)
+}
+
+func TestIDDecodingFailsOnNonNumericString(t *testing.T) {
+	var dc DC
+	err := json.Unmarshal([]byte(`{"id": "dc1"}`), &dc)
+
+	assert.Error(t, err)
+}
+
+func TestZeroBackendIDIsNotOmittedWhenEncoding(t *testing.T) {
+	backend := Backend{ID: NewID(0), DC: DC{ID: 0}}
+
+	data, err := json.Marshal(backend)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, float64(0), decoded["id"])
+	assert.NotContains(t, decoded["dc"], "id")
+}
+
+func TestUnsetDirectorIDIsOmittedWhenEncoding(t *testing.T) {
+	data, err := json.Marshal(Director{Name: "director"})
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(data), `"id"`)
+}
+
+func TestZeroIDRoundTrips(t *testing.T) {
+	var backend Backend
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "0"}`), &backend))
+	require.NotNil(t, backend.ID)
+
+	data, err := json.Marshal(backend)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id":0`)
+}
+
+func TestBackendIDFromURI(t *testing.T) {
+	for uri, expected := range map[string]int{
+		"/api/v0.1/backend/42/":                       42,
+		"/api/v0.1/backend/42":                        42,
+		"http://vaas.example.com/api/v0.1/backend/7/": 7,
+	} {
+		id, err := BackendIDFromURI(uri)
+		require.NoError(t, err, uri)
+		assert.Equal(t, expected, id, uri)
+	}
+
+	for _, uri := range []string{"", "/api/v0.1/director/42/", "/api/v0.1/backend/", "/api/v0.1/backend/x/",
+		"/api/v0.1/backend/-1/", "location"} {
+		_, err := BackendIDFromURI(uri)
+		assert.True(t, errors.Is(err, ErrInvalidResourceURI), uri)
+	}
+}
+
+func TestDirectorIDFromURI(t *testing.T) {
+	id, err := DirectorIDFromURI("/api/v0.1/director/3/")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, id)
+}
+
+func TestNewRegistrationResultParsesIDFromResourceURI(t *testing.T) {
+	backend := Backend{ResourceURI: "/api/v0.1/backend/5/", TaskURI: "/api/v0.1/task/1/"}
+
+	result := NewRegistrationResult(&backend)
+
+	assert.Equal(t, RegistrationResult{ID: 5, ResourceURI: "/api/v0.1/backend/5/", TaskURI: "/api/v0.1/task/1/"}, result)
+	assert.Equal(t, NewID(5), backend.ID)
+}
@@ -0,0 +1,63 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// InFlight counts requests in progress, so that shutdown can wait for them to finish.
+// It is safe for concurrent use, and a single InFlight can be shared by many clients, see WithInFlight.
+type InFlight struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed while no request is in progress
+	idle chan struct{}
+}
+
+// NewInFlight creates an InFlight with no requests in progress.
+func NewInFlight() *InFlight {
+	idle := make(chan struct{})
+	close(idle)
+	return &InFlight{idle: idle}
+}
+
+// WithInFlight makes the client count its requests in inFlight instead of a counter of its own, so that WaitIdle
+// of inFlight waits for requests of every client sharing it.
+func WithInFlight(inFlight *InFlight) Option {
+	return func(c *defaultClient) {
+		c.inFlight = inFlight
+	}
+}
+
+func (f *InFlight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.count == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.count++
+}
+
+func (f *InFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count--
+	if f.count == 0 {
+		close(f.idle)
+	}
+}
+
+// WaitIdle blocks until no request is in progress or ctx is done.
+func (f *InFlight) WaitIdle(ctx context.Context) error {
+	f.mu.Lock()
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for in-flight VaaS requests: %w", ctx.Err())
+	}
+}
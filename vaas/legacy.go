@@ -0,0 +1,58 @@
+package vaas
+
+import "context"
+
+// LegacyClient is the VaaS client interface from before its methods took a context, kept so that tools built
+// against it, such as webhooks and executors, can migrate gradually. Its methods run without deadline or
+// cancellation and fail with the same typed errors as Client.
+//
+// Deprecated: use Client, whose methods take a context, or its versioned import path vaas/v2.
+type LegacyClient interface {
+	FindDirector(name string) (*Director, error)
+	FindDirectorID(name string) (int, error)
+	AddBackend(backend *Backend, director *Director) (string, error)
+	DeleteBackend(id int) error
+	GetDC(name string) (*DC, error)
+	FindBackend(director *Director, address string, port int) (*Backend, error)
+	FindBackendID(director string, address string, port int) (int, error)
+}
+
+// NewLegacyClient adapts client to LegacyClient, calling it with context.Background().
+//
+// Deprecated: call client itself with a context.
+func NewLegacyClient(client Client) LegacyClient {
+	return legacyClient{client: client}
+}
+
+type legacyClient struct {
+	client Client
+}
+
+func (c legacyClient) FindDirector(name string) (*Director, error) {
+	return c.client.FindDirector(context.Background(), name)
+}
+
+func (c legacyClient) FindDirectorID(name string) (int, error) {
+	return c.client.FindDirectorID(context.Background(), name)
+}
+
+func (c legacyClient) AddBackend(backend *Backend, director *Director) (string, error) {
+	result, err := c.client.AddBackend(context.Background(), backend, director)
+	return result.ResourceURI, err
+}
+
+func (c legacyClient) DeleteBackend(id int) error {
+	return c.client.DeleteBackend(context.Background(), id)
+}
+
+func (c legacyClient) GetDC(name string) (*DC, error) {
+	return c.client.GetDC(context.Background(), name)
+}
+
+func (c legacyClient) FindBackend(director *Director, address string, port int) (*Backend, error) {
+	return c.client.FindBackend(context.Background(), director, address, port)
+}
+
+func (c legacyClient) FindBackendID(director string, address string, port int) (int, error) {
+	return c.client.FindBackendID(context.Background(), director, address, port)
+}
@@ -0,0 +1,48 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacyClientCallsClientWithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"objects": []}`))
+	}))
+	defer ts.Close()
+
+	legacy := NewLegacyClient(NewClient(ts.URL, "username", "api-key"))
+
+	_, err := legacy.FindDirector("director")
+	require.True(t, errors.Is(err, ErrDirectorNotFound))
+	_, err = legacy.FindBackendID("director", "127.0.0.1", 80)
+	assert.Error(t, err)
+}
+
+// contextRecorder records the context its FindDirector is called with
+type contextRecorder struct {
+	Client
+	ctx context.Context
+}
+
+func (c *contextRecorder) FindDirector(ctx context.Context, name string) (*Director, error) {
+	c.ctx = ctx
+	return &Director{Name: name}, nil
+}
+
+func TestLegacyClientRunsWithoutDeadline(t *testing.T) {
+	recorder := &contextRecorder{}
+
+	director, err := NewLegacyClient(recorder).FindDirector("director")
+
+	require.NoError(t, err)
+	assert.Equal(t, "director", director.Name)
+	_, hasDeadline := recorder.ctx.Deadline()
+	assert.False(t, hasDeadline)
+}
@@ -0,0 +1,99 @@
+package vaas
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds Prometheus collectors of requests sent to VaaS API.
+// A single Metrics can be shared by many clients.
+type Metrics struct {
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	deduplicated *prometheus.CounterVec
+}
+
+// NewMetrics creates Metrics and registers its collectors with registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	labels := []string{"endpoint", "method"}
+	metrics := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "vaas",
+			Name:      "requests_total",
+			Help:      "Number of requests sent to VaaS API.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "vaas",
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests to VaaS API by status code, \"error\" when no response was received.",
+		}, append(labels, "code")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "vaas",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests to VaaS API.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		deduplicated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "vaas",
+			Name:      "deduplicated_calls_total",
+			Help:      "Number of client calls which shared the request of a concurrent identical call, see WithDeduplication.",
+		}, []string{"operation"}),
+	}
+
+	for _, collector := range []prometheus.Collector{metrics.requests, metrics.errors, metrics.duration, metrics.deduplicated} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+// WithMetrics makes the client record every request in metrics, through MetricsMiddleware, and the calls
+// deduplicated by WithDeduplication.
+func WithMetrics(metrics *Metrics) Option {
+	middleware := WithMiddleware(MetricsMiddleware(metrics))
+	return func(c *defaultClient) {
+		c.metrics = metrics
+		middleware(c)
+	}
+}
+
+// deduplicate records a call of operation which shared the request of a concurrent one.
+func (m *Metrics) deduplicate(operation string) {
+	if m == nil {
+		return
+	}
+	m.deduplicated.WithLabelValues(operation).Inc()
+}
+
+// observe records a request that took given time and ended with response or err.
+func (m *Metrics) observe(request *http.Request, response *http.Response, err error, took time.Duration) {
+	if m == nil {
+		return
+	}
+
+	endpoint := endpointLabel(request.URL.Path)
+	m.requests.WithLabelValues(endpoint, request.Method).Inc()
+	m.duration.WithLabelValues(endpoint, request.Method).Observe(took.Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(endpoint, request.Method, "error").Inc()
+	} else if (response.StatusCode < 200 || response.StatusCode > 299) && response.StatusCode != http.StatusNotModified {
+		m.errors.WithLabelValues(endpoint, request.Method, strconv.Itoa(response.StatusCode)).Inc()
+	}
+}
+
+// endpointLabel replaces IDs in path with ":id" to keep the number of label values bounded.
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,46 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCountRequestsAndErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	client := NewClient(ts.URL, "username", "api-key", WithMetrics(metrics))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 42))
+
+	endpoint := "/api/v0.1/backend/:id/"
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues(endpoint, http.MethodDelete)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.errors.WithLabelValues(endpoint, http.MethodDelete, "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.duration))
+}
+
+func TestNewMetricsFailsOnDuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := NewMetrics(registry)
+	require.NoError(t, err)
+
+	_, err = NewMetrics(registry)
+
+	require.Error(t, err)
+}
+
+func TestEndpointLabelHidesIDs(t *testing.T) {
+	assert.Equal(t, "/api/v0.1/backend/", endpointLabel("/api/v0.1/backend/"))
+	assert.Equal(t, "/api/v0.1/task/:id/", endpointLabel("/api/v0.1/task/17/"))
+}
@@ -0,0 +1,117 @@
+package vaas
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Middleware decorates the transport of a client, e.g. to add headers, sign, audit or fail requests on purpose.
+// It sees every attempt at a request after the client authenticated it and before it is sent to VaaS.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f with request.
+func (f RoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// WithMiddleware wraps the transport of the client in middlewares. The first middleware is the outermost one,
+// seeing requests first and responses last; middlewares of later options are nested inside earlier ones.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *defaultClient) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// applyMiddlewares wraps the transport of the client in its middlewares, once every option has been applied.
+func (c *defaultClient) applyMiddlewares() {
+	if len(c.middlewares) == 0 {
+		return
+	}
+	transport := c.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		transport = c.middlewares[i](transport)
+	}
+	// Copy the HTTP client, which options such as WithClientCredentials may have handed over already to send other requests.
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+}
+
+// LoggingMiddleware logs every request sent to VaaS with its status and duration to logger, at debug level.
+// Credentials in URLs are masked.
+func LoggingMiddleware(logger log.FieldLogger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			start := time.Now()
+			response, err := next.RoundTrip(request)
+			entry := logger.WithField("method", request.Method).
+				WithField("url", redactURL(request.URL)).
+				WithField("duration", time.Since(start))
+			if err != nil {
+				entry.WithError(redactError(err)).Debug("VaaS request failed")
+			} else {
+				entry.WithField("status", response.StatusCode).Debug("VaaS request sent")
+			}
+			return response, err
+		})
+	}
+}
+
+// MetricsMiddleware records every request sent to VaaS in metrics. WithMetrics adds it to a client.
+func MetricsMiddleware(metrics *Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			start := time.Now()
+			response, err := next.RoundTrip(request)
+			metrics.observe(request, response, err, time.Since(start))
+			return response, err
+		})
+	}
+}
+
+// RetryMiddleware retries requests failing with a transport error or a retryable status of policy, below the
+// client, so that retries skip its rate limiter and circuit breaker. WithRetryPolicy retries above them instead.
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			response, err := next.RoundTrip(request)
+			for attempt := 1; policy.shouldRetry(attempt, request, response, failureOf(response, err)); attempt++ {
+				if request.Body != nil && request.GetBody == nil {
+					break
+				}
+				select {
+				case <-time.After(policy.delayAfter(request.Context(), attempt, response)):
+				case <-request.Context().Done():
+					return response, err
+				}
+
+				if response != nil {
+					response.Body.Close()
+				}
+				retry, rewindErr := rewind(request)
+				if rewindErr != nil {
+					return nil, rewindErr
+				}
+				response, err = next.RoundTrip(retry)
+			}
+			return response, err
+		})
+	}
+}
+
+// failureOf returns err, or an error for a response without success status
+func failureOf(response *http.Response, err error) error {
+	if err == nil && (response.StatusCode < 200 || response.StatusCode > 299) {
+		return errors.New(response.Status)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func header(name, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			request.Header.Add(name, value)
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+func TestMiddlewaresWrapRequestsInOrder(t *testing.T) {
+	var order []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = r.Header["X-Middleware"]
+		assert.NotEmpty(t, r.Header.Get(authorizationHeader))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key",
+		WithMiddleware(header("X-Middleware", "first"), header("X-Middleware", "second")),
+		WithMiddleware(header("X-Middleware", "third")))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 42))
+	assert.Equal(t, []string{"first", "second", "third"}, order)
+}
+
+func TestRetryMiddlewareRetriesRetryableStatus(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	client := NewClient(ts.URL, "username", "api-key", WithMiddleware(RetryMiddleware(policy)))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 42))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryMiddlewareGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := RetryPolicy{MaxAttempts: 2, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	client := NewClient(ts.URL, "username", "api-key", WithMiddleware(RetryMiddleware(policy)))
+
+	var apiErr *APIError
+	require.True(t, errors.As(client.DeleteBackend(context.Background(), 42), &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestLoggingMiddlewareLogsRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(log.DebugLevel)
+	client := NewClient(ts.URL, "username", "api-key", WithMiddleware(LoggingMiddleware(logger)))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 42))
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	assert.Equal(t, "VaaS request sent", entry.Message)
+	assert.Equal(t, http.MethodDelete, entry.Data["method"])
+	assert.Equal(t, http.StatusNoContent, entry.Data["status"])
+}
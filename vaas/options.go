@@ -0,0 +1,150 @@
+package vaas
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Connection pool defaults of a client transport.
+const (
+	DefaultMaxIdleConnsPerHost = 8
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Timeout defaults of a client, so that an unresponsive VaaS does not block it forever.
+const (
+	DefaultTimeout             = 30 * time.Second
+	DefaultDialTimeout         = 30 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	keepAlive                  = 30 * time.Second
+)
+
+// unixSocketHost is the placeholder host of VaaS reached through a Unix domain socket
+const unixSocketHost = "http://localhost"
+
+// Option configures optional behaviour of a VaaS client created with NewClient.
+type Option func(*defaultClient)
+
+// WithAccept sets the media type requested in the Accept header of every request.
+// VaaS can return richer representations (e.g. application/vnd.vaas+json) when asked for them.
+// The negotiated type must still be JSON, otherwise the response is rejected. Defaults to application/json.
+func WithAccept(mediaType string) Option {
+	return func(c *defaultClient) {
+		c.accept = mediaType
+	}
+}
+
+// WithUnixSocket makes the client dial VaaS through a Unix domain socket at given path, e.g. the one of an API
+// gateway sidecar. The host part of the VaaS URL is then only a placeholder, while its paths and credentials
+// still apply. A VaaS URL like unix:///run/gateway.sock dials the socket at its path the same way.
+func WithUnixSocket(path string) Option {
+	return func(c *defaultClient) {
+		transport := c.ownTransport()
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return c.dialer.DialContext(ctx, "unix", path)
+		}
+	}
+}
+
+// WithMaxIdleConnsPerHost sets how many idle keep-alive connections to VaaS the client keeps open.
+// Defaults to DefaultMaxIdleConnsPerHost.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *defaultClient) {
+		c.ownTransport().MaxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle keep-alive connection to VaaS stays open.
+// Defaults to DefaultIdleConnTimeout.
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.ownTransport().IdleConnTimeout = timeout
+	}
+}
+
+// WithTimeout limits the time of a single request to VaaS, including reading its response.
+// Retries and task polling make separate requests, each with its own limit. Zero means no limit.
+// Defaults to DefaultTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithOperationTimeout limits a whole call of the client: a request together with its retries, and for AndWait
+// methods also the polling of the VaaS task. Zero means no limit, the default.
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.operationTimeout = timeout
+	}
+}
+
+// WithDialTimeout limits the time of opening a connection to VaaS. Zero means no limit.
+// Defaults to DefaultDialTimeout.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.ownTransport()
+		c.dialer.Timeout = timeout
+	}
+}
+
+// WithTLSHandshakeTimeout limits the time of the TLS handshake with VaaS. Zero means no limit.
+// Defaults to DefaultTLSHandshakeTimeout.
+func WithTLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.ownTransport().TLSHandshakeTimeout = timeout
+	}
+}
+
+// unixSocketURL returns the placeholder URL of VaaS reached through the Unix domain socket of a VaaS URL like
+// unix:///run/gateway.sock together with the socket path, or hostname unchanged if it is not such a URL
+func unixSocketURL(hostname string) (string, string) {
+	const scheme = "unix://"
+	if !strings.HasPrefix(hostname, scheme) || len(hostname) == len(scheme) {
+		return hostname, ""
+	}
+	return unixSocketHost, strings.TrimPrefix(hostname, scheme)
+}
+
+// ownTransport returns the transport dedicated to the client, creating it from http.DefaultTransport if needed.
+func (c *defaultClient) ownTransport() *http.Transport {
+	if c.transport == nil {
+		c.dialer = &net.Dialer{Timeout: DefaultDialTimeout, KeepAlive: keepAlive}
+		c.transport = http.DefaultTransport.(*http.Transport).Clone()
+		c.transport.DialContext = c.dialer.DialContext
+		c.transport.Proxy = c.proxyFor
+		c.transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+		c.transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+		c.transport.IdleConnTimeout = DefaultIdleConnTimeout
+		timeout := DefaultTimeout
+		if c.httpClient != nil {
+			timeout = c.httpClient.Timeout
+		}
+		c.httpClient = &http.Client{Transport: c.transport, Timeout: timeout}
+	}
+	return c.transport
+}
+
+// withOperationTimeout returns ctx limited to the operation timeout of the client, if any
+func (c *defaultClient) withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.operationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.operationTimeout)
+}
+
+// cancelOnClose cancels the context of a request once its response is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body cancelOnClose) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
@@ -0,0 +1,145 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientDialsThroughUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaas-socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "vaas.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiDcPath, r.URL.Path)
+		assert.Equal(t, "ApiKey username:api-key", r.Header.Get(authorizationHeader))
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := NewClient("http://vaas.invalid", "username", "api-key", WithUnixSocket(socket))
+
+	dc, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "dc1", dc.Symbol)
+}
+
+func TestClientDialsUnixSocketOfVaaSURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaas-socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "gateway.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiDcPath, r.URL.Path)
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := NewClient("unix://"+socket, "username", "api-key", WithDCCacheTTL(0))
+
+	dc, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "dc1", dc.Symbol)
+}
+
+func TestUnixSocketURL(t *testing.T) {
+	host, socket := unixSocketURL("unix:///run/gateway.sock")
+	assert.Equal(t, unixSocketHost, host)
+	assert.Equal(t, "/run/gateway.sock", socket)
+
+	host, socket = unixSocketURL("http://vaas.example.com")
+	assert.Equal(t, "http://vaas.example.com", host)
+	assert.Empty(t, socket)
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	var connections int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	for i := 0; i < 3; i++ {
+		_, err := client.GetDC(context.Background(), "dc1")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestClientTimesOutSlowRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTimeout(20*time.Millisecond))
+
+	err := client.ValidateCredentials(context.Background())
+
+	require.Error(t, err)
+}
+
+func TestOperationTimeoutLimitsRetries(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 40 * time.Millisecond, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	client := NewClient(ts.URL, "username", "api-key", WithRetryPolicy(policy), WithOperationTimeout(60*time.Millisecond))
+
+	start := time.Now()
+	err := client.DeleteBackend(context.Background(), 1)
+
+	require.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Less(t, atomic.LoadInt32(&attempts), int32(10))
+}
+
+func TestClientHasDefaultTimeouts(t *testing.T) {
+	client := NewClient("http://localhost", "username", "api-key", WithDialTimeout(time.Second)).(*defaultClient)
+
+	assert.Equal(t, DefaultTimeout, client.httpClient.Timeout)
+	assert.Equal(t, DefaultTLSHandshakeTimeout, client.transport.TLSHandshakeTimeout)
+	assert.Equal(t, time.Second, client.dialer.Timeout)
+}
@@ -0,0 +1,174 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxPages limits how many pages of a single list endpoint the client fetches.
+const DefaultMaxPages = 100
+
+// listPage is implemented by list responses of VaaS API.
+type listPage interface {
+	nextPage() *string
+}
+
+func (l *DCList) nextPage() *string       { return l.Meta.Next }
+func (l *DirectorList) nextPage() *string { return l.Meta.Next }
+
+// WithMaxPages sets how many pages the client fetches from a list endpoint before giving up.
+// It guards against loading enormous result sets into memory. Defaults to DefaultMaxPages.
+func WithMaxPages(maxPages int) Option {
+	return func(c *defaultClient) {
+		c.maxPages = maxPages
+	}
+}
+
+// WithPaging sets the number of objects requested per page of a list endpoint and the offset of the first
+// page. By default both are left to VaaS, which starts at the first object and uses its own page size.
+func WithPaging(limit, offset int) Option {
+	return func(c *defaultClient) {
+		c.pageLimit = limit
+		c.pageOffset = offset
+	}
+}
+
+// listAll fetches list endpoint of resource path with given query, following Meta.Next links until the last page.
+// newPage is called for every page and returns a value to decode the page into along with a function
+// that collects its objects once decoded.
+func (c *defaultClient) listAll(ctx context.Context, path string, query url.Values, newPage func() (listPage, func())) error {
+	target, err := c.endpoint(ctx, path)
+	if err != nil {
+		return err
+	}
+	conditional := c.validators != nil && (path == directorPath || path == dcPath)
+	path = strings.TrimPrefix(target, c.host)
+	for pages := 0; ; pages++ {
+		if pages >= c.maxPages {
+			return fmt.Errorf("listing %s exceeded the limit of %d pages", path, c.maxPages)
+		}
+
+		request, err := c.newRequest(ctx, "GET", target, nil)
+		if err != nil {
+			return err
+		}
+		if pages == 0 {
+			request.URL.RawQuery = c.firstPageQuery(request.URL.Query(), query).Encode()
+		}
+
+		page, collect := newPage()
+		if conditional {
+			err = c.doConditional(request, page)
+		} else {
+			_, err = c.doRequest(request, page)
+		}
+		if err != nil {
+			return err
+		}
+		collect()
+
+		next := page.nextPage()
+		if next == nil || *next == "" {
+			return nil
+		}
+		if target, err = c.resolve(*next); err != nil {
+			return fmt.Errorf("invalid next page link %q: %s", *next, err)
+		}
+		log.WithContext(ctx).Debugf("Fetching next page of %s: %s", path, *next)
+	}
+}
+
+func (c *defaultClient) firstPageQuery(requestQuery, query url.Values) url.Values {
+	for key, values := range query {
+		requestQuery[key] = values
+	}
+	if c.pageLimit > 0 {
+		requestQuery.Set("limit", strconv.Itoa(c.pageLimit))
+	}
+	if c.pageOffset > 0 {
+		requestQuery.Set("offset", strconv.Itoa(c.pageOffset))
+	}
+	return requestQuery
+}
+
+// resolve turns a link returned by VaaS, usually relative to the API host, into an absolute URL.
+func (c *defaultClient) resolve(link string) (string, error) {
+	base, err := url.Parse(c.host)
+	if err != nil {
+		return "", err
+	}
+	reference, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(reference).String(), nil
+}
+
+// ListAllBackends returns every backend registered in VaaS, regardless of director.
+func (c *defaultClient) ListAllBackends(ctx context.Context) ([]Backend, error) {
+	backends, err := c.listBackends(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("backend list fetch failed: %w", err)
+	}
+	return backends, nil
+}
+
+// ListDirectors returns every director defined in VaaS.
+func (c *defaultClient) ListDirectors(ctx context.Context) ([]Director, error) {
+	directors, err := c.listDirectors(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("director list fetch failed: %w", err)
+	}
+	return directors, nil
+}
+
+// ListBackends returns every backend registered in director.
+func (c *defaultClient) ListBackends(ctx context.Context, director *Director) ([]Backend, error) {
+	query := url.Values{}
+	query.Set("director", fmt.Sprintf("%d", director.ID))
+
+	backends, err := c.listBackends(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("backend list fetch failed: %w", err)
+	}
+	return backends, nil
+}
+
+// listBackends decodes backend lists, which can be much longer than others, one backend at a time instead of
+// reading whole pages first. It still returns all of them, IterateBackends does not hold them in memory.
+func (c *defaultClient) listBackends(ctx context.Context, query url.Values) ([]Backend, error) {
+	var backends []Backend
+	list := c.iterate(ctx, backendPath, query)
+	defer list.close()
+	for list.next() {
+		var backend Backend
+		if err := list.decode(&backend); err != nil {
+			return backends, err
+		}
+		backends = append(backends, backend)
+	}
+	return backends, list.err
+}
+
+func (c *defaultClient) listDirectors(ctx context.Context, query url.Values) ([]Director, error) {
+	var directors []Director
+	err := c.listAll(ctx, directorPath, query, func() (listPage, func()) {
+		page := &DirectorList{}
+		return page, func() { directors = append(directors, page.Objects...) }
+	})
+	return directors, err
+}
+
+func (c *defaultClient) listDCs(ctx context.Context, query url.Values) ([]DC, error) {
+	var dcs []DC
+	err := c.listAll(ctx, dcPath, query, func() (listPage, func()) {
+		page := &DCList{}
+		return page, func() { dcs = append(dcs, page.Objects...) }
+	})
+	return dcs, err
+}
@@ -0,0 +1,116 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pagedBackendsHandler(t *testing.T, pages int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiBackendPath, r.URL.Path)
+		assert.Equal(t, "ApiKey username:api-key", r.Header.Get(authorizationHeader))
+		assert.Empty(t, r.URL.Query().Get("api_key"))
+
+		offset := 0
+		if r.URL.Query().Get("offset") == "1" {
+			offset = 1
+		}
+		backend := createBackendWithUri("uri")
+		backend.ID = NewID(offset + 1)
+		backend.DirectorURL = fmt.Sprintf("%s%d/", apiDirectorPath, offset+1)
+		list := BackendList{Objects: []Backend{*backend}, Meta: Meta{TotalCount: pages}}
+		if offset+1 < pages {
+			next := apiBackendPath + "?limit=1&offset=1"
+			list.Meta.Next = &next
+		}
+		data, _ := json.Marshal(list)
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}
+}
+
+func TestListAllBackendsFollowsNextPages(t *testing.T) {
+	ts := httptest.NewServer(pagedBackendsHandler(t, 2))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	backends, err := client.ListAllBackends(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, backends, 2)
+	assert.Equal(t, ID(1), *backends[0].ID)
+	assert.Equal(t, ID(2), *backends[1].ID)
+	assert.Equal(t, "/api/v0.1/director/2/", backends[1].DirectorURL)
+}
+
+func TestListAllBackendsFailsAfterPageLimit(t *testing.T) {
+	ts := httptest.NewServer(pagedBackendsHandler(t, 2))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithMaxPages(1))
+
+	_, err := client.ListAllBackends(context.Background())
+
+	assert.EqualError(t, err, "backend list fetch failed: listing /api/v0.1/backend/ exceeded the limit of 1 pages")
+}
+
+func TestFindDirectorAndDCLookThroughAllPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page interface{}
+		secondPage := r.URL.Query().Get("offset") == "1"
+		next := r.URL.Path + "?offset=1"
+		switch {
+		case r.URL.Path == apiDirectorPath && !secondPage:
+			page = DirectorList{Meta: Meta{Next: &next}, Objects: []Director{{ID: 1, Name: "other"}}}
+		case r.URL.Path == apiDirectorPath:
+			page = DirectorList{Objects: []Director{{ID: 2, Name: "director"}}}
+		case r.URL.Path == apiDcPath && !secondPage:
+			page = DCList{Meta: Meta{Next: &next}, Objects: []DC{{ID: 1, Symbol: "dc1"}}}
+		default:
+			page = DCList{Objects: []DC{{ID: 2, Symbol: "dc2"}}}
+		}
+		data, _ := json.Marshal(page)
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	director, err := client.FindDirector(context.Background(), "director")
+	require.NoError(t, err)
+	assert.Equal(t, ID(2), director.ID)
+
+	directors, err := client.ListDirectors(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, directors, 2)
+
+	dc, err := client.GetDC(context.Background(), "dc2")
+	require.NoError(t, err)
+	assert.Equal(t, ID(2), dc.ID)
+}
+
+func TestPagingOptionSetsLimitAndOffsetOfFirstPage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		assert.Equal(t, "20", r.URL.Query().Get("offset"))
+		_, err := w.Write([]byte(`{"objects": []}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithPaging(10, 20))
+
+	backends, err := client.ListAllBackends(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, backends)
+}
@@ -0,0 +1,76 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+const probePath = "/probe/"
+
+// Probe represents JSON structure of a health check probe in VaaS API. Varnish requests URL of every backend of
+// directors using the probe each Interval seconds, and considers a backend healthy when at least Threshold of the
+// last Window requests got ExpectedResponse within Timeout. Fields left empty take VaaS defaults.
+type Probe struct {
+	ID               ID      `json:"id,omitempty"`
+	Name             string  `json:"name,omitempty"`
+	URL              string  `json:"url,omitempty"`
+	ExpectedResponse int     `json:"expected_response,omitempty"`
+	Interval         int     `json:"interval,omitempty"`
+	Timeout          Seconds `json:"timeout,omitempty"`
+	Window           int     `json:"window,omitempty"`
+	Threshold        int     `json:"threshold,omitempty"`
+	StartAsHealthy   bool    `json:"start_as_healthy,omitempty"`
+	ResourceURI      string  `json:"resource_uri,omitempty"`
+}
+
+// ProbeList represents JSON structure of Probe list used in responses in VaaS API.
+type ProbeList struct {
+	Meta    Meta    `json:"meta,omitempty"`
+	Objects []Probe `json:"objects,omitempty"`
+}
+
+func (l *ProbeList) nextPage() *string { return l.Meta.Next }
+
+// ListProbes returns every probe defined in VaaS.
+func (c *defaultClient) ListProbes(ctx context.Context) ([]Probe, error) {
+	probes, err := c.listProbes(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("probe list fetch failed: %w", err)
+	}
+	return probes, nil
+}
+
+// GetProbe finds probe by name.
+func (c *defaultClient) GetProbe(ctx context.Context, name string) (*Probe, error) {
+	query := url.Values{}
+	query.Set("name", name)
+
+	probes, err := c.listProbes(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("probe list fetch failed: %w", err)
+	}
+	for _, probe := range probes {
+		if probe.Name == name {
+			return &probe, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no probe with name %s", ErrProbeNotFound, name)
+}
+
+// CreateProbe creates probe in VaaS, filling its ID and resource URI from the response.
+func (c *defaultClient) CreateProbe(ctx context.Context, probe *Probe) error {
+	if err := c.createObject(ctx, probePath, probe, &probe.ResourceURI); err != nil {
+		return fmt.Errorf("cannot create probe %s: %w", probe.Name, err)
+	}
+	return nil
+}
+
+func (c *defaultClient) listProbes(ctx context.Context, query url.Values) ([]Probe, error) {
+	var probes []Probe
+	err := c.listAll(ctx, probePath, query, func() (listPage, func()) {
+		page := &ProbeList{}
+		return page, func() { probes = append(probes, page.Objects...) }
+	})
+	return probes, err
+}
@@ -0,0 +1,57 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProbeFindsProbeByName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v0.1/probe/", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"objects": [{"id": 1, "name": "ping", "url": "/status/ping", "expected_response": 200,
+			"interval": 3, "timeout": "1.5", "window": 5, "threshold": 3, "resource_uri": "/api/v0.1/probe/1/"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	probe, err := client.GetProbe(context.Background(), "ping")
+	require.NoError(t, err)
+	_, err = client.GetProbe(context.Background(), "missing")
+
+	assert.Equal(t, &Probe{ID: 1, Name: "ping", URL: "/status/ping", ExpectedResponse: 200, Interval: 3, Timeout: 1.5,
+		Window: 5, Threshold: 3, ResourceURI: "/api/v0.1/probe/1/"}, probe)
+	assert.True(t, errors.Is(err, ErrProbeNotFound), err)
+}
+
+func TestCreateProbe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v0.1/probe/", r.URL.Path)
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"name": "ping", "url": "/status/ping", "timeout": "2"}, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v0.1/probe/2/")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"id": 2, "name": "ping", "url": "/status/ping", "expected_response": 200}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	probe := &Probe{Name: "ping", URL: "/status/ping", Timeout: 2}
+
+	err := NewClient(ts.URL, "username", "api-key").CreateProbe(context.Background(), probe)
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(2), probe.ID)
+	assert.Equal(t, 200, probe.ExpectedResponse)
+	assert.Equal(t, "/api/v0.1/probe/2/", probe.ResourceURI)
+}
@@ -0,0 +1,52 @@
+package vaas
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// WithProxy makes the client reach VaaS through the HTTP(S) or SOCKS5 proxy at proxyURL, e.g.
+// http://proxy.example.com:3128 or socks5://bastion.example.com:1080, instead of the one given by HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY, which the client respects by default. Credentials in proxyURL authenticate the
+// client to the proxy. If proxyURL is invalid, every request fails.
+func WithProxy(proxyURL string) Option {
+	return func(c *defaultClient) {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || proxy.Host == "" || !proxySchemes[proxy.Scheme] {
+			c.optionError(errors.New("invalid proxy URL, expected one like http://proxy.example.com:3128 or socks5://proxy.example.com:1080"))
+			return
+		}
+		c.ownTransport()
+		c.proxyURL = proxy
+	}
+}
+
+// proxySchemes are the schemes of proxies the transport of the client can talk to
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true}
+
+// WithProxyAuth authenticates the client to its proxy, given by WithProxy or the environment, with basic
+// credentials, overriding any in the proxy URL.
+func WithProxyAuth(username, password string) Option {
+	return func(c *defaultClient) {
+		c.ownTransport()
+		c.proxyAuth = url.UserPassword(username, password)
+	}
+}
+
+// proxyFor returns the proxy request is sent through, nil to connect directly
+func (c *defaultClient) proxyFor(request *http.Request) (*url.URL, error) {
+	proxy := c.proxyURL
+	if proxy == nil {
+		var err error
+		if proxy, err = http.ProxyFromEnvironment(request); err != nil || proxy == nil {
+			return proxy, err
+		}
+	}
+	if c.proxyAuth != nil {
+		authenticated := *proxy
+		authenticated.User = c.proxyAuth
+		proxy = &authenticated
+	}
+	return proxy, nil
+}
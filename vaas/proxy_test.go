@@ -0,0 +1,167 @@
+package vaas
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendsRequestsThroughProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host+" "+r.Header.Get("Proxy-Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	client := NewClient("http://vaas.example.com", "username", "api-key",
+		WithProxy(proxy.URL), WithProxyAuth("user", "secret"))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+	credentials := base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	assert.Equal(t, []string{"vaas.example.com Basic " + credentials}, proxied)
+}
+
+func TestClientFailsWithInvalidProxyURL(t *testing.T) {
+	client := NewClient("http://vaas.example.com", "username", "api-key", WithProxy("proxy.example.com"))
+
+	err := client.DeleteBackend(context.Background(), 1)
+
+	assert.EqualError(t, err, "invalid proxy URL, expected one like http://proxy.example.com:3128 or socks5://proxy.example.com:1080")
+}
+
+func TestClientFailsWithUnsupportedProxyScheme(t *testing.T) {
+	client := NewClient("http://vaas.example.com", "username", "api-key", WithProxy("ftp://proxy.example.com:21"))
+
+	err := client.DeleteBackend(context.Background(), 1)
+
+	assert.EqualError(t, err, "invalid proxy URL, expected one like http://proxy.example.com:3128 or socks5://proxy.example.com:1080")
+}
+
+func TestClientSendsRequestsThroughSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	credentials := make(chan string, 1)
+	go serveSOCKS5(listener, credentials)
+
+	client := NewClient(ts.URL, "username", "api-key",
+		WithProxy("socks5://"+listener.Addr().String()), WithProxyAuth("user", "secret"))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, "user:secret", <-credentials)
+}
+
+// serveSOCKS5 relays connections of SOCKS5 clients authenticating with username and password, reporting the
+// credentials they used
+func serveSOCKS5(listener net.Listener, credentials chan<- string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			target, user, err := socks5Handshake(conn)
+			if err != nil {
+				return
+			}
+			select {
+			case credentials <- user:
+			default:
+			}
+			upstream, err := net.Dial("tcp", target)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			go io.Copy(upstream, conn)
+			_, _ = io.Copy(conn, upstream)
+		}()
+	}
+}
+
+func socks5Handshake(conn net.Conn) (target, user string, _ error) {
+	read := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(conn, buf)
+		return buf, err
+	}
+	greeting, err := read(2)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = read(int(greeting[1])); err != nil {
+		return "", "", err
+	}
+	if _, err = conn.Write([]byte{5, 2}); err != nil {
+		return "", "", err
+	}
+
+	header, err := read(2)
+	if err != nil {
+		return "", "", err
+	}
+	username, err := read(int(header[1]))
+	if err != nil {
+		return "", "", err
+	}
+	length, err := read(1)
+	if err != nil {
+		return "", "", err
+	}
+	password, err := read(int(length[0]))
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = conn.Write([]byte{1, 0}); err != nil {
+		return "", "", err
+	}
+
+	request, err := read(4)
+	if err != nil {
+		return "", "", err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip, err := read(net.IPv4len)
+		if err != nil {
+			return "", "", err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length, err := read(1)
+		if err != nil {
+			return "", "", err
+		}
+		name, err := read(int(length[0]))
+		if err != nil {
+			return "", "", err
+		}
+		host = string(name)
+	default:
+		return "", "", io.ErrUnexpectedEOF
+	}
+	port, err := read(2)
+	if err != nil {
+		return "", "", err
+	}
+	target = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	return target, string(username) + ":" + string(password), nil
+}
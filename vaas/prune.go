@@ -0,0 +1,100 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PruneOptions configures Prune.
+type PruneOptions struct {
+	ReconcileOptions
+	// GracePeriod is how long a backend has to be orphaned before it is deleted.
+	GracePeriod time.Duration
+	// FirstSeen maps backends, by address:port, to the time they were first found orphaned. Prune adds
+	// backends found orphaned now and removes ones which are not, so it can be kept between calls.
+	FirstSeen map[string]time.Time
+	// Now is the time the grace period is measured at, the current time when zero.
+	Now time.Time
+}
+
+// Prune deletes backends of director which are not among live ones, matching them by address and port,
+// once they were orphaned for longer than the grace period. VaaS does not tell when a backend was created,
+// so the grace period starts when Prune first finds a backend orphaned, as recorded in FirstSeen.
+// Changes that fail after all retries are reported in ReconcileReport and summarized in the returned error.
+func Prune(ctx context.Context, client Client, director string, live []Backend, opts PruneOptions) (ReconcileReport, error) {
+	report := ReconcileReport{DryRun: opts.DryRun}
+
+	dir, err := client.FindDirector(ctx, director)
+	if err != nil {
+		return report, fmt.Errorf("cannot prune director %s: %w", director, err)
+	}
+	actual, err := client.ListBackends(ctx, dir)
+	if err != nil {
+		return report, fmt.Errorf("cannot prune director %s: %w", director, err)
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	report.Changes = orphansOf(actual, live, opts.FirstSeen, now, opts.GracePeriod)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	applyChanges(ctx, client, dir, report.Changes, opts.ReconcileOptions)
+
+	failed := report.Failed()
+	for _, change := range report.Changes {
+		if change.Err == nil && opts.FirstSeen != nil {
+			delete(opts.FirstSeen, backendKey(change.Backend))
+		}
+	}
+	if len(failed) > 0 {
+		return report, fmt.Errorf("%d of %d orphans in director %s could not be deleted, first: %s: %s",
+			len(failed), len(report.Changes), director, HostPort(failed[0].Backend.Address, failed[0].Backend.Port), failed[0].Err)
+	}
+	return report, nil
+}
+
+// orphansOf returns deletions of actual backends which are not live and were first seen orphaned before
+// grace ended, updating firstSeen unless it is nil
+func orphansOf(actual, live []Backend, firstSeen map[string]time.Time, now time.Time, grace time.Duration) []ReconcileChange {
+	alive := make(map[string]bool, len(live))
+	for _, backend := range live {
+		alive[backendKey(backend)] = true
+	}
+
+	orphaned := make(map[string]bool, len(actual))
+	var changes []ReconcileChange
+	for _, backend := range actual {
+		key := backendKey(backend)
+		if alive[key] {
+			continue
+		}
+		orphaned[key] = true
+
+		seen, ok := firstSeen[key]
+		if !ok {
+			seen = now
+			if firstSeen != nil {
+				firstSeen[key] = now
+			}
+		}
+		if now.Sub(seen) < grace {
+			log.WithField("backend", key).Infof("Orphaned backend is kept for %s more", grace-now.Sub(seen))
+			continue
+		}
+		changes = append(changes, ReconcileChange{Action: ActionDelete, Backend: backend})
+	}
+
+	for key := range firstSeen {
+		if !orphaned[key] {
+			delete(firstSeen, key)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,48 @@
+package vaas
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneDeletesOrphansAfterGracePeriod(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{
+		reconcileBackend(1, "10.0.0.1", 1),
+		reconcileBackend(2, "10.0.0.2", 1),
+		reconcileBackend(3, "10.0.0.3", 1),
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	now := time.Now()
+	live := []Backend{{Address: "10.0.0.1", Port: 80}}
+	firstSeen := map[string]time.Time{"10.0.0.2:80": now.Add(-time.Hour), "10.0.0.9:80": now.Add(-time.Hour)}
+	client := NewClient(ts.URL, "username", "api-key")
+
+	report, err := Prune(context.Background(), client, "director", live,
+		PruneOptions{GracePeriod: 10 * time.Minute, FirstSeen: firstSeen, Now: now})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DELETE /api/v0.1/backend/2/"}, server.requests)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, map[string]time.Time{"10.0.0.3:80": now}, firstSeen)
+}
+
+func TestPruneDryRunKeepsOrphans(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{reconcileBackend(1, "10.0.0.1", 1)}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	report, err := Prune(context.Background(), client, "director", nil, PruneOptions{ReconcileOptions: ReconcileOptions{DryRun: true}})
+
+	require.NoError(t, err)
+	assert.Empty(t, server.requests)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, ActionDelete, report.Changes[0].Action)
+}
@@ -0,0 +1,94 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the rate of requests to VaaS.
+// It can be shared by several clients with WithRateLimiter, so that they respect a common limit.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rps requests per second on average
+// and up to burst requests at once. The bucket starts full.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// WithRateLimit limits requests of the client to rps per second on average, with bursts of up to burst requests.
+// Every attempt of a request, including retries and task polling, takes a token. Zero rps means no limit.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *defaultClient) {
+		c.limiter = nil
+		if rps > 0 {
+			c.limiter = NewRateLimiter(rps, burst)
+		}
+	}
+}
+
+// WithRateLimiter makes the client take tokens from limiter, possibly shared with other clients.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *defaultClient) {
+		c.limiter = limiter
+	}
+}
+
+// Wait blocks until a request may be sent or the context expires.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release()
+		return fmt.Errorf("waiting for VaaS rate limit: %w", ctx.Err())
+	}
+}
+
+// reserve takes a token, possibly one not yet available, and returns how long to wait until it is.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// release gives back a token reserved by a request that was not sent.
+func (l *RateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
@@ -0,0 +1,56 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterAllowsBurstThenRefills(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, limiter.reserve())
+	}
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve())
+
+	now = now.Add(time.Second)
+	assert.Zero(t, limiter.reserve())
+}
+
+func TestRateLimiterWaitIsCancelledWithContext(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(ctx)
+
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientSharesRateLimiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(0.001, 1)
+	first := NewClient(ts.URL, "username", "api-key", WithRateLimiter(limiter))
+	second := NewClient(ts.URL, "username", "api-key", WithRateLimiter(limiter))
+	require.NoError(t, first.DeleteBackend(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := second.DeleteBackend(ctx, 1)
+
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,244 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Reconcile actions.
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ReconcileOptions configures Reconcile.
+type ReconcileOptions struct {
+	// DryRun only computes the changes without applying them.
+	DryRun bool
+	// Concurrency limits how many changes are applied at once. Defaults to 1.
+	Concurrency int
+	// Retries is how many times a failed change is retried.
+	Retries int
+	// RetryDelay is the delay before the first retry, doubled on every following one. Defaults to 1s.
+	RetryDelay time.Duration
+}
+
+// ReconcileChange describes a single change made (or planned) by Reconcile.
+type ReconcileChange struct {
+	Action  string
+	Backend Backend
+	Err     error
+}
+
+// ReconcileReport lists changes made by Reconcile.
+type ReconcileReport struct {
+	DryRun  bool
+	Changes []ReconcileChange
+}
+
+// Failed returns changes that could not be applied.
+func (r ReconcileReport) Failed() []ReconcileChange {
+	var failed []ReconcileChange
+	for _, change := range r.Changes {
+		if change.Err != nil {
+			failed = append(failed, change)
+		}
+	}
+	return failed
+}
+
+// Reconcile makes backends registered in director match desired ones, matching them by address and port.
+// Missing backends are added, backends with different weight or tags are updated and the remaining ones
+// are deleted. Desired backends need a DC, and leaving Weight or Tags unset means any value is accepted and the
+// registered one is kept.
+// Changes that fail after all retries are reported in ReconcileReport and summarized in the returned error.
+func Reconcile(ctx context.Context, client Client, director string, desired []Backend, opts ReconcileOptions) (ReconcileReport, error) {
+	report := ReconcileReport{DryRun: opts.DryRun}
+
+	dir, err := client.FindDirector(ctx, director)
+	if err != nil {
+		return report, fmt.Errorf("cannot reconcile director %s: %w", director, err)
+	}
+	actual, err := client.ListBackends(ctx, dir)
+	if err != nil {
+		return report, fmt.Errorf("cannot reconcile director %s: %w", director, err)
+	}
+
+	report.Changes = mergeUpdates(diffBackends(dir, actual, desired, needsUpdate), actual)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	applyChanges(ctx, client, dir, report.Changes, opts)
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return report, fmt.Errorf("%d of %d changes in director %s failed, first: %s %s: %s",
+			len(failed), len(report.Changes), director, failed[0].Action,
+			HostPort(failed[0].Backend.Address, failed[0].Backend.Port), failed[0].Err)
+	}
+	return report, nil
+}
+
+func backendKey(backend Backend) string {
+	return HostPort(backend.Address, backend.Port)
+}
+
+// diffBackends returns changes making actual backends of director desired ones, updating those changed according
+// to changed
+func diffBackends(director *Director, actual, desired []Backend, changed func(current, desired Backend) bool) []ReconcileChange {
+	existing := make(map[string]Backend, len(actual))
+	for _, backend := range actual {
+		key := backendKey(backend)
+		if previous, ok := existing[key]; ok && lessByID(previous.ID, backend.ID) {
+			continue
+		}
+		existing[key] = backend
+	}
+
+	var changes []ReconcileChange
+	wanted := make(map[string]bool, len(desired))
+	for _, backend := range desired {
+		key := backendKey(backend)
+		wanted[key] = true
+		current, ok := existing[key]
+		backend.DirectorURL = director.ResourceURI
+		switch {
+		case !ok:
+			backend.ID = nil
+			changes = append(changes, ReconcileChange{Action: ActionAdd, Backend: backend})
+		case changed(current, backend):
+			backend.ID = current.ID
+			backend.ResourceURI = current.ResourceURI
+			changes = append(changes, ReconcileChange{Action: ActionUpdate, Backend: backend})
+		}
+	}
+
+	for _, backend := range actual {
+		key := backendKey(backend)
+		if !wanted[key] || !sameID(existing[key].ID, backend.ID) {
+			changes = append(changes, ReconcileChange{Action: ActionDelete, Backend: backend})
+		}
+	}
+	return changes
+}
+
+// mergeUpdates replaces backends updated by changes with the registered ones carrying the weight and tags set in
+// the desired ones, so that updating them keeps the fields left unset
+func mergeUpdates(changes []ReconcileChange, actual []Backend) []ReconcileChange {
+	for i, change := range changes {
+		if change.Action != ActionUpdate {
+			continue
+		}
+		for _, current := range actual {
+			if !sameID(current.ID, change.Backend.ID) {
+				continue
+			}
+			if change.Backend.Weight != nil {
+				current.Weight = change.Backend.Weight
+			}
+			if change.Backend.Tags != nil {
+				current.Tags = change.Backend.Tags
+			}
+			changes[i].Backend = current
+			break
+		}
+	}
+	return changes
+}
+
+func sameID(a, b *ID) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+func needsUpdate(current, desired Backend) bool {
+	if desired.Weight != nil && current.GetWeight() != *desired.Weight {
+		return true
+	}
+	return desired.Tags != nil && !sameTags(current.Tags, desired.Tags)
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return strings.Join(sortedA, "\x00") == strings.Join(sortedB, "\x00")
+}
+
+func applyChanges(ctx context.Context, client Client, director *Director, changes []ReconcileChange, opts ReconcileOptions) {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range changes {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			changes[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(change *ReconcileChange) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			change.Err = withRetries(ctx, opts, func() error {
+				return applyChange(ctx, client, director, change)
+			})
+		}(&changes[i])
+	}
+	wg.Wait()
+}
+
+func applyChange(ctx context.Context, client Client, director *Director, change *ReconcileChange) error {
+	backend := change.Backend
+	logger := log.WithField("action", change.Action).WithField("backend", backendKey(backend))
+	logger.Info("Reconciling backend")
+
+	switch change.Action {
+	case ActionAdd:
+		_, err := client.AddBackend(ctx, &backend, director)
+		return err
+	case ActionUpdate:
+		return client.UpsertBackend(ctx, &backend)
+	case ActionDelete:
+		if backend.ID == nil {
+			return fmt.Errorf("backend %s has no ID", backendKey(backend))
+		}
+		return client.DeleteBackend(ctx, int(*backend.ID))
+	}
+	return fmt.Errorf("unknown reconcile action %q", change.Action)
+}
+
+func withRetries(ctx context.Context, opts ReconcileOptions, operation func() error) error {
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	err := operation()
+	for attempt := 0; err != nil && attempt < opts.Retries; attempt++ {
+		log.WithContext(ctx).Warnf("Retrying in %s after error: %s", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+		err = operation()
+	}
+	return err
+}
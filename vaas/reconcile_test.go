@@ -0,0 +1,126 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reconcileServer struct {
+	sync.Mutex
+	backends []Backend
+	requests []string
+	puts     []Backend
+	failPuts int
+}
+
+func (s *reconcileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case r.URL.Path == apiDirectorPath:
+		data, _ := json.Marshal(DirectorList{Objects: []Director{*createDirector(1)}})
+		_, _ = w.Write(data)
+	case r.Method == http.MethodGet:
+		data, _ := json.Marshal(BackendList{Objects: s.backends})
+		_, _ = w.Write(data)
+	case r.Method == http.MethodPut && s.failPuts > 0:
+		s.failPuts--
+		s.requests = append(s.requests, "failed "+r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case r.Method == http.MethodPost:
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(mockAddBackendResponse)
+	default:
+		if r.Method == http.MethodPut {
+			var backend Backend
+			_ = json.NewDecoder(r.Body).Decode(&backend)
+			s.puts = append(s.puts, backend)
+		}
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func reconcileBackend(id int, address string, weight int) Backend {
+	return Backend{ID: NewID(id), Address: address, Port: 80, Weight: &weight, Tags: []string{"a", "b"}}
+}
+
+func TestReconcileAddsUpdatesAndDeletesBackends(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{
+		reconcileBackend(1, "10.0.0.1", 1),
+		reconcileBackend(2, "10.0.0.2", 1),
+		reconcileBackend(3, "10.0.0.3", 1),
+		reconcileBackend(4, "10.0.0.3", 1),
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	weight := 5
+	desired := []Backend{
+		{Address: "10.0.0.1", Port: 80, Tags: []string{"b", "a"}},
+		{Address: "10.0.0.2", Port: 80, Weight: &weight},
+		{Address: "10.0.0.3", Port: 80},
+		{Address: "10.0.0.4", Port: 80},
+	}
+
+	client := NewClient(ts.URL, "username", "api-key")
+	report, err := Reconcile(context.Background(), client, "director", desired, ReconcileOptions{Concurrency: 2})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"PUT /api/v0.1/backend/2/",
+		"POST /api/v0.1/backend/",
+		"DELETE /api/v0.1/backend/4/",
+	}, server.requests)
+	require.Len(t, report.Changes, 3)
+	assert.Empty(t, report.Failed())
+	require.Len(t, server.puts, 1)
+	assert.Equal(t, 5, server.puts[0].GetWeight())
+	assert.Equal(t, []string{"a", "b"}, server.puts[0].Tags, "tags unset in the desired backend are kept")
+}
+
+func TestReconcileDryRunDoesNotApplyChanges(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{reconcileBackend(1, "10.0.0.1", 1)}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	report, err := Reconcile(context.Background(), client, "director", nil, ReconcileOptions{DryRun: true})
+
+	require.NoError(t, err)
+	assert.Empty(t, server.requests)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, ActionDelete, report.Changes[0].Action)
+}
+
+func TestReconcileRetriesAndReportsFailures(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{reconcileBackend(1, "10.0.0.1", 1)}, failPuts: 2}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	weight := 5
+	desired := []Backend{{Address: "10.0.0.1", Port: 80, Weight: &weight}}
+	client := NewClient(ts.URL, "username", "api-key")
+
+	report, err := Reconcile(context.Background(), client, "director", desired, ReconcileOptions{Retries: 1, RetryDelay: 1})
+
+	require.Error(t, err)
+	require.Len(t, report.Failed(), 1)
+	assert.Equal(t, []string{"failed PUT /api/v0.1/backend/1/", "failed PUT /api/v0.1/backend/1/"}, server.requests)
+
+	server.failPuts = 1
+	server.requests = nil
+	_, err = Reconcile(context.Background(), client, "director", desired, ReconcileOptions{Retries: 1, RetryDelay: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"failed PUT /api/v0.1/backend/1/", "PUT /api/v0.1/backend/1/"}, server.requests)
+}
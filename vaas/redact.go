@@ -0,0 +1,45 @@
+package vaas
+
+import (
+	"net/url"
+)
+
+const redacted = "REDACTED"
+
+// secretParams are query parameters carrying credentials, masked wherever a URL is logged or reported.
+var secretParams = []string{"api_key", "access_token", "client_secret", "password", "token"}
+
+// redactURL returns u with credentials in its query and user info masked.
+func redactURL(u *url.URL) string {
+	masked := *u
+	if password, ok := u.User.Password(); ok && password != "" {
+		masked.User = url.UserPassword(u.User.Username(), redacted)
+	}
+
+	query := masked.Query()
+	changed := false
+	for _, param := range secretParams {
+		if query.Get(param) != "" {
+			query.Set(param, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		masked.RawQuery = query.Encode()
+	}
+	return masked.String()
+}
+
+// redactError masks credentials in the URL of a transport error, which would otherwise show in its message.
+func redactError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+
+	parsed, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		return err
+	}
+	return &url.Error{Op: urlErr.Op, URL: redactURL(parsed), Err: urlErr.Err}
+}
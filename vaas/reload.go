@@ -0,0 +1,200 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const reloadStatusPath = "reload_status/"
+
+// Defaults of ReloadOptions.
+const (
+	DefaultReloadInterval = 2 * time.Second
+	DefaultReloadTimeout  = 2 * time.Minute
+)
+
+// Errors returned while waiting for Varnish servers to load the VCL of a director, to be matched with errors.Is.
+var (
+	// ErrReloadFailed is returned when a Varnish server fails to load the VCL.
+	ErrReloadFailed = errors.New("VCL reload failed")
+	// ErrNotReloaded is returned when the VCL is not live on every Varnish server before the timeout.
+	ErrNotReloaded = errors.New("VCL not reloaded")
+	// ErrReloadStatusUnsupported is returned when VaaS reports no reload status, neither in tasks nor at its own endpoint.
+	ErrReloadStatusUnsupported = errors.New("VaaS does not report VCL reload status")
+)
+
+// ServerReload is the status of loading the VCL of a director on one Varnish server: TaskPending, TaskSuccess
+// once the VCL is live, or TaskFailure.
+type ServerReload struct {
+	Server string `json:"server"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReloadStatus represents JSON structure of the status of applying the VCL of a director to Varnish servers in
+// VaaS API, as reported by its reload status endpoint or in the Info of the task applying a change.
+type ReloadStatus struct {
+	Director string         `json:"director,omitempty"`
+	Servers  []ServerReload `json:"servers"`
+}
+
+// Live returns whether the VCL is live on every Varnish server.
+func (s *ReloadStatus) Live() bool {
+	for _, server := range s.Servers {
+		if server.Status != TaskSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns servers that failed to load the VCL.
+func (s *ReloadStatus) Failures() []ServerReload {
+	var failures []ServerReload
+	for _, server := range s.Servers {
+		if server.Status == TaskFailure {
+			failures = append(failures, server)
+		}
+	}
+	return failures
+}
+
+// String lists servers with their status.
+func (s *ReloadStatus) String() string {
+	servers := make([]string, 0, len(s.Servers))
+	for _, server := range s.Servers {
+		servers = append(servers, server.Server+": "+server.Status)
+	}
+	return strings.Join(servers, ", ")
+}
+
+// ParseReloadInfo reads the reload status from the Info of a VaaS task applying a change, either JSON of a
+// ReloadStatus or "server: STATUS" pairs separated by commas, semicolons or new lines. It returns false when info
+// reports no reload status.
+func ParseReloadInfo(info string) (*ReloadStatus, bool) {
+	info = strings.TrimSpace(info)
+	if strings.HasPrefix(info, "{") {
+		var status ReloadStatus
+		if err := json.Unmarshal([]byte(info), &status); err != nil || len(status.Servers) == 0 {
+			return nil, false
+		}
+		return &status, true
+	}
+
+	status := &ReloadStatus{}
+	for _, pair := range strings.FieldsFunc(info, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		server := ServerReload{Server: strings.TrimSpace(parts[0]), Status: strings.ToUpper(strings.TrimSpace(parts[1]))}
+		switch server.Status {
+		case TaskPending, TaskSuccess, TaskFailure:
+		default:
+			return nil, false
+		}
+		status.Servers = append(status.Servers, server)
+	}
+	return status, len(status.Servers) > 0
+}
+
+// GetReloadStatus returns the status of applying the VCL of director to Varnish servers, or an error matching
+// ErrReloadStatusUnsupported when VaaS has no reload status endpoint.
+func (c *defaultClient) GetReloadStatus(ctx context.Context, director *Director) (*ReloadStatus, error) {
+	endpoint, err := c.endpoint(ctx, directorPath)
+	if err != nil {
+		return nil, err
+	}
+	request, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s%d/%s", endpoint, director.ID, reloadStatusPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ReloadStatus
+	if _, err := c.doRequest(request, &status); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: no reload status of director %s", ErrReloadStatusUnsupported, director.Name)
+		}
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ReloadOptions configures WaitForReload.
+type ReloadOptions struct {
+	// Interval is the delay between checks, DefaultReloadInterval when zero.
+	Interval time.Duration
+	// Timeout is how long the VCL has to become live, DefaultReloadTimeout when zero.
+	Timeout time.Duration
+}
+
+// WaitForReload queries VaaS until the VCL of director is live on every Varnish server, returning an error
+// matching ErrReloadFailed when a server fails to load it and ErrNotReloaded after the timeout. The status is
+// read from the Info of the task at taskURI when it reports one, and from the reload status endpoint otherwise.
+func WaitForReload(ctx context.Context, client Client, director *Director, taskURI string, opts ReloadOptions) error {
+	interval, timeout := opts.Interval, opts.Timeout
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultReloadTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for attempt := 1; ; attempt++ {
+		status, err := reloadStatus(ctx, client, director, taskURI)
+		if err != nil {
+			return err
+		}
+		if failures := status.Failures(); len(failures) > 0 {
+			return reloadFailure(director, failures)
+		}
+		if status.Live() {
+			log.WithContext(ctx).Infof("VCL of director %q is live on %d Varnish servers", director.Name, len(status.Servers))
+			return nil
+		}
+		log.WithContext(ctx).Debugf("VCL of director %q not live yet (attempt %d): %s", director.Name, attempt, status)
+
+		select {
+		case <-time.After(interval):
+		case <-deadline.C:
+			return fmt.Errorf("%w: director %s after %s: %s", ErrNotReloaded, director.Name, timeout, status)
+		case <-ctx.Done():
+			return fmt.Errorf("%w: director %s: %s", ErrNotReloaded, director.Name, ctx.Err())
+		}
+	}
+}
+
+// reloadStatus returns the reload status reported by the task at taskURI, if any, or by the reload status endpoint
+func reloadStatus(ctx context.Context, client Client, director *Director, taskURI string) (*ReloadStatus, error) {
+	if taskURI != "" {
+		task, err := client.GetTask(ctx, taskURI)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check VaaS task %s: %w", taskURI, err)
+		}
+		if status, ok := ParseReloadInfo(task.Info); ok {
+			return status, nil
+		}
+		if task.Status == TaskFailure {
+			return nil, fmt.Errorf("%w: %s: %s", ErrReloadFailed, taskURI, task.Info)
+		}
+	}
+	return client.GetReloadStatus(ctx, director)
+}
+
+func reloadFailure(director *Director, failures []ServerReload) error {
+	messages := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		messages = append(messages, fmt.Sprintf("%s: %s", failure.Server, failure.Error))
+	}
+	return fmt.Errorf("%w: director %s on %s", ErrReloadFailed, director.Name, strings.Join(messages, "; "))
+}
@@ -0,0 +1,89 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReloadInfo(t *testing.T) {
+	status, ok := ParseReloadInfo("varnish-1: success, varnish-2: PENDING")
+	require.True(t, ok)
+	assert.Equal(t, []ServerReload{{Server: "varnish-1", Status: TaskSuccess}, {Server: "varnish-2", Status: TaskPending}}, status.Servers)
+	assert.False(t, status.Live())
+
+	status, ok = ParseReloadInfo(`{"servers": [{"server": "varnish-1", "status": "FAILURE", "error": "VCL compilation failed"}]}`)
+	require.True(t, ok)
+	assert.Equal(t, []ServerReload{{Server: "varnish-1", Status: TaskFailure, Error: "VCL compilation failed"}}, status.Failures())
+
+	for _, info := range []string{"", "Backend added", "varnish-1: unknown", `{"servers": []}`} {
+		_, ok := ParseReloadInfo(info)
+		assert.False(t, ok, info)
+	}
+}
+
+func TestWaitForReloadPollsReloadStatusEndpoint(t *testing.T) {
+	var polls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v0.1/director/1/reload_status/", r.URL.Path)
+		status := TaskPending
+		if atomic.AddInt32(&polls, 1) > 2 {
+			status = TaskSuccess
+		}
+		_, _ = w.Write([]byte(`{"servers": [{"server": "varnish-1", "status": "SUCCESS"}, {"server": "varnish-2", "status": "` + status + `"}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := WaitForReload(context.Background(), client, createDirector(1), "", ReloadOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&polls))
+}
+
+func TestWaitForReloadReadsTaskInfo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v0.1/task/3f2a/", r.URL.Path)
+		_, _ = w.Write([]byte(`{"status": "SUCCESS", "info": "varnish-1: SUCCESS; varnish-2: FAILURE"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := WaitForReload(context.Background(), client, createDirector(1), "/api/v0.1/task/3f2a/", ReloadOptions{Interval: time.Millisecond})
+
+	require.True(t, errors.Is(err, ErrReloadFailed))
+	assert.Contains(t, err.Error(), "varnish-2")
+}
+
+func TestWaitForReloadFailsWithoutReloadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := WaitForReload(context.Background(), client, createDirector(1), "", ReloadOptions{Interval: time.Millisecond})
+
+	require.True(t, errors.Is(err, ErrReloadStatusUnsupported))
+}
+
+func TestWaitForReloadFailsAfterTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"servers": [{"server": "varnish-1", "status": "PENDING"}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := WaitForReload(context.Background(), client, createDirector(1), "",
+		ReloadOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+
+	require.True(t, errors.Is(err, ErrNotReloaded))
+	assert.Contains(t, err.Error(), "varnish-1: PENDING")
+}
@@ -0,0 +1,211 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryPolicy describes how the client retries requests failing with transient errors.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every following one.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, if set.
+	MaxDelay time.Duration
+	// Jitter randomizes every delay by up to this fraction of it, e.g. 0.2 for ±20%.
+	Jitter float64
+	// RetryableStatusCodes lists HTTP statuses worth retrying. Transport errors are always retried.
+	// Only idempotent requests are retried after either, see shouldRetry.
+	RetryableStatusCodes []int
+	// HonorRetryAfter retries 429 Too Many Requests responses, and waits as long as the Retry-After header
+	// of a retried response asks instead of the computed delay, up to MaxDelay.
+	HonorRetryAfter bool
+}
+
+// DefaultRetryPolicy retries errors VaaS returns while Varnish is being reloaded.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          4,
+	BaseDelay:            500 * time.Millisecond,
+	MaxDelay:             5 * time.Second,
+	Jitter:               0.2,
+	RetryableStatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	HonorRetryAfter:      true,
+}
+
+// DefaultLockRetryPolicy retries adding backends to directors changed concurrently, as happens in mass
+// deployments registering many backends of a director at once.
+var DefaultLockRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    15 * time.Second,
+	Jitter:      0.5,
+}
+
+// WithLockRetryPolicy makes the client retry adding backends while VaaS rejects them with ErrDirectorLocked,
+// waiting with jittered exponential backoff of policy, whose RetryableStatusCodes and HonorRetryAfter are not
+// used. Such requests are not retried by default.
+func WithLockRetryPolicy(policy RetryPolicy) Option {
+	return func(c *defaultClient) {
+		c.lockRetry = policy
+	}
+}
+
+// WithRetryPolicy makes the client retry requests according to policy. By default requests are not retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *defaultClient) {
+		c.retry = policy
+	}
+}
+
+// shouldRetry tells whether request, which failed with response or err, is worth another attempt.
+// Requests which are not idempotent, i.e. POSTs creating objects, are retried only after 429 Too Many Requests,
+// as VaaS may have created the object already whatever other failure it reported.
+func (p RetryPolicy) shouldRetry(attempt int, request *http.Request, response *http.Response, err error) bool {
+	if err == nil || attempt >= p.MaxAttempts || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if p.HonorRetryAfter && response != nil && response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !idempotent(request.Method) {
+		return false
+	}
+	if response == nil {
+		return true
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if response.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// idempotent tells whether requests with method can be sent again without changing the outcome.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// delayAfter returns the delay before retrying response, taken from its Retry-After header if honored.
+// The delay VaaS asks for is capped by MaxDelay and by the deadline of ctx, so that it cannot hold up a request
+// for longer than the policy or its caller allow.
+func (p RetryPolicy) delayAfter(ctx context.Context, attempt int, response *http.Response) time.Duration {
+	if !p.HonorRetryAfter || response == nil {
+		return p.delay(attempt)
+	}
+	delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return p.delay(attempt)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+		delay = time.Until(deadline)
+	}
+	return delay
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func (c *defaultClient) doWithRetries(request *http.Request) (*http.Response, error) {
+	response, err := c.send(request)
+	for attempt := 1; c.retry.shouldRetry(attempt, request, response, err); attempt++ {
+		if request.Context().Err() != nil {
+			break
+		}
+
+		delay := c.retry.delayAfter(request.Context(), attempt, response)
+		log.WithContext(request.Context()).Warnf("VaaS request %s %s failed (attempt %d of %d), retrying in %s: %s",
+			request.Method, request.URL.Path, attempt, c.retry.MaxAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-request.Context().Done():
+			return response, err
+		}
+
+		if response != nil {
+			response.Body.Close()
+		}
+		if request, err = rewind(request); err != nil {
+			return nil, err
+		}
+		response, err = c.send(request)
+	}
+	return response, err
+}
+
+// rewind returns a copy of already sent request ready to be sent again.
+func rewind(request *http.Request) (*http.Request, error) {
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// doAddBackend sends request adding a backend, retrying it according to lockRetry while VaaS reports that its
+// director is changed concurrently
+func (c *defaultClient) doAddBackend(request *http.Request) (*http.Response, error) {
+	response, err := c.do(request)
+	for attempt := 1; attempt < c.lockRetry.MaxAttempts && errors.Is(err, ErrDirectorLocked); attempt++ {
+		delay := c.lockRetry.delay(attempt)
+		log.WithContext(request.Context()).Warnf("Director changed concurrently in VaaS (attempt %d of %d), retrying in %s: %s",
+			attempt, c.lockRetry.MaxAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-request.Context().Done():
+			return response, err
+		}
+
+		if response != nil {
+			response.Body.Close()
+		}
+		if request, err = rewind(request); err != nil {
+			return nil, err
+		}
+		response, err = c.do(request)
+	}
+	return response, err
+}
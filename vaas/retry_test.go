@@ -0,0 +1,215 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	BaseDelay:            time.Millisecond,
+	RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+}
+
+func TestRetriesTransientErrorsWithRequestBody(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"weight":10}`, string(body))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithRetryPolicy(testRetryPolicy))
+
+	require.NoError(t, client.SetBackendWeight(context.Background(), 1, 10))
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDoesNotRetryBeyondMaxAttemptsOrNonRetryableStatus(t *testing.T) {
+	attempts := 0
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithRetryPolicy(testRetryPolicy))
+
+	assert.Error(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	status = http.StatusBadRequest
+	assert.Error(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDoesNotRetryByDefault(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	assert.Error(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDoesNotRetryPostingBackends(t *testing.T) {
+	posts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	director := &Director{ID: 1, Name: "director"}
+
+	client := NewClient(ts.URL, "username", "api-key", WithRetryPolicy(testRetryPolicy))
+	_, err := client.AddBackend(context.Background(), &Backend{Address: "127.0.0.1", Port: 80}, director)
+	assert.Error(t, err)
+	assert.Equal(t, 1, posts)
+
+	posts = 0
+	failPosts := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Method == http.MethodPost {
+				posts++
+				return nil, errors.New("connection reset by peer")
+			}
+			return next.RoundTrip(request)
+		})
+	}
+	client = NewClient(ts.URL, "username", "api-key", WithRetryPolicy(testRetryPolicy), WithMiddleware(failPosts))
+	_, err = client.AddBackend(context.Background(), &Backend{Address: "127.0.0.1", Port: 80}, director)
+	assert.Error(t, err)
+	assert.Equal(t, 1, posts)
+}
+
+func TestRetriesAddingBackendToLockedDirector(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"address":"127.0.0.1"`)
+		if attempts < 3 {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_message": "Concurrent VCL generation for director"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(mockAddBackendResponse)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key",
+		WithLockRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: 0.5}))
+
+	result, err := client.AddBackend(context.Background(), createBackend(), createDirector(1))
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.NotZero(t, result.ID)
+}
+
+func TestDoesNotRetryLockedDirectorByDefault(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error_message": "Concurrent VCL generation for director"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	_, err := client.AddBackend(context.Background(), createBackend(), createDirector(1))
+
+	assert.True(t, errors.Is(err, ErrDirectorLocked), err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryDelayIsCappedAndJittered(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+	assert.Equal(t, time.Second, policy.delay(1))
+	assert.Equal(t, 2*time.Second, policy.delay(2))
+	assert.Equal(t, 3*time.Second, policy.delay(3))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		delay := policy.delay(1)
+		assert.True(t, delay >= 500*time.Millisecond && delay <= 1500*time.Millisecond)
+	}
+}
+
+func TestHonorsRetryAfterOnTooManyRequests(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	policy := testRetryPolicy
+	policy.BaseDelay = time.Hour
+	policy.HonorRetryAfter = true
+	client := NewClient(ts.URL, "username", "api-key", WithRetryPolicy(policy))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetryAfterIsCappedByMaxDelayAndDeadline(t *testing.T) {
+	response := &http.Response{Header: http.Header{"Retry-After": []string{"7200"}}}
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, HonorRetryAfter: true}
+
+	assert.Equal(t, 5*time.Second, policy.delayAfter(context.Background(), 1, response))
+
+	policy.MaxDelay = 0
+	assert.Equal(t, 2*time.Hour, policy.delayAfter(context.Background(), 1, response))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	delay := policy.delayAfter(ctx, 1, response)
+	assert.True(t, delay > 0 && delay <= time.Second, delay)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	delay, ok := parseRetryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, ok = parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, delay)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,160 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	routePath    = "/route/"
+	redirectPath = "/redirect/"
+)
+
+// Actions of a route, telling Varnish how to handle matching requests.
+const (
+	RouteActionPass = "pass"
+	RouteActionPipe = "pipe"
+)
+
+// Route represents JSON structure of a routing rule in VaaS API. Requests matching Condition, a VCL expression
+// such as `req.url ~ "^/api"`, in Clusters are sent to Director, given by its resource URI.
+// Rules with lower Priority are matched first.
+type Route struct {
+	ID          ID       `json:"id,omitempty"`
+	Condition   string   `json:"condition,omitempty"`
+	Priority    int      `json:"priority,omitempty"`
+	Action      string   `json:"action,omitempty"`
+	Director    string   `json:"director,omitempty"`
+	Clusters    []string `json:"clusters,omitempty"`
+	ResourceURI string   `json:"resource_uri,omitempty"`
+}
+
+// RouteList represents JSON structure of Route list used in responses in VaaS API.
+type RouteList struct {
+	Meta    Meta    `json:"meta,omitempty"`
+	Objects []Route `json:"objects,omitempty"`
+}
+
+// Redirect represents JSON structure of a redirect in VaaS API. Requests to SourceDomain, given by its resource
+// URI, matching Condition are redirected to Destination with Action as the HTTP status, e.g. 301.
+type Redirect struct {
+	ID                  ID     `json:"id,omitempty"`
+	SourceDomain        string `json:"src_domain,omitempty"`
+	Condition           string `json:"condition,omitempty"`
+	Destination         string `json:"destination,omitempty"`
+	Action              int    `json:"action,omitempty"`
+	Priority            int    `json:"priority,omitempty"`
+	PreserveQueryParams bool   `json:"preserve_query_params,omitempty"`
+	ResourceURI         string `json:"resource_uri,omitempty"`
+}
+
+// RedirectList represents JSON structure of Redirect list used in responses in VaaS API.
+type RedirectList struct {
+	Meta    Meta       `json:"meta,omitempty"`
+	Objects []Redirect `json:"objects,omitempty"`
+}
+
+func (l *RouteList) nextPage() *string    { return l.Meta.Next }
+func (l *RedirectList) nextPage() *string { return l.Meta.Next }
+
+// ListRoutes returns routes sending requests to director, or every route when director is nil.
+func (c *defaultClient) ListRoutes(ctx context.Context, director *Director) ([]Route, error) {
+	query := url.Values{}
+	if director != nil {
+		query.Set("director", fmt.Sprintf("%d", director.ID))
+	}
+
+	var routes []Route
+	err := c.listAll(ctx, routePath, query, func() (listPage, func()) {
+		page := &RouteList{}
+		return page, func() { routes = append(routes, page.Objects...) }
+	})
+	if err != nil {
+		return nil, fmt.Errorf("route list fetch failed: %w", err)
+	}
+	return routes, nil
+}
+
+// CreateRoute creates route in VaaS, filling its ID and resource URI from the response.
+func (c *defaultClient) CreateRoute(ctx context.Context, route *Route) error {
+	if err := c.createObject(ctx, routePath, route, &route.ResourceURI); err != nil {
+		return fmt.Errorf("cannot create route %q: %w", route.Condition, err)
+	}
+	return nil
+}
+
+// DeleteRoute removes route with given id from VaaS.
+func (c *defaultClient) DeleteRoute(ctx context.Context, id int) error {
+	if err := c.deleteObject(ctx, routePath, id); err != nil {
+		return fmt.Errorf("cannot delete route %d: %w", id, err)
+	}
+	return nil
+}
+
+// ListRedirects returns every redirect defined in VaaS.
+func (c *defaultClient) ListRedirects(ctx context.Context) ([]Redirect, error) {
+	var redirects []Redirect
+	err := c.listAll(ctx, redirectPath, nil, func() (listPage, func()) {
+		page := &RedirectList{}
+		return page, func() { redirects = append(redirects, page.Objects...) }
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redirect list fetch failed: %w", err)
+	}
+	return redirects, nil
+}
+
+// CreateRedirect creates redirect in VaaS, filling its ID and resource URI from the response.
+func (c *defaultClient) CreateRedirect(ctx context.Context, redirect *Redirect) error {
+	if err := c.createObject(ctx, redirectPath, redirect, &redirect.ResourceURI); err != nil {
+		return fmt.Errorf("cannot create redirect to %s: %w", redirect.Destination, err)
+	}
+	return nil
+}
+
+// DeleteRedirect removes redirect with given id from VaaS.
+func (c *defaultClient) DeleteRedirect(ctx context.Context, id int) error {
+	if err := c.deleteObject(ctx, redirectPath, id); err != nil {
+		return fmt.Errorf("cannot delete redirect %d: %w", id, err)
+	}
+	return nil
+}
+
+// createObject posts object to resource path and decodes the response into it,
+// taking its resource URI from the Location header when the response has none
+func (c *defaultClient) createObject(ctx context.Context, path string, object interface{}, resourceURI *string) error {
+	endpoint, err := c.endpoint(ctx, path)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodPost, endpoint, object)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.doRequest(request, object)
+	if err != nil {
+		return err
+	}
+	if *resourceURI == "" {
+		*resourceURI = response.Header.Get("Location")
+	}
+	return nil
+}
+
+// deleteObject removes object with given id of resource path
+func (c *defaultClient) deleteObject(ctx context.Context, path string, id int) error {
+	endpoint, err := c.endpoint(ctx, path)
+	if err != nil {
+		return err
+	}
+	request, err := c.newRequest(ctx, http.MethodDelete, fmt.Sprintf("%s%d/", endpoint, id), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(request)
+	return err
+}
@@ -0,0 +1,98 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRoutesOfDirector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v0.1/route/", r.URL.Path)
+		assert.Equal(t, "5", r.URL.Query().Get("director"))
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"objects": [{"id": 1, "condition": "req.url ~ \"^/api\"", "priority": 10,
+			"action": "pass", "director": "/api/v0.1/director/5/", "clusters": ["/api/v0.1/cluster/1/"]}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	routes, err := NewClient(ts.URL, "username", "api-key").ListRoutes(context.Background(), createDirector(5))
+
+	require.NoError(t, err)
+	assert.Equal(t, []Route{{
+		ID:        1,
+		Condition: `req.url ~ "^/api"`,
+		Priority:  10,
+		Action:    RouteActionPass,
+		Director:  "/api/v0.1/director/5/",
+		Clusters:  []string{"/api/v0.1/cluster/1/"},
+	}}, routes)
+}
+
+func TestCreateAndDeleteRoute(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.NotContains(t, body, "id")
+		assert.Equal(t, "/api/v0.1/director/5/", body["director"])
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v0.1/route/3/")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"id": 3, "condition": "req.url ~ \"^/\""}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+	route := &Route{Condition: `req.url ~ "^/"`, Director: "/api/v0.1/director/5/", Action: RouteActionPass}
+
+	require.NoError(t, client.CreateRoute(context.Background(), route))
+	require.NoError(t, client.DeleteRoute(context.Background(), int(route.ID)))
+
+	assert.Equal(t, ID(3), route.ID)
+	assert.Equal(t, "/api/v0.1/route/3/", route.ResourceURI)
+	assert.Equal(t, []string{"POST /api/v0.1/route/", "DELETE /api/v0.1/route/3/"}, requests)
+}
+
+func TestCreateListAndDeleteRedirects(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		var err error
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, err = w.Write([]byte(`{"id": 4, "destination": "https://example.com/new", "action": 301,
+				"resource_uri": "/api/v0.1/redirect/4/"}`))
+		case http.MethodGet:
+			_, err = w.Write([]byte(`{"objects": [{"id": 4, "destination": "https://example.com/new", "action": 301}]}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+	redirect := &Redirect{SourceDomain: "/api/v0.1/domain/1/", Destination: "https://example.com/new", Action: 301}
+
+	require.NoError(t, client.CreateRedirect(context.Background(), redirect))
+	redirects, err := client.ListRedirects(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteRedirect(context.Background(), 4))
+
+	assert.Equal(t, "/api/v0.1/redirect/4/", redirect.ResourceURI)
+	assert.Equal(t, []Redirect{{ID: 4, Destination: "https://example.com/new", Action: 301}}, redirects)
+	assert.Equal(t, []string{"POST /api/v0.1/redirect/", "GET /api/v0.1/redirect/", "DELETE /api/v0.1/redirect/4/"}, requests)
+}
@@ -0,0 +1,103 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// BackendQuery selects backends across directors having all of its set fields, see SearchBackends.
+type BackendQuery struct {
+	// Address of the backend.
+	Address string
+	// Port of the backend.
+	Port int
+	// Tag the backend has.
+	Tag string
+	// DC is the symbol of the DC of the backend.
+	DC string
+	// DirectorRegex matches names of directors of the backend.
+	DirectorRegex *regexp.Regexp
+}
+
+// Values returns tastypie filters of VaaS backend list selecting backends of query.
+func (q BackendQuery) Values() url.Values {
+	values := url.Values{}
+	if q.Address != "" {
+		values.Set("address", NormalizeAddress(q.Address))
+	}
+	if q.Port != 0 {
+		values.Set("port", strconv.Itoa(q.Port))
+	}
+	if q.Tag != "" {
+		values.Set("tags", q.Tag)
+	}
+	if q.DC != "" {
+		values.Set("dc__symbol", q.DC)
+	}
+	if q.DirectorRegex != nil {
+		values.Set("director__name__regex", q.DirectorRegex.String())
+	}
+	return values
+}
+
+// Matches returns whether backend has the set address, port, tag and DC of query. The DC of backends for which
+// VaaS reports no symbol is not checked.
+func (q BackendQuery) Matches(backend Backend) bool {
+	if q.Address != "" && !SameAddress(backend.Address, q.Address) {
+		return false
+	}
+	if q.Port != 0 && backend.Port != q.Port {
+		return false
+	}
+	if q.Tag != "" && !hasTag(backend.Tags, q.Tag) {
+		return false
+	}
+	return q.DC == "" || backend.DC.Symbol == "" || backend.DC.Symbol == q.DC
+}
+
+// SearchBackends returns backends of every director matching query, following pages of the list. Filters are
+// sent to VaaS and checked again on returned backends, as tastypie ignores filters a resource does not allow.
+func (c *defaultClient) SearchBackends(ctx context.Context, query BackendQuery) ([]Backend, error) {
+	var directors map[string]bool
+	if query.DirectorRegex != nil {
+		all, err := c.ListDirectors(ctx)
+		if err != nil {
+			return nil, err
+		}
+		directors = matchingDirectors(all, query.DirectorRegex)
+		if len(directors) == 0 {
+			return nil, nil
+		}
+	}
+
+	backends, err := c.listBackends(ctx, query.Values())
+	if err != nil {
+		return nil, fmt.Errorf("backend search failed: %w", err)
+	}
+	return selectBackends(backends, query, directors), nil
+}
+
+// matchingDirectors returns resource URIs of directors with names matching pattern
+func matchingDirectors(directors []Director, pattern *regexp.Regexp) map[string]bool {
+	matching := map[string]bool{}
+	for _, director := range directors {
+		if pattern.MatchString(director.Name) {
+			matching[director.ResourceURI] = true
+		}
+	}
+	return matching
+}
+
+// selectBackends returns backends matching query, in their order, of directors given by resource URI, if any
+func selectBackends(backends []Backend, query BackendQuery, directors map[string]bool) []Backend {
+	var selected []Backend
+	for _, backend := range backends {
+		if query.Matches(backend) && (directors == nil || directors[backend.DirectorURL]) {
+			selected = append(selected, backend)
+		}
+	}
+	return selected
+}
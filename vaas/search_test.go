@@ -0,0 +1,81 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchBackendsSendsFiltersAndChecksThemAgain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page interface{}
+		switch r.URL.Path {
+		case apiDirectorPath:
+			page = DirectorList{Objects: []Director{
+				{ID: 1, Name: "shop-api", ResourceURI: "/api/v0.1/director/1/"},
+				{ID: 2, Name: "search", ResourceURI: "/api/v0.1/director/2/"},
+			}}
+		case apiBackendPath:
+			query := r.URL.Query()
+			if query.Get("offset") == "" {
+				assert.Equal(t, "80", query.Get("port"))
+				assert.Equal(t, "canary", query.Get("tags"))
+				assert.Equal(t, "dc1", query.Get("dc__symbol"))
+				assert.Equal(t, "^shop-", query.Get("director__name__regex"))
+				next := apiBackendPath + "?offset=2"
+				page = BackendList{Meta: Meta{Next: &next}, Objects: []Backend{
+					{ID: NewID(1), Port: 80, Tags: []string{"canary"}, DC: DC{Symbol: "dc1"}, DirectorURL: "/api/v0.1/director/1/"},
+					{ID: NewID(2), Port: 80, Tags: []string{"canary"}, DC: DC{Symbol: "dc1"}, DirectorURL: "/api/v0.1/director/2/"},
+				}}
+			} else {
+				page = BackendList{Objects: []Backend{
+					{ID: NewID(3), Port: 8080, Tags: []string{"canary"}, DC: DC{Symbol: "dc1"}, DirectorURL: "/api/v0.1/director/1/"},
+					{ID: NewID(4), Port: 80, Tags: []string{"canary"}, DC: DC{Symbol: "dc1"}, DirectorURL: "/api/v0.1/director/1/"},
+				}}
+			}
+		}
+		data, _ := json.Marshal(page)
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	query := BackendQuery{Port: 80, Tag: "canary", DC: "dc1", DirectorRegex: regexp.MustCompile("^shop-")}
+
+	backends, err := client.SearchBackends(context.Background(), query)
+
+	require.NoError(t, err)
+	require.Len(t, backends, 2)
+	assert.Equal(t, ID(1), *backends[0].ID)
+	assert.Equal(t, ID(4), *backends[1].ID)
+}
+
+func TestSearchBackendsWithoutMatchingDirectorsReturnsNone(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiDirectorPath, r.URL.Path)
+		data, _ := json.Marshal(DirectorList{Objects: []Director{{ID: 1, Name: "search"}}})
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	backends, err := client.SearchBackends(context.Background(), BackendQuery{DirectorRegex: regexp.MustCompile("^shop-")})
+
+	require.NoError(t, err)
+	assert.Empty(t, backends)
+}
+
+func TestBackendQueryValuesNormalizeAddress(t *testing.T) {
+	values := BackendQuery{Address: "[2001:db8::10]"}.Values()
+
+	assert.Equal(t, "address=2001%3Adb8%3A%3A10", values.Encode())
+}
@@ -0,0 +1,193 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Snapshot is the set of backends of a director, exported to move them to another director or VaaS, or to
+// restore them. Backends refer to DCs by symbol, so that snapshots apply to VaaS instances with other IDs.
+type Snapshot struct {
+	Director string            `json:"director" yaml:"director"`
+	Exported time.Time         `json:"exported" yaml:"exported"`
+	Backends []SnapshotBackend `json:"backends" yaml:"backends"`
+}
+
+// SnapshotBackend is a backend in a Snapshot.
+type SnapshotBackend struct {
+	Address            string   `json:"address" yaml:"address"`
+	Port               int      `json:"port" yaml:"port"`
+	DC                 string   `json:"dc" yaml:"dc"`
+	Weight             *int     `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Tags               []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Enabled            *bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	InheritTimeProfile bool     `json:"inherit_time_profile,omitempty" yaml:"inherit_time_profile,omitempty"`
+
+	MaxConnections      int     `json:"max_connections,omitempty" yaml:"max_connections,omitempty"`
+	ConnectTimeout      Seconds `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"`
+	FirstByteTimeout    Seconds `json:"first_byte_timeout,omitempty" yaml:"first_byte_timeout,omitempty"`
+	BetweenBytesTimeout Seconds `json:"between_bytes_timeout,omitempty" yaml:"between_bytes_timeout,omitempty"`
+}
+
+// ErrEmptySnapshot is returned by ImportSnapshot for a snapshot without backends, which would delete every backend.
+var ErrEmptySnapshot = errors.New("snapshot has no backends")
+
+// ImportOptions configures ImportSnapshot.
+type ImportOptions struct {
+	// DryRun only computes the changes without applying them.
+	DryRun bool
+	// AllowEmpty imports a snapshot without backends, deleting every backend of the director.
+	AllowEmpty bool
+}
+
+// ExportSnapshot returns a snapshot of backends of director.
+func ExportSnapshot(ctx context.Context, client Client, director string) (Snapshot, error) {
+	dir, err := client.FindDirector(ctx, director)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("cannot export director %s: %w", director, err)
+	}
+	backends, err := client.ListBackends(ctx, dir)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("cannot export director %s: %w", director, err)
+	}
+
+	snapshot := Snapshot{Director: dir.Name, Exported: time.Now().UTC(), Backends: make([]SnapshotBackend, 0, len(backends))}
+	for _, backend := range backends {
+		snapshot.Backends = append(snapshot.Backends, SnapshotBackend{
+			Address:             backend.Address,
+			Port:                backend.Port,
+			DC:                  backend.DC.Symbol,
+			Weight:              backend.Weight,
+			Tags:                backend.Tags,
+			Enabled:             backend.Enabled,
+			InheritTimeProfile:  backend.InheritTimeProfile,
+			MaxConnections:      backend.MaxConnections,
+			ConnectTimeout:      backend.ConnectTimeout,
+			FirstByteTimeout:    backend.FirstByteTimeout,
+			BetweenBytesTimeout: backend.BetweenBytesTimeout,
+		})
+	}
+	return snapshot, nil
+}
+
+// ImportSnapshot makes backends of director match those of snapshot, matching them by address and port: missing
+// backends are added together with AddBackends, changed ones are updated and the remaining ones are deleted.
+// Failed changes are reported in ReconcileReport and summarized in the returned error.
+func ImportSnapshot(ctx context.Context, client Client, director string, snapshot Snapshot, opts ImportOptions) (ReconcileReport, error) {
+	report := ReconcileReport{DryRun: opts.DryRun}
+	if len(snapshot.Backends) == 0 && !opts.AllowEmpty {
+		return report, ErrEmptySnapshot
+	}
+
+	dir, err := client.FindDirector(ctx, director)
+	if err != nil {
+		return report, fmt.Errorf("cannot import into director %s: %w", director, err)
+	}
+	desired, err := snapshotBackends(ctx, client, snapshot)
+	if err != nil {
+		return report, fmt.Errorf("cannot import into director %s: %w", director, err)
+	}
+	actual, err := client.ListBackends(ctx, dir)
+	if err != nil {
+		return report, fmt.Errorf("cannot import into director %s: %w", director, err)
+	}
+
+	report.Changes = diffBackends(dir, actual, desired, snapshotChanged)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	importChanges(ctx, client, dir, report.Changes)
+
+	if failed := report.Failed(); len(failed) > 0 {
+		return report, fmt.Errorf("%d of %d changes in director %s failed, first: %s %s: %s",
+			len(failed), len(report.Changes), director, failed[0].Action,
+			HostPort(failed[0].Backend.Address, failed[0].Backend.Port), failed[0].Err)
+	}
+	return report, nil
+}
+
+// snapshotBackends returns backends of snapshot with their DCs looked up in VaaS
+func snapshotBackends(ctx context.Context, client Client, snapshot Snapshot) ([]Backend, error) {
+	dcs := map[string]DC{}
+	backends := make([]Backend, 0, len(snapshot.Backends))
+	for _, backend := range snapshot.Backends {
+		dc, ok := dcs[backend.DC]
+		if !ok {
+			found, err := client.GetDC(ctx, backend.DC)
+			if err != nil {
+				return nil, err
+			}
+			dc = *found
+			dcs[backend.DC] = dc
+		}
+		backends = append(backends, Backend{
+			Address:             backend.Address,
+			Port:                backend.Port,
+			DC:                  dc,
+			Weight:              backend.Weight,
+			Tags:                backend.Tags,
+			Enabled:             backend.Enabled,
+			InheritTimeProfile:  backend.InheritTimeProfile,
+			MaxConnections:      backend.MaxConnections,
+			ConnectTimeout:      backend.ConnectTimeout,
+			FirstByteTimeout:    backend.FirstByteTimeout,
+			BetweenBytesTimeout: backend.BetweenBytesTimeout,
+		})
+	}
+	return backends, nil
+}
+
+// snapshotChanged tells whether current differs from desired in a field kept in snapshots
+func snapshotChanged(current, desired Backend) bool {
+	return needsUpdate(current, desired) ||
+		current.DC.Symbol != desired.DC.Symbol ||
+		current.IsEnabled() != desired.IsEnabled() ||
+		current.InheritTimeProfile != desired.InheritTimeProfile ||
+		current.MaxConnections != desired.MaxConnections ||
+		current.ConnectTimeout != desired.ConnectTimeout ||
+		current.FirstByteTimeout != desired.FirstByteTimeout ||
+		current.BetweenBytesTimeout != desired.BetweenBytesTimeout
+}
+
+// importChanges adds backends of changes with a single AddBackends call and applies the others one by one,
+// with at most DefaultBulkConcurrency of them at once
+func importChanges(ctx context.Context, client Client, director *Director, changes []ReconcileChange) {
+	var added []*Backend
+	var others []ReconcileChange
+	var addedIndexes, otherIndexes []int
+	for i := range changes {
+		if changes[i].Action == ActionAdd {
+			added = append(added, &changes[i].Backend)
+			addedIndexes = append(addedIndexes, i)
+		} else {
+			others = append(others, changes[i])
+			otherIndexes = append(otherIndexes, i)
+		}
+	}
+
+	if len(added) > 0 {
+		log.WithContext(ctx).Infof("Adding %d backends to director %s", len(added), director.Name)
+		_, err := client.AddBackends(ctx, added, director)
+		var bulkErr *BulkError
+		switch {
+		case errors.As(err, &bulkErr):
+			for _, failure := range bulkErr.Failures {
+				changes[addedIndexes[failure.Index]].Err = failure.Err
+			}
+		case err != nil:
+			for _, i := range addedIndexes {
+				changes[i].Err = err
+			}
+		}
+	}
+
+	applyChanges(ctx, client, director, others, ReconcileOptions{Concurrency: DefaultBulkConcurrency})
+	for i, change := range others {
+		changes[otherIndexes[i]].Err = change.Err
+	}
+}
@@ -0,0 +1,90 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotServer serves DC dc1 besides backends of reconcileServer
+func snapshotServer(server *reconcileServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == apiDcPath {
+			data, _ := json.Marshal(DCList{Objects: []DC{{ID: 1, Symbol: "dc1", ResourceURI: "/api/v0.1/dc/1/"}}})
+			_, _ = w.Write(data)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+}
+
+func TestExportSnapshotKeepsBackendsByDCSymbol(t *testing.T) {
+	backend := reconcileBackend(1, "10.0.0.1", 3)
+	backend.DC = DC{ID: 1, Symbol: "dc1"}
+	backend.MaxConnections = 10
+	ts := httptest.NewServer(&reconcileServer{backends: []Backend{backend}})
+	defer ts.Close()
+
+	snapshot, err := ExportSnapshot(context.Background(), NewClient(ts.URL, "username", "api-key"), "director")
+
+	require.NoError(t, err)
+	assert.Equal(t, "director", snapshot.Director)
+	weight := 3
+	assert.Equal(t, []SnapshotBackend{{Address: "10.0.0.1", Port: 80, DC: "dc1", Weight: &weight, Tags: []string{"a", "b"},
+		MaxConnections: 10}}, snapshot.Backends)
+}
+
+func TestImportSnapshotAddsUpdatesAndDeletesBackends(t *testing.T) {
+	dc := DC{ID: 1, Symbol: "dc1", ResourceURI: "/api/v0.1/dc/1/"}
+	unchanged, changed, extra := reconcileBackend(1, "10.0.0.1", 1), reconcileBackend(2, "10.0.0.2", 1), reconcileBackend(3, "10.0.0.3", 1)
+	unchanged.DC, changed.DC, extra.DC = dc, dc, dc
+	server := &reconcileServer{backends: []Backend{unchanged, changed, extra}}
+	ts := httptest.NewServer(snapshotServer(server))
+	defer ts.Close()
+
+	one := 1
+	snapshot := Snapshot{Director: "old-director", Backends: []SnapshotBackend{
+		{Address: "10.0.0.1", Port: 80, DC: "dc1", Weight: &one, Tags: []string{"a", "b"}},
+		{Address: "10.0.0.2", Port: 80, DC: "dc1", Weight: &one, Tags: []string{"a", "b"}, MaxConnections: 5},
+		{Address: "10.0.0.4", Port: 80, DC: "dc1"},
+	}}
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+
+	report, err := ImportSnapshot(context.Background(), client, "director", snapshot, ImportOptions{})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"PUT /api/v0.1/backend/2/",
+		"POST /api/v0.1/backend/",
+		"DELETE /api/v0.1/backend/3/",
+	}, server.requests)
+	require.Len(t, report.Changes, 3)
+}
+
+func TestImportSnapshotRefusesEmptySnapshot(t *testing.T) {
+	client := NewClient("http://vaas.invalid", "username", "api-key")
+
+	_, err := ImportSnapshot(context.Background(), client, "director", Snapshot{}, ImportOptions{})
+
+	require.True(t, errors.Is(err, ErrEmptySnapshot), err)
+}
+
+func TestImportSnapshotDryRunDoesNotApplyChanges(t *testing.T) {
+	server := &reconcileServer{backends: []Backend{reconcileBackend(1, "10.0.0.1", 1)}}
+	ts := httptest.NewServer(snapshotServer(server))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithDCCacheTTL(0))
+	report, err := ImportSnapshot(context.Background(), client, "director", Snapshot{}, ImportOptions{DryRun: true, AllowEmpty: true})
+
+	require.NoError(t, err)
+	assert.Empty(t, server.requests)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, ActionDelete, report.Changes[0].Action)
+}
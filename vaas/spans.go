@@ -0,0 +1,29 @@
+package vaas
+
+import (
+	"context"
+
+	"github.com/allegro/vaas-registration-hook/tracing"
+)
+
+// Attributes of spans of VaaS operations
+const (
+	attributeDirector  = "vaas.director"
+	attributeAddress   = "vaas.backend.address"
+	attributePort      = "vaas.backend.port"
+	attributeBackendID = "vaas.backend.id"
+	attributeTask      = "vaas.task"
+	attributePolls     = "vaas.task.polls"
+	attributeCached    = "vaas.cached"
+)
+
+// startBackendSpan starts a span of an operation on backend in director
+func startBackendSpan(ctx context.Context, name string, backend *Backend, director *Director) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name, tracing.KindInternal)
+	span.SetAttribute(attributeAddress, backend.Address)
+	span.SetAttribute(attributePort, backend.Port)
+	if director != nil {
+		span.SetAttribute(attributeDirector, director.Name)
+	}
+	return ctx, span
+}
@@ -0,0 +1,296 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BackendIterator walks backends listed by VaaS one at a time, decoding them from responses as they arrive and
+// fetching following pages only when needed, so that tens of thousands of backends are not held in memory.
+//
+//	backends := client.IterateBackends(ctx, director)
+//	defer backends.Close()
+//	for backends.Next() {
+//		backend := backends.Backend()
+//	}
+//	if err := backends.Err(); err != nil {
+type BackendIterator interface {
+	// Next advances to the next backend, returning false after the last one or on error.
+	Next() bool
+	// Backend returns the backend Next advanced to.
+	Backend() Backend
+	// Err returns the error which stopped the iteration, if any.
+	Err() error
+	// Close releases the response being read. Iterating to the end closes it too.
+	Close() error
+}
+
+// NewSliceBackendIterator returns a BackendIterator over backends, e.g. for fakes of Client.
+func NewSliceBackendIterator(backends []Backend) BackendIterator {
+	return &sliceBackendIterator{backends: backends, i: -1}
+}
+
+type sliceBackendIterator struct {
+	backends []Backend
+	i        int
+}
+
+func (it *sliceBackendIterator) Next() bool {
+	if it.i < len(it.backends) {
+		it.i++
+	}
+	return it.i < len(it.backends)
+}
+
+func (it *sliceBackendIterator) Backend() Backend { return it.backends[it.i] }
+func (it *sliceBackendIterator) Err() error       { return nil }
+func (it *sliceBackendIterator) Close() error     { return nil }
+
+// IterateBackends returns an iterator over backends registered in director, or in all directors if it is nil.
+func (c *defaultClient) IterateBackends(ctx context.Context, director *Director) BackendIterator {
+	var query url.Values
+	if director != nil {
+		query = url.Values{}
+		query.Set("director", fmt.Sprintf("%d", director.ID))
+	}
+	return &backendIterator{list: c.iterate(ctx, backendPath, query)}
+}
+
+type backendIterator struct {
+	list    *listIterator
+	backend Backend
+}
+
+func (it *backendIterator) Next() bool {
+	it.backend = Backend{}
+	if !it.list.next() {
+		return false
+	}
+	if err := it.list.decode(&it.backend); err != nil {
+		return false
+	}
+	return true
+}
+
+func (it *backendIterator) Backend() Backend { return it.backend }
+
+func (it *backendIterator) Err() error {
+	if it.list.err != nil {
+		return fmt.Errorf("backend list fetch failed: %w", it.list.err)
+	}
+	return nil
+}
+
+func (it *backendIterator) Close() error { return it.list.close() }
+
+// streamedKey marks contexts of requests whose successful responses are read by the caller as they arrive
+type streamedKey struct{}
+
+// withStreamedResponse makes the client hand over the body of a successful response to a request with ctx unread,
+// instead of reading it up front. The caller has to close it.
+func withStreamedResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamedKey{}, true)
+}
+
+func isStreamed(ctx context.Context) bool {
+	streamed, _ := ctx.Value(streamedKey{}).(bool)
+	return streamed
+}
+
+// listIterator walks objects of list endpoint path, streaming every page and following its next link
+type listIterator struct {
+	c     *defaultClient
+	ctx   context.Context
+	query url.Values
+	path  string
+	// target is the URL of the page to fetch next, empty while reading one or after the last one
+	target string
+	pages  int
+	page   *pageStream
+	err    error
+}
+
+func (c *defaultClient) iterate(ctx context.Context, path string, query url.Values) *listIterator {
+	it := &listIterator{c: c, ctx: ctx, query: query}
+	if it.target, it.err = c.endpoint(ctx, path); it.err == nil {
+		it.path = strings.TrimPrefix(it.target, c.host)
+	}
+	return it
+}
+
+// next reports whether another object is listed, fetching the next page when the current one ends
+func (it *listIterator) next() bool {
+	for it.err == nil {
+		if it.page == nil {
+			if it.target == "" {
+				return false
+			}
+			if err := it.fetch(); err != nil {
+				it.fail(err)
+			}
+			continue
+		}
+
+		more, err := it.page.more()
+		if err != nil {
+			it.fail(err)
+			return false
+		}
+		if more {
+			return true
+		}
+		next := it.page.next
+		it.close()
+		if next == nil || *next == "" {
+			return false
+		}
+		if it.target, err = it.c.resolve(*next); err != nil {
+			it.err = fmt.Errorf("invalid next page link %q: %s", *next, err)
+			return false
+		}
+		log.WithContext(it.ctx).Debugf("Fetching next page of %s: %s", it.path, *next)
+	}
+	return false
+}
+
+// decode decodes the object next reported into v
+func (it *listIterator) decode(v interface{}) error {
+	if err := it.page.decode(it.c, v); err != nil {
+		it.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (it *listIterator) fetch() error {
+	if it.pages >= it.c.maxPages {
+		return fmt.Errorf("listing %s exceeded the limit of %d pages", it.path, it.c.maxPages)
+	}
+	request, err := it.c.newRequest(withStreamedResponse(it.ctx), "GET", it.target, nil)
+	if err != nil {
+		return err
+	}
+	if it.pages == 0 {
+		request.URL.RawQuery = it.c.firstPageQuery(request.URL.Query(), it.query).Encode()
+	}
+	it.pages++
+
+	response, err := it.c.do(request)
+	if err != nil {
+		return err
+	}
+	it.page = &pageStream{response: response, decoder: json.NewDecoder(response.Body)}
+	it.target = ""
+	if err := it.c.checkContentType(response); err != nil {
+		return err
+	}
+	return it.page.open()
+}
+
+func (it *listIterator) fail(err error) {
+	it.err = err
+	it.close()
+}
+
+func (it *listIterator) close() error {
+	if it.page == nil {
+		return nil
+	}
+	err := it.page.response.Body.Close()
+	it.page = nil
+	return err
+}
+
+// pageStream decodes a list page from its response one object at a time. It reads pages of both v0.1, with
+// objects and meta, and v0.2, with results and next.
+type pageStream struct {
+	response *http.Response
+	decoder  *json.Decoder
+	// inObjects is set while the decoder is within the array of listed objects
+	inObjects bool
+	next      *string
+}
+
+func (p *pageStream) open() error {
+	token, err := p.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("unexpected %v at the start of list page", token)
+	}
+	return nil
+}
+
+// more reports whether another object is listed on the page, reading its other fields until one is found
+func (p *pageStream) more() (bool, error) {
+	for {
+		if p.inObjects {
+			if p.decoder.More() {
+				return true, nil
+			}
+			if _, err := p.decoder.Token(); err != nil {
+				return false, err
+			}
+			p.inObjects = false
+		}
+		if !p.decoder.More() {
+			_, err := p.decoder.Token()
+			return false, err
+		}
+
+		token, err := p.decoder.Token()
+		if err != nil {
+			return false, err
+		}
+		switch token {
+		case "objects", "results":
+			if token, err = p.decoder.Token(); err != nil {
+				return false, err
+			}
+			if token != json.Delim('[') && token != nil {
+				return false, fmt.Errorf("unexpected %v in objects of list page", token)
+			}
+			p.inObjects = token != nil
+		case "meta":
+			var meta Meta
+			if err := p.decoder.Decode(&meta); err != nil {
+				return false, err
+			}
+			if meta.Next != nil {
+				p.next = meta.Next
+			}
+		case "next":
+			var next *string
+			if err := p.decoder.Decode(&next); err != nil {
+				return false, err
+			}
+			if next != nil {
+				p.next = next
+			}
+		default:
+			var skipped json.RawMessage
+			if err := p.decoder.Decode(&skipped); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+// decode decodes the object more reported into v, checking its fields as c does
+func (p *pageStream) decode(c *defaultClient, v interface{}) error {
+	var raw json.RawMessage
+	if err := p.decoder.Decode(&raw); err != nil {
+		return err
+	}
+	if err := c.fieldCheck.check(p.response, raw, v); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
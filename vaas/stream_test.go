@@ -0,0 +1,123 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterateBackendsStreamsAllPages(t *testing.T) {
+	ts := httptest.NewServer(pagedBackendsHandler(t, 2))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	backends := client.IterateBackends(context.Background(), nil)
+	defer backends.Close()
+
+	var ids []ID
+	for backends.Next() {
+		ids = append(ids, *backends.Backend().ID)
+	}
+
+	require.NoError(t, backends.Err())
+	assert.Equal(t, []ID{1, 2}, ids)
+}
+
+func TestIterateBackendsYieldsBackendsBeforePageEnds(t *testing.T) {
+	yielded := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"objects": [{"id": 1, "address": "127.0.0.1", "port": 8080}`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-yielded:
+		case <-time.After(5 * time.Second):
+		}
+		_, _ = w.Write([]byte(`, {"id": 2, "address": "127.0.0.2", "port": 8080}], "meta": {"next": null}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	backends := client.IterateBackends(context.Background(), nil)
+	defer backends.Close()
+
+	require.True(t, backends.Next())
+	assert.Equal(t, ID(1), *backends.Backend().ID)
+	close(yielded)
+	require.True(t, backends.Next())
+	assert.Equal(t, ID(2), *backends.Backend().ID)
+	assert.False(t, backends.Next())
+	require.NoError(t, backends.Err())
+}
+
+func TestIterateBackendsOfDirector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "7", r.URL.Query().Get("director"))
+		_, _ = w.Write([]byte(`{"objects": [{"id": 1, "address": "127.0.0.1", "port": 8080}, {"id": 2, "address": "127.0.0.2", "port": 8080}],
+			"meta": {"total_count": 2, "next": null}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	backends := client.IterateBackends(context.Background(), &Director{ID: 7})
+	defer backends.Close()
+
+	var addresses []string
+	for backends.Next() {
+		addresses = append(addresses, backends.Backend().Address)
+	}
+
+	require.NoError(t, backends.Err())
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2"}, addresses)
+}
+
+func TestListAllBackendsStreamsRESTFrameworkPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"count": 2, "next": null, "previous": "/api/v0.2/backend/", "results": [{"id": 2}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"count": 2, "next": "/api/v0.2/backend/?page=2", "previous": null, "results": [{"id": 1}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithAPIVersion(APIVersionV02))
+	backends, err := client.ListAllBackends(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, backends, 2)
+	assert.Equal(t, ID(2), *backends[1].ID)
+}
+
+func TestIterateBackendsFailsOnMalformedPage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"objects": [{"id": 1}, {"id": `))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	backends := client.IterateBackends(context.Background(), nil)
+	defer backends.Close()
+
+	require.True(t, backends.Next())
+	assert.Equal(t, ID(1), *backends.Backend().ID)
+	assert.False(t, backends.Next())
+	assert.Error(t, backends.Err())
+}
+
+func TestSliceBackendIterator(t *testing.T) {
+	backends := NewSliceBackendIterator([]Backend{{Address: "127.0.0.1"}, {Address: "127.0.0.2"}})
+
+	var addresses []string
+	for backends.Next() {
+		addresses = append(addresses, backends.Backend().Address)
+	}
+
+	assert.Equal(t, []string{"127.0.0.1", "127.0.0.2"}, addresses)
+	assert.False(t, backends.Next())
+	assert.NoError(t, backends.Err())
+}
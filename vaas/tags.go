@@ -0,0 +1,86 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// GetBackend fetches backend with given id.
+func (c *defaultClient) GetBackend(ctx context.Context, id int) (*Backend, error) {
+	endpoint, err := c.endpoint(ctx, backendPath)
+	if err != nil {
+		return nil, err
+	}
+	request, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s%d/", endpoint, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var backend Backend
+	if _, err := c.doRequest(request, &backend); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: no backend with ID %d", ErrBackendNotFound, id)
+		}
+		return nil, err
+	}
+	return &backend, nil
+}
+
+// AddBackendTags adds tags to backend with given id, keeping tags it already has.
+func (c *defaultClient) AddBackendTags(ctx context.Context, id int, tags ...string) error {
+	return c.updateBackendTags(ctx, id, func(current []string) []string {
+		return MergeTags(current, tags)
+	})
+}
+
+// RemoveBackendTags removes tags from backend with given id, keeping its other tags.
+func (c *defaultClient) RemoveBackendTags(ctx context.Context, id int, tags ...string) error {
+	return c.updateBackendTags(ctx, id, func(current []string) []string {
+		return WithoutTags(current, tags)
+	})
+}
+
+// updateBackendTags replaces tags of backend with given id with the result of update, when they change.
+// VaaS has no API to change tags one by one, so concurrent changes of tags of the same backend may be lost.
+func (c *defaultClient) updateBackendTags(ctx context.Context, id int, update func(current []string) []string) error {
+	backend, err := c.GetBackend(ctx, id)
+	if err != nil {
+		return err
+	}
+	tags := update(backend.Tags)
+	if sameTags(tags, backend.Tags) {
+		return nil
+	}
+	return c.UpdateBackend(ctx, id, BackendPatch{Tags: tags})
+}
+
+// MergeTags returns current tags followed by added tags missing from them.
+func MergeTags(current, added []string) []string {
+	merged := make([]string, 0, len(current)+len(added))
+	seen := make(map[string]bool, len(current)+len(added))
+	for _, tag := range append(append([]string{}, current...), added...) {
+		if !seen[tag] {
+			seen[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// WithoutTags returns current tags except removed ones.
+func WithoutTags(current, removed []string) []string {
+	drop := make(map[string]bool, len(removed))
+	for _, tag := range removed {
+		drop[tag] = true
+	}
+	kept := make([]string, 0, len(current))
+	for _, tag := range current {
+		if !drop[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
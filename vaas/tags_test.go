@@ -0,0 +1,77 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, MergeTags([]string{"a", "b"}, []string{"b", "c", "c"}))
+	assert.Equal(t, []string{"a"}, MergeTags(nil, []string{"a"}))
+	assert.Equal(t, []string{}, MergeTags(nil, nil))
+}
+
+func TestWithoutTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "c"}, WithoutTags([]string{"a", "b", "c"}, []string{"b", "d"}))
+	assert.Equal(t, []string{}, WithoutTags([]string{"a"}, []string{"a"}))
+}
+
+func tagsServer(t *testing.T, tags []string, patches *[]map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v0.1/backend/123/", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			assert.NoError(t, json.NewEncoder(w).Encode(Backend{Address: "127.0.0.1", Port: 80, Tags: tags}))
+		case http.MethodPatch:
+			patch := map[string][]string{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&patch))
+			*patches = append(*patches, patch)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected %s", r.Method)
+		}
+	}))
+}
+
+func TestAddBackendTagsKeepsCurrentTags(t *testing.T) {
+	var patches []map[string][]string
+	ts := tagsServer(t, []string{"blue"}, &patches)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	require.NoError(t, client.AddBackendTags(context.Background(), 123, "green"))
+	require.NoError(t, client.AddBackendTags(context.Background(), 123, "blue"))
+
+	assert.Equal(t, []map[string][]string{{"tags": {"blue", "green"}}}, patches)
+}
+
+func TestRemoveBackendTagsKeepsOtherTags(t *testing.T) {
+	var patches []map[string][]string
+	ts := tagsServer(t, []string{"blue", "canary"}, &patches)
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	require.NoError(t, client.RemoveBackendTags(context.Background(), 123, "canary"))
+
+	assert.Equal(t, []map[string][]string{{"tags": {"blue"}}}, patches)
+}
+
+func TestGetBackendReportsMissingBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key")
+
+	_, err := client.GetBackend(context.Background(), 123)
+
+	assert.True(t, errors.Is(err, ErrBackendNotFound), err)
+}
@@ -0,0 +1,201 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/tracing"
+)
+
+// Statuses of VaaS tasks.
+const (
+	TaskPending = "PENDING"
+	TaskSuccess = "SUCCESS"
+	TaskFailure = "FAILURE"
+)
+
+// Defaults of the task watcher used by the client.
+const (
+	DefaultTaskPollInterval = time.Second
+	DefaultTaskTimeout      = 2 * time.Minute
+)
+
+// TaskWatcher polls VaaS tasks until they finish.
+type TaskWatcher struct {
+	client   Client
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// NewTaskWatcher creates a TaskWatcher checking tasks with client every interval, for up to timeout.
+func NewTaskWatcher(client Client, interval, timeout time.Duration) *TaskWatcher {
+	return &TaskWatcher{client: client, interval: interval, timeout: timeout}
+}
+
+// WithTaskPolling sets how often and for how long the client polls tasks in AndWait methods.
+func WithTaskPolling(interval, timeout time.Duration) Option {
+	return func(c *defaultClient) {
+		c.tasks = NewTaskWatcher(c, interval, timeout)
+	}
+}
+
+// Wait polls task at uri until it succeeds, fails or the timeout passes.
+// It returns an error unless the task succeeded.
+func (w *TaskWatcher) Wait(ctx context.Context, uri string) (_ *Task, err error) {
+	ctx, span := tracing.Start(ctx, "VaaS task", tracing.KindInternal)
+	span.SetAttribute(attributeTask, uri)
+	polls := 0
+	defer func() {
+		span.SetAttribute(attributePolls, polls)
+		span.End(err)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		polls++
+		task, err := w.client.GetTask(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("cannot check VaaS task %s: %w", uri, err)
+		}
+
+		switch task.Status {
+		case TaskSuccess:
+			return task, nil
+		case TaskFailure:
+			return task, fmt.Errorf("%w: %s: %s", ErrTaskFailed, uri, task.Info)
+		}
+		log.WithField("task", uri).Debugf("VaaS task is %s, waiting", task.Status)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return task, fmt.Errorf("VaaS task %s did not finish: %w", uri, ctx.Err())
+		}
+	}
+}
+
+// GetTask fetches task by its resource URI, as returned in Location header of asynchronous requests.
+func (c *defaultClient) GetTask(ctx context.Context, uri string) (*Task, error) {
+	target, err := c.resolve(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := c.newRequest(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var task Task
+	if _, err := c.doRequest(request, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// AddBackendAndWait adds backend in VaaS director and waits until VaaS applies it.
+// It returns the ID, resource URI and task URI of the backend.
+func (c *defaultClient) AddBackendAndWait(ctx context.Context, backend *Backend, director *Director) (_ RegistrationResult, err error) {
+	ctx, span := startBackendSpan(ctx, "VaaS AddBackendAndWait", backend, director)
+	defer func() { span.End(err) }()
+	ctx, cancel := c.withOperationTimeout(ctx)
+	defer cancel()
+
+	endpoint, err := c.endpoint(ctx, backendPath)
+	if err != nil {
+		return RegistrationResult{}, err
+	}
+	request, err := c.newRequest(ctx, "POST", endpoint, backend)
+	if err != nil {
+		return RegistrationResult{}, err
+	}
+
+	request.Header.Set(preferHeader, respondAsync)
+	response, err := c.doAddBackend(request)
+	if errors.Is(err, ErrConflict) {
+		existing, findErr := c.FindBackend(ctx, director, backend.Address, backend.Port)
+		if findErr != nil {
+			return RegistrationResult{}, fmt.Errorf("%s, but it could not be found: %w", err, findErr)
+		}
+		*backend = *existing
+		return NewRegistrationResult(backend), nil
+	}
+	if err != nil {
+		return RegistrationResult{}, err
+	}
+
+	if response.StatusCode != http.StatusAccepted {
+		rawResponse, err := ioutil.ReadAll(response.Body)
+		if err == nil && len(rawResponse) > 0 {
+			err = c.unmarshal(response, rawResponse, backend)
+		}
+		if location := response.Header.Get("Location"); location != "" {
+			backend.ResourceURI = location
+		}
+		return NewRegistrationResult(backend), err
+	}
+
+	taskURI := response.Header.Get("Location")
+	if taskURI == "" {
+		return c.acceptedBackend(response, backend)
+	}
+	if _, err := c.tasks.Wait(ctx, taskURI); err != nil {
+		return RegistrationResult{}, err
+	}
+	created, err := c.FindBackend(ctx, director, backend.Address, backend.Port)
+	if err != nil {
+		return RegistrationResult{}, fmt.Errorf("backend missing after VaaS task finished: %w", err)
+	}
+	*backend = *created
+	backend.TaskURI = taskURI
+	return NewRegistrationResult(backend), nil
+}
+
+// acceptedBackend returns the result of adding backend from the response of VaaS, which accepted it without a task
+// to wait for. The response has to give the resource URI of the backend.
+func (c *defaultClient) acceptedBackend(response *http.Response, backend *Backend) (RegistrationResult, error) {
+	rawResponse, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return RegistrationResult{}, err
+	}
+	created := *backend
+	created.ID, created.ResourceURI = nil, ""
+	if len(rawResponse) > 0 {
+		if err := c.unmarshal(response, rawResponse, &created); err != nil {
+			return RegistrationResult{}, err
+		}
+	}
+	if created.ResourceURI == "" {
+		return RegistrationResult{}, fmt.Errorf("VaaS accepted backend %s without a task or the backend URI",
+			HostPort(backend.Address, backend.Port))
+	}
+	*backend = created
+	return NewRegistrationResult(backend), nil
+}
+
+// DeleteBackendAndWait removes backend with given id from VaaS director and waits until VaaS applies it.
+func (c *defaultClient) DeleteBackendAndWait(ctx context.Context, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "VaaS DeleteBackendAndWait", tracing.KindInternal)
+	span.SetAttribute(attributeBackendID, id)
+	defer func() { span.End(err) }()
+	ctx, cancel := c.withOperationTimeout(ctx)
+	defer cancel()
+
+	taskURI, err := c.deleteBackend(ctx, id)
+	if err != nil || taskURI == "" {
+		return err
+	}
+
+	_, err = c.tasks.Wait(ctx, taskURI)
+	return err
+}
@@ -0,0 +1,105 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTaskPath = apiPrefixPath + "/task/1/"
+
+func taskServer(t *testing.T, statuses ...string) *httptest.Server {
+	polls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch {
+		case r.URL.Path == testTaskPath:
+			status := statuses[len(statuses)-1]
+			if polls < len(statuses) {
+				status = statuses[polls]
+			}
+			polls++
+			response = Task{Status: status, Info: "task info", ResourceURI: testTaskPath}
+		case r.Method == http.MethodGet:
+			response = BackendList{Objects: []Backend{*createBackendWithUri("/api/v0.1/backend/7/")}}
+		default:
+			assert.Equal(t, respondAsync, r.Header.Get(preferHeader))
+			w.Header().Set("Location", testTaskPath)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(response)
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+}
+
+func TestAddBackendAndWaitPollsTaskUntilSuccess(t *testing.T) {
+	ts := taskServer(t, TaskPending, TaskPending, TaskSuccess)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTaskPolling(time.Millisecond, time.Second))
+
+	backend := createBackend()
+	result, err := client.AddBackendAndWait(context.Background(), backend, createDirector(1))
+
+	require.NoError(t, err)
+	assert.Equal(t, RegistrationResult{ID: 7, ResourceURI: "/api/v0.1/backend/7/", TaskURI: testTaskPath}, result)
+	assert.Equal(t, testTaskPath, backend.TaskURI)
+}
+
+func TestAddBackendAndWaitTakesBackendFromResponseWithoutTask(t *testing.T) {
+	status := http.StatusAccepted
+	body := []byte(`{"id": 8, "resource_uri": "/api/v0.1/backend/8/"}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEqual(t, http.MethodGet, r.Method, "nothing to poll without a task")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTaskPolling(time.Millisecond, time.Second))
+
+	result, err := client.AddBackendAndWait(context.Background(), createBackend(), createDirector(1))
+	require.NoError(t, err)
+	assert.Equal(t, RegistrationResult{ID: 8, ResourceURI: "/api/v0.1/backend/8/"}, result)
+
+	status = http.StatusCreated
+	result, err = client.AddBackendAndWait(context.Background(), createBackend(), createDirector(1))
+	require.NoError(t, err)
+	assert.Equal(t, RegistrationResult{ID: 8, ResourceURI: "/api/v0.1/backend/8/"}, result)
+
+	status, body = http.StatusAccepted, nil
+	_, err = client.AddBackendAndWait(context.Background(), createBackend(), createDirector(1))
+	assert.Error(t, err)
+}
+
+func TestDeleteBackendAndWaitFailsWithTask(t *testing.T) {
+	ts := taskServer(t, TaskPending, TaskFailure)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTaskPolling(time.Millisecond, time.Second))
+
+	err := client.DeleteBackendAndWait(context.Background(), 7)
+
+	assert.True(t, errors.Is(err, ErrTaskFailed))
+	assert.EqualError(t, err, "VaaS task failed: "+testTaskPath+": task info")
+}
+
+func TestTaskWaitTimesOut(t *testing.T) {
+	ts := taskServer(t, TaskPending)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTaskPolling(time.Millisecond, 20*time.Millisecond))
+
+	err := client.DeleteBackendAndWait(context.Background(), 7)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package vaas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Seconds represents a timeout in VaaS API, which encodes them as JSON strings holding decimals.
+// Both strings and numbers are accepted when decoding.
+type Seconds float64
+
+// UnmarshalJSON decodes Seconds from either a JSON number or a JSON string containing a number.
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid VaaS timeout %s: %s", data, err)
+	}
+	*s = Seconds(value)
+	return nil
+}
+
+// MarshalJSON encodes Seconds as a JSON string, like VaaS does.
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(float64(s), 'f', -1, 64))
+}
+
+// TimeProfile represents JSON structure of Time Profile in VaaS API.
+type TimeProfile struct {
+	ID                  ID      `json:"id"`
+	Name                string  `json:"name,omitempty"`
+	Description         string  `json:"description,omitempty"`
+	MaxConnections      int     `json:"max_connections,omitempty"`
+	ConnectTimeout      Seconds `json:"connect_timeout,omitempty"`
+	FirstByteTimeout    Seconds `json:"first_byte_timeout,omitempty"`
+	BetweenBytesTimeout Seconds `json:"between_bytes_timeout,omitempty"`
+	ResourceURI         string  `json:"resource_uri,omitempty"`
+}
+
+// TimeProfileList represents JSON structure of Time Profile list used in responses in VaaS API.
+type TimeProfileList struct {
+	Meta    Meta          `json:"meta,omitempty"`
+	Objects []TimeProfile `json:"objects,omitempty"`
+}
+
+func (l *TimeProfileList) nextPage() *string { return l.Meta.Next }
+
+// ApplyTimeProfile sets connection limits and timeouts of profile on backend, which then stops inheriting
+// the time profile of its director.
+func (b *Backend) ApplyTimeProfile(profile *TimeProfile) {
+	b.InheritTimeProfile = false
+	b.MaxConnections = profile.MaxConnections
+	b.ConnectTimeout = profile.ConnectTimeout
+	b.FirstByteTimeout = profile.FirstByteTimeout
+	b.BetweenBytesTimeout = profile.BetweenBytesTimeout
+}
+
+// ListTimeProfiles returns all time profiles defined in VaaS.
+func (c *defaultClient) ListTimeProfiles(ctx context.Context) ([]TimeProfile, error) {
+	return c.listTimeProfiles(ctx, nil)
+}
+
+// GetTimeProfile finds time profile by name.
+func (c *defaultClient) GetTimeProfile(ctx context.Context, name string) (*TimeProfile, error) {
+	query := url.Values{}
+	query.Set("name", name)
+
+	profiles, err := c.listTimeProfiles(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no time profile with name %s", ErrTimeProfileNotFound, name)
+}
+
+func (c *defaultClient) listTimeProfiles(ctx context.Context, query url.Values) ([]TimeProfile, error) {
+	var profiles []TimeProfile
+	err := c.listAll(ctx, timeProfilePath, query, func() (listPage, func()) {
+		page := &TimeProfileList{}
+		return page, func() { profiles = append(profiles, page.Objects...) }
+	})
+	return profiles, err
+}
@@ -0,0 +1,66 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimeProfileFindsProfileByName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiTimeProfilePath, r.URL.Path)
+		assert.Equal(t, "slow", r.URL.Query().Get("name"))
+
+		data, err := json.Marshal(TimeProfileList{Objects: []TimeProfile{{ID: 3, Name: "slow", FirstByteTimeout: 60}}})
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	profile, err := NewClient(ts.URL, "username", "api-key").GetTimeProfile(context.Background(), "slow")
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(3), profile.ID)
+	assert.Equal(t, Seconds(60), profile.FirstByteTimeout)
+}
+
+func TestGetTimeProfileReturnsNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"objects": []}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	_, err := NewClient(ts.URL, "username", "api-key").GetTimeProfile(context.Background(), "slow")
+
+	require.True(t, errors.Is(err, ErrTimeProfileNotFound))
+}
+
+func TestApplyTimeProfileStopsInheritance(t *testing.T) {
+	backend := createBackend()
+	backend.InheritTimeProfile = true
+
+	backend.ApplyTimeProfile(&TimeProfile{MaxConnections: 10, ConnectTimeout: 0.5, FirstByteTimeout: 5, BetweenBytesTimeout: 1})
+
+	assert.False(t, backend.InheritTimeProfile)
+	assert.Equal(t, 10, backend.MaxConnections)
+	assert.Equal(t, Seconds(0.5), backend.ConnectTimeout)
+	assert.Equal(t, Seconds(5), backend.FirstByteTimeout)
+	assert.Equal(t, Seconds(1), backend.BetweenBytesTimeout)
+}
+
+func TestSecondsAcceptsStringsAndNumbers(t *testing.T) {
+	var profile TimeProfile
+
+	require.NoError(t, json.Unmarshal([]byte(`{"connect_timeout": "0.3", "first_byte_timeout": 5}`), &profile))
+
+	assert.Equal(t, Seconds(0.3), profile.ConnectTimeout)
+	assert.Equal(t, Seconds(5), profile.FirstByteTimeout)
+}
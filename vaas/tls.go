@@ -0,0 +1,74 @@
+package vaas
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WithTLSConfig makes the client use given TLS configuration when connecting to VaaS.
+// Options applied after it, like WithCACertFile, modify a copy of it.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *defaultClient) {
+		c.ownTransport().TLSClientConfig = config.Clone()
+	}
+}
+
+// WithCACertFile makes the client trust only certificate authorities from given PEM file.
+// If the file cannot be used, every request made by the client fails.
+func WithCACertFile(path string) Option {
+	return func(c *defaultClient) {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			c.optionError(fmt.Errorf("unable to read CA certificates: %w", err))
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			c.optionError(fmt.Errorf("no CA certificates found in %s", path))
+			return
+		}
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithClientCert makes the client authenticate to VaaS with a certificate and key from given PEM files.
+// If they cannot be loaded, every request made by the client fails.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *defaultClient) {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			c.optionError(fmt.Errorf("unable to load client certificate: %w", err))
+			return
+		}
+		config := c.tlsConfig()
+		config.Certificates = append(config.Certificates, certificate)
+	}
+}
+
+// WithInsecureSkipVerify disables verification of VaaS certificate. Use it only in lab environments.
+func WithInsecureSkipVerify() Option {
+	return func(c *defaultClient) {
+		log.Warn("VaaS TLS certificate verification is disabled")
+		c.tlsConfig().InsecureSkipVerify = true
+	}
+}
+
+func (c *defaultClient) tlsConfig() *tls.Config {
+	transport := c.ownTransport()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	return transport.TLSClientConfig
+}
+
+// optionError records an error of an option that could not be applied. It will be returned by every request.
+func (c *defaultClient) optionError(err error) {
+	log.Errorf("invalid VaaS client configuration: %s", err)
+	if c.configErr == nil {
+		c.configErr = err
+	}
+}
@@ -0,0 +1,81 @@
+package vaas
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tlsServer(t *testing.T, clientAuth tls.ClientAuthType) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: clientAuth}
+	ts.StartTLS()
+	return ts
+}
+
+// writeServerCert writes certificate and key of test server to PEM files and returns their paths.
+func writeServerCert(t *testing.T, dir string, ts *httptest.Server) (string, string) {
+	certificate := ts.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+	return certFile, keyFile
+}
+
+func TestTLSOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaas-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ts := tlsServer(t, tls.RequireAnyClientCert)
+	defer ts.Close()
+	certFile, keyFile := writeServerCert(t, dir, ts)
+	ctx := context.Background()
+
+	client := NewClient(ts.URL, "username", "api-key", WithCACertFile(certFile))
+	assert.Error(t, client.DeleteBackend(ctx, 1), "client certificate is required")
+
+	client = NewClient(ts.URL, "username", "api-key", WithCACertFile(certFile), WithClientCert(certFile, keyFile))
+	assert.NoError(t, client.DeleteBackend(ctx, 1))
+
+	client = NewClient(ts.URL, "username", "api-key", WithInsecureSkipVerify(), WithClientCert(certFile, keyFile))
+	assert.NoError(t, client.DeleteBackend(ctx, 1))
+
+	client = NewClient(ts.URL, "username", "api-key", WithClientCert(certFile, keyFile))
+	assert.Error(t, client.DeleteBackend(ctx, 1), "server certificate is not trusted")
+}
+
+func TestTLSConfigOption(t *testing.T) {
+	ts := tlsServer(t, tls.NoClientCert)
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key", WithTLSConfig(ts.Client().Transport.(*http.Transport).TLSClientConfig))
+
+	assert.NoError(t, client.DeleteBackend(context.Background(), 1))
+}
+
+func TestInvalidTLSFilesFailRequests(t *testing.T) {
+	client := NewClient("https://vaas.invalid", "username", "api-key", WithCACertFile("/nonexistent/ca.pem"))
+
+	err := client.DeleteBackend(context.Background(), 1)
+
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
@@ -0,0 +1,93 @@
+package vaas
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestTiming holds durations of connection phases of a single VaaS API request.
+// Phases that did not happen, e.g. DNS lookup on a reused connection, are zero.
+type RequestTiming struct {
+	DNSLookup        time.Duration
+	Connect          time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration
+	Total            time.Duration
+	ReusedConnection bool
+}
+
+// TimingReporter receives timings of every request made by the client.
+type TimingReporter func(request *http.Request, timing RequestTiming)
+
+// WithTrace makes the client record connection phase timings of every request and pass them to reporter.
+func WithTrace(reporter TimingReporter) Option {
+	return func(c *defaultClient) {
+		c.timingReporter = reporter
+	}
+}
+
+// LogTiming is a TimingReporter writing timings to the debug log.
+func LogTiming(request *http.Request, timing RequestTiming) {
+	log.WithFields(log.Fields{
+		"method":            request.Method,
+		"path":              request.URL.Path,
+		"dns":               timing.DNSLookup,
+		"connect":           timing.Connect,
+		"tls":               timing.TLSHandshake,
+		"server_processing": timing.ServerProcessing,
+		"total":             timing.Total,
+		"reused":            timing.ReusedConnection,
+	}).Debug("VaaS request timing")
+}
+
+// requestTracer collects RequestTiming from httptrace callbacks.
+type requestTracer struct {
+	start, dnsStart, connectStart, tlsStart, wroteRequest time.Time
+	timing                                                RequestTiming
+}
+
+func (t *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.timing.DNSLookup = time.Since(t.dnsStart) },
+		ConnectStart: func(string, string) {
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.timing.Connect = time.Since(t.connectStart)
+		},
+		TLSHandshakeStart: func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.timing.TLSHandshake = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) { t.timing.ReusedConnection = info.Reused },
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.timing.ServerProcessing = time.Since(t.wroteRequest)
+		},
+	}
+}
+
+// traced returns request instrumented with tracer when the client has a timing reporter configured.
+func (c *defaultClient) traced(request *http.Request) (*http.Request, *requestTracer) {
+	if c.timingReporter == nil {
+		return request, nil
+	}
+	tracer := &requestTracer{start: time.Now()}
+	ctx := httptrace.WithClientTrace(request.Context(), tracer.clientTrace())
+	return request.WithContext(ctx), tracer
+}
+
+func (c *defaultClient) reportTiming(request *http.Request, tracer *requestTracer) {
+	if tracer == nil {
+		return
+	}
+	tracer.timing.Total = time.Since(tracer.start)
+	c.timingReporter(request, tracer.timing)
+}
@@ -0,0 +1,34 @@
+package vaas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceReportsRequestTiming(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	var timings []RequestTiming
+	client := NewClient(ts.URL, "username", "api-key", WithTrace(func(request *http.Request, timing RequestTiming) {
+		assert.Equal(t, http.MethodDelete, request.Method)
+		timings = append(timings, timing)
+	}))
+
+	require.NoError(t, client.DeleteBackend(context.Background(), 1))
+	require.NoError(t, client.DeleteBackend(context.Background(), 2))
+
+	require.Len(t, timings, 2)
+	assert.True(t, timings[0].ServerProcessing >= 5*time.Millisecond)
+	assert.True(t, timings[0].Total >= timings[0].ServerProcessing)
+	assert.True(t, timings[0].Connect > 0)
+}
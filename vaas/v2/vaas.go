@@ -0,0 +1,71 @@
+// Package vaas is the versioned import path of the VaaS client API whose methods take a context and fail with
+// typed errors, for tools migrating from LegacyClient of the unversioned package. Types and errors are aliases
+// of those of the unversioned package, so values, options and errors.Is matching are shared between both.
+package vaas
+
+import (
+	api "github.com/allegro/vaas-registration-hook/vaas"
+)
+
+// Client is an interface for VaaS API, with methods taking a context.
+type Client = api.Client
+
+// Option configures a Client, e.g. api.WithRetryPolicy or api.WithTimeout of the unversioned package.
+type Option = api.Option
+
+// Types of VaaS API objects.
+type (
+	Backend      = api.Backend
+	BackendPatch = api.BackendPatch
+	Director     = api.Director
+	DC           = api.DC
+	ID           = api.ID
+	Task         = api.Task
+	APIError     = api.APIError
+
+	RegistrationResult = api.RegistrationResult
+	BackendIterator    = api.BackendIterator
+)
+
+// Errors returned by the client, to be matched with errors.Is.
+var (
+	ErrUnauthorized        = api.ErrUnauthorized
+	ErrDirectorNotFound    = api.ErrDirectorNotFound
+	ErrBackendNotFound     = api.ErrBackendNotFound
+	ErrDCNotFound          = api.ErrDCNotFound
+	ErrTimeProfileNotFound = api.ErrTimeProfileNotFound
+	ErrProbeNotFound       = api.ErrProbeNotFound
+	ErrClusterNotFound     = api.ErrClusterNotFound
+	ErrConflict            = api.ErrConflict
+	ErrCircuitOpen         = api.ErrCircuitOpen
+	ErrTaskFailed          = api.ErrTaskFailed
+	ErrMaintenance         = api.ErrMaintenance
+	ErrDirectorLocked      = api.ErrDirectorLocked
+	ErrInvalidResourceURI  = api.ErrInvalidResourceURI
+	ErrUnknownFields       = api.ErrUnknownFields
+)
+
+// NewClient creates new REST client for VaaS API.
+func NewClient(hostname string, username string, apiKey string, options ...Option) Client {
+	return api.NewClient(hostname, username, apiKey, options...)
+}
+
+// NewID returns a pointer to an ID with value.
+func NewID(value int) *ID {
+	return api.NewID(value)
+}
+
+// BackendIDFromURI returns the ID of a backend from its resource URI or a Location header naming it.
+func BackendIDFromURI(uri string) (int, error) {
+	return api.BackendIDFromURI(uri)
+}
+
+// DirectorIDFromURI returns the ID of a director from its resource URI or a Location header naming it.
+func DirectorIDFromURI(uri string) (int, error) {
+	return api.DirectorIDFromURI(uri)
+}
+
+// NewSliceBackendIterator returns a BackendIterator over backends, e.g. for fakes of Client.
+func NewSliceBackendIterator(backends []Backend) BackendIterator {
+	return api.NewSliceBackendIterator(backends)
+}
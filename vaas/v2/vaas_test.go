@@ -0,0 +1,28 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	api "github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestClientSharesOptionsAndErrorsWithUnversionedPackage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"objects": []}`))
+	}))
+	defer ts.Close()
+
+	var client api.Client = NewClient(ts.URL, "username", "api-key", api.WithRetryPolicy(api.DefaultRetryPolicy))
+	_, err := client.FindDirector(context.Background(), "director")
+
+	require.True(t, errors.Is(err, ErrDirectorNotFound))
+	require.True(t, errors.Is(err, api.ErrDirectorNotFound))
+	assert.Equal(t, api.NewID(7), NewID(7))
+}
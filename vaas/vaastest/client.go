@@ -0,0 +1,987 @@
+// Package vaastest provides fakes of VaaS for testing code built on the vaas package:
+// an in-memory implementation of vaas.Client and an HTTP server imitating VaaS API.
+package vaastest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+var _ vaas.Client = (*Client)(nil)
+
+// Client is an in-memory implementation of vaas.Client.
+// It keeps directors, DCs and backends in memory and records every call, and it can be scripted to fail calls.
+// It is safe for concurrent use.
+type Client struct {
+	mu        sync.Mutex
+	directors []vaas.Director
+	dcs       []vaas.DC
+	backends  []vaas.Backend
+	profiles  []vaas.TimeProfile
+	probes    []vaas.Probe
+	clusters  []vaas.Cluster
+	routes    []vaas.Route
+	redirects []vaas.Redirect
+	reloads   map[string]vaas.ReloadStatus
+	errors    map[string]error
+	calls     []string
+	lastID    int
+}
+
+// NewClient creates an empty in-memory client.
+func NewClient() *Client {
+	return &Client{errors: map[string]error{}, reloads: map[string]vaas.ReloadStatus{}}
+}
+
+// AddDirector adds a director with given name.
+func (c *Client) AddDirector(name string) vaas.Director {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextID()
+	director := vaas.Director{ID: id, Name: name, ResourceURI: resourceURI(directorPath, id)}
+	c.directors = append(c.directors, director)
+	return director
+}
+
+// AddDC adds a DC with given symbol.
+func (c *Client) AddDC(symbol string) vaas.DC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextID()
+	dc := vaas.DC{ID: id, Name: symbol, Symbol: symbol, ResourceURI: resourceURI(dcPath, id)}
+	c.dcs = append(c.dcs, dc)
+	return dc
+}
+
+// AddTimeProfile adds profile, assigning it an ID and resource URI.
+func (c *Client) AddTimeProfile(profile vaas.TimeProfile) vaas.TimeProfile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	profile.ID = c.nextID()
+	profile.ResourceURI = resourceURI(timeProfilePath, profile.ID)
+	c.profiles = append(c.profiles, profile)
+	return profile
+}
+
+// AddCluster adds a logical cluster with given name.
+func (c *Client) AddCluster(name string) vaas.Cluster {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextID()
+	cluster := vaas.Cluster{ID: id, Name: name, ResourceURI: resourceURI(clusterPath, id)}
+	c.clusters = append(c.clusters, cluster)
+	return cluster
+}
+
+// AddProbe adds probe, assigning it an ID and resource URI.
+func (c *Client) AddProbe(probe vaas.Probe) vaas.Probe {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addProbe(&probe)
+	return probe
+}
+
+func (c *Client) addProbe(probe *vaas.Probe) {
+	probe.ID = c.nextID()
+	probe.ResourceURI = resourceURI(probePath, probe.ID)
+	c.probes = append(c.probes, *probe)
+}
+
+// Backends returns a copy of all backends, ordered by ID.
+func (c *Client) Backends() []vaas.Backend {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]vaas.Backend(nil), c.backends...)
+}
+
+// Routes returns a copy of all routes, in order of creation.
+func (c *Client) Routes() []vaas.Route {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]vaas.Route(nil), c.routes...)
+}
+
+// FailOn makes every following call of method with given name fail with err. Nil err stops failing.
+func (c *Client) FailOn(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		delete(c.errors, method)
+		return
+	}
+	c.errors[method] = err
+}
+
+// Calls returns names of methods called so far, in order.
+func (c *Client) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.calls...)
+}
+
+// call records a call of method and returns the error it was scripted to fail with. It must be called locked.
+func (c *Client) call(method string) error {
+	c.calls = append(c.calls, method)
+	return c.errors[method]
+}
+
+func (c *Client) nextID() vaas.ID {
+	c.lastID++
+	return vaas.ID(c.lastID)
+}
+
+// FindDirector implements vaas.Client.
+func (c *Client) FindDirector(ctx context.Context, name string) (*vaas.Director, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("FindDirector"); err != nil {
+		return nil, err
+	}
+	return c.findDirector(name)
+}
+
+func (c *Client) findDirector(name string) (*vaas.Director, error) {
+	for _, director := range c.directors {
+		if director.Name == name {
+			return &director, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no Director with name %s", vaas.ErrDirectorNotFound, name)
+}
+
+// FindDirectorID implements vaas.Client.
+func (c *Client) FindDirectorID(ctx context.Context, name string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("FindDirectorID"); err != nil {
+		return 0, err
+	}
+	director, err := c.findDirector(name)
+	if err != nil {
+		return 0, err
+	}
+	return int(director.ID), nil
+}
+
+// CreateDirector implements vaas.Client.
+func (c *Client) CreateDirector(ctx context.Context, director *vaas.Director) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("CreateDirector"); err != nil {
+		return err
+	}
+	if _, err := c.findDirector(director.Name); err == nil {
+		return &vaas.APIError{StatusCode: 409, URL: directorPath, Message: "director already exists"}
+	}
+	director.ID = c.nextID()
+	director.ResourceURI = resourceURI(directorPath, director.ID)
+	c.directors = append(c.directors, *director)
+	return nil
+}
+
+// UpdateDirector implements vaas.Client.
+func (c *Client) UpdateDirector(ctx context.Context, director *vaas.Director) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("UpdateDirector"); err != nil {
+		return err
+	}
+	for i := range c.directors {
+		if c.directors[i].ID == director.ID {
+			c.directors[i] = *director
+			return nil
+		}
+	}
+	return &vaas.APIError{StatusCode: 404, URL: resourceURI(directorPath, director.ID), Message: "not found"}
+}
+
+// DeleteDirector implements vaas.Client.
+func (c *Client) DeleteDirector(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteDirector"); err != nil {
+		return err
+	}
+	for i := range c.directors {
+		if int(c.directors[i].ID) == id {
+			c.directors = append(c.directors[:i], c.directors[i+1:]...)
+			return nil
+		}
+	}
+	return &vaas.APIError{StatusCode: 404, URL: resourceURI(directorPath, vaas.ID(id)), Message: "not found"}
+}
+
+// AddBackend implements vaas.Client. Adding a backend already present in director returns the existing one.
+func (c *Client) AddBackend(ctx context.Context, backend *vaas.Backend, director *vaas.Director) (vaas.RegistrationResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("AddBackend"); err != nil {
+		return vaas.RegistrationResult{}, err
+	}
+	c.addBackend(backend, director)
+	return vaas.NewRegistrationResult(backend), nil
+}
+
+// AddBackendAndWait implements vaas.Client.
+func (c *Client) AddBackendAndWait(ctx context.Context, backend *vaas.Backend, director *vaas.Director) (vaas.RegistrationResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("AddBackendAndWait"); err != nil {
+		return vaas.RegistrationResult{}, err
+	}
+	c.addBackend(backend, director)
+	backend.TaskURI = resourceURI(taskPath, c.nextID())
+	return vaas.NewRegistrationResult(backend), nil
+}
+
+// AddBackends implements vaas.Client.
+func (c *Client) AddBackends(ctx context.Context, backends []*vaas.Backend, director *vaas.Director) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("AddBackends"); err != nil {
+		return nil, err
+	}
+	uris := make([]string, len(backends))
+	for i, backend := range backends {
+		uris[i] = c.addBackend(backend, director)
+	}
+	return uris, nil
+}
+
+// AddBackendToDirectors implements vaas.Client.
+func (c *Client) AddBackendToDirectors(ctx context.Context, backend *vaas.Backend, directors []string) ([]vaas.DirectorResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("AddBackendToDirectors"); err != nil {
+		return nil, err
+	}
+	results := make([]vaas.DirectorResult, len(directors))
+	for i, name := range directors {
+		results[i].Director = name
+		director, err := c.findDirector(name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		added := *backend
+		results[i].ResourceURI = c.addBackend(&added, director)
+	}
+	return results, vaas.NewDirectorsError(results)
+}
+
+// EnsureBackend implements vaas.Client.
+func (c *Client) EnsureBackend(ctx context.Context, backend *vaas.Backend, director *vaas.Director) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("EnsureBackend"); err != nil {
+		return false, err
+	}
+	_, created := c.ensureBackend(backend, director)
+	return created, nil
+}
+
+func (c *Client) addBackend(backend *vaas.Backend, director *vaas.Director) string {
+	uri, _ := c.ensureBackend(backend, director)
+	return uri
+}
+
+// ensureBackend stores backend unless it exists, returning its resource URI and whether it was created.
+func (c *Client) ensureBackend(backend *vaas.Backend, director *vaas.Director) (string, bool) {
+	if existing := c.findBackend(director, backend.Address, backend.Port); existing != nil {
+		*backend = *existing
+		return existing.ResourceURI, false
+	}
+
+	id := c.nextID()
+	backend.ID = &id
+	backend.DirectorURL = director.ResourceURI
+	backend.ResourceURI = resourceURI(backendPath, id)
+	c.backends = append(c.backends, *backend)
+	return backend.ResourceURI, true
+}
+
+// UpsertBackend implements vaas.Client.
+func (c *Client) UpsertBackend(ctx context.Context, backend *vaas.Backend) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("UpsertBackend"); err != nil {
+		return err
+	}
+	if backend.ID == nil {
+		id := c.nextID()
+		backend.ID = &id
+	} else if int(*backend.ID) > c.lastID {
+		c.lastID = int(*backend.ID)
+	}
+	backend.ResourceURI = resourceURI(backendPath, *backend.ID)
+
+	if index := c.backendIndex(int(*backend.ID)); index >= 0 {
+		c.backends[index] = *backend
+		return nil
+	}
+	c.backends = append(c.backends, *backend)
+	sort.Slice(c.backends, func(i, j int) bool { return *c.backends[i].ID < *c.backends[j].ID })
+	return nil
+}
+
+// DeleteBackend implements vaas.Client. Deleting a non-existent backend is not an error, like in VaaS client.
+func (c *Client) DeleteBackend(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteBackend"); err != nil {
+		return err
+	}
+	c.deleteBackend(id)
+	return nil
+}
+
+// DeleteBackendAndWait implements vaas.Client.
+func (c *Client) DeleteBackendAndWait(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteBackendAndWait"); err != nil {
+		return err
+	}
+	c.deleteBackend(id)
+	return nil
+}
+
+// DeleteBackendByAddress implements vaas.Client.
+func (c *Client) DeleteBackendByAddress(ctx context.Context, director string, address string, port int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteBackendByAddress"); err != nil {
+		return err
+	}
+	return c.deleteBackendsByAddress(director, address, port)
+}
+
+// DeleteBackendByAddressAndWait implements vaas.Client.
+func (c *Client) DeleteBackendByAddressAndWait(ctx context.Context, director string, address string, port int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteBackendByAddressAndWait"); err != nil {
+		return err
+	}
+	return c.deleteBackendsByAddress(director, address, port)
+}
+
+// DeleteBackendsWhere implements vaas.Client.
+func (c *Client) DeleteBackendsWhere(ctx context.Context, directorName string, filter vaas.BackendFilter) ([]vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteBackendsWhere"); err != nil {
+		return nil, err
+	}
+	if filter.IsEmpty() {
+		return nil, vaas.ErrEmptyFilter
+	}
+	director, err := c.findDirector(directorName)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []vaas.Backend
+	for _, backend := range append([]vaas.Backend{}, c.backends...) {
+		if backend.DirectorURL == director.ResourceURI && filter.Matches(backend) {
+			c.deleteBackend(int(*backend.ID))
+			deleted = append(deleted, backend)
+		}
+	}
+	return deleted, nil
+}
+
+func (c *Client) deleteBackendsByAddress(directorName string, address string, port int) error {
+	director, err := c.findDirector(directorName)
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for backend := c.findBackend(director, address, port); backend != nil; backend = c.findBackend(director, address, port) {
+		c.deleteBackend(int(*backend.ID))
+		deleted++
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: no backend %s in director %s", vaas.ErrBackendNotFound, vaas.HostPort(address, port), directorName)
+	}
+	return nil
+}
+
+func (c *Client) deleteBackend(id int) {
+	if index := c.backendIndex(id); index >= 0 {
+		c.backends = append(c.backends[:index], c.backends[index+1:]...)
+	}
+}
+
+func (c *Client) backendIndex(id int) int {
+	for i, backend := range c.backends {
+		if backend.ID != nil && int(*backend.ID) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetTask implements vaas.Client. Tasks of the in-memory client always succeed.
+func (c *Client) GetTask(ctx context.Context, uri string) (*vaas.Task, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetTask"); err != nil {
+		return nil, err
+	}
+	return &vaas.Task{Status: vaas.TaskSuccess, ResourceURI: uri}, nil
+}
+
+// SetReloadStatus sets the reload status reported for director, by default listing no servers, so that the VCL is live.
+func (c *Client) SetReloadStatus(director string, status vaas.ReloadStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloads[director] = status
+}
+
+// GetReloadStatus implements vaas.Client.
+func (c *Client) GetReloadStatus(ctx context.Context, director *vaas.Director) (*vaas.ReloadStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetReloadStatus"); err != nil {
+		return nil, err
+	}
+	status := c.reloads[director.Name]
+	status.Director = director.Name
+	return &status, nil
+}
+
+// UpdateBackend implements vaas.Client.
+func (c *Client) UpdateBackend(ctx context.Context, id int, patch vaas.BackendPatch) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("UpdateBackend"); err != nil {
+		return err
+	}
+	if patch.Weight != nil && *patch.Weight != vaas.ClampWeight(*patch.Weight) {
+		return fmt.Errorf("weight %d out of range", *patch.Weight)
+	}
+	index := c.backendIndex(id)
+	if index < 0 {
+		return &vaas.APIError{StatusCode: 404, URL: resourceURI(backendPath, vaas.ID(id)), Message: "not found"}
+	}
+	patch.Apply(&c.backends[index])
+	return nil
+}
+
+// SetBackendWeight implements vaas.Client.
+func (c *Client) SetBackendWeight(ctx context.Context, id int, weight int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("SetBackendWeight"); err != nil {
+		return err
+	}
+	if weight != vaas.ClampWeight(weight) {
+		return fmt.Errorf("weight %d out of range", weight)
+	}
+	index := c.backendIndex(id)
+	if index < 0 {
+		return &vaas.APIError{StatusCode: 404, URL: resourceURI(backendPath, vaas.ID(id)), Message: "not found"}
+	}
+	c.backends[index].SetWeight(weight)
+	return nil
+}
+
+// EnableBackend implements vaas.Client.
+func (c *Client) EnableBackend(ctx context.Context, id int) error {
+	return c.setEnabled("EnableBackend", id, true)
+}
+
+// DisableBackend implements vaas.Client.
+func (c *Client) DisableBackend(ctx context.Context, id int) error {
+	return c.setEnabled("DisableBackend", id, false)
+}
+
+func (c *Client) setEnabled(method string, id int, enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(method); err != nil {
+		return err
+	}
+	index := c.backendIndex(id)
+	if index < 0 {
+		return &vaas.APIError{StatusCode: 404, URL: resourceURI(backendPath, vaas.ID(id)), Message: "not found"}
+	}
+	c.backends[index].Enabled = &enabled
+	return nil
+}
+
+// GetBackend implements vaas.Client.
+func (c *Client) GetBackend(ctx context.Context, id int) (*vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetBackend"); err != nil {
+		return nil, err
+	}
+	index := c.backendIndex(id)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: no backend with ID %d", vaas.ErrBackendNotFound, id)
+	}
+	backend := c.backends[index]
+	backend.Tags = append([]string{}, backend.Tags...)
+	return &backend, nil
+}
+
+// AddBackendTags implements vaas.Client.
+func (c *Client) AddBackendTags(ctx context.Context, id int, tags ...string) error {
+	return c.updateTags("AddBackendTags", id, func(current []string) []string {
+		return vaas.MergeTags(current, tags)
+	})
+}
+
+// RemoveBackendTags implements vaas.Client.
+func (c *Client) RemoveBackendTags(ctx context.Context, id int, tags ...string) error {
+	return c.updateTags("RemoveBackendTags", id, func(current []string) []string {
+		return vaas.WithoutTags(current, tags)
+	})
+}
+
+func (c *Client) updateTags(method string, id int, update func(current []string) []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(method); err != nil {
+		return err
+	}
+	index := c.backendIndex(id)
+	if index < 0 {
+		return fmt.Errorf("%w: no backend with ID %d", vaas.ErrBackendNotFound, id)
+	}
+	c.backends[index].Tags = update(c.backends[index].Tags)
+	return nil
+}
+
+// GetDC implements vaas.Client.
+func (c *Client) GetDC(ctx context.Context, name string) (*vaas.DC, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetDC"); err != nil {
+		return nil, err
+	}
+	for _, dc := range c.dcs {
+		if dc.Symbol == name {
+			return &dc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no DC with name %s", vaas.ErrDCNotFound, name)
+}
+
+// ListDCs implements vaas.Client.
+func (c *Client) ListDCs(ctx context.Context) ([]vaas.DC, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListDCs"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.DC(nil), c.dcs...), nil
+}
+
+// ListTimeProfiles implements vaas.Client.
+func (c *Client) ListTimeProfiles(ctx context.Context) ([]vaas.TimeProfile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListTimeProfiles"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.TimeProfile(nil), c.profiles...), nil
+}
+
+// GetTimeProfile implements vaas.Client.
+func (c *Client) GetTimeProfile(ctx context.Context, name string) (*vaas.TimeProfile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetTimeProfile"); err != nil {
+		return nil, err
+	}
+	for _, profile := range c.profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no time profile with name %s", vaas.ErrTimeProfileNotFound, name)
+}
+
+// FindBackend implements vaas.Client.
+func (c *Client) FindBackend(ctx context.Context, director *vaas.Director, address string, port int) (*vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("FindBackend"); err != nil {
+		return nil, err
+	}
+	if backend := c.findBackend(director, address, port); backend != nil {
+		return backend, nil
+	}
+	return nil, fmt.Errorf("%w: no backend %s in director %s", vaas.ErrBackendNotFound, vaas.HostPort(address, port), director.Name)
+}
+
+func (c *Client) findBackend(director *vaas.Director, address string, port int) *vaas.Backend {
+	for _, backend := range c.backends {
+		if backend.DirectorURL == director.ResourceURI && vaas.SameAddress(backend.Address, address) && backend.Port == port {
+			return &backend
+		}
+	}
+	return nil
+}
+
+// FindBackendID implements vaas.Client.
+func (c *Client) FindBackendID(ctx context.Context, director string, address string, port int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("FindBackendID"); err != nil {
+		return 0, err
+	}
+	found, err := c.findDirector(director)
+	if err != nil {
+		return 0, err
+	}
+	backend := c.findBackend(found, address, port)
+	if backend == nil {
+		return 0, fmt.Errorf("%w: no backend %s in director %s", vaas.ErrBackendNotFound, vaas.HostPort(address, port), director)
+	}
+	return int(*backend.ID), nil
+}
+
+// ListDirectors implements vaas.Client.
+func (c *Client) ListDirectors(ctx context.Context) ([]vaas.Director, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListDirectors"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.Director(nil), c.directors...), nil
+}
+
+// ListBackends implements vaas.Client.
+func (c *Client) ListBackends(ctx context.Context, director *vaas.Director) ([]vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListBackends"); err != nil {
+		return nil, err
+	}
+	var backends []vaas.Backend
+	for _, backend := range c.backends {
+		if backend.DirectorURL == director.ResourceURI {
+			backends = append(backends, backend)
+		}
+	}
+	return backends, nil
+}
+
+// ListAllBackends implements vaas.Client.
+func (c *Client) ListAllBackends(ctx context.Context) ([]vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListAllBackends"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.Backend(nil), c.backends...), nil
+}
+
+// IterateBackends implements vaas.Client.
+func (c *Client) IterateBackends(ctx context.Context, director *vaas.Director) vaas.BackendIterator {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("IterateBackends"); err != nil {
+		return failedIterator{err: err}
+	}
+	var backends []vaas.Backend
+	for _, backend := range c.backends {
+		if director == nil || backend.DirectorURL == director.ResourceURI {
+			backends = append(backends, backend)
+		}
+	}
+	return vaas.NewSliceBackendIterator(backends)
+}
+
+// failedIterator is a vaas.BackendIterator failing with err
+type failedIterator struct {
+	err error
+}
+
+func (failedIterator) Next() bool            { return false }
+func (failedIterator) Backend() vaas.Backend { return vaas.Backend{} }
+func (it failedIterator) Err() error         { return it.err }
+func (failedIterator) Close() error          { return nil }
+
+// SearchBackends implements vaas.Client.
+func (c *Client) SearchBackends(ctx context.Context, query vaas.BackendQuery) ([]vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("SearchBackends"); err != nil {
+		return nil, err
+	}
+	directors := map[string]string{}
+	for _, director := range c.directors {
+		directors[director.ResourceURI] = director.Name
+	}
+	var backends []vaas.Backend
+	for _, backend := range c.backends {
+		if !query.Matches(backend) {
+			continue
+		}
+		if query.DirectorRegex != nil && !query.DirectorRegex.MatchString(directors[backend.DirectorURL]) {
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// ValidateCredentials implements vaas.Client.
+func (c *Client) ValidateCredentials(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.call("ValidateCredentials")
+}
+
+// WaitIdle implements vaas.Client. The in-memory client is always idle.
+func (c *Client) WaitIdle(ctx context.Context) error {
+	return nil
+}
+
+// ListClusters implements vaas.Client.
+func (c *Client) ListClusters(ctx context.Context) ([]vaas.Cluster, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListClusters"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.Cluster(nil), c.clusters...), nil
+}
+
+// GetCluster implements vaas.Client.
+func (c *Client) GetCluster(ctx context.Context, name string) (*vaas.Cluster, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetCluster"); err != nil {
+		return nil, err
+	}
+	for _, cluster := range c.clusters {
+		if cluster.Name == name {
+			return &cluster, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no cluster with name %s", vaas.ErrClusterNotFound, name)
+}
+
+// ListClusterDirectors implements vaas.Client.
+func (c *Client) ListClusterDirectors(ctx context.Context, cluster *vaas.Cluster) ([]vaas.Director, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListClusterDirectors"); err != nil {
+		return nil, err
+	}
+	return c.clusterDirectors(cluster), nil
+}
+
+func (c *Client) clusterDirectors(cluster *vaas.Cluster) []vaas.Director {
+	var directors []vaas.Director
+	for i := range c.directors {
+		if c.directors[i].InCluster(cluster) {
+			directors = append(directors, c.directors[i])
+		}
+	}
+	return directors
+}
+
+// ListClusterBackends implements vaas.Client.
+func (c *Client) ListClusterBackends(ctx context.Context, cluster *vaas.Cluster) ([]vaas.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListClusterBackends"); err != nil {
+		return nil, err
+	}
+	var backends []vaas.Backend
+	for _, director := range c.clusterDirectors(cluster) {
+		for _, backend := range c.backends {
+			if backend.DirectorURL == director.ResourceURI {
+				backends = append(backends, backend)
+			}
+		}
+	}
+	return backends, nil
+}
+
+// ListProbes implements vaas.Client.
+func (c *Client) ListProbes(ctx context.Context) ([]vaas.Probe, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListProbes"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.Probe(nil), c.probes...), nil
+}
+
+// GetProbe implements vaas.Client.
+func (c *Client) GetProbe(ctx context.Context, name string) (*vaas.Probe, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("GetProbe"); err != nil {
+		return nil, err
+	}
+	for _, probe := range c.probes {
+		if probe.Name == name {
+			return &probe, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no probe with name %s", vaas.ErrProbeNotFound, name)
+}
+
+// CreateProbe implements vaas.Client.
+func (c *Client) CreateProbe(ctx context.Context, probe *vaas.Probe) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("CreateProbe"); err != nil {
+		return err
+	}
+	c.addProbe(probe)
+	return nil
+}
+
+// ListRoutes implements vaas.Client.
+func (c *Client) ListRoutes(ctx context.Context, director *vaas.Director) ([]vaas.Route, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListRoutes"); err != nil {
+		return nil, err
+	}
+	var routes []vaas.Route
+	for _, route := range c.routes {
+		if director == nil || route.Director == director.ResourceURI {
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// CreateRoute implements vaas.Client.
+func (c *Client) CreateRoute(ctx context.Context, route *vaas.Route) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("CreateRoute"); err != nil {
+		return err
+	}
+	route.ID = c.nextID()
+	route.ResourceURI = resourceURI(routePath, route.ID)
+	c.routes = append(c.routes, *route)
+	return nil
+}
+
+// DeleteRoute implements vaas.Client.
+func (c *Client) DeleteRoute(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteRoute"); err != nil {
+		return err
+	}
+	for i := range c.routes {
+		if int(c.routes[i].ID) == id {
+			c.routes = append(c.routes[:i], c.routes[i+1:]...)
+			return nil
+		}
+	}
+	return &vaas.APIError{StatusCode: 404, URL: resourceURI(routePath, vaas.ID(id)), Message: "not found"}
+}
+
+// ListRedirects implements vaas.Client.
+func (c *Client) ListRedirects(ctx context.Context) ([]vaas.Redirect, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("ListRedirects"); err != nil {
+		return nil, err
+	}
+	return append([]vaas.Redirect(nil), c.redirects...), nil
+}
+
+// CreateRedirect implements vaas.Client.
+func (c *Client) CreateRedirect(ctx context.Context, redirect *vaas.Redirect) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("CreateRedirect"); err != nil {
+		return err
+	}
+	redirect.ID = c.nextID()
+	redirect.ResourceURI = resourceURI(redirectPath, redirect.ID)
+	c.redirects = append(c.redirects, *redirect)
+	return nil
+}
+
+// DeleteRedirect implements vaas.Client.
+func (c *Client) DeleteRedirect(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("DeleteRedirect"); err != nil {
+		return err
+	}
+	for i := range c.redirects {
+		if int(c.redirects[i].ID) == id {
+			c.redirects = append(c.redirects[:i], c.redirects[i+1:]...)
+			return nil
+		}
+	}
+	return &vaas.APIError{StatusCode: 404, URL: resourceURI(redirectPath, vaas.ID(id)), Message: "not found"}
+}
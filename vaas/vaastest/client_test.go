@@ -0,0 +1,67 @@
+package vaastest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestInMemoryClientKeepsBackends(t *testing.T) {
+	client := NewClient()
+	director := client.AddDirector("director")
+	ctx := context.Background()
+
+	backend := vaas.Backend{Address: "10.0.0.1", Port: 80}
+	uri, err := client.AddBackend(ctx, &backend, &director)
+	require.NoError(t, err)
+
+	again := vaas.Backend{Address: "10.0.0.1", Port: 80}
+	sameURI, err := client.AddBackend(ctx, &again, &director)
+	require.NoError(t, err)
+	assert.Equal(t, uri, sameURI)
+
+	id, err := client.FindBackendID(ctx, "director", "10.0.0.1", 80)
+	require.NoError(t, err)
+	require.NoError(t, client.DeleteBackend(ctx, id))
+
+	backends, err := client.ListBackends(ctx, &director)
+	require.NoError(t, err)
+	assert.Empty(t, backends)
+	assert.Equal(t, []string{"AddBackend", "AddBackend", "FindBackendID", "DeleteBackend", "ListBackends"}, client.Calls())
+}
+
+func TestInMemoryClientFailsScriptedCalls(t *testing.T) {
+	client := NewClient()
+	client.AddDC("dc1")
+	failure := errors.New("failure")
+
+	client.FailOn("GetDC", failure)
+	_, err := client.GetDC(context.Background(), "dc1")
+	assert.Equal(t, failure, err)
+
+	client.FailOn("GetDC", nil)
+	dc, err := client.GetDC(context.Background(), "dc1")
+	require.NoError(t, err)
+	assert.Equal(t, "dc1", dc.Symbol)
+}
+
+func TestInMemoryClientWorksWithReconcile(t *testing.T) {
+	client := NewClient()
+	director := client.AddDirector("director")
+	ctx := context.Background()
+	_, err := client.AddBackend(ctx, &vaas.Backend{Address: "10.0.0.1", Port: 80}, &director)
+	require.NoError(t, err)
+
+	desired := []vaas.Backend{{Address: "10.0.0.2", Port: 80}}
+	_, err = vaas.Reconcile(ctx, client, "director", desired, vaas.ReconcileOptions{})
+	require.NoError(t, err)
+
+	backends := client.Backends()
+	require.Len(t, backends, 1)
+	assert.Equal(t, "10.0.0.2", backends[0].Address)
+}
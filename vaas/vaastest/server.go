@@ -0,0 +1,478 @@
+package vaastest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	apiPrefix       = "/api/v0.1"
+	backendPath     = apiPrefix + "/backend/"
+	dcPath          = apiPrefix + "/dc/"
+	directorPath    = apiPrefix + "/director/"
+	taskPath        = apiPrefix + "/task/"
+	routePath       = apiPrefix + "/route/"
+	probePath       = apiPrefix + "/probe/"
+	clusterPath     = apiPrefix + "/cluster/"
+	redirectPath    = apiPrefix + "/redirect/"
+	timeProfilePath = apiPrefix + "/time_profile/"
+	defaultLimit    = 20
+	locationHeader  = "Location"
+)
+
+func resourceURI(path string, id vaas.ID) string {
+	return fmt.Sprintf("%s%d/", path, id)
+}
+
+// Server is an HTTP server imitating VaaS API, backed by in-memory directors, DCs, time profiles and backends.
+// It supports listing with tastypie filters and pagination, backend creation, modification and removal,
+// and asynchronous tasks, which always succeed.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	username  string
+	apiKey    string
+	pageSize  int
+	directors []vaas.Director
+	dcs       []vaas.DC
+	backends  []vaas.Backend
+	profiles  []vaas.TimeProfile
+	tasks     map[string]vaas.Task
+	requests  []string
+	lastID    int
+}
+
+// NewServer starts a fake VaaS API server. It should be closed when no longer needed.
+func NewServer() *Server {
+	server := &Server{pageSize: defaultLimit, tasks: map[string]vaas.Task{}}
+	server.Server = httptest.NewServer(server)
+	return server
+}
+
+// SetCredentials makes the server reject requests with username or API key different from given ones.
+func (s *Server) SetCredentials(username, apiKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.username, s.apiKey = username, apiKey
+}
+
+// SetPageSize sets the default number of objects on a page of list endpoints.
+func (s *Server) SetPageSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pageSize = size
+}
+
+// AddDirector adds a director with given name.
+func (s *Server) AddDirector(name string) vaas.Director {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID()
+	director := vaas.Director{ID: id, Name: name, ResourceURI: resourceURI(directorPath, id)}
+	s.directors = append(s.directors, director)
+	return director
+}
+
+// AddDC adds a DC with given symbol.
+func (s *Server) AddDC(symbol string) vaas.DC {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID()
+	dc := vaas.DC{ID: id, Name: symbol, Symbol: symbol, ResourceURI: resourceURI(dcPath, id)}
+	s.dcs = append(s.dcs, dc)
+	return dc
+}
+
+// AddBackend adds backend as if it was registered in VaaS before, assigning it an ID unless it has one.
+func (s *Server) AddBackend(backend vaas.Backend) vaas.Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.storeBackend(backend)
+}
+
+// AddTimeProfile adds profile, assigning it an ID and resource URI.
+func (s *Server) AddTimeProfile(profile vaas.TimeProfile) vaas.TimeProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile.ID = s.nextID()
+	profile.ResourceURI = resourceURI(timeProfilePath, profile.ID)
+	s.profiles = append(s.profiles, profile)
+	return profile
+}
+
+// Backends returns a copy of all backends.
+func (s *Server) Backends() []vaas.Backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]vaas.Backend(nil), s.backends...)
+}
+
+// Requests returns method and path of every request received so far, e.g. "POST /api/v0.1/backend/".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) nextID() vaas.ID {
+	s.lastID++
+	return vaas.ID(s.lastID)
+}
+
+// authorized checks credentials sent in the Authorization header or, like tastypie does, in the query.
+func (s *Server) authorized(r *http.Request) bool {
+	if header := r.Header.Get("Authorization"); header != "" {
+		return header == fmt.Sprintf("ApiKey %s:%s", s.username, s.apiKey)
+	}
+	query := r.URL.Query()
+	return query.Get("username") == s.username && query.Get("api_key") == s.apiKey
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if s.apiKey != "" && !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	switch {
+	case r.URL.Path == directorPath && r.Method == http.MethodGet:
+		s.listDirectors(w, r)
+	case r.URL.Path == directorPath && r.Method == http.MethodPost:
+		s.createDirector(w, r)
+	case strings.HasPrefix(r.URL.Path, directorPath):
+		s.serveDirector(w, r)
+	case r.URL.Path == dcPath && r.Method == http.MethodGet:
+		s.listDCs(w, r)
+	case r.URL.Path == backendPath && r.Method == http.MethodGet:
+		s.listBackends(w, r)
+	case r.URL.Path == backendPath && r.Method == http.MethodPost:
+		s.createBackend(w, r)
+	case strings.HasPrefix(r.URL.Path, backendPath):
+		s.serveBackend(w, r)
+	case r.URL.Path == timeProfilePath && r.Method == http.MethodGet:
+		s.listTimeProfiles(w, r)
+	case strings.HasPrefix(r.URL.Path, taskPath) && r.Method == http.MethodGet:
+		s.getTask(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) listDirectors(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	var directors []vaas.Director
+	for _, director := range s.directors {
+		if name == "" || director.Name == name {
+			directors = append(directors, director)
+		}
+	}
+
+	meta, from, to := s.paginate(r, len(directors))
+	writeJSON(w, http.StatusOK, vaas.DirectorList{Meta: meta, Objects: directors[from:to]})
+}
+
+func (s *Server) createDirector(w http.ResponseWriter, r *http.Request) {
+	var director vaas.Director
+	if err := decodeBody(r, &director); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if director.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	for _, existing := range s.directors {
+		if existing.Name == director.Name {
+			writeError(w, http.StatusConflict, "director already exists")
+			return
+		}
+	}
+
+	director.ID = s.nextID()
+	director.ResourceURI = resourceURI(directorPath, director.ID)
+	s.directors = append(s.directors, director)
+	w.Header().Set(locationHeader, director.ResourceURI)
+	writeJSON(w, http.StatusCreated, director)
+}
+
+func (s *Server) serveDirector(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, directorPath), "/"))
+	index := -1
+	for i, director := range s.directors {
+		if err == nil && int(director.ID) == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.directors[index])
+	case http.MethodPut:
+		var director vaas.Director
+		if err := decodeBody(r, &director); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		director.ID = s.directors[index].ID
+		director.ResourceURI = s.directors[index].ResourceURI
+		s.directors[index] = director
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.directors = append(s.directors[:index], s.directors[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) listDCs(w http.ResponseWriter, r *http.Request) {
+	meta, from, to := s.paginate(r, len(s.dcs))
+	writeJSON(w, http.StatusOK, vaas.DCList{Meta: meta, Objects: s.dcs[from:to]})
+}
+
+func (s *Server) listTimeProfiles(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	var profiles []vaas.TimeProfile
+	for _, profile := range s.profiles {
+		if name == "" || profile.Name == name {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	meta, from, to := s.paginate(r, len(profiles))
+	writeJSON(w, http.StatusOK, vaas.TimeProfileList{Meta: meta, Objects: profiles[from:to]})
+}
+
+func (s *Server) listBackends(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var backends []vaas.Backend
+	for _, backend := range s.backends {
+		if matchesFilters(backend, query) {
+			backends = append(backends, backend)
+		}
+	}
+
+	meta, from, to := s.paginate(r, len(backends))
+	writeJSON(w, http.StatusOK, vaas.BackendList{Meta: meta, Objects: backends[from:to]})
+}
+
+func matchesFilters(backend vaas.Backend, query url.Values) bool {
+	if director := query.Get("director"); director != "" && backend.DirectorURL != directorPath+director+"/" {
+		return false
+	}
+	if address := query.Get("address"); address != "" && backend.Address != address {
+		return false
+	}
+	if port := query.Get("port"); port != "" && strconv.Itoa(backend.Port) != port {
+		return false
+	}
+	return true
+}
+
+// paginate returns list metadata and bounds of the requested page of total objects.
+func (s *Server) paginate(r *http.Request, total int) (vaas.Meta, int, int) {
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = s.pageSize
+	}
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	meta := vaas.Meta{Limit: limit, Offset: offset, TotalCount: total}
+	if end < total {
+		query.Set("offset", strconv.Itoa(end))
+		query.Set("limit", strconv.Itoa(limit))
+		next := r.URL.Path + "?" + query.Encode()
+		meta.Next = &next
+	}
+	return meta, offset, end
+}
+
+func (s *Server) createBackend(w http.ResponseWriter, r *http.Request) {
+	var backend vaas.Backend
+	if err := decodeBody(r, &backend); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if backend.Address == "" || backend.Port == 0 || backend.DirectorURL == "" {
+		writeError(w, http.StatusBadRequest, "address, port and director are required")
+		return
+	}
+	for _, existing := range s.backends {
+		if existing.DirectorURL == backend.DirectorURL && existing.Address == backend.Address && existing.Port == backend.Port {
+			writeError(w, http.StatusConflict, "backend already exists")
+			return
+		}
+	}
+
+	backend.ID = nil
+	backend = s.storeBackend(backend)
+	if s.respondAsync(w, r) {
+		return
+	}
+	w.Header().Set(locationHeader, backend.ResourceURI)
+	writeJSON(w, http.StatusCreated, backend)
+}
+
+func (s *Server) storeBackend(backend vaas.Backend) vaas.Backend {
+	if backend.ID == nil {
+		id := s.nextID()
+		backend.ID = &id
+	} else if int(*backend.ID) > s.lastID {
+		s.lastID = int(*backend.ID)
+	}
+	backend.ResourceURI = resourceURI(backendPath, *backend.ID)
+	if backend.Tags == nil {
+		backend.Tags = []string{}
+	}
+	s.backends = append(s.backends, backend)
+	return backend
+}
+
+func (s *Server) serveBackend(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, backendPath), "/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	index := -1
+	for i, backend := range s.backends {
+		if int(*backend.ID) == id {
+			index = i
+		}
+	}
+	if index < 0 && r.Method != http.MethodPut {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.backends[index])
+	case http.MethodPut:
+		s.putBackend(w, r, id, index)
+	case http.MethodPatch:
+		s.patchBackend(w, r, index)
+	case http.MethodDelete:
+		s.backends = append(s.backends[:index], s.backends[index+1:]...)
+		if !s.respondAsync(w, r) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) putBackend(w http.ResponseWriter, r *http.Request, id, index int) {
+	var backend vaas.Backend
+	if err := decodeBody(r, &backend); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	backend.ID = vaas.NewID(id)
+	backend.ResourceURI = resourceURI(backendPath, *backend.ID)
+	if index >= 0 {
+		s.backends[index] = backend
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.storeBackend(backend))
+}
+
+func (s *Server) patchBackend(w http.ResponseWriter, r *http.Request, index int) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patched := s.backends[index]
+	if err := json.Unmarshal(body, &patched); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	patched.ID = s.backends[index].ID
+	patched.ResourceURI = s.backends[index].ResourceURI
+	s.backends[index] = patched
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// respondAsync responds with a finished task if the client asked for an asynchronous response.
+func (s *Server) respondAsync(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Prefer") != "respond-async" {
+		return false
+	}
+	uri := resourceURI(taskPath, s.nextID())
+	s.tasks[uri] = vaas.Task{Status: vaas.TaskSuccess, ResourceURI: uri}
+	w.Header().Set(locationHeader, uri)
+	w.WriteHeader(http.StatusAccepted)
+	return true
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.tasks[r.URL.Path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data = []byte(fmt.Sprintf(`{"error_message": %q}`, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error_message": message})
+}
@@ -0,0 +1,97 @@
+package vaastest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+func TestClientAgainstServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetPageSize(1)
+	server.AddDirector("other")
+	server.AddDirector("director")
+	dc := server.AddDC("dc1")
+
+	client := vaas.NewClient(server.URL, "username", "api-key")
+	ctx := context.Background()
+
+	director, err := client.FindDirector(ctx, "director")
+	require.NoError(t, err)
+	foundDC, err := client.GetDC(ctx, "dc1")
+	require.NoError(t, err)
+	assert.Equal(t, dc, *foundDC)
+
+	backend := vaas.Backend{Address: "10.0.0.1", Port: 80, DC: dc, DirectorURL: director.ResourceURI}
+	result, err := client.AddBackend(ctx, &backend, director)
+	require.NoError(t, err)
+
+	second := vaas.Backend{Address: "10.0.0.2", Port: 80, DC: dc, DirectorURL: director.ResourceURI}
+	_, err = client.AddBackendAndWait(ctx, &second, director)
+	require.NoError(t, err)
+
+	backends, err := client.ListBackends(ctx, director)
+	require.NoError(t, err)
+	require.Len(t, backends, 2)
+	assert.Equal(t, result.ResourceURI, backends[0].ResourceURI)
+
+	id, err := client.FindBackendID(ctx, "director", "10.0.0.1", 80)
+	require.NoError(t, err)
+	assert.Equal(t, result.ID, id)
+	require.NoError(t, client.SetBackendWeight(ctx, id, 30))
+	assert.Equal(t, 30, server.Backends()[0].GetWeight())
+
+	require.NoError(t, client.DeleteBackendAndWait(ctx, id))
+	_, err = client.FindBackend(ctx, director, "10.0.0.1", 80)
+	assert.True(t, errors.Is(err, vaas.ErrBackendNotFound))
+	assert.Len(t, server.Backends(), 1)
+}
+
+func TestClientDeletesBackendsWhereFilterMatches(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	director := server.AddDirector("director")
+	server.AddBackend(vaas.Backend{Address: "10.0.0.1", Port: 80, DirectorURL: director.ResourceURI, Tags: []string{"canary"}})
+	server.AddBackend(vaas.Backend{Address: "10.0.0.2", Port: 80, DirectorURL: director.ResourceURI})
+	server.AddBackend(vaas.Backend{Address: "10.0.0.3", Port: 80, DirectorURL: director.ResourceURI, Tags: []string{"canary"}})
+
+	client := vaas.NewClient(server.URL, "username", "api-key")
+	_, err := client.DeleteBackendsWhere(context.Background(), "director", vaas.BackendFilter{})
+	require.True(t, errors.Is(err, vaas.ErrEmptyFilter))
+
+	deleted, err := client.DeleteBackendsWhere(context.Background(), "director", vaas.BackendFilter{Tag: "canary"})
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+	assert.Equal(t, "10.0.0.3", deleted[1].Address)
+	require.Len(t, server.Backends(), 1)
+	assert.Equal(t, "10.0.0.2", server.Backends()[0].Address)
+}
+
+func TestServerRejectsInvalidCredentials(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.SetCredentials("username", "api-key")
+
+	assert.NoError(t, vaas.NewClient(server.URL, "username", "api-key").ValidateCredentials(context.Background()))
+
+	err := vaas.NewClient(server.URL, "username", "wrong").ValidateCredentials(context.Background())
+	assert.True(t, errors.Is(err, vaas.ErrUnauthorized))
+}
+
+func TestServerUpsertsBackendWithID(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	backend := vaas.Backend{ID: vaas.NewID(42), Address: "10.0.0.1", Port: 80}
+	require.NoError(t, vaas.NewClient(server.URL, "username", "api-key").UpsertBackend(context.Background(), &backend))
+
+	require.Len(t, server.Backends(), 1)
+	assert.Equal(t, vaas.ID(42), *server.Backends()[0].ID)
+	assert.Equal(t, []string{"PUT /api/v0.1/backend/42/"}, server.Requests())
+}
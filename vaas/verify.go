@@ -0,0 +1,94 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults of VerifyOptions.
+const (
+	DefaultVerifyInterval = 2 * time.Second
+	DefaultVerifyTimeout  = time.Minute
+)
+
+// ErrNotVerified is returned by VerifyBackend when the backend did not appear in VaaS before the timeout.
+var ErrNotVerified = errors.New("backend not verified in VaaS")
+
+// VerifyOptions configures VerifyBackend.
+type VerifyOptions struct {
+	// Interval is the delay between checks, DefaultVerifyInterval when zero.
+	Interval time.Duration
+	// Timeout is how long the backend has to appear, DefaultVerifyTimeout when zero.
+	Timeout time.Duration
+	// DirectorList requires the backend to be listed among backends of the director as well.
+	DirectorList bool
+}
+
+// VerifyBackend queries VaaS until the backend at address and port is found in director and enabled, and with
+// DirectorList also listed among backends of the director, returning ErrNotVerified after the timeout.
+// A successful AddBackend does not guarantee that the backend reached the configuration of Varnish.
+func VerifyBackend(ctx context.Context, client Client, director *Director, address string, port int,
+	opts VerifyOptions) error {
+	interval, timeout := opts.Interval, opts.Timeout
+	if interval <= 0 {
+		interval = DefaultVerifyInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultVerifyTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for attempt := 1; ; attempt++ {
+		reason := verifyBackend(ctx, client, director, address, port, opts.DirectorList)
+		if reason == nil {
+			log.WithContext(ctx).Infof("Verified backend %s in director %q", HostPort(address, port), director.Name)
+			return nil
+		}
+		log.WithContext(ctx).Debugf("Backend %s not verified yet (attempt %d): %s", HostPort(address, port), attempt, reason)
+
+		select {
+		case <-time.After(interval):
+		case <-deadline.C:
+			return fmt.Errorf("%w: %s in director %s after %s: %s",
+				ErrNotVerified, HostPort(address, port), director.Name, timeout, reason)
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s in director %s: %s", ErrNotVerified, HostPort(address, port), director.Name, ctx.Err())
+		}
+	}
+}
+
+// IsEnabled returns whether the backend is enabled, which it is unless VaaS reports otherwise.
+func (b *Backend) IsEnabled() bool {
+	return b.Enabled == nil || *b.Enabled
+}
+
+// verifyBackend checks the backend once, returning why it is not verified yet
+func verifyBackend(ctx context.Context, client Client, director *Director, address string, port int,
+	directorList bool) error {
+	backend, err := client.FindBackend(ctx, director, address, port)
+	if err != nil {
+		return err
+	}
+	if !backend.IsEnabled() {
+		return errors.New("backend is disabled")
+	}
+	if !directorList {
+		return nil
+	}
+
+	backends, err := client.ListBackends(ctx, director)
+	if err != nil {
+		return err
+	}
+	for _, listed := range backends {
+		if SameAddress(listed.Address, address) && listed.Port == port {
+			return nil
+		}
+	}
+	return errors.New("backend not listed in director")
+}
@@ -0,0 +1,70 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBackendWaitsUntilEnabled(t *testing.T) {
+	var lookups int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enabled := atomic.AddInt32(&lookups, 1) > 2
+		backend := reconcileBackend(1, "10.0.0.1", 1)
+		backend.Enabled = &enabled
+		data, _ := json.Marshal(BackendList{Objects: []Backend{backend}})
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := VerifyBackend(context.Background(), client, createDirector(1), "10.0.0.1", 80,
+		VerifyOptions{Interval: time.Millisecond, Timeout: time.Second})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&lookups))
+}
+
+func TestVerifyBackendFailsAfterTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(BackendList{})
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	err := VerifyBackend(context.Background(), client, createDirector(1), "10.0.0.1", 80,
+		VerifyOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond, DirectorList: true})
+
+	require.True(t, errors.Is(err, ErrNotVerified))
+	assert.Contains(t, err.Error(), "backend not found")
+}
+
+func TestVerifyBackendRequiresDirectorListing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backends := []Backend{reconcileBackend(1, "10.0.0.1", 1)}
+		if r.URL.Query().Get("address") == "" {
+			backends = nil
+		}
+		data, _ := json.Marshal(BackendList{Objects: backends})
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+	opts := VerifyOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond}
+	require.NoError(t, VerifyBackend(context.Background(), client, createDirector(1), "10.0.0.1", 80, opts))
+
+	opts.DirectorList = true
+	err := VerifyBackend(context.Background(), client, createDirector(1), "10.0.0.1", 80, opts)
+	require.True(t, errors.Is(err, ErrNotVerified))
+	assert.Contains(t, err.Error(), "not listed in director")
+}
@@ -0,0 +1,201 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Versions of VaaS API supported by the client.
+const (
+	// APIVersionV01 is the tastypie based API of VaaS 1.x, the default.
+	APIVersionV01 = "v0.1"
+	// APIVersionV02 is the API of newer VaaS releases. It differs from v0.1 by lists paginated with
+	// Django REST framework (count, next, previous and results) instead of tastypie meta and objects.
+	APIVersionV02 = "v0.2"
+	// APIVersionAuto makes the client use the newest version VaaS offers, see WithAPIVersion.
+	APIVersionAuto = "auto"
+)
+
+// DefaultAPIVersion is the API version used unless WithAPIVersion is given.
+const DefaultAPIVersion = APIVersionV01
+
+// ErrUnsupportedAPIVersion is returned when VaaS offers no API version the client supports.
+var ErrUnsupportedAPIVersion = errors.New("unsupported VaaS API version")
+
+// apiSchema adapts responses of an API version to types of the client, which follow v0.1.
+// Requests are the same in every supported version.
+type apiSchema interface {
+	// normalize returns raw response decoded into v as v0.1 would return it
+	normalize(raw []byte, v interface{}) ([]byte, error)
+}
+
+var apiSchemas = map[string]apiSchema{
+	APIVersionV01: tastypieSchema{},
+	APIVersionV02: restFrameworkSchema{},
+}
+
+// SupportedAPIVersions returns API versions the client can talk, oldest first.
+func SupportedAPIVersions() []string {
+	versions := make([]string, 0, len(apiSchemas))
+	for version := range apiSchemas {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+	return versions
+}
+
+// WithAPIVersion makes the client use given version of VaaS API, one of SupportedAPIVersions or APIVersionAuto,
+// while an empty version keeps DefaultAPIVersion.
+// With APIVersionAuto the client asks VaaS for versions it offers with the first request and uses the newest
+// one the client supports, falling back to v0.1 when VaaS does not list its versions, as older releases do.
+func WithAPIVersion(version string) Option {
+	return func(c *defaultClient) {
+		if version == "" {
+			return
+		}
+		if version == APIVersionAuto {
+			c.apiVersion = ""
+			return
+		}
+		if _, ok := apiSchemas[version]; !ok {
+			c.optionError(fmt.Errorf("%w %q, supported are %s", ErrUnsupportedAPIVersion, version,
+				strings.Join(SupportedAPIVersions(), ", ")))
+			return
+		}
+		c.apiVersion = version
+	}
+}
+
+// endpoint returns URL of resource path, e.g. "/backend/", in the API version used by the client.
+func (c *defaultClient) endpoint(ctx context.Context, path string) (string, error) {
+	version, err := c.version(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.host + apiRootPath + version + path, nil
+}
+
+// version returns the API version used by the client, negotiating it with VaaS when needed.
+// Failed negotiation is retried by the next request, unless it ended with an answer from VaaS.
+func (c *defaultClient) version(ctx context.Context) (string, error) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if c.apiVersion != "" {
+		return c.apiVersion, nil
+	}
+
+	version, err := c.negotiateVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot negotiate VaaS API version: %w", err)
+	}
+	c.apiVersion = version
+	return version, nil
+}
+
+// schema returns the schema of the API version used by the client, v0.1 until it is negotiated.
+func (c *defaultClient) schema() apiSchema {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if schema, ok := apiSchemas[c.apiVersion]; ok {
+		return schema
+	}
+	return apiSchemas[DefaultAPIVersion]
+}
+
+// negotiateVersion probes the API root, where VaaS lists its versions as keys of a JSON object,
+// and picks the newest version supported by the client.
+func (c *defaultClient) negotiateVersion(ctx context.Context) (string, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, c.host+apiRootPath, nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := c.do(request)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return DefaultAPIVersion, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	rawResponse, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var offered map[string]json.RawMessage
+	if err := json.Unmarshal(rawResponse, &offered); err != nil {
+		return DefaultAPIVersion, nil
+	}
+	versions := SupportedAPIVersions()
+	for i := len(versions) - 1; i >= 0; i-- {
+		if _, ok := offered[versions[i]]; ok {
+			return versions[i], nil
+		}
+	}
+	names := make([]string, 0, len(offered))
+	for name := range offered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("%w: VaaS offers %s, supported are %s", ErrUnsupportedAPIVersion,
+		strings.Join(names, ", "), strings.Join(versions, ", "))
+}
+
+// compareVersions orders versions like "v0.1" by their numeric parts.
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		var aNumber, bNumber int
+		_, _ = fmt.Sscan(aParts[i], &aNumber)
+		_, _ = fmt.Sscan(bParts[i], &bNumber)
+		if aNumber != bNumber {
+			return aNumber - bNumber
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// tastypieSchema decodes responses of v0.1 as they are
+type tastypieSchema struct{}
+
+func (tastypieSchema) normalize(raw []byte, _ interface{}) ([]byte, error) {
+	return raw, nil
+}
+
+// restFrameworkSchema decodes lists paginated by Django REST framework into tastypie lists
+type restFrameworkSchema struct{}
+
+type restFrameworkPage struct {
+	Count    int             `json:"count"`
+	Next     *string         `json:"next"`
+	Previous *string         `json:"previous"`
+	Results  json.RawMessage `json:"results"`
+}
+
+type tastypiePage struct {
+	Meta    Meta            `json:"meta"`
+	Objects json.RawMessage `json:"objects"`
+}
+
+func (restFrameworkSchema) normalize(raw []byte, v interface{}) ([]byte, error) {
+	if _, ok := v.(listPage); !ok {
+		return raw, nil
+	}
+
+	var page restFrameworkPage
+	if err := json.Unmarshal(raw, &page); err != nil {
+		return nil, err
+	}
+	return json.Marshal(tastypiePage{
+		Meta:    Meta{TotalCount: page.Count, Next: page.Next, Previous: page.Previous},
+		Objects: page.Results,
+	})
+}
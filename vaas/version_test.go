@@ -0,0 +1,110 @@
+package vaas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportedAPIVersions(t *testing.T) {
+	assert.Equal(t, []string{APIVersionV01, APIVersionV02}, SupportedAPIVersions())
+	assert.True(t, compareVersions("v0.2", "v0.10") < 0)
+	assert.True(t, compareVersions("v1", "v0.2") > 0)
+}
+
+func TestClientNegotiatesNewestAPIVersion(t *testing.T) {
+	var requests []string
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		var body string
+		switch r.URL.RequestURI() {
+		case "/api/":
+			body = `{"v0.1": {"list_endpoint": "/api/v0.1/"}, "v0.2": {"list_endpoint": "/api/v0.2/"}, "v9": {}}`
+		case "/api/v0.2/director/?name=director":
+			body = `{"count": 2, "next": "` + ts.URL + `/api/v0.2/director/?name=director&offset=1", "previous": null,
+				"results": [{"id": 1, "name": "other"}]}`
+		case "/api/v0.2/director/?name=director&offset=1":
+			body = `{"count": 2, "next": null, "previous": null, "results": [{"id": 2, "name": "director"}]}`
+		default:
+			t.Errorf("unexpected %s", r.URL.RequestURI())
+		}
+		_, err := w.Write([]byte(body))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithAPIVersion(APIVersionAuto))
+
+	for i := 0; i < 2; i++ {
+		director, err := client.FindDirector(context.Background(), "director")
+		require.NoError(t, err)
+		assert.Equal(t, ID(2), director.ID)
+	}
+
+	assert.Equal(t, "/api/", requests[0])
+	assert.Len(t, requests, 5)
+}
+
+func TestClientFallsBackToDefaultAPIVersion(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == apiRootPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"objects": [{"id": 1, "symbol": "dc1"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithAPIVersion(APIVersionAuto))
+
+	dc, err := client.GetDC(context.Background(), "dc1")
+
+	require.NoError(t, err)
+	assert.Equal(t, ID(1), dc.ID)
+	assert.Equal(t, []string{apiRootPath, apiDcPath}, paths)
+}
+
+func TestClientFailsWithoutCommonAPIVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"v3": {}}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithAPIVersion(APIVersionAuto))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	assert.True(t, errors.Is(err, ErrUnsupportedAPIVersion), err)
+}
+
+func TestWithAPIVersionRejectsUnknownVersion(t *testing.T) {
+	client := NewClient("http://vaas.example.com", "username", "api-key", WithAPIVersion("v7"))
+
+	_, err := client.GetDC(context.Background(), "dc1")
+
+	assert.True(t, errors.Is(err, ErrUnsupportedAPIVersion), err)
+}
+
+func TestWithAPIVersionUsesGivenVersion(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "username", "api-key", WithAPIVersion(APIVersionV02))
+
+	require.NoError(t, client.DeleteDirector(context.Background(), 5))
+
+	assert.Equal(t, []string{"DELETE /api/v0.2/director/5/"}, paths)
+}
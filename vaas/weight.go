@@ -0,0 +1,66 @@
+package vaas
+
+import (
+	"context"
+	"fmt"
+)
+
+// Range of backend weights accepted by VaaS.
+const (
+	MinWeight = 0
+	MaxWeight = 100
+)
+
+// GetWeight returns backend weight, or 0 when it is not set.
+func (b *Backend) GetWeight() int {
+	return b.GetWeightOr(0)
+}
+
+// GetWeightOr returns backend weight, or defaultWeight when it is not set.
+func (b *Backend) GetWeightOr(defaultWeight int) int {
+	if b.Weight == nil {
+		return defaultWeight
+	}
+	return *b.Weight
+}
+
+// SetWeight sets backend weight.
+func (b *Backend) SetWeight(weight int) {
+	b.Weight = &weight
+}
+
+// ClampWeight limits weight to the range accepted by VaaS.
+func ClampWeight(weight int) int {
+	if weight < MinWeight {
+		return MinWeight
+	}
+	if weight > MaxWeight {
+		return MaxWeight
+	}
+	return weight
+}
+
+func validateWeight(weight int) error {
+	if weight != ClampWeight(weight) {
+		return fmt.Errorf("weight %d out of range, must be between %d and %d", weight, MinWeight, MaxWeight)
+	}
+	return nil
+}
+
+// SetBackendWeight changes weight of backend with given id.
+// Weights outside of MinWeight-MaxWeight are rejected, callers can use ClampWeight to fit them.
+func (c *defaultClient) SetBackendWeight(ctx context.Context, id int, weight int) error {
+	return c.UpdateBackend(ctx, id, BackendPatch{Weight: &weight})
+}
+
+// EnableBackend brings backend with given id back into rotation, keeping its weight, ID and place in the VCL.
+func (c *defaultClient) EnableBackend(ctx context.Context, id int) error {
+	enabled := true
+	return c.UpdateBackend(ctx, id, BackendPatch{Enabled: &enabled})
+}
+
+// DisableBackend takes backend with given id out of rotation without deleting it, e.g. for maintenance windows.
+func (c *defaultClient) DisableBackend(ctx context.Context, id int) error {
+	enabled := false
+	return c.UpdateBackend(ctx, id, BackendPatch{Enabled: &enabled})
+}
@@ -0,0 +1,79 @@
+package vaas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendWeightAccessors(t *testing.T) {
+	backend := Backend{}
+
+	assert.Equal(t, 0, backend.GetWeight())
+	assert.Equal(t, 1, backend.GetWeightOr(1))
+
+	backend.SetWeight(50)
+
+	assert.Equal(t, 50, backend.GetWeight())
+	assert.Equal(t, 50, backend.GetWeightOr(1))
+}
+
+func TestClampWeight(t *testing.T) {
+	assert.Equal(t, MinWeight, ClampWeight(-5))
+	assert.Equal(t, 42, ClampWeight(42))
+	assert.Equal(t, MaxWeight, ClampWeight(150))
+}
+
+func TestSetBackendWeightPatchesBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/api/v0.1/backend/123/", r.URL.Path)
+
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var patch map[string]int
+		require.NoError(t, json.Unmarshal(rawRequest, &patch))
+		assert.Equal(t, map[string]int{"weight": 25}, patch)
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	assert.NoError(t, client.SetBackendWeight(context.Background(), 123, 25))
+}
+
+func TestSetBackendWeightRejectsOutOfRangeWeight(t *testing.T) {
+	client := NewClient("http://vaas.invalid", "username", "api-key")
+
+	assert.EqualError(t, client.SetBackendWeight(context.Background(), 123, 101), "weight 101 out of range, must be between 0 and 100")
+	assert.Error(t, client.SetBackendWeight(context.Background(), 123, -1))
+}
+
+func TestEnableAndDisableBackendPatchEnabledFlag(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/api/v0.1/backend/123/", r.URL.Path)
+
+		rawRequest, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(rawRequest))
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "username", "api-key")
+
+	require.NoError(t, client.DisableBackend(context.Background(), 123))
+	require.NoError(t, client.EnableBackend(context.Background(), 123))
+	assert.Equal(t, []string{`{"enabled":false}`, `{"enabled":true}`}, bodies)
+}
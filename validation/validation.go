@@ -0,0 +1,120 @@
+// Package validation checks registration inputs before they are sent to VaaS, reporting every invalid field at
+// once, as tastypie rejects bad input with responses that hardly tell which field is wrong.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+const (
+	// MinPort and MaxPort bound backend ports
+	MinPort = 1
+	MaxPort = 65535
+	// maxHostnameLength is the longest hostname DNS allows
+	maxHostnameLength = 253
+)
+
+var (
+	// directorName matches names VaaS accepts for directors, which are used as VCL identifiers
+	directorName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	// hostnameLabel matches a label of a hostname as defined by RFC 1123
+	hostnameLabel = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+)
+
+// FieldError is an invalid value of a field, e.g. a flag
+type FieldError struct {
+	Field   string
+	Value   interface{}
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Field, fmt.Sprint(e.Value), e.Message)
+}
+
+// Errors are invalid fields of an input, reported together
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Error())
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+// Add records value of field as invalid when err, returned by one of the checks, is not nil
+func (e *Errors) Add(field string, value interface{}, err error) {
+	if err != nil {
+		*e = append(*e, FieldError{Field: field, Value: value, Message: err.Error()})
+	}
+}
+
+// Err returns the errors, or nil when there are none
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Address checks that address is an IP address or a hostname
+func Address(address string) error {
+	if address == "" {
+		return errors.New("is empty")
+	}
+	if net.ParseIP(vaas.NormalizeAddress(address)) != nil {
+		return nil
+	}
+	if len(address) > maxHostnameLength {
+		return fmt.Errorf("is neither an IP address nor a hostname, which is at most %d characters long", maxHostnameLength)
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(address, "."), ".") {
+		if !hostnameLabel.MatchString(label) {
+			return errors.New("is neither an IP address nor a hostname")
+		}
+	}
+	return nil
+}
+
+// Port checks that port is between MinPort and MaxPort
+func Port(port int) error {
+	if port < MinPort || port > MaxPort {
+		return fmt.Errorf("out of range, must be between %d and %d", MinPort, MaxPort)
+	}
+	return nil
+}
+
+// DirectorName checks that name is a valid VaaS director name: letters, digits, underscores and hyphens,
+// starting with a letter or digit
+func DirectorName(name string) error {
+	if name == "" {
+		return errors.New("is empty")
+	}
+	if !directorName.MatchString(name) {
+		return errors.New("may contain only letters, digits, underscores and hyphens, starting with a letter or digit")
+	}
+	return nil
+}
+
+// Weight checks that weight is between vaas.MinWeight and vaas.MaxWeight
+func Weight(weight int) error {
+	if weight != vaas.ClampWeight(weight) {
+		return fmt.Errorf("out of range, must be between %d and %d", vaas.MinWeight, vaas.MaxWeight)
+	}
+	return nil
+}
+
+// DCSymbol checks that a DC symbol is given; whether VaaS knows it can only be checked with the API
+func DCSymbol(symbol string) error {
+	if strings.TrimSpace(symbol) == "" {
+		return errors.New("is empty")
+	}
+	return nil
+}
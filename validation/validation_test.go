@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddress(t *testing.T) {
+	for _, address := range []string{"10.0.0.1", "2001:db8::1", "[2001:db8::1]", "backend.example.com", "backend-1.", "localhost"} {
+		assert.NoError(t, Address(address), address)
+	}
+	for _, address := range []string{"", "10.0.0.1:80", "-backend.example.com", "backend..example.com", "back_end", strings.Repeat("a", 64)} {
+		assert.Error(t, Address(address), address)
+	}
+}
+
+func TestPortDirectorNameWeightAndDCSymbol(t *testing.T) {
+	assert.NoError(t, Port(MinPort))
+	assert.NoError(t, Port(MaxPort))
+	assert.Error(t, Port(0))
+	assert.Error(t, Port(MaxPort+1))
+
+	assert.NoError(t, DirectorName("hook-test_green2"))
+	assert.Error(t, DirectorName(""))
+	assert.Error(t, DirectorName("_director"))
+	assert.Error(t, DirectorName("my director"))
+
+	assert.NoError(t, Weight(0))
+	assert.NoError(t, Weight(100))
+	assert.Error(t, Weight(-1))
+	assert.Error(t, Weight(101))
+
+	assert.NoError(t, DCSymbol("dc1"))
+	assert.Error(t, DCSymbol(" "))
+}
+
+func TestErrorsAggregatesFieldErrors(t *testing.T) {
+	var errs Errors
+	require.NoError(t, errs.Err())
+
+	errs.Add("port", 0, Port(0))
+	errs.Add("dc", "dc1", DCSymbol("dc1"))
+	errs.Add("weight", 101, Weight(101))
+
+	err := errs.Err()
+	require.Error(t, err)
+	assert.EqualError(t, err, `invalid input: port "0": out of range, must be between 1 and 65535; `+
+		`weight "101": out of range, must be between 0 and 100`)
+	var fieldErrors Errors
+	require.True(t, errors.As(err, &fieldErrors))
+	assert.Equal(t, []string{"port", "weight"}, []string{fieldErrors[0].Field, fieldErrors[1].Field})
+}
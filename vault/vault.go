@@ -0,0 +1,425 @@
+// Package vault provides VaaS credentials kept in HashiCorp Vault, read from a KV or dynamic secrets engine over
+// the Vault HTTP API after logging in with a token, AppRole or a Kubernetes service account.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/allegro/vaas-registration-hook/secret"
+	"github.com/allegro/vaas-registration-hook/vaas"
+)
+
+// Auth methods logging in to Vault
+const (
+	// AuthToken uses a given Vault token
+	AuthToken = "token"
+	// AuthAppRole logs in with an AppRole role ID and secret ID
+	AuthAppRole = "approle"
+	// AuthKubernetes logs in with the token of the Kubernetes service account of the pod
+	AuthKubernetes = "kubernetes"
+)
+
+const (
+	// DefaultKubernetesTokenFile is where Kubernetes mounts the service account token of a pod
+	DefaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// DefaultUsernameField is the field of the secret holding the VaaS username
+	DefaultUsernameField = "username"
+	// DefaultAPIKeyField is the field of the secret holding the VaaS API key
+	DefaultAPIKeyField = "api_key"
+	// DefaultRefreshInterval is how often secrets without a lease, such as KV ones, are read again
+	DefaultRefreshInterval = 5 * time.Minute
+
+	namespaceHeader = "X-Vault-Namespace"
+	tokenHeader     = "X-Vault-Token"
+	requestTimeout  = 10 * time.Second
+)
+
+// ErrVault is returned when Vault fails or rejects a request.
+var ErrVault = errors.New("vault request failed")
+
+// Config configures a Provider
+type Config struct {
+	// Address of Vault, e.g. https://vault.example.com:8200
+	Address string
+	// Namespace of Vault Enterprise, none when empty
+	Namespace string
+	// CACertFile is a PEM file of certificate authorities trusted for Vault, the system ones when empty
+	CACertFile string
+
+	// Auth is the auth method, AuthToken when empty
+	Auth string
+	// AuthMount is the path the auth method is mounted at, its name when empty
+	AuthMount string
+	// Token is used with AuthToken
+	Token string
+	// RoleID and SecretID are used with AuthAppRole
+	RoleID   string
+	SecretID string
+	// Role is used with AuthKubernetes
+	Role string
+	// KubernetesTokenFile is the service account token used with AuthKubernetes, DefaultKubernetesTokenFile when empty
+	KubernetesTokenFile string
+
+	// SecretPath is the API path of the secret, e.g. secret/data/vaas for KV version 2 or vaas/creds/hook
+	SecretPath string
+	// UsernameField and APIKeyField name fields of the secret, DefaultUsernameField and DefaultAPIKeyField when empty
+	UsernameField string
+	APIKeyField   string
+	// Username is used when the secret has no username field
+	Username string
+	// RefreshInterval is how often secrets without a lease are read again, DefaultRefreshInterval when zero
+	RefreshInterval time.Duration
+
+	// HTTPClient sends requests to Vault, one trusting CACertFile when nil
+	HTTPClient *http.Client
+}
+
+// Credentials of VaaS read from Vault
+type Credentials struct {
+	Username string
+	APIKey   string
+}
+
+// Provider reads VaaS credentials from Vault and is a vaas.Authenticator sending them. Credentials and the Vault
+// token are reused until two thirds of their lease passed, then their leases are renewed, or credentials read
+// again and the token obtained again by logging in once they cannot be renewed, so that a long-running process
+// follows rotated credentials. It is safe for concurrent use.
+type Provider struct {
+	config Config
+	now    func() time.Time
+
+	mu          sync.Mutex
+	token       lease
+	credentials Credentials
+	secret      lease
+	clientToken string
+}
+
+// lease is a Vault lease, to be renewed once renewAt passed and unusable once expiresAt passed
+type lease struct {
+	id        string
+	renewable bool
+	duration  time.Duration
+	renewAt   time.Time
+	expiresAt time.Time
+}
+
+// valid returns whether the lease was obtained and does not have to be renewed at now
+func (l lease) valid(now time.Time) bool {
+	return !l.renewAt.IsZero() && now.Before(l.renewAt)
+}
+
+// canRenew returns whether the lease can still be renewed at now
+func (l lease) canRenew(now time.Time) bool {
+	return l.renewable && now.Before(l.expiresAt)
+}
+
+func newLease(id string, renewable bool, seconds int, fallback time.Duration, now time.Time) lease {
+	duration := time.Duration(seconds) * time.Second
+	if duration <= 0 {
+		return lease{id: id, renewAt: now.Add(fallback), expiresAt: now.Add(fallback)}
+	}
+	return lease{
+		id:        id,
+		renewable: renewable,
+		duration:  duration,
+		renewAt:   now.Add(duration * 2 / 3),
+		expiresAt: now.Add(duration),
+	}
+}
+
+// New returns a Provider for config, failing when it is incomplete
+func New(config Config) (*Provider, error) {
+	if config.Address == "" {
+		return nil, errors.New("no Vault address")
+	}
+	if config.SecretPath == "" {
+		return nil, errors.New("no Vault secret path")
+	}
+	if config.Auth == "" {
+		config.Auth = AuthToken
+	}
+	switch config.Auth {
+	case AuthToken:
+		if config.Token == "" {
+			return nil, errors.New("no Vault token")
+		}
+	case AuthAppRole:
+		if config.RoleID == "" || config.SecretID == "" {
+			return nil, errors.New("no AppRole role ID or secret ID")
+		}
+	case AuthKubernetes:
+		if config.Role == "" {
+			return nil, errors.New("no Vault role for Kubernetes auth")
+		}
+		if config.KubernetesTokenFile == "" {
+			config.KubernetesTokenFile = DefaultKubernetesTokenFile
+		}
+	default:
+		return nil, fmt.Errorf("unknown Vault auth method %q, expected %s, %s or %s", config.Auth, AuthToken, AuthAppRole, AuthKubernetes)
+	}
+	if config.AuthMount == "" {
+		config.AuthMount = config.Auth
+	}
+	if config.UsernameField == "" {
+		config.UsernameField = DefaultUsernameField
+	}
+	if config.APIKeyField == "" {
+		config.APIKeyField = DefaultAPIKeyField
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	config.SecretPath = strings.Trim(config.SecretPath, "/")
+
+	if config.HTTPClient == nil {
+		client, err := newHTTPClient(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTPClient = client
+	}
+	return &Provider{config: config, now: time.Now}, nil
+}
+
+func newHTTPClient(caCertFile string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCertFile != "" {
+		pem, err := ioutil.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read Vault CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %s", caCertFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{Transport: transport, Timeout: requestTimeout}, nil
+}
+
+// Authenticate implements vaas.Authenticator, sending credentials read from Vault in an "Authorization: ApiKey" header.
+func (p *Provider) Authenticate(request *http.Request) error {
+	credentials, err := p.Credentials(request.Context())
+	if err != nil {
+		return err
+	}
+	return vaas.APIKeyHeader(credentials.Username, credentials.APIKey).Authenticate(request)
+}
+
+// Invalidate drops the credentials, so that the next request reads them from Vault again, e.g. once VaaS rejected them.
+func (p *Provider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secret = lease{}
+}
+
+// Credentials returns VaaS credentials, renewing their lease or reading them from Vault when needed
+func (p *Provider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.secret.valid(now) {
+		return p.credentials, nil
+	}
+	if p.secret.id != "" && p.secret.canRenew(now) {
+		err := p.renewSecret(ctx, now)
+		if err == nil {
+			return p.credentials, nil
+		}
+		log.WithContext(ctx).Warnf("Could not renew lease of VaaS credentials, reading them again: %s", err)
+	}
+
+	err := p.readSecret(ctx, now)
+	if errors.Is(err, errForbidden) && p.config.Auth != AuthToken {
+		// The token may have been revoked before its lease ended, so log in again once.
+		p.token = lease{}
+		err = p.readSecret(ctx, now)
+	}
+	if err != nil {
+		return Credentials{}, err
+	}
+	return p.credentials, nil
+}
+
+// errForbidden marks Vault rejecting the token
+var errForbidden = fmt.Errorf("%w: permission denied", ErrVault)
+
+// response represents a Vault API response
+type response struct {
+	LeaseID       string                 `json:"lease_id"`
+	Renewable     bool                   `json:"renewable"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (p *Provider) readSecret(ctx context.Context, now time.Time) error {
+	token, err := p.vaultToken(ctx, now)
+	if err != nil {
+		return err
+	}
+	secretResponse, err := p.do(ctx, http.MethodGet, p.config.SecretPath, token, nil)
+	if err != nil {
+		return err
+	}
+
+	data := secretResponse.Data
+	// KV version 2 nests fields of the secret under data, next to its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = nested
+	}
+	credentials, err := p.credentialsOf(data)
+	if err != nil {
+		return err
+	}
+
+	if p.credentials.APIKey != "" && credentials != p.credentials {
+		log.WithContext(ctx).Infof("Read rotated VaaS credentials from Vault %s", p.config.SecretPath)
+	}
+	p.credentials = credentials
+	p.secret = newLease(secretResponse.LeaseID, secretResponse.Renewable, secretResponse.LeaseDuration,
+		p.config.RefreshInterval, now)
+	return nil
+}
+
+func (p *Provider) credentialsOf(data map[string]interface{}) (Credentials, error) {
+	apiKey, _ := data[p.config.APIKeyField].(string)
+	apiKey, err := secret.Clean(fmt.Sprintf("field %s of Vault secret %s", p.config.APIKeyField, p.config.SecretPath), apiKey)
+	if err != nil {
+		return Credentials{}, err
+	}
+	username, _ := data[p.config.UsernameField].(string)
+	if username == "" {
+		username = p.config.Username
+	}
+	username, err = secret.Clean("VaaS username", username)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("%s, expected field %s in Vault secret %s", err, p.config.UsernameField, p.config.SecretPath)
+	}
+	return Credentials{Username: username, APIKey: apiKey}, nil
+}
+
+func (p *Provider) renewSecret(ctx context.Context, now time.Time) error {
+	token, err := p.vaultToken(ctx, now)
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{"lease_id": p.secret.id, "increment": int(p.secret.duration.Seconds())}
+	renewed, err := p.do(ctx, http.MethodPut, "sys/leases/renew", token, body)
+	if err != nil {
+		return err
+	}
+	p.secret = newLease(p.secret.id, renewed.Renewable, renewed.LeaseDuration, p.config.RefreshInterval, now)
+	return nil
+}
+
+// vaultToken returns a token valid at now, renewing it or logging in again when needed
+func (p *Provider) vaultToken(ctx context.Context, now time.Time) (string, error) {
+	if p.config.Auth == AuthToken {
+		return p.config.Token, nil
+	}
+	if p.token.valid(now) {
+		return p.clientToken, nil
+	}
+	if p.clientToken != "" && p.token.canRenew(now) {
+		renewed, err := p.do(ctx, http.MethodPost, "auth/token/renew-self", p.clientToken, nil)
+		if err == nil && renewed.Auth != nil {
+			p.token = newLease("", renewed.Auth.Renewable, renewed.Auth.LeaseDuration, p.config.RefreshInterval, now)
+			return p.clientToken, nil
+		}
+		log.WithContext(ctx).Warnf("Could not renew Vault token, logging in again: %v", err)
+	}
+	return p.login(ctx, now)
+}
+
+func (p *Provider) login(ctx context.Context, now time.Time) (string, error) {
+	body := map[string]interface{}{}
+	switch p.config.Auth {
+	case AuthAppRole:
+		body["role_id"], body["secret_id"] = p.config.RoleID, p.config.SecretID
+	case AuthKubernetes:
+		jwt, err := secret.ReadFile("Kubernetes service account token", p.config.KubernetesTokenFile)
+		if err != nil {
+			return "", err
+		}
+		body["role"], body["jwt"] = p.config.Role, jwt
+	}
+
+	loggedIn, err := p.do(ctx, http.MethodPost, "auth/"+strings.Trim(p.config.AuthMount, "/")+"/login", "", body)
+	if err != nil {
+		return "", fmt.Errorf("could not log in to Vault with %s: %w", p.config.Auth, err)
+	}
+	if loggedIn.Auth == nil || loggedIn.Auth.ClientToken == "" {
+		return "", fmt.Errorf("%w: login with %s returned no token", ErrVault, p.config.Auth)
+	}
+	p.clientToken = loggedIn.Auth.ClientToken
+	p.token = newLease("", loggedIn.Auth.Renewable, loggedIn.Auth.LeaseDuration, p.config.RefreshInterval, now)
+	return p.clientToken, nil
+}
+
+// do sends a request to path of Vault API, with token unless it is empty and body as JSON unless it is nil
+func (p *Provider) do(ctx context.Context, method, path, token string, body interface{}) (*response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, method, p.config.Address+"/v1/"+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set(tokenHeader, token)
+	}
+	if p.config.Namespace != "" {
+		request.Header.Set(namespaceHeader, p.config.Namespace)
+	}
+
+	httpResponse, err := p.config.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrVault, err)
+	}
+	defer httpResponse.Body.Close()
+	rawResponse, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrVault, err)
+	}
+
+	var parsed response
+	if len(rawResponse) > 0 {
+		if err := json.Unmarshal(rawResponse, &parsed); err != nil {
+			return nil, fmt.Errorf("%w: unable to parse response to %s %s: %s", ErrVault, method, path, err)
+		}
+	}
+	if httpResponse.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: %s %s", errForbidden, method, path)
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: %s %s (HTTP %d): %s",
+			ErrVault, method, path, httpResponse.StatusCode, strings.Join(parsed.Errors, "; "))
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultServer is a fake Vault recording requests as "METHOD path"
+type vaultServer struct {
+	sync.Mutex
+	requests []string
+	bodies   []map[string]interface{}
+	secret   string
+	token    string
+}
+
+func (s *vaultServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.bodies = append(s.bodies, body)
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login", "/v1/auth/k8s/login":
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "s.login", "lease_duration": 3600, "renewable": true}}`))
+		return
+	}
+	if r.Header.Get(tokenHeader) != s.token {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/secret/data/vaas":
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "admin", "api_key": "` + s.secret + `"}, "metadata": {"version": 3}}}`))
+	case "/v1/vaas/creds/hook":
+		_, _ = w.Write([]byte(`{"lease_id": "vaas/creds/hook/1", "renewable": true, "lease_duration": 60, "data": {"username": "dynamic", "api_key": "` + s.secret + `"}}`))
+	case "/v1/sys/leases/renew":
+		_, _ = w.Write([]byte(`{"lease_id": "vaas/creds/hook/1", "renewable": true, "lease_duration": 60}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": []}`))
+	}
+}
+
+func TestProviderReadsKVVersion2Secret(t *testing.T) {
+	server := &vaultServer{secret: "key-1", token: "s.static"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	provider, err := New(Config{Address: ts.URL, Token: "s.static", SecretPath: "/secret/data/vaas"})
+	require.NoError(t, err)
+	request, _ := http.NewRequest(http.MethodGet, "http://vaas/api/v0.1/", nil)
+
+	require.NoError(t, provider.Authenticate(request))
+	assert.Equal(t, "ApiKey admin:key-1", request.Header.Get("Authorization"))
+
+	server.secret = "key-2"
+	require.NoError(t, provider.Authenticate(request))
+	assert.Equal(t, "ApiKey admin:key-1", request.Header.Get("Authorization"))
+
+	provider.Invalidate()
+	require.NoError(t, provider.Authenticate(request))
+	assert.Equal(t, "ApiKey admin:key-2", request.Header.Get("Authorization"))
+}
+
+func TestProviderRenewsLeaseOfDynamicSecret(t *testing.T) {
+	server := &vaultServer{secret: "key-1", token: "s.login"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	provider, err := New(Config{Address: ts.URL, Auth: AuthAppRole, RoleID: "role", SecretID: "secret", SecretPath: "vaas/creds/hook"})
+	require.NoError(t, err)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	credentials, err := provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "dynamic", APIKey: "key-1"}, credentials)
+
+	now = now.Add(45 * time.Second)
+	credentials, err = provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", credentials.APIKey)
+
+	now = now.Add(2 * time.Minute)
+	server.secret = "key-2"
+	credentials, err = provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", credentials.APIKey)
+
+	assert.Equal(t, []string{
+		"POST /v1/auth/approle/login",
+		"GET /v1/vaas/creds/hook",
+		"PUT /v1/sys/leases/renew",
+		"GET /v1/vaas/creds/hook",
+	}, server.requests)
+	assert.Equal(t, map[string]interface{}{"role_id": "role", "secret_id": "secret"}, server.bodies[0])
+}
+
+func TestProviderLogsInWithKubernetesServiceAccount(t *testing.T) {
+	server := &vaultServer{secret: "key-1", token: "s.login"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "vault")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("jwt\n"), 0600))
+
+	provider, err := New(Config{Address: ts.URL, Auth: AuthKubernetes, AuthMount: "k8s", Role: "vaas-hook",
+		KubernetesTokenFile: tokenFile, SecretPath: "secret/data/vaas"})
+	require.NoError(t, err)
+
+	credentials, err := provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", credentials.APIKey)
+	assert.Equal(t, map[string]interface{}{"role": "vaas-hook", "jwt": "jwt"}, server.bodies[0])
+}
+
+func TestProviderFailsOnRejectedToken(t *testing.T) {
+	server := &vaultServer{secret: "key-1", token: "s.other"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	provider, err := New(Config{Address: ts.URL, Token: "s.static", SecretPath: "secret/data/vaas"})
+	require.NoError(t, err)
+
+	_, err = provider.Credentials(context.Background())
+	require.True(t, errors.Is(err, ErrVault))
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestNewRejectsIncompleteConfig(t *testing.T) {
+	_, err := New(Config{Address: "http://vault", SecretPath: "secret/data/vaas"})
+	assert.EqualError(t, err, "no Vault token")
+	_, err = New(Config{Address: "http://vault", SecretPath: "secret/data/vaas", Auth: "ldap"})
+	assert.EqualError(t, err, `unknown Vault auth method "ldap", expected token, approle or kubernetes`)
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AdmissionReview is an admission.k8s.io/v1 AdmissionReview, limited to fields used by the webhook
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion,omitempty"`
+	Kind       string             `json:"kind,omitempty"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest describes the object being admitted
+type AdmissionRequest struct {
+	UID       string          `json:"uid"`
+	Name      string          `json:"name,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Object    json.RawMessage `json:"object"`
+}
+
+// AdmissionResponse tells whether the object is admitted and how it is modified
+type AdmissionResponse struct {
+	UID       string  `json:"uid"`
+	Allowed   bool    `json:"allowed"`
+	Patch     []byte  `json:"patch,omitempty"`
+	PatchType *string `json:"patchType,omitempty"`
+	Result    *Status `json:"status,omitempty"`
+}
+
+// Status explains why an object is not admitted
+type Status struct {
+	Message string `json:"message"`
+}
+
+// Pod holds fields of a Pod the webhook reads. Pods are decoded partially, so that the webhook does not depend
+// on the full Kubernetes API and keeps admitting Pods using fields it does not know.
+type Pod struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		InitContainers []Container    `json:"initContainers"`
+		Containers     []Container    `json:"containers"`
+		Volumes        []NamedElement `json:"volumes"`
+	} `json:"spec"`
+}
+
+// Container holds fields of a container the webhook reads
+type Container struct {
+	Name         string                     `json:"name"`
+	Env          []NamedElement             `json:"env"`
+	VolumeMounts []NamedElement             `json:"volumeMounts"`
+	Lifecycle    map[string]json.RawMessage `json:"lifecycle"`
+}
+
+// NamedElement is an element of a list of a Pod identified by its name
+type NamedElement struct {
+	Name string `json:"name"`
+}
+
+// containerIndex returns the index of the container with given name, the first one when name is empty
+func (pod *Pod) containerIndex(name string) (int, error) {
+	if len(pod.Spec.Containers) == 0 {
+		return 0, fmt.Errorf("pod has no containers")
+	}
+	if name == "" {
+		return 0, nil
+	}
+	for i, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("pod has no container named %q", name)
+}
+
+func (container Container) hasEnv(name string) bool {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+// Package webhook implements a Kubernetes mutating admission webhook injecting the hook into annotated Pods.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AnnotationPrefix starts annotations read by the webhook
+	AnnotationPrefix = "vaas.allegro.tech/"
+	// AnnotationInject marks Pods the hook is injected into, when set to "true"
+	AnnotationInject = AnnotationPrefix + "inject"
+	// AnnotationContainer names the container the hook is injected into, the first one when not set
+	AnnotationContainer = AnnotationPrefix + "container"
+
+	// InitContainerName is the name of the container copying the hook binary into Pods
+	InitContainerName = "vaas-hook"
+	// VolumeName is the name of the volume sharing the hook binary with the container
+	VolumeName = "vaas-hook"
+	// MountPath is where the hook binary is available in the container
+	MountPath = "/vaas-hook"
+	// DefaultBinary is the path of the hook binary in the image of the init container
+	DefaultBinary = "/vaas-hook"
+)
+
+// downwardEnv are variables read by the hook in Kubernetes, exposed through the downward API
+var downwardEnv = map[string]string{
+	"KUBERNETES_POD_NAME":      "metadata.name",
+	"KUBERNETES_POD_NAMESPACE": "metadata.namespace",
+	"KUBERNETES_POD_IP":        "status.podIP",
+	"KUBERNETES_HOST_IP":       "status.hostIP",
+}
+
+// Injector adds an init container copying the hook binary and postStart and preStop hooks registering and
+// deregistering the Pod to Pods annotated with AnnotationInject. Other annotations with AnnotationPrefix are given
+// to the hook as flags, e.g. vaas.allegro.tech/director: d1 becomes --director=d1.
+type Injector struct {
+	// Image of the init container, holding the hook binary
+	Image string
+	// Binary is the path of the hook binary in Image, DefaultBinary when empty
+	Binary string
+	// Args are global flags given to every hook, before flags from annotations
+	Args []string
+	// RegisterFlags are names of flags of the register k8s subcommand, which have to follow it
+	RegisterFlags []string
+}
+
+// PatchOperation is a JSON Patch operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Patch returns operations injecting the hook into pod, none when it is not annotated or already injected
+func (i *Injector) Patch(pod *Pod) ([]PatchOperation, error) {
+	if pod.Metadata.Annotations[AnnotationInject] != "true" {
+		return nil, nil
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == InitContainerName {
+			return nil, nil
+		}
+	}
+
+	index, err := pod.containerIndex(pod.Metadata.Annotations[AnnotationContainer])
+	if err != nil {
+		return nil, err
+	}
+	container := pod.Spec.Containers[index]
+	if container.Lifecycle["postStart"] != nil || container.Lifecycle["preStop"] != nil {
+		return nil, fmt.Errorf("container %s already has lifecycle hooks", container.Name)
+	}
+
+	binary := i.Binary
+	if binary == "" {
+		binary = DefaultBinary
+	}
+	hookBinary := path.Join(MountPath, path.Base(binary))
+	globalArgs, registerArgs := i.args(pod.Metadata.Annotations)
+
+	containerPath := fmt.Sprintf("/spec/containers/%d", index)
+	operations := []PatchOperation{
+		appendTo("/spec/initContainers", len(pod.Spec.InitContainers) == 0, map[string]interface{}{
+			"name":         InitContainerName,
+			"image":        i.Image,
+			"command":      []string{"cp", binary, hookBinary},
+			"volumeMounts": []interface{}{volumeMount()},
+		}),
+		appendTo("/spec/volumes", len(pod.Spec.Volumes) == 0, map[string]interface{}{
+			"name":     VolumeName,
+			"emptyDir": map[string]interface{}{},
+		}),
+		appendTo(containerPath+"/volumeMounts", len(container.VolumeMounts) == 0, volumeMount()),
+	}
+	operations = append(operations, envOperations(containerPath, container)...)
+
+	register := append(append(append([]string{hookBinary}, globalArgs...), "register", "k8s"), registerArgs...)
+	deregister := append(append([]string{hookBinary}, globalArgs...), "deregister", "k8s")
+	hooks := map[string]interface{}{
+		"postStart": map[string]interface{}{"exec": map[string]interface{}{"command": register}},
+		"preStop":   map[string]interface{}{"exec": map[string]interface{}{"command": deregister}},
+	}
+	if container.Lifecycle == nil {
+		return append(operations, PatchOperation{Op: "add", Path: containerPath + "/lifecycle", Value: hooks}), nil
+	}
+	return append(operations,
+		PatchOperation{Op: "add", Path: containerPath + "/lifecycle/postStart", Value: hooks["postStart"]},
+		PatchOperation{Op: "add", Path: containerPath + "/lifecycle/preStop", Value: hooks["preStop"]},
+	), nil
+}
+
+// args returns global flags and flags of the register subcommand read from annotations
+func (i *Injector) args(annotations map[string]string) (global, register []string) {
+	global = append(global, i.Args...)
+	for _, key := range sortedKeys(annotations) {
+		name := strings.TrimPrefix(key, AnnotationPrefix)
+		if name == key || key == AnnotationInject || key == AnnotationContainer {
+			continue
+		}
+
+		arg := fmt.Sprintf("--%s=%s", name, annotations[key])
+		if i.isRegisterFlag(name) {
+			register = append(register, arg)
+		} else {
+			global = append(global, arg)
+		}
+	}
+	return global, register
+}
+
+func (i *Injector) isRegisterFlag(name string) bool {
+	for _, flag := range i.RegisterFlags {
+		if flag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP answers AdmissionReview requests of the API server with patches of their Pods
+func (i *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("invalid AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review.Response = i.review(review.Request)
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Errorf("Could not write AdmissionReview response: %s", err)
+	}
+}
+
+func (i *Injector) review(request *AdmissionRequest) *AdmissionResponse {
+	response := &AdmissionResponse{UID: request.UID, Allowed: true}
+	logger := log.WithField("pod", request.Namespace+"/"+request.Name)
+
+	pod := &Pod{}
+	if err := json.Unmarshal(request.Object, pod); err != nil {
+		return denied(response, fmt.Errorf("could not decode pod: %s", err))
+	}
+	operations, err := i.Patch(pod)
+	if err != nil {
+		logger.Warnf("Not injecting hook: %s", err)
+		return response
+	}
+	if len(operations) == 0 {
+		return response
+	}
+
+	if response.Patch, err = json.Marshal(operations); err != nil {
+		return denied(response, err)
+	}
+	patchType := "JSONPatch"
+	response.PatchType = &patchType
+	logger.Info("Injecting hook")
+	return response
+}
+
+func denied(response *AdmissionResponse, err error) *AdmissionResponse {
+	response.Allowed = false
+	response.Result = &Status{Message: err.Error()}
+	return response
+}
+
+// appendTo adds value to the array at path, creating the array when it does not exist
+func appendTo(path string, create bool, value interface{}) PatchOperation {
+	if create {
+		return PatchOperation{Op: "add", Path: path, Value: []interface{}{value}}
+	}
+	return PatchOperation{Op: "add", Path: path + "/-", Value: value}
+}
+
+// envOperations add variables read by the hook that container does not define yet
+func envOperations(containerPath string, container Container) []PatchOperation {
+	var operations []PatchOperation
+	for _, name := range sortedKeys(downwardEnv) {
+		if container.hasEnv(name) {
+			continue
+		}
+		value := map[string]interface{}{
+			"name":      name,
+			"valueFrom": map[string]interface{}{"fieldRef": map[string]string{"fieldPath": downwardEnv[name]}},
+		}
+		operations = append(operations, appendTo(containerPath+"/env", len(container.Env) == 0 && len(operations) == 0, value))
+	}
+	return operations
+}
+
+func volumeMount() map[string]interface{} {
+	return map[string]interface{}{"name": VolumeName, "mountPath": MountPath}
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
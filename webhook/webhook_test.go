@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testInjector = &Injector{
+	Image:         "vaas-hook:latest",
+	Args:          []string{"--key-file=/etc/vaas/key"},
+	RegisterFlags: []string{"health-check"},
+}
+
+func testPod(t *testing.T, raw string) *Pod {
+	pod := &Pod{}
+	require.NoError(t, json.Unmarshal([]byte(raw), pod))
+	return pod
+}
+
+func TestPatchInjectsHookWithFlagsFromAnnotations(t *testing.T) {
+	pod := testPod(t, `{
+		"metadata": {"annotations": {
+			"vaas.allegro.tech/inject": "true",
+			"vaas.allegro.tech/container": "app",
+			"vaas.allegro.tech/director": "d1",
+			"vaas.allegro.tech/health-check": "tcp"
+		}},
+		"spec": {"containers": [
+			{"name": "sidecar"},
+			{"name": "app", "env": [{"name": "KUBERNETES_POD_IP"}], "volumeMounts": [{"name": "data"}]}
+		], "volumes": [{"name": "data"}]}
+	}`)
+
+	operations, err := testInjector.Patch(pod)
+
+	require.NoError(t, err)
+	paths := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		paths = append(paths, operation.Path)
+	}
+	assert.Equal(t, []string{
+		"/spec/initContainers",
+		"/spec/volumes/-",
+		"/spec/containers/1/volumeMounts/-",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/lifecycle",
+	}, paths)
+
+	hooks, err := json.Marshal(operations[len(operations)-1].Value)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"postStart": {"exec": {"command": ["/vaas-hook/vaas-hook", "--key-file=/etc/vaas/key", "--director=d1",
+			"register", "k8s", "--health-check=tcp"]}},
+		"preStop": {"exec": {"command": ["/vaas-hook/vaas-hook", "--key-file=/etc/vaas/key", "--director=d1",
+			"deregister", "k8s"]}}
+	}`, string(hooks))
+}
+
+func TestPatchSkipsPodsNotAnnotatedOrInjected(t *testing.T) {
+	operations, err := testInjector.Patch(testPod(t, `{"spec": {"containers": [{"name": "app"}]}}`))
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	operations, err = testInjector.Patch(testPod(t, `{
+		"metadata": {"annotations": {"vaas.allegro.tech/inject": "true"}},
+		"spec": {"initContainers": [{"name": "vaas-hook"}], "containers": [{"name": "app"}]}
+	}`))
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+}
+
+func TestPatchRefusesToReplaceLifecycleHooks(t *testing.T) {
+	_, err := testInjector.Patch(testPod(t, `{
+		"metadata": {"annotations": {"vaas.allegro.tech/inject": "true"}},
+		"spec": {"containers": [{"name": "app", "lifecycle": {"preStop": {"exec": {"command": ["sleep", "5"]}}}}]}
+	}`))
+
+	require.EqualError(t, err, "container app already has lifecycle hooks")
+}
+
+func TestServeHTTPRespondsWithPatch(t *testing.T) {
+	request := `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "42", "object": {
+		"metadata": {"annotations": {"vaas.allegro.tech/inject": "true"}},
+		"spec": {"containers": [{"name": "app"}]}
+	}}}`
+	recorder := httptest.NewRecorder()
+
+	testInjector.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewBufferString(request)))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	review := &AdmissionReview{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), review))
+	assert.Equal(t, "AdmissionReview", review.Kind)
+	require.NotNil(t, review.Response)
+	assert.Equal(t, "42", review.Response.UID)
+	assert.True(t, review.Response.Allowed)
+	assert.Equal(t, "JSONPatch", *review.Response.PatchType)
+
+	var operations []PatchOperation
+	require.NoError(t, json.Unmarshal(review.Response.Patch, &operations))
+	assert.Equal(t, "/spec/containers/0/lifecycle", operations[len(operations)-1].Path)
+}
+
+func TestServeHTTPAdmitsPodWithLifecycleHooksUnpatched(t *testing.T) {
+	request := `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "42", "object": {
+		"metadata": {"annotations": {"vaas.allegro.tech/inject": "true"}},
+		"spec": {"containers": [{"name": "app", "lifecycle": {"preStop": {"exec": {"command": ["sleep", "5"]}}}}]}
+	}}}`
+	recorder := httptest.NewRecorder()
+
+	testInjector.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewBufferString(request)))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	review := &AdmissionReview{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), review))
+	require.NotNil(t, review.Response)
+	assert.True(t, review.Response.Allowed)
+	assert.Nil(t, review.Response.Patch)
+	assert.Nil(t, review.Response.PatchType)
+	assert.Nil(t, review.Response.Result)
+}
+
+func TestServeHTTPRejectsInvalidReview(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	testInjector.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewBufferString(`{}`)))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}